@@ -205,14 +205,14 @@ func (a *Agent) Test(ctx context.Context, waitDuration time.Duration) error {
 		}
 
 		acc := NewAccumulator(input, metricC)
-		acc.SetPrecision(a.Precision())
+		acc.SetPrecision(a.inputPrecision(input))
 
 		// Special instructions for some inputs. cpu, for example, needs to be
 		// run twice in order to return cpu usage percentages.
 		switch input.Config.Name {
 		case "cpu", "mongodb", "procstat":
 			nulAcc := NewAccumulator(input, nulC)
-			nulAcc.SetPrecision(a.Precision())
+			nulAcc.SetPrecision(a.inputPrecision(input))
 			if err := input.Input.Gather(nulAcc); err != nil {
 				acc.AddError(err)
 			}
@@ -261,7 +261,7 @@ func (a *Agent) runInputs(
 		}
 
 		acc := NewAccumulator(input, dst)
-		acc.SetPrecision(a.Precision())
+		acc.SetPrecision(a.inputPrecision(input))
 
 		wg.Add(1)
 		go func(input *models.RunningInput) {
@@ -278,6 +278,14 @@ func (a *Agent) runInputs(
 			a.gatherOnInterval(ctx, acc, input, interval, jitter)
 		}(input)
 	}
+
+	if a.Config.Agent.HeartbeatInterval.Duration > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runHeartbeat(ctx, startTime, dst)
+		}()
+	}
 	wg.Wait()
 
 	return nil
@@ -690,12 +698,16 @@ func (a *Agent) startServiceInputs(
 
 	for _, input := range a.Config.Inputs {
 		if si, ok := input.Input.(telegraf.ServiceInput); ok {
-			// Service input plugins are not subject to timestamp rounding.
-			// This only applies to the accumulator passed to Start(), the
-			// Gather() accumulator does apply rounding according to the
-			// precision agent setting.
+			// Service input plugins are not subject to timestamp rounding,
+			// unless the input sets its own precision.  This only applies
+			// to the accumulator passed to Start(), the Gather()
+			// accumulator does apply rounding according to the precision
+			// agent setting.
 			acc := NewAccumulator(input, dst)
 			acc.SetPrecision(time.Nanosecond)
+			if input.Config.Precision > 0 {
+				acc.SetPrecision(input.Config.Precision)
+			}
 
 			err := si.Start(acc)
 			if err != nil {
@@ -725,6 +737,15 @@ func (a *Agent) stopServiceInputs() {
 	}
 }
 
+// inputPrecision returns the rounding precision for the metrics of the
+// input, its own precision when set.
+func (a *Agent) inputPrecision(input *models.RunningInput) time.Duration {
+	if input.Config.Precision > 0 {
+		return input.Config.Precision
+	}
+	return a.Precision()
+}
+
 // Returns the rounding precision for metrics.
 func (a *Agent) Precision() time.Duration {
 	precision := a.Config.Agent.Precision.Duration
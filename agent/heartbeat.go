@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// runHeartbeat emits a heartbeat metric on the heartbeat interval until the
+// context is done.
+func (a *Agent) runHeartbeat(
+	ctx context.Context,
+	startTime time.Time,
+	dst chan<- telegraf.Metric,
+) {
+	interval := a.Config.Agent.HeartbeatInterval.Duration
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m, err := a.heartbeat(startTime, time.Now())
+		if err != nil {
+			log.Printf("E! [agent] Error creating heartbeat: %v", err)
+		} else {
+			dst <- m
+		}
+
+		select {
+		case <-ticker.C:
+			continue
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// heartbeat returns a metric describing the agent, it can be used to detect
+// dead agents and agents running with a different config.
+func (a *Agent) heartbeat(startTime, now time.Time) (telegraf.Metric, error) {
+	tags := make(map[string]string, len(a.Config.Tags)+3)
+	for k, v := range a.Config.Tags {
+		tags[k] = v
+	}
+	tags["version"] = internal.Version()
+	tags["os"] = runtime.GOOS
+	tags["arch"] = runtime.GOARCH
+
+	fields := map[string]interface{}{
+		"config_hash":     a.Config.Hash(),
+		"uptime_ns":       int64(now.Sub(startTime)),
+		"inputs":          pluginList(a.Config.InputNames()),
+		"processors":      pluginList(a.Config.ProcessorNames()),
+		"aggregators":     pluginList(a.Config.AggregatorNames()),
+		"outputs":         pluginList(a.Config.OutputNames()),
+		"num_inputs":      int64(len(a.Config.Inputs)),
+		"num_processors":  int64(len(a.Config.Processors)),
+		"num_aggregators": int64(len(a.Config.Aggregators)),
+		"num_outputs":     int64(len(a.Config.Outputs)),
+	}
+
+	return metric.New("telegraf_heartbeat", tags, fields,
+		now.Truncate(a.Precision()), telegraf.Gauge)
+}
+
+// pluginList returns the sorted, comma separated unique plugin names.
+func pluginList(names []string) string {
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	sort.Strings(unique)
+	return strings.Join(unique, ",")
+}
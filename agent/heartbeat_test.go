@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeat(t *testing.T) {
+	c := config.NewConfig()
+	c.Tags["dc"] = "us-east-1"
+	c.InputFilters = []string{"mysql", "memcached"}
+	require.NoError(t, c.LoadConfig("../internal/config/testdata/telegraf-agent.toml"))
+	require.Len(t, c.Hash(), 64)
+
+	a, _ := NewAgent(c)
+	startTime := time.Unix(0, 0)
+	m, err := a.heartbeat(startTime, startTime.Add(time.Minute))
+	require.NoError(t, err)
+
+	require.Equal(t, "telegraf_heartbeat", m.Name())
+	require.Equal(t, "us-east-1", m.Tags()["dc"])
+	require.Equal(t, runtime.GOOS, m.Tags()["os"])
+	require.Equal(t, runtime.GOARCH, m.Tags()["arch"])
+
+	fields := m.Fields()
+	require.Equal(t, c.Hash(), fields["config_hash"])
+	require.Equal(t, int64(time.Minute), fields["uptime_ns"])
+	require.Equal(t, "memcached,mysql", fields["inputs"])
+	require.Equal(t, int64(len(c.Inputs)), fields["num_inputs"])
+}
+
+func TestPluginList(t *testing.T) {
+	require.Equal(t, "", pluginList(nil))
+	require.Equal(t, "cpu,mem", pluginList([]string{"mem", "cpu", "mem"}))
+}
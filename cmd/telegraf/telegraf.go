@@ -5,13 +5,16 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	_ "net/http/pprof" // Comment this line to disable pprof endpoint.
 	"os"
+	"os/exec"
 	"os/signal"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -20,6 +23,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
 	"github.com/influxdata/telegraf/internal/goplugin"
+	"github.com/influxdata/telegraf/internal/update"
 	"github.com/influxdata/telegraf/logger"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/all"
 	"github.com/influxdata/telegraf/plugins/inputs"
@@ -65,6 +69,10 @@ var fService = flag.String("service", "",
 	"operate on the service (windows only)")
 var fServiceName = flag.String("service-name", "telegraf", "service name (windows only)")
 var fServiceDisplayName = flag.String("service-display-name", "Telegraf Data Collector Service", "service display name (windows only)")
+var fServiceRestartDelay = flag.Duration("service-restart-delay", time.Minute,
+	"delay before restarting the service after a failure, 0s disables restarts (windows only)")
+var fServiceRestartReset = flag.Duration("service-restart-reset", 24*time.Hour,
+	"period without failures after which the failure count is reset (windows only)")
 var fRunAsConsole = flag.Bool("console", false, "run as console application (windows only)")
 var fPlugins = flag.String("plugin-directory", "",
 	"path to directory containing external plugins")
@@ -200,7 +208,61 @@ func runAgent(ctx context.Context,
 		}
 	}
 
-	return ag.Run(ctx)
+	if c.Agent.UpdateURL == "" {
+		return ag.Run(ctx)
+	}
+
+	var staged string
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	updater, err := newUpdater(c.Agent)
+	if err != nil {
+		return err
+	}
+	go updater.Run(ctx, c.Agent.UpdateInterval.Duration,
+		c.Agent.UpdateMode != "check",
+		func(path string) {
+			if c.Agent.UpdateMode == "restart" {
+				staged = path
+				log.Printf("I! Restarting Telegraf to apply update")
+				cancel()
+			}
+		})
+
+	err = ag.Run(ctx)
+	if err != nil || staged == "" {
+		return err
+	}
+	return update.Apply(staged)
+}
+
+func newUpdater(ac *config.AgentConfig) (*update.Updater, error) {
+	switch ac.UpdateMode {
+	case "check", "stage", "restart":
+	default:
+		return nil, fmt.Errorf("invalid update_mode %q", ac.UpdateMode)
+	}
+	if ac.UpdateInterval.Duration <= 0 {
+		return nil, fmt.Errorf("update_interval must be positive, found %s",
+			ac.UpdateInterval.Duration)
+	}
+
+	updater := &update.Updater{
+		URL:        ac.UpdateURL,
+		StagingDir: ac.UpdateStagingDir,
+		Version:    version,
+	}
+	if ac.UpdatePublicKey != "" {
+		key, err := ioutil.ReadFile(ac.UpdatePublicKey)
+		if err != nil {
+			return nil, err
+		}
+		updater.PublicKey = key
+	} else if ac.UpdateMode != "check" {
+		return nil, fmt.Errorf("update_public_key is required with update_mode %q",
+			ac.UpdateMode)
+	}
+	return updater, updater.Init()
 }
 
 func usageExit(rc int) {
@@ -216,6 +278,7 @@ type program struct {
 }
 
 func (p *program) Start(s service.Service) error {
+	logger.LogEvent(logger.EventIDServiceStart, "Telegraf service started")
 	go p.run()
 	return nil
 }
@@ -230,10 +293,34 @@ func (p *program) run() {
 	)
 }
 func (p *program) Stop(s service.Service) error {
+	logger.LogEvent(logger.EventIDServiceStop, "Telegraf service stopping")
 	close(stop)
 	return nil
 }
 
+// setRecoveryActions configures the service manager to restart the service
+// when it fails.
+func setRecoveryActions(name string, delay, reset time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	restart := fmt.Sprintf("restart/%d", delay/time.Millisecond)
+	cmd := exec.Command("sc.exe", "failure", name,
+		"reset=", strconv.FormatInt(int64(reset/time.Second), 10),
+		"actions=", strings.Join([]string{restart, restart, restart}, "/"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("setting recovery actions failed: %v: %s", err, out)
+	}
+
+	// Also restart when the service stops with an error instead of crashing.
+	cmd = exec.Command("sc.exe", "failureflag", name, "1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("setting failure flag failed: %v: %s", err, out)
+	}
+	return nil
+}
+
 func formatFullVersion() string {
 	var parts = []string{"Telegraf"}
 
@@ -385,9 +472,15 @@ func main() {
 		if programFiles == "" { // Should never happen
 			programFiles = "C:\\Program Files"
 		}
+		// Give additional instances a distinct default display name.
+		displayName := *fServiceDisplayName
+		if *fServiceName != "telegraf" && !flagSet("service-display-name") {
+			displayName = fmt.Sprintf("%s (%s)", displayName, *fServiceName)
+		}
+
 		svcConfig := &service.Config{
 			Name:        *fServiceName,
-			DisplayName: *fServiceDisplayName,
+			DisplayName: displayName,
 			Description: "Collects data using a series of plugins and publishes it to" +
 				"another series of plugins.",
 			Arguments: []string{"--config", programFiles + "\\Telegraf\\telegraf.conf"},
@@ -419,6 +512,13 @@ func main() {
 			if err != nil {
 				log.Fatal("E! " + err.Error())
 			}
+			if *fService == "install" {
+				err := setRecoveryActions(*fServiceName,
+					*fServiceRestartDelay, *fServiceRestartReset)
+				if err != nil {
+					log.Fatal("E! " + err.Error())
+				}
+			}
 			os.Exit(0)
 		} else {
 			winlogger, err := s.Logger(nil)
@@ -445,6 +545,17 @@ func main() {
 	}
 }
 
+// flagSet returns true if the flag was set on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 // Return true if Telegraf should create a Windows service.
 func windowsRunAsService() bool {
 	if *fService != "" {
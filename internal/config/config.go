@@ -2,8 +2,11 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io/ioutil"
 	"log"
 	"math"
@@ -66,6 +69,9 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// digest is the hash of the loaded config files
+	digest hash.Hash
 }
 
 func NewConfig() *Config {
@@ -77,6 +83,8 @@ func NewConfig() *Config {
 			FlushInterval:              internal.Duration{Duration: 10 * time.Second},
 			LogTarget:                  "file",
 			LogfileRotationMaxArchives: 5,
+			UpdateInterval:             internal.Duration{Duration: 24 * time.Hour},
+			UpdateMode:                 "check",
 		},
 
 		Tags:          make(map[string]string),
@@ -170,6 +178,29 @@ type AgentConfig struct {
 
 	Hostname     string
 	OmitHostname bool
+
+	// HeartbeatInterval is the interval at which the agent emits a heartbeat
+	// metric describing itself.  When set to 0 no heartbeat is emitted.
+	HeartbeatInterval internal.Duration `toml:"heartbeat_interval"`
+
+	// UpdateURL is the URL of the release manifest checked for new releases.
+	// When empty no update checks are performed.
+	UpdateURL string `toml:"update_url"`
+
+	// UpdateInterval is the interval at which the release manifest is checked.
+	UpdateInterval internal.Duration `toml:"update_interval"`
+
+	// UpdateMode controls what is done when a new release is found and can be
+	// one of "check", "stage" or "restart".
+	UpdateMode string `toml:"update_mode"`
+
+	// UpdatePublicKey is the path of the PEM encoded public key used to verify
+	// the signature of release binaries.
+	UpdatePublicKey string `toml:"update_public_key"`
+
+	// UpdateStagingDir is the directory verified release binaries are written
+	// to.
+	UpdateStagingDir string `toml:"update_staging_dir"`
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -208,6 +239,15 @@ func (c *Config) OutputNames() []string {
 	return name
 }
 
+// Hash returns the hex encoded SHA-256 hash of the loaded config files, in
+// the order they were loaded.
+func (c *Config) Hash() string {
+	if c.digest == nil {
+		return ""
+	}
+	return hex.EncodeToString(c.digest.Sum(nil))
+}
+
 // ListTags returns a string of tags specified in the config,
 // line-protocol style
 func (c *Config) ListTags() string {
@@ -321,6 +361,28 @@ var agentConfig = `
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Interval at which a heartbeat metric is emitted, reporting the agent
+  ## version, config hash, enabled plugins and uptime.  When set to "0s" no
+  ## heartbeat is emitted.
+  # heartbeat_interval = "0s"
+
+  ## URL of a release manifest checked for new Telegraf releases, when empty
+  ## no checks are performed.
+  # update_url = ""
+  ## Interval at which the release manifest is checked.
+  # update_interval = "24h"
+  ## Action taken when a new release is found:
+  ##   check   -- log that the release is available
+  ##   stage   -- download and verify the binary into update_staging_dir
+  ##   restart -- stage the binary, replace the running executable and restart
+  ##              Telegraf after flushing the outputs (not supported on Windows)
+  # update_mode = "check"
+  ## PEM encoded RSA or ECDSA public key verifying the release binaries, it
+  ## is required to stage releases.
+  # update_public_key = "/etc/telegraf/release.pem"
+  ## Directory the verified binaries are written to.
+  # update_staging_dir = "/var/lib/telegraf/updates"
+
 `
 
 var outputHeader = `
@@ -687,6 +749,11 @@ func (c *Config) LoadConfig(path string) error {
 		return fmt.Errorf("Error loading %s, %s", path, err)
 	}
 
+	if c.digest == nil {
+		c.digest = sha256.New()
+	}
+	c.digest.Write(data)
+
 	tbl, err := parseConfig(data)
 	if err != nil {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
@@ -1376,6 +1443,18 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["precision"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				precision, err := parsePrecision(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				cp.Precision = precision
+			}
+		}
+	}
+
 	cp.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
@@ -1390,6 +1469,7 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "precision")
 	delete(tbl.Fields, "tags")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
@@ -1399,6 +1479,27 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	return cp, nil
 }
 
+// parsePrecision parses the precision of the timestamps, either as a unit,
+// "s", "ms", "us" or "ns", or as a duration.
+func parsePrecision(s string) (time.Duration, error) {
+	switch s {
+	case "s":
+		return time.Second, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "us":
+		return time.Microsecond, nil
+	case "ns":
+		return time.Nanosecond, nil
+	}
+
+	precision, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid precision %q", s)
+	}
+	return precision, nil
+}
+
 // buildParser grabs the necessary entries from the ast.Table for creating
 // a parsers.Parser object, and creates it, which can then be added onto
 // an Input object.
@@ -1810,8 +1911,20 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				if _, err := time.LoadLocation(str.Value); err != nil {
+					return nil, fmt.Errorf("invalid timezone %q: %v", str.Value, err)
+				}
+				c.Timezone = str.Value
+			}
+		}
+	}
+
 	c.MetricName = name
 
+	delete(tbl.Fields, "timezone")
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "separator")
 	delete(tbl.Fields, "templates")
@@ -1927,6 +2040,14 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["influx_dialect"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.InfluxDialect = str.Value
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["graphite_tag_support"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if b, ok := kv.Value.(*ast.Boolean); ok {
@@ -2042,6 +2163,7 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 	delete(tbl.Fields, "influx_max_line_bytes")
 	delete(tbl.Fields, "influx_sort_fields")
 	delete(tbl.Fields, "influx_uint_support")
+	delete(tbl.Fields, "influx_dialect")
 	delete(tbl.Fields, "graphite_tag_support")
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "prefix")
@@ -2138,11 +2260,37 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["precision"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				precision, err := parsePrecision(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.Precision = precision
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_metric_age"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.MaxMetricAge = dur
+			}
+		}
+	}
+
 	delete(tbl.Fields, "flush_interval")
 	delete(tbl.Fields, "flush_jitter")
 	delete(tbl.Fields, "metric_buffer_limit")
 	delete(tbl.Fields, "metric_batch_size")
 	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "precision")
+	delete(tbl.Fields, "max_metric_age")
 
 	return oc, nil
 }
@@ -4,15 +4,38 @@ import (
 	"bytes"
 	"compress/gzip"
 	"errors"
+	"fmt"
 	"io"
+
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
+// EncodingOption is an option of the content encoders.
+type EncodingOption func(*encodingOptions)
+
+type encodingOptions struct {
+	level int
+}
+
+// WithCompressionLevel sets the compression level of the gzip (1 to 9) and
+// zstd (1 to 22) encoders, 0 is the default level of the encoding.
+func WithCompressionLevel(level int) EncodingOption {
+	return func(o *encodingOptions) {
+		o.level = level
+	}
+}
+
 // NewContentEncoder returns a ContentEncoder for the encoding type.
-func NewContentEncoder(encoding string) (ContentEncoder, error) {
+func NewContentEncoder(encoding string, options ...EncodingOption) (ContentEncoder, error) {
 	switch encoding {
 	case "gzip":
-		return NewGzipEncoder()
-
+		return NewGzipEncoder(options...)
+	case "zstd":
+		return NewZstdEncoder(options...)
+	case "snappy":
+		return NewSnappyEncoder(), nil
 	case "identity", "":
 		return NewIdentityEncoder(), nil
 	default:
@@ -25,6 +48,10 @@ func NewContentDecoder(encoding string) (ContentDecoder, error) {
 	switch encoding {
 	case "gzip":
 		return NewGzipDecoder()
+	case "zstd":
+		return NewZstdDecoder()
+	case "snappy":
+		return NewSnappyDecoder(), nil
 	case "identity", "":
 		return NewIdentityDecoder(), nil
 	default:
@@ -37,16 +64,29 @@ type ContentEncoder interface {
 	Encode([]byte) ([]byte, error)
 }
 
-// GzipEncoder compresses the buffer using gzip at the default level.
+// GzipEncoder compresses the buffer using gzip, at the default level unless
+// set.
 type GzipEncoder struct {
 	writer *gzip.Writer
 	buf    *bytes.Buffer
 }
 
-func NewGzipEncoder() (*GzipEncoder, error) {
+func NewGzipEncoder(options ...EncodingOption) (*GzipEncoder, error) {
+	var o encodingOptions
+	for _, option := range options {
+		option(&o)
+	}
+	if o.level == 0 {
+		o.level = gzip.DefaultCompression
+	}
+
 	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, o.level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip compression level %d", o.level)
+	}
 	return &GzipEncoder{
-		writer: gzip.NewWriter(&buf),
+		writer: writer,
 		buf:    &buf,
 	}, nil
 }
@@ -66,6 +106,83 @@ func (e *GzipEncoder) Encode(data []byte) ([]byte, error) {
 	return e.buf.Bytes(), nil
 }
 
+// ZstdEncoder compresses the buffer using zstd.  The encoder and its tables
+// are reused for all the buffers.
+type ZstdEncoder struct {
+	encoder *zstd.Encoder
+	buf     []byte
+}
+
+func NewZstdEncoder(options ...EncodingOption) (*ZstdEncoder, error) {
+	var o encodingOptions
+	for _, option := range options {
+		option(&o)
+	}
+
+	var zopts []zstd.EOption
+	if o.level != 0 {
+		if o.level < 1 || o.level > 22 {
+			return nil, fmt.Errorf("invalid zstd compression level %d", o.level)
+		}
+		zopts = append(zopts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(o.level)))
+	}
+
+	encoder, err := zstd.NewWriter(nil, zopts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdEncoder{encoder: encoder}, nil
+}
+
+func (e *ZstdEncoder) Encode(data []byte) ([]byte, error) {
+	e.buf = e.encoder.EncodeAll(data, e.buf[:0])
+	return e.buf, nil
+}
+
+// SnappyEncoder compresses the buffer using the snappy block format.
+type SnappyEncoder struct {
+	buf []byte
+}
+
+func NewSnappyEncoder() *SnappyEncoder {
+	return &SnappyEncoder{}
+}
+
+func (e *SnappyEncoder) Encode(data []byte) ([]byte, error) {
+	e.buf = snappy.Encode(e.buf[:cap(e.buf)], data)
+	return e.buf, nil
+}
+
+// StatsEncoder counts the bytes before and after the encoding of the
+// wrapped encoder in the internal "bytes_uncompressed" and
+// "bytes_compressed" stats.
+type StatsEncoder struct {
+	ContentEncoder
+
+	uncompressed selfstat.Stat
+	compressed   selfstat.Stat
+}
+
+// NewStatsEncoder returns the encoder counting its bytes in the stats of the
+// measurement, with the tags.
+func NewStatsEncoder(encoder ContentEncoder, measurement string, tags map[string]string) *StatsEncoder {
+	return &StatsEncoder{
+		ContentEncoder: encoder,
+		uncompressed:   selfstat.Register(measurement, "bytes_uncompressed", tags),
+		compressed:     selfstat.Register(measurement, "bytes_compressed", tags),
+	}
+}
+
+func (e *StatsEncoder) Encode(data []byte) ([]byte, error) {
+	encoded, err := e.ContentEncoder.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+	e.uncompressed.Incr(int64(len(data)))
+	e.compressed.Incr(int64(len(encoded)))
+	return encoded, nil
+}
+
 // IdentityEncoder is a null encoder that applies no transformation.
 type IdentityEncoder struct{}
 
@@ -110,6 +227,40 @@ func (d *GzipDecoder) Decode(data []byte) ([]byte, error) {
 	return d.buf.Bytes(), nil
 }
 
+// ZstdDecoder decompresses buffers with zstd compression.
+type ZstdDecoder struct {
+	decoder *zstd.Decoder
+	buf     []byte
+}
+
+func NewZstdDecoder() (*ZstdDecoder, error) {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdDecoder{decoder: decoder}, nil
+}
+
+func (d *ZstdDecoder) Decode(data []byte) ([]byte, error) {
+	buf, err := d.decoder.DecodeAll(data, d.buf[:0])
+	if err != nil {
+		return nil, err
+	}
+	d.buf = buf
+	return buf, nil
+}
+
+// SnappyDecoder decompresses buffers with snappy block compression.
+type SnappyDecoder struct{}
+
+func NewSnappyDecoder() *SnappyDecoder {
+	return &SnappyDecoder{}
+}
+
+func (*SnappyDecoder) Decode(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
 // IdentityDecoder is a null decoder that returns the input.
 type IdentityDecoder struct{}
 
@@ -56,3 +56,70 @@ func TestIdentityEncodeDecode(t *testing.T) {
 
 	require.Equal(t, "howdy", string(actual))
 }
+
+func TestGzipCompressionLevel(t *testing.T) {
+	enc, err := NewContentEncoder("gzip", WithCompressionLevel(9))
+	require.NoError(t, err)
+	dec, err := NewContentDecoder("gzip")
+	require.NoError(t, err)
+
+	payload, err := enc.Encode([]byte("howdy"))
+	require.NoError(t, err)
+
+	actual, err := dec.Decode(payload)
+	require.NoError(t, err)
+
+	require.Equal(t, "howdy", string(actual))
+
+	_, err = NewContentEncoder("gzip", WithCompressionLevel(10))
+	require.Error(t, err)
+}
+
+func TestZstdEncodeDecode(t *testing.T) {
+	enc, err := NewContentEncoder("zstd", WithCompressionLevel(3))
+	require.NoError(t, err)
+	dec, err := NewContentDecoder("zstd")
+	require.NoError(t, err)
+
+	for _, s := range []string{"howdy", "doody"} {
+		payload, err := enc.Encode([]byte(s))
+		require.NoError(t, err)
+
+		actual, err := dec.Decode(payload)
+		require.NoError(t, err)
+
+		require.Equal(t, s, string(actual))
+	}
+
+	_, err = NewContentEncoder("zstd", WithCompressionLevel(23))
+	require.Error(t, err)
+}
+
+func TestSnappyEncodeDecode(t *testing.T) {
+	enc, err := NewContentEncoder("snappy")
+	require.NoError(t, err)
+	dec, err := NewContentDecoder("snappy")
+	require.NoError(t, err)
+
+	for _, s := range []string{"howdy", "doody"} {
+		payload, err := enc.Encode([]byte(s))
+		require.NoError(t, err)
+
+		actual, err := dec.Decode(payload)
+		require.NoError(t, err)
+
+		require.Equal(t, s, string(actual))
+	}
+}
+
+func TestStatsEncoder(t *testing.T) {
+	gzip, err := NewGzipEncoder()
+	require.NoError(t, err)
+	enc := NewStatsEncoder(gzip, "test_encoder", map[string]string{"output": "test"})
+
+	payload, err := enc.Encode([]byte("howdy"))
+	require.NoError(t, err)
+
+	require.Equal(t, int64(5), enc.uncompressed.Get())
+	require.Equal(t, int64(len(payload)), enc.compressed.Get())
+}
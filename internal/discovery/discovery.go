@@ -0,0 +1,568 @@
+// Package discovery discovers the targets of the probe inputs from files,
+// DNS SRV records, Consul services, HTTP service discovery endpoints and
+// Kubernetes objects.  The files, records, services and endpoints are
+// refreshed periodically so the targets follow their changes without a
+// reload of the configuration, the Kubernetes objects are watched.
+package discovery
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/k8sdiscovery"
+	"github.com/influxdata/telegraf/internal/tls"
+)
+
+const defaultRefreshInterval = time.Minute
+
+// lookupSRV resolves the SRV records, replaced by the tests.
+var lookupSRV = net.LookupSRV
+
+// Config is the configuration of the discovery, inputs have it as their
+// "discovery" table.
+type Config struct {
+	// Files with one target per line.
+	Files []string `toml:"files"`
+
+	// Names of the DNS SRV records of the targets.
+	DNSSRV []string `toml:"dns_srv"`
+
+	// Consul services of the targets.
+	Consul *ConsulConfig `toml:"consul"`
+
+	// HTTP service discovery endpoints of the targets.
+	HTTP *HTTPConfig `toml:"http"`
+
+	// Kubernetes pods or services of the targets.
+	Kubernetes *k8sdiscovery.Config `toml:"kubernetes"`
+
+	// Defaults of the target URL when the target is a host, the port is
+	// also used for the hosts without port.
+	Scheme string `toml:"scheme"`
+	Port   string `toml:"port"`
+	Path   string `toml:"path"`
+
+	// Interval of the refresh of the files, DNS SRV records, Consul
+	// services and HTTP endpoints.
+	RefreshInterval internal.Duration `toml:"refresh_interval"`
+}
+
+// ConsulConfig is the configuration of the discovery of Consul services.
+type ConsulConfig struct {
+	Address    string `toml:"address"`
+	Scheme     string `toml:"scheme"`
+	Token      string `toml:"token"`
+	Datacenter string `toml:"datacenter"`
+
+	// Services of the targets, with the tag when set.  All the services of
+	// the catalog having the tags are targets when empty.
+	Services []string `toml:"services"`
+	Tag      string   `toml:"tag"`
+
+	// Tags the instances must all have, in addition to the tag.
+	Tags []string `toml:"tags"`
+
+	// Metadata of the services added to the target tags, by metadata key
+	// to tag name.
+	MetaTags map[string]string `toml:"meta_tags"`
+
+	// Whether the instances failing their health checks are targets.
+	IncludeFailing bool `toml:"include_failing"`
+}
+
+// HTTPConfig is the configuration of the discovery from the endpoints of
+// the Prometheus HTTP service discovery, returning the target groups as
+// [{"targets": ["host:port"], "labels": {"name": "value"}}].
+type HTTPConfig struct {
+	URLs    []string          `toml:"urls"`
+	Timeout internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+}
+
+// httpTargetGroup is a target group of the HTTP service discovery.
+type httpTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// Target is a discovered target.
+type Target struct {
+	// Host is the host name or IP of the target.
+	Host string
+	// Port of the target, empty when unknown.
+	Port string
+	// URL of the target, built from the host and port with the defaults of
+	// the configuration when the source has no URL.
+	URL *url.URL
+	// Tags of the target, from its source.
+	Tags map[string]string
+}
+
+// Accumulator returns an accumulator adding the tags of the target to the
+// metrics, the tags of the metrics take precedence.
+func (t *Target) Accumulator(acc telegraf.Accumulator) telegraf.Accumulator {
+	return &targetAccumulator{Accumulator: acc, tags: t.Tags}
+}
+
+type targetAccumulator struct {
+	telegraf.Accumulator
+	tags map[string]string
+}
+
+func (a *targetAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	merged := make(map[string]string, len(a.tags)+len(tags))
+	for k, v := range a.tags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	a.Accumulator.AddFields(measurement, fields, merged, t...)
+}
+
+// consulHealth is the health endpoint of the Consul API.
+type consulHealth interface {
+	Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error)
+}
+
+// consulCatalog is the catalog endpoint of the Consul API.
+type consulCatalog interface {
+	Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error)
+}
+
+// Discovery keeps the discovered targets.
+type Discovery struct {
+	config Config
+	log    telegraf.Logger
+
+	consul        consulHealth
+	consulCatalog consulCatalog
+	httpClient    *http.Client
+	kubernetes    *k8sdiscovery.Discovery
+
+	lock sync.Mutex
+	// targets of the refreshed sources, by source
+	targets map[string][]*Target
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New returns a discovery with the configuration, the defaults are set for
+// the empty settings.
+func New(config Config, log telegraf.Logger) *Discovery {
+	if config.Scheme == "" {
+		config.Scheme = "http"
+	}
+	if config.RefreshInterval.Duration == 0 {
+		config.RefreshInterval.Duration = defaultRefreshInterval
+	}
+	return &Discovery{
+		config:  config,
+		log:     log,
+		targets: make(map[string][]*Target),
+	}
+}
+
+// Start refreshes the targets once, then periodically, and starts the
+// discovery of the Kubernetes objects.
+func (d *Discovery) Start() error {
+	if c := d.config.Consul; c != nil && d.consul == nil {
+		config := api.DefaultConfig()
+		if c.Address != "" {
+			config.Address = c.Address
+		}
+		if c.Scheme != "" {
+			config.Scheme = c.Scheme
+		}
+		config.Datacenter = c.Datacenter
+		config.Token = c.Token
+
+		client, err := api.NewClient(config)
+		if err != nil {
+			return fmt.Errorf("creating consul client failed: %v", err)
+		}
+		d.consul = client.Health()
+		d.consulCatalog = client.Catalog()
+	}
+
+	if c := d.config.HTTP; c != nil && d.httpClient == nil {
+		tlsCfg, err := c.ClientConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		timeout := c.Timeout.Duration
+		if timeout == 0 {
+			timeout = 5 * time.Second
+		}
+		d.httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: timeout,
+		}
+	}
+
+	if d.config.Kubernetes != nil {
+		d.kubernetes = k8sdiscovery.New(*d.config.Kubernetes, d.log)
+		if err := d.kubernetes.Start(); err != nil {
+			return err
+		}
+	}
+
+	d.refresh()
+
+	d.done = make(chan struct{})
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.config.RefreshInterval.Duration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+				d.refresh()
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the refresh and the discovery of the Kubernetes objects.
+func (d *Discovery) Stop() {
+	if d.done != nil {
+		close(d.done)
+		d.wg.Wait()
+		d.done = nil
+	}
+	if d.kubernetes != nil {
+		d.kubernetes.Stop()
+	}
+}
+
+// Targets returns the current targets of all the sources, sorted by URL.
+// The targets found by several sources are returned once.
+func (d *Discovery) Targets() []*Target {
+	byURL := make(map[string]*Target)
+
+	d.lock.Lock()
+	for _, targets := range d.targets {
+		for _, t := range targets {
+			byURL[t.URL.String()] = t
+		}
+	}
+	d.lock.Unlock()
+
+	if d.kubernetes != nil {
+		for _, k := range d.kubernetes.Targets() {
+			byURL[k.URL.String()] = &Target{
+				Host: k.Address,
+				Port: k.URL.Port(),
+				URL:  k.URL,
+				Tags: k.Tags,
+			}
+		}
+	}
+
+	targets := make([]*Target, 0, len(byURL))
+	for _, t := range byURL {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].URL.String() < targets[j].URL.String()
+	})
+	return targets
+}
+
+// refresh reads the targets of the files, DNS SRV records, Consul services
+// and HTTP endpoints.  The previous targets of a source are kept when it
+// fails.
+func (d *Discovery) refresh() {
+	for _, path := range d.config.Files {
+		targets, err := d.readFile(path)
+		d.update("file:"+path, targets, err)
+	}
+	for _, name := range d.config.DNSSRV {
+		targets, err := d.lookupSRV(name)
+		d.update("dns_srv:"+name, targets, err)
+	}
+	if d.consul != nil {
+		if len(d.config.Consul.Services) == 0 {
+			targets, err := d.consulServices()
+			d.update("consul", targets, err)
+		}
+		for _, service := range d.config.Consul.Services {
+			targets, err := d.consulService(service)
+			d.update("consul:"+service, targets, err)
+		}
+	}
+	if d.httpClient != nil {
+		for _, u := range d.config.HTTP.URLs {
+			targets, err := d.httpTargets(u)
+			d.update("http:"+u, targets, err)
+		}
+	}
+}
+
+func (d *Discovery) update(source string, targets []*Target, err error) {
+	if err != nil {
+		d.log.Errorf("Discovering targets from %s failed: %v", source, err)
+		return
+	}
+
+	d.lock.Lock()
+	d.targets[source] = targets
+	d.lock.Unlock()
+}
+
+// readFile reads the targets of the file, one by line as a URL or a
+// "host[:port]" followed by optional "key=value" tags.  Empty lines and lines
+// starting with "#" are skipped.
+func (d *Discovery) readFile(path string) ([]*Target, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var targets []*Target
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		tags := make(map[string]string)
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("line %d: invalid tag %q", n, field)
+			}
+			tags[kv[0]] = kv[1]
+		}
+
+		var t *Target
+		if strings.Contains(fields[0], "://") {
+			u, err := url.Parse(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			t = &Target{Host: u.Hostname(), Port: u.Port(), URL: u}
+		} else {
+			host, port, err := net.SplitHostPort(fields[0])
+			if err != nil {
+				host, port = fields[0], ""
+			}
+			t = d.target(host, port)
+		}
+		t.Tags = tags
+		targets = append(targets, t)
+	}
+	return targets, scanner.Err()
+}
+
+// lookupSRV resolves the targets of the DNS SRV record.
+func (d *Discovery) lookupSRV(name string) ([]*Target, error) {
+	_, records, err := lookupSRV("", "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*Target, 0, len(records))
+	for _, r := range records {
+		t := d.target(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+		t.Tags = map[string]string{"srv_record": name}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// consulServices returns the targets of the services of the Consul catalog
+// having the tags.
+func (d *Discovery) consulServices() ([]*Target, error) {
+	c := d.config.Consul
+	services, _, err := d.consulCatalog.Services(&api.QueryOptions{Datacenter: c.Datacenter})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(services))
+	for name, tags := range services {
+		if c.Tag != "" && !hasTags(tags, []string{c.Tag}) {
+			continue
+		}
+		if hasTags(tags, c.Tags) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var targets []*Target
+	for _, name := range names {
+		serviceTargets, err := d.consulService(name)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, serviceTargets...)
+	}
+	return targets, nil
+}
+
+// consulService returns the targets of the instances of the Consul service,
+// the healthy ones unless the failing ones are included.
+func (d *Discovery) consulService(service string) ([]*Target, error) {
+	c := d.config.Consul
+	entries, _, err := d.consul.Service(service, c.Tag, !c.IncludeFailing,
+		&api.QueryOptions{Datacenter: c.Datacenter})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*Target, 0, len(entries))
+	for _, e := range entries {
+		if e.Service == nil || e.Node == nil || !hasTags(e.Service.Tags, c.Tags) {
+			continue
+		}
+
+		// The service address defaults to the address of its node
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		var port string
+		if e.Service.Port != 0 {
+			port = strconv.Itoa(e.Service.Port)
+		}
+
+		t := d.target(host, port)
+		t.Tags = map[string]string{
+			"consul_service": service,
+			"consul_node":    e.Node.Node,
+		}
+		for key, tag := range c.MetaTags {
+			if value, ok := e.Service.Meta[key]; ok {
+				t.Tags[tag] = value
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// httpTargets returns the targets of the groups of the HTTP service discovery
+// endpoint.  The labels are the tags of the targets, except the labels
+// starting with "__": the "__scheme__" and "__metrics_path__" labels are the
+// scheme and path of the URL, and the "__param_<name>" labels its query
+// parameters.
+func (d *Discovery) httpTargets(u string) ([]*Target, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Prometheus-Refresh-Interval-Seconds",
+		strconv.Itoa(int(d.config.RefreshInterval.Duration.Seconds())))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+	var groups []httpTargetGroup
+	if err := json.NewDecoder(resp.Body).Decode(&groups); err != nil {
+		return nil, fmt.Errorf("decoding target groups of %s failed: %v", u, err)
+	}
+
+	var targets []*Target
+	for _, g := range groups {
+		for _, address := range g.Targets {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				host, port = address, ""
+			}
+
+			t := d.target(host, port)
+			t.Tags = make(map[string]string)
+			query := url.Values{}
+			for name, value := range g.Labels {
+				switch {
+				case name == "__scheme__":
+					t.URL.Scheme = value
+				case name == "__metrics_path__":
+					t.URL.Path = value
+				case strings.HasPrefix(name, "__param_"):
+					query.Set(strings.TrimPrefix(name, "__param_"), value)
+				case !strings.HasPrefix(name, "__"):
+					t.Tags[name] = value
+				}
+			}
+			t.URL.RawQuery = query.Encode()
+			targets = append(targets, t)
+		}
+	}
+	return targets, nil
+}
+
+// hasTags returns whether the tags contain all the wanted tags.
+func hasTags(tags, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, tag := range tags {
+			if tag == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// target returns the target of the host, with the URL built from the
+// defaults of the configuration.
+func (d *Discovery) target(host, port string) *Target {
+	if port == "" {
+		port = d.config.Port
+	}
+
+	hostport := host
+	if port != "" {
+		hostport = net.JoinHostPort(host, port)
+	}
+	return &Target{
+		Host: host,
+		Port: port,
+		URL: &url.URL{
+			Scheme: d.config.Scheme,
+			Host:   hostport,
+			Path:   d.config.Path,
+		},
+	}
+}
@@ -0,0 +1,250 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discovery")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "targets")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+# web servers
+web1.example.org
+web2.example.org:8080 env=prod  rack=a1
+https://api.example.org/healthz
+`), 0644))
+
+	d := New(Config{Port: "80", Files: []string{path}}, testutil.Logger{})
+	d.refresh()
+
+	targets := d.Targets()
+	require.Len(t, targets, 3)
+	assert.Equal(t, "http://web1.example.org:80", targets[0].URL.String())
+	assert.Equal(t, "web1.example.org", targets[0].Host)
+	assert.Equal(t, "80", targets[0].Port)
+	assert.Equal(t, "http://web2.example.org:8080", targets[1].URL.String())
+	assert.Equal(t, map[string]string{"env": "prod", "rack": "a1"}, targets[1].Tags)
+	assert.Equal(t, "https://api.example.org/healthz", targets[2].URL.String())
+	assert.Equal(t, "api.example.org", targets[2].Host)
+}
+
+func TestReadFileInvalidTagKeepsTargets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discovery")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "targets")
+	require.NoError(t, ioutil.WriteFile(path, []byte("web1.example.org\n"), 0644))
+
+	d := New(Config{Files: []string{path}}, testutil.Logger{})
+	d.refresh()
+	require.Len(t, d.Targets(), 1)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("web1.example.org env\n"), 0644))
+	d.refresh()
+	require.Len(t, d.Targets(), 1)
+}
+
+func TestLookupSRV(t *testing.T) {
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		if name != "_http._tcp.example.org" {
+			return "", nil, errors.New("no such host")
+		}
+		return "", []*net.SRV{
+			{Target: "web1.example.org.", Port: 8080},
+			{Target: "web2.example.org.", Port: 8081},
+		}, nil
+	}
+	defer func() { lookupSRV = net.LookupSRV }()
+
+	d := New(Config{Scheme: "https", DNSSRV: []string{"_http._tcp.example.org"}}, testutil.Logger{})
+	d.refresh()
+
+	targets := d.Targets()
+	require.Len(t, targets, 2)
+	assert.Equal(t, "https://web1.example.org:8080", targets[0].URL.String())
+	assert.Equal(t, "web1.example.org", targets[0].Host)
+	assert.Equal(t, map[string]string{"srv_record": "_http._tcp.example.org"}, targets[0].Tags)
+	assert.Equal(t, "https://web2.example.org:8081", targets[1].URL.String())
+}
+
+type fakeHealth struct {
+	passingOnly bool
+	services    []string
+	entries     []*api.ServiceEntry
+}
+
+func (h *fakeHealth) Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	h.passingOnly = passingOnly
+	h.services = append(h.services, service)
+	return h.entries, nil, nil
+}
+
+type fakeCatalog struct {
+	services map[string][]string
+}
+
+func (c *fakeCatalog) Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error) {
+	return c.services, nil, nil
+}
+
+func TestConsulService(t *testing.T) {
+	health := &fakeHealth{
+		entries: []*api.ServiceEntry{
+			{
+				Node:    &api.Node{Node: "node1", Address: "10.0.0.1"},
+				Service: &api.AgentService{Service: "web", Port: 8080},
+			},
+			{
+				Node:    &api.Node{Node: "node2", Address: "10.0.0.2"},
+				Service: &api.AgentService{Service: "web", Address: "10.0.1.2", Port: 8080},
+			},
+		},
+	}
+
+	d := New(Config{Consul: &ConsulConfig{Services: []string{"web"}}}, testutil.Logger{})
+	d.consul = health
+	d.refresh()
+
+	assert.True(t, health.passingOnly)
+	targets := d.Targets()
+	require.Len(t, targets, 2)
+	assert.Equal(t, "http://10.0.0.1:8080", targets[0].URL.String())
+	assert.Equal(t, map[string]string{"consul_service": "web", "consul_node": "node1"}, targets[0].Tags)
+	assert.Equal(t, "http://10.0.1.2:8080", targets[1].URL.String())
+	assert.Equal(t, "10.0.1.2", targets[1].Host)
+}
+
+func TestConsulServiceTagsAndMeta(t *testing.T) {
+	health := &fakeHealth{
+		entries: []*api.ServiceEntry{
+			{
+				Node: &api.Node{Node: "node1", Address: "10.0.0.1"},
+				Service: &api.AgentService{
+					Service: "web",
+					Port:    9100,
+					Tags:    []string{"metrics", "prod"},
+					Meta:    map[string]string{"version": "1.2", "owner": "web-team"},
+				},
+			},
+			{
+				Node:    &api.Node{Node: "node2", Address: "10.0.0.2"},
+				Service: &api.AgentService{Service: "web", Port: 9100, Tags: []string{"metrics"}},
+			},
+		},
+	}
+
+	d := New(Config{Consul: &ConsulConfig{
+		Services: []string{"web"},
+		Tags:     []string{"metrics", "prod"},
+		MetaTags: map[string]string{"version": "service_version"},
+	}}, testutil.Logger{})
+	d.consul = health
+	d.refresh()
+
+	targets := d.Targets()
+	require.Len(t, targets, 1)
+	assert.Equal(t, "http://10.0.0.1:9100", targets[0].URL.String())
+	assert.Equal(t, map[string]string{
+		"consul_service":  "web",
+		"consul_node":     "node1",
+		"service_version": "1.2",
+	}, targets[0].Tags)
+}
+
+func TestConsulCatalogServices(t *testing.T) {
+	health := &fakeHealth{
+		entries: []*api.ServiceEntry{
+			{
+				Node:    &api.Node{Node: "node1", Address: "10.0.0.1"},
+				Service: &api.AgentService{Port: 9100, Tags: []string{"metrics"}},
+			},
+		},
+	}
+	catalog := &fakeCatalog{services: map[string][]string{
+		"web":    {"metrics", "prod"},
+		"db":     {"metrics"},
+		"consul": {},
+	}}
+
+	d := New(Config{Consul: &ConsulConfig{Tags: []string{"metrics"}}}, testutil.Logger{})
+	d.consul = health
+	d.consulCatalog = catalog
+	d.refresh()
+
+	assert.Equal(t, []string{"db", "web"}, health.services)
+	assert.Len(t, d.targets["consul"], 2)
+}
+
+func TestHTTPTargets(t *testing.T) {
+	response := `[
+		{"targets": ["10.0.0.1:9100", "10.0.0.2:9100"], "labels": {"env": "prod", "__meta_datacenter": "eu"}},
+		{"targets": ["10.0.0.3:8443"], "labels": {"__scheme__": "https", "__metrics_path__": "/probe", "__param_module": "http_2xx"}}
+	]`
+	var refreshInterval string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshInterval = r.Header.Get("X-Prometheus-Refresh-Interval-Seconds")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, response)
+	}))
+	defer ts.Close()
+
+	d := New(Config{Path: "/metrics", HTTP: &HTTPConfig{URLs: []string{ts.URL}}}, testutil.Logger{})
+	require.NoError(t, d.Start())
+	defer d.Stop()
+
+	assert.Equal(t, "60", refreshInterval)
+	targets := d.Targets()
+	require.Len(t, targets, 3)
+	assert.Equal(t, "http://10.0.0.1:9100/metrics", targets[0].URL.String())
+	assert.Equal(t, map[string]string{"env": "prod"}, targets[0].Tags)
+	assert.Equal(t, "http://10.0.0.2:9100/metrics", targets[1].URL.String())
+	assert.Equal(t, "https://10.0.0.3:8443/probe?module=http_2xx", targets[2].URL.String())
+	assert.Equal(t, "10.0.0.3", targets[2].Host)
+	assert.Empty(t, targets[2].Tags)
+
+	// The targets are kept when the endpoint fails.
+	response = `{"error": "unavailable"}`
+	d.refresh()
+	assert.Len(t, d.Targets(), 3)
+
+	response = `[]`
+	d.refresh()
+	assert.Empty(t, d.Targets())
+}
+
+func TestTargetsDeduplicated(t *testing.T) {
+	d := New(Config{}, testutil.Logger{})
+	d.update("file:a", []*Target{d.target("web1.example.org", "80")}, nil)
+	d.update("file:b", []*Target{d.target("web1.example.org", "80")}, nil)
+	assert.Len(t, d.Targets(), 1)
+}
+
+func TestTargetAccumulator(t *testing.T) {
+	target := &Target{Tags: map[string]string{"env": "prod", "url": "target"}}
+
+	var acc testutil.Accumulator
+	target.Accumulator(&acc).AddFields("ping",
+		map[string]interface{}{"result_code": 0},
+		map[string]string{"url": "web1.example.org"})
+
+	acc.AssertContainsTaggedFields(t, "ping",
+		map[string]interface{}{"result_code": 0},
+		map[string]string{"env": "prod", "url": "web1.example.org"})
+}
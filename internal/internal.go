@@ -297,16 +297,27 @@ func (r *ReadWaitCloser) Close() error {
 // it through a gzip.Writer returning an io.Reader containing
 // the gzipped data.
 // An error is returned if passing data to the gzip.Writer fails
-func CompressWithGzip(data io.Reader) (io.ReadCloser, error) {
+// or if the compression level of the options is invalid.
+func CompressWithGzip(data io.Reader, options ...EncodingOption) (io.ReadCloser, error) {
+	var o encodingOptions
+	for _, option := range options {
+		option(&o)
+	}
+	if o.level == 0 {
+		o.level = gzip.DefaultCompression
+	}
+
 	pipeReader, pipeWriter := io.Pipe()
-	gzipWriter := gzip.NewWriter(pipeWriter)
+	gzipWriter, err := gzip.NewWriterLevel(pipeWriter, o.level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip compression level %d", o.level)
+	}
 
 	rc := &ReadWaitCloser{
 		pipeReader: pipeReader,
 	}
 
 	rc.wg.Add(1)
-	var err error
 	go func() {
 		_, err = io.Copy(gzipWriter, data)
 		gzipWriter.Close()
@@ -0,0 +1,124 @@
+package k8sdiscovery
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ericchiang/k8s"
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+// syncEndpoints lists the annotated services and their endpoints to replace
+// the cached targets, then applies the events of a watch of the endpoints
+// until the resync interval elapsed.  The annotations of the services are
+// only read on the resync.
+func (d *Discovery) syncEndpoints(ctx context.Context, client *k8s.Client) error {
+	var services corev1.ServiceList
+	if err := client.List(ctx, d.config.Namespace, &services); err != nil {
+		return err
+	}
+
+	scraped := make(map[string]*corev1.Service)
+	for _, service := range services.GetItems() {
+		if d.annotation(service.GetMetadata(), "scrape") == "true" {
+			scraped[serviceKey(service.GetMetadata().GetNamespace(), service.GetMetadata().GetName())] = service
+		}
+	}
+
+	var endpoints corev1.EndpointsList
+	if err := client.List(ctx, d.config.Namespace, &endpoints); err != nil {
+		return err
+	}
+
+	targets := make(map[string]*Target)
+	for _, e := range endpoints.GetItems() {
+		for _, t := range d.endpointsTargets(scraped, e) {
+			targets[t.URL.String()] = t
+		}
+	}
+	d.replaceTargets(targets)
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.ResyncInterval.Duration)
+	defer cancel()
+
+	watcher, err := client.Watch(ctx, d.config.Namespace, &corev1.Endpoints{},
+		k8s.ResourceVersion(endpoints.GetMetadata().GetResourceVersion()))
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for {
+		e := &corev1.Endpoints{}
+		eventType, err := watcher.Next(e)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil
+			}
+			return err
+		}
+		d.handleEndpoints(eventType, scraped, e)
+	}
+}
+
+// handleEndpoints replaces the targets of the service of the endpoints, the
+// addresses are added and removed as the pods behind the service change.
+func (d *Discovery) handleEndpoints(eventType string, services map[string]*corev1.Service, e *corev1.Endpoints) {
+	d.removeServiceTargets(e.GetMetadata().GetNamespace(), e.GetMetadata().GetName())
+	if eventType == k8s.EventDeleted {
+		return
+	}
+	for _, t := range d.endpointsTargets(services, e) {
+		d.addTarget(t)
+	}
+}
+
+// endpointsTargets returns a target for each ready address of the
+// endpoints, or nil if their service is not annotated.
+func (d *Discovery) endpointsTargets(services map[string]*corev1.Service, e *corev1.Endpoints) []*Target {
+	service, ok := services[serviceKey(e.GetMetadata().GetNamespace(), e.GetMetadata().GetName())]
+	if !ok {
+		return nil
+	}
+
+	var targets []*Target
+	for _, subset := range e.GetSubsets() {
+		port := d.config.Port
+		if ports := subset.GetPorts(); port == "" && len(ports) > 0 {
+			port = strconv.Itoa(int(ports[0].GetPort()))
+		}
+
+		for _, address := range subset.GetAddresses() {
+			if address.GetIp() == "" {
+				continue
+			}
+			u := d.buildURL(service.GetMetadata(), address.GetIp(), port)
+			tags := d.tags(service.GetMetadata(), "service_name")
+			if ref := address.GetTargetRef(); ref.GetKind() == "Pod" {
+				tags["pod_name"] = ref.GetName()
+			}
+			targets = append(targets, &Target{
+				URL:     u,
+				Address: u.Hostname(),
+				Tags:    tags,
+				Timeout: d.timeout(service.GetMetadata()),
+			})
+		}
+	}
+	return targets
+}
+
+// removeServiceTargets removes the targets of the service.
+func (d *Discovery) removeServiceTargets(namespace, name string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for key, t := range d.targets {
+		if t.Tags["service_name"] == name && t.Tags["namespace"] == namespace {
+			delete(d.targets, key)
+		}
+	}
+}
+
+func serviceKey(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,87 @@
+package k8sdiscovery
+
+import (
+	"testing"
+
+	"github.com/ericchiang/k8s"
+	v1 "github.com/ericchiang/k8s/apis/core/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointsTargets(t *testing.T) {
+	d := New(Config{Role: RoleEndpoints}, testutil.Logger{})
+
+	s := service()
+	s.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	services := map[string]*v1.Service{"default/myService": s}
+
+	targets := d.endpointsTargets(services, endpoints("10.1.0.1", "10.1.0.2"))
+	require.Equal(t, 2, len(targets))
+	assert.Equal(t, "http://10.1.0.1:9100", targets[0].URL.String())
+	assert.Equal(t, "10.1.0.1", targets[0].Address)
+	assert.Equal(t, "myService", targets[0].Tags["service_name"])
+	assert.Equal(t, "default", targets[0].Tags["namespace"])
+	assert.Equal(t, "pod-10.1.0.1", targets[0].Tags["pod_name"])
+	assert.Equal(t, "http://10.1.0.2:9100", targets[1].URL.String())
+}
+
+func TestEndpointsTargetsAnnotations(t *testing.T) {
+	d := New(Config{Role: RoleEndpoints}, testutil.Logger{})
+
+	s := service()
+	s.Metadata.Annotations = map[string]string{
+		"prometheus.io/scrape": "true",
+		"prometheus.io/port":   "8443",
+		"prometheus.io/path":   "/metrics",
+	}
+	services := map[string]*v1.Service{"default/myService": s}
+
+	targets := d.endpointsTargets(services, endpoints("10.1.0.1"))
+	require.Equal(t, 1, len(targets))
+	assert.Equal(t, "http://10.1.0.1:8443/metrics", targets[0].URL.String())
+}
+
+func TestEndpointsTargetsNoService(t *testing.T) {
+	d := New(Config{Role: RoleEndpoints}, testutil.Logger{})
+	assert.Nil(t, d.endpointsTargets(map[string]*v1.Service{}, endpoints("10.1.0.1")))
+}
+
+func TestHandleEndpointsEvents(t *testing.T) {
+	d := New(Config{Role: RoleEndpoints}, testutil.Logger{})
+
+	s := service()
+	s.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	services := map[string]*v1.Service{"default/myService": s}
+
+	d.handleEndpoints(k8s.EventAdded, services, endpoints("10.1.0.1", "10.1.0.2"))
+	assert.Equal(t, 2, len(d.Targets()))
+
+	d.handleEndpoints(k8s.EventModified, services, endpoints("10.1.0.2", "10.1.0.3"))
+	targets := d.Targets()
+	require.Equal(t, 2, len(targets))
+	assert.Equal(t, "http://10.1.0.2:9100", targets[0].URL.String())
+	assert.Equal(t, "http://10.1.0.3:9100", targets[1].URL.String())
+
+	d.handleEndpoints(k8s.EventDeleted, services, endpoints("10.1.0.2", "10.1.0.3"))
+	assert.Equal(t, 0, len(d.Targets()))
+}
+
+func endpoints(ips ...string) *v1.Endpoints {
+	port := int32(9100)
+	subset := &v1.EndpointSubset{Ports: []*v1.EndpointPort{{Port: &port}}}
+	for _, ip := range ips {
+		subset.Addresses = append(subset.Addresses, &v1.EndpointAddress{
+			Ip:        str(ip),
+			TargetRef: &v1.ObjectReference{Kind: str("Pod"), Name: str("pod-" + ip)},
+		})
+	}
+
+	e := &v1.Endpoints{Metadata: &metav1.ObjectMeta{}}
+	e.Metadata.Name = str("myService")
+	e.Metadata.Namespace = str("default")
+	e.Subsets = []*v1.EndpointSubset{subset}
+	return e
+}
@@ -0,0 +1,604 @@
+// Package k8sdiscovery discovers the targets of the inputs from the
+// annotations of Kubernetes pods, services or the endpoints of services.
+// The annotated objects are cached and the cache is kept up to date by a
+// watch of the API server, with a periodic full resync so missed events
+// can't leave stale targets behind.
+package k8sdiscovery
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericchiang/k8s"
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/ghodss/yaml"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+const (
+	defaultAnnotationPrefix = "prometheus.io"
+	defaultScheme           = "http"
+	defaultResyncInterval   = 5 * time.Minute
+)
+
+const (
+	// RolePod discovers the pods, at their IP.
+	RolePod = "pod"
+	// RoleService discovers the services, at their cluster IP.
+	RoleService = "service"
+	// RoleEndpoints discovers the ready addresses of the endpoints of the
+	// services.
+	RoleEndpoints = "endpoints"
+)
+
+// Config is the configuration of the discovery, inputs have it as their
+// "urls_from_kubernetes" table.
+type Config struct {
+	// Role is the kind of the discovered objects, "pod", "service" or
+	// "endpoints".
+	Role string `toml:"role"`
+
+	// Prefix of the annotations: the objects with the "<prefix>/scrape"
+	// annotation set to "true" are targets.
+	AnnotationPrefix string `toml:"annotation_prefix"`
+
+	// Defaults of the target URL when the objects don't have the
+	// "<prefix>/scheme", "<prefix>/port" and "<prefix>/path" annotations.
+	// Services and endpoints default to their first port.  The
+	// "<prefix>/param_<name>" annotations are added as query parameters.
+	Scheme string `toml:"scheme"`
+	Port   string `toml:"port"`
+	Path   string `toml:"path"`
+
+	// Namespace to discover the pods in, all namespaces when empty.
+	Namespace string `toml:"namespace"`
+
+	// Node to discover the pods on, all nodes when empty.  Set to the node
+	// of the agent when running as a DaemonSet.
+	NodeName string `toml:"node_name"`
+
+	// Label and field selectors of the pods, such as "app=metrics" and
+	// "status.phase=Running", only the matching pods are listed and watched.
+	LabelSelector string `toml:"label_selector"`
+	FieldSelector string `toml:"field_selector"`
+
+	// Location of the kubernetes config file, used when not running in a
+	// pod.
+	KubeConfig string `toml:"kube_config"`
+
+	// Scrape the pods through the proxy of the API server with the
+	// credentials of the kubernetes config, when the pod IPs are not
+	// routable from the agent.
+	APIServerProxy bool `toml:"api_server_proxy"`
+
+	// Add the node of the pods and the kind and name of their controller as
+	// the node_name, controller_kind and controller_name tags.
+	PodMetadataTags bool `toml:"pod_metadata_tags"`
+
+	// Annotations and labels of the objects not added to the target tags.
+	ExcludeAnnotations []string `toml:"exclude_annotations"`
+	ExcludeLabels      []string `toml:"exclude_labels"`
+
+	// Interval of the full resync of the cached objects.
+	ResyncInterval internal.Duration `toml:"resync_interval"`
+}
+
+// Target is a discovered pod or service.
+type Target struct {
+	// URL built from the IP and annotations.
+	URL *url.URL
+	// Address is the pod IP, the service cluster IP or the endpoint IP.
+	Address string
+	// Tags are the annotations and labels of the object, with its name and
+	// namespace.
+	Tags map[string]string
+	// Transport sends the requests of the targets scraped through the proxy
+	// of the API server with the credentials of the API server, nil for the
+	// other targets.
+	Transport http.RoundTripper
+	// Timeout of the scrape from the "<prefix>/timeout" annotation, zero
+	// when not annotated.
+	Timeout time.Duration
+}
+
+// Accumulator returns an accumulator adding the tags of the target to the
+// metrics added with AddFields, the tags of the metrics take precedence.
+func (t *Target) Accumulator(acc telegraf.Accumulator) telegraf.Accumulator {
+	return &targetAccumulator{Accumulator: acc, tags: t.Tags}
+}
+
+type targetAccumulator struct {
+	telegraf.Accumulator
+	tags map[string]string
+}
+
+func (a *targetAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	merged := make(map[string]string, len(a.tags)+len(tags))
+	for k, v := range a.tags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	a.Accumulator.AddFields(measurement, fields, merged, t...)
+}
+
+// Discovery keeps the targets of the annotated pods or services.
+type Discovery struct {
+	config Config
+	log    telegraf.Logger
+
+	lock    sync.Mutex
+	targets map[string]*Target
+	proxy   *apiProxy
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New returns a discovery with the configuration, the defaults are set for
+// the empty settings.
+func New(config Config, log telegraf.Logger) *Discovery {
+	if config.Role == "" {
+		config.Role = RolePod
+	}
+	if config.AnnotationPrefix == "" {
+		config.AnnotationPrefix = defaultAnnotationPrefix
+	}
+	if config.Scheme == "" {
+		config.Scheme = defaultScheme
+	}
+	if config.ResyncInterval.Duration == 0 {
+		config.ResyncInterval.Duration = defaultResyncInterval
+	}
+	return &Discovery{
+		config:  config,
+		log:     log,
+		targets: make(map[string]*Target),
+	}
+}
+
+// Start connects to the API server and starts watching the objects.
+func (d *Discovery) Start() error {
+	var sync func(context.Context, *k8s.Client) error
+	switch d.config.Role {
+	case RolePod:
+		sync = d.syncPods
+	case RoleService:
+		sync = d.syncServices
+	case RoleEndpoints:
+		sync = d.syncEndpoints
+	default:
+		return fmt.Errorf("unknown role %q", d.config.Role)
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+	if d.config.APIServerProxy {
+		if d.proxy, err = newAPIProxy(client); err != nil {
+			return err
+		}
+	}
+
+	var ctx context.Context
+	ctx, d.cancel = context.WithCancel(context.Background())
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			err := sync(ctx, client)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				d.log.Errorf("Unable to watch %ss: %v", d.config.Role, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops watching the objects.
+func (d *Discovery) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+}
+
+// Targets returns the current targets, sorted by URL.
+func (d *Discovery) Targets() []*Target {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	targets := make([]*Target, 0, len(d.targets))
+	for _, t := range d.targets {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].URL.String() < targets[j].URL.String()
+	})
+	return targets
+}
+
+func (d *Discovery) client() (*k8s.Client, error) {
+	client, err := k8s.NewInClusterClient()
+	if err == nil {
+		return client, nil
+	}
+
+	configLocation := d.config.KubeConfig
+	if configLocation == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current user: %v", err)
+		}
+		configLocation = filepath.Join(u.HomeDir, ".kube/config")
+	}
+	return loadClient(configLocation)
+}
+
+// loadClient parses a kubeconfig from a file and returns a Kubernetes
+// client. It does not support extensions or client auth providers.
+func loadClient(kubeconfigPath string) (*k8s.Client, error) {
+	data, err := ioutil.ReadFile(kubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading '%s': %v", kubeconfigPath, err)
+	}
+
+	// Unmarshal YAML into a Kubernetes config object.
+	var config k8s.Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return k8s.NewClient(&config)
+}
+
+// replaceTargets replaces the cached targets, on a resync.  The targets
+// missing from the resync are the ones of missed delete events.
+func (d *Discovery) replaceTargets(targets map[string]*Target) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for u := range d.targets {
+		if _, ok := targets[u]; !ok {
+			d.log.Debugf("Will stop scraping for %q, not found on resync", u)
+		}
+	}
+	d.targets = targets
+}
+
+func (d *Discovery) addTarget(t *Target) {
+	d.log.Debugf("Will scrape metrics from %q", t.URL.String())
+
+	d.lock.Lock()
+	d.targets[t.URL.String()] = t
+	d.lock.Unlock()
+}
+
+func (d *Discovery) removeTarget(u *url.URL) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, ok := d.targets[u.String()]; ok {
+		delete(d.targets, u.String())
+		d.log.Debugf("Will stop scraping for %q", u.String())
+	}
+}
+
+// syncPods lists the pods to replace the cached targets, then applies the
+// events of a watch until the resync interval elapsed.
+func (d *Discovery) syncPods(ctx context.Context, client *k8s.Client) error {
+	var pods corev1.PodList
+	if err := client.List(ctx, d.config.Namespace, &pods, d.selectors()...); err != nil {
+		return err
+	}
+
+	targets := make(map[string]*Target)
+	for _, pod := range pods.GetItems() {
+		if !d.scrape(pod) {
+			continue
+		}
+		if t := d.target(pod); t != nil {
+			targets[t.URL.String()] = t
+		}
+	}
+	d.replaceTargets(targets)
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.ResyncInterval.Duration)
+	defer cancel()
+
+	options := append(d.selectors(),
+		k8s.ResourceVersion(pods.GetMetadata().GetResourceVersion()))
+	watcher, err := client.Watch(ctx, d.config.Namespace, &corev1.Pod{}, options...)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for {
+		pod := &corev1.Pod{}
+		// An error here means we need to reconnect the watcher.
+		eventType, err := watcher.Next(pod)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil
+			}
+			return err
+		}
+		d.handle(eventType, pod)
+	}
+}
+
+// selectors returns the options selecting the pods by label and field, the
+// node being added to the field selector.
+func (d *Discovery) selectors() []k8s.Option {
+	var options []k8s.Option
+	if d.config.LabelSelector != "" {
+		options = append(options, k8s.QueryParam("labelSelector", d.config.LabelSelector))
+	}
+	fieldSelector := d.config.FieldSelector
+	if d.config.NodeName != "" {
+		if fieldSelector != "" {
+			fieldSelector += ","
+		}
+		fieldSelector += "spec.nodeName=" + d.config.NodeName
+	}
+	if fieldSelector != "" {
+		options = append(options, k8s.QueryParam("fieldSelector", fieldSelector))
+	}
+	return options
+}
+
+// An edge case exists if a pod goes offline at the same time a new pod is created
+// (without the scrape annotations). K8s may re-assign the old pod ip to the non-scrape
+// pod, causing errors in the logs. This is only true if the pod going offline is not
+// directed to do so by K8s.
+func (d *Discovery) handle(eventType string, pod *corev1.Pod) {
+	if eventType == k8s.EventDeleted {
+		d.unregisterPod(pod)
+		return
+	}
+
+	// If the pod is not "ready", there will be no ip associated with it.
+	if !d.scrape(pod) {
+		return
+	}
+
+	switch eventType {
+	case k8s.EventAdded:
+		d.registerPod(pod)
+	case k8s.EventModified:
+		// To avoid multiple actions for each event, unregister on the first event
+		// in the delete sequence, when the containers are still "ready".
+		if pod.GetMetadata().GetDeletionTimestamp() != nil {
+			d.unregisterPod(pod)
+		} else {
+			d.registerPod(pod)
+		}
+	}
+}
+
+// scrape returns whether the pod is a ready target, scheduled on the node if
+// set.
+func (d *Discovery) scrape(pod *corev1.Pod) bool {
+	if d.config.NodeName != "" && pod.GetSpec().GetNodeName() != d.config.NodeName {
+		return false
+	}
+	return d.annotation(pod.GetMetadata(), "scrape") == "true" &&
+		podReady(pod.GetStatus().GetContainerStatuses())
+}
+
+func (d *Discovery) annotation(meta *metav1.ObjectMeta, name string) string {
+	return meta.GetAnnotations()[d.config.AnnotationPrefix+"/"+name]
+}
+
+func podReady(statuss []*corev1.ContainerStatus) bool {
+	if len(statuss) == 0 {
+		return false
+	}
+	for _, cs := range statuss {
+		if !cs.GetReady() {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *Discovery) registerPod(pod *corev1.Pod) {
+	if t := d.target(pod); t != nil {
+		d.addTarget(t)
+	}
+}
+
+func (d *Discovery) unregisterPod(pod *corev1.Pod) {
+	u := d.scrapeURL(pod)
+	if u == nil {
+		return
+	}
+
+	d.log.Debugf("Registered a delete request for %q in namespace %q",
+		pod.GetMetadata().GetName(), pod.GetMetadata().GetNamespace())
+
+	d.removeTarget(u)
+}
+
+// target returns the target of the pod, or nil if the pod has no IP yet.
+func (d *Discovery) target(pod *corev1.Pod) *Target {
+	u := d.scrapeURL(pod)
+	if u == nil {
+		return nil
+	}
+
+	t := &Target{
+		URL:     u,
+		Address: pod.GetStatus().GetPodIP(),
+		Tags:    d.tags(pod.GetMetadata(), "pod_name"),
+		Timeout: d.timeout(pod.GetMetadata()),
+	}
+	if d.config.PodMetadataTags {
+		addPodMetadataTags(t.Tags, pod)
+	}
+	if d.proxy != nil {
+		t.Transport = d.proxy.transport
+	}
+	return t
+}
+
+// tags returns the tags of the object, its annotations and labels that are
+// not excluded, its name as the name tag and its namespace.
+func (d *Discovery) tags(meta *metav1.ObjectMeta, name string) map[string]string {
+	tags := map[string]string{}
+
+	// add annotations that are not excluded as metrics tags
+	copyKeyValues(tags, meta.GetAnnotations(), d.config.ExcludeAnnotations)
+
+	// add default annotations
+	tags[name] = meta.GetName()
+	tags["namespace"] = meta.GetNamespace()
+
+	// add labels that are not excluded as metrics tags
+	copyKeyValues(tags, meta.GetLabels(), d.config.ExcludeLabels)
+
+	return tags
+}
+
+// addPodMetadataTags adds the node of the pod and the kind and name of its
+// controller, such as its ReplicaSet or DaemonSet, when set.
+func addPodMetadataTags(tags map[string]string, pod *corev1.Pod) {
+	if node := pod.GetSpec().GetNodeName(); node != "" {
+		tags["node_name"] = node
+	}
+	for _, owner := range pod.GetMetadata().GetOwnerReferences() {
+		if owner.GetController() {
+			tags["controller_kind"] = owner.GetKind()
+			tags["controller_name"] = owner.GetName()
+			break
+		}
+	}
+}
+
+// scrapeURL returns the URL of the pod built from its annotations, or nil
+// if the pod has no IP yet.  The URL is the one of the proxy of the API
+// server when scraping through it.
+func (d *Discovery) scrapeURL(pod *corev1.Pod) *url.URL {
+	ip := pod.GetStatus().GetPodIP()
+	if ip == "" {
+		// return as if scrape was disabled, we will be notified again once the pod
+		// has an IP
+		return nil
+	}
+	u := d.buildURL(pod.GetMetadata(), ip, d.config.Port)
+	if d.proxy != nil {
+		return d.proxy.proxyURL(pod.GetMetadata(), u)
+	}
+	return u
+}
+
+// timeout returns the duration of the timeout annotation of the object, or
+// zero when not annotated.
+func (d *Discovery) timeout(meta *metav1.ObjectMeta) time.Duration {
+	annotation := d.annotation(meta, "timeout")
+	if annotation == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(annotation)
+	if err != nil || timeout <= 0 {
+		d.log.Warnf("Invalid timeout %q of %q in namespace %q, ignoring it",
+			annotation, meta.GetName(), meta.GetNamespace())
+		return 0
+	}
+	return timeout
+}
+
+// buildURL returns the URL of the IP built from the annotations of the
+// object, with the port when the object has no port annotation.
+func (d *Discovery) buildURL(meta *metav1.ObjectMeta, ip, defaultPort string) *url.URL {
+	scheme := strings.ToLower(strings.TrimSpace(d.annotation(meta, "scheme")))
+	path := d.annotation(meta, "path")
+	port := d.annotation(meta, "port")
+
+	switch scheme {
+	case "http", "https":
+	case "":
+		scheme = d.config.Scheme
+	default:
+		d.log.Warnf("Unsupported scheme %q of %q in namespace %q, using %q",
+			scheme, meta.GetName(), meta.GetNamespace(), d.config.Scheme)
+		scheme = d.config.Scheme
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	if path == "" {
+		path = d.config.Path
+	}
+
+	host := ip
+	if port != "" {
+		host = net.JoinHostPort(ip, port)
+	} else if net.ParseIP(ip).To4() == nil {
+		host = "[" + ip + "]"
+	}
+
+	return &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     path,
+		RawQuery: d.params(meta).Encode(),
+	}
+}
+
+// params returns the query parameters of the URL of the object from its
+// "<prefix>/param_<name>" annotations, such as the module of the probes of
+// the blackbox exporter.
+func (d *Discovery) params(meta *metav1.ObjectMeta) url.Values {
+	prefix := d.config.AnnotationPrefix + "/param_"
+	params := url.Values{}
+	for k, v := range meta.GetAnnotations() {
+		if strings.HasPrefix(k, prefix) && len(k) > len(prefix) {
+			params.Set(k[len(prefix):], v)
+		}
+	}
+	return params
+}
+
+func copyKeyValues(target map[string]string, source map[string]string, excluded []string) {
+	for k, v := range source {
+		if !contains(excluded, k) {
+			target[k] = v
+		}
+	}
+}
+
+func contains(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,410 @@
+package k8sdiscovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ericchiang/k8s"
+	v1 "github.com/ericchiang/k8s/apis/core/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrapeURLNoAnnotations(t *testing.T) {
+	p := &v1.Pod{Metadata: &metav1.ObjectMeta{}}
+	p.GetMetadata().Annotations = map[string]string{}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Nil(t, url)
+}
+
+func TestScrapeURLAnnotationsNoScrape(t *testing.T) {
+	p := &v1.Pod{Metadata: &metav1.ObjectMeta{}}
+	p.Metadata.Name = str("myPod")
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "false"}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Nil(t, url)
+}
+
+func TestScrapeURLAnnotations(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Equal(t, "http://127.0.0.1:9102/metrics", url.String())
+}
+
+func TestScrapeURLAnnotationsCustomPort(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "9000"}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Equal(t, "http://127.0.0.1:9000/metrics", url.String())
+}
+
+func TestScrapeURLAnnotationsCustomPath(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/path": "mymetrics"}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Equal(t, "http://127.0.0.1:9102/mymetrics", url.String())
+}
+
+func TestScrapeURLAnnotationsCustomPathWithSep(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/path": "/mymetrics"}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Equal(t, "http://127.0.0.1:9102/mymetrics", url.String())
+}
+
+func TestScrapeURLAnnotationsParams(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{
+		"prometheus.io/scrape":       "true",
+		"prometheus.io/path":         "/probe",
+		"prometheus.io/param_module": "http_2xx",
+		"prometheus.io/param_target": "http://example.org/health",
+		"prometheus.io/param_":       "ignored",
+	}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Equal(t, "http://127.0.0.1:9102/probe?module=http_2xx&target=http%3A%2F%2Fexample.org%2Fhealth", url.String())
+}
+
+func TestScrapeURLAnnotationsScheme(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/scheme": "HTTPS"}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Equal(t, "https://127.0.0.1:9102/metrics", url.String())
+}
+
+func TestScrapeURLAnnotationsUnsupportedScheme(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/scheme": "ftp"}
+	url := discovery(Config{}).scrapeURL(p)
+	assert.Equal(t, "http://127.0.0.1:9102/metrics", url.String())
+}
+
+func TestTargetTimeout(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/timeout": "500ms"}
+	assert.Equal(t, 500*time.Millisecond, discovery(Config{}).target(p).Timeout)
+
+	p.Metadata.Annotations["prometheus.io/timeout"] = "soon"
+	assert.Equal(t, time.Duration(0), discovery(Config{}).target(p).Timeout)
+
+	delete(p.Metadata.Annotations, "prometheus.io/timeout")
+	assert.Equal(t, time.Duration(0), discovery(Config{}).target(p).Timeout)
+}
+
+func TestSelectors(t *testing.T) {
+	assert.Empty(t, discovery(Config{}).selectors())
+	assert.Len(t, discovery(Config{LabelSelector: "app=metrics"}).selectors(), 1)
+	assert.Len(t, discovery(Config{
+		LabelSelector: "app=metrics",
+		FieldSelector: "status.phase=Running",
+	}).selectors(), 2)
+	assert.Len(t, discovery(Config{
+		FieldSelector: "status.phase=Running",
+		NodeName:      "node-1",
+	}).selectors(), 1)
+}
+
+func TestAddPod(t *testing.T) {
+	d := discovery(Config{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.registerPod(p)
+	assert.Equal(t, 1, len(d.targets))
+}
+
+func TestAddMultipleDuplicatePods(t *testing.T) {
+	d := discovery(Config{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.registerPod(p)
+	p.Metadata.Name = str("Pod2")
+	d.registerPod(p)
+	assert.Equal(t, 1, len(d.targets))
+}
+
+func TestAddMultiplePods(t *testing.T) {
+	d := discovery(Config{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.registerPod(p)
+	p.Metadata.Name = str("Pod2")
+	p.Status.PodIP = str("127.0.0.2")
+	d.registerPod(p)
+	assert.Equal(t, 2, len(d.targets))
+}
+
+func TestDeletePods(t *testing.T) {
+	d := discovery(Config{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.registerPod(p)
+	d.unregisterPod(p)
+	assert.Equal(t, 0, len(d.targets))
+}
+
+func TestAddPodAddsAllAnnotationsToTags(t *testing.T) {
+	d := discovery(Config{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "test.io/annotation": "yes"}
+	d.registerPod(p)
+	assert.Equal(t, 1, len(d.targets))
+
+	_, ok := d.targets["http://127.0.0.1:9102/metrics"].Tags["test.io/annotation"]
+	assert.True(t, ok, "Annotation 'test.io/annotation' must be in the tags")
+}
+
+func TestAddPodAddsAllAnnotationsNotExcludedToTags(t *testing.T) {
+	d := discovery(Config{ExcludeAnnotations: []string{"test.io/annotation2"}})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true",
+		"test.io/annotation":  "yes",
+		"test.io/annotation2": "no"}
+	d.registerPod(p)
+
+	assert.Equal(t, 1, len(d.targets))
+
+	_, ok := d.targets["http://127.0.0.1:9102/metrics"].Tags["test.io/annotation"]
+	assert.True(t, ok, "Annotation 'test.io/annotation' must be in the tags")
+	_, ok = d.targets["http://127.0.0.1:9102/metrics"].Tags["test.io/annotation2"]
+	assert.False(t, ok, "Annotation 'test.io/annotation2' must NOT be in the tags")
+}
+
+func TestAddPodAddsAllLabelsToTags(t *testing.T) {
+	d := discovery(Config{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	p.Metadata.Labels = map[string]string{"some-label-1": "value1"}
+	d.registerPod(p)
+
+	assert.Equal(t, 1, len(d.targets))
+
+	_, ok := d.targets["http://127.0.0.1:9102/metrics"].Tags["some-label-1"]
+	assert.True(t, ok, "Annotation 'some-label-1' must be in the tags")
+}
+
+func TestAddPodAddsAllLabelsNotExcludedToTags(t *testing.T) {
+	d := discovery(Config{ExcludeLabels: []string{"some-label-2"}})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	p.Metadata.Labels = map[string]string{"some-label-1": "value1"}
+	d.registerPod(p)
+
+	assert.Equal(t, 1, len(d.targets))
+
+	_, ok := d.targets["http://127.0.0.1:9102/metrics"].Tags["some-label-1"]
+	assert.True(t, ok, "Annotation 'some-label-1' must be in the tags")
+	_, ok = d.targets["http://127.0.0.1:9102/metrics"].Tags["some-label-2"]
+	assert.False(t, ok, "Annotation 'some-label-2' must NOT be in the tags")
+}
+
+func TestAddPodMetadataTags(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	p.Metadata.OwnerReferences = []*metav1.OwnerReference{
+		{Kind: str("Node"), Name: str("node-1")},
+		{Kind: str("ReplicaSet"), Name: str("web-5d4f8"), Controller: boolean(true)},
+	}
+	p.Spec = &v1.PodSpec{NodeName: str("node-1")}
+
+	d := discovery(Config{})
+	d.registerPod(p)
+	assert.Equal(t, map[string]string{
+		"prometheus.io/scrape": "true",
+		"pod_name":             "myPod",
+		"namespace":            "default",
+	}, d.targets["http://127.0.0.1:9102/metrics"].Tags)
+
+	d = discovery(Config{PodMetadataTags: true})
+	d.registerPod(p)
+	assert.Equal(t, map[string]string{
+		"prometheus.io/scrape": "true",
+		"pod_name":             "myPod",
+		"namespace":            "default",
+		"node_name":            "node-1",
+		"controller_kind":      "ReplicaSet",
+		"controller_name":      "web-5d4f8",
+	}, d.targets["http://127.0.0.1:9102/metrics"].Tags)
+}
+
+func TestReplaceTargets(t *testing.T) {
+	d := discovery(Config{})
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.registerPod(p)
+
+	// The delete event of the pod was missed, the resync removes it.
+	p = pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	p.Status.PodIP = str("127.0.0.2")
+	t2 := d.target(p)
+	d.replaceTargets(map[string]*Target{t2.URL.String(): t2})
+
+	targets := d.Targets()
+	assert.Equal(t, 1, len(targets))
+	assert.Equal(t, "http://127.0.0.2:9102/metrics", targets[0].URL.String())
+}
+
+func TestHandleEvents(t *testing.T) {
+	d := discovery(Config{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.handle(k8s.EventAdded, p)
+	assert.Equal(t, 1, len(d.Targets()))
+
+	// Pods not ready are not registered
+	p2 := pod()
+	p2.Status.PodIP = str("127.0.0.2")
+	p2.Status.ContainerStatuses[0].Ready = boolean(false)
+	p2.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.handle(k8s.EventAdded, p2)
+	assert.Equal(t, 1, len(d.Targets()))
+
+	p.Metadata.DeletionTimestamp = &metav1.Time{}
+	d.handle(k8s.EventModified, p)
+	assert.Equal(t, 0, len(d.Targets()))
+
+	d.handle(k8s.EventAdded, pod())
+	assert.Equal(t, 0, len(d.Targets()), "pods without the scrape annotation must not be registered")
+}
+
+func TestHandleDeleted(t *testing.T) {
+	d := discovery(Config{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.handle(k8s.EventAdded, p)
+	assert.Equal(t, 1, len(d.Targets()))
+
+	d.handle(k8s.EventDeleted, p)
+	assert.Equal(t, 0, len(d.Targets()))
+}
+
+func TestAnnotationPrefix(t *testing.T) {
+	d := New(Config{AnnotationPrefix: "telegraf.influxdata.com", Scheme: "https"}, testutil.Logger{})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{
+		"prometheus.io/scrape":         "true",
+		"telegraf.influxdata.com/path": "/health",
+	}
+	assert.False(t, d.scrape(p))
+
+	p.Metadata.Annotations["telegraf.influxdata.com/scrape"] = "true"
+	assert.True(t, d.scrape(p))
+	assert.Equal(t, "https://127.0.0.1/health", d.scrapeURL(p).String())
+}
+
+func TestNodeName(t *testing.T) {
+	d := discovery(Config{NodeName: "node-1"})
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.handle(k8s.EventAdded, p)
+	assert.Equal(t, 0, len(d.Targets()), "pods without a node must not be registered")
+
+	p.Spec = &v1.PodSpec{NodeName: str("node-2")}
+	d.handle(k8s.EventAdded, p)
+	assert.Equal(t, 0, len(d.Targets()), "pods of other nodes must not be registered")
+
+	p.Spec.NodeName = str("node-1")
+	d.handle(k8s.EventAdded, p)
+	assert.Equal(t, 1, len(d.Targets()))
+}
+
+func TestScrapeURLIPv6WithoutPort(t *testing.T) {
+	d := New(Config{}, testutil.Logger{})
+
+	p := pod()
+	p.Status.PodIP = str("fd00::1")
+	assert.Equal(t, "http://[fd00::1]", d.scrapeURL(p).String())
+	assert.Equal(t, "fd00::1", d.target(p).Address)
+}
+
+func discovery(config Config) *Discovery {
+	config.Port = "9102"
+	config.Path = "/metrics"
+	return New(config, testutil.Logger{})
+}
+
+func pod() *v1.Pod {
+	p := &v1.Pod{Metadata: &metav1.ObjectMeta{}, Status: &v1.PodStatus{}}
+	p.Status.PodIP = str("127.0.0.1")
+	p.Status.ContainerStatuses = []*v1.ContainerStatus{{Ready: boolean(true)}}
+	p.Metadata.Name = str("myPod")
+	p.Metadata.Namespace = str("default")
+	return p
+}
+
+func str(x string) *string {
+	return &x
+}
+
+func boolean(x bool) *bool {
+	return &x
+}
+
+func TestTargetAccumulator(t *testing.T) {
+	target := &Target{Tags: map[string]string{"pod_name": "myPod", "url": "pod"}}
+
+	var acc testutil.Accumulator
+	target.Accumulator(&acc).AddFields("ping",
+		map[string]interface{}{"result_code": 0},
+		map[string]string{"url": "127.0.0.1"})
+
+	acc.AssertContainsTaggedFields(t, "ping",
+		map[string]interface{}{"result_code": 0},
+		map[string]string{"pod_name": "myPod", "url": "127.0.0.1"})
+}
+
+func TestAPIServerProxy(t *testing.T) {
+	var gotHeader string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Authorization")
+		assert.Equal(t, "/k8s/api/v1/namespaces/default/pods/https:myPod:9102/proxy/metrics", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	d := discovery(Config{APIServerProxy: true})
+	var err error
+	d.proxy, err = newAPIProxy(&k8s.Client{
+		Endpoint: ts.URL + "/k8s",
+		SetHeaders: func(h http.Header) error {
+			h.Set("Authorization", "Bearer token")
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/scheme": "https"}
+	target := d.target(p)
+	assert.Equal(t, ts.URL+"/k8s/api/v1/namespaces/default/pods/https:myPod:9102/proxy/metrics", target.URL.String())
+	assert.Equal(t, "127.0.0.1", target.Address)
+
+	client := &http.Client{Transport: target.Transport}
+	req, err := http.NewRequest("GET", target.URL.String(), nil)
+	assert.NoError(t, err)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "Bearer token", gotHeader)
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	d.handle(k8s.EventAdded, p)
+	assert.Len(t, d.Targets(), 1)
+	d.handle(k8s.EventDeleted, p)
+	assert.Empty(t, d.Targets())
+}
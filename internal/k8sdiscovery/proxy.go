@@ -0,0 +1,82 @@
+package k8sdiscovery
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/ericchiang/k8s"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+)
+
+// apiProxy scrapes the pods through the proxy of the API server, for agents
+// outside of the cluster where the pod IPs are not routable.
+type apiProxy struct {
+	endpoint  *url.URL
+	transport http.RoundTripper
+}
+
+// newAPIProxy returns the proxy of the API server of the client, the
+// requests being sent with the credentials of the client.
+func newAPIProxy(client *k8s.Client) (*apiProxy, error) {
+	endpoint, err := url.Parse(client.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport
+	if client.Client != nil && client.Client.Transport != nil {
+		transport = client.Client.Transport
+	}
+	return &apiProxy{
+		endpoint: endpoint,
+		transport: &headersTransport{
+			transport:  transport,
+			setHeaders: client.SetHeaders,
+		},
+	}, nil
+}
+
+// proxyURL returns the URL of the pod through the proxy from the URL of the
+// pod, the API server proxying /api/v1/namespaces/<namespace>/pods/<pod>:<port>/proxy/<path>
+// to the pod, with an "https:" prefix before the pod name for https.
+func (p *apiProxy) proxyURL(meta *metav1.ObjectMeta, u *url.URL) *url.URL {
+	pod := meta.GetName()
+	if _, port, err := net.SplitHostPort(u.Host); err == nil {
+		pod += ":" + port
+	}
+	if u.Scheme == "https" {
+		pod = "https:" + pod
+	}
+
+	proxied := *p.endpoint
+	proxied.Path = path.Join(p.endpoint.Path, "/api/v1/namespaces", meta.GetNamespace(), "pods", pod, "proxy", u.Path)
+	proxied.RawQuery = u.RawQuery
+	return &proxied
+}
+
+// headersTransport sets the headers of the client, such as the bearer token,
+// on the requests.
+type headersTransport struct {
+	transport  http.RoundTripper
+	setHeaders func(h http.Header) error
+}
+
+func (t *headersTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.setHeaders == nil {
+		return t.transport.RoundTrip(req)
+	}
+
+	// The request must not be modified by the transport.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	if err := t.setHeaders(r.Header); err != nil {
+		return nil, err
+	}
+	return t.transport.RoundTrip(r)
+}
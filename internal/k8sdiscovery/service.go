@@ -0,0 +1,103 @@
+package k8sdiscovery
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/ericchiang/k8s"
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+// syncServices lists the services to replace the cached targets, then
+// applies the events of a watch until the resync interval elapsed.
+func (d *Discovery) syncServices(ctx context.Context, client *k8s.Client) error {
+	var services corev1.ServiceList
+	if err := client.List(ctx, d.config.Namespace, &services); err != nil {
+		return err
+	}
+
+	targets := make(map[string]*Target)
+	for _, service := range services.GetItems() {
+		if t := d.serviceTarget(service); t != nil {
+			targets[t.URL.String()] = t
+		}
+	}
+	d.replaceTargets(targets)
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.ResyncInterval.Duration)
+	defer cancel()
+
+	watcher, err := client.Watch(ctx, d.config.Namespace, &corev1.Service{},
+		k8s.ResourceVersion(services.GetMetadata().GetResourceVersion()))
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for {
+		service := &corev1.Service{}
+		eventType, err := watcher.Next(service)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil
+			}
+			return err
+		}
+		d.handleService(eventType, service)
+	}
+}
+
+func (d *Discovery) handleService(eventType string, service *corev1.Service) {
+	switch eventType {
+	case k8s.EventAdded:
+		if t := d.serviceTarget(service); t != nil {
+			d.addTarget(t)
+		}
+	case k8s.EventModified:
+		// The annotations or ports may have changed, so the target of the
+		// service is replaced.
+		d.removeServiceTargets(service.GetMetadata().GetNamespace(),
+			service.GetMetadata().GetName())
+		if t := d.serviceTarget(service); t != nil {
+			d.addTarget(t)
+		}
+	case k8s.EventDeleted:
+		if u := d.serviceURL(service); u != nil {
+			d.removeTarget(u)
+		}
+	}
+}
+
+// serviceTarget returns the target of the service, or nil if the service
+// has no scrape annotation or no cluster IP.
+func (d *Discovery) serviceTarget(service *corev1.Service) *Target {
+	if d.annotation(service.GetMetadata(), "scrape") != "true" {
+		return nil
+	}
+	u := d.serviceURL(service)
+	if u == nil {
+		return nil
+	}
+	return &Target{
+		URL:     u,
+		Address: u.Hostname(),
+		Tags:    d.tags(service.GetMetadata(), "service_name"),
+		Timeout: d.timeout(service.GetMetadata()),
+	}
+}
+
+// serviceURL returns the URL of the cluster IP of the service, or nil for
+// headless services.
+func (d *Discovery) serviceURL(service *corev1.Service) *url.URL {
+	ip := service.GetSpec().GetClusterIP()
+	if ip == "" || ip == "None" {
+		return nil
+	}
+
+	port := d.config.Port
+	if ports := service.GetSpec().GetPorts(); port == "" && len(ports) > 0 {
+		port = strconv.Itoa(int(ports[0].GetPort()))
+	}
+	return d.buildURL(service.GetMetadata(), ip, port)
+}
@@ -0,0 +1,81 @@
+package k8sdiscovery
+
+import (
+	"testing"
+
+	"github.com/ericchiang/k8s"
+	v1 "github.com/ericchiang/k8s/apis/core/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceTargetDefaultsToFirstPort(t *testing.T) {
+	d := New(Config{Role: RoleService}, testutil.Logger{})
+
+	s := service()
+	s.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	target := d.serviceTarget(s)
+	require.NotNil(t, target)
+	assert.Equal(t, "http://10.0.0.1:8080", target.URL.String())
+	assert.Equal(t, "10.0.0.1", target.Address)
+	assert.Equal(t, "myService", target.Tags["service_name"])
+	assert.Equal(t, "default", target.Tags["namespace"])
+}
+
+func TestServiceTargetAnnotations(t *testing.T) {
+	d := New(Config{Role: RoleService}, testutil.Logger{})
+
+	s := service()
+	s.Metadata.Annotations = map[string]string{
+		"prometheus.io/scrape": "true",
+		"prometheus.io/scheme": "https",
+		"prometheus.io/port":   "8443",
+		"prometheus.io/path":   "/healthz",
+	}
+	target := d.serviceTarget(s)
+	require.NotNil(t, target)
+	assert.Equal(t, "https://10.0.0.1:8443/healthz", target.URL.String())
+}
+
+func TestServiceTargetNoScrape(t *testing.T) {
+	d := New(Config{Role: RoleService}, testutil.Logger{})
+	assert.Nil(t, d.serviceTarget(service()))
+}
+
+func TestServiceTargetHeadless(t *testing.T) {
+	d := New(Config{Role: RoleService}, testutil.Logger{})
+
+	s := service()
+	s.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	s.Spec.ClusterIP = str("None")
+	assert.Nil(t, d.serviceTarget(s))
+}
+
+func TestHandleServiceEvents(t *testing.T) {
+	d := New(Config{Role: RoleService}, testutil.Logger{})
+
+	s := service()
+	s.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+	d.handleService(k8s.EventAdded, s)
+	assert.Equal(t, 1, len(d.targets))
+
+	s.Metadata.Annotations["prometheus.io/port"] = "9090"
+	d.handleService(k8s.EventModified, s)
+	require.Equal(t, 1, len(d.Targets()))
+	assert.Equal(t, "http://10.0.0.1:9090", d.Targets()[0].URL.String())
+
+	d.handleService(k8s.EventDeleted, s)
+	assert.Equal(t, 0, len(d.targets))
+}
+
+func service() *v1.Service {
+	port := int32(8080)
+	s := &v1.Service{Metadata: &metav1.ObjectMeta{}, Spec: &v1.ServiceSpec{}}
+	s.Metadata.Name = str("myService")
+	s.Metadata.Namespace = str("default")
+	s.Spec.ClusterIP = str("10.0.0.1")
+	s.Spec.Ports = []*v1.ServicePort{{Port: &port}}
+	return s
+}
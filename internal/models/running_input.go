@@ -54,6 +54,9 @@ type InputConfig struct {
 	Name     string
 	Alias    string
 	Interval time.Duration
+	// Precision of the timestamps of the input, overrides the precision
+	// of the agent when set.
+	Precision time.Duration
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -27,6 +27,13 @@ type OutputConfig struct {
 	FlushJitter       *time.Duration
 	MetricBufferLimit int
 	MetricBatchSize   int
+
+	// Precision the timestamps of the metrics are truncated to, when set.
+	Precision time.Duration
+
+	// MaxMetricAge is the age after which the metrics are dropped instead
+	// of written, when set.
+	MaxMetricAge time.Duration
 }
 
 // RunningOutput contains the output configuration
@@ -41,6 +48,7 @@ type RunningOutput struct {
 	MetricBatchSize   int
 
 	MetricsFiltered selfstat.Stat
+	MetricsTooOld   selfstat.Stat
 	WriteTime       selfstat.Stat
 
 	BatchReady chan time.Time
@@ -94,6 +102,11 @@ func NewRunningOutput(
 			"metrics_filtered",
 			tags,
 		),
+		MetricsTooOld: selfstat.Register(
+			"write",
+			"metrics_too_old",
+			tags,
+		),
 		WriteTime: selfstat.RegisterTiming(
 			"write",
 			"write_time_ns",
@@ -114,6 +127,40 @@ func (ro *RunningOutput) metricFiltered(metric telegraf.Metric) {
 	metric.Drop()
 }
 
+// tooOld returns true if the metric is older than the maximum metric age.
+func (ro *RunningOutput) tooOld(metric telegraf.Metric, now time.Time) bool {
+	return ro.Config.MaxMetricAge > 0 && now.Sub(metric.Time()) > ro.Config.MaxMetricAge
+}
+
+func (ro *RunningOutput) metricTooOld(metric telegraf.Metric) {
+	ro.MetricsTooOld.Incr(1)
+	metric.Drop()
+}
+
+// dropTooOld drops the metrics of the batch older than the maximum metric age,
+// which may have been buffered while the output was failing, and returns the
+// metrics to write.
+func (ro *RunningOutput) dropTooOld(batch []telegraf.Metric) []telegraf.Metric {
+	if ro.Config.MaxMetricAge <= 0 {
+		return batch
+	}
+
+	now := time.Now()
+	fresh := make([]telegraf.Metric, 0, len(batch))
+	for _, metric := range batch {
+		if ro.tooOld(metric, now) {
+			ro.metricTooOld(metric)
+			continue
+		}
+		fresh = append(fresh, metric)
+	}
+
+	if dropped := len(batch) - len(fresh); dropped > 0 {
+		ro.log.Debugf("Dropped %d metrics older than %s", dropped, ro.Config.MaxMetricAge)
+	}
+	return fresh
+}
+
 func (r *RunningOutput) Init() error {
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
@@ -140,6 +187,15 @@ func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
 		return
 	}
 
+	if ro.tooOld(metric, time.Now()) {
+		ro.metricTooOld(metric)
+		return
+	}
+
+	if ro.Config.Precision > 0 {
+		metric.SetTime(metric.Time().Truncate(ro.Config.Precision))
+	}
+
 	if output, ok := ro.Output.(telegraf.AggregatingOutput); ok {
 		ro.aggMutex.Lock()
 		output.Add(metric)
@@ -183,6 +239,12 @@ func (ro *RunningOutput) Write() error {
 			break
 		}
 
+		batch = ro.dropTooOld(batch)
+		if len(batch) == 0 {
+			ro.buffer.Accept(batch)
+			continue
+		}
+
 		err := ro.write(batch)
 		if err != nil {
 			ro.buffer.Reject(batch)
@@ -200,6 +262,12 @@ func (ro *RunningOutput) WriteBatch() error {
 		return nil
 	}
 
+	batch = ro.dropTooOld(batch)
+	if len(batch) == 0 {
+		ro.buffer.Accept(batch)
+		return nil
+	}
+
 	err := ro.write(batch)
 	if err != nil {
 		ro.buffer.Reject(batch)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
@@ -132,6 +133,80 @@ func TestRunningOutput_PassFilter(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+func TestRunningOutput_Precision(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:    Filter{},
+		Precision: time.Second,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	ro.AddMetric(testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 42},
+		time.Unix(42, 999999999)))
+
+	err := ro.Write()
+	assert.NoError(t, err)
+	require.Len(t, m.Metrics(), 1)
+	assert.Equal(t, time.Unix(42, 0), m.Metrics()[0].Time())
+}
+
+func TestRunningOutput_MaxMetricAge(t *testing.T) {
+	conf := &OutputConfig{
+		Name:         "max_metric_age",
+		Filter:       Filter{},
+		MaxMetricAge: time.Hour,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	ro.AddMetric(testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 42},
+		time.Now().Add(-2*time.Hour)))
+	ro.AddMetric(testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 43},
+		time.Now()))
+
+	err := ro.Write()
+	assert.NoError(t, err)
+	require.Len(t, m.Metrics(), 1)
+	assert.Equal(t, int64(1), ro.MetricsTooOld.Get())
+}
+
+func TestRunningOutput_MaxMetricAgeBuffered(t *testing.T) {
+	conf := &OutputConfig{
+		Name:         "max_metric_age_buffered",
+		Filter:       Filter{},
+		MaxMetricAge: 50 * time.Millisecond,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	ro.AddMetric(testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 42},
+		time.Now()))
+
+	err := ro.Write()
+	require.Error(t, err)
+
+	// The buffered metric becomes too old while the output is failing
+	time.Sleep(100 * time.Millisecond)
+	m.failWrite = false
+	err = ro.Write()
+	assert.NoError(t, err)
+	assert.Len(t, m.Metrics(), 0)
+	assert.Equal(t, int64(1), ro.MetricsTooOld.Get())
+	assert.Equal(t, 0, ro.buffer.Len())
+}
+
 // Test that tags are properly included
 func TestRunningOutput_TagIncludeNoMatch(t *testing.T) {
 	conf := &OutputConfig{
@@ -0,0 +1,22 @@
+// +build !windows
+
+package update
+
+import (
+	"os"
+	"syscall"
+)
+
+// Apply replaces the running executable with the staged binary and restarts
+// the process with the same arguments and environment.  Apply only returns
+// on error.
+func Apply(path string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(path, exe); err != nil {
+		return err
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
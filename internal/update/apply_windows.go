@@ -0,0 +1,13 @@
+// +build windows
+
+package update
+
+import (
+	"errors"
+)
+
+// Apply is not supported on Windows, where the running executable cannot
+// be replaced; the staged binary must be installed by the service manager.
+func Apply(path string) error {
+	return errors.New("applying updates is not supported on windows")
+}
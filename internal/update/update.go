@@ -0,0 +1,316 @@
+// Package update checks for new releases of the agent and stages verified
+// release artifacts.
+package update
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Artifact is a release binary for a platform.
+type Artifact struct {
+	URL string `json:"url"`
+
+	// SHA256 is the hex encoded SHA-256 hash of the binary.
+	SHA256 string `json:"sha256"`
+
+	// Signature is the base64 encoded signature of the SHA-256 hash of the
+	// binary, made with the release signing key.
+	Signature string `json:"signature"`
+}
+
+// Manifest describes the latest release, the artifacts are keyed by
+// "<os>_<arch>", ie "linux_amd64".
+type Manifest struct {
+	Version   string               `json:"version"`
+	Artifacts map[string]*Artifact `json:"artifacts"`
+}
+
+// Artifact returns the artifact for the platform the agent runs on.
+func (m *Manifest) Artifact() (*Artifact, bool) {
+	a, ok := m.Artifacts[runtime.GOOS+"_"+runtime.GOARCH]
+	return a, ok
+}
+
+// Updater checks for and stages new releases.
+type Updater struct {
+	// URL of the release manifest.
+	URL string
+
+	// PublicKey is the PEM encoded RSA or ECDSA public key of the release
+	// signing key.
+	PublicKey []byte
+
+	// StagingDir is the directory the verified binaries are written to.
+	StagingDir string
+
+	// Version is the version of the running agent.
+	Version string
+
+	Client *http.Client
+
+	key crypto.PublicKey
+}
+
+// Init parses the public key.
+func (u *Updater) Init() error {
+	if u.URL == "" {
+		return errors.New("update url must be set")
+	}
+	if u.Client == nil {
+		u.Client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	if u.StagingDir == "" {
+		u.StagingDir = os.TempDir()
+	}
+
+	if len(u.PublicKey) == 0 {
+		return nil
+	}
+	block, _ := pem.Decode(u.PublicKey)
+	if block == nil {
+		return errors.New("public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parsing public key failed: %v", err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	u.key = key
+	return nil
+}
+
+// Check fetches the manifest and returns it if it describes a release newer
+// than the running version.
+func (u *Updater) Check(ctx context.Context) (*Manifest, error) {
+	body, err := u.get(ctx, u.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var m Manifest
+	if err := json.NewDecoder(body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decoding manifest failed: %v", err)
+	}
+	if parseVersion(m.Version) == nil {
+		return nil, fmt.Errorf("manifest has invalid version %q", m.Version)
+	}
+	if parseVersion(u.Version) == nil {
+		return nil, fmt.Errorf("running version %q is not a release", u.Version)
+	}
+
+	if compareVersions(m.Version, u.Version) <= 0 {
+		return nil, nil
+	}
+	return &m, nil
+}
+
+// Stage downloads the artifact of the platform, verifies its hash and
+// signature and returns the path of the staged binary.  Artifacts are only
+// staged when a public key is configured.
+func (u *Updater) Stage(ctx context.Context, m *Manifest) (string, error) {
+	if u.key == nil {
+		return "", errors.New("no public key configured, refusing to stage unverified artifact")
+	}
+	a, ok := m.Artifact()
+	if !ok {
+		return "", fmt.Errorf("release %s has no artifact for %s/%s",
+			m.Version, runtime.GOOS, runtime.GOARCH)
+	}
+
+	body, err := u.get(ctx, a.URL)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(u.StagingDir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(u.StagingDir, ".telegraf-download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("downloading %s failed: %v", a.URL, err)
+	}
+
+	digest := h.Sum(nil)
+	if a.SHA256 != "" && !strings.EqualFold(a.SHA256, hex.EncodeToString(digest)) {
+		return "", fmt.Errorf("hash of %s does not match manifest", a.URL)
+	}
+	if err := u.verify(digest, a.Signature); err != nil {
+		return "", fmt.Errorf("verifying %s failed: %v", a.URL, err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return "", err
+	}
+	name := "telegraf-" + m.Version
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	path := filepath.Join(u.StagingDir, name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// verify checks the signature of the SHA-256 digest with the public key.
+func (u *Updater) verify(digest []byte, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(sig) == 0 {
+		return errors.New("invalid signature")
+	}
+
+	switch key := u.key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig)
+	case *ecdsa.PublicKey:
+		var esig struct {
+			R, S *big.Int
+		}
+		if _, err := asn1.Unmarshal(sig, &esig); err != nil {
+			return errors.New("invalid signature")
+		}
+		if !ecdsa.Verify(key, digest, esig.R, esig.S) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return errors.New("no public key configured")
+}
+
+func (u *Updater) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s returned status %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Run checks for updates on the interval until the context is done.  When
+// stage is true new releases are staged and onStaged is called with the
+// path of the staged binary, checking stops after a release is staged.
+func (u *Updater) Run(
+	ctx context.Context,
+	interval time.Duration,
+	stage bool,
+	onStaged func(path string),
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m, err := u.Check(ctx)
+		if err != nil {
+			log.Printf("E! [agent] Error checking for updates: %v", err)
+		} else if m != nil {
+			log.Printf("I! [agent] Telegraf %s is available, running %s", m.Version, u.Version)
+			if stage {
+				path, err := u.Stage(ctx, m)
+				if err != nil {
+					log.Printf("E! [agent] Error staging update: %v", err)
+				} else {
+					log.Printf("I! [agent] Staged Telegraf %s at %s", m.Version, path)
+					onStaged(path)
+					return
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// compareVersions compares the dot separated numeric versions, ignoring a
+// "v" prefix and pre-release or build suffixes.
+func compareVersions(a, b string) int {
+	va, vb := parseVersion(a), parseVersion(b)
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseVersion returns the numeric parts of the version, or nil if the
+// version is not a release version.
+func parseVersion(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+~ "); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,150 @@
+package update
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var binary = []byte("#!/bin/sh\necho telegraf\n")
+
+func newServer(t *testing.T, key *ecdsa.PrivateKey, artifact []byte) *httptest.Server {
+	digest := sha256.Sum256(binary)
+	sig, err := key.Sign(rand.Reader, digest[:], nil)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	mux.HandleFunc("/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		m := Manifest{
+			Version: "1.14.0",
+			Artifacts: map[string]*Artifact{
+				runtime.GOOS + "_" + runtime.GOARCH: {
+					URL:       ts.URL + "/telegraf",
+					SHA256:    hex.EncodeToString(digest[:]),
+					Signature: base64.StdEncoding.EncodeToString(sig),
+				},
+			},
+		}
+		json.NewEncoder(w).Encode(m)
+	})
+	mux.HandleFunc("/telegraf", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(artifact)
+	})
+	return ts
+}
+
+func newUpdater(t *testing.T, url string, key *ecdsa.PrivateKey) *Updater {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "update")
+	require.NoError(t, err)
+
+	u := &Updater{
+		URL:        url + "/manifest.json",
+		PublicKey:  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		StagingDir: dir,
+		Version:    "1.13.4",
+	}
+	require.NoError(t, u.Init())
+	return u
+}
+
+func TestStage(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ts := newServer(t, key, binary)
+	defer ts.Close()
+
+	u := newUpdater(t, ts.URL, key)
+	defer os.RemoveAll(u.StagingDir)
+
+	m, err := u.Check(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	require.Equal(t, "1.14.0", m.Version)
+
+	path, err := u.Stage(context.Background(), m)
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, binary, data)
+}
+
+func TestStageTampered(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ts := newServer(t, key, []byte("#!/bin/sh\nrm -rf /\n"))
+	defer ts.Close()
+
+	u := newUpdater(t, ts.URL, key)
+	defer os.RemoveAll(u.StagingDir)
+
+	m, err := u.Check(context.Background())
+	require.NoError(t, err)
+	_, err = u.Stage(context.Background(), m)
+	require.Error(t, err)
+
+	files, err := ioutil.ReadDir(u.StagingDir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestStageWrongKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ts := newServer(t, other, binary)
+	defer ts.Close()
+
+	u := newUpdater(t, ts.URL, key)
+	defer os.RemoveAll(u.StagingDir)
+
+	m, err := u.Check(context.Background())
+	require.NoError(t, err)
+	_, err = u.Stage(context.Background(), m)
+	require.Error(t, err)
+}
+
+func TestCheckCurrent(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ts := newServer(t, key, binary)
+	defer ts.Close()
+
+	u := newUpdater(t, ts.URL, key)
+	defer os.RemoveAll(u.StagingDir)
+
+	u.Version = "1.14.0"
+	m, err := u.Check(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, m)
+
+	u.Version = "unknown"
+	_, err = u.Check(context.Background())
+	require.Error(t, err)
+}
+
+func TestCompareVersions(t *testing.T) {
+	require.Equal(t, 0, compareVersions("1.14.0", "v1.14"))
+	require.Equal(t, 1, compareVersions("1.14.1", "1.14.0~rc1"))
+	require.Equal(t, -1, compareVersions("1.9.0", "1.14.0"))
+	require.Equal(t, 1, compareVersions("2.0.0-beta", "1.99.9"))
+}
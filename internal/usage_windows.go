@@ -40,6 +40,10 @@ The commands & flags are:
   --service <service>            operate on the service (windows only)
   --service-name                 service name (windows only)
   --service-display-name         service display name (windows only)
+  --service-restart-delay        delay before restarting the service after a
+                                 failure, 0s disables restarts (windows only)
+  --service-restart-reset        period without failures after which the
+                                 failure count is reset (windows only)
 
 Examples:
 
@@ -69,4 +73,7 @@ Examples:
 
   # install telegraf service with custom name
   telegraf --service install --service-name=my-telegraf --service-display-name="My Telegraf"
+
+  # install a second telegraf service with its own config
+  telegraf --service install --service-name=telegraf-2 --config "C:\Program Files\Telegraf\telegraf-2.conf"
 `
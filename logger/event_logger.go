@@ -12,6 +12,24 @@ const (
 	LogTargetEventlog = "eventlog"
 )
 
+// Event IDs of the messages written to the Windows Event Log.  Log messages
+// use the ID of their level, service state changes have their own IDs.
+const (
+	EventIDInfo    = 1
+	EventIDWarning = 2
+	EventIDError   = 3
+
+	EventIDServiceStart = 100
+	EventIDServiceStop  = 101
+)
+
+// eventIDLogger is implemented by service loggers supporting event IDs.
+type eventIDLogger interface {
+	NInfo(eventID uint32, v ...interface{}) error
+	NWarning(eventID uint32, v ...interface{}) error
+	NError(eventID uint32, v ...interface{}) error
+}
+
 type eventLogger struct {
 	logger service.Logger
 }
@@ -20,22 +38,43 @@ func (t *eventLogger) Write(b []byte) (n int, err error) {
 	loc := prefixRegex.FindIndex(b)
 	n = len(b)
 	if loc == nil {
-		err = t.logger.Info(b)
+		err = t.info(EventIDInfo, b)
 	} else if n > 2 { //skip empty log messages
 		line := strings.Trim(string(b[loc[1]:]), " \t\r\n")
 		switch rune(b[loc[0]]) {
 		case 'I':
-			err = t.logger.Info(line)
+			err = t.info(EventIDInfo, line)
 		case 'W':
-			err = t.logger.Warning(line)
+			err = t.warning(EventIDWarning, line)
 		case 'E':
-			err = t.logger.Error(line)
+			err = t.error(EventIDError, line)
 		}
 	}
 
 	return
 }
 
+func (t *eventLogger) info(id uint32, v interface{}) error {
+	if l, ok := t.logger.(eventIDLogger); ok {
+		return l.NInfo(id, v)
+	}
+	return t.logger.Info(v)
+}
+
+func (t *eventLogger) warning(id uint32, v interface{}) error {
+	if l, ok := t.logger.(eventIDLogger); ok {
+		return l.NWarning(id, v)
+	}
+	return t.logger.Warning(v)
+}
+
+func (t *eventLogger) error(id uint32, v interface{}) error {
+	if l, ok := t.logger.(eventIDLogger); ok {
+		return l.NError(id, v)
+	}
+	return t.logger.Error(v)
+}
+
 type eventLoggerCreator struct {
 	serviceLogger service.Logger
 }
@@ -44,6 +83,19 @@ func (e *eventLoggerCreator) CreateLogger(config LogConfig) (io.Writer, error) {
 	return wlog.NewWriter(&eventLogger{logger: e.serviceLogger}), nil
 }
 
+// serviceEventLogger is the registered event logger, used for the events
+// with their own IDs.
+var serviceEventLogger *eventLogger
+
 func RegisterEventLogger(serviceLogger service.Logger) {
+	serviceEventLogger = &eventLogger{logger: serviceLogger}
 	registerLogger(LogTargetEventlog, &eventLoggerCreator{serviceLogger: serviceLogger})
 }
+
+// LogEvent writes an informational message with the event ID to the Windows
+// Event Log, if an event logger is registered.
+func LogEvent(id uint32, msg string) {
+	if serviceEventLogger != nil {
+		serviceEventLogger.info(id, msg)
+	}
+}
@@ -0,0 +1,255 @@
+package execd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/common/shim"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+const sampleConfig = `
+  ## Program to run as the aggregator, with its arguments.  The program
+  ## receives the metrics on stdin and writes its aggregates to stdout in the
+  ## InfluxDB line protocol, it must exit when stdin is closed.
+  command = ["/usr/bin/my-aggregator", "--config", "/etc/my-aggregator.conf"]
+
+  ## Delay before restarting the program after it exited.
+  # restart_delay = "10s"
+
+  ## Maximum time the program may take to push its aggregates.
+  # push_timeout = "5s"
+
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+`
+
+// Execd runs an external program as aggregator.
+type Execd struct {
+	Command      []string          `toml:"command"`
+	RestartDelay internal.Duration `toml:"restart_delay"`
+	PushTimeout  internal.Duration `toml:"push_timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	serializer *serializer.Serializer
+	parser     *influx.Parser
+	process    *process
+	started    time.Time
+}
+
+// process is a running instance of the program.
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	writer *bufio.Writer
+	lines  chan string
+	done   chan struct{}
+}
+
+func (e *Execd) Description() string {
+	return "Run an external program as aggregator"
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Init() error {
+	if len(e.Command) == 0 {
+		return errors.New("no command set")
+	}
+	e.serializer = serializer.NewSerializer()
+	e.parser = influx.NewParser(influx.NewMetricHandler())
+	return e.start()
+}
+
+func (e *Execd) Add(m telegraf.Metric) {
+	if !e.running() {
+		return
+	}
+	b, err := e.serializer.Serialize(m)
+	if err != nil {
+		e.Log.Errorf("Serializing metric failed: %v", err)
+		return
+	}
+	if _, err := e.process.writer.Write(b); err != nil {
+		e.Log.Errorf("Writing metric failed: %v", err)
+	}
+}
+
+func (e *Execd) Push(acc telegraf.Accumulator) {
+	if !e.running() {
+		return
+	}
+	p := e.process
+
+	// The aggregates are written with their own timestamps
+	acc.SetPrecision(time.Nanosecond)
+
+	// Discard the output written out of a push
+	for len(p.lines) > 0 {
+		e.Log.Warnf("Unexpected output: %s", <-p.lines)
+	}
+
+	if err := e.signal(fmt.Sprintf("%s %d", shim.PushSignal, time.Now().UnixNano())); err != nil {
+		e.Log.Errorf("Signaling push failed: %v", err)
+		return
+	}
+
+	timeout := time.NewTimer(e.PushTimeout.Duration)
+	defer timeout.Stop()
+	for {
+		select {
+		case line := <-p.lines:
+			if line == shim.PushedSignal {
+				return
+			}
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			m, err := e.parser.ParseLine(line)
+			if err != nil {
+				e.Log.Errorf("Parsing aggregate failed: %v", err)
+				continue
+			}
+			acc.AddMetric(m)
+		case <-p.done:
+			e.Log.Error("Program exited during push")
+			return
+		case <-timeout.C:
+			e.Log.Errorf("Program did not push its aggregates within %s", e.PushTimeout.Duration)
+			return
+		}
+	}
+}
+
+func (e *Execd) Reset() {
+	if !e.running() {
+		return
+	}
+	if err := e.signal(shim.ResetSignal); err != nil {
+		e.Log.Errorf("Signaling reset failed: %v", err)
+	}
+}
+
+// signal writes a control message after the pending metrics.
+func (e *Execd) signal(message string) error {
+	w := e.process.writer
+	if _, err := fmt.Fprintln(w, message); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// running returns whether the program is running, restarting it once the
+// restart delay elapsed if it exited.
+func (e *Execd) running() bool {
+	if e.process != nil {
+		select {
+		case <-e.process.done:
+		default:
+			return true
+		}
+	}
+
+	if time.Since(e.started) < e.RestartDelay.Duration {
+		return false
+	}
+	if err := e.start(); err != nil {
+		e.Log.Errorf("Restarting program failed: %v", err)
+		return false
+	}
+	return true
+}
+
+func (e *Execd) start() error {
+	e.started = time.Now()
+
+	cmd := exec.Command(e.Command[0], e.Command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s failed: %v", e.Command[0], err)
+	}
+
+	p := &process{
+		cmd:    cmd,
+		stdin:  stdin,
+		writer: bufio.NewWriter(stdin),
+		lines:  make(chan string, 1024),
+		done:   make(chan struct{}),
+	}
+	e.process = p
+
+	go e.logStderr(stderr)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			p.lines <- scanner.Text()
+		}
+		err := cmd.Wait()
+		if err != nil {
+			e.Log.Errorf("Program exited: %v", err)
+		} else {
+			e.Log.Warn("Program exited")
+		}
+		stdin.Close()
+		close(p.done)
+	}()
+	return nil
+}
+
+// logStderr logs the lines of stderr, with the level of their "E!", "W!",
+// "I!" or "D!" prefix as the shim writes them.
+func (e *Execd) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "D! "):
+			e.Log.Debug(line[3:])
+		case strings.HasPrefix(line, "I! "):
+			e.Log.Info(line[3:])
+		case strings.HasPrefix(line, "W! "):
+			e.Log.Warn(line[3:])
+		case strings.HasPrefix(line, "E! "):
+			e.Log.Error(line[3:])
+		default:
+			e.Log.Error(line)
+		}
+	}
+}
+
+func init() {
+	aggregators.Add("execd", func() telegraf.Aggregator {
+		return &Execd{
+			RestartDelay: internal.Duration{Duration: 10 * time.Second},
+			PushTimeout:  internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
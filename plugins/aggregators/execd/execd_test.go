@@ -0,0 +1,121 @@
+package execd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/shim"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// counter counts the metrics of each measurement.
+type counter struct {
+	counts map[string]int64
+}
+
+func (c *counter) SampleConfig() string { return "" }
+func (c *counter) Description() string  { return "" }
+
+func (c *counter) Add(m telegraf.Metric) {
+	c.counts[m.Name()]++
+}
+
+func (c *counter) Push(acc telegraf.Accumulator) {
+	for name, count := range c.counts {
+		acc.AddFields("count", map[string]interface{}{"value": count}, map[string]string{"measurement": name}, time.Unix(1600000000, 0))
+	}
+}
+
+func (c *counter) Reset() {
+	c.counts = make(map[string]int64)
+}
+
+// TestMain runs the test binary as the aggregator program when the
+// environment variable is set.
+func TestMain(m *testing.M) {
+	if os.Getenv("EXECD_TEST_AGGREGATOR") == "1" {
+		s := shim.New()
+		if err := s.AddAggregator(&counter{counts: make(map[string]int64)}); err != nil {
+			os.Exit(1)
+		}
+		if err := s.Run(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestAggregate(t *testing.T) {
+	os.Setenv("EXECD_TEST_AGGREGATOR", "1")
+	defer os.Unsetenv("EXECD_TEST_AGGREGATOR")
+
+	plugin := &Execd{
+		Command:     []string{os.Args[0]},
+		PushTimeout: internal.Duration{Duration: 5 * time.Second},
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.process.stdin.Close()
+
+	for i := 0; i < 3; i++ {
+		plugin.Add(testutil.MustMetric("cpu", nil, map[string]interface{}{"usage": 42.0}, time.Unix(1600000000, 0)))
+	}
+	plugin.Add(testutil.MustMetric("mem", nil, map[string]interface{}{"used": 1024}, time.Unix(1600000000, 0)))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	plugin.Reset()
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("count",
+			map[string]string{"measurement": "cpu"},
+			map[string]interface{}{"value": int64(3)},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric("count",
+			map[string]string{"measurement": "mem"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+
+	// The aggregates were reset
+	plugin.Add(testutil.MustMetric("cpu", nil, map[string]interface{}{"usage": 42.0}, time.Unix(1600000000, 0)))
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	expected = []telegraf.Metric{
+		testutil.MustMetric("count",
+			map[string]string{"measurement": "cpu"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestRestart(t *testing.T) {
+	plugin := &Execd{
+		Command:     []string{"true"},
+		PushTimeout: internal.Duration{Duration: time.Second},
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	<-plugin.process.done
+
+	// The program is restarted without restart delay
+	first := plugin.process
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	require.NotEqual(t, first, plugin.process)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestNoCommand(t *testing.T) {
+	require.Error(t, (&Execd{}).Init())
+}
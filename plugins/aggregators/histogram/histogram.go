@@ -14,13 +14,23 @@ const bucketTag = "le"
 // bucketInf is the right bucket border for infinite values
 const bucketInf = "+Inf"
 
+// bucketLeftTag is the tag, which contains left bucket border of non-cumulative histograms
+const bucketLeftTag = "gt"
+
+// bucketNegInf is the left bucket border for the first bucket of non-cumulative histograms
+const bucketNegInf = "-Inf"
+
 // HistogramAggregator is aggregator with histogram configs and particular histograms for defined metrics
 type HistogramAggregator struct {
-	Configs      []config `toml:"config"`
-	ResetBuckets bool     `toml:"reset"`
+	Configs           []config `toml:"config"`
+	ResetBuckets      bool     `toml:"reset"`
+	Cumulative        bool     `toml:"cumulative"`
+	ExpirationPeriods int      `toml:"expiration_periods"`
 
 	buckets bucketsByMetrics
 	cache   map[uint64]metricHistogramCollection
+	// period is the number of the current period
+	period int
 }
 
 // config is the config, which contains name, field of metric and histogram buckets.
@@ -44,6 +54,8 @@ type metricHistogramCollection struct {
 	histogramCollection map[string]counts
 	name                string
 	tags                map[string]string
+	// updated is the number of the period the collection was last updated in
+	updated int
 }
 
 // counts is the number of hits in the bucket
@@ -58,7 +70,7 @@ type groupedByCountFields struct {
 
 // NewHistogramAggregator creates new histogram aggregator
 func NewHistogramAggregator() telegraf.Aggregator {
-	h := &HistogramAggregator{}
+	h := &HistogramAggregator{Cumulative: true}
 	h.buckets = make(bucketsByMetrics)
 	h.resetCache()
 
@@ -77,6 +89,16 @@ var sampleConfig = `
   ## of accumulating the results.
   reset = false
 
+  ## If false, each bucket only counts the values within its borders, given
+  ## by the "gt" and "le" tags, instead of the values less than or equal to
+  ## its right border.
+  # cumulative = true
+
+  ## Number of periods without new values after which the histograms of a
+  ## series are dropped, to bound the memory used by series with changing
+  ## tags.  If 0, the histograms are kept until reset.
+  # expiration_periods = 0
+
   ## Example config that aggregates all fields of the metric.
   # [[aggregators.histogram.config]]
   #   ## The set of buckets.
@@ -141,6 +163,7 @@ func (h *HistogramAggregator) Add(in telegraf.Metric) {
 		}
 	}
 
+	agr.updated = h.period
 	h.cache[id] = agr
 }
 
@@ -167,6 +190,11 @@ func (h *HistogramAggregator) groupFieldsByBuckets(
 	tags map[string]string,
 	counts []int64,
 ) {
+	if !h.Cumulative {
+		h.groupFieldsByBorders(metricsWithGroupedFields, name, field, tags, counts)
+		return
+	}
+
 	count := int64(0)
 	for index, bucket := range h.getBuckets(name, field) {
 		count += counts[index]
@@ -181,6 +209,30 @@ func (h *HistogramAggregator) groupFieldsByBuckets(
 	h.groupField(metricsWithGroupedFields, name, field, count, tags)
 }
 
+// groupFieldsByBorders groups fields of non-cumulative histograms by both borders of the buckets
+func (h *HistogramAggregator) groupFieldsByBorders(
+	metricsWithGroupedFields *[]groupedByCountFields,
+	name string,
+	field string,
+	tags map[string]string,
+	counts []int64,
+) {
+	left := bucketNegInf
+	for index, bucket := range h.getBuckets(name, field) {
+		right := strconv.FormatFloat(bucket, 'f', -1, 64)
+
+		tags[bucketLeftTag] = left
+		tags[bucketTag] = right
+		h.groupField(metricsWithGroupedFields, name, field, counts[index], copyTags(tags))
+		left = right
+	}
+
+	tags[bucketLeftTag] = left
+	tags[bucketTag] = bucketInf
+
+	h.groupField(metricsWithGroupedFields, name, field, counts[len(counts)-1], tags)
+}
+
 // groupField groups field by count value
 func (h *HistogramAggregator) groupField(
 	metricsWithGroupedFields *[]groupedByCountFields,
@@ -214,6 +266,20 @@ func (h *HistogramAggregator) Reset() {
 		h.resetCache()
 		h.buckets = make(bucketsByMetrics)
 	}
+
+	h.period++
+	if h.ExpirationPeriods > 0 {
+		h.expire()
+	}
+}
+
+// expire drops the histograms of the series not updated in the last expiration periods
+func (h *HistogramAggregator) expire() {
+	for id, agr := range h.cache {
+		if h.period-agr.updated > h.ExpirationPeriods {
+			delete(h.cache, id)
+		}
+	}
 }
 
 // resetCache resets cached counts(hits) in the buckets
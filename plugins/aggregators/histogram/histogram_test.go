@@ -13,7 +13,7 @@ import (
 
 // NewTestHistogram creates new test histogram aggregation with specified config
 func NewTestHistogram(cfg []config, reset bool) telegraf.Aggregator {
-	htm := &HistogramAggregator{Configs: cfg, ResetBuckets: reset}
+	htm := &HistogramAggregator{Configs: cfg, ResetBuckets: reset, Cumulative: true}
 	htm.buckets = make(bucketsByMetrics)
 	htm.resetCache()
 
@@ -177,6 +177,71 @@ func TestHistogramDifferentPeriodsAndAllFields(t *testing.T) {
 	assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"a_bucket": int64(2), "b_bucket": int64(1), "c_bucket": int64(1)}, bucketInf)
 }
 
+// TestHistogramNonCumulative tests the counts and borders of non-cumulative buckets
+func TestHistogramNonCumulative(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Buckets: []float64{0.0, 10.0, 20.0}})
+	histogram := NewTestHistogram(cfg, false).(*HistogramAggregator)
+	histogram.Cumulative = false
+
+	acc := &testutil.Accumulator{}
+
+	histogram.Add(firstMetric1)
+	histogram.Add(firstMetric2)
+	histogram.Push(acc)
+
+	expected := []telegraf.Metric{}
+	for _, bucket := range []struct {
+		gt    string
+		le    string
+		count int64
+	}{
+		{bucketNegInf, "0", 0},
+		{"0", "10", 0},
+		{"10", "20", 2},
+		{"20", bucketInf, 0},
+	} {
+		expected = append(expected, testutil.MustMetric(
+			"first_metric_name",
+			map[string]string{"tag_name": "tag_value", bucketLeftTag: bucket.gt, bucketTag: bucket.le},
+			map[string]interface{}{"a_bucket": bucket.count},
+			time.Unix(0, 0),
+		))
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+// TestHistogramExpiration tests the dropping of series without new values
+func TestHistogramExpiration(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Buckets: []float64{0.0, 10.0, 20.0, 30.0, 40.0}})
+	cfg = append(cfg, config{Metric: "second_metric_name", Buckets: []float64{0.0, 4.0, 10.0, 23.0, 30.0}})
+	histogram := NewTestHistogram(cfg, false).(*HistogramAggregator)
+	histogram.ExpirationPeriods = 1
+
+	acc := &testutil.Accumulator{}
+
+	histogram.Add(firstMetric1)
+	histogram.Add(secondMetric)
+	histogram.Push(acc)
+	histogram.Reset()
+	assert.Len(t, acc.Metrics, 12)
+
+	// The second metric has no new values in this period but is not expired yet
+	acc.ClearMetrics()
+	histogram.Add(firstMetric2)
+	histogram.Push(acc)
+	histogram.Reset()
+	assert.Len(t, acc.Metrics, 12)
+
+	acc.ClearMetrics()
+	histogram.Add(firstMetric1)
+	histogram.Push(acc)
+	histogram.Reset()
+	assert.Len(t, acc.Metrics, 6)
+	assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"a_bucket": int64(3)}, bucketInf)
+}
+
 // TestWrongBucketsOrder tests the calling panic with incorrect order of buckets
 func TestWrongBucketsOrder(t *testing.T) {
 	defer func() {
@@ -0,0 +1,431 @@
+package forward
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// eventTimeType is the msgpack extension type of the Fluentd EventTime.
+const eventTimeType = 0
+
+// Ext is a msgpack extension value other than EventTime.
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// Decoder reads msgpack encoded values from a stream.
+//
+// Values are decoded to nil, bool, int64, uint64, float64, string, []byte,
+// []interface{}, map[string]interface{}, time.Time for the EventTime
+// extension and Ext for other extensions.
+type Decoder struct {
+	r *bufio.Reader
+	// MaxLength limits the size of strings, binaries, arrays and maps.
+	MaxLength int
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), MaxLength: 64 * 1024 * 1024}
+}
+
+// Buffered returns the number of bytes that can be decoded without reading
+// from the underlying reader.
+func (d *Decoder) Buffered() int {
+	return d.r.Buffered()
+}
+
+func (d *Decoder) readN(n int) ([]byte, error) {
+	if n > d.MaxLength {
+		return nil, fmt.Errorf("msgpack value of length %d exceeds limit", n)
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(d.r, b)
+	return b, unexpectedEOF(err)
+}
+
+func (d *Decoder) readUint(size int) (uint64, error) {
+	b, err := d.readN(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *Decoder) readLength(size int) (int, error) {
+	n, err := d.readUint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(d.MaxLength) {
+		return 0, fmt.Errorf("msgpack value of length %d exceeds limit", n)
+	}
+	return int(n), nil
+}
+
+// Decode reads the next value.  It returns io.EOF if the stream ends before
+// the first byte of the value.
+func (d *Decoder) Decode() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= 0x80 && c <= 0x8f:
+		return d.decodeMap(int(c & 0x0f))
+	case c >= 0x90 && c <= 0x9f:
+		return d.decodeArray(int(c & 0x0f))
+	case c >= 0xa0 && c <= 0xbf:
+		b, err := d.readN(int(c & 0x1f))
+		return string(b), err
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readLength(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.readN(n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readLength(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(n)
+	case 0xca:
+		v, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.readUint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.readUint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0:
+		v, err := d.readUint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.readUint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.readUint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.readUint(8)
+		return int64(v), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readLength(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.readN(n)
+		return string(b), err
+	case 0xdc, 0xdd:
+		n, err := d.readLength(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.readLength(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("invalid msgpack format 0x%02x", c)
+}
+
+func (d *Decoder) decodeNext() (interface{}, error) {
+	v, err := d.Decode()
+	return v, unexpectedEOF(err)
+}
+
+func (d *Decoder) decodeArray(n int) (interface{}, error) {
+	if n > d.MaxLength {
+		return nil, fmt.Errorf("msgpack array of length %d exceeds limit", n)
+	}
+	a := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.decodeNext()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+func (d *Decoder) decodeMap(n int) (interface{}, error) {
+	if n > d.MaxLength {
+		return nil, fmt.Errorf("msgpack map of length %d exceeds limit", n)
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decodeNext()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decodeNext()
+		if err != nil {
+			return nil, err
+		}
+		switch key := k.(type) {
+		case string:
+			m[key] = v
+		case []byte:
+			m[string(key)] = v
+		default:
+			m[fmt.Sprint(key)] = v
+		}
+	}
+	return m, nil
+}
+
+func (d *Decoder) decodeExt(n int) (interface{}, error) {
+	t, err := d.readUint(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.readN(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(t) == eventTimeType && len(data) == 8 {
+		sec := binary.BigEndian.Uint32(data[:4])
+		nsec := binary.BigEndian.Uint32(data[4:])
+		return time.Unix(int64(sec), int64(nsec)), nil
+	}
+	return Ext{Type: int8(t), Data: data}, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Encoder appends msgpack encoded values to a buffer.
+type Encoder struct {
+	buf []byte
+}
+
+// Bytes returns the encoded values.
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) Reset() {
+	e.buf = e.buf[:0]
+}
+
+func (e *Encoder) writeUint(c byte, size int, v uint64) {
+	e.buf = append(e.buf, c)
+	switch size {
+	case 1:
+		e.buf = append(e.buf, byte(v))
+	case 2:
+		e.buf = append(e.buf, byte(v>>8), byte(v))
+	case 4:
+		e.buf = append(e.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	case 8:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		e.buf = append(e.buf, b[:]...)
+	}
+}
+
+func (e *Encoder) EncodeNil() {
+	e.buf = append(e.buf, 0xc0)
+}
+
+func (e *Encoder) EncodeBool(v bool) {
+	if v {
+		e.buf = append(e.buf, 0xc3)
+	} else {
+		e.buf = append(e.buf, 0xc2)
+	}
+}
+
+func (e *Encoder) EncodeInt(v int64) {
+	switch {
+	case v >= 0:
+		e.EncodeUint(uint64(v))
+	case v >= -32:
+		e.buf = append(e.buf, byte(v))
+	case v >= math.MinInt8:
+		e.writeUint(0xd0, 1, uint64(v))
+	case v >= math.MinInt16:
+		e.writeUint(0xd1, 2, uint64(v))
+	case v >= math.MinInt32:
+		e.writeUint(0xd2, 4, uint64(v))
+	default:
+		e.writeUint(0xd3, 8, uint64(v))
+	}
+}
+
+func (e *Encoder) EncodeUint(v uint64) {
+	switch {
+	case v <= 0x7f:
+		e.buf = append(e.buf, byte(v))
+	case v <= math.MaxUint8:
+		e.writeUint(0xcc, 1, v)
+	case v <= math.MaxUint16:
+		e.writeUint(0xcd, 2, v)
+	case v <= math.MaxUint32:
+		e.writeUint(0xce, 4, v)
+	default:
+		e.writeUint(0xcf, 8, v)
+	}
+}
+
+func (e *Encoder) EncodeFloat(v float64) {
+	e.writeUint(0xcb, 8, math.Float64bits(v))
+}
+
+func (e *Encoder) EncodeString(v string) {
+	n := len(v)
+	switch {
+	case n <= 31:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.writeUint(0xd9, 1, uint64(n))
+	case n <= math.MaxUint16:
+		e.writeUint(0xda, 2, uint64(n))
+	default:
+		e.writeUint(0xdb, 4, uint64(n))
+	}
+	e.buf = append(e.buf, v...)
+}
+
+func (e *Encoder) EncodeBytes(v []byte) {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		e.writeUint(0xc4, 1, uint64(n))
+	case n <= math.MaxUint16:
+		e.writeUint(0xc5, 2, uint64(n))
+	default:
+		e.writeUint(0xc6, 4, uint64(n))
+	}
+	e.buf = append(e.buf, v...)
+}
+
+// EncodeArrayLen writes the header of an array of n values, which must be
+// followed by the values.
+func (e *Encoder) EncodeArrayLen(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.writeUint(0xdc, 2, uint64(n))
+	default:
+		e.writeUint(0xdd, 4, uint64(n))
+	}
+}
+
+// EncodeMapLen writes the header of a map of n key value pairs, which must
+// be followed by the keys and values.
+func (e *Encoder) EncodeMapLen(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.writeUint(0xde, 2, uint64(n))
+	default:
+		e.writeUint(0xdf, 4, uint64(n))
+	}
+}
+
+// EncodeEventTime writes the time as Fluentd EventTime extension.
+func (e *Encoder) EncodeEventTime(t time.Time) {
+	e.buf = append(e.buf, 0xd7, eventTimeType)
+	e.buf = append(e.buf,
+		byte(t.Unix()>>24), byte(t.Unix()>>16), byte(t.Unix()>>8), byte(t.Unix()))
+	nsec := t.Nanosecond()
+	e.buf = append(e.buf, byte(nsec>>24), byte(nsec>>16), byte(nsec>>8), byte(nsec))
+}
+
+// Encode writes a value of the types returned by the Decoder, map keys are
+// written in sorted order.
+func (e *Encoder) Encode(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		e.EncodeNil()
+	case bool:
+		e.EncodeBool(v)
+	case int:
+		e.EncodeInt(int64(v))
+	case int64:
+		e.EncodeInt(v)
+	case uint64:
+		e.EncodeUint(v)
+	case float64:
+		e.EncodeFloat(v)
+	case string:
+		e.EncodeString(v)
+	case []byte:
+		e.EncodeBytes(v)
+	case time.Time:
+		e.EncodeEventTime(v)
+	case []interface{}:
+		e.EncodeArrayLen(len(v))
+		for _, item := range v {
+			if err := e.Encode(item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		e.EncodeMapLen(len(v))
+		for _, k := range keys {
+			e.EncodeString(k)
+			if err := e.Encode(v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported msgpack type %T", v)
+	}
+	return nil
+}
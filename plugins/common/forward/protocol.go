@@ -0,0 +1,350 @@
+// Package forward implements the Fluentd forward protocol used by Fluentd
+// and Fluent Bit, see
+// https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1
+package forward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// Entry is an event of a forward message.
+type Entry struct {
+	Time   time.Time
+	Record map[string]interface{}
+}
+
+// Message is a forward message of events sharing the same tag.
+type Message struct {
+	Tag     string
+	Entries []Entry
+	// Chunk is the chunk id of the message, if set the receiver is expected
+	// to acknowledge the message.
+	Chunk string
+}
+
+// ParseMessage converts a decoded value to a message.  All of Message,
+// Forward, PackedForward and CompressedPackedForward modes are supported.
+func ParseMessage(v interface{}) (*Message, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) < 2 {
+		return nil, errors.New("message is not an array")
+	}
+
+	tag, ok := toString(arr[0])
+	if !ok {
+		return nil, errors.New("message tag is not a string")
+	}
+	msg := &Message{Tag: tag}
+
+	var option map[string]interface{}
+	var err error
+	switch events := arr[1].(type) {
+	case []interface{}:
+		// Forward mode: [tag, [[time, record], ...], option]
+		option = optionAt(arr, 2)
+		for _, e := range events {
+			entry, err := parseEntry(e)
+			if err != nil {
+				return nil, err
+			}
+			msg.Entries = append(msg.Entries, entry)
+		}
+	case string, []byte:
+		// PackedForward mode: [tag, msgpack stream of entries, option]
+		option = optionAt(arr, 2)
+		packed, _ := toBytes(events)
+		if compressed, _ := toString(option["compressed"]); compressed == "gzip" {
+			packed, err = gunzip(packed)
+			if err != nil {
+				return nil, err
+			}
+		} else if compressed != "" && compressed != "text" {
+			return nil, fmt.Errorf("unsupported compression %q", compressed)
+		}
+
+		d := NewDecoder(bytes.NewReader(packed))
+		for {
+			e, err := d.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			entry, err := parseEntry(e)
+			if err != nil {
+				return nil, err
+			}
+			msg.Entries = append(msg.Entries, entry)
+		}
+	default:
+		// Message mode: [tag, time, record, option]
+		if len(arr) < 3 {
+			return nil, errors.New("message has no record")
+		}
+		option = optionAt(arr, 3)
+		entry, err := parseEntry(arr[1:3])
+		if err != nil {
+			return nil, err
+		}
+		msg.Entries = append(msg.Entries, entry)
+	}
+
+	msg.Chunk, _ = toString(option["chunk"])
+	return msg, nil
+}
+
+func optionAt(arr []interface{}, i int) map[string]interface{} {
+	if len(arr) <= i {
+		return nil
+	}
+	option, _ := arr[i].(map[string]interface{})
+	return option
+}
+
+func parseEntry(v interface{}) (Entry, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) != 2 {
+		return Entry{}, errors.New("entry is not an array of time and record")
+	}
+
+	t, err := parseTime(arr[0])
+	if err != nil {
+		return Entry{}, err
+	}
+	record, ok := arr[1].(map[string]interface{})
+	if !ok {
+		return Entry{}, errors.New("entry record is not a map")
+	}
+	return Entry{Time: t, Record: record}, nil
+}
+
+func parseTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case int64:
+		return time.Unix(t, 0), nil
+	case uint64:
+		return time.Unix(int64(t), 0), nil
+	case float64:
+		sec := int64(t)
+		return time.Unix(sec, int64((t-float64(sec))*1e9)), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid entry time type %T", v)
+	}
+}
+
+func gunzip(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// EncodeAck writes the acknowledgement of a chunk.
+func EncodeAck(e *Encoder, chunk string) {
+	e.EncodeMapLen(1)
+	e.EncodeString("ack")
+	e.EncodeString(chunk)
+}
+
+// ParseAck returns the chunk id of an acknowledgement.
+func ParseAck(v interface{}) (string, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", errors.New("ack response is not a map")
+	}
+	chunk, ok := toString(m["ack"])
+	if !ok {
+		return "", errors.New("ack response has no chunk id")
+	}
+	return chunk, nil
+}
+
+// NewChunkID returns a random chunk id.
+func NewChunkID() (string, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// digest returns the hex encoded SHA-512 of the concatenated values, as used
+// to authenticate with the shared key.
+func digest(values ...[]byte) string {
+	h := sha512.New()
+	for _, v := range values {
+		h.Write(v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+// ServerHandshake authenticates a client with the shared key, by sending a
+// HELO message, verifying the PING of the client and answering with PONG.
+func ServerHandshake(w io.Writer, d *Decoder, hostname, sharedKey string) error {
+	nonce, err := randomBytes(16)
+	if err != nil {
+		return err
+	}
+
+	e := &Encoder{}
+	e.EncodeArrayLen(2)
+	e.EncodeString("HELO")
+	e.EncodeMapLen(3)
+	e.EncodeString("nonce")
+	e.EncodeBytes(nonce)
+	e.EncodeString("auth")
+	e.EncodeBytes(nil)
+	e.EncodeString("keepalive")
+	e.EncodeBool(true)
+	if _, err := w.Write(e.Bytes()); err != nil {
+		return err
+	}
+
+	v, err := d.Decode()
+	if err != nil {
+		return err
+	}
+	ping, ok := v.([]interface{})
+	if !ok || len(ping) < 4 {
+		return errors.New("invalid PING message")
+	}
+	if name, _ := toString(ping[0]); name != "PING" {
+		return fmt.Errorf("expected PING message, got %q", name)
+	}
+	clientHostname, _ := toBytes(ping[1])
+	salt, _ := toBytes(ping[2])
+	clientDigest, _ := toBytes(ping[3])
+
+	expected := digest(salt, clientHostname, nonce, []byte(sharedKey))
+	authenticated := subtle.ConstantTimeCompare(clientDigest, []byte(expected)) == 1
+
+	var reason string
+	if !authenticated {
+		reason = "shared_key mismatch"
+	}
+
+	e.Reset()
+	e.EncodeArrayLen(5)
+	e.EncodeString("PONG")
+	e.EncodeBool(authenticated)
+	e.EncodeString(reason)
+	e.EncodeString(hostname)
+	e.EncodeString(digest(salt, []byte(hostname), nonce, []byte(sharedKey)))
+	if _, err := w.Write(e.Bytes()); err != nil {
+		return err
+	}
+
+	if !authenticated {
+		return fmt.Errorf("authentication of %q failed: %s", clientHostname, reason)
+	}
+	return nil
+}
+
+// ClientHandshake authenticates to the server with the shared key, by
+// answering the HELO message of the server with PING and verifying the PONG.
+func ClientHandshake(w io.Writer, d *Decoder, hostname, sharedKey string) error {
+	v, err := d.Decode()
+	if err != nil {
+		return err
+	}
+	helo, ok := v.([]interface{})
+	if !ok || len(helo) < 2 {
+		return errors.New("invalid HELO message")
+	}
+	if name, _ := toString(helo[0]); name != "HELO" {
+		return fmt.Errorf("expected HELO message, got %q", name)
+	}
+	options, _ := helo[1].(map[string]interface{})
+	nonce, ok := toBytes(options["nonce"])
+	if !ok {
+		return errors.New("HELO message has no nonce")
+	}
+	if auth, _ := toBytes(options["auth"]); len(auth) > 0 {
+		return errors.New("user authentication is not supported")
+	}
+
+	salt, err := randomBytes(16)
+	if err != nil {
+		return err
+	}
+	salt = []byte(hex.EncodeToString(salt))
+
+	e := &Encoder{}
+	e.EncodeArrayLen(6)
+	e.EncodeString("PING")
+	e.EncodeString(hostname)
+	e.EncodeBytes(salt)
+	e.EncodeString(digest(salt, []byte(hostname), nonce, []byte(sharedKey)))
+	e.EncodeString("")
+	e.EncodeString("")
+	if _, err := w.Write(e.Bytes()); err != nil {
+		return err
+	}
+
+	v, err = d.Decode()
+	if err != nil {
+		return err
+	}
+	pong, ok := v.([]interface{})
+	if !ok || len(pong) < 5 {
+		return errors.New("invalid PONG message")
+	}
+	if name, _ := toString(pong[0]); name != "PONG" {
+		return fmt.Errorf("expected PONG message, got %q", name)
+	}
+	if authenticated, _ := pong[1].(bool); !authenticated {
+		reason, _ := toString(pong[2])
+		return fmt.Errorf("authentication failed: %s", reason)
+	}
+
+	serverHostname, _ := toBytes(pong[3])
+	serverDigest, _ := toBytes(pong[4])
+	expected := digest(salt, serverHostname, nonce, []byte(sharedKey))
+	if subtle.ConstantTimeCompare(serverDigest, []byte(expected)) != 1 {
+		return errors.New("shared_key mismatch in PONG message")
+	}
+	return nil
+}
+
+func toString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case []byte:
+		return string(s), true
+	default:
+		return "", false
+	}
+}
+
+func toBytes(v interface{}) ([]byte, bool) {
+	switch b := v.(type) {
+	case string:
+		return []byte(b), true
+	case []byte:
+		return b, true
+	default:
+		return nil, false
+	}
+}
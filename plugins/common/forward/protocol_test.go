@@ -0,0 +1,162 @@
+package forward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	values := []interface{}{
+		nil,
+		true,
+		false,
+		int64(0),
+		int64(-1),
+		int64(-33),
+		int64(-200),
+		int64(-40000),
+		int64(-3000000000),
+		int64(127),
+		int64(200),
+		int64(70000),
+		int64(5000000000),
+		uint64(18446744073709551615),
+		1.5,
+		"",
+		"short",
+		string(bytes.Repeat([]byte("a"), 300)),
+		[]byte{1, 2, 3},
+		[]interface{}{int64(1), "two"},
+		map[string]interface{}{"a": int64(1), "b": []interface{}{"c"}},
+		time.Unix(1234567890, 123456789),
+	}
+
+	e := &Encoder{}
+	for _, v := range values {
+		require.NoError(t, e.Encode(v))
+	}
+
+	d := NewDecoder(bytes.NewReader(e.Bytes()))
+	for _, expected := range values {
+		v, err := d.Decode()
+		require.NoError(t, err)
+		if ts, ok := expected.(time.Time); ok {
+			require.True(t, ts.Equal(v.(time.Time)))
+			continue
+		}
+		require.Equal(t, expected, v)
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	e := &Encoder{}
+	require.NoError(t, e.Encode([]interface{}{"tag", int64(1)}))
+
+	b := e.Bytes()
+	d := NewDecoder(bytes.NewReader(b[:len(b)-1]))
+	_, err := d.Decode()
+	require.Error(t, err)
+}
+
+func encodeEntry(e *Encoder, ts time.Time, record map[string]interface{}) {
+	e.EncodeArrayLen(2)
+	e.EncodeEventTime(ts)
+	e.Encode(record)
+}
+
+func TestParseMessageModes(t *testing.T) {
+	now := time.Unix(1500000000, 42)
+	record := map[string]interface{}{"value": int64(1)}
+
+	var entries Encoder
+	encodeEntry(&entries, now, record)
+	encodeEntry(&entries, now, record)
+
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write(entries.Bytes())
+	w.Close()
+
+	tests := []struct {
+		name    string
+		message []interface{}
+		entries int
+		chunk   string
+	}{
+		{
+			name:    "message",
+			message: []interface{}{"tag", int64(now.Unix()), record, map[string]interface{}{"chunk": "abc"}},
+			entries: 1,
+			chunk:   "abc",
+		},
+		{
+			name: "forward",
+			message: []interface{}{"tag", []interface{}{
+				[]interface{}{now, record},
+				[]interface{}{now, record},
+				[]interface{}{now, record},
+			}},
+			entries: 3,
+		},
+		{
+			name:    "packed forward",
+			message: []interface{}{"tag", entries.Bytes(), map[string]interface{}{"size": int64(2)}},
+			entries: 2,
+		},
+		{
+			name:    "compressed packed forward",
+			message: []interface{}{"tag", compressed.Bytes(), map[string]interface{}{"compressed": "gzip", "chunk": "def"}},
+			entries: 2,
+			chunk:   "def",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseMessage(tt.message)
+			require.NoError(t, err)
+			require.Equal(t, "tag", msg.Tag)
+			require.Equal(t, tt.chunk, msg.Chunk)
+			require.Len(t, msg.Entries, tt.entries)
+			require.Equal(t, record, msg.Entries[0].Record)
+		})
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	tests := []struct {
+		name      string
+		clientKey string
+		serverKey string
+		success   bool
+	}{
+		{name: "matching key", clientKey: "secret", serverKey: "secret", success: true},
+		{name: "key mismatch", clientKey: "secret", serverKey: "other", success: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			defer client.Close()
+
+			errs := make(chan error, 1)
+			go func() {
+				errs <- ServerHandshake(server, NewDecoder(server), "server", tt.serverKey)
+			}()
+
+			err := ClientHandshake(client, NewDecoder(client), "client", tt.clientKey)
+			serverErr := <-errs
+			if tt.success {
+				require.NoError(t, err)
+				require.NoError(t, serverErr)
+			} else {
+				require.Error(t, err)
+				require.Error(t, serverErr)
+			}
+		})
+	}
+}
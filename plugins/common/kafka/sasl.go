@@ -1,9 +1,12 @@
 package kafka
 
 import (
+	"context"
 	"errors"
 
 	"github.com/Shopify/sarama"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
 func SASLVersion(kafkaVersion sarama.KafkaVersion, saslVersion *int) (int16, error) {
@@ -23,3 +26,34 @@ func SASLVersion(kafkaVersion sarama.KafkaVersion, saslVersion *int) (int16, err
 		return 0, errors.New("invalid SASL version")
 	}
 }
+
+// OAuthTokenProvider provides the tokens of the SASL/OAUTHBEARER mechanism,
+// obtained with the OAuth2 client credentials grant.  The token is cached
+// and a new one is requested once it expires, as the brokers authenticate
+// the new connections.
+type OAuthTokenProvider struct {
+	source     oauth2.TokenSource
+	extensions map[string]string
+}
+
+func NewOAuthTokenProvider(tokenURL, clientID, clientSecret string, scopes []string, extensions map[string]string) *OAuthTokenProvider {
+	config := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL,
+		Scopes:       scopes,
+	}
+	return &OAuthTokenProvider{
+		source:     config.TokenSource(context.Background()),
+		extensions: extensions,
+	}
+}
+
+// Token implements sarama.AccessTokenProvider.
+func (p *OAuthTokenProvider) Token() (*sarama.AccessToken, error) {
+	token, err := p.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	return &sarama.AccessToken{Token: token.AccessToken, Extensions: p.extensions}, nil
+}
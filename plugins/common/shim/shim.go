@@ -0,0 +1,233 @@
+// Package shim runs Telegraf plugins as external processes of the execd
+// plugins.  The metrics are exchanged in the InfluxDB line protocol over
+// stdin and stdout, and the control messages are line protocol comments.
+//
+// An aggregator receives the metrics to aggregate on stdin.  At the end of
+// each period Telegraf sends PushSignal, the aggregator writes its
+// aggregates to stdout followed by PushedSignal, then Telegraf sends
+// ResetSignal to start the next period.
+package shim
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+const (
+	// PushSignal asks the aggregator to push its aggregates, it is followed
+	// by the end of the period as a Unix timestamp in nanoseconds.
+	PushSignal = "# telegraf:push"
+	// PushedSignal follows the aggregates of a push.
+	PushedSignal = "# telegraf:pushed"
+	// ResetSignal asks the aggregator to reset its aggregates.
+	ResetSignal = "# telegraf:reset"
+)
+
+// maxLineSize is the maximum size of the lines read from stdin.
+const maxLineSize = 1024 * 1024
+
+// Shim runs a plugin with stdin and stdout.
+type Shim struct {
+	aggregator telegraf.Aggregator
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// New returns a shim using the stdin, stdout and stderr of the process.
+func New() *Shim {
+	return &Shim{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+}
+
+// AddAggregator sets the aggregator to run, initializing it if it is a
+// telegraf.Initializer.
+func (s *Shim) AddAggregator(a telegraf.Aggregator) error {
+	if s.aggregator != nil {
+		return errors.New("only one aggregator can be run")
+	}
+
+	setLogger(a, &logger{w: s.stderr})
+	if p, ok := a.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			return fmt.Errorf("initializing aggregator failed: %v", err)
+		}
+	}
+	s.aggregator = a
+	return nil
+}
+
+// Run runs the plugin until stdin is closed.
+func (s *Shim) Run() error {
+	if s.aggregator == nil {
+		return errors.New("no plugin added")
+	}
+
+	parser := influx.NewParser(influx.NewMetricHandler())
+	out := bufio.NewWriter(s.stdout)
+	acc := &accumulator{
+		serializer: serializer.NewSerializer(),
+		w:          out,
+		stderr:     s.stderr,
+	}
+
+	scanner := bufio.NewScanner(s.stdin)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, PushSignal):
+			s.aggregator.Push(acc)
+			if _, err := fmt.Fprintln(out, PushedSignal); err != nil {
+				return err
+			}
+			if err := out.Flush(); err != nil {
+				return err
+			}
+		case line == ResetSignal:
+			s.aggregator.Reset()
+		case line[0] == '#':
+		default:
+			m, err := parser.ParseLine(line)
+			if err != nil {
+				fmt.Fprintf(s.stderr, "E! Parsing metric failed: %v\n", err)
+				continue
+			}
+			s.aggregator.Add(m)
+		}
+	}
+	return scanner.Err()
+}
+
+// setLogger sets the Log field of the plugin, as Telegraf does.
+func setLogger(plugin interface{}, log telegraf.Logger) {
+	v := reflect.ValueOf(plugin)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	field := v.Elem().FieldByName("Log")
+	if field.IsValid() && field.CanSet() && field.Type().String() == "telegraf.Logger" {
+		field.Set(reflect.ValueOf(log))
+	}
+}
+
+// accumulator writes the metrics to stdout.
+type accumulator struct {
+	serializer *serializer.Serializer
+	w          io.Writer
+	stderr     io.Writer
+	precision  time.Duration
+}
+
+func (a *accumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Untyped, t...)
+}
+
+func (a *accumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Gauge, t...)
+}
+
+func (a *accumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Counter, t...)
+}
+
+func (a *accumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Summary, t...)
+}
+
+func (a *accumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Histogram, t...)
+}
+
+func (a *accumulator) add(measurement string, fields map[string]interface{}, tags map[string]string, tp telegraf.ValueType, t ...time.Time) {
+	tm := time.Now()
+	if len(t) > 0 {
+		tm = t[0]
+	}
+	m, err := metric.New(measurement, tags, fields, tm, tp)
+	if err != nil {
+		a.AddError(err)
+		return
+	}
+	a.AddMetric(m)
+}
+
+func (a *accumulator) AddMetric(m telegraf.Metric) {
+	if a.precision > 0 {
+		m.SetTime(m.Time().Round(a.precision))
+	}
+	b, err := a.serializer.Serialize(m)
+	if err != nil {
+		a.AddError(err)
+		return
+	}
+	if _, err := a.w.Write(b); err != nil {
+		a.AddError(err)
+	}
+}
+
+func (a *accumulator) SetPrecision(precision time.Duration) {
+	a.precision = precision
+}
+
+func (a *accumulator) AddError(err error) {
+	fmt.Fprintf(a.stderr, "E! %v\n", err)
+}
+
+func (a *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	panic("tracking is not supported by the shim")
+}
+
+// logger writes the messages of the plugin to stderr, where the execd
+// plugins log them.
+type logger struct {
+	w io.Writer
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "E! "+format+"\n", args...)
+}
+
+func (l *logger) Error(args ...interface{}) {
+	fmt.Fprintln(l.w, append([]interface{}{"E!"}, args...)...)
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "D! "+format+"\n", args...)
+}
+
+func (l *logger) Debug(args ...interface{}) {
+	fmt.Fprintln(l.w, append([]interface{}{"D!"}, args...)...)
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "W! "+format+"\n", args...)
+}
+
+func (l *logger) Warn(args ...interface{}) {
+	fmt.Fprintln(l.w, append([]interface{}{"W!"}, args...)...)
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "I! "+format+"\n", args...)
+}
+
+func (l *logger) Info(args ...interface{}) {
+	fmt.Fprintln(l.w, append([]interface{}{"I!"}, args...)...)
+}
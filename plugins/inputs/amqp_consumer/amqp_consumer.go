@@ -38,9 +38,12 @@ type AMQPConsumer struct {
 	MaxUndeliveredMessages int               `toml:"max_undelivered_messages"`
 
 	// Queue Name
-	Queue           string `toml:"queue"`
-	QueueDurability string `toml:"queue_durability"`
-	QueuePassive    bool   `toml:"queue_passive"`
+	Queue           string            `toml:"queue"`
+	QueueDurability string            `toml:"queue_durability"`
+	QueuePassive    bool              `toml:"queue_passive"`
+	QueueType       string            `toml:"queue_type"`
+	QueueArguments  map[string]string `toml:"queue_arguments"`
+	StreamOffset    string            `toml:"stream_offset"`
 
 	// Binding Key
 	BindingKey string `toml:"binding_key"`
@@ -83,6 +86,7 @@ const (
 	DefaultExchangeDurability = "durable"
 
 	DefaultQueueDurability = "durable"
+	DefaultQueueType       = "classic"
 
 	DefaultPrefetchCount = 50
 )
@@ -127,6 +131,18 @@ func (a *AMQPConsumer) SampleConfig() string {
   ## If true, queue will be passively declared.
   # queue_passive = false
 
+  ## Queue type can be "classic", "quorum" or "stream".  Quorum and stream
+  ## queues require RabbitMQ 3.8 and 3.9 respectively and are always durable.
+  # queue_type = "classic"
+
+  ## Additional queue arguments.
+  # queue_arguments = { }
+  # queue_arguments = {"x-dead-letter-exchange" = "telegraf-dlx"}
+
+  ## Offset to start consuming from when the queue_type is "stream".  Can be
+  ## "first", "last", "next" or a timestamp in RFC3339 format.
+  # stream_offset = "next"
+
   ## A binding between the exchange and queue using this binding key is
   ## created.  If unset, no binding is created.
   binding_key = "#"
@@ -219,6 +235,12 @@ func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	switch a.QueueType {
+	case "", "classic", "quorum", "stream":
+	default:
+		return fmt.Errorf("unknown queue_type %q", a.QueueType)
+	}
+
 	msgs, err := a.connect(amqpConf)
 	if err != nil {
 		return err
@@ -321,7 +343,8 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 		ch,
 		a.Queue,
 		a.QueueDurability,
-		a.QueuePassive)
+		a.QueuePassive,
+		a.queueArguments())
 	if err != nil {
 		return nil, err
 	}
@@ -348,14 +371,19 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 		return nil, fmt.Errorf("Failed to set QoS: %s", err)
 	}
 
+	consumeArgs, err := a.consumeArguments()
+	if err != nil {
+		return nil, err
+	}
+
 	msgs, err := ch.Consume(
-		q.Name, // queue
-		"",     // consumer
-		false,  // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // arguments
+		q.Name,      // queue
+		"",          // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		consumeArgs, // arguments
 	)
 	if err != nil {
 		return nil, fmt.Errorf("Failed establishing connection to queue: %s", err)
@@ -364,6 +392,38 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 	return msgs, err
 }
 
+// queueArguments returns the arguments used when declaring the queue.
+func (a *AMQPConsumer) queueArguments() amqp.Table {
+	args := make(amqp.Table, len(a.QueueArguments)+1)
+	for k, v := range a.QueueArguments {
+		args[k] = v
+	}
+
+	switch a.QueueType {
+	case "quorum", "stream":
+		args["x-queue-type"] = a.QueueType
+	}
+	return args
+}
+
+// consumeArguments returns the arguments used when starting the consumer.
+func (a *AMQPConsumer) consumeArguments() (amqp.Table, error) {
+	if a.QueueType != "stream" || a.StreamOffset == "" {
+		return nil, nil
+	}
+
+	switch a.StreamOffset {
+	case "first", "last", "next":
+		return amqp.Table{"x-stream-offset": a.StreamOffset}, nil
+	}
+
+	offset, err := time.Parse(time.RFC3339, a.StreamOffset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stream_offset %q: %v", a.StreamOffset, err)
+	}
+	return amqp.Table{"x-stream-offset": offset}, nil
+}
+
 func declareExchange(
 	channel *amqp.Channel,
 	exchangeName string,
@@ -405,6 +465,7 @@ func declareQueue(
 	queueName string,
 	queueDurability string,
 	queuePassive bool,
+	queueArguments amqp.Table,
 ) (*amqp.Queue, error) {
 	var queue amqp.Queue
 	var err error
@@ -417,23 +478,28 @@ func declareQueue(
 		queueDurable = true
 	}
 
+	// Quorum and stream queues can only be declared as durable.
+	if queueArguments["x-queue-type"] != nil {
+		queueDurable = true
+	}
+
 	if queuePassive {
 		queue, err = channel.QueueDeclarePassive(
-			queueName,    // queue
-			queueDurable, // durable
-			false,        // delete when unused
-			false,        // exclusive
-			false,        // no-wait
-			nil,          // arguments
+			queueName,      // queue
+			queueDurable,   // durable
+			false,          // delete when unused
+			false,          // exclusive
+			false,          // no-wait
+			queueArguments, // arguments
 		)
 	} else {
 		queue, err = channel.QueueDeclare(
-			queueName,    // queue
-			queueDurable, // durable
-			false,        // delete when unused
-			false,        // exclusive
-			false,        // no-wait
-			nil,          // arguments
+			queueName,      // queue
+			queueDurable,   // durable
+			false,          // delete when unused
+			false,          // exclusive
+			false,          // no-wait
+			queueArguments, // arguments
 		)
 	}
 	if err != nil {
@@ -551,6 +617,7 @@ func init() {
 			ExchangeType:           DefaultExchangeType,
 			ExchangeDurability:     DefaultExchangeDurability,
 			QueueDurability:        DefaultQueueDurability,
+			QueueType:              DefaultQueueType,
 			PrefetchCount:          DefaultPrefetchCount,
 			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
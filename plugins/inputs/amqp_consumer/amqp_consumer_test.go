@@ -0,0 +1,65 @@
+package amqp_consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueArguments(t *testing.T) {
+	a := &AMQPConsumer{
+		QueueType: "quorum",
+		QueueArguments: map[string]string{
+			"x-dead-letter-exchange": "telegraf-dlx",
+		},
+	}
+	require.Equal(t, amqp.Table{
+		"x-queue-type":           "quorum",
+		"x-dead-letter-exchange": "telegraf-dlx",
+	}, a.queueArguments())
+
+	a = &AMQPConsumer{QueueType: "classic"}
+	require.Equal(t, amqp.Table{}, a.queueArguments())
+}
+
+func TestConsumeArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		consumer *AMQPConsumer
+		expected amqp.Table
+		err      bool
+	}{
+		{
+			name:     "classic queue",
+			consumer: &AMQPConsumer{QueueType: "classic", StreamOffset: "first"},
+		},
+		{
+			name:     "stream named offset",
+			consumer: &AMQPConsumer{QueueType: "stream", StreamOffset: "first"},
+			expected: amqp.Table{"x-stream-offset": "first"},
+		},
+		{
+			name:     "stream timestamp offset",
+			consumer: &AMQPConsumer{QueueType: "stream", StreamOffset: "2020-01-01T00:00:00Z"},
+			expected: amqp.Table{"x-stream-offset": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "stream invalid offset",
+			consumer: &AMQPConsumer{QueueType: "stream", StreamOffset: "yesterday"},
+			err:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := tt.consumer.consumeArguments()
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, args)
+		})
+	}
+}
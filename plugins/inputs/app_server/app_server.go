@@ -0,0 +1,284 @@
+package app_server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultStatusPath = "/status"
+
+const (
+	typePHPFPM = "phpfpm"
+	typeUWSGI  = "uwsgi"
+	typeNGINX  = "nginx"
+)
+
+// Server is an application server to gather the status of.
+type Server struct {
+	Type       string `toml:"type"`
+	URL        string `toml:"url"`
+	StatusPath string `toml:"status_path"`
+}
+
+// AppServer gathers the status of PHP-FPM, uWSGI and NGINX fronted servers,
+// such as Gunicorn, in a single schema.
+type AppServer struct {
+	Servers []*Server         `toml:"server"`
+	Timeout internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+
+	lock     sync.Mutex
+	previous map[string]requestCount
+}
+
+// requestCount is the request counter of a pool at the previous gather, for
+// the request rate.
+type requestCount struct {
+	requests int64
+	time     time.Time
+}
+
+// status is the status of a pool of workers.
+type status struct {
+	pool     string
+	busy     int64
+	idle     int64
+	total    int64
+	queue    int64
+	hasQueue bool
+	requests int64
+}
+
+var sampleConfig = `
+  ## Servers to gather the status of.  The type is one of "phpfpm",
+  ## "uwsgi" or "nginx", the latter for the stub status of NGINX in front of
+  ## servers without status page such as Gunicorn.
+  ##
+  ## The URL is an "http" or "https" URL of the status page, or a "unix" or
+  ## "tcp" address of the socket: the FastCGI socket of PHP-FPM, the stats
+  ## socket of uWSGI or an HTTP socket of NGINX, with the status at the
+  ## status path.
+  [[inputs.app_server.server]]
+    type = "phpfpm"
+    url = "unix:///run/php/php-fpm.sock"
+    # status_path = "/status"
+
+  # [[inputs.app_server.server]]
+  #   type = "uwsgi"
+  #   url = "tcp://127.0.0.1:1717"
+
+  # [[inputs.app_server.server]]
+  #   type = "nginx"
+  #   url = "http://localhost/nginx_status"
+
+  ## Duration allowed to complete the requests.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (a *AppServer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AppServer) Description() string {
+	return "Read the worker status of PHP-FPM, uWSGI and Gunicorn application servers"
+}
+
+func (a *AppServer) Init() error {
+	for _, s := range a.Servers {
+		switch s.Type {
+		case typePHPFPM, typeUWSGI, typeNGINX:
+		default:
+			return fmt.Errorf("unknown server type %q", s.Type)
+		}
+
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url %q: %v", s.URL, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "unix", "tcp":
+		default:
+			return fmt.Errorf("unsupported scheme %q of url %q", u.Scheme, s.URL)
+		}
+
+		if s.StatusPath == "" {
+			s.StatusPath = defaultStatusPath
+		}
+	}
+
+	tlsCfg, err := a.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	a.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   a.Timeout.Duration,
+	}
+	a.previous = make(map[string]requestCount)
+	return nil
+}
+
+func (a *AppServer) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, s := range a.Servers {
+		wg.Add(1)
+		go func(s *Server) {
+			defer wg.Done()
+			if err := a.gatherServer(s, acc); err != nil {
+				acc.AddError(fmt.Errorf("%s: %v", s.URL, err))
+			}
+		}(s)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (a *AppServer) gatherServer(s *Server, acc telegraf.Accumulator) error {
+	body, err := a.read(s)
+	if err != nil {
+		return err
+	}
+
+	var statuses []*status
+	switch s.Type {
+	case typePHPFPM:
+		statuses, err = parsePHPFPM(body)
+	case typeUWSGI:
+		statuses, err = parseUWSGI(body)
+	case typeNGINX:
+		statuses, err = parseNGINX(body)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, st := range statuses {
+		tags := map[string]string{
+			"type":   s.Type,
+			"server": s.URL,
+		}
+		if st.pool != "" {
+			tags["pool"] = st.pool
+		}
+
+		fields := map[string]interface{}{
+			"workers_busy":  st.busy,
+			"workers_idle":  st.idle,
+			"workers_total": st.total,
+			"requests":      st.requests,
+		}
+		if st.hasQueue {
+			fields["queue_depth"] = st.queue
+		}
+		if rate, ok := a.requestRate(s.URL+"\x00"+st.pool, st.requests, now); ok {
+			fields["requests_per_second"] = rate
+		}
+
+		acc.AddFields("app_server", fields, tags, now)
+	}
+	return nil
+}
+
+// requestRate returns the rate of the requests since the previous gather,
+// or false on the first gather or when the counter was reset.
+func (a *AppServer) requestRate(key string, requests int64, now time.Time) (float64, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	prev, ok := a.previous[key]
+	a.previous[key] = requestCount{requests: requests, time: now}
+
+	elapsed := now.Sub(prev.time).Seconds()
+	if !ok || requests < prev.requests || elapsed <= 0 {
+		return 0, false
+	}
+	return float64(requests-prev.requests) / elapsed, true
+}
+
+// read returns the status of the server.
+func (a *AppServer) read(s *Server) ([]byte, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return a.get(a.client, s.URL)
+	}
+
+	network, address := u.Scheme, u.Host
+	if network == "unix" {
+		address = u.Path
+	}
+
+	switch s.Type {
+	case typePHPFPM:
+		return fcgiGet(network, address, s.StatusPath, a.Timeout.Duration)
+	case typeNGINX:
+		// NGINX listens for HTTP on the socket.
+		client := &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, network, address)
+				},
+			},
+			Timeout: a.Timeout.Duration,
+		}
+		return a.get(client, "http://localhost"+s.StatusPath)
+	default:
+		// The stats server of uWSGI writes the stats on connection.
+		conn, err := net.DialTimeout(network, address, a.Timeout.Duration)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		if a.Timeout.Duration > 0 {
+			conn.SetDeadline(time.Now().Add(a.Timeout.Duration))
+		}
+		return ioutil.ReadAll(conn)
+	}
+}
+
+func (a *AppServer) get(client *http.Client, u string) ([]byte, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func init() {
+	inputs.Add("app_server", func() telegraf.Input {
+		return &AppServer{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
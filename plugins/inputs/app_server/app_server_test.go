@@ -0,0 +1,244 @@
+package app_server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const phpfpmStatus = `pool:                 www
+process manager:      dynamic
+start time:           01/Dec/2019:10:00:00 +0000
+start since:          1200
+accepted conn:        3021
+listen queue:         2
+max listen queue:     5
+listen queue len:     128
+idle processes:       3
+active processes:     2
+total processes:      5
+max active processes: 5
+max children reached: 0
+slow requests:        1
+`
+
+const uwsgiStatsJSON = `{
+  "version": "2.0.18",
+  "listen_queue": 4,
+  "workers": [
+    {"id": 1, "status": "busy", "requests": 100},
+    {"id": 2, "status": "idle", "requests": 50},
+    {"id": 3, "status": "cheap", "requests": 0}
+  ]
+}`
+
+const nginxStatus = `Active connections: 7
+server accepts handled requests
+ 120 120 4567
+Reading: 1 Writing: 2 Waiting: 4
+`
+
+func TestParsePHPFPM(t *testing.T) {
+	statuses, err := parsePHPFPM([]byte(phpfpmStatus))
+	require.NoError(t, err)
+	require.Equal(t, []*status{{
+		pool:     "www",
+		busy:     2,
+		idle:     3,
+		total:    5,
+		queue:    2,
+		hasQueue: true,
+		requests: 3021,
+	}}, statuses)
+
+	_, err = parsePHPFPM([]byte("File not found.\n"))
+	require.Error(t, err)
+}
+
+func TestParseUWSGI(t *testing.T) {
+	statuses, err := parseUWSGI([]byte(uwsgiStatsJSON))
+	require.NoError(t, err)
+	require.Equal(t, []*status{{
+		busy:     1,
+		idle:     1,
+		total:    3,
+		queue:    4,
+		hasQueue: true,
+		requests: 150,
+	}}, statuses)
+}
+
+func TestParseNGINX(t *testing.T) {
+	statuses, err := parseNGINX([]byte(nginxStatus))
+	require.NoError(t, err)
+	require.Equal(t, []*status{{
+		busy:     3,
+		idle:     4,
+		total:    7,
+		requests: 4567,
+	}}, statuses)
+
+	_, err = parseNGINX([]byte("Active connections: 7\n"))
+	require.Error(t, err)
+}
+
+func TestRequestRate(t *testing.T) {
+	a := &AppServer{previous: make(map[string]requestCount)}
+	now := time.Now()
+
+	_, ok := a.requestRate("server", 100, now)
+	require.False(t, ok)
+
+	rate, ok := a.requestRate("server", 150, now.Add(10*time.Second))
+	require.True(t, ok)
+	require.Equal(t, 5.0, rate)
+
+	// A restart of the server resets the counter.
+	_, ok = a.requestRate("server", 10, now.Add(20*time.Second))
+	require.False(t, ok)
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "app_server")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestGatherSockets(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// PHP-FPM speaks FastCGI on its socket.
+	phpfpmSocket := filepath.Join(dir, "php-fpm.sock")
+	phpfpmListener, err := net.Listen("unix", phpfpmSocket)
+	require.NoError(t, err)
+	defer phpfpmListener.Close()
+	go fcgi.Serve(phpfpmListener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fpm-status" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, phpfpmStatus)
+	}))
+
+	// The stats server of uWSGI writes its stats on connection.
+	uwsgiListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer uwsgiListener.Close()
+	go func() {
+		for {
+			conn, err := uwsgiListener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(uwsgiStatsJSON))
+			conn.Close()
+		}
+	}()
+
+	// NGINX serves HTTP on its socket.
+	nginxSocket := filepath.Join(dir, "nginx.sock")
+	nginxListener, err := net.Listen("unix", nginxSocket)
+	require.NoError(t, err)
+	nginxServer := &httptest.Server{
+		Listener: nginxListener,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, nginxStatus)
+		})},
+	}
+	nginxServer.Start()
+	defer nginxServer.Close()
+
+	plugin := &AppServer{
+		Servers: []*Server{
+			{Type: "phpfpm", URL: "unix://" + phpfpmSocket, StatusPath: "/fpm-status"},
+			{Type: "uwsgi", URL: "tcp://" + uwsgiListener.Addr().String()},
+			{Type: "nginx", URL: "unix://" + nginxSocket},
+		},
+	}
+	plugin.Timeout.Duration = 5 * time.Second
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"app_server",
+			map[string]string{"type": "phpfpm", "server": "unix://" + phpfpmSocket, "pool": "www"},
+			map[string]interface{}{
+				"workers_busy":  int64(2),
+				"workers_idle":  int64(3),
+				"workers_total": int64(5),
+				"queue_depth":   int64(2),
+				"requests":      int64(3021),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"app_server",
+			map[string]string{"type": "uwsgi", "server": "tcp://" + uwsgiListener.Addr().String()},
+			map[string]interface{}{
+				"workers_busy":  int64(1),
+				"workers_idle":  int64(1),
+				"workers_total": int64(3),
+				"queue_depth":   int64(4),
+				"requests":      int64(150),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"app_server",
+			map[string]string{"type": "nginx", "server": "unix://" + nginxSocket},
+			map[string]interface{}{
+				"workers_busy":  int64(3),
+				"workers_idle":  int64(4),
+				"workers_total": int64(7),
+				"requests":      int64(4567),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(),
+		testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, nginxStatus)
+	}))
+	defer server.Close()
+
+	plugin := &AppServer{Servers: []*Server{{Type: "nginx", URL: server.URL + "/nginx_status"}}}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 2)
+	require.False(t, metrics[0].HasField("requests_per_second"))
+	require.True(t, metrics[1].HasField("requests_per_second"))
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := &AppServer{Servers: []*Server{{Type: "gunicorn", URL: "http://localhost"}}}
+	require.Error(t, plugin.Init())
+
+	plugin = &AppServer{Servers: []*Server{{Type: "uwsgi", URL: "udp://localhost:1717"}}}
+	require.Error(t, plugin.Init())
+}
@@ -0,0 +1,120 @@
+package app_server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// FastCGI record types, see the FastCGI specification.
+const (
+	fcgiBeginRequest = 1
+	fcgiEndRequest   = 3
+	fcgiParams       = 4
+	fcgiStdin        = 5
+	fcgiStdout       = 6
+	fcgiStderr       = 7
+
+	fcgiResponder = 1
+	fcgiRequestID = 1
+)
+
+// fcgiGet requests the path from the FastCGI server and returns the body of
+// the response.
+func fcgiGet(network, address, path string, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	var req bytes.Buffer
+	writeRecord(&req, fcgiBeginRequest, []byte{0, fcgiResponder, 0, 0, 0, 0, 0, 0})
+	writeRecord(&req, fcgiParams, encodeParams(map[string]string{
+		"SCRIPT_NAME":     path,
+		"SCRIPT_FILENAME": path,
+		"REQUEST_METHOD":  "GET",
+		"QUERY_STRING":    "",
+		"SERVER_PROTOCOL": "HTTP/1.0",
+		"REMOTE_ADDR":     "127.0.0.1",
+	}))
+	writeRecord(&req, fcgiParams, nil)
+	writeRecord(&req, fcgiStdin, nil)
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(header[4:6]))
+		padding := int(header[6])
+		content := make([]byte, length+padding)
+		if _, err := io.ReadFull(conn, content); err != nil {
+			return nil, err
+		}
+
+		switch header[1] {
+		case fcgiStdout:
+			stdout.Write(content[:length])
+		case fcgiStderr:
+			stderr.Write(content[:length])
+		case fcgiEndRequest:
+			if stderr.Len() > 0 {
+				return nil, errors.New(stderr.String())
+			}
+			return fcgiBody(stdout.Bytes())
+		}
+	}
+}
+
+// fcgiBody returns the body of the response, after its headers.
+func fcgiBody(response []byte) ([]byte, error) {
+	for _, sep := range [][]byte{[]byte("\r\n\r\n"), []byte("\n\n")} {
+		if i := bytes.Index(response, sep); i >= 0 {
+			headers := string(response[:i])
+			if bytes.HasPrefix(response, []byte("Status:")) && !bytes.HasPrefix(response, []byte("Status: 200")) {
+				return nil, errors.New(headers)
+			}
+			return response[i+len(sep):], nil
+		}
+	}
+	return nil, errors.New("invalid FastCGI response")
+}
+
+func writeRecord(w *bytes.Buffer, recordType byte, content []byte) {
+	header := []byte{1, recordType, 0, fcgiRequestID, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(header[4:6], uint16(len(content)))
+	w.Write(header)
+	w.Write(content)
+}
+
+func encodeParams(params map[string]string) []byte {
+	var b bytes.Buffer
+	for k, v := range params {
+		writeLength(&b, len(k))
+		writeLength(&b, len(v))
+		b.WriteString(k)
+		b.WriteString(v)
+	}
+	return b.Bytes()
+}
+
+func writeLength(b *bytes.Buffer, n int) {
+	if n < 128 {
+		b.WriteByte(byte(n))
+		return
+	}
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(n)|1<<31)
+	b.Write(buf[:])
+}
@@ -0,0 +1,142 @@
+package app_server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePHPFPM parses the plain text status page of a PHP-FPM pool.
+func parsePHPFPM(body []byte) ([]*status, error) {
+	st := &status{hasQueue: true}
+	var found bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		if key == "pool" {
+			st.pool = value
+			found = true
+			continue
+		}
+
+		var field *int64
+		switch key {
+		case "active processes":
+			field = &st.busy
+		case "idle processes":
+			field = &st.idle
+		case "total processes":
+			field = &st.total
+		case "listen queue":
+			field = &st.queue
+		case "accepted conn":
+			field = &st.requests
+		default:
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q: %v", key, err)
+		}
+		*field = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no pool in the status page")
+	}
+	return []*status{st}, nil
+}
+
+// uwsgiStats are the fields of the uWSGI stats server in the schema.
+type uwsgiStats struct {
+	ListenQueue int64 `json:"listen_queue"`
+	Workers     []struct {
+		Status   string `json:"status"`
+		Requests int64  `json:"requests"`
+	} `json:"workers"`
+}
+
+// parseUWSGI parses the JSON stats of uWSGI.
+func parseUWSGI(body []byte) ([]*status, error) {
+	var stats uwsgiStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return nil, fmt.Errorf("failed to decode json payload: %v", err)
+	}
+
+	st := &status{
+		queue:    stats.ListenQueue,
+		hasQueue: true,
+		total:    int64(len(stats.Workers)),
+	}
+	for _, w := range stats.Workers {
+		switch w.Status {
+		case "busy":
+			st.busy++
+		case "idle":
+			st.idle++
+		}
+		st.requests += w.Requests
+	}
+	return []*status{st}, nil
+}
+
+// parseNGINX parses the stub status of NGINX, the connections being read or
+// written are the busy workers of the server behind NGINX and the waiting
+// connections the idle ones.
+func parseNGINX(body []byte) ([]*status, error) {
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 4 {
+		return nil, fmt.Errorf("unexpected stub status with %d lines", len(lines))
+	}
+
+	parse := func(s string) (int64, error) {
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	}
+
+	st := &status{}
+	active := strings.SplitN(lines[0], ":", 2)
+	if len(active) != 2 {
+		return nil, fmt.Errorf("invalid active connections %q", lines[0])
+	}
+	var err error
+	if st.total, err = parse(active[1]); err != nil {
+		return nil, err
+	}
+
+	counters := strings.Fields(lines[2])
+	if len(counters) != 3 {
+		return nil, fmt.Errorf("invalid counters %q", lines[2])
+	}
+	if st.requests, err = parse(counters[2]); err != nil {
+		return nil, err
+	}
+
+	// Reading: 0 Writing: 1 Waiting: 2
+	states := strings.Fields(lines[3])
+	if len(states) != 6 {
+		return nil, fmt.Errorf("invalid connection states %q", lines[3])
+	}
+	for i := 1; i < len(states); i += 2 {
+		n, err := parse(states[i])
+		if err != nil {
+			return nil, err
+		}
+		if states[i-1] == "Waiting:" {
+			st.idle += n
+		} else {
+			st.busy += n
+		}
+	}
+	return []*status{st}, nil
+}
@@ -0,0 +1,293 @@
+package arrow_flight_sql
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Address of the Flight SQL service, use the "grpc+tls" scheme to connect
+  ## with TLS.
+  address = "grpc://localhost:32010"
+
+  ## Credentials sent with each request, either as bearer token or as basic
+  ## authentication.
+  # token = ""
+  # username = ""
+  # password = ""
+
+  ## Additional gRPC headers sent with each request, for example the
+  ## database of InfluxDB 3.
+  # [inputs.arrow_flight_sql.headers]
+  #   database = "telegraf"
+
+  ## Timeout for executing each query and receiving its result.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Queries to execute, each row of the result is converted to a metric.
+  [[inputs.arrow_flight_sql.query]]
+    ## SQL query to execute.
+    query = "SELECT * FROM system_stats"
+
+    ## Measurement name of the metrics, or the column containing it.
+    # measurement = "arrow_flight_sql"
+    # measurement_column = ""
+
+    ## Timestamp, date or integer column containing the time of the metrics.
+    ## Integers are interpreted with the given time_precision.  If not set,
+    ## the time of the query is used.
+    # time_column = ""
+    # time_precision = "1ns"
+
+    ## Columns added as tags, all other columns are added as fields.
+    # tag_columns_include = []
+
+    ## Columns added as fields, by default all columns that are not tags.
+    # field_columns_include = []
+    # field_columns_exclude = []
+`
+
+// Query is a query executed each interval.
+type Query struct {
+	Query               string            `toml:"query"`
+	Measurement         string            `toml:"measurement"`
+	MeasurementColumn   string            `toml:"measurement_column"`
+	TimeColumn          string            `toml:"time_column"`
+	TimePrecision       internal.Duration `toml:"time_precision"`
+	TagColumnsInclude   []string          `toml:"tag_columns_include"`
+	FieldColumnsInclude []string          `toml:"field_columns_include"`
+	FieldColumnsExclude []string          `toml:"field_columns_exclude"`
+
+	tagFilter   filter.Filter
+	fieldFilter filter.Filter
+}
+
+type queryClient interface {
+	query(ctx context.Context, query string) ([]*batch, error)
+	close() error
+}
+
+// ArrowFlightSQL is an input plugin polling databases with Flight SQL.
+type ArrowFlightSQL struct {
+	Address  string            `toml:"address"`
+	Token    string            `toml:"token"`
+	Username string            `toml:"username"`
+	Password string            `toml:"password"`
+	Headers  map[string]string `toml:"headers"`
+	Timeout  internal.Duration `toml:"timeout"`
+	Queries  []*Query          `toml:"query"`
+	tls.ClientConfig
+
+	Log telegraf.Logger
+
+	client queryClient
+}
+
+func (a *ArrowFlightSQL) Description() string {
+	return "Read metrics from SQL queries executed with Arrow Flight SQL"
+}
+
+func (a *ArrowFlightSQL) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *ArrowFlightSQL) Init() error {
+	if a.Token != "" && a.Username != "" {
+		return fmt.Errorf("only one of token and username can be set")
+	}
+
+	for _, q := range a.Queries {
+		if q.Query == "" {
+			return fmt.Errorf("query must be set")
+		}
+		if q.Measurement == "" {
+			q.Measurement = "arrow_flight_sql"
+		}
+		if q.TimePrecision.Duration <= 0 {
+			q.TimePrecision.Duration = time.Nanosecond
+		}
+
+		var err error
+		q.tagFilter, err = filter.Compile(q.TagColumnsInclude)
+		if err != nil {
+			return fmt.Errorf("error compiling tag_columns_include: %v", err)
+		}
+		q.fieldFilter, err = filter.NewIncludeExcludeFilter(q.FieldColumnsInclude, q.FieldColumnsExclude)
+		if err != nil {
+			return fmt.Errorf("error compiling field columns filter: %v", err)
+		}
+	}
+	return nil
+}
+
+// metadata returns the gRPC headers sent with each request.
+func (a *ArrowFlightSQL) metadata() []string {
+	var md []string
+	switch {
+	case a.Token != "":
+		md = append(md, "authorization", "Bearer "+a.Token)
+	case a.Username != "":
+		auth := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+		md = append(md, "authorization", "Basic "+auth)
+	}
+	for k, v := range a.Headers {
+		md = append(md, strings.ToLower(k), v)
+	}
+	return md
+}
+
+func (a *ArrowFlightSQL) Start(_ telegraf.Accumulator) error {
+	tlsConfig, err := a.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := newFlightClient(context.Background(), a.Address, tlsConfig, a.metadata())
+	if err != nil {
+		return err
+	}
+	a.client = client
+	return nil
+}
+
+func (a *ArrowFlightSQL) Stop() {
+	if a.client != nil {
+		a.client.close()
+	}
+}
+
+func (a *ArrowFlightSQL) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, q := range a.Queries {
+		wg.Add(1)
+		go func(q *Query) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), a.Timeout.Duration)
+			defer cancel()
+
+			now := time.Now()
+			batches, err := a.client.query(ctx, q.Query)
+			if err != nil {
+				acc.AddError(fmt.Errorf("query %q failed: %v", q.Query, err))
+				return
+			}
+			for _, b := range batches {
+				a.addBatch(acc, q, b, now)
+			}
+		}(q)
+	}
+	wg.Wait()
+	return nil
+}
+
+// addBatch adds a metric for each row of the batch.
+func (a *ArrowFlightSQL) addBatch(acc telegraf.Accumulator, q *Query, b *batch, now time.Time) {
+	for row := 0; row < b.length; row++ {
+		name := q.Measurement
+		tags := make(map[string]string)
+		fields := make(map[string]interface{})
+		t := now
+
+		for i, f := range b.fields {
+			if row >= len(b.columns[i]) {
+				continue
+			}
+			v := b.columns[i][row]
+			if v == nil {
+				continue
+			}
+
+			switch {
+			case f.name == q.MeasurementColumn:
+				if s, ok := v.(string); ok {
+					name = s
+				}
+			case f.name == q.TimeColumn:
+				ts, err := toTime(v, q.TimePrecision.Duration)
+				if err != nil {
+					acc.AddError(fmt.Errorf("column %q: %v", f.name, err))
+					continue
+				}
+				t = ts
+			case q.tagFilter != nil && q.tagFilter.Match(f.name):
+				tags[f.name] = tagValue(v)
+			case q.fieldFilter.Match(f.name):
+				if ts, ok := v.(time.Time); ok {
+					v = ts.UnixNano()
+				}
+				fields[f.name] = v
+			}
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+		m, err := metric.New(name, tags, fields, t)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		acc.AddMetric(m)
+	}
+}
+
+func toTime(v interface{}, precision time.Duration) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.Unix(0, v*int64(precision)), nil
+	case uint64:
+		return time.Unix(0, int64(v)*int64(precision)), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported time type %T", v)
+	}
+}
+
+func tagValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func init() {
+	inputs.Add("arrow_flight_sql", func() telegraf.Input {
+		return &ArrowFlightSQL{
+			Address: "grpc://localhost:32010",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
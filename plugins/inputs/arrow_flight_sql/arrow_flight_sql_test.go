@@ -0,0 +1,160 @@
+package arrow_flight_sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct {
+	batches map[string][]*batch
+}
+
+func (c *fakeClient) query(_ context.Context, query string) ([]*batch, error) {
+	batches, ok := c.batches[query]
+	if !ok {
+		return nil, errors.New("table not found")
+	}
+	return batches, nil
+}
+
+func (c *fakeClient) close() error {
+	return nil
+}
+
+func fields(names ...string) []*arrowField {
+	var fields []*arrowField
+	for _, name := range names {
+		fields = append(fields, &arrowField{name: name})
+	}
+	return fields
+}
+
+func TestGather(t *testing.T) {
+	plugin := &ArrowFlightSQL{
+		Timeout: internal.Duration{Duration: time.Second},
+		Queries: []*Query{
+			{
+				Query:             "SELECT * FROM cpu",
+				MeasurementColumn: "name",
+				TimeColumn:        "time",
+				TagColumnsInclude: []string{"host"},
+			},
+			{
+				Query:               "SELECT * FROM mem",
+				Measurement:         "memory",
+				TimeColumn:          "ts",
+				TimePrecision:       internal.Duration{Duration: time.Second},
+				FieldColumnsExclude: []string{"ignored"},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	plugin.client = &fakeClient{
+		batches: map[string][]*batch{
+			"SELECT * FROM cpu": {
+				{
+					fields: fields("name", "host", "time", "usage", "count"),
+					columns: [][]interface{}{
+						{"cpu", "cpu"},
+						{"a", nil},
+						{time.Unix(10, 0), time.Unix(20, 0)},
+						{1.5, nil},
+						{int64(1), uint64(2)},
+					},
+					length: 2,
+				},
+			},
+			"SELECT * FROM mem": {
+				{
+					fields: fields("ts", "used", "ignored", "empty"),
+					columns: [][]interface{}{
+						{int64(30)},
+						{int64(42)},
+						{"x"},
+						{nil},
+					},
+					length: 1,
+				},
+			},
+		},
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 1.5, "count": int64(1)},
+			time.Unix(10, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"count": uint64(2)},
+			time.Unix(20, 0),
+		),
+		testutil.MustMetric(
+			"memory",
+			map[string]string{},
+			map[string]interface{}{"used": int64(42)},
+			time.Unix(30, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestGatherQueryError(t *testing.T) {
+	plugin := &ArrowFlightSQL{
+		Queries: []*Query{{Query: "SELECT * FROM missing"}},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	plugin.client = &fakeClient{}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "table not found")
+}
+
+func TestMetadata(t *testing.T) {
+	plugin := &ArrowFlightSQL{
+		Username: "user",
+		Password: "pass",
+		Headers:  map[string]string{"Database": "telegraf"},
+	}
+	require.Equal(t, []string{
+		"authorization", "Basic dXNlcjpwYXNz",
+		"database", "telegraf",
+	}, plugin.metadata())
+}
+
+func TestFlightInfoUnmarshal(t *testing.T) {
+	var endpoint []byte
+	endpoint = appendBytes(endpoint, 1, appendBytes(nil, 1, []byte("ticket-1")))
+	endpoint = appendBytes(endpoint, 2, appendBytes(nil, 1, []byte("grpc+tls://node1:443")))
+
+	var b []byte
+	b = appendBytes(b, 1, []byte("schema"))
+	b = appendBytes(b, 3, endpoint)
+	b = appendKey(b, 4, wireVarint)
+	b = appendVarint(b, 10)
+
+	info := &flightInfo{}
+	require.NoError(t, info.Unmarshal(b))
+	require.Equal(t, []flightEndpoint{
+		{ticket: []byte("ticket-1"), locations: []string{"grpc+tls://node1:443"}},
+	}, info.endpoints)
+}
@@ -0,0 +1,317 @@
+package arrow_flight_sql
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	methodGetFlightInfo = "/arrow.flight.protocol.FlightService/GetFlightInfo"
+	methodDoGet         = "/arrow.flight.protocol.FlightService/DoGet"
+
+	commandStatementQueryURL = "type.googleapis.com/arrow.flight.protocol.sql.CommandStatementQuery"
+	reuseConnectionScheme    = "arrow-flight-reuse-connection"
+
+	// Record batches are sent in single gRPC messages which may exceed the
+	// default limit of 4MB.
+	maxMessageSize = 128 * 1024 * 1024
+)
+
+// Protocol buffer wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// The messages of the Flight protocol are encoded by hand to avoid depending
+// on the generated code of the Arrow module, they implement the Marshaler and
+// Unmarshaler interfaces used by the gRPC codec.
+
+func appendKey(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendKey(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// readFields calls fn for each length-delimited field of the message and
+// skips all other fields.
+func readFields(b []byte, fn func(field int, v []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("truncated message")
+		}
+		b = b[n:]
+
+		field, wireType := int(key>>3), int(key&7)
+		switch wireType {
+		case wireVarint:
+			_, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errors.New("truncated message")
+			}
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		case wireBytes:
+			l, m := binary.Uvarint(b)
+			if m <= 0 || uint64(len(b)-m) < l {
+				return errors.New("truncated message")
+			}
+			if err := fn(field, b[m:m+int(l)]); err != nil {
+				return err
+			}
+			n = m + int(l)
+		default:
+			return fmt.Errorf("unsupported wire type %d", wireType)
+		}
+		if n > len(b) {
+			return errors.New("truncated message")
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// flightDescriptor is a command descriptor of a flight.
+type flightDescriptor struct {
+	cmd []byte
+}
+
+func (m *flightDescriptor) Reset()         { *m = flightDescriptor{} }
+func (m *flightDescriptor) String() string { return "FlightDescriptor" }
+func (m *flightDescriptor) ProtoMessage()  {}
+
+func (m *flightDescriptor) Marshal() ([]byte, error) {
+	// The type is CMD
+	b := appendKey(nil, 1, wireVarint)
+	b = appendVarint(b, 2)
+	return appendBytes(b, 2, m.cmd), nil
+}
+
+// newStatementQuery returns the descriptor of a Flight SQL query, which is
+// a CommandStatementQuery packed in a google.protobuf.Any.
+func newStatementQuery(query string) *flightDescriptor {
+	command := appendBytes(nil, 1, []byte(query))
+
+	var any []byte
+	any = appendBytes(any, 1, []byte(commandStatementQueryURL))
+	any = appendBytes(any, 2, command)
+	return &flightDescriptor{cmd: any}
+}
+
+type flightEndpoint struct {
+	ticket    []byte
+	locations []string
+}
+
+// flightInfo contains the endpoints to retrieve the result of a query.
+type flightInfo struct {
+	endpoints []flightEndpoint
+}
+
+func (m *flightInfo) Reset()         { *m = flightInfo{} }
+func (m *flightInfo) String() string { return "FlightInfo" }
+func (m *flightInfo) ProtoMessage()  {}
+
+func (m *flightInfo) Unmarshal(b []byte) error {
+	return readFields(b, func(field int, v []byte) error {
+		if field != 3 {
+			return nil
+		}
+
+		var endpoint flightEndpoint
+		err := readFields(v, func(field int, v []byte) error {
+			switch field {
+			case 1:
+				// Ticket message
+				return readFields(v, func(field int, v []byte) error {
+					if field == 1 {
+						endpoint.ticket = v
+					}
+					return nil
+				})
+			case 2:
+				// Location message
+				return readFields(v, func(field int, v []byte) error {
+					if field == 1 {
+						endpoint.locations = append(endpoint.locations, string(v))
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		m.endpoints = append(m.endpoints, endpoint)
+		return nil
+	})
+}
+
+type ticket struct {
+	ticket []byte
+}
+
+func (m *ticket) Reset()         { *m = ticket{} }
+func (m *ticket) String() string { return "Ticket" }
+func (m *ticket) ProtoMessage()  {}
+
+func (m *ticket) Marshal() ([]byte, error) {
+	return appendBytes(nil, 1, m.ticket), nil
+}
+
+// flightData is an Arrow IPC message with its body.
+type flightData struct {
+	header []byte
+	body   []byte
+}
+
+func (m *flightData) Reset()         { *m = flightData{} }
+func (m *flightData) String() string { return "FlightData" }
+func (m *flightData) ProtoMessage()  {}
+
+func (m *flightData) Unmarshal(b []byte) error {
+	return readFields(b, func(field int, v []byte) error {
+		switch field {
+		case 2:
+			m.header = v
+		case 1000:
+			m.body = v
+		}
+		return nil
+	})
+}
+
+// flightClient executes queries using Flight SQL.
+type flightClient struct {
+	conn      *grpc.ClientConn
+	tlsConfig *tls.Config
+	metadata  []string
+}
+
+func dial(ctx context.Context, location string, tlsConfig *tls.Config) (*grpc.ClientConn, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize)),
+	}
+	switch u.Scheme {
+	case "grpc", "grpc+tcp":
+		opts = append(opts, grpc.WithInsecure())
+	case "grpc+tls":
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	default:
+		return nil, fmt.Errorf("unsupported location scheme %q", u.Scheme)
+	}
+	return grpc.DialContext(ctx, u.Host, opts...)
+}
+
+func newFlightClient(ctx context.Context, address string, tlsConfig *tls.Config, md []string) (*flightClient, error) {
+	conn, err := dial(ctx, address, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &flightClient{conn: conn, tlsConfig: tlsConfig, metadata: md}, nil
+}
+
+func (c *flightClient) close() error {
+	return c.conn.Close()
+}
+
+// query executes the query and returns the record batches of all endpoints.
+func (c *flightClient) query(ctx context.Context, query string) ([]*batch, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, c.metadata...)
+
+	info := &flightInfo{}
+	if err := c.conn.Invoke(ctx, methodGetFlightInfo, newStatementQuery(query), info); err != nil {
+		return nil, err
+	}
+
+	var batches []*batch
+	for _, endpoint := range info.endpoints {
+		b, err := c.doGet(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, b...)
+	}
+	return batches, nil
+}
+
+func (c *flightClient) doGet(ctx context.Context, endpoint flightEndpoint) ([]*batch, error) {
+	// Without locations the data is retrieved from the same service.  Any of
+	// the locations can be used, only the first one is tried.
+	conn := c.conn
+	if len(endpoint.locations) > 0 && !strings.HasPrefix(endpoint.locations[0], reuseConnectionScheme+":") {
+		var err error
+		conn, err = dial(ctx, endpoint.locations[0], c.tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+	}
+
+	desc := &grpc.StreamDesc{StreamName: "DoGet", ServerStreams: true}
+	stream, err := conn.NewStream(ctx, desc, methodDoGet)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(&ticket{ticket: endpoint.ticket}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	var batches []*batch
+	decoder := &streamDecoder{}
+	for {
+		data := &flightData{}
+		err := stream.RecvMsg(data)
+		if err == io.EOF {
+			return batches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := decoder.decode(data.header, data.body)
+		if err != nil {
+			return nil, err
+		}
+		if b != nil {
+			batches = append(batches, b)
+		}
+	}
+}
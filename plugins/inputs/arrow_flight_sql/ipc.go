@@ -0,0 +1,660 @@
+package arrow_flight_sql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+)
+
+// Arrow IPC message headers, see Message.fbs of the Arrow format.
+const (
+	headerSchema          = 1
+	headerDictionaryBatch = 2
+	headerRecordBatch     = 3
+)
+
+// Arrow data types, see Schema.fbs of the Arrow format.
+const (
+	typeNull            = 1
+	typeInt             = 2
+	typeFloatingPoint   = 3
+	typeBinary          = 4
+	typeUtf8            = 5
+	typeBool            = 6
+	typeDecimal         = 7
+	typeDate            = 8
+	typeTime            = 9
+	typeTimestamp       = 10
+	typeInterval        = 11
+	typeList            = 12
+	typeStruct          = 13
+	typeFixedSizeBinary = 15
+	typeFixedSizeList   = 16
+	typeMap             = 17
+	typeDuration        = 18
+	typeLargeBinary     = 19
+	typeLargeUtf8       = 20
+	typeLargeList       = 21
+)
+
+// Body compression codecs
+const (
+	codecLZ4Frame = 0
+	codecZstd     = 1
+)
+
+// fbTable is a table of a flatbuffer.
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+func fbRoot(buf []byte) fbTable {
+	return fbTable{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+// offset returns the position of the field relative to the table, or 0 if
+// the field is not set.
+func (t fbTable) offset(field int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	vtableLen := int(binary.LittleEndian.Uint16(t.buf[vtable:]))
+	entry := 4 + 2*field
+	if entry+2 > vtableLen {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint16(t.buf[vtable+entry:]))
+}
+
+func (t fbTable) uint8(field int, def uint8) uint8 {
+	if o := t.offset(field); o != 0 {
+		return t.buf[t.pos+o]
+	}
+	return def
+}
+
+func (t fbTable) bool(field int) bool {
+	return t.uint8(field, 0) != 0
+}
+
+func (t fbTable) int16(field int, def int16) int16 {
+	if o := t.offset(field); o != 0 {
+		return int16(binary.LittleEndian.Uint16(t.buf[t.pos+o:]))
+	}
+	return def
+}
+
+func (t fbTable) int32(field int, def int32) int32 {
+	if o := t.offset(field); o != 0 {
+		return int32(binary.LittleEndian.Uint32(t.buf[t.pos+o:]))
+	}
+	return def
+}
+
+func (t fbTable) int64(field int, def int64) int64 {
+	if o := t.offset(field); o != 0 {
+		return int64(binary.LittleEndian.Uint64(t.buf[t.pos+o:]))
+	}
+	return def
+}
+
+// indirect follows the offset stored at pos.
+func (t fbTable) indirect(pos int) int {
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbTable) table(field int) (fbTable, bool) {
+	o := t.offset(field)
+	if o == 0 {
+		return fbTable{}, false
+	}
+	return fbTable{buf: t.buf, pos: t.indirect(t.pos + o)}, true
+}
+
+// vector returns the position of the first element and the length of a
+// vector field.
+func (t fbTable) vector(field int) (int, int) {
+	o := t.offset(field)
+	if o == 0 {
+		return 0, 0
+	}
+	start := t.indirect(t.pos + o)
+	return start + 4, int(binary.LittleEndian.Uint32(t.buf[start:]))
+}
+
+func (t fbTable) string(field int) string {
+	start, n := t.vector(field)
+	if n == 0 {
+		return ""
+	}
+	return string(t.buf[start : start+n])
+}
+
+func (t fbTable) tables(field int) []fbTable {
+	start, n := t.vector(field)
+	tables := make([]fbTable, 0, n)
+	for i := 0; i < n; i++ {
+		tables = append(tables, fbTable{buf: t.buf, pos: t.indirect(start + 4*i)})
+	}
+	return tables
+}
+
+// arrowType is the data type of a column.
+type arrowType struct {
+	id        uint8
+	bitWidth  int
+	signed    bool
+	precision int16
+	unit      int16
+	scale     int
+}
+
+// arrowField is a column of the schema.
+type arrowField struct {
+	name       string
+	typ        arrowType
+	dictionary int64
+	indexType  *arrowType
+	children   []*arrowField
+}
+
+func parseType(id uint8, t fbTable) arrowType {
+	typ := arrowType{id: id}
+	switch id {
+	case typeInt:
+		typ.bitWidth = int(t.int32(0, 0))
+		typ.signed = t.bool(1)
+	case typeFloatingPoint:
+		typ.precision = t.int16(0, 0)
+	case typeDecimal:
+		typ.precision = int16(t.int32(0, 0))
+		typ.scale = int(t.int32(1, 0))
+		typ.bitWidth = int(t.int32(2, 128))
+	case typeDate:
+		typ.unit = t.int16(0, 1)
+	case typeTimestamp:
+		typ.unit = t.int16(0, 0)
+	}
+	return typ
+}
+
+func parseField(t fbTable) *arrowField {
+	f := &arrowField{name: t.string(0)}
+
+	typeTable, _ := t.table(3)
+	f.typ = parseType(t.uint8(2, 0), typeTable)
+
+	if dict, ok := t.table(4); ok {
+		f.dictionary = dict.int64(0, 0)
+		index := arrowType{id: typeInt, bitWidth: 32, signed: true}
+		if indexTable, ok := dict.table(1); ok {
+			index = parseType(typeInt, indexTable)
+		}
+		f.indexType = &index
+	}
+
+	for _, child := range t.tables(5) {
+		f.children = append(f.children, parseField(child))
+	}
+	return f
+}
+
+// batch is a decoded record batch, the columns are ordered as the fields
+// and contain nil for null values.
+type batch struct {
+	fields  []*arrowField
+	columns [][]interface{}
+	length  int
+}
+
+type fieldNode struct {
+	length    int
+	nullCount int
+}
+
+// recordReader reads the nodes and buffers of a record batch in order.
+type recordReader struct {
+	nodes   []fieldNode
+	buffers [][2]int64
+	body    []byte
+	codec   int
+	zstd    *zstd.Decoder
+}
+
+func (r *recordReader) node() (fieldNode, error) {
+	if len(r.nodes) == 0 {
+		return fieldNode{}, errors.New("missing field node")
+	}
+	n := r.nodes[0]
+	r.nodes = r.nodes[1:]
+	return n, nil
+}
+
+func (r *recordReader) buffer() ([]byte, error) {
+	if len(r.buffers) == 0 {
+		return nil, errors.New("missing buffer")
+	}
+	offset, length := r.buffers[0][0], r.buffers[0][1]
+	r.buffers = r.buffers[1:]
+
+	if offset < 0 || length < 0 || offset+length > int64(len(r.body)) {
+		return nil, errors.New("buffer out of range of the message body")
+	}
+	buf := r.body[offset : offset+length]
+	if r.codec < 0 || len(buf) == 0 {
+		return buf, nil
+	}
+
+	// Compressed buffers are prefixed with the uncompressed length, -1
+	// means the buffer is not compressed.
+	if len(buf) < 8 {
+		return nil, errors.New("compressed buffer too short")
+	}
+	uncompressed := int64(binary.LittleEndian.Uint64(buf))
+	if uncompressed == -1 {
+		return buf[8:], nil
+	}
+
+	var data []byte
+	var err error
+	switch r.codec {
+	case codecLZ4Frame:
+		data, err = ioutil.ReadAll(lz4.NewReader(bytes.NewReader(buf[8:])))
+	case codecZstd:
+		if r.zstd == nil {
+			r.zstd, err = zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		data, err = r.zstd.DecodeAll(buf[8:], nil)
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %d", r.codec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != uncompressed {
+		return nil, errors.New("invalid length of decompressed buffer")
+	}
+	return data, nil
+}
+
+// column decodes the values of a field, values of unsupported types are
+// returned as nil.
+func (r *recordReader) column(f *arrowField, dictionaries map[int64][]interface{}) ([]interface{}, error) {
+	node, err := r.node()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.indexType != nil {
+		indices, err := r.values(*f.indexType, node)
+		if err != nil {
+			return nil, err
+		}
+		dictionary, ok := dictionaries[f.dictionary]
+		if !ok {
+			return nil, fmt.Errorf("unknown dictionary %d", f.dictionary)
+		}
+		for i, index := range indices {
+			var idx int64
+			switch v := index.(type) {
+			case int64:
+				idx = v
+			case uint64:
+				idx = int64(v)
+			default:
+				continue
+			}
+			if idx < 0 || idx >= int64(len(dictionary)) {
+				return nil, fmt.Errorf("index %d out of range of dictionary %d", idx, f.dictionary)
+			}
+			indices[i] = dictionary[idx]
+		}
+		return indices, nil
+	}
+
+	switch f.typ.id {
+	case typeNull:
+		return make([]interface{}, node.length), nil
+	case typeList, typeLargeList, typeMap, typeFixedSizeList, typeStruct:
+		// Nested types are skipped, but their buffers and children have to be
+		// consumed.
+		buffers := 2
+		if f.typ.id == typeFixedSizeList || f.typ.id == typeStruct {
+			buffers = 1
+		}
+		for i := 0; i < buffers; i++ {
+			if _, err := r.buffer(); err != nil {
+				return nil, err
+			}
+		}
+		for _, child := range f.children {
+			if _, err := r.column(child, dictionaries); err != nil {
+				return nil, err
+			}
+		}
+		return make([]interface{}, node.length), nil
+	}
+	return r.values(f.typ, node)
+}
+
+// values decodes the buffers of a column of a primitive or binary type.
+func (r *recordReader) values(typ arrowType, node fieldNode) ([]interface{}, error) {
+	validity, err := r.buffer()
+	if err != nil {
+		return nil, err
+	}
+	valid := func(i int) bool {
+		if node.nullCount == 0 || len(validity) == 0 {
+			return true
+		}
+		return i/8 < len(validity) && validity[i/8]&(1<<uint(i%8)) != 0
+	}
+
+	var offsets []byte
+	switch typ.id {
+	case typeBinary, typeUtf8, typeLargeBinary, typeLargeUtf8:
+		offsets, err = r.buffer()
+		if err != nil {
+			return nil, err
+		}
+	case typeInt, typeFloatingPoint, typeBool, typeDecimal, typeDate, typeTime,
+		typeTimestamp, typeInterval, typeFixedSizeBinary, typeDuration:
+	default:
+		return nil, fmt.Errorf("unsupported column type %d", typ.id)
+	}
+
+	data, err := r.buffer()
+	if err != nil {
+		return nil, err
+	}
+
+	width := typ.bitWidth / 8
+	switch typ.id {
+	case typeFloatingPoint:
+		width = 2 << uint(typ.precision)
+	case typeDate:
+		width = 4 << uint(typ.unit)
+	case typeTimestamp:
+		width = 8
+	}
+
+	values := make([]interface{}, node.length)
+	for i := range values {
+		if !valid(i) {
+			continue
+		}
+
+		switch typ.id {
+		case typeBool:
+			if i/8 >= len(data) {
+				return nil, errTruncated
+			}
+			values[i] = data[i/8]&(1<<uint(i%8)) != 0
+			continue
+		case typeBinary, typeUtf8, typeLargeBinary, typeLargeUtf8:
+			start, end, err := valueOffsets(typ.id, offsets, i)
+			if err != nil {
+				return nil, err
+			}
+			if start > end || end > len(data) {
+				return nil, errTruncated
+			}
+			if typ.id == typeUtf8 || typ.id == typeLargeUtf8 {
+				values[i] = string(data[start:end])
+			}
+			continue
+		case typeTime, typeInterval, typeFixedSizeBinary, typeDuration:
+			// Not converted to metrics
+			continue
+		}
+
+		if width <= 0 || (i+1)*width > len(data) {
+			return nil, errTruncated
+		}
+		v := data[i*width : (i+1)*width]
+
+		switch typ.id {
+		case typeInt:
+			values[i] = intValue(v, typ.signed)
+		case typeFloatingPoint:
+			switch width {
+			case 4:
+				values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(v)))
+			case 8:
+				values[i] = math.Float64frombits(binary.LittleEndian.Uint64(v))
+			}
+		case typeDecimal:
+			values[i] = decimalValue(v, typ.scale)
+		case typeDate:
+			if typ.unit == 0 {
+				days := int64(int32(binary.LittleEndian.Uint32(v)))
+				values[i] = time.Unix(days*24*60*60, 0).UTC()
+			} else {
+				ms := int64(binary.LittleEndian.Uint64(v))
+				values[i] = time.Unix(0, ms*int64(time.Millisecond)).UTC()
+			}
+		case typeTimestamp:
+			ts := int64(binary.LittleEndian.Uint64(v))
+			values[i] = timestampValue(ts, typ.unit)
+		}
+	}
+	return values, nil
+}
+
+var errTruncated = errors.New("buffer too short for column values")
+
+func valueOffsets(id uint8, offsets []byte, i int) (int, int, error) {
+	if id == typeLargeBinary || id == typeLargeUtf8 {
+		if (i+2)*8 > len(offsets) {
+			return 0, 0, errTruncated
+		}
+		return int(binary.LittleEndian.Uint64(offsets[i*8:])),
+			int(binary.LittleEndian.Uint64(offsets[(i+1)*8:])), nil
+	}
+	if (i+2)*4 > len(offsets) {
+		return 0, 0, errTruncated
+	}
+	return int(int32(binary.LittleEndian.Uint32(offsets[i*4:]))),
+		int(int32(binary.LittleEndian.Uint32(offsets[(i+1)*4:]))), nil
+}
+
+func intValue(v []byte, signed bool) interface{} {
+	var u uint64
+	switch len(v) {
+	case 1:
+		if signed {
+			return int64(int8(v[0]))
+		}
+		u = uint64(v[0])
+	case 2:
+		if signed {
+			return int64(int16(binary.LittleEndian.Uint16(v)))
+		}
+		u = uint64(binary.LittleEndian.Uint16(v))
+	case 4:
+		if signed {
+			return int64(int32(binary.LittleEndian.Uint32(v)))
+		}
+		u = uint64(binary.LittleEndian.Uint32(v))
+	default:
+		if signed {
+			return int64(binary.LittleEndian.Uint64(v))
+		}
+		u = binary.LittleEndian.Uint64(v)
+	}
+	return u
+}
+
+// decimalValue converts a little-endian two's complement decimal to a float.
+func decimalValue(v []byte, scale int) float64 {
+	be := make([]byte, len(v))
+	for i := range v {
+		be[len(v)-1-i] = v[i]
+	}
+	n := new(big.Int).SetBytes(be)
+	if v[len(v)-1]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(v))))
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f / math.Pow10(scale)
+}
+
+func timestampValue(ts int64, unit int16) time.Time {
+	switch unit {
+	case 0:
+		return time.Unix(ts, 0).UTC()
+	case 1:
+		return time.Unix(0, ts*int64(time.Millisecond)).UTC()
+	case 2:
+		return time.Unix(0, ts*int64(time.Microsecond)).UTC()
+	default:
+		return time.Unix(0, ts).UTC()
+	}
+}
+
+// streamDecoder decodes the IPC messages of a stream: a schema followed by
+// dictionary and record batches.
+type streamDecoder struct {
+	fields       []*arrowField
+	dictionaries map[int64][]interface{}
+}
+
+// decode decodes a message with its body, returning the record batch or nil
+// for schema and dictionary messages.
+func (d *streamDecoder) decode(header, body []byte) (b *batch, err error) {
+	if len(header) < 4 {
+		return nil, errors.New("message header too short")
+	}
+
+	// The flatbuffer offsets are not validated, reading out of range of the
+	// message is reported as an invalid message.
+	defer func() {
+		if r := recover(); r != nil {
+			b, err = nil, fmt.Errorf("invalid message: %v", r)
+		}
+	}()
+
+	msg := fbRoot(header)
+	content, ok := msg.table(2)
+	if !ok {
+		return nil, errors.New("message has no header")
+	}
+
+	switch msg.uint8(1, 0) {
+	case headerSchema:
+		d.fields = make([]*arrowField, 0)
+		for _, f := range content.tables(1) {
+			d.fields = append(d.fields, parseField(f))
+		}
+		d.dictionaries = make(map[int64][]interface{})
+		return nil, nil
+	case headerDictionaryBatch:
+		return nil, d.decodeDictionary(content, body)
+	case headerRecordBatch:
+		if d.fields == nil {
+			return nil, errors.New("record batch before schema")
+		}
+		columns, length, err := d.decodeRecordBatch(content, body, d.fields)
+		if err != nil {
+			return nil, err
+		}
+		return &batch{fields: d.fields, columns: columns, length: length}, nil
+	default:
+		// Tensors are ignored
+		return nil, nil
+	}
+}
+
+func (d *streamDecoder) decodeDictionary(t fbTable, body []byte) error {
+	id := t.int64(0, 0)
+
+	var field *arrowField
+	for _, f := range d.allFields(d.fields) {
+		if f.indexType != nil && f.dictionary == id {
+			field = f
+			break
+		}
+	}
+	if field == nil {
+		return fmt.Errorf("dictionary %d not in schema", id)
+	}
+
+	data, ok := t.table(1)
+	if !ok {
+		return fmt.Errorf("dictionary %d has no data", id)
+	}
+
+	// The dictionary batch has a single column of the value type.
+	valueField := &arrowField{name: field.name, typ: field.typ, children: field.children}
+	columns, _, err := d.decodeRecordBatch(data, body, []*arrowField{valueField})
+	if err != nil {
+		return err
+	}
+
+	if t.bool(2) {
+		d.dictionaries[id] = append(d.dictionaries[id], columns[0]...)
+	} else {
+		d.dictionaries[id] = columns[0]
+	}
+	return nil
+}
+
+func (d *streamDecoder) allFields(fields []*arrowField) []*arrowField {
+	var all []*arrowField
+	for _, f := range fields {
+		all = append(all, f)
+		all = append(all, d.allFields(f.children)...)
+	}
+	return all
+}
+
+func (d *streamDecoder) decodeRecordBatch(t fbTable, body []byte, fields []*arrowField) ([][]interface{}, int, error) {
+	r := &recordReader{body: body, codec: -1}
+
+	start, n := t.vector(1)
+	for i := 0; i < n; i++ {
+		pos := start + 16*i
+		r.nodes = append(r.nodes, fieldNode{
+			length:    int(binary.LittleEndian.Uint64(t.buf[pos:])),
+			nullCount: int(binary.LittleEndian.Uint64(t.buf[pos+8:])),
+		})
+	}
+
+	start, n = t.vector(2)
+	for i := 0; i < n; i++ {
+		pos := start + 16*i
+		r.buffers = append(r.buffers, [2]int64{
+			int64(binary.LittleEndian.Uint64(t.buf[pos:])),
+			int64(binary.LittleEndian.Uint64(t.buf[pos+8:])),
+		})
+	}
+
+	if compression, ok := t.table(3); ok {
+		r.codec = int(compression.uint8(0, 0))
+	}
+	defer func() {
+		if r.zstd != nil {
+			r.zstd.Close()
+		}
+	}()
+
+	columns := make([][]interface{}, 0, len(fields))
+	for _, f := range fields {
+		values, err := r.column(f, d.dictionaries)
+		if err != nil {
+			return nil, 0, fmt.Errorf("column %q: %v", f.name, err)
+		}
+		columns = append(columns, values)
+	}
+	return columns, int(t.int64(0, 0)), nil
+}
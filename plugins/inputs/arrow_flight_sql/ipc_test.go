@@ -0,0 +1,350 @@
+package arrow_flight_sql
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// fbBuilder builds flatbuffers back to front, references are the distance
+// of the object from the end of the buffer.
+type fbBuilder struct {
+	buf []byte
+}
+
+func (b *fbBuilder) prepend(data []byte) int {
+	b.buf = append(append([]byte{}, data...), b.buf...)
+	return len(b.buf)
+}
+
+func (b *fbBuilder) prependUint32(v uint32) int {
+	var data [4]byte
+	binary.LittleEndian.PutUint32(data[:], v)
+	return b.prepend(data[:])
+}
+
+func (b *fbBuilder) string(s string) int {
+	b.prepend(append([]byte(s), 0))
+	return b.prependUint32(uint32(len(s)))
+}
+
+func (b *fbBuilder) tableVector(refs []int) int {
+	for i := len(refs) - 1; i >= 0; i-- {
+		b.prependUint32(uint32(len(b.buf) + 4 - refs[i]))
+	}
+	return b.prependUint32(uint32(len(refs)))
+}
+
+func (b *fbBuilder) structVector(data []byte, n int) int {
+	b.prepend(data)
+	return b.prependUint32(uint32(n))
+}
+
+// fbField is a field of a table, either a scalar or a reference.
+type fbField struct {
+	index  int
+	scalar []byte
+	ref    int
+}
+
+func scalar(index int, v interface{}) fbField {
+	var data []byte
+	switch v := v.(type) {
+	case uint8:
+		data = []byte{v}
+	case bool:
+		if v {
+			data = []byte{1}
+		} else {
+			data = []byte{0}
+		}
+	case int16:
+		data = make([]byte, 2)
+		binary.LittleEndian.PutUint16(data, uint16(v))
+	case int32:
+		data = make([]byte, 4)
+		binary.LittleEndian.PutUint32(data, uint32(v))
+	case int64:
+		data = make([]byte, 8)
+		binary.LittleEndian.PutUint64(data, uint64(v))
+	}
+	return fbField{index: index, scalar: data}
+}
+
+func ref(index int, r int) fbField {
+	return fbField{index: index, ref: r}
+}
+
+func (b *fbBuilder) table(fields ...fbField) int {
+	maxIndex := -1
+	end := len(b.buf)
+	positions := make(map[int]int)
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.scalar != nil {
+			positions[f.index] = b.prepend(f.scalar)
+		} else {
+			positions[f.index] = b.prependUint32(uint32(len(b.buf) + 4 - f.ref))
+		}
+		if f.index > maxIndex {
+			maxIndex = f.index
+		}
+	}
+	tablePos := b.prependUint32(0)
+
+	vtable := make([]byte, 4+2*(maxIndex+1))
+	binary.LittleEndian.PutUint16(vtable, uint16(len(vtable)))
+	binary.LittleEndian.PutUint16(vtable[2:], uint16(tablePos-end))
+	for index, pos := range positions {
+		binary.LittleEndian.PutUint16(vtable[4+2*index:], uint16(tablePos-pos))
+	}
+	vtablePos := b.prepend(vtable)
+
+	// Patch the offset from the table to the vtable
+	i := len(b.buf) - tablePos
+	binary.LittleEndian.PutUint32(b.buf[i:], uint32(vtablePos-tablePos))
+	return tablePos
+}
+
+func (b *fbBuilder) finish(root int) []byte {
+	b.prependUint32(uint32(len(b.buf) + 4 - root))
+	return b.buf
+}
+
+// Helpers building Arrow messages
+
+type testField struct {
+	name       string
+	typeID     uint8
+	typeFields func(b *fbBuilder) int
+	dictionary int64
+}
+
+func intType(bitWidth int32, signed bool) func(b *fbBuilder) int {
+	return func(b *fbBuilder) int {
+		return b.table(scalar(0, bitWidth), scalar(1, signed))
+	}
+}
+
+func floatType(precision int16) func(b *fbBuilder) int {
+	return func(b *fbBuilder) int {
+		return b.table(scalar(0, precision))
+	}
+}
+
+func timestampType(unit int16) func(b *fbBuilder) int {
+	return func(b *fbBuilder) int {
+		return b.table(scalar(0, unit))
+	}
+}
+
+func emptyType(b *fbBuilder) int {
+	return b.table()
+}
+
+func schemaMessage(fields []testField) []byte {
+	b := &fbBuilder{}
+	var refs []int
+	for _, f := range fields {
+		var dict int
+		if f.dictionary != 0 {
+			index := intType(32, true)(b)
+			dict = b.table(scalar(0, f.dictionary), ref(1, index))
+		}
+		typ := f.typeFields(b)
+		name := b.string(f.name)
+		tableFields := []fbField{ref(0, name), scalar(1, true), scalar(2, f.typeID), ref(3, typ)}
+		if dict != 0 {
+			tableFields = append(tableFields, ref(4, dict))
+		}
+		refs = append(refs, b.table(tableFields...))
+	}
+	fieldVector := b.tableVector(refs)
+	schema := b.table(ref(1, fieldVector))
+	msg := b.table(scalar(0, int16(4)), scalar(1, uint8(headerSchema)), ref(2, schema))
+	return b.finish(msg)
+}
+
+// testColumn is a column of a record batch, with the buffers in order.
+type testColumn struct {
+	length    int
+	nullCount int
+	buffers   [][]byte
+}
+
+func recordBatch(b *fbBuilder, length int, columns []testColumn, codec int) (int, []byte) {
+	var nodes, buffers, body []byte
+	for _, c := range columns {
+		node := make([]byte, 16)
+		binary.LittleEndian.PutUint64(node, uint64(c.length))
+		binary.LittleEndian.PutUint64(node[8:], uint64(c.nullCount))
+		nodes = append(nodes, node...)
+
+		for _, data := range c.buffers {
+			if codec == codecZstd && len(data) > 0 {
+				encoder, _ := zstd.NewWriter(nil)
+				compressed := make([]byte, 8)
+				binary.LittleEndian.PutUint64(compressed, uint64(len(data)))
+				data = encoder.EncodeAll(data, compressed)
+			}
+			buffer := make([]byte, 16)
+			binary.LittleEndian.PutUint64(buffer, uint64(len(body)))
+			binary.LittleEndian.PutUint64(buffer[8:], uint64(len(data)))
+			buffers = append(buffers, buffer...)
+			body = append(body, data...)
+		}
+	}
+
+	var compression int
+	if codec >= 0 {
+		compression = b.table(scalar(0, uint8(codec)))
+	}
+	bufferVector := b.structVector(buffers, len(buffers)/16)
+	nodeVector := b.structVector(nodes, len(nodes)/16)
+	fields := []fbField{scalar(0, int64(length)), ref(1, nodeVector), ref(2, bufferVector)}
+	if codec >= 0 {
+		fields = append(fields, ref(3, compression))
+	}
+	return b.table(fields...), body
+}
+
+func recordBatchMessage(length int, columns []testColumn, codec int) ([]byte, []byte) {
+	b := &fbBuilder{}
+	rb, body := recordBatch(b, length, columns, codec)
+	msg := b.table(scalar(0, int16(4)), scalar(1, uint8(headerRecordBatch)), ref(2, rb), scalar(3, int64(len(body))))
+	return b.finish(msg), body
+}
+
+func dictionaryBatchMessage(id int64, length int, column testColumn) ([]byte, []byte) {
+	b := &fbBuilder{}
+	rb, body := recordBatch(b, length, []testColumn{column}, -1)
+	dict := b.table(scalar(0, id), ref(1, rb))
+	msg := b.table(scalar(0, int16(4)), scalar(1, uint8(headerDictionaryBatch)), ref(2, dict), scalar(3, int64(len(body))))
+	return b.finish(msg), body
+}
+
+func int64Buffer(values ...int64) []byte {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[8*i:], uint64(v))
+	}
+	return b
+}
+
+func int32Buffer(values ...int32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(b[4*i:], uint32(v))
+	}
+	return b
+}
+
+func float64Buffer(values ...float64) []byte {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[8*i:], math.Float64bits(v))
+	}
+	return b
+}
+
+func stringBuffers(values ...string) ([]byte, []byte) {
+	var data []byte
+	offsets := []int32{0}
+	for _, v := range values {
+		data = append(data, v...)
+		offsets = append(offsets, int32(len(data)))
+	}
+	return int32Buffer(offsets...), data
+}
+
+var testSchema = []testField{
+	{name: "host", typeID: typeUtf8, typeFields: emptyType},
+	{name: "time", typeID: typeTimestamp, typeFields: timestampType(3)},
+	{name: "usage", typeID: typeFloatingPoint, typeFields: floatType(2)},
+	{name: "count", typeID: typeInt, typeFields: intType(64, true)},
+	{name: "ok", typeID: typeBool, typeFields: emptyType},
+}
+
+func testColumns() []testColumn {
+	offsets, data := stringBuffers("a", "b")
+	return []testColumn{
+		{length: 2, buffers: [][]byte{nil, offsets, data}},
+		{length: 2, buffers: [][]byte{nil, int64Buffer(1000, 2000)}},
+		{length: 2, buffers: [][]byte{nil, float64Buffer(1.5, 2.5)}},
+		// The second value is null
+		{length: 2, nullCount: 1, buffers: [][]byte{{0x01}, int64Buffer(42, 0)}},
+		{length: 2, buffers: [][]byte{nil, {0x02}}},
+	}
+}
+
+func TestDecodeRecordBatch(t *testing.T) {
+	for _, codec := range []int{-1, codecZstd} {
+		d := &streamDecoder{}
+		b, err := d.decode(schemaMessage(testSchema), nil)
+		require.NoError(t, err)
+		require.Nil(t, b)
+
+		b, err = d.decode(recordBatchMessage(2, testColumns(), codec))
+		require.NoError(t, err)
+		require.NotNil(t, b)
+
+		require.Equal(t, 2, b.length)
+		require.Len(t, b.fields, 5)
+		require.Equal(t, "usage", b.fields[2].name)
+		require.Equal(t, [][]interface{}{
+			{"a", "b"},
+			{time.Unix(0, 1000).UTC(), time.Unix(0, 2000).UTC()},
+			{1.5, 2.5},
+			{int64(42), nil},
+			{false, true},
+		}, b.columns)
+	}
+}
+
+func TestDecodeDictionary(t *testing.T) {
+	schema := []testField{
+		{name: "region", typeID: typeUtf8, typeFields: emptyType, dictionary: 7},
+	}
+
+	d := &streamDecoder{}
+	_, err := d.decode(schemaMessage(schema), nil)
+	require.NoError(t, err)
+
+	offsets, data := stringBuffers("east", "west")
+	_, err = d.decode(dictionaryBatchMessage(7, 2, testColumn{length: 2, buffers: [][]byte{nil, offsets, data}}))
+	require.NoError(t, err)
+
+	b, err := d.decode(recordBatchMessage(3, []testColumn{
+		{length: 3, buffers: [][]byte{nil, int32Buffer(1, 0, 1)}},
+	}, -1))
+	require.NoError(t, err)
+	require.Equal(t, [][]interface{}{{"west", "east", "west"}}, b.columns)
+}
+
+func TestDecodeInvalidMessage(t *testing.T) {
+	d := &streamDecoder{}
+	_, err := d.decode(schemaMessage(testSchema), nil)
+	require.NoError(t, err)
+
+	header, body := recordBatchMessage(2, testColumns(), -1)
+	_, err = d.decode(header, body[:len(body)-1])
+	require.Error(t, err)
+
+	_, err = d.decode(header[:len(header)/2], body)
+	require.Error(t, err)
+}
+
+func TestDecimalValue(t *testing.T) {
+	v := make([]byte, 16)
+	binary.LittleEndian.PutUint64(v, uint64(12345))
+	require.Equal(t, 123.45, decimalValue(v, 2))
+
+	// -12345 in two's complement
+	binary.LittleEndian.PutUint64(v, uint64(math.MaxUint64-12344))
+	binary.LittleEndian.PutUint64(v[8:], math.MaxUint64)
+	require.Equal(t, -123.45, decimalValue(v, 2))
+}
@@ -0,0 +1,250 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// resticSnapshot is a snapshot of "restic snapshots --json", the summary is
+// written by restic 0.17 and later.
+type resticSnapshot struct {
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname"`
+	Summary  *struct {
+		BackupStart         time.Time `json:"backup_start"`
+		BackupEnd           time.Time `json:"backup_end"`
+		TotalBytesProcessed int64     `json:"total_bytes_processed"`
+	} `json:"summary"`
+}
+
+// gatherRestic returns the status of the snapshots of each host of the
+// repository.
+func (b *Backup) gatherRestic(j *Job) ([]*status, error) {
+	out, err := b.run(b.Timeout.Duration, j.Environment, j.Binary,
+		"--repo", j.Repository, "--no-lock", "snapshots", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []resticSnapshot
+	if err := json.Unmarshal(out, &snapshots); err != nil {
+		return nil, fmt.Errorf("error parsing snapshots: %v", err)
+	}
+
+	var statuses []*status
+	hosts := make(map[string]*status)
+	latest := make(map[string]time.Time)
+	for _, snapshot := range snapshots {
+		s, ok := hosts[snapshot.Hostname]
+		if !ok {
+			s = &status{
+				tags: map[string]string{
+					"repository": stripCredentials(j.Repository),
+					"client":     snapshot.Hostname,
+				},
+				hasCount: true,
+			}
+			hosts[snapshot.Hostname] = s
+			statuses = append(statuses, s)
+		}
+		s.count++
+
+		if snapshot.Time.Before(latest[snapshot.Hostname]) {
+			continue
+		}
+		latest[snapshot.Hostname] = snapshot.Time
+		s.lastSuccess = snapshot.Time
+		s.duration = 0
+		s.hasSize = false
+		if summary := snapshot.Summary; summary != nil {
+			s.lastSuccess = summary.BackupEnd
+			s.duration = summary.BackupEnd.Sub(summary.BackupStart)
+			s.size = summary.TotalBytesProcessed
+			s.hasSize = true
+		}
+	}
+	return statuses, nil
+}
+
+// borgTimeLayout is the layout of the times of borg, in local time.
+const borgTimeLayout = "2006-01-02T15:04:05.999999"
+
+// borgInfo is the output of "borg info --json".
+type borgInfo struct {
+	Archives []struct {
+		Start    string  `json:"start"`
+		End      string  `json:"end"`
+		Duration float64 `json:"duration"`
+		Stats    struct {
+			OriginalSize int64 `json:"original_size"`
+		} `json:"stats"`
+	} `json:"archives"`
+}
+
+// gatherBorg returns the status of the last archive of the repository,
+// borg does not count the archives without listing them.
+func (b *Backup) gatherBorg(j *Job) ([]*status, error) {
+	out, err := b.run(b.Timeout.Duration, j.Environment, j.Binary,
+		"info", "--json", "--last", "1", j.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	var info borgInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("error parsing info: %v", err)
+	}
+
+	s := &status{
+		tags: map[string]string{"repository": stripCredentials(j.Repository)},
+	}
+	for _, archive := range info.Archives {
+		end, err := time.ParseInLocation(borgTimeLayout, archive.End, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end time %q: %v", archive.End, err)
+		}
+		if end.Before(s.lastSuccess) {
+			continue
+		}
+		s.lastSuccess = end
+		s.duration = time.Duration(archive.Duration * float64(time.Second))
+		s.size = archive.Stats.OriginalSize
+		s.hasSize = true
+	}
+	return []*status{s}, nil
+}
+
+// pgBackRestStanza is a stanza of "pgbackrest info --output=json".
+type pgBackRestStanza struct {
+	Name   string `json:"name"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+	Backup []struct {
+		Timestamp struct {
+			Start int64 `json:"start"`
+			Stop  int64 `json:"stop"`
+		} `json:"timestamp"`
+		Info struct {
+			Size int64 `json:"size"`
+		} `json:"info"`
+	} `json:"backup"`
+}
+
+// gatherPgBackRest returns the status of the backups of each stanza.
+func (b *Backup) gatherPgBackRest(j *Job) ([]*status, error) {
+	args := []string{"info", "--output=json"}
+	if j.Stanza != "" {
+		args = append(args, "--stanza="+j.Stanza)
+	}
+	out, err := b.run(b.Timeout.Duration, j.Environment, j.Binary, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var stanzas []pgBackRestStanza
+	if err := json.Unmarshal(out, &stanzas); err != nil {
+		return nil, fmt.Errorf("error parsing info: %v", err)
+	}
+
+	var statuses []*status
+	for _, stanza := range stanzas {
+		s := &status{
+			tags:     map[string]string{"stanza": stanza.Name},
+			count:    len(stanza.Backup),
+			hasCount: true,
+			state:    stanza.Status.Message,
+		}
+		for _, backup := range stanza.Backup {
+			stop := time.Unix(backup.Timestamp.Stop, 0)
+			if stop.Before(s.lastSuccess) {
+				continue
+			}
+			s.lastSuccess = stop
+			s.duration = time.Duration(backup.Timestamp.Stop-backup.Timestamp.Start) * time.Second
+			s.size = backup.Info.Size
+			s.hasSize = true
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// veleroScheduleLabel is the label of the backups made by a schedule.
+const veleroScheduleLabel = "velero.io/schedule-name"
+
+const veleroPhaseCompleted = "Completed"
+
+type veleroBackup struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		CreationTimestamp time.Time         `json:"creationTimestamp"`
+		Labels            map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Status struct {
+		Phase               string    `json:"phase"`
+		StartTimestamp      time.Time `json:"startTimestamp"`
+		CompletionTimestamp time.Time `json:"completionTimestamp"`
+	} `json:"status"`
+}
+
+// veleroBackups is the output of "velero backup get -o json", a list of
+// backups or a single backup.
+type veleroBackups struct {
+	veleroBackup
+	Items []veleroBackup `json:"items"`
+}
+
+// gatherVelero returns the status of the backups of each schedule, the
+// status being the phase of the latest backup.
+func (b *Backup) gatherVelero(j *Job) ([]*status, error) {
+	args := []string{"backup", "get", "--output", "json"}
+	if j.Namespace != "" {
+		args = append(args, "--namespace", j.Namespace)
+	}
+	out, err := b.run(b.Timeout.Duration, j.Environment, j.Binary, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var list veleroBackups
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("error parsing backups: %v", err)
+	}
+	backups := list.Items
+	if len(backups) == 0 && list.Metadata.Name != "" {
+		backups = []veleroBackup{list.veleroBackup}
+	}
+
+	var statuses []*status
+	schedules := make(map[string]*status)
+	latest := make(map[string]time.Time)
+	for _, backup := range backups {
+		schedule := backup.Metadata.Labels[veleroScheduleLabel]
+		s, ok := schedules[schedule]
+		if !ok {
+			s = &status{tags: map[string]string{}, hasCount: true}
+			if schedule != "" {
+				s.tags["schedule"] = schedule
+			}
+			schedules[schedule] = s
+			statuses = append(statuses, s)
+		}
+		s.count++
+
+		if created := backup.Metadata.CreationTimestamp; !created.Before(latest[schedule]) {
+			latest[schedule] = created
+			s.state = backup.Status.Phase
+		}
+
+		completed := backup.Status.CompletionTimestamp
+		if backup.Status.Phase != veleroPhaseCompleted || completed.Before(s.lastSuccess) {
+			continue
+		}
+		s.lastSuccess = completed
+		s.duration = completed.Sub(backup.Status.StartTimestamp)
+	}
+	return statuses, nil
+}
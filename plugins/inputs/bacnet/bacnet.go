@@ -0,0 +1,524 @@
+package bacnet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	defaultPort = "47808"
+
+	// covProcessID identifies the subscriptions of the plugin.
+	covProcessID = 1
+)
+
+const sampleConfig = `
+  ## Local address of the client.  The devices may answer the discovery
+  ## requests by broadcast to the BACnet port, which must then be bound.
+  # address = ":47808"
+
+  ## Broadcast address of the discovery requests (Who-Is).
+  # broadcast_address = "255.255.255.255:47808"
+
+  ## Timeout and number of retries of the requests.
+  # timeout = "3s"
+  # retries = 2
+
+  ## Interval of the discovery of the devices of the network, reported in
+  ## the bacnet_device metric, 0 to disable it.  The devices configured
+  ## without address are located anyway.
+  # discovery_interval = "1h"
+
+  ## Devices and the objects polled.
+  [[inputs.bacnet.device]]
+    ## Instance number of the device.
+    instance = 1001
+    ## Address of the device, located with a Who-Is request if not set.
+    # address = "192.168.1.20:47808"
+    ## Name of the device, reported in the device_name tag.
+    # name = "ahu1"
+
+    ## Subscribe to the changes of value (COV) of the objects, reported as
+    ## they are notified between the polls.  The subscriptions are renewed at
+    ## half their lifetime.
+    # cov = false
+    # cov_lifetime = "10m"
+
+    [[inputs.bacnet.device.object]]
+      ## Type of the object, such as "analog-input", "analog-value",
+      ## "binary-input", "binary-value" or "multi-state-value".
+      type = "analog-input"
+      instance = 1
+      ## Name of the object, reported in the object_name tag.
+      # name = "supply_air_temperature"
+      ## Properties polled, by name or identifier.
+      # properties = ["present-value", "status-flags"]
+`
+
+// BACnet polls the properties of the objects of BACnet/IP devices.
+type BACnet struct {
+	Address           string            `toml:"address"`
+	BroadcastAddress  string            `toml:"broadcast_address"`
+	Timeout           internal.Duration `toml:"timeout"`
+	Retries           int               `toml:"retries"`
+	DiscoveryInterval internal.Duration `toml:"discovery_interval"`
+	Devices           []*Device         `toml:"device"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	conn      *net.UDPConn
+	broadcast *net.UDPAddr
+	acc       telegraf.Accumulator
+	devices   map[uint32]*Device
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	lastDiscovery time.Time
+
+	sync.Mutex
+	invokeID  byte
+	pending   map[byte]chan *apdu
+	addresses map[uint32]*net.UDPAddr
+}
+
+// Device is a device and its objects polled.
+type Device struct {
+	Instance    uint32            `toml:"instance"`
+	Address     string            `toml:"address"`
+	Name        string            `toml:"name"`
+	COV         bool              `toml:"cov"`
+	COVLifetime internal.Duration `toml:"cov_lifetime"`
+	Objects     []*Object         `toml:"object"`
+
+	addr       *net.UDPAddr
+	objects    map[objectID]*Object
+	covRenewal time.Time
+}
+
+// Object is an object and its properties polled.
+type Object struct {
+	Type       string   `toml:"type"`
+	Instance   uint32   `toml:"instance"`
+	Name       string   `toml:"name"`
+	Properties []string `toml:"properties"`
+
+	id         objectID
+	properties []uint32
+}
+
+func (b *BACnet) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BACnet) Description() string {
+	return "Poll the objects of BACnet/IP devices"
+}
+
+// resolveAddress returns the UDP address of an address, with the BACnet
+// port if it has none.
+func resolveAddress(address string) (*net.UDPAddr, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultPort)
+	}
+	return net.ResolveUDPAddr("udp4", address)
+}
+
+// propertyField returns the name of the field of a property.
+func propertyField(property uint32) string {
+	for name, id := range properties {
+		if id == property {
+			return strings.Replace(name, "-", "_", -1)
+		}
+	}
+	return "property_" + strconv.FormatUint(uint64(property), 10)
+}
+
+func (b *BACnet) Init() error {
+	if len(b.Devices) == 0 {
+		return errors.New("no device configured")
+	}
+
+	broadcast, err := resolveAddress(b.BroadcastAddress)
+	if err != nil {
+		return fmt.Errorf("invalid broadcast address: %v", err)
+	}
+	b.broadcast = broadcast
+
+	b.devices = make(map[uint32]*Device, len(b.Devices))
+	for _, d := range b.Devices {
+		if d.Instance > maxInstance {
+			return fmt.Errorf("device %d: invalid instance", d.Instance)
+		}
+		if _, ok := b.devices[d.Instance]; ok {
+			return fmt.Errorf("device %d: configured twice", d.Instance)
+		}
+		b.devices[d.Instance] = d
+
+		if d.Address != "" {
+			if d.addr, err = resolveAddress(d.Address); err != nil {
+				return fmt.Errorf("device %d: invalid address: %v", d.Instance, err)
+			}
+		}
+		if d.COVLifetime.Duration == 0 {
+			d.COVLifetime.Duration = 10 * time.Minute
+		}
+		if d.COV && d.COVLifetime.Duration < time.Minute {
+			return fmt.Errorf("device %d: cov_lifetime must be at least 1m", d.Instance)
+		}
+
+		d.objects = make(map[objectID]*Object, len(d.Objects))
+		for _, o := range d.Objects {
+			typ, ok := objectTypes[o.Type]
+			if !ok {
+				return fmt.Errorf("device %d: unknown object type %q", d.Instance, o.Type)
+			}
+			if o.Instance > maxInstance {
+				return fmt.Errorf("device %d: invalid instance of %s %d", d.Instance, o.Type, o.Instance)
+			}
+			o.id = objectID{typ: typ, instance: o.Instance}
+			d.objects[o.id] = o
+
+			if len(o.Properties) == 0 {
+				o.Properties = []string{"present-value", "status-flags"}
+			}
+			o.properties = o.properties[:0]
+			for _, p := range o.Properties {
+				id, ok := properties[p]
+				if !ok {
+					n, err := strconv.ParseUint(p, 10, 22)
+					if err != nil {
+						return fmt.Errorf("device %d: unknown property %q", d.Instance, p)
+					}
+					id = uint32(n)
+				}
+				o.properties = append(o.properties, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *BACnet) Start(acc telegraf.Accumulator) error {
+	local, err := net.ResolveUDPAddr("udp4", b.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address: %v", err)
+	}
+	b.conn, err = net.ListenUDP("udp4", local)
+	if err != nil {
+		return err
+	}
+	b.Log.Infof("Listening on %s", b.conn.LocalAddr())
+
+	b.acc = acc
+	b.pending = make(map[byte]chan *apdu)
+	b.addresses = make(map[uint32]*net.UDPAddr)
+	b.done = make(chan struct{})
+
+	b.wg.Add(1)
+	go b.read()
+	return nil
+}
+
+func (b *BACnet) Stop() {
+	// The subscriptions are cancelled, without waiting for the answers.
+	for _, d := range b.Devices {
+		if !d.COV || d.covRenewal.IsZero() {
+			continue
+		}
+		addr := b.address(d)
+		if addr == nil {
+			continue
+		}
+		for _, o := range d.Objects {
+			if _, err := b.conn.WriteToUDP(subscribeCOV(0, covProcessID, o.id, 0), addr); err != nil {
+				b.Log.Debugf("Cancelling the subscription of device %d: %v", d.Instance, err)
+				break
+			}
+		}
+	}
+
+	close(b.done)
+	b.conn.Close()
+	b.wg.Wait()
+}
+
+// read handles the messages received until the plugin is stopped.
+func (b *BACnet) read() {
+	defer b.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := b.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-b.done:
+				return
+			default:
+			}
+			b.acc.AddError(err)
+			continue
+		}
+
+		a, err := decodeAPDU(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			b.Log.Debugf("Invalid message from %s: %v", addr, err)
+			continue
+		}
+		if a == nil {
+			continue
+		}
+		if a.source != nil {
+			addr = a.source
+		}
+
+		switch a.typ {
+		case pduUnconfirmedRequest:
+			switch a.service {
+			case serviceIAm:
+				b.handleIAm(a, addr)
+			case serviceUnconfirmedCOVNotification:
+				b.handleCOVNotification(a)
+			}
+		case pduSimpleAck, pduComplexAck, pduError, pduReject, pduAbort:
+			b.Lock()
+			ch, ok := b.pending[a.invokeID]
+			b.Unlock()
+			if ok {
+				select {
+				case ch <- a:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (b *BACnet) handleIAm(a *apdu, addr *net.UDPAddr) {
+	i, err := decodeIAm(a.data)
+	if err != nil {
+		b.Log.Debugf("Invalid I-Am from %s: %v", addr, err)
+		return
+	}
+
+	b.Lock()
+	b.addresses[i.device] = addr
+	b.Unlock()
+
+	if b.DiscoveryInterval.Duration > 0 {
+		b.acc.AddFields("bacnet_device",
+			map[string]interface{}{
+				"vendor_id":    int64(i.vendorID),
+				"max_apdu":     int64(i.maxAPDU),
+				"segmentation": int64(i.segmentation),
+			},
+			map[string]string{
+				"device":  strconv.FormatUint(uint64(i.device), 10),
+				"address": addr.String(),
+			})
+	}
+}
+
+func (b *BACnet) handleCOVNotification(a *apdu) {
+	n, err := decodeCOVNotification(a.data)
+	if err != nil {
+		b.Log.Debugf("Invalid COV notification: %v", err)
+		return
+	}
+	if n.processID != covProcessID {
+		return
+	}
+	d, ok := b.devices[n.device]
+	if !ok {
+		return
+	}
+	o, ok := d.objects[n.object]
+	if !ok {
+		return
+	}
+
+	fields := make(map[string]interface{}, len(n.values))
+	for property, value := range n.values {
+		fields[propertyField(property)] = value
+	}
+	if len(fields) > 0 {
+		b.acc.AddFields("bacnet", fields, tags(d, o))
+	}
+}
+
+func tags(d *Device, o *Object) map[string]string {
+	tags := map[string]string{
+		"device":          strconv.FormatUint(uint64(d.Instance), 10),
+		"object_type":     o.Type,
+		"object_instance": strconv.FormatUint(uint64(o.Instance), 10),
+	}
+	if d.Name != "" {
+		tags["device_name"] = d.Name
+	}
+	if o.Name != "" {
+		tags["object_name"] = o.Name
+	}
+	return tags
+}
+
+func (b *BACnet) Gather(acc telegraf.Accumulator) error {
+	if b.DiscoveryInterval.Duration > 0 && time.Since(b.lastDiscovery) >= b.DiscoveryInterval.Duration {
+		b.lastDiscovery = time.Now()
+		if _, err := b.conn.WriteToUDP(whoIs(0, 0), b.broadcast); err != nil {
+			acc.AddError(fmt.Errorf("discovery: %v", err))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range b.Devices {
+		wg.Add(1)
+		go func(d *Device) {
+			defer wg.Done()
+			if err := b.gatherDevice(acc, d); err != nil {
+				acc.AddError(fmt.Errorf("device %d: %v", d.Instance, err))
+			}
+		}(d)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (b *BACnet) gatherDevice(acc telegraf.Accumulator, d *Device) error {
+	addr, err := b.locate(d)
+	if err != nil {
+		return err
+	}
+
+	if d.COV && !time.Now().Before(d.covRenewal) {
+		lifetime := uint32(d.COVLifetime.Duration / time.Second)
+		for _, o := range d.Objects {
+			_, err := b.request(addr, serviceSubscribeCOV, func(invokeID byte) []byte {
+				return subscribeCOV(invokeID, covProcessID, o.id, lifetime)
+			})
+			if err != nil {
+				acc.AddError(fmt.Errorf("device %d: subscribing to %s %d: %v", d.Instance, o.Type, o.Instance, err))
+			}
+		}
+		d.covRenewal = time.Now().Add(d.COVLifetime.Duration / 2)
+	}
+
+	for _, o := range d.Objects {
+		fields := make(map[string]interface{}, len(o.properties))
+		for i, property := range o.properties {
+			a, err := b.request(addr, serviceReadProperty, func(invokeID byte) []byte {
+				return readProperty(invokeID, o.id, property)
+			})
+			if err == nil {
+				var value interface{}
+				value, err = decodeReadPropertyAck(a.data)
+				if value != nil {
+					fields[propertyField(property)] = value
+				}
+			}
+			if err != nil {
+				acc.AddError(fmt.Errorf("device %d: reading %s of %s %d: %v", d.Instance, o.Properties[i], o.Type, o.Instance, err))
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields("bacnet", fields, tags(d, o))
+		}
+	}
+	return nil
+}
+
+// address returns the address of a device, configured or discovered.
+func (b *BACnet) address(d *Device) *net.UDPAddr {
+	if d.addr != nil {
+		return d.addr
+	}
+	b.Lock()
+	defer b.Unlock()
+	return b.addresses[d.Instance]
+}
+
+// locate returns the address of a device, the device is searched with a
+// Who-Is request if its address is unknown.
+func (b *BACnet) locate(d *Device) (*net.UDPAddr, error) {
+	if addr := b.address(d); addr != nil {
+		return addr, nil
+	}
+
+	if _, err := b.conn.WriteToUDP(whoIs(d.Instance, d.Instance), b.broadcast); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(b.Timeout.Duration)
+	for time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if addr := b.address(d); addr != nil {
+			return addr, nil
+		}
+	}
+	return nil, errors.New("device not found")
+}
+
+// request sends a confirmed request and waits for its answer, the request
+// is sent again when it times out.
+func (b *BACnet) request(addr *net.UDPAddr, service byte, encode func(invokeID byte) []byte) (*apdu, error) {
+	ch := make(chan *apdu, 1)
+	invokeID, err := b.allocateInvokeID(ch)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		b.Lock()
+		delete(b.pending, invokeID)
+		b.Unlock()
+	}()
+
+	packet := encode(invokeID)
+	for i := 0; i <= b.Retries; i++ {
+		if _, err := b.conn.WriteToUDP(packet, addr); err != nil {
+			return nil, err
+		}
+		select {
+		case a := <-ch:
+			switch {
+			case a.typ == pduError || a.typ == pduReject || a.typ == pduAbort:
+				return nil, decodeError(a)
+			case a.service != service:
+				return nil, fmt.Errorf("unexpected answer to service %d", a.service)
+			}
+			return a, nil
+		case <-time.After(b.Timeout.Duration):
+		}
+	}
+	return nil, errors.New("request timed out")
+}
+
+// allocateInvokeID returns an identifier for the answer of a request.
+func (b *BACnet) allocateInvokeID(ch chan *apdu) (byte, error) {
+	b.Lock()
+	defer b.Unlock()
+	for i := 0; i < 256; i++ {
+		b.invokeID++
+		if _, ok := b.pending[b.invokeID]; !ok {
+			b.pending[b.invokeID] = ch
+			return b.invokeID, nil
+		}
+	}
+	return 0, errors.New("too many pending requests")
+}
+
+func init() {
+	inputs.Add("bacnet", func() telegraf.Input {
+		return &BACnet{
+			Address:           ":" + defaultPort,
+			BroadcastAddress:  "255.255.255.255:" + defaultPort,
+			Timeout:           internal.Duration{Duration: 3 * time.Second},
+			Retries:           2,
+			DiscoveryInterval: internal.Duration{Duration: time.Hour},
+		}
+	})
+}
@@ -0,0 +1,349 @@
+package bacnet
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// value encodes an application tagged value.
+type value func(e *encoder)
+
+func real(v float32) value {
+	return func(e *encoder) {
+		e.tag(tagReal, false, 4)
+		e.buf = append(e.buf, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(e.buf[len(e.buf)-4:], math.Float32bits(v))
+	}
+}
+
+func enumerated(v uint32) value {
+	return func(e *encoder) {
+		e.enumerated(v)
+	}
+}
+
+// statusFlags encodes the bit string of the 4 status flags.
+func statusFlags(bits byte) value {
+	return func(e *encoder) {
+		e.tag(tagBitString, false, 2)
+		e.buf = append(e.buf, 4, bits<<4)
+	}
+}
+
+// fakeDevice answers the requests as a device with the values of the
+// properties of its objects.
+type fakeDevice struct {
+	instance uint32
+	conn     *net.UDPConn
+	values   map[objectID]map[uint32]value
+
+	sync.Mutex
+	subscribers []*net.UDPAddr
+}
+
+func newFakeDevice(t *testing.T, instance uint32, values map[objectID]map[uint32]value) *fakeDevice {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	d := &fakeDevice{instance: instance, conn: conn, values: values}
+	go d.serve()
+	return d
+}
+
+func (d *fakeDevice) addr() string {
+	return d.conn.LocalAddr().String()
+}
+
+func (d *fakeDevice) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := d.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		a, err := decodeAPDU(buf[:n])
+		if err != nil || a == nil {
+			continue
+		}
+
+		switch {
+		case a.typ == pduUnconfirmedRequest && a.service == serviceWhoIs:
+			e := newEncoder(false, false)
+			e.buf = append(e.buf, pduUnconfirmedRequest<<4, serviceIAm)
+			e.objectID(tagObjectID, false, objectID{typ: 8, instance: d.instance})
+			e.unsigned(tagUnsignedInt, false, 1476)
+			e.enumerated(3)
+			e.unsigned(tagUnsignedInt, false, 260)
+			d.conn.WriteToUDP(e.bytes(), addr)
+
+		case a.typ == pduConfirmedRequest && a.service == serviceReadProperty:
+			dec := &decoder{data: a.data}
+			t, _ := dec.contextTag(0)
+			object, _ := decodeObjectID(t.data)
+			t, _ = dec.contextTag(1)
+			property, _ := decodeUnsigned(t.data)
+
+			v, ok := d.values[object][property]
+			if !ok {
+				// The unknown-property error of the property class.
+				e := newEncoder(false, false)
+				e.buf = append(e.buf, pduError<<4, a.invokeID, serviceReadProperty)
+				e.enumerated(2)
+				e.enumerated(32)
+				d.conn.WriteToUDP(e.bytes(), addr)
+				continue
+			}
+			e := newEncoder(false, false)
+			e.buf = append(e.buf, pduComplexAck<<4, a.invokeID, serviceReadProperty)
+			e.objectID(0, true, object)
+			e.unsigned(1, true, property)
+			e.openingTag(3)
+			v(e)
+			e.closingTag(3)
+			d.conn.WriteToUDP(e.bytes(), addr)
+
+		case a.typ == pduConfirmedRequest && a.service == serviceSubscribeCOV:
+			e := newEncoder(false, false)
+			e.buf = append(e.buf, pduSimpleAck<<4, a.invokeID, serviceSubscribeCOV)
+			d.conn.WriteToUDP(e.bytes(), addr)
+
+			d.Lock()
+			d.subscribers = append(d.subscribers, addr)
+			d.Unlock()
+		}
+	}
+}
+
+// notify sends a COV notification of the present value of an object to the
+// subscribers.
+func (d *fakeDevice) notify(object objectID, v value) {
+	e := newEncoder(false, false)
+	e.buf = append(e.buf, pduUnconfirmedRequest<<4, serviceUnconfirmedCOVNotification)
+	e.unsigned(0, true, covProcessID)
+	e.objectID(1, true, objectID{typ: 8, instance: d.instance})
+	e.objectID(2, true, object)
+	e.unsigned(3, true, 300)
+	e.openingTag(4)
+	e.unsigned(0, true, properties["present-value"])
+	e.openingTag(2)
+	v(e)
+	e.closingTag(2)
+	e.unsigned(0, true, properties["status-flags"])
+	e.openingTag(2)
+	statusFlags(0x8)(e)
+	e.closingTag(2)
+	e.closingTag(4)
+
+	d.Lock()
+	defer d.Unlock()
+	for _, addr := range d.subscribers {
+		d.conn.WriteToUDP(e.bytes(), addr)
+	}
+}
+
+func newBACnet(broadcast string, devices ...*Device) *BACnet {
+	return &BACnet{
+		Address:          "127.0.0.1:0",
+		BroadcastAddress: broadcast,
+		Timeout:          internal.Duration{Duration: time.Second},
+		Retries:          1,
+		Devices:          devices,
+		Log:              testutil.Logger{},
+	}
+}
+
+func TestGather(t *testing.T) {
+	device := newFakeDevice(t, 1001, map[objectID]map[uint32]value{
+		{typ: 0, instance: 1}: {
+			properties["present-value"]: real(21.5),
+			properties["status-flags"]:  statusFlags(0),
+		},
+		{typ: 5, instance: 2}: {
+			properties["present-value"]: enumerated(1),
+		},
+	})
+	defer device.conn.Close()
+
+	b := newBACnet(device.addr(), &Device{
+		Instance: 1001,
+		Name:     "ahu1",
+		Objects: []*Object{
+			{Type: "analog-input", Instance: 1, Name: "supply_air_temperature"},
+			{Type: "binary-value", Instance: 2, Properties: []string{"present-value", "out-of-service"}},
+		},
+	})
+	require.NoError(t, b.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Start(&acc))
+	defer b.Stop()
+
+	require.NoError(t, b.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "reading out-of-service of binary-value 2: error class 2, code 32")
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"bacnet",
+			map[string]string{
+				"device":          "1001",
+				"device_name":     "ahu1",
+				"object_type":     "analog-input",
+				"object_instance": "1",
+				"object_name":     "supply_air_temperature",
+			},
+			map[string]interface{}{"present_value": 21.5, "status_flags": int64(0)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"bacnet",
+			map[string]string{
+				"device":          "1001",
+				"device_name":     "ahu1",
+				"object_type":     "binary-value",
+				"object_instance": "2",
+			},
+			map[string]interface{}{"present_value": int64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherDeviceNotFound(t *testing.T) {
+	device := newFakeDevice(t, 1001, nil)
+	defer device.conn.Close()
+
+	b := newBACnet(device.addr(), &Device{
+		Instance: 2002,
+		Objects:  []*Object{{Type: "analog-input", Instance: 1}},
+	})
+	b.Timeout.Duration = 100 * time.Millisecond
+	require.NoError(t, b.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Start(&acc))
+	defer b.Stop()
+
+	require.NoError(t, b.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "device 2002: device not found")
+}
+
+func TestDiscovery(t *testing.T) {
+	device := newFakeDevice(t, 1001, nil)
+	defer device.conn.Close()
+
+	b := newBACnet(device.addr(), &Device{
+		Instance: 1001,
+		Address:  device.addr(),
+	})
+	b.DiscoveryInterval.Duration = time.Hour
+	require.NoError(t, b.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Start(&acc))
+	defer b.Stop()
+
+	require.NoError(t, b.Gather(&acc))
+	acc.Wait(1)
+
+	m, ok := acc.Get("bacnet_device")
+	require.True(t, ok)
+	require.Equal(t, "1001", m.Tags["device"])
+	require.Equal(t, device.addr(), m.Tags["address"])
+	require.Equal(t, int64(260), m.Fields["vendor_id"])
+	require.Equal(t, int64(1476), m.Fields["max_apdu"])
+}
+
+func TestCOV(t *testing.T) {
+	object := objectID{typ: 2, instance: 3}
+	device := newFakeDevice(t, 1001, map[objectID]map[uint32]value{
+		object: {properties["present-value"]: real(50)},
+	})
+	defer device.conn.Close()
+
+	b := newBACnet(device.addr(), &Device{
+		Instance: 1001,
+		Address:  device.addr(),
+		COV:      true,
+		Objects:  []*Object{{Type: "analog-value", Instance: 3, Properties: []string{"85"}}},
+	})
+	require.NoError(t, b.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Start(&acc))
+	defer b.Stop()
+
+	require.NoError(t, b.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Equal(t, uint64(1), acc.NMetrics())
+
+	device.notify(object, real(55))
+	acc.Wait(2)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"bacnet",
+			map[string]string{"device": "1001", "object_type": "analog-value", "object_instance": "3"},
+			map[string]interface{}{"present_value": 50.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"bacnet",
+			map[string]string{"device": "1001", "object_type": "analog-value", "object_instance": "3"},
+			map[string]interface{}{"present_value": 55.0, "status_flags": int64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestApplicationValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected interface{}
+	}{
+		{"boolean", []byte{0x11}, true},
+		{"unsigned", []byte{0x22, 0x01, 0x00}, int64(256)},
+		{"signed", []byte{0x31, 0xfe}, int64(-2)},
+		{"real", []byte{0x44, 0x41, 0xac, 0x00, 0x00}, 21.5},
+		{"character string", []byte{0x75, 0x04, 0x00, 'A', 'H', 'U'}, "AHU"},
+		{"bit string", []byte{0x82, 0x04, 0x50}, int64(0x0a)},
+		{"enumerated", []byte{0x91, 0x03}, int64(3)},
+		{"date", []byte{0xa4, 0x78, 0x05, 0x0e, 0x04}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &decoder{data: tt.data}
+			tag, err := d.next()
+			require.NoError(t, err)
+			require.True(t, d.done())
+			v, err := applicationValue(tag)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, newBACnet("255.255.255.255").Init())
+	require.Error(t, newBACnet("255.255.255.255", &Device{
+		Instance: 1,
+		Objects:  []*Object{{Type: "analog-thing", Instance: 1}},
+	}).Init())
+	require.Error(t, newBACnet("255.255.255.255", &Device{
+		Instance: 1,
+		Objects:  []*Object{{Type: "analog-input", Instance: 1, Properties: []string{"present-values"}}},
+	}).Init())
+	require.Error(t, newBACnet("255.255.255.255", &Device{Instance: 1}, &Device{Instance: 1}).Init())
+}
@@ -0,0 +1,745 @@
+package bacnet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+)
+
+// The BACnet/IP virtual link layer functions.
+const (
+	bvlcType              = 0x81
+	bvlcOriginalUnicast   = 0x0a
+	bvlcOriginalBroadcast = 0x0b
+	bvlcForwardedNPDU     = 0x04
+)
+
+// The APDU types.
+const (
+	pduConfirmedRequest   = 0
+	pduUnconfirmedRequest = 1
+	pduSimpleAck          = 2
+	pduComplexAck         = 3
+	pduError              = 5
+	pduReject             = 6
+	pduAbort              = 7
+)
+
+// The services.
+const (
+	serviceIAm                        = 0x00
+	serviceUnconfirmedCOVNotification = 0x02
+	serviceWhoIs                      = 0x08
+
+	serviceSubscribeCOV = 0x05
+	serviceReadProperty = 0x0c
+)
+
+// The application tags of the values.
+const (
+	tagNull        = 0
+	tagBoolean     = 1
+	tagUnsignedInt = 2
+	tagSignedInt   = 3
+	tagReal        = 4
+	tagDouble      = 5
+	tagOctetString = 6
+	tagCharString  = 7
+	tagBitString   = 8
+	tagEnumerated  = 9
+	tagDate        = 10
+	tagTime        = 11
+	tagObjectID    = 12
+)
+
+const (
+	// maxInstance is the maximum instance number of an object.
+	maxInstance = 0x3fffff
+
+	// maxAPDU is the maximum length of the answers, up to 1476 octets.
+	maxAPDU = 0x05
+)
+
+// objectTypes are the types of the objects by name.
+var objectTypes = map[string]uint16{
+	"analog-input":       0,
+	"analog-output":      1,
+	"analog-value":       2,
+	"binary-input":       3,
+	"binary-output":      4,
+	"binary-value":       5,
+	"device":             8,
+	"multi-state-input":  13,
+	"multi-state-output": 14,
+	"multi-state-value":  19,
+}
+
+// properties are the identifiers of the properties by name.
+var properties = map[string]uint32{
+	"description":    28,
+	"event-state":    36,
+	"object-name":    77,
+	"out-of-service": 81,
+	"present-value":  85,
+	"reliability":    103,
+	"status-flags":   111,
+	"units":          117,
+}
+
+// objectID is the identifier of an object, its type and instance.
+type objectID struct {
+	typ      uint16
+	instance uint32
+}
+
+// apdu is an application layer message.
+type apdu struct {
+	typ      byte
+	invokeID byte
+	service  byte
+	data     []byte
+
+	// source is the address of the originating device of the messages
+	// forwarded by a broadcast management device.
+	source *net.UDPAddr
+}
+
+// encoder builds a message, starting with the virtual link layer and
+// network layer headers.
+type encoder struct {
+	buf []byte
+}
+
+// newEncoder starts a message to a single device, or broadcast to all the
+// devices of the network.
+func newEncoder(broadcast, expectReply bool) *encoder {
+	function := byte(bvlcOriginalUnicast)
+	if broadcast {
+		function = bvlcOriginalBroadcast
+	}
+	control := byte(0)
+	if expectReply {
+		control = 0x04
+	}
+	return &encoder{buf: []byte{bvlcType, function, 0, 0, 0x01, control}}
+}
+
+// bytes returns the message with the length of the link layer.
+func (e *encoder) bytes() []byte {
+	binary.BigEndian.PutUint16(e.buf[2:], uint16(len(e.buf)))
+	return e.buf
+}
+
+func (e *encoder) tag(number byte, context bool, length int) {
+	class := byte(0)
+	if context {
+		class = 0x08
+	}
+	switch {
+	case length <= 4:
+		e.buf = append(e.buf, number<<4|class|byte(length))
+	case length <= 253:
+		e.buf = append(e.buf, number<<4|class|5, byte(length))
+	default:
+		e.buf = append(e.buf, number<<4|class|5, 254, byte(length>>8), byte(length))
+	}
+}
+
+func (e *encoder) openingTag(number byte) {
+	e.buf = append(e.buf, number<<4|0x0e)
+}
+
+func (e *encoder) closingTag(number byte) {
+	e.buf = append(e.buf, number<<4|0x0f)
+}
+
+func unsignedBytes(v uint32) []byte {
+	switch {
+	case v < 1<<8:
+		return []byte{byte(v)}
+	case v < 1<<16:
+		return []byte{byte(v >> 8), byte(v)}
+	case v < 1<<24:
+		return []byte{byte(v >> 16), byte(v >> 8), byte(v)}
+	default:
+		return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	}
+}
+
+func (e *encoder) unsigned(number byte, context bool, v uint32) {
+	b := unsignedBytes(v)
+	e.tag(number, context, len(b))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) enumerated(v uint32) {
+	e.unsigned(tagEnumerated, false, v)
+}
+
+func (e *encoder) objectID(number byte, context bool, id objectID) {
+	e.tag(number, context, 4)
+	e.buf = append(e.buf, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(e.buf[len(e.buf)-4:], uint32(id.typ)<<22|id.instance&maxInstance)
+}
+
+func (e *encoder) contextBoolean(number byte, v bool) {
+	e.tag(number, true, 1)
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// whoIs encodes a Who-Is request for the devices in the range of instances,
+// all the devices if high is 0.
+func whoIs(low, high uint32) []byte {
+	e := newEncoder(true, false)
+	e.buf = append(e.buf, pduUnconfirmedRequest<<4, serviceWhoIs)
+	if high > 0 {
+		e.unsigned(0, true, low)
+		e.unsigned(1, true, high)
+	}
+	return e.bytes()
+}
+
+// confirmedRequest starts a confirmed request of a service.
+func confirmedRequest(invokeID, service byte) *encoder {
+	e := newEncoder(false, true)
+	e.buf = append(e.buf, pduConfirmedRequest<<4, maxAPDU, invokeID, service)
+	return e
+}
+
+// readProperty encodes a ReadProperty request of a property of an object.
+func readProperty(invokeID byte, object objectID, property uint32) []byte {
+	e := confirmedRequest(invokeID, serviceReadProperty)
+	e.objectID(0, true, object)
+	e.unsigned(1, true, property)
+	return e.bytes()
+}
+
+// subscribeCOV encodes a SubscribeCOV request for unconfirmed notifications
+// of the changes of an object, or cancels the subscription if lifetime is 0.
+func subscribeCOV(invokeID byte, processID uint32, object objectID, lifetime uint32) []byte {
+	e := confirmedRequest(invokeID, serviceSubscribeCOV)
+	e.unsigned(0, true, processID)
+	e.objectID(1, true, object)
+	if lifetime > 0 {
+		e.contextBoolean(2, false)
+		e.unsigned(3, true, lifetime)
+	}
+	return e.bytes()
+}
+
+// decodeAPDU returns the application layer message of a packet.  The network
+// layer messages are ignored, nil is returned for them.
+func decodeAPDU(packet []byte) (*apdu, error) {
+	if len(packet) < 4 || packet[0] != bvlcType {
+		return nil, errors.New("not a BACnet/IP packet")
+	}
+	if int(binary.BigEndian.Uint16(packet[2:])) != len(packet) {
+		return nil, errors.New("invalid length")
+	}
+
+	npdu := packet[4:]
+	var source *net.UDPAddr
+	switch packet[1] {
+	case bvlcOriginalUnicast, bvlcOriginalBroadcast:
+	case bvlcForwardedNPDU:
+		// The forwarded messages have the address of the originating device.
+		if len(npdu) < 6 {
+			return nil, errors.New("truncated packet")
+		}
+		source = &net.UDPAddr{
+			IP:   net.IPv4(npdu[0], npdu[1], npdu[2], npdu[3]),
+			Port: int(binary.BigEndian.Uint16(npdu[4:])),
+		}
+		npdu = npdu[6:]
+	default:
+		return nil, nil
+	}
+
+	if len(npdu) < 2 || npdu[0] != 0x01 {
+		return nil, errors.New("invalid network layer version")
+	}
+	control := npdu[1]
+	pos := 2
+	if control&0x20 != 0 {
+		// The destination network, address length and address.
+		if len(npdu) < pos+3 {
+			return nil, errors.New("truncated packet")
+		}
+		pos += 3 + int(npdu[pos+2])
+	}
+	if control&0x08 != 0 {
+		// The source network, address length and address.
+		if len(npdu) < pos+3 {
+			return nil, errors.New("truncated packet")
+		}
+		pos += 3 + int(npdu[pos+2])
+	}
+	if control&0x20 != 0 {
+		// The hop count.
+		pos++
+	}
+	if control&0x80 != 0 {
+		return nil, nil
+	}
+	if len(npdu) < pos+2 {
+		return nil, errors.New("truncated packet")
+	}
+
+	b := npdu[pos:]
+	a := &apdu{typ: b[0] >> 4, source: source}
+	switch a.typ {
+	case pduUnconfirmedRequest:
+		a.service = b[1]
+		a.data = b[2:]
+	case pduSimpleAck, pduError:
+		if len(b) < 3 {
+			return nil, errors.New("truncated packet")
+		}
+		a.invokeID, a.service, a.data = b[1], b[2], b[3:]
+	case pduComplexAck:
+		if b[0]&0x08 != 0 {
+			return nil, errors.New("segmented responses are not supported")
+		}
+		if len(b) < 3 {
+			return nil, errors.New("truncated packet")
+		}
+		a.invokeID, a.service, a.data = b[1], b[2], b[3:]
+	case pduReject, pduAbort:
+		if len(b) < 3 {
+			return nil, errors.New("truncated packet")
+		}
+		a.invokeID, a.data = b[1], b[2:3]
+	case pduConfirmedRequest:
+		if len(b) < 4 {
+			return nil, errors.New("truncated packet")
+		}
+		a.invokeID, a.service, a.data = b[2], b[3], b[4:]
+	default:
+		return nil, fmt.Errorf("unknown PDU type %d", a.typ)
+	}
+	return a, nil
+}
+
+// tag is a decoded tag and its content.
+type tag struct {
+	number  byte
+	context bool
+	opening bool
+	closing bool
+	// lvt is the length, or the value of the application booleans.
+	lvt  int
+	data []byte
+}
+
+// decoder reads the tags of the data of a message.
+type decoder struct {
+	data []byte
+}
+
+func (d *decoder) done() bool {
+	return len(d.data) == 0
+}
+
+// peek returns the next tag without consuming it.
+func (d *decoder) peek() (tag, int, error) {
+	if len(d.data) == 0 {
+		return tag{}, 0, errors.New("unexpected end of data")
+	}
+	b := d.data[0]
+	t := tag{number: b >> 4, context: b&0x08 != 0, lvt: int(b & 0x07)}
+	pos := 1
+	if t.number == 0x0f {
+		if len(d.data) < 2 {
+			return tag{}, 0, errors.New("unexpected end of data")
+		}
+		t.number = d.data[1]
+		pos++
+	}
+
+	switch {
+	case t.context && t.lvt == 6:
+		t.opening = true
+		return t, pos, nil
+	case t.context && t.lvt == 7:
+		t.closing = true
+		return t, pos, nil
+	case !t.context && t.number == tagBoolean:
+		return t, pos, nil
+	case t.lvt == 5:
+		if len(d.data) < pos+1 {
+			return tag{}, 0, errors.New("unexpected end of data")
+		}
+		t.lvt = int(d.data[pos])
+		pos++
+		if t.lvt == 254 {
+			if len(d.data) < pos+2 {
+				return tag{}, 0, errors.New("unexpected end of data")
+			}
+			t.lvt = int(binary.BigEndian.Uint16(d.data[pos:]))
+			pos += 2
+		} else if t.lvt == 255 {
+			return tag{}, 0, errors.New("unsupported length")
+		}
+	}
+	if len(d.data) < pos+t.lvt {
+		return tag{}, 0, errors.New("unexpected end of data")
+	}
+	t.data = d.data[pos : pos+t.lvt]
+	return t, pos + t.lvt, nil
+}
+
+// next consumes the next tag.
+func (d *decoder) next() (tag, error) {
+	t, n, err := d.peek()
+	if err != nil {
+		return t, err
+	}
+	d.data = d.data[n:]
+	return t, nil
+}
+
+// contextTag consumes the next tag, which must be the context tag.
+func (d *decoder) contextTag(number byte) (tag, error) {
+	t, err := d.next()
+	if err != nil {
+		return t, err
+	}
+	if !t.context || t.number != number || t.opening || t.closing {
+		return t, fmt.Errorf("expected context tag %d", number)
+	}
+	return t, nil
+}
+
+// optionalContextTag consumes the next tag if it is the context tag.
+func (d *decoder) optionalContextTag(number byte) (tag, bool, error) {
+	if d.done() {
+		return tag{}, false, nil
+	}
+	t, n, err := d.peek()
+	if err != nil {
+		return t, false, err
+	}
+	if !t.context || t.number != number || t.opening || t.closing {
+		return t, false, nil
+	}
+	d.data = d.data[n:]
+	return t, true, nil
+}
+
+// expect consumes the next tag, which must be the opening or closing tag.
+func (d *decoder) expect(number byte, opening bool) error {
+	t, err := d.next()
+	if err != nil {
+		return err
+	}
+	if !t.context || t.number != number || t.opening != opening || t.closing == opening {
+		return fmt.Errorf("expected opening or closing tag %d", number)
+	}
+	return nil
+}
+
+// values consumes the application tagged values up to the closing tag, which
+// is consumed too.
+func (d *decoder) values(number byte) ([]interface{}, error) {
+	var values []interface{}
+	for {
+		t, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		if t.context && t.closing && t.number == number {
+			return values, nil
+		}
+		if t.context {
+			// Constructed values, such as the priority arrays, are skipped.
+			if t.opening {
+				if err := d.skip(t.number); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		v, err := applicationValue(t)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			values = append(values, v)
+		}
+	}
+}
+
+// skip consumes the tags up to the closing tag.
+func (d *decoder) skip(number byte) error {
+	depth := 1
+	for {
+		t, err := d.next()
+		if err != nil {
+			return err
+		}
+		if t.opening {
+			depth++
+		}
+		if t.closing {
+			depth--
+			if depth == 0 {
+				if t.number != number {
+					return fmt.Errorf("expected closing tag %d", number)
+				}
+				return nil
+			}
+		}
+	}
+}
+
+func decodeUnsigned(data []byte) (uint32, error) {
+	if len(data) == 0 || len(data) > 4 {
+		return 0, errors.New("invalid unsigned length")
+	}
+	var v uint32
+	for _, b := range data {
+		v = v<<8 | uint32(b)
+	}
+	return v, nil
+}
+
+func decodeObjectID(data []byte) (objectID, error) {
+	if len(data) != 4 {
+		return objectID{}, errors.New("invalid object identifier length")
+	}
+	v := binary.BigEndian.Uint32(data)
+	return objectID{typ: uint16(v >> 22), instance: v & maxInstance}, nil
+}
+
+// applicationValue returns the value of an application tag as a field value,
+// nil for the values not reported such as the dates.
+func applicationValue(t tag) (interface{}, error) {
+	switch t.number {
+	case tagNull:
+		return nil, nil
+	case tagBoolean:
+		return t.lvt != 0, nil
+	case tagUnsignedInt, tagEnumerated:
+		v, err := decodeUnsigned(t.data)
+		return int64(v), err
+	case tagSignedInt:
+		if len(t.data) == 0 || len(t.data) > 4 {
+			return nil, errors.New("invalid signed length")
+		}
+		v := int64(int8(t.data[0]))
+		for _, b := range t.data[1:] {
+			v = v<<8 | int64(b)
+		}
+		return v, nil
+	case tagReal:
+		if len(t.data) != 4 {
+			return nil, errors.New("invalid real length")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(t.data))), nil
+	case tagDouble:
+		if len(t.data) != 8 {
+			return nil, errors.New("invalid double length")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(t.data)), nil
+	case tagCharString:
+		// The strings encoded in UTF-8, or its ANSI X3.4 subset, only.
+		if len(t.data) == 0 || t.data[0] != 0 {
+			return nil, nil
+		}
+		return string(t.data[1:]), nil
+	case tagBitString:
+		// The bits are reported as an integer, the first bit as the bit 0.
+		if len(t.data) == 0 || len(t.data) > 5 {
+			return nil, nil
+		}
+		var v int64
+		bits := (len(t.data)-1)*8 - int(t.data[0])
+		for i := 0; i < bits; i++ {
+			if t.data[1+i/8]&(0x80>>uint(i%8)) != 0 {
+				v |= 1 << uint(i)
+			}
+		}
+		return v, nil
+	case tagObjectID:
+		id, err := decodeObjectID(t.data)
+		if err != nil {
+			return nil, err
+		}
+		return int64(id.instance), nil
+	default:
+		// The octet strings, dates and times.
+		return nil, nil
+	}
+}
+
+// iAm is the answer of a device to a Who-Is request.
+type iAm struct {
+	device       uint32
+	maxAPDU      uint32
+	segmentation uint32
+	vendorID     uint32
+}
+
+func decodeIAm(data []byte) (*iAm, error) {
+	d := &decoder{data: data}
+	var values []tag
+	for i := 0; i < 4; i++ {
+		t, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, t)
+	}
+	if values[0].context || values[0].number != tagObjectID {
+		return nil, errors.New("expected the device identifier")
+	}
+	id, err := decodeObjectID(values[0].data)
+	if err != nil {
+		return nil, err
+	}
+	maxAPDU, err := decodeUnsigned(values[1].data)
+	if err != nil {
+		return nil, err
+	}
+	segmentation, err := decodeUnsigned(values[2].data)
+	if err != nil {
+		return nil, err
+	}
+	vendorID, err := decodeUnsigned(values[3].data)
+	if err != nil {
+		return nil, err
+	}
+	return &iAm{device: id.instance, maxAPDU: maxAPDU, segmentation: segmentation, vendorID: vendorID}, nil
+}
+
+// decodeReadPropertyAck returns the value of the property of the answer to
+// a ReadProperty request, the first value if the property is a list.
+func decodeReadPropertyAck(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+	if _, err := d.contextTag(0); err != nil {
+		return nil, err
+	}
+	if _, err := d.contextTag(1); err != nil {
+		return nil, err
+	}
+	if _, _, err := d.optionalContextTag(2); err != nil {
+		return nil, err
+	}
+	if err := d.expect(3, true); err != nil {
+		return nil, err
+	}
+	values, err := d.values(3)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values[0], nil
+}
+
+// covNotification is a notification of the changes of the properties of an
+// object.
+type covNotification struct {
+	processID uint32
+	device    uint32
+	object    objectID
+	values    map[uint32]interface{}
+}
+
+func decodeCOVNotification(data []byte) (*covNotification, error) {
+	d := &decoder{data: data}
+	n := &covNotification{values: make(map[uint32]interface{})}
+
+	t, err := d.contextTag(0)
+	if err != nil {
+		return nil, err
+	}
+	if n.processID, err = decodeUnsigned(t.data); err != nil {
+		return nil, err
+	}
+	if t, err = d.contextTag(1); err != nil {
+		return nil, err
+	}
+	device, err := decodeObjectID(t.data)
+	if err != nil {
+		return nil, err
+	}
+	n.device = device.instance
+	if t, err = d.contextTag(2); err != nil {
+		return nil, err
+	}
+	if n.object, err = decodeObjectID(t.data); err != nil {
+		return nil, err
+	}
+	if _, err = d.contextTag(3); err != nil {
+		return nil, err
+	}
+
+	// The list of the property values, each the property, an optional array
+	// index, the value and an optional priority.
+	if err := d.expect(4, true); err != nil {
+		return nil, err
+	}
+	for {
+		if t, _, err := d.peek(); err != nil {
+			return nil, err
+		} else if t.context && t.closing && t.number == 4 {
+			break
+		}
+		t, err := d.contextTag(0)
+		if err != nil {
+			return nil, err
+		}
+		property, err := decodeUnsigned(t.data)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := d.optionalContextTag(1); err != nil {
+			return nil, err
+		}
+		if err := d.expect(2, true); err != nil {
+			return nil, err
+		}
+		values, err := d.values(2)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := d.optionalContextTag(3); err != nil {
+			return nil, err
+		}
+		if len(values) > 0 {
+			n.values[property] = values[0]
+		}
+	}
+	return n, nil
+}
+
+// decodeError returns the error of an Error, Reject or Abort answer.
+func decodeError(a *apdu) error {
+	switch a.typ {
+	case pduReject:
+		return fmt.Errorf("request rejected, reason %d", a.data[0])
+	case pduAbort:
+		return fmt.Errorf("request aborted, reason %d", a.data[0])
+	}
+
+	d := &decoder{data: a.data}
+	class, err := d.next()
+	if err != nil {
+		return errors.New("error")
+	}
+	code, err := d.next()
+	if err != nil {
+		return errors.New("error")
+	}
+	c1, _ := decodeUnsigned(class.data)
+	c2, _ := decodeUnsigned(code.data)
+	return fmt.Errorf("error class %d, code %d", c1, c2)
+}
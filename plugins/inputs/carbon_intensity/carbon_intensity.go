@@ -0,0 +1,161 @@
+package carbon_intensity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Timeout for HTTP requests.
+  # timeout = "10s"
+
+  ## Carbon intensity and day-ahead electricity prices of zones from
+  ## the ElectricityMaps API.
+  # [inputs.carbon_intensity.electricitymaps]
+  #   api_token = ""
+  #   ## Zones, such as "DE", "FR" or "US-CAL-CISO".
+  #   zones = ["DE"]
+  #   ## Gather the day-ahead electricity prices of the zones.
+  #   # prices = false
+  #   # url = "https://api.electricitymap.org"
+
+  ## Marginal operating emissions rate of balancing authorities from the
+  ## WattTime API.  The rate is reported as a percent of the last month
+  ## only with the free plan.
+  # [inputs.carbon_intensity.watttime]
+  #   username = ""
+  #   password = ""
+  #   ## Balancing authorities, such as "CAISO_NORTH" or "PJM_DC".
+  #   zones = ["CAISO_NORTH"]
+  #   # url = "https://api2.watttime.org"
+
+  ## The APIs update the carbon intensity every 5 minutes to 1 hour.
+  interval = "5m"
+`
+
+// CarbonIntensity gathers the carbon intensity and the price of the
+// electricity of grid zones.
+type CarbonIntensity struct {
+	Timeout         internal.Duration      `toml:"timeout"`
+	ElectricityMaps *ElectricityMapsConfig `toml:"electricitymaps"`
+	WattTime        *WattTimeConfig        `toml:"watttime"`
+
+	electricityMaps *electricityMaps
+	wattTime        *wattTime
+}
+
+func (c *CarbonIntensity) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CarbonIntensity) Description() string {
+	return "Read the carbon intensity and electricity prices of grid zones"
+}
+
+func (c *CarbonIntensity) Init() error {
+	client := &http.Client{Timeout: c.Timeout.Duration}
+
+	if c.ElectricityMaps != nil {
+		e, err := newElectricityMaps(c.ElectricityMaps, client)
+		if err != nil {
+			return fmt.Errorf("electricitymaps: %v", err)
+		}
+		c.electricityMaps = e
+	}
+	if c.WattTime != nil {
+		w, err := newWattTime(c.WattTime, client)
+		if err != nil {
+			return fmt.Errorf("watttime: %v", err)
+		}
+		c.wattTime = w
+	}
+	if c.electricityMaps == nil && c.wattTime == nil {
+		return errors.New("electricitymaps or watttime must be configured")
+	}
+	return nil
+}
+
+func (c *CarbonIntensity) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+
+	if c.electricityMaps != nil {
+		for _, zone := range c.electricityMaps.config.Zones {
+			wg.Add(1)
+			go func(zone string) {
+				defer wg.Done()
+				if err := c.electricityMaps.gather(acc, zone); err != nil {
+					acc.AddError(fmt.Errorf("electricitymaps: %s: %v", zone, err))
+				}
+			}(zone)
+		}
+	}
+	if c.wattTime != nil {
+		for _, zone := range c.wattTime.config.Zones {
+			wg.Add(1)
+			go func(zone string) {
+				defer wg.Done()
+				if err := c.wattTime.gather(acc, zone); err != nil {
+					acc.AddError(fmt.Errorf("watttime: %s: %v", zone, err))
+				}
+			}(zone)
+		}
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// statusError is the error of a response with an unexpected status.
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received status code %d: %s", e.code, e.body)
+}
+
+// getJSON decodes the JSON body of the response of the request.
+func getJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{code: resp.StatusCode, body: string(body)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+	return nil
+}
+
+// parseTime parses the timestamp of a value, the time of the gathering is
+// used if it is missing.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Now(), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func init() {
+	inputs.Add("carbon_intensity", func() telegraf.Input {
+		return &CarbonIntensity{
+			Timeout: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package carbon_intensity
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherElectricityMaps(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("auth-token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path + "?" + r.URL.RawQuery {
+		case "/v3/carbon-intensity/latest?zone=DE":
+			fmt.Fprint(w, `{"zone":"DE","carbonIntensity":302,"datetime":"2020-05-14T12:00:00.000Z","isEstimated":false}`)
+		case "/v3/price-day-ahead/latest?zone=DE":
+			fmt.Fprint(w, `{"zone":"DE","datetime":"2020-05-14T12:00:00.000Z","value":28.5,"unit":"EUR/MWh"}`)
+		case "/v3/carbon-intensity/latest?zone=US-CAL-CISO":
+			fmt.Fprint(w, `{"zone":"US-CAL-CISO","carbonIntensity":null,"datetime":"2020-05-14T12:00:00.000Z"}`)
+		case "/v3/price-day-ahead/latest?zone=US-CAL-CISO":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	c := &CarbonIntensity{
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		ElectricityMaps: &ElectricityMapsConfig{
+			APIToken: "secret",
+			Zones:    []string{"DE", "US-CAL-CISO"},
+			Prices:   true,
+			URL:      ts.URL,
+		},
+	}
+	require.NoError(t, c.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "US-CAL-CISO")
+
+	tm := time.Date(2020, 5, 14, 12, 0, 0, 0, time.UTC)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"carbon_intensity",
+			map[string]string{"provider": "electricitymaps", "zone": "DE"},
+			map[string]interface{}{"carbon_intensity": 302.0, "estimated": false},
+			tm,
+		),
+		testutil.MustMetric(
+			"electricity_price",
+			map[string]string{"provider": "electricitymaps", "zone": "DE", "unit": "EUR/MWh"},
+			map[string]interface{}{"price": 28.5},
+			tm,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestGatherWattTime(t *testing.T) {
+	logins := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/login":
+			user, password, ok := r.BasicAuth()
+			if !ok || user != "user" || password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			logins++
+			fmt.Fprintf(w, `{"token":"token%d"}`, logins)
+		case "/v2/index":
+			// The first token is expired.
+			if r.Header.Get("Authorization") != "Bearer token2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("ba") != "CAISO_NORTH" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"freq":"300","ba":"CAISO_NORTH","percent":"53","moer":"850.5","point_time":"2020-05-14T12:05:00.00Z"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := &CarbonIntensity{
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		WattTime: &WattTimeConfig{
+			Username: "user",
+			Password: "password",
+			Zones:    []string{"CAISO_NORTH"},
+			URL:      ts.URL,
+		},
+	}
+	require.NoError(t, c.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(c.Gather))
+	require.Equal(t, 2, logins)
+
+	moer := 850.5
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"carbon_intensity",
+			map[string]string{"provider": "watttime", "zone": "CAISO_NORTH"},
+			map[string]interface{}{"carbon_intensity": moer * gramsPerKWh, "percent": 53.0},
+			time.Date(2020, 5, 14, 12, 5, 0, 0, time.UTC),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// The token of the last login is reused.
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(c.Gather))
+	require.Equal(t, 2, logins)
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&CarbonIntensity{}).Init())
+	require.Error(t, (&CarbonIntensity{
+		ElectricityMaps: &ElectricityMapsConfig{Zones: []string{"DE"}},
+	}).Init())
+	require.Error(t, (&CarbonIntensity{
+		WattTime: &WattTimeConfig{Username: "user", Password: "password"},
+	}).Init())
+}
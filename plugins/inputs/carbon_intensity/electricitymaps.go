@@ -0,0 +1,102 @@
+package carbon_intensity
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+const electricityMapsURL = "https://api.electricitymap.org"
+
+// ElectricityMapsConfig is the account and the zones of ElectricityMaps.
+type ElectricityMapsConfig struct {
+	APIToken string   `toml:"api_token"`
+	Zones    []string `toml:"zones"`
+	Prices   bool     `toml:"prices"`
+	URL      string   `toml:"url"`
+}
+
+type electricityMaps struct {
+	config *ElectricityMapsConfig
+	client *http.Client
+}
+
+type electricityMapsIntensity struct {
+	CarbonIntensity *float64 `json:"carbonIntensity"`
+	Datetime        string   `json:"datetime"`
+	IsEstimated     *bool    `json:"isEstimated"`
+}
+
+type electricityMapsPrice struct {
+	Value    *float64 `json:"value"`
+	Unit     string   `json:"unit"`
+	Datetime string   `json:"datetime"`
+}
+
+func newElectricityMaps(config *ElectricityMapsConfig, client *http.Client) (*electricityMaps, error) {
+	if config.APIToken == "" {
+		return nil, errors.New("api_token must be set")
+	}
+	if len(config.Zones) == 0 {
+		return nil, errors.New("zones must be set")
+	}
+	if config.URL == "" {
+		config.URL = electricityMapsURL
+	}
+	return &electricityMaps{config: config, client: client}, nil
+}
+
+func (e *electricityMaps) gather(acc telegraf.Accumulator, zone string) error {
+	tags := map[string]string{"provider": "electricitymaps", "zone": zone}
+
+	var intensity electricityMapsIntensity
+	if err := e.get("/v3/carbon-intensity/latest", zone, &intensity); err != nil {
+		return err
+	}
+	// The intensity of zones without live data is null.
+	if intensity.CarbonIntensity != nil {
+		tm, err := parseTime(intensity.Datetime)
+		if err != nil {
+			return err
+		}
+		fields := map[string]interface{}{"carbon_intensity": *intensity.CarbonIntensity}
+		if intensity.IsEstimated != nil {
+			fields["estimated"] = *intensity.IsEstimated
+		}
+		acc.AddFields("carbon_intensity", fields, tags, tm)
+	}
+
+	if !e.config.Prices {
+		return nil
+	}
+
+	var price electricityMapsPrice
+	if err := e.get("/v3/price-day-ahead/latest", zone, &price); err != nil {
+		return err
+	}
+	if price.Value != nil {
+		tm, err := parseTime(price.Datetime)
+		if err != nil {
+			return err
+		}
+		priceTags := map[string]string{"provider": "electricitymaps", "zone": zone}
+		if price.Unit != "" {
+			priceTags["unit"] = price.Unit
+		}
+		acc.AddFields("electricity_price", map[string]interface{}{"price": *price.Value}, priceTags, tm)
+	}
+	return nil
+}
+
+func (e *electricityMaps) get(path, zone string, v interface{}) error {
+	u := strings.TrimRight(e.config.URL, "/") + path + "?zone=" + url.QueryEscape(zone)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("auth-token", e.config.APIToken)
+	return getJSON(e.client, req, v)
+}
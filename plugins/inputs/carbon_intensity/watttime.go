@@ -0,0 +1,143 @@
+package carbon_intensity
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	wattTimeURL = "https://api2.watttime.org"
+
+	// gramsPerKWh converts a rate in lbs/MWh to gCO2/kWh.
+	gramsPerKWh = 0.45359237
+)
+
+// WattTimeConfig is the account and the balancing authorities of WattTime.
+type WattTimeConfig struct {
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	Zones    []string `toml:"zones"`
+	URL      string   `toml:"url"`
+}
+
+type wattTime struct {
+	config *WattTimeConfig
+	client *http.Client
+
+	sync.Mutex
+	// token is the token of the last login, it expires after 30 minutes.
+	token string
+}
+
+// wattTimeIndex is the real time emissions index of a balancing authority,
+// the values are strings.
+type wattTimeIndex struct {
+	Percent   string `json:"percent"`
+	MOER      string `json:"moer"`
+	PointTime string `json:"point_time"`
+}
+
+func newWattTime(config *WattTimeConfig, client *http.Client) (*wattTime, error) {
+	if config.Username == "" || config.Password == "" {
+		return nil, errors.New("username and password must be set")
+	}
+	if len(config.Zones) == 0 {
+		return nil, errors.New("zones must be set")
+	}
+	if config.URL == "" {
+		config.URL = wattTimeURL
+	}
+	return &wattTime{config: config, client: client}, nil
+}
+
+func (w *wattTime) gather(acc telegraf.Accumulator, zone string) error {
+	var index wattTimeIndex
+	err := w.index(zone, &index)
+	if serr, ok := err.(*statusError); ok && serr.code == http.StatusUnauthorized {
+		// The token expired, the request is retried after a new login.
+		w.Lock()
+		w.token = ""
+		w.Unlock()
+		err = w.index(zone, &index)
+	}
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	if index.Percent != "" {
+		percent, err := strconv.ParseFloat(index.Percent, 64)
+		if err != nil {
+			return fmt.Errorf("invalid percent %q", index.Percent)
+		}
+		fields["percent"] = percent
+	}
+	if index.MOER != "" {
+		moer, err := strconv.ParseFloat(index.MOER, 64)
+		if err != nil {
+			return fmt.Errorf("invalid moer %q", index.MOER)
+		}
+		fields["carbon_intensity"] = moer * gramsPerKWh
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	tm, err := parseTime(index.PointTime)
+	if err != nil {
+		return err
+	}
+	tags := map[string]string{"provider": "watttime", "zone": zone}
+	acc.AddFields("carbon_intensity", fields, tags, tm)
+	return nil
+}
+
+func (w *wattTime) index(zone string, index *wattTimeIndex) error {
+	token, err := w.login()
+	if err != nil {
+		return err
+	}
+
+	u := strings.TrimRight(w.config.URL, "/") + "/v2/index?style=all&ba=" + url.QueryEscape(zone)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return getJSON(w.client, req, index)
+}
+
+// login returns the token of the last login, or of a new login if there is
+// none.
+func (w *wattTime) login() (string, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.token != "" {
+		return w.token, nil
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimRight(w.config.URL, "/")+"/v2/login", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(w.config.Username, w.config.Password)
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := getJSON(w.client, req, &login); err != nil {
+		return "", fmt.Errorf("login failed: %v", err)
+	}
+	if login.Token == "" {
+		return "", errors.New("login failed: no token")
+	}
+	w.token = login.Token
+	return w.token, nil
+}
@@ -0,0 +1,175 @@
+package cloud_cost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+)
+
+const awsDateFormat = "2006-01-02"
+
+var awsMetrics = map[string]bool{
+	"UnblendedCost":    true,
+	"BlendedCost":      true,
+	"AmortizedCost":    true,
+	"NetUnblendedCost": true,
+	"NetAmortizedCost": true,
+}
+
+// AWSConfig is the account of the costs of AWS.
+type AWSConfig struct {
+	Region         string   `toml:"region"`
+	AccessKey      string   `toml:"access_key"`
+	SecretKey      string   `toml:"secret_key"`
+	RoleARN        string   `toml:"role_arn"`
+	Profile        string   `toml:"profile"`
+	CredentialPath string   `toml:"shared_credential_file"`
+	Token          string   `toml:"token"`
+	EndpointURL    string   `toml:"endpoint_url"`
+	Metric         string   `toml:"metric"`
+	GroupBy        []string `toml:"group_by"`
+}
+
+type costExplorerClient interface {
+	GetCostAndUsageWithContext(ctx aws.Context, input *costexplorer.GetCostAndUsageInput, opts ...request.Option) (*costexplorer.GetCostAndUsageOutput, error)
+}
+
+type awsProvider struct {
+	metric  string
+	groupBy []*costexplorer.GroupDefinition
+	tags    []string
+	client  costExplorerClient
+}
+
+func newAWSProvider(config *AWSConfig) (*awsProvider, error) {
+	if config.Metric == "" {
+		config.Metric = "UnblendedCost"
+	}
+	if !awsMetrics[config.Metric] {
+		return nil, fmt.Errorf("unknown metric %q", config.Metric)
+	}
+	if len(config.GroupBy) > 2 {
+		return nil, errors.New("costs can be grouped by up to 2 dimensions")
+	}
+	if config.Region == "" {
+		// The Cost Explorer API is served from us-east-1 only.
+		config.Region = "us-east-1"
+	}
+
+	p := &awsProvider{metric: config.Metric}
+	for _, group := range config.GroupBy {
+		definition := &costexplorer.GroupDefinition{
+			Type: aws.String(costexplorer.GroupDefinitionTypeDimension),
+			Key:  aws.String(group),
+		}
+		if strings.HasPrefix(group, "TAG:") {
+			definition.Type = aws.String(costexplorer.GroupDefinitionTypeTag)
+			definition.Key = aws.String(group[len("TAG:"):])
+		}
+		p.groupBy = append(p.groupBy, definition)
+		p.tags = append(p.tags, groupTag(group))
+	}
+
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      config.Region,
+		AccessKey:   config.AccessKey,
+		SecretKey:   config.SecretKey,
+		RoleARN:     config.RoleARN,
+		Profile:     config.Profile,
+		Filename:    config.CredentialPath,
+		Token:       config.Token,
+		EndpointURL: config.EndpointURL,
+	}
+	p.client = costexplorer.New(credentialConfig.Credentials())
+	return p, nil
+}
+
+func (p *awsProvider) name() string {
+	return "aws"
+}
+
+func (p *awsProvider) costs(ctx context.Context, start, end time.Time) ([]cost, error) {
+	input := &costexplorer.GetCostAndUsageInput{
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String(start.Format(awsDateFormat)),
+			End:   aws.String(end.Format(awsDateFormat)),
+		},
+		Granularity: aws.String(costexplorer.GranularityDaily),
+		Metrics:     []*string{aws.String(p.metric)},
+	}
+	if len(p.groupBy) > 0 {
+		input.GroupBy = p.groupBy
+	}
+
+	var costs []cost
+	for {
+		output, err := p.client.GetCostAndUsageWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range output.ResultsByTime {
+			day, err := time.Parse(awsDateFormat, aws.StringValue(result.TimePeriod.Start))
+			if err != nil {
+				return nil, err
+			}
+
+			// The costs are in the total when not grouped.
+			if len(p.groupBy) == 0 {
+				c, err := p.cost(day, result.Total[p.metric], result.Estimated)
+				if err != nil {
+					return nil, err
+				}
+				costs = append(costs, c)
+				continue
+			}
+
+			for _, group := range result.Groups {
+				c, err := p.cost(day, group.Metrics[p.metric], result.Estimated)
+				if err != nil {
+					return nil, err
+				}
+				c.groups = make(map[string]string, len(p.tags))
+				for i, key := range group.Keys {
+					if i >= len(p.tags) {
+						break
+					}
+					// The keys of the tags are "<key>$<value>".
+					value := aws.StringValue(key)
+					if p.groupBy[i].Type != nil && *p.groupBy[i].Type == costexplorer.GroupDefinitionTypeTag {
+						value = value[strings.Index(value, "$")+1:]
+					}
+					c.groups[p.tags[i]] = value
+				}
+				costs = append(costs, c)
+			}
+		}
+
+		if output.NextPageToken == nil {
+			return costs, nil
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+}
+
+func (p *awsProvider) cost(day time.Time, value *costexplorer.MetricValue, estimated *bool) (cost, error) {
+	c := cost{day: day, estimated: aws.Bool(aws.BoolValue(estimated))}
+	if value == nil {
+		return c, nil
+	}
+	amount, err := strconv.ParseFloat(aws.StringValue(value.Amount), 64)
+	if err != nil {
+		return c, fmt.Errorf("invalid amount %q", aws.StringValue(value.Amount))
+	}
+	c.amount = amount
+	c.currency = aws.StringValue(value.Unit)
+	return c, nil
+}
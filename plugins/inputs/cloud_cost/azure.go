@@ -0,0 +1,230 @@
+package cloud_cost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+)
+
+const (
+	azureEndpoint     = "https://management.azure.com"
+	azureAuthResource = "https://management.azure.com/"
+	azureAPIVersion   = "2019-11-01"
+
+	// azureCostColumn is the name of the column of the sum of the costs.
+	azureCostColumn = "PreTaxCost"
+)
+
+// AzureConfig is the scope of the costs of Azure.
+type AzureConfig struct {
+	Scope    string   `toml:"scope"`
+	CostType string   `toml:"cost_type"`
+	GroupBy  []string `toml:"group_by"`
+}
+
+type azureProvider struct {
+	config   *AzureConfig
+	tagKey   string
+	endpoint string
+	auth     autorest.Authorizer
+	client   *http.Client
+}
+
+// azureQueryResult is the result of a query, with a row of values for each
+// day and group.
+type azureQueryResult struct {
+	Properties struct {
+		NextLink *string `json:"nextLink"`
+		Columns  []struct {
+			Name string `json:"name"`
+		} `json:"columns"`
+		Rows [][]interface{} `json:"rows"`
+	} `json:"properties"`
+}
+
+func newAzureProvider(config *AzureConfig) (*azureProvider, error) {
+	if config.Scope == "" {
+		return nil, errors.New("scope must be set")
+	}
+	switch config.CostType {
+	case "":
+		config.CostType = "ActualCost"
+	case "ActualCost", "AmortizedCost":
+	default:
+		return nil, fmt.Errorf("unknown cost type %q", config.CostType)
+	}
+	if len(config.GroupBy) > 2 {
+		return nil, errors.New("costs can be grouped by up to 2 dimensions")
+	}
+	var tagKey string
+	for _, group := range config.GroupBy {
+		if strings.HasPrefix(group, "TAG:") {
+			if tagKey != "" {
+				return nil, errors.New("costs can be grouped by a single tag")
+			}
+			tagKey = group[len("TAG:"):]
+		}
+	}
+
+	authorizer, err := auth.NewAuthorizerFromEnvironmentWithResource(azureAuthResource)
+	if err != nil {
+		return nil, err
+	}
+	return &azureProvider{
+		config:   config,
+		tagKey:   tagKey,
+		endpoint: azureEndpoint,
+		auth:     authorizer,
+		client:   &http.Client{},
+	}, nil
+}
+
+func (p *azureProvider) name() string {
+	return "azure"
+}
+
+func (p *azureProvider) costs(ctx context.Context, start, end time.Time) ([]cost, error) {
+	dataset := map[string]interface{}{
+		"granularity": "Daily",
+		"aggregation": map[string]interface{}{
+			"totalCost": map[string]string{"name": azureCostColumn, "function": "Sum"},
+		},
+	}
+	var grouping []map[string]string
+	for _, group := range p.config.GroupBy {
+		if strings.HasPrefix(group, "TAG:") {
+			grouping = append(grouping, map[string]string{"type": "TagKey", "name": group[len("TAG:"):]})
+		} else {
+			grouping = append(grouping, map[string]string{"type": "Dimension", "name": group})
+		}
+	}
+	if len(grouping) > 0 {
+		dataset["grouping"] = grouping
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"type":      p.config.CostType,
+		"timeframe": "Custom",
+		"timePeriod": map[string]string{
+			"from": start.Format(time.RFC3339),
+			"to":   end.Add(-time.Second).Format(time.RFC3339),
+		},
+		"dataset": dataset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var costs []cost
+	u := p.endpoint + strings.TrimRight(p.config.Scope, "/") +
+		"/providers/Microsoft.CostManagement/query?api-version=" + azureAPIVersion
+	for u != "" {
+		var result azureQueryResult
+		if err := p.query(ctx, u, body, &result); err != nil {
+			return nil, err
+		}
+
+		rows, err := azureCosts(&result, p.tagKey)
+		if err != nil {
+			return nil, err
+		}
+		costs = append(costs, rows...)
+
+		u = ""
+		if result.Properties.NextLink != nil {
+			u = *result.Properties.NextLink
+		}
+	}
+	return costs, nil
+}
+
+// azureCosts returns the costs of the rows of the result.  The columns are
+// the cost, the day as a number such as 20200514, the currency and the
+// dimensions the costs are grouped by.  The value of the tag the costs are
+// grouped by is in the TagValue column, empty for the untagged resources.
+func azureCosts(result *azureQueryResult, tagKey string) ([]cost, error) {
+	var costs []cost
+	for _, row := range result.Properties.Rows {
+		if len(row) != len(result.Properties.Columns) {
+			return nil, errors.New("unexpected number of columns")
+		}
+
+		c := cost{groups: make(map[string]string)}
+		for i, column := range result.Properties.Columns {
+			switch column.Name {
+			case azureCostColumn:
+				amount, ok := row[i].(float64)
+				if !ok {
+					return nil, fmt.Errorf("invalid cost %v", row[i])
+				}
+				c.amount = amount
+			case "UsageDate":
+				date, ok := row[i].(float64)
+				if !ok {
+					return nil, fmt.Errorf("invalid date %v", row[i])
+				}
+				day, err := time.Parse("20060102", strconv.FormatInt(int64(date), 10))
+				if err != nil {
+					return nil, fmt.Errorf("invalid date %v", row[i])
+				}
+				c.day = day
+			case "Currency":
+				c.currency = fmt.Sprint(row[i])
+			case "TagKey":
+				// The tag is named after the key configured.
+			case "TagValue":
+				value := ""
+				if row[i] != nil {
+					value = fmt.Sprint(row[i])
+				}
+				c.groups[groupTag("TAG:"+tagKey)] = value
+			default:
+				value := ""
+				if row[i] != nil {
+					value = fmt.Sprint(row[i])
+				}
+				c.groups[groupTag(column.Name)] = value
+			}
+		}
+		costs = append(costs, c)
+	}
+	return costs, nil
+}
+
+func (p *azureProvider) query(ctx context.Context, u string, body []byte, result interface{}) error {
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	req, err = autorest.CreatePreparer(p.auth.WithAuthorization()).Prepare(req)
+	if err != nil {
+		return fmt.Errorf("unable to fetch authentication credentials: %v", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, result)
+}
@@ -0,0 +1,204 @@
+package cloud_cost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// retryInterval is the maximum interval of the queries after an error.
+const retryInterval = 15 * time.Minute
+
+const sampleConfig = `
+  ## Number of days of costs queried, including the current day.  The costs
+  ## of the last days are revised by the providers as the usage is billed.
+  # days = 7
+
+  ## Interval of the queries of the billing APIs, which are rate limited and
+  ## may be charged by request.  The costs are reported from the last query
+  ## at each interval in between.
+  # refresh_interval = "6h"
+
+  ## Timeout of the queries.
+  # timeout = "1m"
+
+  ## Costs of AWS from the Cost Explorer API.
+  # [inputs.cloud_cost.aws]
+  #   ## Amazon Credentials, see the cloudwatch input for the precedence.
+  #   region = "us-east-1"
+  #   # access_key = ""
+  #   # secret_key = ""
+  #   # token = ""
+  #   # role_arn = ""
+  #   # profile = ""
+  #   # shared_credential_file = ""
+  #
+  #   ## Cost metric, one of "UnblendedCost", "BlendedCost", "AmortizedCost",
+  #   ## "NetUnblendedCost" or "NetAmortizedCost".
+  #   # metric = "UnblendedCost"
+  #
+  #   ## Up to 2 dimensions the costs are grouped by, such as "SERVICE",
+  #   ## "LINKED_ACCOUNT" or "REGION", or "TAG:<key>" for a cost allocation
+  #   ## tag.
+  #   # group_by = ["SERVICE"]
+
+  ## Costs of Azure from the Cost Management API.  The credentials are read
+  ## from the environment as for the azure_monitor output.
+  # [inputs.cloud_cost.azure]
+  #   ## Scope of the costs, such as "/subscriptions/<id>" or
+  #   ## "/subscriptions/<id>/resourceGroups/<name>".
+  #   scope = "/subscriptions/00000000-0000-0000-0000-000000000000"
+  #
+  #   ## Type of the costs, "ActualCost" or "AmortizedCost".
+  #   # cost_type = "ActualCost"
+  #
+  #   ## Up to 2 dimensions the costs are grouped by, such as "ServiceName",
+  #   ## "ResourceGroupName" or "SubscriptionId", or "TAG:<key>" for a tag.
+  #   # group_by = ["ServiceName"]
+`
+
+// cost is the cost of a day of a group of resources.
+type cost struct {
+	day       time.Time
+	groups    map[string]string
+	amount    float64
+	currency  string
+	estimated *bool
+}
+
+type provider interface {
+	name() string
+	costs(ctx context.Context, start, end time.Time) ([]cost, error)
+}
+
+// CloudCost gathers the daily costs of cloud providers.
+type CloudCost struct {
+	Days            int               `toml:"days"`
+	RefreshInterval internal.Duration `toml:"refresh_interval"`
+	Timeout         internal.Duration `toml:"timeout"`
+	AWS             *AWSConfig        `toml:"aws"`
+	Azure           *AzureConfig      `toml:"azure"`
+
+	providers []*cachedProvider
+	now       func() time.Time
+}
+
+// cachedProvider is a provider with the costs of its last query.
+type cachedProvider struct {
+	provider
+	costs       []cost
+	nextRefresh time.Time
+}
+
+func (c *CloudCost) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CloudCost) Description() string {
+	return "Read the daily costs of AWS and Azure from their billing APIs"
+}
+
+func (c *CloudCost) Init() error {
+	if c.Days < 1 {
+		return errors.New("days must be at least 1")
+	}
+
+	if c.AWS != nil {
+		p, err := newAWSProvider(c.AWS)
+		if err != nil {
+			return fmt.Errorf("aws: %v", err)
+		}
+		c.providers = append(c.providers, &cachedProvider{provider: p})
+	}
+	if c.Azure != nil {
+		p, err := newAzureProvider(c.Azure)
+		if err != nil {
+			return fmt.Errorf("azure: %v", err)
+		}
+		c.providers = append(c.providers, &cachedProvider{provider: p})
+	}
+	if len(c.providers) == 0 {
+		return errors.New("aws or azure must be configured")
+	}
+	return nil
+}
+
+func (c *CloudCost) Gather(acc telegraf.Accumulator) error {
+	now := c.now()
+	end := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -c.Days)
+
+	for _, p := range c.providers {
+		if !now.Before(p.nextRefresh) {
+			ctx, cancel := context.WithTimeout(context.Background(), c.Timeout.Duration)
+			costs, err := p.provider.costs(ctx, start, end)
+			cancel()
+			if err != nil {
+				// The previous costs are reported until a query succeeds,
+				// the queries are retried sooner than the refresh.
+				acc.AddError(fmt.Errorf("%s: %v", p.name(), err))
+				retry := c.RefreshInterval.Duration
+				if retry > retryInterval {
+					retry = retryInterval
+				}
+				p.nextRefresh = now.Add(retry)
+			} else {
+				p.costs = costs
+				p.nextRefresh = now.Add(c.RefreshInterval.Duration)
+			}
+		}
+
+		for _, cost := range p.costs {
+			tags := map[string]string{"provider": p.name()}
+			if cost.currency != "" {
+				tags["currency"] = cost.currency
+			}
+			for k, v := range cost.groups {
+				tags[k] = v
+			}
+			fields := map[string]interface{}{"cost": cost.amount}
+			if cost.estimated != nil {
+				fields["estimated"] = *cost.estimated
+			}
+			acc.AddFields("cloud_cost", fields, tags, cost.day)
+		}
+	}
+	return nil
+}
+
+// groupTag returns the name of the tag of a dimension or tag the costs are
+// grouped by, "SERVICE" is "service", "ResourceGroupName" is
+// "resource_group_name" and "TAG:team" is "tag_team".
+func groupTag(group string) string {
+	if strings.HasPrefix(group, "TAG:") {
+		return "tag_" + group[len("TAG:"):]
+	}
+
+	var b strings.Builder
+	runes := []rune(group)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func init() {
+	inputs.Add("cloud_cost", func() telegraf.Input {
+		return &CloudCost{
+			Days:            7,
+			RefreshInterval: internal.Duration{Duration: 6 * time.Hour},
+			Timeout:         internal.Duration{Duration: time.Minute},
+			now:             time.Now,
+		}
+	})
+}
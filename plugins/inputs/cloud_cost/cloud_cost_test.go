@@ -0,0 +1,313 @@
+package cloud_cost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	calls int
+	err   error
+	start time.Time
+	end   time.Time
+}
+
+func (p *fakeProvider) name() string {
+	return "fake"
+}
+
+func (p *fakeProvider) costs(ctx context.Context, start, end time.Time) ([]cost, error) {
+	p.calls++
+	p.start = start
+	p.end = end
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []cost{
+		{
+			day:      start,
+			groups:   map[string]string{"service": "storage"},
+			amount:   1.5,
+			currency: "USD",
+		},
+	}, nil
+}
+
+func TestGatherCache(t *testing.T) {
+	now := time.Date(2020, 5, 14, 10, 0, 0, 0, time.UTC)
+	p := &fakeProvider{}
+	c := &CloudCost{
+		Days:            7,
+		RefreshInterval: internal.Duration{Duration: 6 * time.Hour},
+		Timeout:         internal.Duration{Duration: time.Second},
+		providers:       []*cachedProvider{{provider: p}},
+		now:             func() time.Time { return now },
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Equal(t, 1, p.calls)
+	require.Equal(t, time.Date(2020, 5, 8, 0, 0, 0, 0, time.UTC), p.start)
+	require.Equal(t, time.Date(2020, 5, 15, 0, 0, 0, 0, time.UTC), p.end)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cloud_cost",
+			map[string]string{"provider": "fake", "currency": "USD", "service": "storage"},
+			map[string]interface{}{"cost": 1.5},
+			time.Date(2020, 5, 8, 0, 0, 0, 0, time.UTC),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// The costs are reported from the cache until the refresh.
+	now = now.Add(time.Hour)
+	acc.ClearMetrics()
+	require.NoError(t, c.Gather(&acc))
+	require.Equal(t, 1, p.calls)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// The cached costs are kept on errors, the query is retried sooner.
+	now = now.Add(5 * time.Hour)
+	p.err = errors.New("rate exceeded")
+	acc.ClearMetrics()
+	require.NoError(t, c.Gather(&acc))
+	require.Equal(t, 2, p.calls)
+	require.Len(t, acc.Errors, 1)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	now = now.Add(15 * time.Minute)
+	p.err = nil
+	require.NoError(t, c.Gather(&acc))
+	require.Equal(t, 3, p.calls)
+}
+
+func TestGroupTag(t *testing.T) {
+	require.Equal(t, "service", groupTag("SERVICE"))
+	require.Equal(t, "linked_account", groupTag("LINKED_ACCOUNT"))
+	require.Equal(t, "resource_group_name", groupTag("ResourceGroupName"))
+	require.Equal(t, "subscription_id", groupTag("SubscriptionId"))
+	require.Equal(t, "tag_team", groupTag("TAG:team"))
+}
+
+type fakeCostExplorer struct {
+	inputs  []*costexplorer.GetCostAndUsageInput
+	outputs []*costexplorer.GetCostAndUsageOutput
+}
+
+func (f *fakeCostExplorer) GetCostAndUsageWithContext(ctx aws.Context, input *costexplorer.GetCostAndUsageInput, opts ...request.Option) (*costexplorer.GetCostAndUsageOutput, error) {
+	copied := *input
+	f.inputs = append(f.inputs, &copied)
+	output := f.outputs[0]
+	f.outputs = f.outputs[1:]
+	return output, nil
+}
+
+func TestAWSCosts(t *testing.T) {
+	metric := func(amount string) map[string]*costexplorer.MetricValue {
+		return map[string]*costexplorer.MetricValue{
+			"UnblendedCost": {Amount: aws.String(amount), Unit: aws.String("USD")},
+		}
+	}
+	client := &fakeCostExplorer{
+		outputs: []*costexplorer.GetCostAndUsageOutput{
+			{
+				ResultsByTime: []*costexplorer.ResultByTime{
+					{
+						TimePeriod: &costexplorer.DateInterval{Start: aws.String("2020-05-13"), End: aws.String("2020-05-14")},
+						Groups: []*costexplorer.Group{
+							{Keys: []*string{aws.String("Amazon S3"), aws.String("team$storage")}, Metrics: metric("12.5")},
+							{Keys: []*string{aws.String("Amazon EC2"), aws.String("team$")}, Metrics: metric("40.25")},
+						},
+					},
+				},
+				NextPageToken: aws.String("page2"),
+			},
+			{
+				ResultsByTime: []*costexplorer.ResultByTime{
+					{
+						TimePeriod: &costexplorer.DateInterval{Start: aws.String("2020-05-14"), End: aws.String("2020-05-15")},
+						Groups: []*costexplorer.Group{
+							{Keys: []*string{aws.String("Amazon S3"), aws.String("team$storage")}, Metrics: metric("3.1")},
+						},
+						Estimated: aws.Bool(true),
+					},
+				},
+			},
+		},
+	}
+
+	p := &awsProvider{
+		metric: "UnblendedCost",
+		groupBy: []*costexplorer.GroupDefinition{
+			{Type: aws.String("DIMENSION"), Key: aws.String("SERVICE")},
+			{Type: aws.String("TAG"), Key: aws.String("team")},
+		},
+		tags:   []string{"service", "tag_team"},
+		client: client,
+	}
+	start := time.Date(2020, 5, 13, 0, 0, 0, 0, time.UTC)
+	costs, err := p.costs(context.Background(), start, start.AddDate(0, 0, 2))
+	require.NoError(t, err)
+
+	require.Len(t, client.inputs, 2)
+	require.Equal(t, "2020-05-13", aws.StringValue(client.inputs[0].TimePeriod.Start))
+	require.Equal(t, "2020-05-15", aws.StringValue(client.inputs[0].TimePeriod.End))
+	require.Equal(t, "DAILY", aws.StringValue(client.inputs[0].Granularity))
+	require.Nil(t, client.inputs[0].NextPageToken)
+	require.Equal(t, "page2", aws.StringValue(client.inputs[1].NextPageToken))
+
+	require.Equal(t, []cost{
+		{
+			day:       start,
+			groups:    map[string]string{"service": "Amazon S3", "tag_team": "storage"},
+			amount:    12.5,
+			currency:  "USD",
+			estimated: aws.Bool(false),
+		},
+		{
+			day:       start,
+			groups:    map[string]string{"service": "Amazon EC2", "tag_team": ""},
+			amount:    40.25,
+			currency:  "USD",
+			estimated: aws.Bool(false),
+		},
+		{
+			day:       start.AddDate(0, 0, 1),
+			groups:    map[string]string{"service": "Amazon S3", "tag_team": "storage"},
+			amount:    3.1,
+			currency:  "USD",
+			estimated: aws.Bool(true),
+		},
+	}, costs)
+}
+
+func TestAzureCosts(t *testing.T) {
+	var body map[string]interface{}
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/subscriptions/sub1/providers/Microsoft.CostManagement/query":
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"properties": {
+  "nextLink": "%s/page2",
+  "columns": [
+    {"name": "PreTaxCost", "type": "Number"},
+    {"name": "UsageDate", "type": "Number"},
+    {"name": "ServiceName", "type": "String"},
+    {"name": "TagKey", "type": "String"},
+    {"name": "TagValue", "type": "String"},
+    {"name": "Currency", "type": "String"}
+  ],
+  "rows": [
+    [12.5, 20200513, "Storage", "team", "storage", "EUR"],
+    [40.25, 20200513, "Virtual Machines", "", null, "EUR"]
+  ]
+}}`, ts.URL)
+		case "/page2":
+			fmt.Fprint(w, `{"properties": {
+  "nextLink": null,
+  "columns": [
+    {"name": "PreTaxCost", "type": "Number"},
+    {"name": "UsageDate", "type": "Number"},
+    {"name": "ServiceName", "type": "String"},
+    {"name": "TagKey", "type": "String"},
+    {"name": "TagValue", "type": "String"},
+    {"name": "Currency", "type": "String"}
+  ],
+  "rows": [
+    [3.1, 20200514, "Storage", "team", "storage", "EUR"]
+  ]
+}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p := &azureProvider{
+		config: &AzureConfig{
+			Scope:    "/subscriptions/sub1",
+			CostType: "ActualCost",
+			GroupBy:  []string{"ServiceName", "TAG:team"},
+		},
+		tagKey:   "team",
+		endpoint: ts.URL,
+		auth:     autorest.NullAuthorizer{},
+		client:   ts.Client(),
+	}
+	start := time.Date(2020, 5, 13, 0, 0, 0, 0, time.UTC)
+	costs, err := p.costs(context.Background(), start, start.AddDate(0, 0, 2))
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]interface{}{
+		"type":      "ActualCost",
+		"timeframe": "Custom",
+		"timePeriod": map[string]interface{}{
+			"from": "2020-05-13T00:00:00Z",
+			"to":   "2020-05-14T23:59:59Z",
+		},
+		"dataset": map[string]interface{}{
+			"granularity": "Daily",
+			"aggregation": map[string]interface{}{
+				"totalCost": map[string]interface{}{"name": "PreTaxCost", "function": "Sum"},
+			},
+			"grouping": []interface{}{
+				map[string]interface{}{"type": "Dimension", "name": "ServiceName"},
+				map[string]interface{}{"type": "TagKey", "name": "team"},
+			},
+		},
+	}, body)
+
+	require.Equal(t, []cost{
+		{
+			day:      start,
+			groups:   map[string]string{"service_name": "Storage", "tag_team": "storage"},
+			amount:   12.5,
+			currency: "EUR",
+		},
+		{
+			day:      start,
+			groups:   map[string]string{"service_name": "Virtual Machines", "tag_team": ""},
+			amount:   40.25,
+			currency: "EUR",
+		},
+		{
+			day:      start.AddDate(0, 0, 1),
+			groups:   map[string]string{"service_name": "Storage", "tag_team": "storage"},
+			amount:   3.1,
+			currency: "EUR",
+		},
+	}, costs)
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&CloudCost{Days: 7}).Init())
+	require.Error(t, (&CloudCost{Days: 0, AWS: &AWSConfig{}}).Init())
+	require.Error(t, (&CloudCost{Days: 7, AWS: &AWSConfig{Metric: "UsageQuantity"}}).Init())
+	require.Error(t, (&CloudCost{Days: 7, AWS: &AWSConfig{GroupBy: []string{"SERVICE", "REGION", "TAG:team"}}}).Init())
+	require.Error(t, (&CloudCost{Days: 7, Azure: &AzureConfig{}}).Init())
+	require.Error(t, (&CloudCost{Days: 7, Azure: &AzureConfig{Scope: "/subscriptions/sub1", CostType: "Usage"}}).Init())
+}
@@ -0,0 +1,56 @@
+package cloud_pubsub
+
+import "sync"
+
+// idCache is a set holding up to size message IDs, the oldest ID is evicted
+// when the set is full.
+type idCache struct {
+	sync.Mutex
+
+	size  int
+	ids   map[string]struct{}
+	order []string
+}
+
+func newIDCache(size int) *idCache {
+	return &idCache{
+		size:  size,
+		ids:   make(map[string]struct{}, size),
+		order: make([]string, 0, size),
+	}
+}
+
+// add inserts the id and returns false if the id was already present.
+func (c *idCache) add(id string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.ids[id]; ok {
+		return false
+	}
+
+	if len(c.order) >= c.size {
+		delete(c.ids, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.ids[id] = struct{}{}
+	c.order = append(c.order, id)
+	return true
+}
+
+// remove forgets the id so that it is accepted when added again.
+func (c *idCache) remove(id string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.ids[id]; !ok {
+		return
+	}
+	delete(c.ids, id)
+	for i, v := range c.order {
+		if v == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/selfstat"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 )
@@ -43,6 +44,10 @@ type PubSub struct {
 
 	Base64Data bool `toml:"base64_data"`
 
+	// Delivery settings
+	DeduplicateMaxIDs int  `toml:"deduplicate_max_ids"`
+	NackParseErrors   bool `toml:"nack_parse_errors"`
+
 	Log telegraf.Logger
 
 	sub     subscription
@@ -56,6 +61,13 @@ type PubSub struct {
 
 	undelivered map[telegraf.TrackingID]message
 	sem         semaphore
+	seen        *idCache
+
+	MessagesReceived   selfstat.Stat
+	MessagesAcked      selfstat.Stat
+	MessagesNacked     selfstat.Stat
+	MessagesDuplicated selfstat.Stat
+	ParseErrors        selfstat.Stat
 }
 
 func (ps *PubSub) Description() string {
@@ -91,6 +103,19 @@ func (ps *PubSub) Start(ac telegraf.Accumulator) error {
 	ps.sem = make(semaphore, ps.MaxUndeliveredMessages)
 	ps.acc = ac.WithTracking(ps.MaxUndeliveredMessages)
 
+	if ps.DeduplicateMaxIDs > 0 {
+		ps.seen = newIDCache(ps.DeduplicateMaxIDs)
+	}
+
+	tags := map[string]string{
+		"subscription": ps.Subscription,
+	}
+	ps.MessagesReceived = selfstat.Register("cloud_pubsub", "messages_received", tags)
+	ps.MessagesAcked = selfstat.Register("cloud_pubsub", "messages_acked", tags)
+	ps.MessagesNacked = selfstat.Register("cloud_pubsub", "messages_nacked", tags)
+	ps.MessagesDuplicated = selfstat.Register("cloud_pubsub", "messages_duplicated", tags)
+	ps.ParseErrors = selfstat.Register("cloud_pubsub", "parse_errors", tags)
+
 	// Create top-level context with cancel that will be called on Stop().
 	ctx, cancel := context.WithCancel(context.Background())
 	ps.cancel = cancel
@@ -169,8 +194,18 @@ func (ps *PubSub) startReceiver(parentCtx context.Context) error {
 
 // onMessage handles parsing and adding a received message to the accumulator.
 func (ps *PubSub) onMessage(ctx context.Context, msg message) error {
+	ps.MessagesReceived.Incr(1)
+
+	// Messages can be redelivered by PubSub even after they have been
+	// acknowledged; drop the ones that have already been processed.
+	if ps.seen != nil && !ps.seen.add(msg.ID()) {
+		ps.MessagesDuplicated.Incr(1)
+		ps.ack(msg)
+		return nil
+	}
+
 	if ps.MaxMessageLen > 0 && len(msg.Data()) > ps.MaxMessageLen {
-		msg.Ack()
+		ps.reject(msg)
 		return fmt.Errorf("message longer than max_message_len (%d > %d)", len(msg.Data()), ps.MaxMessageLen)
 	}
 
@@ -178,6 +213,7 @@ func (ps *PubSub) onMessage(ctx context.Context, msg message) error {
 	if ps.Base64Data {
 		strData, err := base64.StdEncoding.DecodeString(string(msg.Data()))
 		if err != nil {
+			ps.reject(msg)
 			return fmt.Errorf("unable to base64 decode message: %v", err)
 		}
 		data = []byte(strData)
@@ -187,17 +223,18 @@ func (ps *PubSub) onMessage(ctx context.Context, msg message) error {
 
 	metrics, err := ps.parser.Parse(data)
 	if err != nil {
-		msg.Ack()
+		ps.reject(msg)
 		return err
 	}
 
 	if len(metrics) == 0 {
-		msg.Ack()
+		ps.ack(msg)
 		return nil
 	}
 
 	select {
 	case <-ctx.Done():
+		ps.nack(msg)
 		return ctx.Err()
 	case ps.sem <- empty{}:
 		break
@@ -223,14 +260,46 @@ func (ps *PubSub) waitForDelivery(parentCtx context.Context) {
 		case info := <-ps.acc.Delivered():
 			<-ps.sem
 			msg := ps.removeDelivered(info.ID())
+			if msg == nil {
+				continue
+			}
 
-			if msg != nil {
-				msg.Ack()
+			if info.Delivered() {
+				ps.ack(msg)
+			} else {
+				ps.nack(msg)
 			}
 		}
 	}
 }
 
+func (ps *PubSub) ack(msg message) {
+	msg.Ack()
+	ps.MessagesAcked.Incr(1)
+}
+
+// nack returns the message to PubSub for redelivery.
+func (ps *PubSub) nack(msg message) {
+	if ps.seen != nil {
+		ps.seen.remove(msg.ID())
+	}
+	msg.Nack()
+	ps.MessagesNacked.Incr(1)
+}
+
+// reject handles a message which can never be processed.  By default the
+// message is acknowledged and dropped, with nack_parse_errors the message is
+// returned to PubSub so it can be forwarded to the dead-letter topic of the
+// subscription.
+func (ps *PubSub) reject(msg message) {
+	ps.ParseErrors.Incr(1)
+	if ps.NackParseErrors {
+		ps.nack(msg)
+		return
+	}
+	ps.ack(msg)
+}
+
 func (ps *PubSub) removeDelivered(id telegraf.TrackingID) message {
 	ps.Lock()
 	defer ps.Unlock()
@@ -365,4 +434,15 @@ const sampleConfig = `
   ## Optional. If true, Telegraf will attempt to base64 decode the 
   ## PubSub message data before parsing
   # base64_data = false
+
+  ## Optional. Number of most recently received message IDs to remember.
+  ## Messages redelivered by PubSub with an ID that has already been
+  ## processed are acknowledged and dropped.  If 0, no deduplication is done.
+  # deduplicate_max_ids = 0
+
+  ## Optional. If true, messages that cannot be parsed are negatively
+  ## acknowledged instead of dropped.  Use together with a dead-letter policy
+  ## on the subscription to forward them to a dead-letter topic after the
+  ## maximum number of delivery attempts.
+  # nack_parse_errors = false
 `
@@ -231,6 +231,104 @@ func TestRunErrorInSubscriber(t *testing.T) {
 	assert.Regexp(t, fakeErrStr, acc.Errors[0])
 }
 
+func TestRunDeduplicate(t *testing.T) {
+	subId := "sub-deduplicate"
+
+	testParser, _ := parsers.NewInfluxParser()
+
+	sub := &stubSub{
+		id:       subId,
+		messages: make(chan *testMsg, 100),
+	}
+	sub.receiver = testMessagesReceive(sub)
+
+	ps := &PubSub{
+		Log:                    testutil.Logger{},
+		parser:                 testParser,
+		stubSub:                func() subscription { return sub },
+		Project:                "projectIDontMatterForTests",
+		Subscription:           subId,
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		DeduplicateMaxIDs:      10,
+	}
+
+	acc := &testutil.Accumulator{}
+	if err := ps.Start(acc); err != nil {
+		t.Fatalf("test PubSub failed to start: %s", err)
+	}
+	defer ps.Stop()
+
+	dupTracker := &testTracker{}
+	sub.messages <- &testMsg{id: "1", value: msgInflux, tracker: &testTracker{}}
+	sub.messages <- &testMsg{id: "1", value: msgInflux, tracker: dupTracker}
+	sub.messages <- &testMsg{id: "2", value: msgInflux, tracker: &testTracker{}}
+
+	acc.Wait(2)
+	assert.Equal(t, 2, len(acc.Metrics))
+
+	// The duplicate is acknowledged so it is not redelivered again.
+	dupTracker.WaitForAck(1)
+}
+
+func TestRunNackParseErrors(t *testing.T) {
+	subId := "sub-nack-parse-errors"
+
+	testParser, _ := parsers.NewInfluxParser()
+
+	sub := &stubSub{
+		id:       subId,
+		messages: make(chan *testMsg, 100),
+	}
+	sub.receiver = testMessagesReceive(sub)
+
+	ps := &PubSub{
+		Log:                    testutil.Logger{},
+		parser:                 testParser,
+		stubSub:                func() subscription { return sub },
+		Project:                "projectIDontMatterForTests",
+		Subscription:           subId,
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		NackParseErrors:        true,
+	}
+
+	acc := &testutil.Accumulator{}
+	if err := ps.Start(acc); err != nil {
+		t.Fatalf("test PubSub failed to start: %s", err)
+	}
+	defer ps.Stop()
+
+	testTracker := &testTracker{}
+	msg := &testMsg{
+		value:   "~invalidInfluxMsg~",
+		tracker: testTracker,
+	}
+	sub.messages <- msg
+
+	acc.WaitError(1)
+
+	// Make sure the message was returned so it can be dead-lettered.
+	testTracker.WaitForNack(1)
+	assert.Equal(t, 0, testTracker.numAcks)
+
+	assert.Equal(t, acc.NFields(), 0)
+}
+
+func TestIDCache(t *testing.T) {
+	c := newIDCache(2)
+
+	assert.True(t, c.add("a"))
+	assert.False(t, c.add("a"))
+	assert.True(t, c.add("b"))
+
+	// Adding a third ID evicts the oldest.
+	assert.True(t, c.add("c"))
+	assert.True(t, c.add("a"))
+	assert.False(t, c.add("c"))
+
+	c.remove("c")
+	assert.True(t, c.add("c"))
+}
+
 func validateTestInfluxMetric(t *testing.T, m *testutil.Metric) {
 	assert.Equal(t, "cpu_load_short", m.Measurement)
 	assert.Equal(t, "server01", m.Tags["host"])
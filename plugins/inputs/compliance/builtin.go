@@ -0,0 +1,127 @@
+package compliance
+
+// builtinChecks are the checks of the hardening recommendations of the CIS
+// benchmarks for Linux distributions which are common to the distributions.
+const builtinChecks = `
+- id: passwd_permissions
+  title: Permissions on /etc/passwd
+  type: file
+  path: /etc/passwd
+  mode: "0644"
+  owner: root
+  group: root
+- id: shadow_permissions
+  title: Permissions on /etc/shadow
+  type: file
+  path: /etc/shadow
+  mode: "0640"
+  owner: root
+- id: group_permissions
+  title: Permissions on /etc/group
+  type: file
+  path: /etc/group
+  mode: "0644"
+  owner: root
+  group: root
+- id: gshadow_permissions
+  title: Permissions on /etc/gshadow
+  type: file
+  path: /etc/gshadow
+  mode: "0640"
+  owner: root
+- id: sshd_config_permissions
+  title: Permissions on /etc/ssh/sshd_config
+  type: file
+  path: /etc/ssh/sshd_config
+  mode: "0600"
+  owner: root
+  group: root
+- id: crontab_permissions
+  title: Permissions on /etc/crontab
+  type: file
+  path: /etc/crontab
+  mode: "0600"
+  owner: root
+  group: root
+
+- id: ip_forward_disabled
+  title: IP forwarding is disabled
+  type: sysctl
+  key: net.ipv4.ip_forward
+  value: "0"
+- id: send_redirects_disabled
+  title: Packet redirect sending is disabled
+  type: sysctl
+  key: net.ipv4.conf.all.send_redirects
+  value: "0"
+- id: accept_redirects_disabled
+  title: ICMP redirects are not accepted
+  type: sysctl
+  key: net.ipv4.conf.all.accept_redirects
+  value: "0"
+- id: accept_source_route_disabled
+  title: Source routed packets are not accepted
+  type: sysctl
+  key: net.ipv4.conf.all.accept_source_route
+  value: "0"
+- id: log_martians_enabled
+  title: Suspicious packets are logged
+  type: sysctl
+  key: net.ipv4.conf.all.log_martians
+  value: "1"
+- id: tcp_syncookies_enabled
+  title: TCP SYN cookies are enabled
+  type: sysctl
+  key: net.ipv4.tcp_syncookies
+  value: "1"
+- id: aslr_enabled
+  title: Address space layout randomization is enabled
+  type: sysctl
+  key: kernel.randomize_va_space
+  value: "2"
+- id: suid_dumpable_disabled
+  title: Core dumps of setuid programs are disabled
+  type: sysctl
+  key: fs.suid_dumpable
+  value: "0"
+
+- id: auditd_installed
+  title: auditd is installed
+  type: service
+  name: auditd
+- id: avahi_not_installed
+  title: Avahi server is not installed
+  type: service
+  name: avahi-daemon
+  present: false
+- id: cups_not_installed
+  title: CUPS is not installed
+  type: service
+  name: cups
+  present: false
+- id: rpcbind_not_installed
+  title: rpcbind is not installed
+  type: service
+  name: rpcbind
+  present: false
+- id: vsftpd_not_installed
+  title: FTP server is not installed
+  type: service
+  name: vsftpd
+  present: false
+- id: telnet_not_installed
+  title: telnet server is not installed
+  type: service
+  name: telnet.socket
+  present: false
+- id: tftp_not_installed
+  title: TFTP server is not installed
+  type: service
+  name: tftp.socket
+  present: false
+- id: rsh_not_installed
+  title: rsh server is not installed
+  type: service
+  name: rsh.socket
+  present: false
+`
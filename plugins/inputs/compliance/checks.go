@@ -0,0 +1,213 @@
+package compliance
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	checkFile    = "file"
+	checkSysctl  = "sysctl"
+	checkService = "service"
+)
+
+// unitDirs are the directories of the systemd units, by precedence.
+var unitDirs = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// Check is a check of the configuration of the host, read from the YAML
+// files of checks.
+type Check struct {
+	ID    string `yaml:"id"`
+	Title string `yaml:"title"`
+	Type  string `yaml:"type"`
+
+	// File checks: whether the file exists, and its maximum permissions and
+	// its owner and group, by name or id.
+	Path   string `yaml:"path"`
+	Exists *bool  `yaml:"exists"`
+	Mode   string `yaml:"mode"`
+	Owner  string `yaml:"owner"`
+	Group  string `yaml:"group"`
+
+	// Sysctl checks: the value of the kernel parameter.
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+
+	// Service checks: whether the systemd unit is installed, the units
+	// without suffix being services.
+	Name    string `yaml:"name"`
+	Present *bool  `yaml:"present"`
+
+	mode uint32
+}
+
+func (c *Check) init() error {
+	if c.ID == "" {
+		return fmt.Errorf("check without id")
+	}
+
+	switch c.Type {
+	case checkFile:
+		if c.Path == "" {
+			return fmt.Errorf("check %s: file check without path", c.ID)
+		}
+		if c.Mode != "" {
+			mode, err := strconv.ParseUint(c.Mode, 8, 32)
+			if err != nil || mode > 07777 {
+				return fmt.Errorf("check %s: invalid mode %q", c.ID, c.Mode)
+			}
+			c.mode = uint32(mode)
+		}
+	case checkSysctl:
+		if c.Key == "" {
+			return fmt.Errorf("check %s: sysctl check without key", c.ID)
+		}
+	case checkService:
+		if c.Name == "" {
+			return fmt.Errorf("check %s: service check without name", c.ID)
+		}
+	default:
+		return fmt.Errorf("check %s: unknown type %q", c.ID, c.Type)
+	}
+	return nil
+}
+
+// run runs the check, returning whether it passed and why it failed.  An
+// error is returned when the check could not be run.
+func (c *Compliance) run(check *Check) (bool, string, error) {
+	switch check.Type {
+	case checkFile:
+		return c.runFile(check)
+	case checkSysctl:
+		return c.runSysctl(check)
+	case checkService:
+		return c.runService(check)
+	}
+	return false, "", fmt.Errorf("unknown type %q", check.Type)
+}
+
+func (c *Compliance) runFile(check *Check) (bool, string, error) {
+	exists := check.Exists == nil || *check.Exists
+
+	info, err := os.Stat(filepath.Join(c.root, check.Path))
+	if os.IsNotExist(err) {
+		if exists {
+			return false, "missing", nil
+		}
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if !exists {
+		return false, "exists", nil
+	}
+
+	var failures []string
+	// The permissions must not exceed the mode of the check.
+	if mode := fileMode(info.Mode()); check.Mode != "" && mode&^check.mode != 0 {
+		failures = append(failures, fmt.Sprintf("mode %04o exceeds %04o", mode, check.mode))
+	}
+	if check.Owner != "" || check.Group != "" {
+		owner, group, err := fileOwner(info)
+		if err != nil {
+			return false, "", err
+		}
+		if check.Owner != "" && !owner.matches(check.Owner) {
+			failures = append(failures, fmt.Sprintf("owner %s is not %s", owner, check.Owner))
+		}
+		if check.Group != "" && !group.matches(check.Group) {
+			failures = append(failures, fmt.Sprintf("group %s is not %s", group, check.Group))
+		}
+	}
+	return len(failures) == 0, strings.Join(failures, ", "), nil
+}
+
+// fileMode returns the mode as the octal permissions of chmod, the special
+// bits of os.FileMode not being stored in the permission bits.
+func fileMode(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return m
+}
+
+// id is the name and id of a user or group, the name being empty when it
+// cannot be resolved.
+type id struct {
+	name string
+	id   string
+}
+
+func (i id) matches(s string) bool {
+	return s == i.id || (i.name != "" && s == i.name)
+}
+
+func (i id) String() string {
+	if i.name == "" {
+		return i.id
+	}
+	return i.name
+}
+
+func (c *Compliance) runSysctl(check *Check) (bool, string, error) {
+	path := filepath.Join(c.root, "/proc/sys", strings.Replace(check.Key, ".", "/", -1))
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, "", err
+	}
+
+	// The values of several numbers are separated by tabs.
+	value := strings.Join(strings.Fields(string(content)), " ")
+	if value != strings.Join(strings.Fields(check.Value), " ") {
+		return false, fmt.Sprintf("value is %q", value), nil
+	}
+	return true, "", nil
+}
+
+func (c *Compliance) runService(check *Check) (bool, string, error) {
+	present := check.Present == nil || *check.Present
+
+	name := check.Name
+	if !strings.Contains(name, ".") {
+		name += ".service"
+	}
+
+	installed := false
+	for _, dir := range unitDirs {
+		path := filepath.Join(c.root, dir, name)
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		// The masked units are links to /dev/null.
+		if target, err := os.Readlink(path); err == nil && target == "/dev/null" {
+			break
+		}
+		installed = true
+		break
+	}
+
+	switch {
+	case present && !installed:
+		return false, "not installed", nil
+	case !present && installed:
+		return false, "installed", nil
+	}
+	return true, "", nil
+}
@@ -0,0 +1,169 @@
+package compliance
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Compliance runs checks of the configuration of the host, such as the
+// permissions of files, the kernel parameters and the installed services.
+type Compliance struct {
+	BuiltinChecks bool     `toml:"builtin_checks"`
+	ChecksFiles   []string `toml:"checks_files"`
+	Include       []string `toml:"include"`
+	Exclude       []string `toml:"exclude"`
+
+	checks []*Check
+	// root is the prefix of the files checked, for tests.
+	root string
+}
+
+var sampleConfig = `
+  ## Run the built-in checks, a subset of the CIS benchmarks common to the
+  ## Linux distributions.
+  # builtin_checks = true
+
+  ## YAML files of checks, the checks override the checks with the same id.
+  ## The paths may contain globs.
+  # checks_files = ["/etc/telegraf/compliance/*.yaml"]
+
+  ## Ids of the checks to run or not to run, globs are supported.
+  # include = []
+  # exclude = []
+`
+
+func (c *Compliance) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Compliance) Description() string {
+	return "Run compliance checks of the files, kernel parameters and services of the host"
+}
+
+func (c *Compliance) Init() error {
+	var checks []*Check
+	if c.BuiltinChecks {
+		builtin, err := parseChecks([]byte(builtinChecks))
+		if err != nil {
+			return fmt.Errorf("built-in checks: %s", err)
+		}
+		checks = append(checks, builtin...)
+	}
+
+	for _, pattern := range c.ChecksFiles {
+		g, err := globpath.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("could not compile glob %q: %s", pattern, err)
+		}
+		files := g.Match()
+		sort.Strings(files)
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			fileChecks, err := parseChecks(content)
+			if err != nil {
+				return fmt.Errorf("%s: %s", file, err)
+			}
+			checks = append(checks, fileChecks...)
+		}
+	}
+
+	f, err := filter.NewIncludeExcludeFilter(c.Include, c.Exclude)
+	if err != nil {
+		return err
+	}
+
+	// The later checks override the earlier ones with the same id, at their
+	// position.
+	index := make(map[string]int)
+	c.checks = nil
+	for _, check := range checks {
+		if !f.Match(check.ID) {
+			continue
+		}
+		if i, ok := index[check.ID]; ok {
+			c.checks[i] = check
+			continue
+		}
+		index[check.ID] = len(c.checks)
+		c.checks = append(c.checks, check)
+	}
+
+	if len(c.checks) == 0 {
+		return fmt.Errorf("no checks to run")
+	}
+	return nil
+}
+
+func parseChecks(content []byte) ([]*Check, error) {
+	var checks []*Check
+	if err := yaml.Unmarshal(content, &checks); err != nil {
+		return nil, err
+	}
+	for _, check := range checks {
+		if err := check.init(); err != nil {
+			return nil, err
+		}
+	}
+	return checks, nil
+}
+
+func (c *Compliance) Gather(acc telegraf.Accumulator) error {
+	var passed, failed, errors int
+	for _, check := range c.checks {
+		ok, details, err := c.run(check)
+		if err != nil {
+			acc.AddError(fmt.Errorf("check %s: %s", check.ID, err))
+			errors++
+			continue
+		}
+
+		tags := map[string]string{
+			"check_id": check.ID,
+			"type":     check.Type,
+		}
+		if check.Title != "" {
+			tags["title"] = check.Title
+		}
+		fields := map[string]interface{}{
+			"passed": ok,
+		}
+		if ok {
+			passed++
+		} else {
+			fields["details"] = details
+			failed++
+		}
+		acc.AddFields("compliance_check", fields, tags)
+	}
+
+	fields := map[string]interface{}{
+		"checks": len(c.checks),
+		"passed": passed,
+		"failed": failed,
+		"errors": errors,
+	}
+	if passed+failed > 0 {
+		fields["score"] = float64(passed) * 100 / float64(passed+failed)
+	}
+	acc.AddFields("compliance", fields, nil)
+	return nil
+}
+
+func init() {
+	inputs.Add("compliance", func() telegraf.Input {
+		return &Compliance{
+			BuiltinChecks: true,
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package compliance
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// testRoot creates the files of the root of the tests.
+func testRoot(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "compliance")
+	require.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	return root
+}
+
+func TestGather(t *testing.T) {
+	root := testRoot(t, map[string]string{
+		"etc/passwd":                             "root:x:0:0::/root:/bin/sh\n",
+		"etc/shadow":                             "root:*::0:::::\n",
+		"proc/sys/net/ipv4/ip_forward":           "1\n",
+		"proc/sys/net/ipv4/tcp_syncookies":       "1\n",
+		"proc/sys/net/ipv4/ip_local_port_range":  "32768\t60999\n",
+		"lib/systemd/system/auditd.service":      "[Unit]\n",
+		"usr/lib/systemd/system/cups.service":    "[Unit]\n",
+		"usr/lib/systemd/system/rpcbind.service": "[Unit]\n",
+		"usr/lib/systemd/system/vsftpd.service":  "[Unit]\n",
+		"usr/lib/systemd/system/telnet.socket":   "[Unit]\n",
+	})
+	defer os.RemoveAll(root)
+	require.NoError(t, os.Chmod(filepath.Join(root, "etc/passwd"), 0644))
+	require.NoError(t, os.Chmod(filepath.Join(root, "etc/shadow"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc/systemd/system"), 0755))
+	require.NoError(t, os.Symlink("/dev/null", filepath.Join(root, "etc/systemd/system/rpcbind.service")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc/telegraf/compliance"), 0755))
+
+	current, err := user.Current()
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "etc/telegraf/compliance/01-files.yaml"), []byte(`
+- id: passwd_permissions
+  title: Permissions on /etc/passwd
+  type: file
+  path: /etc/passwd
+  mode: "0600"
+  owner: `+current.Username+`
+- id: shadow_permissions
+  type: file
+  path: /etc/shadow
+  mode: "0640"
+  owner: `+current.Uid+`
+- id: nologin_absent
+  type: file
+  path: /etc/nologin
+  exists: false
+- id: securetty_present
+  type: file
+  path: /etc/securetty
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "etc/telegraf/compliance/02-network.yaml"), []byte(`
+- id: ip_forward_disabled
+  type: sysctl
+  key: net.ipv4.ip_forward
+  value: "0"
+- id: tcp_syncookies_enabled
+  type: sysctl
+  key: net.ipv4.tcp_syncookies
+  value: "1"
+- id: local_port_range
+  type: sysctl
+  key: net.ipv4.ip_local_port_range
+  value: "32768 60999"
+- id: missing_sysctl
+  type: sysctl
+  key: net.ipv6.conf.all.forwarding
+  value: "0"
+- id: auditd_installed
+  type: service
+  name: auditd
+- id: cups_not_installed
+  type: service
+  name: cups
+  present: false
+- id: rpcbind_not_installed
+  type: service
+  name: rpcbind
+  present: false
+- id: telnet_not_installed
+  type: service
+  name: telnet.socket
+  present: false
+- id: avahi_not_installed
+  type: service
+  name: avahi-daemon
+  present: false
+`), 0644))
+
+	plugin := &Compliance{
+		ChecksFiles: []string{filepath.Join(root, "etc/telegraf/compliance/*.yaml")},
+		Exclude:     []string{"vsftpd*"},
+		root:        root,
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+
+	check := func(id, typ string, passed bool, details string) telegraf.Metric {
+		fields := map[string]interface{}{"passed": passed}
+		if !passed {
+			fields["details"] = details
+		}
+		return testutil.MustMetric("compliance_check",
+			map[string]string{"check_id": id, "type": typ},
+			fields,
+			time.Unix(0, 0),
+		)
+	}
+	passwd := check("passwd_permissions", "file", false, "mode 0644 exceeds 0600")
+	passwd.AddTag("title", "Permissions on /etc/passwd")
+	expected := []telegraf.Metric{
+		passwd,
+		check("shadow_permissions", "file", true, ""),
+		check("nologin_absent", "file", true, ""),
+		check("securetty_present", "file", false, "missing"),
+		check("ip_forward_disabled", "sysctl", false, `value is "1"`),
+		check("tcp_syncookies_enabled", "sysctl", true, ""),
+		check("local_port_range", "sysctl", true, ""),
+		check("auditd_installed", "service", true, ""),
+		check("cups_not_installed", "service", false, "installed"),
+		check("rpcbind_not_installed", "service", true, ""),
+		check("telnet_not_installed", "service", false, "installed"),
+		check("avahi_not_installed", "service", true, ""),
+		testutil.MustMetric("compliance",
+			map[string]string{},
+			map[string]interface{}{
+				"checks": 13,
+				"passed": 7,
+				"failed": 5,
+				"errors": 1,
+				"score":  float64(7) * 100 / 12,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestInitOverride(t *testing.T) {
+	root := testRoot(t, map[string]string{
+		"checks.yaml": `
+- id: ip_forward_disabled
+  type: sysctl
+  key: net.ipv4.ip_forward
+  value: "1"
+`,
+	})
+	defer os.RemoveAll(root)
+
+	plugin := &Compliance{
+		BuiltinChecks: true,
+		ChecksFiles:   []string{filepath.Join(root, "checks.yaml")},
+		Include:       []string{"ip_*", "aslr_enabled"},
+	}
+	require.NoError(t, plugin.Init())
+	require.Len(t, plugin.checks, 2)
+	require.Equal(t, "ip_forward_disabled", plugin.checks[0].ID)
+	require.Equal(t, "1", plugin.checks[0].Value)
+	require.Equal(t, "aslr_enabled", plugin.checks[1].ID)
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, checks := range []string{
+		"- type: sysctl\n  key: kernel.randomize_va_space\n",
+		"- id: a\n  type: registry\n",
+		"- id: a\n  type: file\n  path: /etc/passwd\n  mode: rw\n",
+		"- id: a\n  type: service\n",
+	} {
+		root := testRoot(t, map[string]string{"checks.yaml": checks})
+		defer os.RemoveAll(root)
+
+		plugin := &Compliance{
+			ChecksFiles: []string{filepath.Join(root, "checks.yaml")},
+		}
+		require.Error(t, plugin.Init(), checks)
+	}
+
+	require.Error(t, (&Compliance{}).Init())
+}
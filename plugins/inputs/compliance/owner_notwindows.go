@@ -0,0 +1,29 @@
+// +build !windows
+
+package compliance
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner returns the owner and group of the file.
+func fileOwner(info os.FileInfo) (id, id, error) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return id{}, id{}, fmt.Errorf("no owner for %s", info.Name())
+	}
+
+	owner := id{id: strconv.FormatUint(uint64(stat.Uid), 10)}
+	if u, err := user.LookupId(owner.id); err == nil {
+		owner.name = u.Username
+	}
+	group := id{id: strconv.FormatUint(uint64(stat.Gid), 10)}
+	if g, err := user.LookupGroupId(group.id); err == nil {
+		group.name = g.Name
+	}
+	return owner, group, nil
+}
@@ -0,0 +1,11 @@
+package compliance
+
+import (
+	"fmt"
+	"os"
+)
+
+// fileOwner returns the owner and group of the file.
+func fileOwner(info os.FileInfo) (id, id, error) {
+	return id{}, id{}, fmt.Errorf("file owners are not supported on windows")
+}
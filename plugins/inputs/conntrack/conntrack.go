@@ -3,13 +3,16 @@
 package conntrack
 
 import (
+	"bufio"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"path/filepath"
 )
@@ -18,6 +21,15 @@ type Conntrack struct {
 	Path  string
 	Dirs  []string
 	Files []string
+
+	Entries    bool              `toml:"entries"`
+	ZoneLimits map[string]int64  `toml:"zone_limits"`
+	Nftables   bool              `toml:"nftables"`
+	UseSudo    bool              `toml:"use_sudo"`
+	Timeout    internal.Duration `toml:"timeout"`
+
+	procPath string
+	nft      func(timeout time.Duration, useSudo bool) ([]byte, error)
 }
 
 const (
@@ -63,6 +75,23 @@ var sampleConfig = `
    ## Directories to search within for the conntrack files above.
    ## Missing directrories will be ignored.
    dirs = ["/proc/sys/net/ipv4/netfilter","/proc/sys/net/netfilter"]
+
+   ## Count the entries of the conntrack table by protocol and state, and by
+   ## zone.  Reading the table is expensive on large tables.
+   # entries = false
+
+   ## Read the named counters and sets of nftables with "nft -j".
+   # nftables = false
+
+   ## Run nft with sudo, which must be configured to not ask for a password.
+   # use_sudo = false
+
+   ## Timeout for the nft command to complete.
+   # timeout = "5s"
+
+   ## Limits of the conntrack zones, to report the usage of each zone.
+   # [inputs.conntrack.zone_limits]
+   #   "1" = 65536
 `
 
 func (c *Conntrack) SampleConfig() string {
@@ -111,9 +140,103 @@ func (c *Conntrack) Gather(acc telegraf.Accumulator) error {
 	}
 
 	acc.AddFields(inputName, fields, nil)
+
+	if c.Entries {
+		if err := c.gatherEntries(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+
+	if c.Nftables {
+		if err := c.gatherNftables(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// entryKey identifies the entries of a protocol and state.
+type entryKey struct {
+	family   string
+	protocol string
+	state    string
+}
+
+// gatherEntries counts the entries of the conntrack table, which has lines
+// like "ipv4 2 tcp 6 117 TIME_WAIT src=10.0.0.1 ... zone=3 use=2".
+func (c *Conntrack) gatherEntries(acc telegraf.Accumulator) error {
+	f, err := os.Open(filepath.Join(c.procPath, "net", "nf_conntrack"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries := make(map[entryKey]int64)
+	zones := make(map[string]int64)
+	for zone := range c.ZoneLimits {
+		zones[zone] = 0
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 6 {
+			continue
+		}
+
+		key := entryKey{family: parts[0], protocol: parts[2]}
+		// Only the connection oriented protocols have a state
+		if !strings.Contains(parts[5], "=") {
+			key.state = parts[5]
+		}
+		entries[key]++
+
+		// The zone is only shown for the entries not in the default zone
+		zone := "0"
+		for _, part := range parts[6:] {
+			if strings.HasPrefix(part, "zone=") {
+				zone = strings.TrimPrefix(part, "zone=")
+				break
+			}
+		}
+		zones[zone]++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for key, count := range entries {
+		tags := map[string]string{
+			"family":   key.family,
+			"protocol": key.protocol,
+		}
+		if key.state != "" {
+			tags["state"] = key.state
+		}
+		acc.AddGauge(inputName+"_entries", map[string]interface{}{"entries": count}, tags)
+	}
+
+	for zone, count := range zones {
+		fields := map[string]interface{}{"entries": count}
+		if limit, ok := c.ZoneLimits[zone]; ok && limit > 0 {
+			fields["limit"] = limit
+			fields["used_percent"] = 100 * float64(count) / float64(limit)
+		}
+		acc.AddGauge(inputName+"_zone", fields, map[string]string{"zone": zone})
+	}
 	return nil
 }
 
 func init() {
-	inputs.Add(inputName, func() telegraf.Input { return &Conntrack{} })
+	inputs.Add(inputName, func() telegraf.Input {
+		procPath := "/proc"
+		if hostProc := os.Getenv("HOST_PROC"); hostProc != "" {
+			procPath = hostProc
+		}
+		return &Conntrack{
+			Timeout:  internal.Duration{Duration: 5 * time.Second},
+			procPath: procPath,
+			nft:      runNft,
+		}
+	})
 }
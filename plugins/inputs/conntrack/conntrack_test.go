@@ -6,12 +6,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func restoreDflts(savedFiles, savedDirs []string) {
@@ -92,3 +96,117 @@ func TestConfigsUsed(t *testing.T) {
 			fix(maxFname): float64(max),
 		})
 }
+
+const nfConntrack = `ipv4     2 tcp      6 431999 ESTABLISHED src=10.0.0.1 dst=10.0.0.2 sport=22 dport=51234 src=10.0.0.2 dst=10.0.0.1 sport=51234 dport=22 [ASSURED] mark=0 use=1
+ipv4     2 tcp      6 117 TIME_WAIT src=10.0.0.1 dst=10.0.0.3 sport=443 dport=40000 src=10.0.0.3 dst=10.0.0.1 sport=40000 dport=443 [ASSURED] mark=0 zone=1 use=1
+ipv4     2 tcp      6 431999 ESTABLISHED src=10.0.0.1 dst=10.0.0.4 sport=22 dport=51235 src=10.0.0.4 dst=10.0.0.1 sport=51235 dport=22 [ASSURED] mark=0 zone=1 use=1
+ipv4     2 udp      17 28 src=10.0.0.1 dst=10.0.0.53 sport=41000 dport=53 [UNREPLIED] src=10.0.0.53 dst=10.0.0.1 sport=53 dport=41000 mark=0 use=1
+ipv6     10 icmpv6   58 29 src=fe80::1 dst=fe80::2 type=128 code=0 id=1 src=fe80::2 dst=fe80::1 type=129 code=0 id=1 mark=0 use=1
+`
+
+const nftRuleset = `{"nftables": [{"metainfo": {"version": "0.9.3", "release_name": "Topsy", "json_schema_version": 1}},
+{"table": {"family": "inet", "name": "filter", "handle": 1}},
+{"counter": {"family": "inet", "name": "ssh", "table": "filter", "handle": 2, "packets": 1024, "bytes": 65536}},
+{"set": {"family": "inet", "name": "blocklist", "table": "filter", "type": "ipv4_addr", "handle": 3, "size": 4, "elem": ["10.0.0.5", "10.0.0.6", {"prefix": {"addr": "192.168.0.0", "len": 16}}]}},
+{"set": {"family": "inet", "name": "allowed", "table": "filter", "type": ["ipv4_addr", "inet_service"], "handle": 4}}]}
+`
+
+func TestGatherEntries(t *testing.T) {
+	defer restoreDflts(dfltFiles, dfltDirs)
+	tmpdir, err := ioutil.TempDir("", "conntrack")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "nf_conntrack_count"), []byte("5\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpdir, "net"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "net", "nf_conntrack"), []byte(nfConntrack), 0644))
+
+	dfltDirs = []string{tmpdir}
+	dfltFiles = []string{"nf_conntrack_count"}
+	c := &Conntrack{
+		Entries:    true,
+		ZoneLimits: map[string]int64{"1": 4, "2": 100},
+		procPath:   tmpdir,
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, c.Gather(acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(inputName,
+			map[string]string{},
+			map[string]interface{}{"ip_conntrack_count": float64(5)},
+			time.Unix(0, 0)),
+		testutil.MustMetric(inputName+"_entries",
+			map[string]string{"family": "ipv4", "protocol": "tcp", "state": "ESTABLISHED"},
+			map[string]interface{}{"entries": int64(2)},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric(inputName+"_entries",
+			map[string]string{"family": "ipv4", "protocol": "tcp", "state": "TIME_WAIT"},
+			map[string]interface{}{"entries": int64(1)},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric(inputName+"_entries",
+			map[string]string{"family": "ipv4", "protocol": "udp"},
+			map[string]interface{}{"entries": int64(1)},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric(inputName+"_entries",
+			map[string]string{"family": "ipv6", "protocol": "icmpv6"},
+			map[string]interface{}{"entries": int64(1)},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric(inputName+"_zone",
+			map[string]string{"zone": "0"},
+			map[string]interface{}{"entries": int64(3)},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric(inputName+"_zone",
+			map[string]string{"zone": "1"},
+			map[string]interface{}{"entries": int64(2), "limit": int64(4), "used_percent": 50.0},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric(inputName+"_zone",
+			map[string]string{"zone": "2"},
+			map[string]interface{}{"entries": int64(0), "limit": int64(100), "used_percent": 0.0},
+			time.Unix(0, 0), telegraf.Gauge),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherNftables(t *testing.T) {
+	defer restoreDflts(dfltFiles, dfltDirs)
+	tmpdir, err := ioutil.TempDir("", "conntrack")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "nf_conntrack_count"), []byte("5\n"), 0644))
+
+	dfltDirs = []string{tmpdir}
+	dfltFiles = []string{"nf_conntrack_count"}
+	c := &Conntrack{
+		Nftables: true,
+		nft: func(time.Duration, bool) ([]byte, error) {
+			return []byte(nftRuleset), nil
+		},
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, c.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(inputName,
+			map[string]string{},
+			map[string]interface{}{"ip_conntrack_count": float64(5)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("nftables_counter",
+			map[string]string{"family": "inet", "table": "filter", "name": "ssh"},
+			map[string]interface{}{"packets": uint64(1024), "bytes": uint64(65536)},
+			time.Unix(0, 0), telegraf.Counter),
+		testutil.MustMetric("nftables_set",
+			map[string]string{"family": "inet", "table": "filter", "name": "blocklist", "type": "ipv4_addr"},
+			map[string]interface{}{"elements": int64(3), "size": int64(4), "used_percent": 75.0},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric("nftables_set",
+			map[string]string{"family": "inet", "table": "filter", "name": "allowed"},
+			map[string]interface{}{"elements": int64(0)},
+			time.Unix(0, 0), telegraf.Gauge),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
@@ -0,0 +1,114 @@
+// +build linux
+
+package conntrack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// nftOutput is the JSON output of "nft -j list ruleset", an array of objects
+// with one key naming the object type.
+type nftOutput struct {
+	Nftables []struct {
+		Counter *nftCounter `json:"counter"`
+		Set     *nftSet     `json:"set"`
+	} `json:"nftables"`
+}
+
+type nftCounter struct {
+	Family  string `json:"family"`
+	Table   string `json:"table"`
+	Name    string `json:"name"`
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+type nftSet struct {
+	Family string            `json:"family"`
+	Table  string            `json:"table"`
+	Name   string            `json:"name"`
+	Type   json.RawMessage   `json:"type"`
+	Size   int64             `json:"size"`
+	Elem   []json.RawMessage `json:"elem"`
+}
+
+func runNft(timeout time.Duration, useSudo bool) ([]byte, error) {
+	bin, err := exec.LookPath("nft")
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"-j", "list", "ruleset"}
+	if useSudo {
+		args = append([]string{"-n", bin}, args...)
+		bin = "sudo"
+	}
+
+	cmd := exec.Command(bin, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, timeout); err != nil {
+		return nil, fmt.Errorf("running nft failed: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// gatherNftables adds the named counters and the number of elements of the
+// named sets of the nftables ruleset.
+func (c *Conntrack) gatherNftables(acc telegraf.Accumulator) error {
+	out, err := c.nft(c.Timeout.Duration, c.UseSudo)
+	if err != nil {
+		return err
+	}
+
+	var ruleset nftOutput
+	if err := json.Unmarshal(out, &ruleset); err != nil {
+		return fmt.Errorf("parsing nft output failed: %v", err)
+	}
+
+	for _, object := range ruleset.Nftables {
+		switch {
+		case object.Counter != nil:
+			counter := object.Counter
+			tags := map[string]string{
+				"family": counter.Family,
+				"table":  counter.Table,
+				"name":   counter.Name,
+			}
+			fields := map[string]interface{}{
+				"packets": counter.Packets,
+				"bytes":   counter.Bytes,
+			}
+			acc.AddCounter("nftables_counter", fields, tags)
+		case object.Set != nil:
+			set := object.Set
+			tags := map[string]string{
+				"family": set.Family,
+				"table":  set.Table,
+				"name":   set.Name,
+			}
+			// The type of concatenated sets is an array of types
+			var typ string
+			if err := json.Unmarshal(set.Type, &typ); err == nil {
+				tags["type"] = typ
+			}
+
+			fields := map[string]interface{}{
+				"elements": int64(len(set.Elem)),
+			}
+			// The size is only known if declared or for dynamic sets
+			if set.Size > 0 {
+				fields["size"] = set.Size
+				fields["used_percent"] = 100 * float64(len(set.Elem)) / float64(set.Size)
+			}
+			acc.AddGauge("nftables_set", fields, tags)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package coredns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type Zone struct {
+	Name    string `toml:"name"`
+	Primary string `toml:"primary"`
+}
+
+type CoreDNS struct {
+	HealthURL string            `toml:"health_url"`
+	ReadyURL  string            `toml:"ready_url"`
+	Server    string            `toml:"server"`
+	Zones     []Zone            `toml:"zone"`
+	Timeout   internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+	// lookupSerial returns the SOA serial of the zone on the server.
+	lookupSerial func(server, zone string) (uint32, error)
+}
+
+var sampleConfig = `
+  ## URL of the health plugin of CoreDNS.
+  health_url = "http://localhost:8080/health"
+
+  ## URL of the ready plugin of CoreDNS, listing the plugins of the chain
+  ## which are not ready.
+  ready_url = "http://localhost:8181/ready"
+
+  ## Address of CoreDNS to query the SOA serial of the zones from.
+  # server = "127.0.0.1:53"
+
+  ## Timeout of the HTTP requests and DNS queries.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Zones transferred by CoreDNS with the secondary plugin, their SOA serial
+  ## is compared with the serial of the primary to report the transfer
+  ## status.
+  # [[inputs.coredns.zone]]
+  #   name = "example.org"
+  #   primary = "10.0.0.1:53"
+`
+
+func (c *CoreDNS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CoreDNS) Description() string {
+	return "Read the health, plugin readiness and zone transfer status of CoreDNS"
+}
+
+func (c *CoreDNS) Init() error {
+	if c.Server == "" {
+		c.Server = "127.0.0.1:53"
+	}
+	if c.Timeout.Duration == 0 {
+		c.Timeout.Duration = 5 * time.Second
+	}
+	for _, zone := range c.Zones {
+		if zone.Name == "" || zone.Primary == "" {
+			return fmt.Errorf("zones need a name and a primary")
+		}
+	}
+
+	tlsCfg, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	c.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: c.Timeout.Duration,
+	}
+	c.lookupSerial = c.querySerial
+	return nil
+}
+
+func (c *CoreDNS) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+
+	if c.HealthURL != "" || c.ReadyURL != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.gatherHealth(acc)
+		}()
+	}
+
+	for _, zone := range c.Zones {
+		wg.Add(1)
+		go func(zone Zone) {
+			defer wg.Done()
+			acc.AddError(c.gatherZone(acc, zone))
+		}(zone)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// gatherHealth reports whether CoreDNS is healthy and its plugins are ready,
+// the plugins not ready being reported individually.
+func (c *CoreDNS) gatherHealth(acc telegraf.Accumulator) {
+	fields := make(map[string]interface{})
+
+	if c.HealthURL != "" {
+		ok, _, err := c.get(c.HealthURL)
+		if err != nil {
+			acc.AddError(err)
+		}
+		fields["healthy"] = ok
+	}
+
+	if c.ReadyURL != "" {
+		ok, body, err := c.get(c.ReadyURL)
+		if err != nil {
+			acc.AddError(err)
+		}
+		fields["ready"] = ok
+
+		// The ready plugin answers with the comma separated plugins which
+		// are not ready.
+		var notReady []string
+		if !ok && err == nil {
+			for _, plugin := range strings.Split(body, ",") {
+				if plugin = strings.TrimSpace(plugin); plugin != "" {
+					notReady = append(notReady, plugin)
+				}
+			}
+		}
+		fields["plugins_not_ready"] = len(notReady)
+		for _, plugin := range notReady {
+			acc.AddFields("coredns_plugin",
+				map[string]interface{}{"ready": false},
+				map[string]string{"plugin": plugin},
+			)
+		}
+	}
+
+	acc.AddFields("coredns", fields, nil)
+}
+
+// get returns whether the URL answered 200 OK, and the body otherwise.  An
+// error is only returned when CoreDNS did not answer.
+func (c *CoreDNS) get(url string) (bool, string, error) {
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return false, "", fmt.Errorf("error making HTTP request to %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return true, "", nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", fmt.Errorf("error reading response from %s: %s", url, err)
+	}
+	return false, string(body), nil
+}
+
+// gatherZone compares the SOA serial of the zone on CoreDNS with the serial
+// on the primary.
+func (c *CoreDNS) gatherZone(acc telegraf.Accumulator, zone Zone) error {
+	serial, err := c.lookupSerial(c.Server, zone.Name)
+	if err != nil {
+		return fmt.Errorf("zone %s: %s", zone.Name, err)
+	}
+	primarySerial, err := c.lookupSerial(zone.Primary, zone.Name)
+	if err != nil {
+		return fmt.Errorf("zone %s: primary %s: %s", zone.Name, zone.Primary, err)
+	}
+
+	// The serials are compared with the serial number arithmetic of
+	// RFC 1982, the lag is negative when the primary is behind.
+	lag := int32(primarySerial - serial)
+	acc.AddFields("coredns_zone",
+		map[string]interface{}{
+			"serial":         serial,
+			"primary_serial": primarySerial,
+			"serial_lag":     lag,
+			"in_sync":        lag == 0,
+		},
+		map[string]string{
+			"zone":    zone.Name,
+			"primary": zone.Primary,
+		},
+	)
+	return nil
+}
+
+func (c *CoreDNS) querySerial(server, zone string) (uint32, error) {
+	client := new(dns.Client)
+	client.Timeout = c.Timeout.Duration
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+
+	r, _, err := client.Exchange(m, server)
+	if err != nil {
+		return 0, err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return 0, fmt.Errorf("invalid answer (%s) from %s for SOA of %s", dns.RcodeToString[r.Rcode], server, zone)
+	}
+	for _, rr := range r.Answer {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa.Serial, nil
+		}
+	}
+	return 0, fmt.Errorf("no SOA for %s on %s", zone, server)
+}
+
+func init() {
+	inputs.Add("coredns", func() telegraf.Input {
+		return &CoreDNS{}
+	})
+}
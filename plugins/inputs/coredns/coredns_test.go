@@ -0,0 +1,171 @@
+package coredns
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeCoreDNS(ready string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			fmt.Fprint(w, "OK")
+		case "/ready":
+			if ready != "" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, ready)
+				return
+			}
+			fmt.Fprint(w, "OK")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestGatherHealth(t *testing.T) {
+	ts := fakeCoreDNS("")
+	defer ts.Close()
+
+	plugin := &CoreDNS{
+		HealthURL: ts.URL + "/health",
+		ReadyURL:  ts.URL + "/ready",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("coredns",
+			map[string]string{},
+			map[string]interface{}{
+				"healthy":           true,
+				"ready":             true,
+				"plugins_not_ready": 0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherNotReady(t *testing.T) {
+	ts := fakeCoreDNS("kubernetes,erratic")
+	defer ts.Close()
+
+	plugin := &CoreDNS{
+		HealthURL: ts.URL + "/health",
+		ReadyURL:  ts.URL + "/ready",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("coredns",
+			map[string]string{},
+			map[string]interface{}{
+				"healthy":           true,
+				"ready":             false,
+				"plugins_not_ready": 2,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("coredns_plugin",
+			map[string]string{"plugin": "kubernetes"},
+			map[string]interface{}{"ready": false},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("coredns_plugin",
+			map[string]string{"plugin": "erratic"},
+			map[string]interface{}{"ready": false},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherDown(t *testing.T) {
+	ts := fakeCoreDNS("")
+	ts.Close()
+
+	plugin := &CoreDNS{
+		HealthURL: ts.URL + "/health",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	acc.AssertContainsFields(t, "coredns", map[string]interface{}{"healthy": false})
+}
+
+func TestGatherZones(t *testing.T) {
+	plugin := &CoreDNS{
+		Zones: []Zone{
+			{Name: "example.org", Primary: "10.0.0.1:53"},
+			{Name: "example.com", Primary: "10.0.0.2:53"},
+			{Name: "example.net", Primary: "10.0.0.3:53"},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	serials := map[string]uint32{
+		"127.0.0.1:53/example.org": 2020030301,
+		"10.0.0.1:53/example.org":  2020030301,
+		"127.0.0.1:53/example.com": 4294967295,
+		"10.0.0.2:53/example.com":  2,
+		"127.0.0.1:53/example.net": 12,
+	}
+	plugin.lookupSerial = func(server, zone string) (uint32, error) {
+		serial, ok := serials[server+"/"+zone]
+		if !ok {
+			return 0, fmt.Errorf("timeout")
+		}
+		return serial, nil
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("coredns_zone",
+			map[string]string{"zone": "example.org", "primary": "10.0.0.1:53"},
+			map[string]interface{}{
+				"serial":         uint64(2020030301),
+				"primary_serial": uint64(2020030301),
+				"serial_lag":     int64(0),
+				"in_sync":        true,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("coredns_zone",
+			map[string]string{"zone": "example.com", "primary": "10.0.0.2:53"},
+			map[string]interface{}{
+				"serial":         uint64(4294967295),
+				"primary_serial": uint64(2),
+				"serial_lag":     int64(3),
+				"in_sync":        false,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestInitZoneWithoutPrimary(t *testing.T) {
+	plugin := &CoreDNS{
+		Zones: []Zone{{Name: "example.org"}},
+	}
+	require.Error(t, plugin.Init())
+}
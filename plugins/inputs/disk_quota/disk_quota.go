@@ -0,0 +1,288 @@
+package disk_quota
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+)
+
+const sampleConfig = `
+  ## Mount points to collect, globs are supported.  All mount points of the
+  ## filesystem types are collected by default.
+  # mount_points = []
+
+  ## Filesystem types to collect.
+  # fstypes = ["ext2", "ext3", "ext4", "xfs"]
+
+  ## Collect the quotas of the filesystems mounted with quotas enabled with
+  ## "repquota", which must run as root.
+  # quotas = true
+
+  ## DiskQuota types to collect, "user", "group" or "project".
+  # quota_types = ["user", "group", "project"]
+
+  ## Duration the inode consumption rate is computed over.
+  # trend_window = "1h"
+
+  ## Run repquota with sudo, which must be configured to not ask for a
+  ## password.
+  # use_sudo = false
+
+  ## Timeout for repquota to complete.
+  # timeout = "5s"
+`
+
+// repquotaFlags are the flags of repquota selecting the quota type.
+var repquotaFlags = map[string]string{
+	"user":    "-u",
+	"group":   "-g",
+	"project": "-P",
+}
+
+// quotaOptions are the mount options enabling each quota type on ext4 and
+// xfs.
+var quotaOptions = map[string][]string{
+	"user":    {"usrquota", "uquota", "usrjquota", "uqnoenforce", "qnoenforce", "quota"},
+	"group":   {"grpquota", "gquota", "grpjquota", "gqnoenforce"},
+	"project": {"prjquota", "pquota", "pqnoenforce"},
+}
+
+// DiskQuota gathers the quota usage and the inode consumption of the
+// filesystems.
+type DiskQuota struct {
+	MountPoints []string          `toml:"mount_points"`
+	FSTypes     []string          `toml:"fstypes"`
+	Quotas      bool              `toml:"quotas"`
+	QuotaTypes  []string          `toml:"quota_types"`
+	TrendWindow internal.Duration `toml:"trend_window"`
+	UseSudo     bool              `toml:"use_sudo"`
+	Timeout     internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	procPath    string
+	mountPrefix string
+	mountFilter filter.Filter
+	history     map[string][]inodeSample
+	repquota    func(mountpoint, quotaType string, timeout internal.Duration, useSudo bool) ([]byte, error)
+	statfs      func(path string) (total, free uint64, err error)
+}
+
+func (q *DiskQuota) Description() string {
+	return "Gather user, group and project quota usage and inode consumption trends of filesystems"
+}
+
+func (q *DiskQuota) SampleConfig() string {
+	return sampleConfig
+}
+
+// mount is a filesystem of the mount table.
+type mount struct {
+	device     string
+	mountpoint string
+	fstype     string
+	options    map[string]bool
+}
+
+// quotaTypes returns the quota types enabled by the mount options.
+func (m *mount) quotaTypes() []string {
+	var types []string
+	for _, typ := range []string{"user", "group", "project"} {
+		for _, option := range quotaOptions[typ] {
+			if m.options[option] {
+				types = append(types, typ)
+				break
+			}
+		}
+	}
+	return types
+}
+
+// parseMounts returns the mounts of the mount table, with the mounts of a
+// device mounted several times only returned once.
+func parseMounts(r io.Reader) ([]*mount, error) {
+	var mounts []*mount
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 4 || seen[parts[0]] {
+			continue
+		}
+		seen[parts[0]] = true
+
+		options := make(map[string]bool)
+		for _, option := range strings.Split(parts[3], ",") {
+			// Options such as usrjquota=aquota.user
+			if i := strings.IndexByte(option, '='); i >= 0 {
+				option = option[:i]
+			}
+			options[option] = true
+		}
+		mounts = append(mounts, &mount{
+			device:     parts[0],
+			mountpoint: unescapeMount(parts[1]),
+			fstype:     parts[2],
+			options:    options,
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMount decodes the octal escapes of the mount table, such as
+// "\040" for a space.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// quotaEntry is the quota of a user, group or project.
+type quotaEntry struct {
+	name   string
+	fields map[string]interface{}
+}
+
+// repquotaColumns are the fields of the numeric columns of the CSV output of
+// repquota, the block columns are in kilobytes.
+var repquotaColumns = map[string]string{
+	"BlockUsed":      "used_bytes",
+	"BlockSoftLimit": "soft_limit_bytes",
+	"BlockHardLimit": "hard_limit_bytes",
+	"FileUsed":       "files_used",
+	"FileSoftLimit":  "files_soft_limit",
+	"FileHardLimit":  "files_hard_limit",
+}
+
+// parseRepquota returns the quotas of the CSV output of repquota, which has
+// a header with the columns such as "User,BlockStatus,FileStatus,BlockUsed".
+func parseRepquota(out []byte) ([]*quotaEntry, error) {
+	r := csv.NewReader(bytes.NewReader(out))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var header []string
+	var entries []*quotaEntry
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing repquota output failed: %v", err)
+		}
+		// The output may have report headlines without commas
+		if len(record) < 2 {
+			continue
+		}
+		if header == nil {
+			header = record
+			continue
+		}
+		if len(record) != len(header) {
+			continue
+		}
+
+		entry := &quotaEntry{name: record[0], fields: make(map[string]interface{})}
+		for i, column := range header[1:] {
+			value := strings.TrimSpace(record[i+1])
+			switch column {
+			case "BlockStatus":
+				entry.fields["block_status"] = value
+			case "FileStatus":
+				entry.fields["file_status"] = value
+			default:
+				field, ok := repquotaColumns[column]
+				if !ok {
+					continue
+				}
+				v, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("parsing %s of %s failed: %v", column, entry.name, err)
+				}
+				if strings.HasPrefix(column, "Block") {
+					v *= 1024
+				}
+				entry.fields[field] = v
+			}
+		}
+		addUsedPercent(entry.fields, "used_bytes", "soft_limit_bytes", "hard_limit_bytes", "used_percent")
+		addUsedPercent(entry.fields, "files_used", "files_soft_limit", "files_hard_limit", "files_used_percent")
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// addUsedPercent adds the usage relative to the hard limit, or to the soft
+// limit for quotas without a hard limit.
+func addUsedPercent(fields map[string]interface{}, used, soft, hard, percent string) {
+	u, ok := fields[used].(uint64)
+	if !ok {
+		return
+	}
+	limit, _ := fields[hard].(uint64)
+	if limit == 0 {
+		limit, _ = fields[soft].(uint64)
+	}
+	if limit > 0 {
+		fields[percent] = 100 * float64(u) / float64(limit)
+	}
+}
+
+// inodeSample is the number of used inodes of a filesystem at a time.
+type inodeSample struct {
+	used uint64
+	time time.Time
+}
+
+// inodeFields returns the inode usage of the filesystem, with the rate of
+// consumption over the trend window and the time until the inodes are
+// exhausted at that rate.
+func (q *DiskQuota) inodeFields(mountpoint string, total, free uint64, t time.Time) map[string]interface{} {
+	used := total - free
+	fields := map[string]interface{}{
+		"total":        total,
+		"free":         free,
+		"used":         used,
+		"used_percent": 100 * float64(used) / float64(total),
+	}
+
+	samples := append(q.history[mountpoint], inodeSample{used: used, time: t})
+	// The oldest sample is the last one at least the trend window old
+	for len(samples) > 1 && t.Sub(samples[1].time) >= q.TrendWindow.Duration {
+		samples = samples[1:]
+	}
+	q.history[mountpoint] = samples
+
+	first := samples[0]
+	if elapsed := t.Sub(first.time).Seconds(); elapsed > 0 {
+		rate := (float64(used) - float64(first.used)) / elapsed
+		fields["used_per_hour"] = rate * 3600
+		if rate > 0 {
+			fields["exhaustion_seconds"] = int64(float64(free) / rate)
+		}
+	}
+	return fields
+}
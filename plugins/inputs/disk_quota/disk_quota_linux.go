@@ -0,0 +1,163 @@
+// +build linux
+
+package disk_quota
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (q *DiskQuota) Init() error {
+	for _, typ := range q.QuotaTypes {
+		if _, ok := repquotaFlags[typ]; !ok {
+			return fmt.Errorf("unknown quota type %q", typ)
+		}
+	}
+
+	var err error
+	q.mountFilter, err = filter.Compile(q.MountPoints)
+	if err != nil {
+		return err
+	}
+	q.history = make(map[string][]inodeSample)
+	return nil
+}
+
+func (q *DiskQuota) Gather(acc telegraf.Accumulator) error {
+	f, err := os.Open(filepath.Join(q.procPath, "self", "mounts"))
+	if err != nil {
+		return err
+	}
+	mounts, err := parseMounts(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	t := time.Now()
+	for _, m := range mounts {
+		if !choice(m.fstype, q.FSTypes) {
+			continue
+		}
+		if q.mountFilter != nil && !q.mountFilter.Match(m.mountpoint) {
+			continue
+		}
+
+		tags := map[string]string{
+			"path":   m.mountpoint,
+			"device": m.device,
+			"fstype": m.fstype,
+		}
+
+		total, free, err := q.statfs(filepath.Join(q.mountPrefix, m.mountpoint))
+		if err != nil {
+			acc.AddError(fmt.Errorf("reading inodes of %s failed: %v", m.mountpoint, err))
+		} else if total > 0 {
+			// Filesystems such as btrfs allocate inodes dynamically
+			acc.AddGauge("disk_inodes", q.inodeFields(m.mountpoint, total, free, t), tags, t)
+		}
+
+		if !q.Quotas {
+			continue
+		}
+		for _, typ := range m.quotaTypes() {
+			if !choice(typ, q.QuotaTypes) {
+				continue
+			}
+			if err := q.gatherQuotas(acc, m, typ, tags); err != nil {
+				acc.AddError(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (q *DiskQuota) gatherQuotas(acc telegraf.Accumulator, m *mount, typ string, mountTags map[string]string) error {
+	out, err := q.repquota(filepath.Join(q.mountPrefix, m.mountpoint), typ, q.Timeout, q.UseSudo)
+	if err != nil {
+		return err
+	}
+	entries, err := parseRepquota(out)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		tags := map[string]string{
+			"type": typ,
+			"name": entry.name,
+		}
+		for k, v := range mountTags {
+			tags[k] = v
+		}
+		acc.AddGauge("disk_quota", entry.fields, tags)
+	}
+	return nil
+}
+
+func choice(s string, choices []string) bool {
+	for _, c := range choices {
+		if s == c {
+			return true
+		}
+	}
+	return false
+}
+
+func runRepquota(mountpoint, quotaType string, timeout internal.Duration, useSudo bool) ([]byte, error) {
+	bin, err := exec.LookPath("repquota")
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"-O", "csv", repquotaFlags[quotaType], mountpoint}
+	if useSudo {
+		args = append([]string{"-n", bin}, args...)
+		bin = "sudo"
+	}
+
+	cmd := exec.Command(bin, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, timeout.Duration); err != nil {
+		return nil, fmt.Errorf("running repquota on %s failed: %v", mountpoint, err)
+	}
+	return out.Bytes(), nil
+}
+
+func statfs(path string) (uint64, uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Files, st.Ffree, nil
+}
+
+func init() {
+	inputs.Add("disk_quota", func() telegraf.Input {
+		procPath := "/proc"
+		if hostProc := os.Getenv("HOST_PROC"); hostProc != "" {
+			procPath = hostProc
+		}
+		return &DiskQuota{
+			FSTypes:     []string{"ext2", "ext3", "ext4", "xfs"},
+			Quotas:      true,
+			QuotaTypes:  []string{"user", "group", "project"},
+			TrendWindow: internal.Duration{Duration: time.Hour},
+			Timeout:     internal.Duration{Duration: 5 * time.Second},
+			procPath:    procPath,
+			mountPrefix: os.Getenv("HOST_MOUNT_PREFIX"),
+			repquota:    runRepquota,
+			statfs:      statfs,
+		}
+	})
+}
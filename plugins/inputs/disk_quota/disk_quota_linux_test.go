@@ -0,0 +1,218 @@
+// +build linux
+
+package disk_quota
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newQuota(t *testing.T) *DiskQuota {
+	q := &DiskQuota{
+		FSTypes:     []string{"ext2", "ext3", "ext4", "xfs"},
+		Quotas:      true,
+		QuotaTypes:  []string{"user", "group", "project"},
+		TrendWindow: internal.Duration{Duration: time.Hour},
+		procPath:    "testdata",
+		repquota: func(mountpoint, quotaType string, timeout internal.Duration, useSudo bool) ([]byte, error) {
+			return ioutil.ReadFile(fmt.Sprintf("testdata/repquota_%s.csv", quotaType))
+		},
+		statfs: func(path string) (uint64, uint64, error) {
+			return 1000, 750, nil
+		},
+	}
+	require.NoError(t, q.Init())
+	return q
+}
+
+func TestGather(t *testing.T) {
+	q := newQuota(t)
+	q.MountPoints = []string{"/srv/*", "/data"}
+	require.NoError(t, q.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, q.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	homeTags := func(typ, name string) map[string]string {
+		return map[string]string{
+			"path":   "/srv/home dir",
+			"device": "/dev/sdb1",
+			"fstype": "ext4",
+			"type":   typ,
+			"name":   name,
+		}
+	}
+	dataTags := func(typ, name string) map[string]string {
+		return map[string]string{
+			"path":   "/data",
+			"device": "/dev/sdc1",
+			"fstype": "xfs",
+			"type":   typ,
+			"name":   name,
+		}
+	}
+	inodes := map[string]interface{}{
+		"total":        uint64(1000),
+		"free":         uint64(750),
+		"used":         uint64(250),
+		"used_percent": 25.0,
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("disk_inodes",
+			map[string]string{"path": "/srv/home dir", "device": "/dev/sdb1", "fstype": "ext4"},
+			inodes,
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("disk_quota", homeTags("user", "root"),
+			map[string]interface{}{
+				"block_status":     "ok",
+				"file_status":      "ok",
+				"used_bytes":       uint64(20480),
+				"soft_limit_bytes": uint64(0),
+				"hard_limit_bytes": uint64(0),
+				"files_used":       uint64(2),
+				"files_soft_limit": uint64(0),
+				"files_hard_limit": uint64(0),
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("disk_quota", homeTags("user", "alice"),
+			map[string]interface{}{
+				"block_status":       "soft",
+				"file_status":        "ok",
+				"used_bytes":         uint64(1073741824),
+				"soft_limit_bytes":   uint64(1024000000),
+				"hard_limit_bytes":   uint64(2147483648),
+				"used_percent":       50.0,
+				"files_used":         uint64(1200),
+				"files_soft_limit":   uint64(0),
+				"files_hard_limit":   uint64(10000),
+				"files_used_percent": 12.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("disk_quota", homeTags("user", "#1001"),
+			map[string]interface{}{
+				"block_status":       "ok",
+				"file_status":        "ok",
+				"used_bytes":         uint64(0),
+				"soft_limit_bytes":   uint64(0),
+				"hard_limit_bytes":   uint64(0),
+				"files_used":         uint64(0),
+				"files_soft_limit":   uint64(500),
+				"files_hard_limit":   uint64(0),
+				"files_used_percent": 0.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("disk_quota", homeTags("group", "users"),
+			map[string]interface{}{
+				"block_status":     "ok",
+				"file_status":      "ok",
+				"used_bytes":       uint64(2097152),
+				"soft_limit_bytes": uint64(0),
+				"hard_limit_bytes": uint64(4194304),
+				"used_percent":     50.0,
+				"files_used":       uint64(10),
+				"files_soft_limit": uint64(0),
+				"files_hard_limit": uint64(0),
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("disk_inodes",
+			map[string]string{"path": "/data", "device": "/dev/sdc1", "fstype": "xfs"},
+			inodes,
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("disk_quota", dataTags("project", "#0"),
+			map[string]interface{}{
+				"block_status":     "ok",
+				"file_status":      "ok",
+				"used_bytes":       uint64(0),
+				"soft_limit_bytes": uint64(0),
+				"hard_limit_bytes": uint64(0),
+				"files_used":       uint64(3),
+				"files_soft_limit": uint64(0),
+				"files_hard_limit": uint64(0),
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("disk_quota", dataTags("project", "web"),
+			map[string]interface{}{
+				"block_status":       "hard",
+				"file_status":        "hard",
+				"used_bytes":         uint64(10485760),
+				"soft_limit_bytes":   uint64(5242880),
+				"hard_limit_bytes":   uint64(10485760),
+				"used_percent":       100.0,
+				"files_used":         uint64(100),
+				"files_soft_limit":   uint64(50),
+				"files_hard_limit":   uint64(100),
+				"files_used_percent": 100.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherQuotaTypes(t *testing.T) {
+	q := newQuota(t)
+	q.Quotas = false
+	q.FSTypes = []string{"ext4", "btrfs"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, q.Gather(&acc))
+
+	var paths []string
+	for _, m := range acc.GetTelegrafMetrics() {
+		require.Equal(t, "disk_inodes", m.Name())
+		paths = append(paths, m.Tags()["path"])
+	}
+	require.Equal(t, []string{"/", "/srv/home dir", "/backup"}, paths)
+}
+
+func TestInvalidQuotaType(t *testing.T) {
+	q := &DiskQuota{QuotaTypes: []string{"users"}}
+	require.Error(t, q.Init())
+}
+
+func TestInodeTrend(t *testing.T) {
+	q := newQuota(t)
+	q.TrendWindow = internal.Duration{Duration: 10 * time.Minute}
+
+	start := time.Unix(1600000000, 0)
+	fields := q.inodeFields("/data", 1000, 900, start)
+	require.NotContains(t, fields, "used_per_hour")
+
+	// 20 inodes in 5 minutes
+	fields = q.inodeFields("/data", 1000, 880, start.Add(5*time.Minute))
+	require.Equal(t, 240.0, fields["used_per_hour"])
+	require.Equal(t, int64(13200), fields["exhaustion_seconds"])
+
+	// The first sample is out of the window, 20 inodes in 10 minutes
+	fields = q.inodeFields("/data", 1000, 860, start.Add(15*time.Minute))
+	require.Equal(t, 120.0, fields["used_per_hour"])
+
+	// 20 inodes are released in 15 minutes
+	fields = q.inodeFields("/data", 1000, 900, start.Add(20*time.Minute))
+	require.Equal(t, -80.0, fields["used_per_hour"])
+	require.NotContains(t, fields, "exhaustion_seconds")
+}
@@ -0,0 +1,23 @@
+// +build !linux
+
+package disk_quota
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (q *DiskQuota) Init() error {
+	q.Log.Warn("Current platform is not supported")
+	return nil
+}
+
+func (q *DiskQuota) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("disk_quota", func() telegraf.Input {
+		return &DiskQuota{}
+	})
+}
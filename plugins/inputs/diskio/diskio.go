@@ -22,12 +22,16 @@ type DiskIO struct {
 	DeviceTags       []string
 	NameTemplates    []string
 	SkipSerialNumber bool
+	Latency          bool
+	LatencyWindow    int
 
 	Log telegraf.Logger
 
 	infoCache    map[string]diskInfoCache
 	deviceFilter filter.Filter
 	initialized  bool
+	lastStats    map[string]diskSample
+	windows      map[string]*latencyWindow
 }
 
 func (_ *DiskIO) Description() string {
@@ -60,6 +64,12 @@ var diskIOsampleConfig = `
   ## The typical use case is for LVM volumes, to get the VG/LV name instead of
   ## the near-meaningless DM-0 name.
   # name_templates = ["$ID_FS_LABEL","$DM_VG_NAME/$DM_LV_NAME"]
+  #
+  ## Compute the latency, queue depth and utilization of the devices over
+  ## each interval into the diskio_latency measurement.
+  # latency = false
+  ## Number of intervals the latency percentiles are computed over.
+  # latency_window = 60
 `
 
 func (_ *DiskIO) SampleConfig() string {
@@ -152,6 +162,12 @@ func (s *DiskIO) Gather(acc telegraf.Accumulator) error {
 			"merged_writes":    io.MergedWriteCount,
 		}
 		acc.AddCounter("diskio", fields, tags)
+
+		if s.Latency {
+			if fields := s.latencyFields(io); fields != nil {
+				acc.AddGauge("diskio_latency", fields, tags)
+			}
+		}
 	}
 
 	return nil
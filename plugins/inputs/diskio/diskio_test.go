@@ -2,6 +2,7 @@ package diskio
 
 import (
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/plugins/inputs/system"
 	"github.com/influxdata/telegraf/testutil"
@@ -125,3 +126,71 @@ func TestDiskIO(t *testing.T) {
 		})
 	}
 }
+
+func TestDiskIOLatency(t *testing.T) {
+	defer func() { now = time.Now }()
+
+	diskio := &DiskIO{Latency: true, LatencyWindow: 3}
+
+	start := time.Unix(1600000000, 0)
+	now = func() time.Time { return start }
+	require.Nil(t, diskio.latencyFields(disk.IOCountersStat{Name: "sda"}))
+
+	// 100 reads of 2ms and 10 writes of 10ms in 1s
+	now = func() time.Time { return start.Add(time.Second) }
+	fields := diskio.latencyFields(disk.IOCountersStat{
+		Name:       "sda",
+		ReadCount:  100,
+		ReadTime:   200,
+		WriteCount: 10,
+		WriteTime:  100,
+		WeightedIO: 300,
+		IoTime:     250,
+	})
+	require.Equal(t, map[string]interface{}{
+		"read_latency_ms":      2.0,
+		"write_latency_ms":     10.0,
+		"latency_ms":           300.0 / 110,
+		"queue_depth":          0.3,
+		"utilization_percent":  25.0,
+		"read_latency_p50_ms":  2.0,
+		"read_latency_p99_ms":  2.0,
+		"write_latency_p50_ms": 10.0,
+		"write_latency_p99_ms": 10.0,
+	}, fields)
+
+	// 10 reads of 50ms in 1s, no writes
+	now = func() time.Time { return start.Add(2 * time.Second) }
+	fields = diskio.latencyFields(disk.IOCountersStat{
+		Name:       "sda",
+		ReadCount:  110,
+		ReadTime:   700,
+		WriteCount: 10,
+		WriteTime:  100,
+		WeightedIO: 800,
+		IoTime:     750,
+	})
+	require.Equal(t, 50.0, fields["read_latency_ms"])
+	require.NotContains(t, fields, "write_latency_ms")
+	require.Equal(t, 2.0, fields["read_latency_p50_ms"])
+	require.Equal(t, 50.0, fields["read_latency_p99_ms"])
+	require.Equal(t, 10.0, fields["write_latency_p50_ms"])
+}
+
+func TestPercentile(t *testing.T) {
+	samples := []latencySample{
+		{latency: 5, count: 1},
+		{latency: 1, count: 90},
+		{latency: 3, count: 9},
+	}
+	p50, ok := percentile(samples, 50)
+	require.True(t, ok)
+	require.Equal(t, 1.0, p50)
+	p99, _ := percentile(samples, 99)
+	require.Equal(t, 3.0, p99)
+	p100, _ := percentile(samples, 100)
+	require.Equal(t, 5.0, p100)
+
+	_, ok = percentile(nil, 50)
+	require.False(t, ok)
+}
@@ -0,0 +1,134 @@
+package diskio
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/disk"
+)
+
+const defaultLatencyWindow = 60
+
+var now = time.Now
+
+// diskSample is the last counters of a device.
+type diskSample struct {
+	stat disk.IOCountersStat
+	time time.Time
+}
+
+// latencySample is the average latency of the requests completed during an
+// interval, weighted by the number of requests.
+type latencySample struct {
+	latency float64
+	count   uint64
+}
+
+// latencyWindow holds the latency samples of the last intervals of a device.
+type latencyWindow struct {
+	reads  []latencySample
+	writes []latencySample
+	size   int
+}
+
+func (w *latencyWindow) add(samples []latencySample, latency float64, count uint64) []latencySample {
+	if count == 0 {
+		return samples
+	}
+	samples = append(samples, latencySample{latency: latency, count: count})
+	if len(samples) > w.size {
+		samples = samples[len(samples)-w.size:]
+	}
+	return samples
+}
+
+// percentile returns the latency below which the given percentage of the
+// requests of the samples completed.
+func percentile(samples []latencySample, p float64) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	sorted := make([]latencySample, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].latency < sorted[j].latency })
+
+	var total uint64
+	for _, s := range sorted {
+		total += s.count
+	}
+	rank := p / 100 * float64(total)
+	var seen uint64
+	for _, s := range sorted {
+		seen += s.count
+		if float64(seen) >= rank {
+			return s.latency, true
+		}
+	}
+	return sorted[len(sorted)-1].latency, true
+}
+
+// latencyFields returns the latency and queue depth of the device during the
+// interval since the last gather, or nil on the first gather.
+func (s *DiskIO) latencyFields(io disk.IOCountersStat) map[string]interface{} {
+	if s.lastStats == nil {
+		s.lastStats = make(map[string]diskSample)
+		s.windows = make(map[string]*latencyWindow)
+	}
+
+	t := now()
+	last, ok := s.lastStats[io.Name]
+	s.lastStats[io.Name] = diskSample{stat: io, time: t}
+	if !ok {
+		return nil
+	}
+
+	elapsed := float64(t.Sub(last.time)) / float64(time.Millisecond)
+	// The counters are reset when the device is removed and added again
+	if elapsed <= 0 || io.ReadCount < last.stat.ReadCount || io.WriteCount < last.stat.WriteCount {
+		return nil
+	}
+
+	reads := io.ReadCount - last.stat.ReadCount
+	writes := io.WriteCount - last.stat.WriteCount
+	readTime := float64(io.ReadTime - last.stat.ReadTime)
+	writeTime := float64(io.WriteTime - last.stat.WriteTime)
+
+	fields := map[string]interface{}{
+		"queue_depth":         float64(io.WeightedIO-last.stat.WeightedIO) / elapsed,
+		"utilization_percent": 100 * float64(io.IoTime-last.stat.IoTime) / elapsed,
+	}
+	var readLatency, writeLatency float64
+	if reads > 0 {
+		readLatency = readTime / float64(reads)
+		fields["read_latency_ms"] = readLatency
+	}
+	if writes > 0 {
+		writeLatency = writeTime / float64(writes)
+		fields["write_latency_ms"] = writeLatency
+	}
+	if reads+writes > 0 {
+		fields["latency_ms"] = (readTime + writeTime) / float64(reads+writes)
+	}
+
+	size := s.LatencyWindow
+	if size <= 0 {
+		size = defaultLatencyWindow
+	}
+	w, ok := s.windows[io.Name]
+	if !ok {
+		w = &latencyWindow{size: size}
+		s.windows[io.Name] = w
+	}
+	w.reads = w.add(w.reads, readLatency, reads)
+	w.writes = w.add(w.writes, writeLatency, writes)
+
+	if v, ok := percentile(w.reads, 50); ok {
+		fields["read_latency_p50_ms"] = v
+		fields["read_latency_p99_ms"], _ = percentile(w.reads, 99)
+	}
+	if v, ok := percentile(w.writes, 50); ok {
+		fields["write_latency_p50_ms"] = v
+		fields["write_latency_p99_ms"], _ = percentile(w.writes, 99)
+	}
+	return fields
+}
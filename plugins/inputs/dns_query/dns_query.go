@@ -10,6 +10,7 @@ import (
 	"github.com/miekg/dns"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/discovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -39,6 +40,14 @@ type DnsQuery struct {
 
 	// Dns query timeout in seconds. 0 means no timeout
 	Timeout int
+
+	// Discovery of the servers from files, DNS SRV records, Consul and
+	// Kubernetes services
+	Discovery *discovery.Config `toml:"discovery"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	targets *discovery.Discovery
 }
 
 var sampleConfig = `
@@ -60,6 +69,36 @@ var sampleConfig = `
 
   ## Query timeout in seconds.
   # timeout = 2
+
+  ## Query the servers discovered from files, DNS SRV records, Consul
+  ## services or Kubernetes services, at their discovered port or at the port
+  ## above.  The servers are refreshed periodically, without reloading the
+  ## configuration.
+  # [inputs.dns_query.discovery]
+  #   ## Files with one server per line, as "host[:port]" followed by
+  #   ## optional "key=value" tags.  Lines starting with "#" are skipped.
+  #   files = ["/etc/telegraf/dns_servers"]
+  #   ## Names of DNS SRV records.
+  #   dns_srv = ["_dns._udp.example.org"]
+  #   ## Interval of the refresh of the files, records and Consul services.
+  #   refresh_interval = "1m"
+  #
+  #   ## Instances of Consul services, the healthy ones unless failing ones
+  #   ## are included.
+  #   [inputs.dns_query.discovery.consul]
+  #     address = "localhost:8500"
+  #     services = ["dns"]
+  #     # tag = ""
+  #     # datacenter = ""
+  #     # token = ""
+  #     # include_failing = false
+  #
+  #   ## Kubernetes services with the "prometheus.io/scrape" annotation set
+  #   ## to "true", at their cluster IP.
+  #   [inputs.dns_query.discovery.kubernetes]
+  #     role = "service"
+  #     # namespace = ""
+  #     # kube_config = "/path/to/kubernetes.config"
 `
 
 func (d *DnsQuery) SampleConfig() string {
@@ -69,6 +108,23 @@ func (d *DnsQuery) SampleConfig() string {
 func (d *DnsQuery) Description() string {
 	return "Query given DNS server and gives statistics"
 }
+
+// Start starts the discovery of the servers if enabled in the
+// configuration.
+func (d *DnsQuery) Start(_ telegraf.Accumulator) error {
+	if d.Discovery == nil {
+		return nil
+	}
+	d.targets = discovery.New(*d.Discovery, d.Log)
+	return d.targets.Start()
+}
+
+func (d *DnsQuery) Stop() {
+	if d.targets != nil {
+		d.targets.Stop()
+	}
+}
+
 func (d *DnsQuery) Gather(acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
 	d.setDefaultValues()
@@ -77,32 +133,29 @@ func (d *DnsQuery) Gather(acc telegraf.Accumulator) error {
 		for _, server := range d.Servers {
 			wg.Add(1)
 			go func(domain, server string) {
-				fields := make(map[string]interface{}, 2)
-				tags := map[string]string{
-					"server":      server,
-					"domain":      domain,
-					"record_type": d.RecordType,
-				}
+				defer wg.Done()
+				d.query(acc, domain, server, d.Port)
+			}(domain, server)
+		}
 
-				dnsQueryTime, rcode, err := d.getDnsQueryTime(domain, server)
-				if rcode >= 0 {
-					tags["rcode"] = dns.RcodeToString[rcode]
-					fields["rcode_value"] = rcode
-				}
-				if err == nil {
-					setResult(Success, fields, tags)
-					fields["query_time_ms"] = dnsQueryTime
-				} else if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
-					setResult(Timeout, fields, tags)
-				} else if err != nil {
-					setResult(Error, fields, tags)
-					acc.AddError(err)
+		if d.targets == nil {
+			continue
+		}
+		for _, t := range d.targets.Targets() {
+			port := d.Port
+			if t.Port != "" {
+				var err error
+				if port, err = strconv.Atoi(t.Port); err != nil {
+					acc.AddError(fmt.Errorf("invalid port of server %s: %v", t.Host, err))
+					continue
 				}
+			}
 
-				acc.AddFields("dns_query", fields, tags)
-
-				wg.Done()
-			}(domain, server)
+			wg.Add(1)
+			go func(domain string, t *discovery.Target, port int) {
+				defer wg.Done()
+				d.query(t.Accumulator(acc), domain, t.Host, port)
+			}(domain, t, port)
 		}
 	}
 
@@ -110,6 +163,33 @@ func (d *DnsQuery) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// query adds the metrics of the query of the domain to the server.
+func (d *DnsQuery) query(acc telegraf.Accumulator, domain, server string, port int) {
+	fields := make(map[string]interface{}, 2)
+	tags := map[string]string{
+		"server":      server,
+		"domain":      domain,
+		"record_type": d.RecordType,
+	}
+
+	dnsQueryTime, rcode, err := d.getDnsQueryTime(domain, server, port)
+	if rcode >= 0 {
+		tags["rcode"] = dns.RcodeToString[rcode]
+		fields["rcode_value"] = rcode
+	}
+	if err == nil {
+		setResult(Success, fields, tags)
+		fields["query_time_ms"] = dnsQueryTime
+	} else if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
+		setResult(Timeout, fields, tags)
+	} else if err != nil {
+		setResult(Error, fields, tags)
+		acc.AddError(err)
+	}
+
+	acc.AddFields("dns_query", fields, tags)
+}
+
 func (d *DnsQuery) setDefaultValues() {
 	if d.Network == "" {
 		d.Network = "udp"
@@ -133,7 +213,7 @@ func (d *DnsQuery) setDefaultValues() {
 	}
 }
 
-func (d *DnsQuery) getDnsQueryTime(domain string, server string) (float64, int, error) {
+func (d *DnsQuery) getDnsQueryTime(domain string, server string, port int) (float64, int, error) {
 	dnsQueryTime := float64(0)
 
 	c := new(dns.Client)
@@ -148,7 +228,7 @@ func (d *DnsQuery) getDnsQueryTime(domain string, server string) (float64, int,
 	m.SetQuestion(dns.Fqdn(domain), recordType)
 	m.RecursionDesired = true
 
-	r, rtt, err := c.Exchange(m, net.JoinHostPort(server, strconv.Itoa(d.Port)))
+	r, rtt, err := c.Exchange(m, net.JoinHostPort(server, strconv.Itoa(port)))
 	if err != nil {
 		return dnsQueryTime, -1, err
 	}
@@ -29,7 +29,8 @@ type Docker struct {
 	Endpoint       string
 	ContainerNames []string // deprecated in 1.4; use container_name_include
 
-	GatherServices bool `toml:"gather_services"`
+	GatherServices   bool `toml:"gather_services"`
+	GatherSwarmNodes bool `toml:"gather_swarm_nodes"`
 
 	Timeout        internal.Duration
 	PerDevice      bool     `toml:"perdevice"`
@@ -89,6 +90,10 @@ var sampleConfig = `
   ## Set to true to collect Swarm metrics(desired_replicas, running_replicas)
   gather_services = false
 
+  ## Set to true to collect the availability and the running tasks of the
+  ## nodes of the Swarm cluster.
+  # gather_swarm_nodes = false
+
   ## Only collect metrics for these containers, collect all if empty
   container_names = []
 
@@ -181,6 +186,13 @@ func (d *Docker) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 
+	if d.GatherSwarmNodes {
+		err := d.gatherSwarmNodes(acc)
+		if err != nil {
+			acc.AddError(err)
+		}
+	}
+
 	filterArgs := filters.NewArgs()
 	for _, state := range containerStates {
 		if d.stateFilter.Match(state) {
@@ -295,6 +307,56 @@ func (d *Docker) gatherSwarmInfo(acc telegraf.Accumulator) error {
 	return nil
 }
 
+func (d *Docker) gatherSwarmNodes(acc telegraf.Accumulator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout.Duration)
+	defer cancel()
+
+	nodes, err := d.client.NodeList(ctx, types.NodeListOptions{})
+	if err == context.DeadlineExceeded {
+		return errNodeTimeout
+	}
+	if err != nil {
+		return err
+	}
+
+	tasks, err := d.client.TaskList(ctx, types.TaskListOptions{})
+	if err != nil {
+		return err
+	}
+
+	running := map[string]int{}
+	for _, task := range tasks {
+		if task.Status.State == swarm.TaskStateRunning {
+			running[task.NodeID]++
+		}
+	}
+
+	now := time.Now()
+	for _, n := range nodes {
+		tags := map[string]string{
+			"node_id":       n.ID,
+			"node_hostname": n.Description.Hostname,
+			"node_role":     string(n.Spec.Role),
+		}
+		fields := map[string]interface{}{
+			"state":         string(n.Status.State),
+			"availability":  string(n.Spec.Availability),
+			"ready":         n.Status.State == swarm.NodeStateReady,
+			"available":     n.Status.State == swarm.NodeStateReady && n.Spec.Availability == swarm.NodeAvailabilityActive,
+			"tasks_running": running[n.ID],
+			"nano_cpus":     n.Description.Resources.NanoCPUs,
+			"memory_bytes":  n.Description.Resources.MemoryBytes,
+		}
+		if n.ManagerStatus != nil {
+			fields["leader"] = n.ManagerStatus.Leader
+			fields["reachability"] = string(n.ManagerStatus.Reachability)
+		}
+		acc.AddFields("docker_swarm_node", fields, tags, now)
+	}
+
+	return nil
+}
+
 func (d *Docker) gatherInfo(acc telegraf.Accumulator) error {
 	// Init vars
 	dataFields := make(map[string]interface{})
@@ -757,61 +819,53 @@ func gatherBlockIOMetrics(
 	blkioStats := stat.BlkioStats
 	// Make a map of devices to their block io stats
 	deviceStatMap := make(map[string]map[string]interface{})
-
-	for _, metric := range blkioStats.IoServiceBytesRecursive {
-		device := fmt.Sprintf("%d:%d", metric.Major, metric.Minor)
-		_, ok := deviceStatMap[device]
+	// A device may be missing from some of the stats.
+	deviceStats := func(major, minor uint64) map[string]interface{} {
+		device := fmt.Sprintf("%d:%d", major, minor)
+		fields, ok := deviceStatMap[device]
 		if !ok {
-			deviceStatMap[device] = make(map[string]interface{})
+			fields = make(map[string]interface{})
+			deviceStatMap[device] = fields
 		}
+		return fields
+	}
 
+	for _, metric := range blkioStats.IoServiceBytesRecursive {
 		field := fmt.Sprintf("io_service_bytes_recursive_%s", strings.ToLower(metric.Op))
-		deviceStatMap[device][field] = metric.Value
+		deviceStats(metric.Major, metric.Minor)[field] = metric.Value
 	}
 
 	for _, metric := range blkioStats.IoServicedRecursive {
-		device := fmt.Sprintf("%d:%d", metric.Major, metric.Minor)
-		_, ok := deviceStatMap[device]
-		if !ok {
-			deviceStatMap[device] = make(map[string]interface{})
-		}
-
 		field := fmt.Sprintf("io_serviced_recursive_%s", strings.ToLower(metric.Op))
-		deviceStatMap[device][field] = metric.Value
+		deviceStats(metric.Major, metric.Minor)[field] = metric.Value
 	}
 
 	for _, metric := range blkioStats.IoQueuedRecursive {
-		device := fmt.Sprintf("%d:%d", metric.Major, metric.Minor)
 		field := fmt.Sprintf("io_queue_recursive_%s", strings.ToLower(metric.Op))
-		deviceStatMap[device][field] = metric.Value
+		deviceStats(metric.Major, metric.Minor)[field] = metric.Value
 	}
 
 	for _, metric := range blkioStats.IoServiceTimeRecursive {
-		device := fmt.Sprintf("%d:%d", metric.Major, metric.Minor)
 		field := fmt.Sprintf("io_service_time_recursive_%s", strings.ToLower(metric.Op))
-		deviceStatMap[device][field] = metric.Value
+		deviceStats(metric.Major, metric.Minor)[field] = metric.Value
 	}
 
 	for _, metric := range blkioStats.IoWaitTimeRecursive {
-		device := fmt.Sprintf("%d:%d", metric.Major, metric.Minor)
 		field := fmt.Sprintf("io_wait_time_%s", strings.ToLower(metric.Op))
-		deviceStatMap[device][field] = metric.Value
+		deviceStats(metric.Major, metric.Minor)[field] = metric.Value
 	}
 
 	for _, metric := range blkioStats.IoMergedRecursive {
-		device := fmt.Sprintf("%d:%d", metric.Major, metric.Minor)
 		field := fmt.Sprintf("io_merged_recursive_%s", strings.ToLower(metric.Op))
-		deviceStatMap[device][field] = metric.Value
+		deviceStats(metric.Major, metric.Minor)[field] = metric.Value
 	}
 
 	for _, metric := range blkioStats.IoTimeRecursive {
-		device := fmt.Sprintf("%d:%d", metric.Major, metric.Minor)
-		deviceStatMap[device]["io_time_recursive"] = metric.Value
+		deviceStats(metric.Major, metric.Minor)["io_time_recursive"] = metric.Value
 	}
 
 	for _, metric := range blkioStats.SectorsRecursive {
-		device := fmt.Sprintf("%d:%d", metric.Major, metric.Minor)
-		deviceStatMap[device]["sectors_recursive"] = metric.Value
+		deviceStats(metric.Major, metric.Minor)["sectors_recursive"] = metric.Value
 	}
 
 	totalStatMap := make(map[string]interface{})
@@ -249,6 +249,43 @@ func TestDockerGatherContainerStats(t *testing.T) {
 	acc.AssertDoesNotContainsTaggedFields(t, "docker_container_cpu", cpu3fields, cputags)
 }
 
+func TestDockerGatherBlockIOPartialDevice(t *testing.T) {
+	var acc testutil.Accumulator
+	stats := &types.StatsJSON{}
+	stats.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{
+		{Major: 8, Minor: 0, Op: "Read", Value: 100},
+	}
+	// The device 8:16 is only reported in the time stats.
+	stats.BlkioStats.IoTimeRecursive = []types.BlkioStatEntry{
+		{Major: 8, Minor: 0, Op: "", Value: 10},
+		{Major: 8, Minor: 16, Op: "", Value: 20},
+	}
+
+	tags := map[string]string{"container_name": "redis"}
+	gatherBlockIOMetrics(stats, &acc, tags, time.Now(), "123456789", true, true)
+
+	acc.AssertContainsTaggedFields(t, "docker_container_blkio",
+		map[string]interface{}{
+			"io_service_bytes_recursive_read": uint64(100),
+			"io_time_recursive":               uint64(10),
+			"container_id":                    "123456789",
+		},
+		map[string]string{"container_name": "redis", "device": "8:0"})
+	acc.AssertContainsTaggedFields(t, "docker_container_blkio",
+		map[string]interface{}{
+			"io_time_recursive": uint64(20),
+			"container_id":      "123456789",
+		},
+		map[string]string{"container_name": "redis", "device": "8:16"})
+	acc.AssertContainsTaggedFields(t, "docker_container_blkio",
+		map[string]interface{}{
+			"io_service_bytes_recursive_read": uint64(100),
+			"io_time_recursive":               uint64(30),
+			"container_id":                    "123456789",
+		},
+		map[string]string{"container_name": "redis", "device": "total"})
+}
+
 func TestDocker_WindowsMemoryContainerStats(t *testing.T) {
 	var acc testutil.Accumulator
 
@@ -934,6 +971,56 @@ func TestDockerGatherSwarmInfo(t *testing.T) {
 	)
 }
 
+func TestDockerGatherSwarmNodes(t *testing.T) {
+	var acc testutil.Accumulator
+	d := Docker{
+		Log:              testutil.Logger{},
+		newClient:        newClient,
+		GatherSwarmNodes: true,
+	}
+
+	err := acc.GatherError(d.Gather)
+	require.NoError(t, err)
+
+	acc.AssertContainsTaggedFields(t,
+		"docker_swarm_node",
+		map[string]interface{}{
+			"state":         "ready",
+			"availability":  "active",
+			"ready":         true,
+			"available":     true,
+			"tasks_running": int(3),
+			"nano_cpus":     int64(2000000000),
+			"memory_bytes":  int64(2101661696),
+			"leader":        true,
+			"reachability":  "reachable",
+		},
+		map[string]string{
+			"node_id":       "0cl4jturcyd1ks3fwpd010kor",
+			"node_hostname": "manager1",
+			"node_role":     "manager",
+		},
+	)
+
+	acc.AssertContainsTaggedFields(t,
+		"docker_swarm_node",
+		map[string]interface{}{
+			"state":         "down",
+			"availability":  "drain",
+			"ready":         false,
+			"available":     false,
+			"tasks_running": int(0),
+			"nano_cpus":     int64(1000000000),
+			"memory_bytes":  int64(1044373504),
+		},
+		map[string]string{
+			"node_id":       "5ck6dsw1r5ugtzhkdz1xxdwmc",
+			"node_hostname": "worker1",
+			"node_role":     "worker",
+		},
+	)
+}
+
 func TestContainerStateFilter(t *testing.T) {
 	var tests = []struct {
 		name     string
@@ -209,14 +209,40 @@ var TaskList = []swarm.Task{
 var NodeList = []swarm.Node{
 	{
 		ID: "0cl4jturcyd1ks3fwpd010kor",
+		Spec: swarm.NodeSpec{
+			Role:         "manager",
+			Availability: "active",
+		},
+		Description: swarm.NodeDescription{
+			Hostname: "manager1",
+			Resources: swarm.Resources{
+				NanoCPUs:    2000000000,
+				MemoryBytes: 2101661696,
+			},
+		},
 		Status: swarm.NodeStatus{
 			State: "ready",
 		},
+		ManagerStatus: &swarm.ManagerStatus{
+			Leader:       true,
+			Reachability: "reachable",
+		},
 	},
 	{
-		ID: "0cl4jturcyd1ks3fwpd010kor",
+		ID: "5ck6dsw1r5ugtzhkdz1xxdwmc",
+		Spec: swarm.NodeSpec{
+			Role:         "worker",
+			Availability: "drain",
+		},
+		Description: swarm.NodeDescription{
+			Hostname: "worker1",
+			Resources: swarm.Resources{
+				NanoCPUs:    1000000000,
+				MemoryBytes: 1044373504,
+			},
+		},
 		Status: swarm.NodeStatus{
-			State: "ready",
+			State: "down",
 		},
 	},
 }
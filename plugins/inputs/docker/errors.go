@@ -8,4 +8,5 @@ var (
 	errInspectTimeout = errors.New("timeout retrieving container environment")
 	errListTimeout    = errors.New("timeout retrieving container list")
 	errServiceTimeout = errors.New("timeout retrieving swarm service list")
+	errNodeTimeout    = errors.New("timeout retrieving swarm node list")
 )
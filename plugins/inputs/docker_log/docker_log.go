@@ -44,6 +44,10 @@ var sampleConfig = `
   # container_state_include = []
   # container_state_exclude = []
 
+  ## Labels the containers must have to be included, as "key" or
+  ## "key=value".  Note that an empty array will include all containers
+  # container_label_filter = []
+
   ## docker labels to include and exclude as tags.  Globs accepted.
   ## Note that an empty array for both will include all labels as tags
   # docker_label_include = []
@@ -85,6 +89,7 @@ type DockerLogs struct {
 	ContainerExclude      []string          `toml:"container_name_exclude"`
 	ContainerStateInclude []string          `toml:"container_state_include"`
 	ContainerStateExclude []string          `toml:"container_state_exclude"`
+	ContainerLabelFilter  []string          `toml:"container_label_filter"`
 	IncludeSourceTag      bool              `toml:"source_tag"`
 
 	tlsint.ClientConfig
@@ -148,6 +153,9 @@ func (d *DockerLogs) Init() error {
 			filterArgs.Add("status", state)
 		}
 	}
+	for _, label := range d.ContainerLabelFilter {
+		filterArgs.Add("label", label)
+	}
 
 	if filterArgs.Len() != 0 {
 		d.opts = types.ContainerListOptions{
@@ -231,7 +239,7 @@ func (d *DockerLogs) Gather(acc telegraf.Accumulator) error {
 			defer d.wg.Done()
 			defer d.removeFromContainerList(container.ID)
 
-			err = d.tailContainerLogs(ctx, acc, container, containerName)
+			err := d.tailContainerLogs(ctx, acc, container, containerName)
 			if err != nil && err != context.Canceled {
 				acc.AddError(err)
 			}
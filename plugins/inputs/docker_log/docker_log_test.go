@@ -176,3 +176,26 @@ func Test(t *testing.T) {
 		})
 	}
 }
+
+func TestContainerLabelFilter(t *testing.T) {
+	var options types.ContainerListOptions
+	plugin := &DockerLogs{
+		Timeout:              internal.Duration{Duration: time.Second * 5},
+		ContainerLabelFilter: []string{"logs", "team=core"},
+		newClient: func(string, *tls.Config) (Client, error) {
+			return &MockClient{
+				ContainerListF: func(ctx context.Context, o types.ContainerListOptions) ([]types.Container, error) {
+					options = o
+					return nil, nil
+				},
+			}, nil
+		},
+		containerList: make(map[string]context.CancelFunc),
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.ElementsMatch(t, []string{"logs", "team=core"}, options.Filters.Get("label"))
+	require.Equal(t, []string{"running"}, options.Filters.Get("status"))
+}
@@ -0,0 +1,261 @@
+package edac
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Collect the error counts of the DIMMs, in addition to those of the
+  ## memory controllers.
+  # dimms = true
+
+  ## Collect the error counts per DIMM label recorded by rasdaemon with
+  ## "ras-mc-ctl --error-count", which must run as root.
+  # rasdaemon = false
+
+  ## Run ras-mc-ctl with sudo, which must be configured to not ask for a
+  ## password.
+  # use_sudo = false
+
+  ## Timeout for ras-mc-ctl to complete.
+  # timeout = "5s"
+`
+
+// EDAC gathers the memory error counters of the EDAC drivers.
+type EDAC struct {
+	DIMMs     bool              `toml:"dimms"`
+	Rasdaemon bool              `toml:"rasdaemon"`
+	UseSudo   bool              `toml:"use_sudo"`
+	Timeout   internal.Duration `toml:"timeout"`
+
+	sysPath  string
+	rasMcCtl func(timeout internal.Duration, useSudo bool) ([]byte, error)
+}
+
+func (e *EDAC) Description() string {
+	return "Gather the corrected and uncorrected memory error counts of the memory controllers and DIMMs"
+}
+
+func (e *EDAC) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *EDAC) Gather(acc telegraf.Accumulator) error {
+	controllers, err := filepath.Glob(filepath.Join(e.sysPath, "devices", "system", "edac", "mc", "mc[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range controllers {
+		controller := filepath.Base(dir)
+		tags := map[string]string{"controller": controller}
+		if name, err := readString(filepath.Join(dir, "mc_name")); err == nil {
+			tags["mc_name"] = name
+		}
+
+		fields := readUints(dir, map[string]string{
+			"ce_count":            "ce_count",
+			"ue_count":            "ue_count",
+			"ce_noinfo_count":     "ce_noinfo_count",
+			"ue_noinfo_count":     "ue_noinfo_count",
+			"size_mb":             "size_mb",
+			"seconds_since_reset": "seconds_since_reset",
+		})
+		if len(fields) > 0 {
+			acc.AddFields("edac_mc", fields, tags)
+		}
+
+		if e.DIMMs {
+			if err := gatherDIMMs(acc, dir, controller); err != nil {
+				acc.AddError(err)
+			}
+		}
+	}
+
+	if e.Rasdaemon {
+		if err := e.gatherRasdaemon(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// gatherDIMMs adds the error counts of the DIMMs of the memory controller,
+// which are dimm<n> or rank<n> directories on recent kernels and the
+// channels of the csrow<n> directories on older kernels.
+func gatherDIMMs(acc telegraf.Accumulator, dir, controller string) error {
+	dimms, err := filepath.Glob(filepath.Join(dir, "dimm[0-9]*"))
+	if err != nil {
+		return err
+	}
+	ranks, err := filepath.Glob(filepath.Join(dir, "rank[0-9]*"))
+	if err != nil {
+		return err
+	}
+	dimms = append(dimms, ranks...)
+
+	for _, dimm := range dimms {
+		tags := map[string]string{
+			"controller": controller,
+			"dimm":       filepath.Base(dimm),
+		}
+		for file, tag := range map[string]string{
+			"dimm_label":     "label",
+			"dimm_location":  "location",
+			"dimm_mem_type":  "mem_type",
+			"dimm_edac_mode": "edac_mode",
+		} {
+			if v, err := readString(filepath.Join(dimm, file)); err == nil && v != "" {
+				tags[tag] = v
+			}
+		}
+
+		fields := readUints(dimm, map[string]string{
+			"dimm_ce_count": "ce_count",
+			"dimm_ue_count": "ue_count",
+			"size":          "size_mb",
+		})
+		if len(fields) > 0 {
+			acc.AddFields("edac_dimm", fields, tags)
+		}
+	}
+	if len(dimms) > 0 {
+		return nil
+	}
+
+	channels, err := filepath.Glob(filepath.Join(dir, "csrow[0-9]*", "ch[0-9]*_ce_count"))
+	if err != nil {
+		return err
+	}
+	for _, path := range channels {
+		csrow := filepath.Dir(path)
+		channel := strings.TrimSuffix(filepath.Base(path), "_ce_count")
+
+		tags := map[string]string{
+			"controller": controller,
+			"dimm":       filepath.Base(csrow) + "_" + channel,
+		}
+		if label, err := readString(filepath.Join(csrow, channel+"_dimm_label")); err == nil && label != "" {
+			tags["label"] = label
+		}
+		if memType, err := readString(filepath.Join(csrow, "mem_type")); err == nil {
+			tags["mem_type"] = memType
+		}
+
+		// The uncorrected errors are only counted per csrow
+		fields := readUints(csrow, map[string]string{
+			channel + "_ce_count": "ce_count",
+		})
+		if len(fields) > 0 {
+			acc.AddFields("edac_dimm", fields, tags)
+		}
+	}
+	return nil
+}
+
+// gatherRasdaemon adds the error counts of the output of ras-mc-ctl, which
+// has a "Label CE UE" header followed by a line for each DIMM label.
+func (e *EDAC) gatherRasdaemon(acc telegraf.Accumulator) error {
+	out, err := e.rasMcCtl(e.Timeout, e.UseSudo)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 3 || parts[0] == "Label" {
+			continue
+		}
+		ce, err := strconv.ParseUint(parts[len(parts)-2], 10, 64)
+		if err != nil {
+			continue
+		}
+		ue, err := strconv.ParseUint(parts[len(parts)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+		acc.AddCounter("rasdaemon",
+			map[string]interface{}{
+				"ce_count": ce,
+				"ue_count": ue,
+			},
+			map[string]string{"label": strings.Join(parts[:len(parts)-2], " ")},
+		)
+	}
+	return scanner.Err()
+}
+
+// readUints returns the values of the files of the directory which exist,
+// by field name.
+func readUints(dir string, files map[string]string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for file, field := range files {
+		s, err := readString(filepath.Join(dir, file))
+		if err != nil {
+			continue
+		}
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[field] = v
+	}
+	return fields
+}
+
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func runRasMcCtl(timeout internal.Duration, useSudo bool) ([]byte, error) {
+	bin, err := exec.LookPath("ras-mc-ctl")
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"--error-count"}
+	if useSudo {
+		args = append([]string{"-n", bin}, args...)
+		bin = "sudo"
+	}
+
+	cmd := exec.Command(bin, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, timeout.Duration); err != nil {
+		return nil, fmt.Errorf("running ras-mc-ctl failed: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+func init() {
+	inputs.Add("edac", func() telegraf.Input {
+		sysPath := "/sys"
+		if hostSys := os.Getenv("HOST_SYS"); hostSys != "" {
+			sysPath = hostSys
+		}
+		return &EDAC{
+			DIMMs:    true,
+			Timeout:  internal.Duration{Duration: 5 * time.Second},
+			sysPath:  sysPath,
+			rasMcCtl: runRasMcCtl,
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package edac
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const rasMcCtlOutput = `Label               	CE	UE
+CPU_SrcID#0_Ha#0_Chan#0_DIMM#0	12	0
+mc#1csrow#0channel#0	0	1
+`
+
+func write(t *testing.T, root, path, content string) {
+	path = filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestGather(t *testing.T) {
+	root, err := ioutil.TempDir("", "edac")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	mc0 := "devices/system/edac/mc/mc0/"
+	write(t, root, mc0+"mc_name", "Skylake Socket#0 IMC#0\n")
+	write(t, root, mc0+"ce_count", "12\n")
+	write(t, root, mc0+"ue_count", "0\n")
+	write(t, root, mc0+"ce_noinfo_count", "0\n")
+	write(t, root, mc0+"ue_noinfo_count", "0\n")
+	write(t, root, mc0+"size_mb", "32768\n")
+	write(t, root, mc0+"seconds_since_reset", "86400\n")
+	write(t, root, mc0+"dimm0/dimm_label", "CPU_SrcID#0_Ha#0_Chan#0_DIMM#0\n")
+	write(t, root, mc0+"dimm0/dimm_location", "channel 0 slot 0 \n")
+	write(t, root, mc0+"dimm0/dimm_mem_type", "Registered-DDR4\n")
+	write(t, root, mc0+"dimm0/dimm_edac_mode", "SECDED\n")
+	write(t, root, mc0+"dimm0/dimm_ce_count", "12\n")
+	write(t, root, mc0+"dimm0/dimm_ue_count", "0\n")
+	write(t, root, mc0+"dimm0/size", "16384\n")
+
+	// Older kernels only have the csrow directories
+	mc1 := "devices/system/edac/mc/mc1/"
+	write(t, root, mc1+"mc_name", "i5000\n")
+	write(t, root, mc1+"ce_count", "3\n")
+	write(t, root, mc1+"ue_count", "1\n")
+	write(t, root, mc1+"csrow0/mem_type", "Registered-DDR2\n")
+	write(t, root, mc1+"csrow0/ch0_ce_count", "2\n")
+	write(t, root, mc1+"csrow0/ch0_dimm_label", "DIMM_A1\n")
+	write(t, root, mc1+"csrow0/ch1_ce_count", "1\n")
+	write(t, root, mc1+"csrow0/ch1_dimm_label", "\n")
+
+	plugin := &EDAC{
+		DIMMs:     true,
+		Rasdaemon: true,
+		sysPath:   root,
+		rasMcCtl: func(internal.Duration, bool) ([]byte, error) {
+			return []byte(rasMcCtlOutput), nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("edac_mc",
+			map[string]string{"controller": "mc0", "mc_name": "Skylake Socket#0 IMC#0"},
+			map[string]interface{}{
+				"ce_count":            uint64(12),
+				"ue_count":            uint64(0),
+				"ce_noinfo_count":     uint64(0),
+				"ue_noinfo_count":     uint64(0),
+				"size_mb":             uint64(32768),
+				"seconds_since_reset": uint64(86400),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("edac_dimm",
+			map[string]string{
+				"controller": "mc0",
+				"dimm":       "dimm0",
+				"label":      "CPU_SrcID#0_Ha#0_Chan#0_DIMM#0",
+				"location":   "channel 0 slot 0",
+				"mem_type":   "Registered-DDR4",
+				"edac_mode":  "SECDED",
+			},
+			map[string]interface{}{
+				"ce_count": uint64(12),
+				"ue_count": uint64(0),
+				"size_mb":  uint64(16384),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("edac_mc",
+			map[string]string{"controller": "mc1", "mc_name": "i5000"},
+			map[string]interface{}{
+				"ce_count": uint64(3),
+				"ue_count": uint64(1),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("edac_dimm",
+			map[string]string{
+				"controller": "mc1",
+				"dimm":       "csrow0_ch0",
+				"label":      "DIMM_A1",
+				"mem_type":   "Registered-DDR2",
+			},
+			map[string]interface{}{
+				"ce_count": uint64(2),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("edac_dimm",
+			map[string]string{
+				"controller": "mc1",
+				"dimm":       "csrow0_ch1",
+				"mem_type":   "Registered-DDR2",
+			},
+			map[string]interface{}{
+				"ce_count": uint64(1),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("rasdaemon",
+			map[string]string{"label": "CPU_SrcID#0_Ha#0_Chan#0_DIMM#0"},
+			map[string]interface{}{
+				"ce_count": uint64(12),
+				"ue_count": uint64(0),
+			},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric("rasdaemon",
+			map[string]string{"label": "mc#1csrow#0channel#0"},
+			map[string]interface{}{
+				"ce_count": uint64(0),
+				"ue_count": uint64(1),
+			},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
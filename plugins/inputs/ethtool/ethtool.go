@@ -2,15 +2,41 @@ package ethtool
 
 import (
 	"net"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/influxdata/telegraf"
 )
 
+// queueStatRegex matches the per-queue statistics of the drivers, such as
+// "rx_queue_0_drops", "tx-0.tx_packets" or "rx0_packets".
+var queueStatRegex = regexp.MustCompile(`^(rx|tx)(?:_queue_(\d+)_|-(\d+)\.|(\d+)_)(.+)$`)
+
 type Command interface {
 	Init() error
 	DriverName(intf string) (string, error)
 	Interfaces() ([]net.Interface, error)
 	Stats(intf string) (map[string]uint64, error)
+	Link(intf string) (*Link, error)
+}
+
+// Link is the link state of an interface.
+type Link struct {
+	// Speed in Mb/s, zero if unknown
+	Speed   uint32
+	Duplex  string
+	Autoneg bool
+
+	Carrier        bool
+	CarrierChanges uint64
+
+	// Master is the bond or team device of a slave interface, the slave
+	// state is only known for bond slaves.
+	Master           string
+	SlaveState       string
+	MiiStatus        string
+	LinkFailureCount uint64
 }
 
 type Ethtool struct {
@@ -20,10 +46,24 @@ type Ethtool struct {
 	// This is the list of interface names to ignore
 	InterfaceExclude []string `toml:"interface_exclude"`
 
+	// Report the per-queue statistics in the ethtool_queue measurement
+	QueueStats bool `toml:"queue_stats"`
+
+	// Report the link state and the bond or team of the interface
+	LinkState bool `toml:"link_state"`
+
+	// Report the changes of the link state in the ethtool_link_event
+	// measurement
+	LinkEvents bool `toml:"link_events"`
+
 	Log telegraf.Logger `toml:"-"`
 
 	// the ethtool command
 	command Command
+
+	// the link state of the last gather, used for the link events
+	mu        sync.Mutex
+	lastLinks map[string]*Link
 }
 
 const (
@@ -37,6 +77,19 @@ const (
 
   ## List of interfaces to ignore when pulling metrics.
   # interface_exclude = ["eth1"]
+
+  ## Report the per-queue statistics of the driver, such as rx_queue_0_drops,
+  ## in the ethtool_queue measurement with a queue tag rather than as fields
+  ## of the ethtool measurement.
+  # queue_stats = false
+
+  ## Report the speed, duplex and carrier of the link, and the state of bond
+  ## and team slaves.
+  # link_state = false
+
+  ## Report the carrier, speed and duplex changes of the link in the
+  ## ethtool_link_event measurement.
+  # link_events = false
 `
 )
 
@@ -48,3 +101,16 @@ func (e *Ethtool) SampleConfig() string {
 func (e *Ethtool) Description() string {
 	return "Returns ethtool statistics for given interfaces"
 }
+
+// splitQueueStat returns the direction, queue and name of a per-queue
+// statistic, the name is stripped of the direction prefix.
+func splitQueueStat(key string) (string, string, string, bool) {
+	match := queueStatRegex.FindStringSubmatch(key)
+	if match == nil {
+		return "", "", "", false
+	}
+	direction := match[1]
+	queue := match[2] + match[3] + match[4]
+	name := strings.TrimPrefix(match[5], direction+"_")
+	return direction, queue, name, true
+}
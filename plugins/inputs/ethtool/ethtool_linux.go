@@ -3,8 +3,15 @@
 package ethtool
 
 import (
+	"io/ioutil"
+	"math"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/filter"
@@ -15,6 +22,7 @@ import (
 
 type CommandEthtool struct {
 	ethtool *ethtool.Ethtool
+	sysPath string
 }
 
 func (e *Ethtool) Gather(acc telegraf.Accumulator) error {
@@ -80,15 +88,128 @@ func (e *Ethtool) gatherEthtoolStats(iface net.Interface, acc telegraf.Accumulat
 		return
 	}
 
+	queues := make(map[string]map[string]interface{})
 	for k, v := range stats {
+		if e.QueueStats {
+			if direction, queue, name, ok := splitQueueStat(k); ok {
+				key := direction + queue
+				if queues[key] == nil {
+					queues[key] = make(map[string]interface{})
+				}
+				queues[key][name] = v
+				continue
+			}
+		}
 		fields[k] = v
 	}
 
+	if e.LinkState || e.LinkEvents {
+		link, err := e.command.Link(iface.Name)
+		if err != nil {
+			acc.AddError(errors.Wrapf(err, "%s link", iface.Name))
+		} else {
+			if e.LinkState {
+				addLinkFields(fields, tags, link)
+			}
+			if e.LinkEvents {
+				e.addLinkEvents(acc, iface.Name, driverName, link)
+			}
+		}
+	}
+
 	acc.AddFields(pluginName, fields, tags)
+
+	for key, queueFields := range queues {
+		queueTags := map[string]string{
+			tagInterface:  iface.Name,
+			tagDriverName: driverName,
+			"direction":   key[:2],
+			"queue":       key[2:],
+		}
+		acc.AddFields(pluginName+"_queue", queueFields, queueTags)
+	}
+}
+
+func addLinkFields(fields map[string]interface{}, tags map[string]string, link *Link) {
+	if link.Speed > 0 {
+		fields["link_speed_mbps"] = uint64(link.Speed)
+	}
+	fields["link_duplex"] = link.Duplex
+	fields["link_autoneg"] = link.Autoneg
+	fields["link_carrier"] = link.Carrier
+	fields["link_carrier_changes"] = link.CarrierChanges
+
+	if link.Master != "" {
+		tags["master"] = link.Master
+	}
+	if link.SlaveState != "" {
+		fields["slave_state"] = link.SlaveState
+	}
+	if link.MiiStatus != "" {
+		fields["slave_mii_status"] = link.MiiStatus
+	}
+	if link.Master != "" && link.SlaveState != "" {
+		fields["slave_link_failure_count"] = link.LinkFailureCount
+	}
+}
+
+// addLinkEvents adds an event for each change of the carrier, speed and
+// duplex of the link since the last gather.
+func (e *Ethtool) addLinkEvents(acc telegraf.Accumulator, intf, driverName string, link *Link) {
+	e.mu.Lock()
+	if e.lastLinks == nil {
+		e.lastLinks = make(map[string]*Link)
+	}
+	last, ok := e.lastLinks[intf]
+	e.lastLinks[intf] = link
+	e.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	event := func(name string, fields map[string]interface{}) {
+		tags := map[string]string{
+			tagInterface:  intf,
+			tagDriverName: driverName,
+			"event":       name,
+		}
+		acc.AddFields(pluginName+"_link_event", fields, tags)
+	}
+
+	if link.Carrier != last.Carrier {
+		name := "carrier_down"
+		if link.Carrier {
+			name = "carrier_up"
+		}
+		event(name, map[string]interface{}{
+			"carrier":         link.Carrier,
+			"carrier_changes": link.CarrierChanges,
+		})
+	}
+	// The speed and duplex are unknown while the carrier is down
+	if link.Carrier && last.Carrier {
+		if link.Speed != last.Speed {
+			event("speed_change", map[string]interface{}{
+				"speed_mbps":          uint64(link.Speed),
+				"previous_speed_mbps": uint64(last.Speed),
+			})
+		}
+		if link.Duplex != last.Duplex {
+			event("duplex_change", map[string]interface{}{
+				"duplex":          link.Duplex,
+				"previous_duplex": last.Duplex,
+			})
+		}
+	}
 }
 
 func NewCommandEthtool() *CommandEthtool {
-	return &CommandEthtool{}
+	sysPath := "/sys"
+	if hostSys := os.Getenv("HOST_SYS"); hostSys != "" {
+		sysPath = hostSys
+	}
+	return &CommandEthtool{sysPath: sysPath}
 }
 
 func (c *CommandEthtool) Init() error {
@@ -113,6 +234,65 @@ func (c *CommandEthtool) Stats(intf string) (map[string]uint64, error) {
 	return c.ethtool.Stats(intf)
 }
 
+// Link returns the link settings of the ETHTOOL_GSET ioctl and the carrier
+// and bonding state of sysfs.
+func (c *CommandEthtool) Link(intf string) (*Link, error) {
+	link := &Link{Duplex: "unknown"}
+
+	// Virtual interfaces may not support the link settings
+	var cmd ethtool.EthtoolCmd
+	if speed, err := c.ethtool.CmdGet(&cmd, intf); err == nil {
+		if speed != math.MaxUint32 && speed != math.MaxUint16 {
+			link.Speed = speed
+		}
+		switch cmd.Duplex {
+		case 0:
+			link.Duplex = "half"
+		case 1:
+			link.Duplex = "full"
+		}
+		link.Autoneg = cmd.Autoneg == 1
+	}
+
+	dir := filepath.Join(c.sysPath, "class", "net", intf)
+	carrier, err := readSysfs(filepath.Join(dir, "carrier"))
+	if err != nil && !os.IsNotExist(err) && !isInvalid(err) {
+		return nil, err
+	}
+	link.Carrier = carrier == "1"
+	if changes, err := readSysfs(filepath.Join(dir, "carrier_changes")); err == nil {
+		link.CarrierChanges, _ = strconv.ParseUint(changes, 10, 64)
+	}
+
+	if master, err := os.Readlink(filepath.Join(dir, "master")); err == nil {
+		link.Master = filepath.Base(master)
+		bonding := filepath.Join(dir, "bonding_slave")
+		link.SlaveState, _ = readSysfs(filepath.Join(bonding, "state"))
+		link.MiiStatus, _ = readSysfs(filepath.Join(bonding, "mii_status"))
+		if count, err := readSysfs(filepath.Join(bonding, "link_failure_count")); err == nil {
+			link.LinkFailureCount, _ = strconv.ParseUint(count, 10, 64)
+		}
+	}
+	return link, nil
+}
+
+func readSysfs(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// isInvalid returns true for the EINVAL error of reading the carrier of an
+// interface that is administratively down.
+func isInvalid(err error) bool {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err == syscall.EINVAL
+	}
+	return false
+}
+
 func (c *CommandEthtool) Interfaces() ([]net.Interface, error) {
 
 	// Get the list of interfaces
@@ -17,6 +17,7 @@ type InterfaceMock struct {
 	DriverName string
 	Stat       map[string]uint64
 	LoopBack   bool
+	Link       *Link
 }
 
 type CommandEthtoolMock struct {
@@ -69,6 +70,14 @@ func (c *CommandEthtoolMock) Stats(intf string) (stat map[string]uint64, err err
 	return stat, errors.New("interface not found")
 }
 
+func (c *CommandEthtoolMock) Link(intf string) (*Link, error) {
+	i := c.InterfaceMap[intf]
+	if i != nil {
+		return i.Link, nil
+	}
+	return nil, errors.New("interface not found")
+}
+
 func setup() {
 
 	interfaceMap = make(map[string]*InterfaceMock)
@@ -171,7 +180,17 @@ func setup() {
 		"tx_tso_fallbacks":               0,
 		"tx_tso_long_headers":            0,
 	}
-	eth1 := &InterfaceMock{"eth1", "driver1", eth1Stat, false}
+	eth1Link := &Link{
+		Speed:          10000,
+		Duplex:         "full",
+		Autoneg:        true,
+		Carrier:        true,
+		CarrierChanges: 2,
+		Master:         "bond0",
+		SlaveState:     "active",
+		MiiStatus:      "up",
+	}
+	eth1 := &InterfaceMock{"eth1", "driver1", eth1Stat, false, eth1Link}
 	interfaceMap[eth1.Name] = eth1
 
 	eth2Stat := map[string]uint64{
@@ -272,14 +291,20 @@ func setup() {
 		"tx_tso_fallbacks":               0,
 		"tx_tso_long_headers":            0,
 	}
-	eth2 := &InterfaceMock{"eth2", "driver1", eth2Stat, false}
+	eth2Link := &Link{
+		Speed:          25000,
+		Duplex:         "full",
+		Carrier:        true,
+		CarrierChanges: 1,
+	}
+	eth2 := &InterfaceMock{"eth2", "driver1", eth2Stat, false, eth2Link}
 	interfaceMap[eth2.Name] = eth2
 
 	// dummy loopback including dummy stat to ensure that the ignore feature is working
 	lo0Stat := map[string]uint64{
 		"dummy": 0,
 	}
-	lo0 := &InterfaceMock{"lo0", "", lo0Stat, true}
+	lo0 := &InterfaceMock{"lo0", "", lo0Stat, true, &Link{}}
 	interfaceMap[lo0.Name] = lo0
 
 	c := &CommandEthtoolMock{interfaceMap}
@@ -377,3 +402,129 @@ func TestGatherIgnoreInterfaces(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, pluginName, expectedFieldsEth2, expectedTagsEth2)
 
 }
+
+func TestGatherQueueStats(t *testing.T) {
+
+	setup()
+	var acc testutil.Accumulator
+
+	command.InterfaceInclude = append(command.InterfaceInclude, "eth1")
+	command.QueueStats = true
+
+	err := command.Gather(&acc)
+	assert.NoError(t, err)
+
+	fields, ok := acc.Get(pluginName)
+	assert.True(t, ok)
+	assert.NotContains(t, fields.Fields, "rx-0.rx_packets")
+	assert.Contains(t, fields.Fields, "port_rx_1024_to_15xx")
+
+	acc.AssertContainsTaggedFields(t, pluginName+"_queue",
+		map[string]interface{}{"packets": uint64(55659234)},
+		map[string]string{"interface": "eth1", "driver": "driver1", "direction": "rx", "queue": "0"})
+	acc.AssertContainsTaggedFields(t, pluginName+"_queue",
+		map[string]interface{}{"packets": uint64(207561010)},
+		map[string]string{"interface": "eth1", "driver": "driver1", "direction": "tx", "queue": "3"})
+}
+
+func TestSplitQueueStat(t *testing.T) {
+	tests := []struct {
+		key       string
+		direction string
+		queue     string
+		name      string
+		ok        bool
+	}{
+		{"rx_queue_0_drops", "rx", "0", "drops", true},
+		{"tx_queue_12_bytes", "tx", "12", "bytes", true},
+		{"tx-3.tx_packets", "tx", "3", "packets", true},
+		{"rx5_xdp_drop", "rx", "5", "xdp_drop", true},
+		{"rx_1024_to_1522_octet_packets", "", "", "", false},
+		{"port_rx_bytes", "", "", "", false},
+		{"fec_corrected_blocks", "", "", "", false},
+	}
+	for _, tt := range tests {
+		direction, queue, name, ok := splitQueueStat(tt.key)
+		assert.Equal(t, tt.ok, ok, tt.key)
+		assert.Equal(t, tt.direction, direction, tt.key)
+		assert.Equal(t, tt.queue, queue, tt.key)
+		assert.Equal(t, tt.name, name, tt.key)
+	}
+}
+
+func TestGatherLinkState(t *testing.T) {
+
+	setup()
+	var acc testutil.Accumulator
+
+	command.LinkState = true
+
+	err := command.Gather(&acc)
+	assert.NoError(t, err)
+
+	expectedFieldsEth1 := toStringMapInterface(interfaceMap["eth1"].Stat)
+	expectedFieldsEth1["link_speed_mbps"] = uint64(10000)
+	expectedFieldsEth1["link_duplex"] = "full"
+	expectedFieldsEth1["link_autoneg"] = true
+	expectedFieldsEth1["link_carrier"] = true
+	expectedFieldsEth1["link_carrier_changes"] = uint64(2)
+	expectedFieldsEth1["slave_state"] = "active"
+	expectedFieldsEth1["slave_mii_status"] = "up"
+	expectedFieldsEth1["slave_link_failure_count"] = uint64(0)
+	expectedTagsEth1 := map[string]string{
+		"interface": "eth1",
+		"driver":    "driver1",
+		"master":    "bond0",
+	}
+	acc.AssertContainsTaggedFields(t, pluginName, expectedFieldsEth1, expectedTagsEth1)
+
+	expectedFieldsEth2 := toStringMapInterface(interfaceMap["eth2"].Stat)
+	expectedFieldsEth2["link_speed_mbps"] = uint64(25000)
+	expectedFieldsEth2["link_duplex"] = "full"
+	expectedFieldsEth2["link_autoneg"] = false
+	expectedFieldsEth2["link_carrier"] = true
+	expectedFieldsEth2["link_carrier_changes"] = uint64(1)
+	expectedTagsEth2 := map[string]string{
+		"interface": "eth2",
+		"driver":    "driver1",
+	}
+	acc.AssertContainsTaggedFields(t, pluginName, expectedFieldsEth2, expectedTagsEth2)
+}
+
+func TestGatherLinkEvents(t *testing.T) {
+
+	setup()
+	var acc testutil.Accumulator
+
+	command.LinkEvents = true
+
+	// The first gather only records the link state
+	err := command.Gather(&acc)
+	assert.NoError(t, err)
+	assert.False(t, acc.HasMeasurement(pluginName+"_link_event"))
+
+	interfaceMap["eth1"].Link = &Link{Duplex: "unknown", CarrierChanges: 3}
+	interfaceMap["eth2"].Link = &Link{Speed: 10000, Duplex: "half", Carrier: true, CarrierChanges: 1}
+
+	acc.ClearMetrics()
+	err = command.Gather(&acc)
+	assert.NoError(t, err)
+
+	acc.AssertContainsTaggedFields(t, pluginName+"_link_event",
+		map[string]interface{}{"carrier": false, "carrier_changes": uint64(3)},
+		map[string]string{"interface": "eth1", "driver": "driver1", "event": "carrier_down"})
+	acc.AssertContainsTaggedFields(t, pluginName+"_link_event",
+		map[string]interface{}{"speed_mbps": uint64(10000), "previous_speed_mbps": uint64(25000)},
+		map[string]string{"interface": "eth2", "driver": "driver1", "event": "speed_change"})
+	acc.AssertContainsTaggedFields(t, pluginName+"_link_event",
+		map[string]interface{}{"duplex": "half", "previous_duplex": "full"},
+		map[string]string{"interface": "eth2", "driver": "driver1", "event": "duplex_change"})
+
+	var events int
+	for _, m := range acc.Metrics {
+		if m.Measurement == pluginName+"_link_event" {
+			events++
+		}
+	}
+	assert.Equal(t, 3, events)
+}
@@ -0,0 +1,80 @@
+package eventhub_consumer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const captureSchema = `{
+  "type": "record",
+  "name": "EventData",
+  "namespace": "Microsoft.ServiceBus.Messaging",
+  "fields": [
+    {"name": "SequenceNumber", "type": "long"},
+    {"name": "Offset", "type": "string"},
+    {"name": "EnqueuedTimeUtc", "type": "string"},
+    {"name": "SystemProperties", "type": {"type": "map", "values": ["long", "double", "string", "bytes"]}},
+    {"name": "Properties", "type": {"type": "map", "values": ["long", "double", "string", "bytes", "null"]}},
+    {"name": "Body", "type": ["null", "bytes"]}
+  ]
+}`
+
+func captureRecord(seq int64, enqueued string, body string) map[string]interface{} {
+	return map[string]interface{}{
+		"SequenceNumber":   seq,
+		"Offset":           "0",
+		"EnqueuedTimeUtc":  enqueued,
+		"SystemProperties": map[string]interface{}{},
+		"Properties":       map[string]interface{}{},
+		"Body":             goavro.Union("bytes", []byte(body)),
+	}
+}
+
+func TestReadCapture(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:      &buf,
+		Schema: captureSchema,
+	})
+	require.NoError(t, err)
+
+	err = w.Append([]interface{}{
+		captureRecord(1, "9/5/2018 1:22:14 PM", "cpu value=42 0\n"),
+		captureRecord(2, "9/5/2018 1:22:15 PM", "~invalid~"),
+		captureRecord(3, "9/5/2018 1:22:16 PM", "mem value=1 0\n"),
+	})
+	require.NoError(t, err)
+
+	parser, _ := parsers.NewInfluxParser()
+	acc := &testutil.Accumulator{}
+	e := &EventHub{
+		EnqueuedTimeAsTs: true,
+		Log:              testutil.Logger{},
+		parser:           parser,
+		acc:              acc.WithTracking(10),
+	}
+
+	var metrics []telegraf.Metric
+	err = e.readCapture(context.Background(), &buf, func(m []telegraf.Metric) error {
+		metrics = append(metrics, m...)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, metrics, 2)
+	require.Equal(t, "cpu", metrics[0].Name())
+	require.Equal(t, time.Date(2018, 9, 5, 13, 22, 14, 0, time.UTC), metrics[0].Time())
+	require.Equal(t, "mem", metrics[1].Name())
+	require.Equal(t, time.Date(2018, 9, 5, 13, 22, 16, 0, time.UTC), metrics[1].Time())
+
+	// The event which could not be parsed is reported and skipped.
+	require.Len(t, acc.Errors, 1)
+}
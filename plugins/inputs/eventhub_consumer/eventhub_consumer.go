@@ -0,0 +1,408 @@
+package eventhub_consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/Azure/azure-event-hubs-go/v3/eph"
+	"github.com/Azure/azure-event-hubs-go/v3/persist"
+	"github.com/Azure/azure-event-hubs-go/v3/storage"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const (
+	defaultMaxUndeliveredMessages = 1000
+)
+
+type empty struct{}
+type semaphore chan empty
+
+// EventHub is the Azure Event Hubs consumer input plugin
+type EventHub struct {
+	// Connection
+	ConnectionString string `toml:"connection_string"`
+	ConsumerGroup    string `toml:"consumer_group"`
+	UserAgent        string `toml:"user_agent"`
+	WebSocket        bool   `toml:"websocket"`
+
+	// Receive settings
+	PartitionIDs  []string `toml:"partition_ids"`
+	Latest        bool     `toml:"latest"`
+	PrefetchCount uint32   `toml:"prefetch_count"`
+
+	// Checkpoint store
+	PersistenceDir       string `toml:"persistence_dir"`
+	StorageAccountName   string `toml:"storage_account_name"`
+	StorageAccountKey    string `toml:"storage_account_key"`
+	StorageContainerName string `toml:"storage_container_name"`
+
+	// Captured file replay
+	CaptureFiles []string `toml:"capture_files"`
+
+	// Metric settings
+	PartitionIDTag   string `toml:"partition_id_tag"`
+	EnqueuedTimeAsTs bool   `toml:"enqueued_time_as_ts"`
+
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
+	Log telegraf.Logger
+
+	hub    *eventhub.Hub
+	host   *eph.EventProcessorHost
+	parser parsers.Parser
+	acc    telegraf.TrackingAccumulator
+	sem    semaphore
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+const sampleConfig = `
+  ## The Event Hub connection string, including the EntityPath of the hub.
+  connection_string = ""
+
+  ## Consumer group to read with, if unset the default consumer group is used.
+  # consumer_group = ""
+
+  ## Set a custom user agent.
+  # user_agent = "telegraf"
+
+  ## Connect using AMQP over WebSockets on port 443 instead of AMQP on port
+  ## 5671.  Use when outbound AMQP traffic is blocked by a firewall or proxy.
+  # websocket = false
+
+  ## Partitions to read, if unset all partitions are read.  This option is
+  ## ignored when a storage account is used, as partitions are then balanced
+  ## between all consumers sharing the checkpoint store.
+  # partition_ids = ["0", "1"]
+
+  ## If true, partitions without a checkpoint are read from the latest event,
+  ## otherwise from the oldest retained event.
+  # latest = false
+
+  ## Number of events to request from the service ahead of processing.
+  # prefetch_count = 300
+
+  ## Directory to persist the partition checkpoints to.  Checkpoints stored
+  ## this way are not shared between instances.
+  # persistence_dir = ""
+
+  ## Azure Blob Storage account used as checkpoint store.  When set, the
+  ## ownership of partitions is coordinated between all Telegraf instances
+  ## using the same container and consumer group, and reading resumes from
+  ## the last checkpoint after a restart.
+  # storage_account_name = ""
+  # storage_account_key = ""
+  # storage_container_name = ""
+
+  ## Event Hubs Capture Avro files to replay on startup, before reading
+  ## from the hub.  Glob patterns are supported.
+  # capture_files = ["/var/lib/telegraf/capture/**.avro"]
+
+  ## Tag to store the partition ID of the event in, if unset no tag is added.
+  # partition_id_tag = ""
+
+  ## Use the enqueued time of the event as metric timestamp.
+  # enqueued_time_as_ts = false
+
+  ## Maximum messages to read from the hub that have not been written by an
+  ## output.  For best throughput set based on the number of metrics within
+  ## each message and the size of the output's metric_batch_size.
+  ##
+  ## For example, if each message contains 10 metrics and the output
+  ## metric_batch_size is 1000, setting this to 100 will ensure that a
+  ## full batch is collected and the write is triggered immediately without
+  ## waiting until the next flush_interval.
+  # max_undelivered_messages = 1000
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+// SampleConfig is provided here
+func (*EventHub) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description of the plugin
+func (*EventHub) Description() string {
+	return "Azure Event Hubs service input plugin"
+}
+
+// SetParser sets the parser
+func (e *EventHub) SetParser(parser parsers.Parser) {
+	e.parser = parser
+}
+
+// Gather function is unused
+func (*EventHub) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+// Init the EventHub plugin
+func (e *EventHub) Init() error {
+	if e.ConnectionString == "" && len(e.CaptureFiles) == 0 {
+		return errors.New("connection_string or capture_files must be configured")
+	}
+
+	if e.StorageAccountName != "" || e.StorageContainerName != "" {
+		if e.StorageAccountName == "" || e.StorageAccountKey == "" || e.StorageContainerName == "" {
+			return errors.New("storage_account_name, storage_account_key and storage_container_name must all be configured")
+		}
+		if e.PersistenceDir != "" {
+			return errors.New("persistence_dir cannot be used with a storage account")
+		}
+		if len(e.PartitionIDs) > 0 {
+			return errors.New("partition_ids cannot be used with a storage account")
+		}
+	}
+
+	if e.MaxUndeliveredMessages <= 0 {
+		return errors.New("max_undelivered_messages must be greater than 0")
+	}
+	return nil
+}
+
+// Start the EventHub ServiceInput
+func (e *EventHub) Start(acc telegraf.Accumulator) error {
+	e.acc = acc.WithTracking(e.MaxUndeliveredMessages)
+	e.sem = make(semaphore, e.MaxUndeliveredMessages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.onDelivery(ctx)
+	}()
+
+	if len(e.CaptureFiles) > 0 {
+		if err := e.replayCaptureFiles(ctx); err != nil {
+			e.Stop()
+			return err
+		}
+	}
+
+	if e.ConnectionString == "" {
+		return nil
+	}
+
+	var err error
+	if e.StorageAccountName != "" {
+		err = e.startHost(ctx)
+	} else {
+		err = e.startHub(ctx)
+	}
+	if err != nil {
+		e.Stop()
+		return err
+	}
+	return nil
+}
+
+// startHub reads the configured partitions directly from the hub.
+func (e *EventHub) startHub(ctx context.Context) error {
+	opts := []eventhub.HubOption{
+		eventhub.HubWithUserAgent(e.UserAgent),
+	}
+	if e.WebSocket {
+		opts = append(opts, eventhub.HubWithWebSocketConnection())
+	}
+	if e.PersistenceDir != "" {
+		persister, err := persist.NewFilePersister(e.PersistenceDir)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, eventhub.HubWithOffsetPersistence(persister))
+	}
+
+	hub, err := eventhub.NewHubFromConnectionString(e.ConnectionString, opts...)
+	if err != nil {
+		return err
+	}
+	e.hub = hub
+
+	partitions := e.PartitionIDs
+	if len(partitions) == 0 {
+		info, err := hub.GetRuntimeInformation(ctx)
+		if err != nil {
+			return err
+		}
+		partitions = info.PartitionIDs
+	}
+
+	receiveOpts := e.receiveOptions()
+	for _, partitionID := range partitions {
+		_, err := hub.Receive(ctx, partitionID, e.onMessage, receiveOpts...)
+		if err != nil {
+			return fmt.Errorf("creating receiver for partition %q: %v", partitionID, err)
+		}
+	}
+	return nil
+}
+
+func (e *EventHub) receiveOptions() []eventhub.ReceiveOption {
+	var opts []eventhub.ReceiveOption
+	if e.ConsumerGroup != "" {
+		opts = append(opts, eventhub.ReceiveWithConsumerGroup(e.ConsumerGroup))
+	}
+	if e.PrefetchCount != 0 {
+		opts = append(opts, eventhub.ReceiveWithPrefetchCount(e.PrefetchCount))
+	}
+	// A persisted checkpoint takes precedence over the starting offset.
+	if e.Latest {
+		opts = append(opts, eventhub.ReceiveWithLatestOffset())
+	}
+	return opts
+}
+
+// startHost reads the hub using an event processor host, which balances
+// the partitions between all consumers using the same blob container and
+// stores the partition checkpoints in it.
+func (e *EventHub) startHost(ctx context.Context) error {
+	credential, err := azblob.NewSharedKeyCredential(e.StorageAccountName, e.StorageAccountKey)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.NewStorageLeaserCheckpointer(credential, e.StorageAccountName, e.StorageContainerName, azure.PublicCloud)
+	if err != nil {
+		return err
+	}
+
+	opts := []eph.EventProcessorHostOption{
+		eph.WithNoBanner(),
+	}
+	if e.ConsumerGroup != "" {
+		opts = append(opts, eph.WithConsumerGroup(e.ConsumerGroup))
+	}
+	if e.WebSocket {
+		opts = append(opts, eph.WithWebSocketConnection())
+	}
+
+	host, err := eph.NewFromConnectionString(ctx, e.ConnectionString, store, store, opts...)
+	if err != nil {
+		return err
+	}
+	e.host = host
+
+	if _, err := host.RegisterHandler(ctx, e.onMessage); err != nil {
+		return err
+	}
+	return host.StartNonBlocking(ctx)
+}
+
+// onMessage adds the metrics of the event to the accumulator, blocking while
+// max_undelivered_messages is reached.  Returning from the handler allows the
+// checkpoint to advance past the event.
+func (e *EventHub) onMessage(ctx context.Context, event *eventhub.Event) error {
+	metrics, err := e.createMetrics(event)
+	if err != nil {
+		// Skip the event, it will never be parsed successfully.
+		e.acc.AddError(err)
+		return nil
+	}
+
+	return e.addMetrics(ctx, metrics)
+}
+
+func (e *EventHub) addMetrics(ctx context.Context, metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case e.sem <- empty{}:
+	}
+
+	e.acc.AddTrackingMetricGroup(metrics)
+	return nil
+}
+
+func (e *EventHub) onDelivery(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case info := <-e.acc.Delivered():
+			<-e.sem
+			if !info.Delivered() {
+				e.Log.Debug("Metric group failed to process")
+			}
+		}
+	}
+}
+
+func (e *EventHub) createMetrics(event *eventhub.Event) ([]telegraf.Metric, error) {
+	metrics, err := e.parser.Parse(event.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitionID string
+	var enqueuedTime *time.Time
+	if sp := event.SystemProperties; sp != nil {
+		if sp.PartitionID != nil {
+			partitionID = fmt.Sprintf("%d", *sp.PartitionID)
+		}
+		enqueuedTime = sp.EnqueuedTime
+	}
+
+	e.annotate(metrics, partitionID, enqueuedTime)
+	return metrics, nil
+}
+
+// annotate adds the event metadata to the metrics.
+func (e *EventHub) annotate(metrics []telegraf.Metric, partitionID string, enqueuedTime *time.Time) {
+	for _, m := range metrics {
+		if e.PartitionIDTag != "" && partitionID != "" {
+			m.AddTag(e.PartitionIDTag, partitionID)
+		}
+		if e.EnqueuedTimeAsTs && enqueuedTime != nil {
+			m.SetTime(*enqueuedTime)
+		}
+	}
+}
+
+// Stop the EventHub ServiceInput
+func (e *EventHub) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if e.host != nil {
+		if err := e.host.Close(ctx); err != nil {
+			e.Log.Errorf("Error closing event processor host: %v", err)
+		}
+	}
+	if e.hub != nil {
+		if err := e.hub.Close(ctx); err != nil {
+			e.Log.Errorf("Error closing hub: %v", err)
+		}
+	}
+
+	e.cancel()
+	e.wg.Wait()
+}
+
+func init() {
+	inputs.Add("eventhub_consumer", func() telegraf.Input {
+		return &EventHub{
+			UserAgent:              internal.ProductToken(),
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
+	})
+}
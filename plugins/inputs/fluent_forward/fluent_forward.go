@@ -0,0 +1,293 @@
+package fluent_forward
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/forward"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Address and port to listen on for forward protocol connections.
+  service_address = ":24224"
+
+  ## Maximum number of concurrent connections, 0 means unlimited.
+  # max_connections = 0
+
+  ## Close connections without messages for this duration, 0 means never.
+  # read_timeout = "0s"
+
+  ## Shared key used to authenticate the clients, if set the shared key
+  ## handshake is required.  The self_hostname is sent to the clients and
+  ## defaults to the hostname of the system.
+  # shared_key = ""
+  # self_hostname = ""
+
+  ## Record keys to add as tags instead of fields.  The tag of the events is
+  ## used as measurement name.
+  # tag_keys = []
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+`
+
+// FluentForward is an input plugin receiving events with the Fluentd forward
+// protocol.
+type FluentForward struct {
+	ServiceAddress string            `toml:"service_address"`
+	MaxConnections int               `toml:"max_connections"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	SharedKey      string            `toml:"shared_key"`
+	SelfHostname   string            `toml:"self_hostname"`
+	TagKeys        []string          `toml:"tag_keys"`
+	tlsint.ServerConfig
+
+	Log telegraf.Logger
+
+	listener    net.Listener
+	acc         telegraf.Accumulator
+	wg          sync.WaitGroup
+	connections map[string]net.Conn
+	mu          sync.Mutex
+}
+
+func (f *FluentForward) Description() string {
+	return "Receive events from Fluentd and Fluent Bit using the forward protocol"
+}
+
+func (f *FluentForward) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *FluentForward) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (f *FluentForward) Start(acc telegraf.Accumulator) error {
+	if f.SharedKey != "" && f.SelfHostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		f.SelfHostname = hostname
+	}
+
+	tlsConf, err := f.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", f.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", f.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+	f.listener = listener
+	f.acc = acc
+	f.connections = make(map[string]net.Conn)
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.listen()
+	}()
+
+	f.Log.Infof("Listening on %s", listener.Addr().String())
+	return nil
+}
+
+func (f *FluentForward) Stop() {
+	f.listener.Close()
+	f.wg.Wait()
+}
+
+func (f *FluentForward) listen() {
+	for {
+		c, err := f.listener.Accept()
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				f.Log.Error(err.Error())
+			}
+			break
+		}
+
+		f.mu.Lock()
+		if f.MaxConnections > 0 && len(f.connections) >= f.MaxConnections {
+			f.mu.Unlock()
+			f.Log.Warnf("Refused connection from %s, max_connections reached", c.RemoteAddr())
+			c.Close()
+			continue
+		}
+		f.connections[c.RemoteAddr().String()] = c
+		f.mu.Unlock()
+
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			defer f.removeConnection(c)
+			if err := f.handle(c); err != nil {
+				f.Log.Errorf("Connection from %s: %v", c.RemoteAddr(), err)
+			}
+		}()
+	}
+
+	f.mu.Lock()
+	for _, c := range f.connections {
+		c.Close()
+	}
+	f.mu.Unlock()
+}
+
+func (f *FluentForward) removeConnection(c net.Conn) {
+	f.mu.Lock()
+	delete(f.connections, c.RemoteAddr().String())
+	f.mu.Unlock()
+	c.Close()
+}
+
+func (f *FluentForward) handle(c net.Conn) error {
+	d := forward.NewDecoder(c)
+
+	if f.SharedKey != "" {
+		f.setDeadline(c)
+		if err := forward.ServerHandshake(c, d, f.SelfHostname, f.SharedKey); err != nil {
+			return err
+		}
+	}
+
+	e := &forward.Encoder{}
+	for {
+		f.setDeadline(c)
+		v, err := d.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				f.Log.Debugf("Timeout in plugin: %v", err)
+				return nil
+			}
+			return err
+		}
+
+		msg, err := forward.ParseMessage(v)
+		if err != nil {
+			// The stream can not be resynchronized after an invalid message.
+			return fmt.Errorf("invalid message: %v", err)
+		}
+
+		for _, entry := range msg.Entries {
+			m, err := f.convert(msg.Tag, entry)
+			if err != nil {
+				f.acc.AddError(err)
+				continue
+			}
+			f.acc.AddMetric(m)
+		}
+
+		if msg.Chunk != "" {
+			e.Reset()
+			forward.EncodeAck(e, msg.Chunk)
+			if _, err := c.Write(e.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (f *FluentForward) setDeadline(c net.Conn) {
+	if f.ReadTimeout.Duration > 0 {
+		c.SetReadDeadline(time.Now().Add(f.ReadTimeout.Duration))
+	}
+}
+
+// convert creates a metric from an event, the tag of the event is used as
+// measurement.  Nested maps and arrays of the record are flattened.
+func (f *FluentForward) convert(tag string, entry forward.Entry) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	for k, v := range entry.Record {
+		if f.isTagKey(k) {
+			if s, ok := tagValue(v); ok {
+				tags[k] = s
+			}
+			continue
+		}
+		flatten(fields, k, v)
+	}
+	return metric.New(tag, tags, fields, entry.Time)
+}
+
+func (f *FluentForward) isTagKey(key string) bool {
+	for _, k := range f.TagKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func tagValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+func flatten(fields map[string]interface{}, key string, v interface{}) {
+	switch v := v.(type) {
+	case string, int64, uint64, float64, bool:
+		fields[key] = v
+	case []byte:
+		fields[key] = string(v)
+	case map[string]interface{}:
+		for k, item := range v {
+			flatten(fields, key+"_"+k, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(fields, key+"_"+strconv.Itoa(i), item)
+		}
+	}
+}
+
+func init() {
+	inputs.Add("fluent_forward", func() telegraf.Input {
+		return &FluentForward{
+			ServiceAddress: ":24224",
+		}
+	})
+}
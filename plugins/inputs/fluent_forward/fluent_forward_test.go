@@ -0,0 +1,120 @@
+package fluent_forward
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/forward"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPlugin() *FluentForward {
+	return &FluentForward{
+		ServiceAddress: "localhost:0",
+		TagKeys:        []string{"host"},
+		Log:            testutil.Logger{},
+	}
+}
+
+func TestForwardMessage(t *testing.T) {
+	plugin := newTestPlugin()
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	conn, err := net.Dial("tcp", plugin.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	e := &forward.Encoder{}
+	require.NoError(t, e.Encode([]interface{}{
+		"app.log",
+		[]interface{}{
+			[]interface{}{
+				time.Unix(10, 500),
+				map[string]interface{}{
+					"host":    "server01",
+					"message": []byte("request done"),
+					"latency": 0.25,
+					"http":    map[string]interface{}{"status": int64(200)},
+				},
+			},
+		},
+		map[string]interface{}{"chunk": "chunk-1"},
+	}))
+	_, err = conn.Write(e.Bytes())
+	require.NoError(t, err)
+
+	// The message is acknowledged after the metrics were added.
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	v, err := forward.NewDecoder(conn).Decode()
+	require.NoError(t, err)
+	chunk, err := forward.ParseAck(v)
+	require.NoError(t, err)
+	require.Equal(t, "chunk-1", chunk)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"app.log",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{
+				"message":     "request done",
+				"latency":     0.25,
+				"http_status": int64(200),
+			},
+			time.Unix(10, 500),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestSharedKey(t *testing.T) {
+	plugin := newTestPlugin()
+	plugin.SharedKey = "secret"
+	plugin.SelfHostname = "telegraf"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	conn, err := net.Dial("tcp", plugin.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	require.NoError(t, forward.ClientHandshake(conn, forward.NewDecoder(conn), "client", "secret"))
+
+	e := &forward.Encoder{}
+	require.NoError(t, e.Encode([]interface{}{"cpu", int64(20), map[string]interface{}{"usage": 1.5}}))
+	_, err = conn.Write(e.Bytes())
+	require.NoError(t, err)
+
+	acc.Wait(1)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"usage": 1.5},
+			time.Unix(20, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestSharedKeyMismatch(t *testing.T) {
+	plugin := newTestPlugin()
+	plugin.SharedKey = "secret"
+	plugin.SelfHostname = "telegraf"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	conn, err := net.Dial("tcp", plugin.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	require.Error(t, forward.ClientHandshake(conn, forward.NewDecoder(conn), "client", "wrong"))
+}
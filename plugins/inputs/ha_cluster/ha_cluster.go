@@ -0,0 +1,170 @@
+package ha_cluster
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	sourceKeepalived = "keepalived"
+	sourcePacemaker  = "pacemaker"
+	sourceCorosync   = "corosync"
+)
+
+type runner func(timeout time.Duration, useSudo bool, command string, args ...string) ([]byte, error)
+
+// HACluster gathers the state of the VRRP instances of keepalived and of the
+// nodes and resources of Corosync/Pacemaker clusters.
+type HACluster struct {
+	Sources []string `toml:"sources"`
+
+	KeepalivedJSONFile   string `toml:"keepalived_json_file"`
+	KeepalivedPidFile    string `toml:"keepalived_pid_file"`
+	KeepalivedJSONSignal int    `toml:"keepalived_json_signal"`
+
+	CrmMonBinary          string `toml:"crm_mon_binary"`
+	CorosyncCmapctlBinary string `toml:"corosync_cmapctl_binary"`
+	UseSudo               bool   `toml:"use_sudo"`
+
+	Timeout internal.Duration `toml:"timeout"`
+
+	run runner
+
+	// states of the objects of each source at the previous gather, by
+	// object and name, to report the transitions.
+	states map[string]map[objectKey]string
+}
+
+// objectKey identifies an object of a source, such as a VRRP instance or a
+// Pacemaker resource.
+type objectKey struct {
+	object string
+	name   string
+}
+
+var sampleConfig = `
+  ## Sources of the cluster state, any of:
+  ##   "keepalived": VRRP instances from the JSON dump of keepalived
+  ##   "pacemaker":  nodes and resources from "crm_mon --as-xml"
+  ##   "corosync":   members from "corosync-cmapctl runtime.members"
+  # sources = ["keepalived"]
+
+  ## JSON dump of the VRRP instances.  When the pid file is set, keepalived is
+  ## sent the signal to write the dump on each interval, the signal number is
+  ## given by "keepalived --signum=JSON".
+  # keepalived_json_file = "/tmp/keepalived.json"
+  # keepalived_pid_file = "/run/keepalived.pid"
+  # keepalived_json_signal = 36
+
+  ## Location of the Pacemaker and Corosync binaries, run with sudo when the
+  ## user of Telegraf is not allowed to query the cluster.
+  # crm_mon_binary = "crm_mon"
+  # corosync_cmapctl_binary = "corosync-cmapctl"
+  # use_sudo = false
+
+  ## Timeout of the commands and of the keepalived dump.
+  # timeout = "5s"
+`
+
+func (h *HACluster) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HACluster) Description() string {
+	return "Gather the state of keepalived VRRP instances and Pacemaker clusters"
+}
+
+func (h *HACluster) Init() error {
+	for _, source := range h.Sources {
+		switch source {
+		case sourceKeepalived, sourcePacemaker, sourceCorosync:
+		default:
+			return fmt.Errorf("unknown source %q", source)
+		}
+	}
+	h.states = make(map[string]map[objectKey]string)
+	return nil
+}
+
+func (h *HACluster) Gather(acc telegraf.Accumulator) error {
+	for _, source := range h.Sources {
+		var states map[objectKey]string
+		var err error
+		switch source {
+		case sourceKeepalived:
+			states, err = h.gatherKeepalived(acc)
+		case sourcePacemaker:
+			states, err = h.gatherPacemaker(acc)
+		case sourceCorosync:
+			states, err = h.gatherCorosync(acc)
+		}
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		h.addTransitions(acc, source, states)
+	}
+	return nil
+}
+
+// addTransitions adds a transition event for each object of the source whose
+// state changed since the previous gather.
+func (h *HACluster) addTransitions(acc telegraf.Accumulator, source string, states map[objectKey]string) {
+	previous, ok := h.states[source]
+	h.states[source] = states
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	for key, state := range states {
+		from, ok := previous[key]
+		if !ok || from == state {
+			continue
+		}
+		tags := map[string]string{
+			"source": source,
+			"object": key.object,
+			"name":   key.name,
+		}
+		fields := map[string]interface{}{
+			"from": from,
+			"to":   state,
+		}
+		acc.AddFields("ha_cluster_transition", fields, tags, now)
+	}
+}
+
+func execRunner(timeout time.Duration, useSudo bool, command string, args ...string) ([]byte, error) {
+	cmd := exec.Command(command, args...)
+	if useSudo {
+		cmd = exec.Command("sudo", append([]string{"-n", command}, args...)...)
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, timeout); err != nil {
+		return nil, fmt.Errorf("error running %s: %v", command, err)
+	}
+	return out.Bytes(), nil
+}
+
+func init() {
+	inputs.Add("ha_cluster", func() telegraf.Input {
+		return &HACluster{
+			Sources:               []string{sourceKeepalived},
+			KeepalivedJSONFile:    "/tmp/keepalived.json",
+			KeepalivedJSONSignal:  36,
+			CrmMonBinary:          "crm_mon",
+			CorosyncCmapctlBinary: "corosync-cmapctl",
+			Timeout:               internal.Duration{Duration: 5 * time.Second},
+			run:                   execRunner,
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package ha_cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const keepalivedJSON = `[
+  {
+    "data": {
+      "iname": "VI_1",
+      "ifp_ifname": "eth0",
+      "vrid": 51,
+      "state": 2,
+      "base_priority": 100,
+      "effective_priority": 110,
+      "last_transition": 1589450000.5
+    },
+    "stats": {
+      "advert_rcvd": 10,
+      "advert_sent": 200,
+      "become_master": 2,
+      "release_master": 1,
+      "pri_zero_rcvd": 0,
+      "pri_zero_sent": 1
+    }
+  }
+]`
+
+const crmMonXML = `<?xml version="1.0"?>
+<crm_mon version="2.0.3">
+  <summary>
+    <current_dc present="true" version="2.0.3" name="node1" id="1" with_quorum="true"/>
+    <nodes_configured number="2"/>
+    <resources_configured number="4" disabled="0" blocked="0"/>
+  </summary>
+  <nodes>
+    <node name="node1" id="1" online="true" standby="false" standby_onfail="false" maintenance="false" pending="false" unclean="false" shutdown="false" expected_up="true" is_dc="true" resources_running="3" type="member"/>
+    <node name="node2" id="2" online="false" standby="false" standby_onfail="false" maintenance="false" pending="false" unclean="false" shutdown="false" expected_up="false" is_dc="false" resources_running="0" type="member"/>
+  </nodes>
+  <resources>
+    <resource id="vip" resource_agent="ocf::heartbeat:IPaddr2" role="Started" active="true" orphaned="false" blocked="false" managed="true" failed="false" failure_ignored="false" nodes_running_on="1">
+      <node name="node1" id="1" cached="false"/>
+    </resource>
+    <group id="web" number_resources="1">
+      <resource id="nginx" resource_agent="ocf::heartbeat:nginx" role="Started" active="true" orphaned="false" blocked="false" managed="true" failed="false" failure_ignored="false" nodes_running_on="1">
+        <node name="node1" id="1" cached="false"/>
+      </resource>
+    </group>
+    <clone id="ping-clone" multi_state="false" unique="false" managed="true" failed="false" failure_ignored="false">
+      <resource id="ping" resource_agent="ocf::pacemaker:ping" role="Started" active="true" orphaned="false" blocked="false" managed="true" failed="false" failure_ignored="false" nodes_running_on="1">
+        <node name="node1" id="1" cached="false"/>
+      </resource>
+      <resource id="ping" resource_agent="ocf::pacemaker:ping" role="Stopped" active="false" orphaned="false" blocked="false" managed="true" failed="false" failure_ignored="false" nodes_running_on="0"/>
+    </clone>
+  </resources>
+  <failures>
+    <failure op_key="nginx_monitor_10000" node="node2" exitstatus="not running" exitreason="" exitcode="7" call="12" status="complete" last-rc-change="Thu May 14 10:00:00 2020" queued="0" exec="0" interval="10000" task="monitor"/>
+  </failures>
+</crm_mon>`
+
+const cmapctlOutput = `runtime.members.1.config_version (u64) = 0
+runtime.members.1.ip (str) = r(0) ip(10.0.0.1) 
+runtime.members.1.join_count (u32) = 1
+runtime.members.1.status (str) = joined
+runtime.members.2.config_version (u64) = 0
+runtime.members.2.ip (str) = r(0) ip(10.0.0.2) 
+runtime.members.2.join_count (u32) = 3
+runtime.members.2.status (str) = left
+`
+
+func fakeRunner(outputs map[string]string) runner {
+	return func(timeout time.Duration, useSudo bool, command string, args ...string) ([]byte, error) {
+		out, ok := outputs[command]
+		if !ok {
+			return nil, fmt.Errorf("unexpected command %q", command)
+		}
+		return []byte(out), nil
+	}
+}
+
+func newHACluster(sources ...string) *HACluster {
+	return &HACluster{
+		Sources:               sources,
+		CrmMonBinary:          "crm_mon",
+		CorosyncCmapctlBinary: "corosync-cmapctl",
+		Timeout:               internal.Duration{Duration: time.Second},
+	}
+}
+
+func TestGatherKeepalived(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ha_cluster")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	h := newHACluster(sourceKeepalived)
+	h.KeepalivedJSONFile = filepath.Join(dir, "keepalived.json")
+	require.NoError(t, ioutil.WriteFile(h.KeepalivedJSONFile, []byte(keepalivedJSON), 0644))
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(h.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"ha_cluster_vrrp",
+			map[string]string{
+				"instance":  "VI_1",
+				"interface": "eth0",
+				"vrid":      "51",
+			},
+			map[string]interface{}{
+				"state":                  "MASTER",
+				"state_code":             2,
+				"priority":               int64(110),
+				"base_priority":          int64(100),
+				"last_transition":        1589450000.5,
+				"advert_received":        int64(10),
+				"advert_sent":            int64(200),
+				"become_master":          int64(2),
+				"release_master":         int64(1),
+				"priority_zero_received": int64(0),
+				"priority_zero_sent":     int64(1),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherKeepalivedMissingFile(t *testing.T) {
+	h := newHACluster(sourceKeepalived)
+	h.KeepalivedJSONFile = "/nonexistent/keepalived.json"
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(h.Gather))
+}
+
+func TestGatherPacemaker(t *testing.T) {
+	h := newHACluster(sourcePacemaker)
+	h.run = fakeRunner(map[string]string{"crm_mon": crmMonXML})
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(h.Gather))
+
+	resource := func(id, agent, parent, node, role string, active bool, running int64) telegraf.Metric {
+		tags := map[string]string{"resource": id, "agent": agent}
+		if parent != "" {
+			tags["parent"] = parent
+		}
+		if node != "" {
+			tags["node"] = node
+		}
+		return testutil.MustMetric(
+			"ha_cluster_resource",
+			tags,
+			map[string]interface{}{
+				"role":             role,
+				"active":           active,
+				"orphaned":         false,
+				"blocked":          false,
+				"managed":          true,
+				"failed":           false,
+				"failure_ignored":  false,
+				"nodes_running_on": running,
+			},
+			time.Unix(0, 0),
+		)
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"ha_cluster_pacemaker",
+			map[string]string{},
+			map[string]interface{}{
+				"quorum":               true,
+				"dc":                   "node1",
+				"nodes_configured":     int64(2),
+				"resources_configured": int64(4),
+				"resources_disabled":   int64(0),
+				"resources_blocked":    int64(0),
+				"failures":             1,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"ha_cluster_node",
+			map[string]string{"node": "node1", "type": "member"},
+			map[string]interface{}{
+				"state":             "online",
+				"online":            true,
+				"standby":           false,
+				"maintenance":       false,
+				"pending":           false,
+				"unclean":           false,
+				"shutdown":          false,
+				"is_dc":             true,
+				"resources_running": int64(3),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"ha_cluster_node",
+			map[string]string{"node": "node2", "type": "member"},
+			map[string]interface{}{
+				"state":             "offline",
+				"online":            false,
+				"standby":           false,
+				"maintenance":       false,
+				"pending":           false,
+				"unclean":           false,
+				"shutdown":          false,
+				"is_dc":             false,
+				"resources_running": int64(0),
+			},
+			time.Unix(0, 0),
+		),
+		resource("vip", "ocf::heartbeat:IPaddr2", "", "node1", "Started", true, 1),
+		resource("nginx", "ocf::heartbeat:nginx", "web", "node1", "Started", true, 1),
+		resource("ping", "ocf::pacemaker:ping", "ping-clone", "node1", "Started", true, 1),
+		resource("ping", "ocf::pacemaker:ping", "ping-clone", "", "Stopped", false, 0),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherCorosync(t *testing.T) {
+	h := newHACluster(sourceCorosync)
+	h.run = fakeRunner(map[string]string{"corosync-cmapctl": cmapctlOutput})
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(h.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"ha_cluster_member",
+			map[string]string{"node_id": "1", "address": "10.0.0.1"},
+			map[string]interface{}{
+				"status":     "joined",
+				"joined":     true,
+				"join_count": int64(1),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"ha_cluster_member",
+			map[string]string{"node_id": "2", "address": "10.0.0.2"},
+			map[string]interface{}{
+				"status":     "left",
+				"joined":     false,
+				"join_count": int64(3),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestTransitions(t *testing.T) {
+	output := map[string]string{"crm_mon": crmMonXML}
+	h := newHACluster(sourcePacemaker)
+	h.run = fakeRunner(output)
+	require.NoError(t, h.Init())
+
+	// No transitions are reported on the first gather.
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(h.Gather))
+	require.False(t, acc.HasMeasurement("ha_cluster_transition"))
+
+	// The resource moved to node2, back online.
+	moved := strings.Replace(crmMonXML,
+		`name="node2" id="2" online="false"`, `name="node2" id="2" online="true"`, 1)
+	moved = strings.Replace(moved, `<node name="node1" id="1" cached="false"/>
+    </resource>
+    <group`, `<node name="node2" id="2" cached="false"/>
+    </resource>
+    <group`, 1)
+	output["crm_mon"] = moved
+
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(h.Gather))
+
+	var transitions []telegraf.Metric
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "ha_cluster_transition" {
+			transitions = append(transitions, m)
+		}
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"ha_cluster_transition",
+			map[string]string{"source": "pacemaker", "object": "node", "name": "node2"},
+			map[string]interface{}{"from": "offline", "to": "online"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"ha_cluster_transition",
+			map[string]string{"source": "pacemaker", "object": "resource", "name": "vip"},
+			map[string]interface{}{"from": "Started@node1", "to": "Started@node2"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, transitions, testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+func TestInitUnknownSource(t *testing.T) {
+	h := newHACluster("heartbeat")
+	require.Error(t, h.Init())
+}
@@ -0,0 +1,152 @@
+package ha_cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// keepalivedStates are the names of the VRRP states of keepalived.
+var keepalivedStates = map[int]string{
+	0: "INIT",
+	1: "BACKUP",
+	2: "MASTER",
+	3: "FAULT",
+}
+
+// keepalivedInstance is a VRRP instance of the JSON dump of keepalived.
+type keepalivedInstance struct {
+	Data struct {
+		Name              string  `json:"iname"`
+		Interface         string  `json:"ifp_ifname"`
+		VRID              int     `json:"vrid"`
+		State             int     `json:"state"`
+		BasePriority      int64   `json:"base_priority"`
+		EffectivePriority int64   `json:"effective_priority"`
+		LastTransition    float64 `json:"last_transition"`
+	} `json:"data"`
+	Stats struct {
+		AdvertReceived       int64 `json:"advert_rcvd"`
+		AdvertSent           int64 `json:"advert_sent"`
+		BecomeMaster         int64 `json:"become_master"`
+		ReleaseMaster        int64 `json:"release_master"`
+		PriorityZeroReceived int64 `json:"pri_zero_rcvd"`
+		PriorityZeroSent     int64 `json:"pri_zero_sent"`
+	} `json:"stats"`
+}
+
+func (h *HACluster) gatherKeepalived(acc telegraf.Accumulator) (map[objectKey]string, error) {
+	var instances []keepalivedInstance
+	var err error
+	if h.KeepalivedPidFile != "" {
+		instances, err = h.dumpKeepalived()
+	} else {
+		instances, err = readKeepalived(h.KeepalivedJSONFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[objectKey]string)
+	for _, i := range instances {
+		state, ok := keepalivedStates[i.Data.State]
+		if !ok {
+			state = "UNKNOWN"
+		}
+		states[objectKey{"vrrp_instance", i.Data.Name}] = state
+
+		tags := map[string]string{
+			"instance":  i.Data.Name,
+			"interface": i.Data.Interface,
+			"vrid":      strconv.Itoa(i.Data.VRID),
+		}
+		fields := map[string]interface{}{
+			"state":                  state,
+			"state_code":             i.Data.State,
+			"priority":               i.Data.EffectivePriority,
+			"base_priority":          i.Data.BasePriority,
+			"last_transition":        i.Data.LastTransition,
+			"advert_received":        i.Stats.AdvertReceived,
+			"advert_sent":            i.Stats.AdvertSent,
+			"become_master":          i.Stats.BecomeMaster,
+			"release_master":         i.Stats.ReleaseMaster,
+			"priority_zero_received": i.Stats.PriorityZeroReceived,
+			"priority_zero_sent":     i.Stats.PriorityZeroSent,
+		}
+		acc.AddFields("ha_cluster_vrrp", fields, tags)
+	}
+	return states, nil
+}
+
+// dumpKeepalived sends keepalived the signal to write its JSON dump, and
+// reads the dump once rewritten.
+func (h *HACluster) dumpKeepalived() ([]keepalivedInstance, error) {
+	pid, err := readPid(h.KeepalivedPidFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var modTime time.Time
+	if info, err := os.Stat(h.KeepalivedJSONFile); err == nil {
+		modTime = info.ModTime()
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	if err := process.Signal(syscall.Signal(h.KeepalivedJSONSignal)); err != nil {
+		return nil, fmt.Errorf("error signaling keepalived: %v", err)
+	}
+
+	// The dump may be read while it is written, it is read again until it
+	// can be parsed.
+	deadline := time.Now().Add(h.Timeout.Duration)
+	for {
+		info, err := os.Stat(h.KeepalivedJSONFile)
+		if err == nil && !info.ModTime().Equal(modTime) {
+			instances, err := readKeepalived(h.KeepalivedJSONFile)
+			if err == nil {
+				return instances, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("keepalived did not write %q within %s",
+				h.KeepalivedJSONFile, h.Timeout.Duration)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func readKeepalived(path string) ([]keepalivedInstance, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []keepalivedInstance
+	if err := json.Unmarshal(data, &instances); err != nil {
+		return nil, fmt.Errorf("error parsing %q: %v", path, err)
+	}
+	return instances, nil
+}
+
+func readPid(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %q: %v", path, err)
+	}
+	return pid, nil
+}
@@ -0,0 +1,269 @@
+package ha_cluster
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// crmMon is the XML output of crm_mon.
+type crmMon struct {
+	Summary struct {
+		CurrentDC struct {
+			Present    bool   `xml:"present,attr"`
+			Name       string `xml:"name,attr"`
+			WithQuorum bool   `xml:"with_quorum,attr"`
+		} `xml:"current_dc"`
+		NodesConfigured struct {
+			Number int64 `xml:"number,attr"`
+		} `xml:"nodes_configured"`
+		ResourcesConfigured struct {
+			Number   int64 `xml:"number,attr"`
+			Disabled int64 `xml:"disabled,attr"`
+			Blocked  int64 `xml:"blocked,attr"`
+		} `xml:"resources_configured"`
+	} `xml:"summary"`
+	Nodes     []crmNode `xml:"nodes>node"`
+	Resources struct {
+		crmResources
+		Groups  []crmGroup  `xml:"group"`
+		Clones  []crmClone  `xml:"clone"`
+		Bundles []crmBundle `xml:"bundle"`
+	} `xml:"resources"`
+	Failures []struct{} `xml:"failures>failure"`
+}
+
+type crmNode struct {
+	Name             string `xml:"name,attr"`
+	Type             string `xml:"type,attr"`
+	Online           bool   `xml:"online,attr"`
+	Standby          bool   `xml:"standby,attr"`
+	Maintenance      bool   `xml:"maintenance,attr"`
+	Pending          bool   `xml:"pending,attr"`
+	Unclean          bool   `xml:"unclean,attr"`
+	Shutdown         bool   `xml:"shutdown,attr"`
+	IsDC             bool   `xml:"is_dc,attr"`
+	ResourcesRunning int64  `xml:"resources_running,attr"`
+}
+
+// state returns the state of the node as reported by crm_mon.
+func (n *crmNode) state() string {
+	switch {
+	case n.Unclean:
+		return "unclean"
+	case !n.Online:
+		return "offline"
+	case n.Standby:
+		return "standby"
+	case n.Maintenance:
+		return "maintenance"
+	}
+	return "online"
+}
+
+type crmResources struct {
+	Resources []crmResource `xml:"resource"`
+}
+
+type crmResource struct {
+	ID             string `xml:"id,attr"`
+	Agent          string `xml:"resource_agent,attr"`
+	Role           string `xml:"role,attr"`
+	Active         bool   `xml:"active,attr"`
+	Orphaned       bool   `xml:"orphaned,attr"`
+	Blocked        bool   `xml:"blocked,attr"`
+	Managed        bool   `xml:"managed,attr"`
+	Failed         bool   `xml:"failed,attr"`
+	FailureIgnored bool   `xml:"failure_ignored,attr"`
+	NodesRunningOn int64  `xml:"nodes_running_on,attr"`
+	Nodes          []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"node"`
+}
+
+type crmGroup struct {
+	ID string `xml:"id,attr"`
+	crmResources
+}
+
+type crmClone struct {
+	ID string `xml:"id,attr"`
+	crmResources
+	Groups []crmGroup `xml:"group"`
+}
+
+type crmBundle struct {
+	ID       string         `xml:"id,attr"`
+	Replicas []crmResources `xml:"replica"`
+}
+
+func (h *HACluster) gatherPacemaker(acc telegraf.Accumulator) (map[objectKey]string, error) {
+	out, err := h.run(h.Timeout.Duration, h.UseSudo, h.CrmMonBinary, "--as-xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var mon crmMon
+	if err := xml.Unmarshal(out, &mon); err != nil {
+		return nil, fmt.Errorf("error parsing the output of crm_mon: %v", err)
+	}
+
+	summary := mon.Summary
+	fields := map[string]interface{}{
+		"quorum":               summary.CurrentDC.WithQuorum,
+		"nodes_configured":     summary.NodesConfigured.Number,
+		"resources_configured": summary.ResourcesConfigured.Number,
+		"resources_disabled":   summary.ResourcesConfigured.Disabled,
+		"resources_blocked":    summary.ResourcesConfigured.Blocked,
+		"failures":             len(mon.Failures),
+	}
+	if summary.CurrentDC.Present {
+		fields["dc"] = summary.CurrentDC.Name
+	}
+	acc.AddFields("ha_cluster_pacemaker", fields, nil)
+
+	states := make(map[objectKey]string)
+	for _, n := range mon.Nodes {
+		states[objectKey{"node", n.Name}] = n.state()
+
+		tags := map[string]string{
+			"node": n.Name,
+			"type": n.Type,
+		}
+		fields := map[string]interface{}{
+			"state":             n.state(),
+			"online":            n.Online,
+			"standby":           n.Standby,
+			"maintenance":       n.Maintenance,
+			"pending":           n.Pending,
+			"unclean":           n.Unclean,
+			"shutdown":          n.Shutdown,
+			"is_dc":             n.IsDC,
+			"resources_running": n.ResourcesRunning,
+		}
+		acc.AddFields("ha_cluster_node", fields, tags)
+	}
+
+	// The instances of a resource, such as the instances of a clone, make the
+	// state of the resource: the roles and nodes of its instances.
+	instances := make(map[string][]string)
+	addResources := func(parent string, resources []crmResource) {
+		for _, r := range resources {
+			node := ""
+			if len(r.Nodes) > 0 {
+				node = r.Nodes[0].Name
+			}
+			instance := r.Role
+			if node != "" {
+				instance += "@" + node
+			}
+			instances[r.ID] = append(instances[r.ID], instance)
+
+			tags := map[string]string{
+				"resource": r.ID,
+				"agent":    r.Agent,
+			}
+			if parent != "" {
+				tags["parent"] = parent
+			}
+			if node != "" {
+				tags["node"] = node
+			}
+			fields := map[string]interface{}{
+				"role":             r.Role,
+				"active":           r.Active,
+				"orphaned":         r.Orphaned,
+				"blocked":          r.Blocked,
+				"managed":          r.Managed,
+				"failed":           r.Failed,
+				"failure_ignored":  r.FailureIgnored,
+				"nodes_running_on": r.NodesRunningOn,
+			}
+			acc.AddFields("ha_cluster_resource", fields, tags)
+		}
+	}
+
+	resources := mon.Resources
+	addResources("", resources.Resources)
+	for _, g := range resources.Groups {
+		addResources(g.ID, g.Resources)
+	}
+	for _, c := range resources.Clones {
+		addResources(c.ID, c.Resources)
+		for _, g := range c.Groups {
+			addResources(g.ID, g.Resources)
+		}
+	}
+	for _, b := range resources.Bundles {
+		for _, r := range b.Replicas {
+			addResources(b.ID, r.Resources)
+		}
+	}
+
+	for id, i := range instances {
+		sort.Strings(i)
+		states[objectKey{"resource", id}] = strings.Join(i, ",")
+	}
+	return states, nil
+}
+
+// cmapMember matches the keys of the members in the output of
+// corosync-cmapctl, such as "runtime.members.1.status (str) = joined".
+var cmapMember = regexp.MustCompile(`^runtime\.members\.(\d+)\.(\w+) \(\w+\) = (.*)$`)
+
+// cmapAddress matches the address of the first ring of a member, in
+// "r(0) ip(10.0.0.1) ".
+var cmapAddress = regexp.MustCompile(`ip\(([^)]*)\)`)
+
+func (h *HACluster) gatherCorosync(acc telegraf.Accumulator) (map[objectKey]string, error) {
+	out, err := h.run(h.Timeout.Duration, h.UseSudo, h.CorosyncCmapctlBinary, "runtime.members")
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]map[string]string)
+	var ids []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := cmapMember.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		if _, ok := members[m[1]]; !ok {
+			members[m[1]] = make(map[string]string)
+			ids = append(ids, m[1])
+		}
+		members[m[1]][m[2]] = strings.TrimSpace(m[3])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	states := make(map[objectKey]string)
+	for _, id := range ids {
+		member := members[id]
+		status := member["status"]
+		states[objectKey{"member", id}] = status
+
+		tags := map[string]string{"node_id": id}
+		if m := cmapAddress.FindStringSubmatch(member["ip"]); m != nil {
+			tags["address"] = m[1]
+		}
+		fields := map[string]interface{}{
+			"status": status,
+			"joined": status == "joined",
+		}
+		var joinCount int64
+		if _, err := fmt.Sscan(member["join_count"], &joinCount); err == nil {
+			fields["join_count"] = joinCount
+		}
+		acc.AddFields("ha_cluster_member", fields, tags)
+	}
+	return states, nil
+}
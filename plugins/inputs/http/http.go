@@ -11,6 +11,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/k8sdiscovery"
 	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
@@ -33,7 +34,12 @@ type HTTP struct {
 
 	Timeout internal.Duration `toml:"timeout"`
 
-	client *http.Client
+	URLsFromKubernetes *k8sdiscovery.Config `toml:"urls_from_kubernetes"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	client    *http.Client
+	discovery *k8sdiscovery.Discovery
 
 	// The parser will automatically be set by Telegraf core code because
 	// this plugin implements the ParserInput interface (i.e. the SetParser method)
@@ -81,6 +87,27 @@ var sampleConfig = `
   ## more about them here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
   # data_format = "influx"
+
+  ## Read the URLs of the Kubernetes pods with the "prometheus.io/scrape"
+  ## annotation set to "true", built from the pod IP and the
+  ## "prometheus.io/scheme", "prometheus.io/port" and "prometheus.io/path"
+  ## annotations.  The annotations and labels of the pods are added as tags.
+  # [inputs.http.urls_from_kubernetes]
+  #   ## Prefix of the annotations.
+  #   annotation_prefix = "prometheus.io"
+  #   ## Scheme, port and path of the URLs of the pods without the annotations.
+  #   scheme = "http"
+  #   port = ""
+  #   path = ""
+  #   ## Restricts the discovery to a namespace, all namespaces by default.
+  #   namespace = ""
+  #   ## Kubernetes config file, used when not running in a pod.
+  #   kube_config = "/path/to/kubernetes.config"
+  #   ## Annotations and labels not added as tags.
+  #   exclude_annotations = []
+  #   exclude_labels = []
+  #   ## Interval of the full resync of the pods with the API server.
+  #   resync_interval = "5m"
 `
 
 // SampleConfig returns the default configuration of the Input
@@ -114,6 +141,22 @@ func (h *HTTP) Init() error {
 	return nil
 }
 
+// Start starts the discovery of the Kubernetes pods if enabled in the
+// configuration.
+func (h *HTTP) Start(_ telegraf.Accumulator) error {
+	if h.URLsFromKubernetes == nil {
+		return nil
+	}
+	h.discovery = k8sdiscovery.New(*h.URLsFromKubernetes, h.Log)
+	return h.discovery.Start()
+}
+
+func (h *HTTP) Stop() {
+	if h.discovery != nil {
+		h.discovery.Stop()
+	}
+}
+
 // Gather takes in an accumulator and adds the metrics that the Input
 // gathers. This is called every "interval"
 func (h *HTTP) Gather(acc telegraf.Accumulator) error {
@@ -128,6 +171,19 @@ func (h *HTTP) Gather(acc telegraf.Accumulator) error {
 		}(u)
 	}
 
+	if h.discovery != nil {
+		for _, t := range h.discovery.Targets() {
+			wg.Add(1)
+			go func(t *k8sdiscovery.Target) {
+				defer wg.Done()
+				url := t.URL.String()
+				if err := h.gatherURL(t.Accumulator(acc), url); err != nil {
+					acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
+				}
+			}(t)
+		}
+	}
+
 	wg.Wait()
 
 	return nil
@@ -15,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/discovery"
 	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
@@ -33,10 +34,15 @@ type HTTPResponse struct {
 	Interface           string
 	tls.ClientConfig
 
+	// Discovery of the URLs from files, DNS SRV records, Consul and
+	// Kubernetes services
+	Discovery *discovery.Config `toml:"discovery"`
+
 	Log telegraf.Logger
 
 	compiledStringMatch *regexp.Regexp
 	client              *http.Client
+	targets             *discovery.Discovery
 }
 
 // Description returns the plugin Description
@@ -87,6 +93,39 @@ var sampleConfig = `
 
   ## Interface to use when dialing an address
   # interface = "eth0"
+
+  ## Query the URLs discovered from files, DNS SRV records, Consul services
+  ## or Kubernetes services.  The URLs are refreshed periodically, without
+  ## reloading the configuration.
+  # [inputs.http_response.discovery]
+  #   ## Files with one target per line, as a URL or "host[:port]" followed
+  #   ## by optional "key=value" tags.  Lines starting with "#" are skipped.
+  #   files = ["/etc/telegraf/http_targets"]
+  #   ## Names of DNS SRV records.
+  #   dns_srv = ["_http._tcp.example.org"]
+  #   ## Scheme, port and path of the targets without them.
+  #   scheme = "http"
+  #   port = ""
+  #   path = "/"
+  #   ## Interval of the refresh of the files, records and Consul services.
+  #   refresh_interval = "1m"
+  #
+  #   ## Instances of Consul services, the healthy ones unless failing ones
+  #   ## are included.
+  #   [inputs.http_response.discovery.consul]
+  #     address = "localhost:8500"
+  #     services = ["web"]
+  #     # tag = ""
+  #     # datacenter = ""
+  #     # token = ""
+  #     # include_failing = false
+  #
+  #   ## Kubernetes services with the "prometheus.io/scrape" annotation set
+  #   ## to "true", at their cluster IP.
+  #   [inputs.http_response.discovery.kubernetes]
+  #     role = "service"
+  #     # namespace = ""
+  #     # kube_config = "/path/to/kubernetes.config"
 `
 
 // SampleConfig returns the plugin SampleConfig
@@ -94,6 +133,21 @@ func (h *HTTPResponse) SampleConfig() string {
 	return sampleConfig
 }
 
+// Start starts the discovery of the URLs if enabled in the configuration.
+func (h *HTTPResponse) Start(_ telegraf.Accumulator) error {
+	if h.Discovery == nil {
+		return nil
+	}
+	h.targets = discovery.New(*h.Discovery, h.Log)
+	return h.targets.Start()
+}
+
+func (h *HTTPResponse) Stop() {
+	if h.targets != nil {
+		h.targets.Stop()
+	}
+}
+
 // ErrRedirectAttempted indicates that a redirect occurred
 var ErrRedirectAttempted = errors.New("redirect")
 
@@ -319,7 +373,7 @@ func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
 		h.Method = "GET"
 	}
 
-	if len(h.URLs) == 0 {
+	if len(h.URLs) == 0 && h.Discovery == nil {
 		if h.Address == "" {
 			h.URLs = []string{"http://localhost"}
 		} else {
@@ -337,33 +391,40 @@ func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
 	}
 
 	for _, u := range h.URLs {
-		addr, err := url.Parse(u)
-		if err != nil {
-			acc.AddError(err)
-			continue
-		}
+		h.gatherURL(u, acc)
+	}
 
-		if addr.Scheme != "http" && addr.Scheme != "https" {
-			acc.AddError(errors.New("Only http and https are supported"))
-			continue
+	if h.targets != nil {
+		for _, t := range h.targets.Targets() {
+			h.gatherURL(t.URL.String(), t.Accumulator(acc))
 		}
+	}
 
-		// Prepare data
-		var fields map[string]interface{}
-		var tags map[string]string
+	return nil
+}
 
-		// Gather data
-		fields, tags, err = h.httpGather(u)
-		if err != nil {
-			acc.AddError(err)
-			continue
-		}
+// gatherURL adds the metrics of the response of the URL.
+func (h *HTTPResponse) gatherURL(u string, acc telegraf.Accumulator) {
+	addr, err := url.Parse(u)
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
 
-		// Add metrics
-		acc.AddFields("http_response", fields, tags)
+	if addr.Scheme != "http" && addr.Scheme != "https" {
+		acc.AddError(errors.New("Only http and https are supported"))
+		return
 	}
 
-	return nil
+	// Gather data
+	fields, tags, err := h.httpGather(u)
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+
+	// Add metrics
+	acc.AddFields("http_response", fields, tags)
 }
 
 func init() {
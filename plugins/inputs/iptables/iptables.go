@@ -4,6 +4,7 @@ package iptables
 
 import (
 	"errors"
+	"fmt"
 	"os/exec"
 	"regexp"
 	"strconv"
@@ -15,12 +16,15 @@ import (
 
 // Iptables is a telegraf plugin to gather packets and bytes throughput from Linux's iptables packet filter.
 type Iptables struct {
-	UseSudo bool
-	UseLock bool
-	Binary  string
-	Table   string
-	Chains  []string
-	lister  chainLister
+	UseSudo    bool
+	UseLock    bool
+	Binary     string
+	Table      string
+	Chains     []string
+	Method     string
+	IPv6       bool
+	lister     chainLister
+	ruleLister ruleLister
 }
 
 // Description returns a short description of the plugin.
@@ -41,6 +45,14 @@ func (ipt *Iptables) SampleConfig() string {
   use_lock = false
   ## Define an alternate executable, such as "ip6tables". Default is "iptables".
   # binary = "ip6tables"
+  ## Method to read the counters with, either "exec" to run iptables or
+  ## "netlink" to read the rules of the nf_tables kernel interface used by
+  ## iptables-nft, without running a command.  The netlink method requires
+  ## the CAP_NET_ADMIN capability instead of sudo.
+  # method = "exec"
+  ## Collect the IPv6 rules as well, with ip6tables for the exec method,
+  ## the metrics get a family tag.
+  # ipv6 = false
   ## defines the table to monitor:
   table = "filter"
   ## defines the chains to monitor.
@@ -55,26 +67,51 @@ func (ipt *Iptables) Gather(acc telegraf.Accumulator) error {
 	if ipt.Table == "" || len(ipt.Chains) == 0 {
 		return nil
 	}
+	switch ipt.Method {
+	case "", "exec", "netlink":
+	default:
+		return fmt.Errorf("unknown method %q", ipt.Method)
+	}
+
+	families := []string{familyIPv4}
+	if ipt.IPv6 {
+		families = append(families, familyIPv6)
+	}
+
 	// best effort : we continue through the chains even if an error is encountered,
 	// but we keep track of the last error.
-	for _, chain := range ipt.Chains {
-		data, e := ipt.lister(ipt.Table, chain)
-		if e != nil {
-			acc.AddError(e)
-			continue
-		}
-		e = ipt.parseAndGather(data, acc)
-		if e != nil {
-			acc.AddError(e)
-			continue
+	for _, family := range families {
+		for _, chain := range ipt.Chains {
+			if ipt.Method == "netlink" {
+				rules, e := ipt.ruleLister(family, ipt.Table, chain)
+				if e != nil {
+					acc.AddError(e)
+					continue
+				}
+				ipt.gatherRules(rules, family, chain, acc)
+				continue
+			}
+
+			data, e := ipt.lister(family, ipt.Table, chain)
+			if e != nil {
+				acc.AddError(e)
+				continue
+			}
+			e = ipt.parseAndGather(data, family, acc)
+			if e != nil {
+				acc.AddError(e)
+				continue
+			}
 		}
 	}
 	return nil
 }
 
-func (ipt *Iptables) chainList(table, chain string) (string, error) {
+func (ipt *Iptables) chainList(family, table, chain string) (string, error) {
 	var binary string
-	if ipt.Binary != "" {
+	if family == familyIPv6 {
+		binary = "ip6tables"
+	} else if ipt.Binary != "" {
 		binary = ipt.Binary
 	} else {
 		binary = "iptables"
@@ -100,12 +137,17 @@ func (ipt *Iptables) chainList(table, chain string) (string, error) {
 
 const measurement = "iptables"
 
+const (
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+)
+
 var errParse = errors.New("Cannot parse iptables list information")
 var chainNameRe = regexp.MustCompile(`^Chain\s+(\S+)`)
 var fieldsHeaderRe = regexp.MustCompile(`^\s*pkts\s+bytes\s+`)
 var valuesRe = regexp.MustCompile(`^\s*(\d+)\s+(\d+)\s+.*?/\*\s*(.+?)\s*\*/\s*`)
 
-func (ipt *Iptables) parseAndGather(data string, acc telegraf.Accumulator) error {
+func (ipt *Iptables) parseAndGather(data, family string, acc telegraf.Accumulator) error {
 	lines := strings.Split(data, "\n")
 	if len(lines) < 3 {
 		return nil
@@ -127,7 +169,7 @@ func (ipt *Iptables) parseAndGather(data string, acc telegraf.Accumulator) error
 		bytes := matches[2]
 		comment := matches[3]
 
-		tags := map[string]string{"table": ipt.Table, "chain": mchain[1], "ruleid": comment}
+		tags := ipt.tags(family, mchain[1], comment)
 		fields := make(map[string]interface{})
 
 		var err error
@@ -144,12 +186,45 @@ func (ipt *Iptables) parseAndGather(data string, acc telegraf.Accumulator) error
 	return nil
 }
 
-type chainLister func(table, chain string) (string, error)
+// gatherRules adds the counters of the rules with a comment.
+func (ipt *Iptables) gatherRules(rules []rule, family, chain string, acc telegraf.Accumulator) {
+	for _, r := range rules {
+		if r.comment == "" || !r.hasCounter {
+			continue
+		}
+		fields := map[string]interface{}{
+			"pkts":  r.pkts,
+			"bytes": r.bytes,
+		}
+		acc.AddFields(measurement, fields, ipt.tags(family, chain, r.comment))
+	}
+}
+
+func (ipt *Iptables) tags(family, chain, ruleid string) map[string]string {
+	tags := map[string]string{"table": ipt.Table, "chain": chain, "ruleid": ruleid}
+	if ipt.IPv6 {
+		tags["family"] = family
+	}
+	return tags
+}
+
+// rule is the counter of a rule read with netlink.
+type rule struct {
+	comment    string
+	pkts       uint64
+	bytes      uint64
+	hasCounter bool
+}
+
+type chainLister func(family, table, chain string) (string, error)
+
+type ruleLister func(family, table, chain string) ([]rule, error)
 
 func init() {
 	inputs.Add("iptables", func() telegraf.Input {
 		ipt := new(Iptables)
 		ipt.lister = ipt.chainList
+		ipt.ruleLister = listRules
 		return ipt
 	})
 }
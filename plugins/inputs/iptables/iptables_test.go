@@ -177,7 +177,7 @@ func TestIptables_Gather(t *testing.T) {
 			ipt := &Iptables{
 				Table:  tt.table,
 				Chains: tt.chains,
-				lister: func(table, chain string) (string, error) {
+				lister: func(family, table, chain string) (string, error) {
 					if len(tt.values) > 0 {
 						v := tt.values[0]
 						tt.values = tt.values[1:]
@@ -241,7 +241,7 @@ func TestIptables_Gather_listerError(t *testing.T) {
 	ipt := &Iptables{
 		Table:  "nat",
 		Chains: []string{"foo", "bar"},
-		lister: func(table, chain string) (string, error) {
+		lister: func(family, table, chain string) (string, error) {
 			return "", errFoo
 		},
 	}
@@ -251,3 +251,117 @@ func TestIptables_Gather_listerError(t *testing.T) {
 		t.Errorf("Expected error %#v got\n%#v\n", errFoo, err)
 	}
 }
+
+func TestIptables_Gather_netlink(t *testing.T) {
+	ipt := &Iptables{
+		Table:  "filter",
+		Chains: []string{"INPUT"},
+		Method: "netlink",
+		IPv6:   true,
+		ruleLister: func(family, table, chain string) ([]rule, error) {
+			if family == familyIPv6 {
+				return []rule{
+					{comment: "ssh", pkts: 7, bytes: 512, hasCounter: true},
+				}, nil
+			}
+			return []rule{
+				{comment: "ssh", pkts: 100, bytes: 1024, hasCounter: true},
+				{pkts: 5, bytes: 300, hasCounter: true},
+				{comment: "nocounter"},
+			}, nil
+		},
+	}
+	acc := new(testutil.Accumulator)
+	err := acc.GatherError(ipt.Gather)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acc.AssertContainsTaggedFields(t, measurement,
+		map[string]interface{}{"pkts": uint64(100), "bytes": uint64(1024)},
+		map[string]string{"table": "filter", "chain": "INPUT", "ruleid": "ssh", "family": "ipv4"})
+	acc.AssertContainsTaggedFields(t, measurement,
+		map[string]interface{}{"pkts": uint64(7), "bytes": uint64(512)},
+		map[string]string{"table": "filter", "chain": "INPUT", "ruleid": "ssh", "family": "ipv6"})
+	if len(acc.Metrics) != 2 {
+		t.Errorf("expected 2 metrics got %d", len(acc.Metrics))
+	}
+}
+
+func TestIptables_Gather_ipv6Exec(t *testing.T) {
+	var families []string
+	ipt := &Iptables{
+		Table:  "filter",
+		Chains: []string{"INPUT"},
+		IPv6:   true,
+		lister: func(family, table, chain string) (string, error) {
+			families = append(families, family)
+			return `Chain INPUT (policy ACCEPT 58 packets, 5096 bytes)
+					pkts bytes target     prot opt in     out     source               destination
+					100   1024   ACCEPT     tcp  --  *      *       ::/0       ::/0            tcp dpt:22 /* ssh */
+					`, nil
+		},
+	}
+	acc := new(testutil.Accumulator)
+	err := acc.GatherError(ipt.Gather)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(families, []string{familyIPv4, familyIPv6}) {
+		t.Errorf("expected both families listed got %v", families)
+	}
+	acc.AssertContainsTaggedFields(t, measurement,
+		map[string]interface{}{"pkts": uint64(100), "bytes": uint64(1024)},
+		map[string]string{"table": "filter", "chain": "INPUT", "ruleid": "ssh", "family": "ipv6"})
+}
+
+func TestIptables_Gather_unknownMethod(t *testing.T) {
+	ipt := &Iptables{
+		Table:  "filter",
+		Chains: []string{"INPUT"},
+		Method: "ioctl",
+	}
+	acc := new(testutil.Accumulator)
+	err := acc.GatherError(ipt.Gather)
+	if err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	counter := make([]byte, 0)
+	counter = append(counter, encodeAttr(nftaCounterBytes, []byte{0, 0, 0, 0, 0, 0, 0x04, 0x00})...)
+	counter = append(counter, encodeAttr(nftaCounterPackets, []byte{0, 0, 0, 0, 0, 0, 0, 100})...)
+	counterExpr := append(encodeAttr(nftaExprName, []byte("counter\x00")), encodeAttr(nftaExprData|0x8000, counter)...)
+	acceptExpr := encodeAttr(nftaExprName, []byte("immediate\x00"))
+	exprs := append(encodeAttr(nftaListElem|0x8000, acceptExpr), encodeAttr(nftaListElem|0x8000, counterExpr)...)
+
+	// The comment is a TLV with type 0 and the length including the NUL
+	udata := append([]byte{udataRuleComment, 4}, []byte("ssh\x00")...)
+
+	data := append(encodeAttr(nftaRuleTable, []byte("filter\x00")), encodeAttr(nftaRuleChain, []byte("INPUT\x00"))...)
+	data = append(data, encodeAttr(nftaRuleExpressions|0x8000, exprs)...)
+	data = append(data, encodeAttr(nftaRuleUserdata, udata)...)
+
+	r := parseRule(parseAttrs(data))
+	expected := rule{comment: "ssh", pkts: 100, bytes: 1024, hasCounter: true}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected %#v got %#v", expected, r)
+	}
+}
+
+func TestParseRule_commentMatch(t *testing.T) {
+	info := make([]byte, 256)
+	copy(info, "httpd")
+	match := append(encodeAttr(nftaMatchName, []byte("comment\x00")), encodeAttr(nftaMatchInfo, info)...)
+	matchExpr := append(encodeAttr(nftaExprName, []byte("match\x00")), encodeAttr(nftaExprData|0x8000, match)...)
+	exprs := encodeAttr(nftaListElem|0x8000, matchExpr)
+
+	r := parseRule(parseAttrs(encodeAttr(nftaRuleExpressions|0x8000, exprs)))
+	if r.comment != "httpd" {
+		t.Errorf("expected comment httpd got %q", r.comment)
+	}
+	if r.hasCounter {
+		t.Error("expected no counter")
+	}
+}
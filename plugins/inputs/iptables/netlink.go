@@ -0,0 +1,241 @@
+// +build linux
+
+package iptables
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Netlink constants of the linux/netfilter/nfnetlink.h and
+// linux/netfilter/nf_tables.h headers
+const (
+	nfnlSubsysNftables = 10
+	nftMsgGetRule      = 7
+
+	nfprotoIPv4 = 2
+	nfprotoIPv6 = 10
+
+	nftaRuleTable       = 1
+	nftaRuleChain       = 2
+	nftaRuleExpressions = 4
+	nftaRuleUserdata    = 7
+
+	nftaListElem = 1
+	nftaExprName = 1
+	nftaExprData = 2
+
+	nftaCounterBytes   = 1
+	nftaCounterPackets = 2
+
+	nftaMatchName = 1
+	nftaMatchInfo = 3
+
+	// Type of the comment in the user data of the rules added by
+	// iptables-nft and nft
+	udataRuleComment = 0
+
+	nlaTypeMask = 0x3FFF
+
+	sizeofNfgenmsg = 4
+)
+
+var nativeEndian binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	i := uint16(1)
+	if (*[2]byte)(unsafe.Pointer(&i))[0] == 0 {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// listRules returns the rules of the chain of the nf_tables table, as
+// managed by iptables-nft.
+func listRules(family, table, chain string) ([]rule, error) {
+	nfproto := uint8(nfprotoIPv4)
+	if family == familyIPv6 {
+		nfproto = nfprotoIPv6
+	}
+
+	// The table and chain attributes restrict the dump on recent kernels
+	req := make([]byte, sizeofNfgenmsg)
+	req[0] = nfproto
+	req = append(req, encodeAttr(nftaRuleTable, append([]byte(table), 0))...)
+	req = append(req, encodeAttr(nftaRuleChain, append([]byte(chain), 0))...)
+
+	msgs, err := netfilterDump(nfnlSubsysNftables<<8|nftMsgGetRule, req)
+	if err != nil {
+		return nil, fmt.Errorf("listing rules of %s %s failed: %v", table, chain, err)
+	}
+
+	var rules []rule
+	for _, data := range msgs {
+		if len(data) < sizeofNfgenmsg {
+			continue
+		}
+		attrs := parseAttrs(data[sizeofNfgenmsg:])
+		if cString(attrs[nftaRuleTable]) != table || cString(attrs[nftaRuleChain]) != chain {
+			continue
+		}
+		rules = append(rules, parseRule(attrs))
+	}
+	return rules, nil
+}
+
+// parseRule returns the counter and the comment of the rule attributes.
+func parseRule(attrs map[uint16][]byte) rule {
+	var r rule
+	if udata, ok := attrs[nftaRuleUserdata]; ok {
+		r.comment = udataComment(udata)
+	}
+
+	for _, expr := range parseList(attrs[nftaRuleExpressions]) {
+		exprAttrs := parseAttrs(expr)
+		data := parseAttrs(exprAttrs[nftaExprData])
+		switch cString(exprAttrs[nftaExprName]) {
+		case "counter":
+			if b := data[nftaCounterBytes]; len(b) == 8 {
+				r.bytes = binary.BigEndian.Uint64(b)
+			}
+			if p := data[nftaCounterPackets]; len(p) == 8 {
+				r.pkts = binary.BigEndian.Uint64(p)
+			}
+			r.hasCounter = true
+		case "match":
+			// Comments of older iptables-nft versions are xt comment
+			// matches
+			if r.comment == "" && cString(data[nftaMatchName]) == "comment" {
+				r.comment = cString(data[nftaMatchInfo])
+			}
+		}
+	}
+	return r
+}
+
+// udataComment returns the comment of the type-length-value user data.
+func udataComment(udata []byte) string {
+	for len(udata) >= 2 {
+		typ, length := udata[0], int(udata[1])
+		if 2+length > len(udata) {
+			break
+		}
+		if typ == udataRuleComment {
+			return cString(udata[2 : 2+length])
+		}
+		udata = udata[2+length:]
+	}
+	return ""
+}
+
+// netfilterDump sends a dump request of the message type to the netfilter
+// netlink socket and returns the payloads of the replies.
+func netfilterDump(typ uint16, payload []byte) ([][]byte, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	sa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
+	if err := syscall.Bind(fd, sa); err != nil {
+		return nil, err
+	}
+
+	req := make([]byte, syscall.NLMSG_HDRLEN+len(payload))
+	nativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	nativeEndian.PutUint16(req[4:6], typ)
+	nativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	nativeEndian.PutUint32(req[8:12], 1)
+	copy(req[syscall.NLMSG_HDRLEN:], payload)
+	if err := syscall.Sendto(fd, req, 0, sa); err != nil {
+		return nil, err
+	}
+
+	var payloads [][]byte
+	buf := make([]byte, 8*os.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return payloads, nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return nil, fmt.Errorf("invalid netlink error message")
+				}
+				if errno := int32(nativeEndian.Uint32(m.Data[:4])); errno != 0 {
+					return nil, syscall.Errno(-errno)
+				}
+				return payloads, nil
+			}
+			// The buffer is reused for the next messages
+			payloads = append(payloads, append([]byte(nil), m.Data...))
+		}
+	}
+}
+
+func encodeAttr(typ uint16, data []byte) []byte {
+	length := syscall.SizeofRtAttr + len(data)
+	b := make([]byte, (length+syscall.RTA_ALIGNTO-1)&^(syscall.RTA_ALIGNTO-1))
+	nativeEndian.PutUint16(b[0:2], uint16(length))
+	nativeEndian.PutUint16(b[2:4], typ)
+	copy(b[syscall.SizeofRtAttr:], data)
+	return b
+}
+
+// parseAttrs returns the netlink attributes of the data by type.
+func parseAttrs(data []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for _, attr := range splitAttrs(data) {
+		attrs[nativeEndian.Uint16(attr[2:4])&nlaTypeMask] = attr[syscall.SizeofRtAttr:]
+	}
+	return attrs
+}
+
+// parseList returns the values of the list elements of a nested attribute.
+func parseList(data []byte) [][]byte {
+	var values [][]byte
+	for _, attr := range splitAttrs(data) {
+		if nativeEndian.Uint16(attr[2:4])&nlaTypeMask == nftaListElem {
+			values = append(values, attr[syscall.SizeofRtAttr:])
+		}
+	}
+	return values
+}
+
+// splitAttrs splits the data into the attributes, including their header.
+func splitAttrs(data []byte) [][]byte {
+	var attrs [][]byte
+	for len(data) >= syscall.SizeofRtAttr {
+		length := int(nativeEndian.Uint16(data[0:2]))
+		if length < syscall.SizeofRtAttr || length > len(data) {
+			break
+		}
+		attrs = append(attrs, data[:length])
+
+		length = (length + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if length > len(data) {
+			break
+		}
+		data = data[length:]
+	}
+	return attrs
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
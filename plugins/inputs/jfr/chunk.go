@@ -0,0 +1,615 @@
+package jfr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	headerSize = 68
+
+	// typeMetadata and typeConstantPool are the type ids of the metadata and
+	// of the constant pool events.
+	typeMetadata     = 0
+	typeConstantPool = 1
+
+	// stateUpdating is the file state of a chunk whose header is being
+	// updated by the JVM.
+	stateUpdating = 255
+	// flagCompressedInts is set when the integers are LEB128 encoded.
+	flagCompressedInts = 1
+)
+
+var (
+	magic        = []byte("FLR\x00")
+	errTruncated = errors.New("truncated chunk")
+	// errNotReady is returned for the chunks being written, before their
+	// first flush or while their header is updated.
+	errNotReady = errors.New("chunk being written")
+)
+
+// header is the header of a chunk of a Flight Recorder file.
+type header struct {
+	size           int64
+	metadataAt     int64
+	startNanos     int64
+	startTicks     int64
+	ticksPerSecond int64
+	compressedInts bool
+}
+
+func parseHeader(buf []byte) (*header, error) {
+	if len(buf) < headerSize {
+		return nil, errTruncated
+	}
+	if string(buf[:4]) != string(magic) {
+		return nil, errors.New("not a Flight Recorder chunk")
+	}
+	if major := binary.BigEndian.Uint16(buf[4:]); major < 1 || major > 2 {
+		return nil, fmt.Errorf("unsupported chunk version %d", major)
+	}
+	if buf[64] == stateUpdating {
+		return nil, errNotReady
+	}
+	h := &header{
+		size:           int64(binary.BigEndian.Uint64(buf[8:])),
+		metadataAt:     int64(binary.BigEndian.Uint64(buf[24:])),
+		startNanos:     int64(binary.BigEndian.Uint64(buf[32:])),
+		startTicks:     int64(binary.BigEndian.Uint64(buf[48:])),
+		ticksPerSecond: int64(binary.BigEndian.Uint64(buf[56:])),
+		compressedInts: buf[67]&flagCompressedInts != 0,
+	}
+	if h.ticksPerSecond <= 0 {
+		return nil, errors.New("invalid ticks per second")
+	}
+	return h, nil
+}
+
+// reader reads the values of a chunk.
+type reader struct {
+	buf            []byte
+	pos            int
+	compressedInts bool
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errTruncated
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) fixed(n int) (uint64, error) {
+	if r.pos+n > len(r.buf) {
+		return 0, errTruncated
+	}
+	var v uint64
+	for _, b := range r.buf[r.pos : r.pos+n] {
+		v = v<<8 | uint64(b)
+	}
+	r.pos += n
+	return v, nil
+}
+
+// varint reads a LEB128 integer of up to 9 bytes, the last byte holding 8
+// bits.
+func (r *reader) varint() (uint64, error) {
+	var v uint64
+	for i := uint(0); i < 8; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return v, nil
+		}
+	}
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	return v | uint64(b)<<56, nil
+}
+
+// integer reads an integer of n bytes when the integers are not compressed.
+func (r *reader) integer(n int) (int64, error) {
+	if r.compressedInts {
+		v, err := r.varint()
+		return int64(v), err
+	}
+	v, err := r.fixed(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 2:
+		return int64(int16(v)), nil
+	case 4:
+		return int64(int32(v)), nil
+	}
+	return int64(v), nil
+}
+
+func (r *reader) int() (int64, error) {
+	return r.integer(4)
+}
+
+func (r *reader) long() (int64, error) {
+	return r.integer(8)
+}
+
+// count reads the number of items following, bounded by the remaining bytes.
+func (r *reader) count() (int, error) {
+	n, err := r.int()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > int64(len(r.buf)-r.pos) {
+		return 0, fmt.Errorf("invalid count %d", n)
+	}
+	return int(n), nil
+}
+
+// poolString is a string of the string constant pool.
+type poolString int64
+
+// string reads a string, nil for a null string.
+func (r *reader) string() (interface{}, error) {
+	encoding, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch encoding {
+	case 0:
+		return nil, nil
+	case 1:
+		return "", nil
+	case 2:
+		v, err := r.long()
+		return poolString(v), err
+	case 3, 5:
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		if r.pos+n > len(r.buf) {
+			return nil, errTruncated
+		}
+		b := r.buf[r.pos : r.pos+n]
+		r.pos += n
+		if encoding == 3 {
+			return string(b), nil
+		}
+		runes := make([]rune, n)
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes), nil
+	case 4:
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		chars := make([]uint16, n)
+		for i := range chars {
+			c, err := r.integer(2)
+			if err != nil {
+				return nil, err
+			}
+			chars[i] = uint16(c)
+		}
+		return string(utf16.Decode(chars)), nil
+	}
+	return nil, fmt.Errorf("invalid string encoding %d", encoding)
+}
+
+type field struct {
+	name     string
+	typeID   int64
+	pooled   bool
+	repeated bool
+}
+
+// class is the description of a type of the metadata.
+type class struct {
+	id     int64
+	name   string
+	fields []*field
+}
+
+// element is an element of the metadata tree.
+type element struct {
+	name       string
+	attributes map[string]string
+	children   []*element
+}
+
+// poolRef is a reference to a value of a constant pool.
+type poolRef struct {
+	typeID int64
+	key    int64
+}
+
+// chunk is a parsed chunk, its metadata and constant pools.
+type chunk struct {
+	header  *header
+	buf     []byte
+	classes map[int64]*class
+	byName  map[string]*class
+	pools   map[int64]map[int64]interface{}
+}
+
+// parseChunk parses the metadata and the constant pools of a chunk, buf
+// holding the chunk up to its size or the end of the file.
+func parseChunk(buf []byte) (*chunk, error) {
+	h, err := parseHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+	if h.size < int64(len(buf)) {
+		buf = buf[:h.size]
+	}
+	c := &chunk{
+		header:  h,
+		buf:     buf,
+		classes: make(map[int64]*class),
+		byName:  make(map[string]*class),
+		pools:   make(map[int64]map[int64]interface{}),
+	}
+
+	if h.metadataAt == 0 {
+		return nil, errNotReady
+	}
+	if h.metadataAt < headerSize || h.metadataAt >= int64(len(buf)) {
+		return nil, errors.New("invalid metadata offset")
+	}
+	if err := c.parseMetadata(c.reader(int(h.metadataAt))); err != nil {
+		return nil, fmt.Errorf("metadata: %v", err)
+	}
+
+	_, err = c.events(headerSize, func(typeID int64, r *reader) error {
+		if typeID != typeConstantPool {
+			return nil
+		}
+		return c.parseConstantPool(r)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("constant pools: %v", err)
+	}
+	return c, nil
+}
+
+func (c *chunk) reader(pos int) *reader {
+	return &reader{buf: c.buf, pos: pos, compressedInts: c.header.compressedInts}
+}
+
+// events calls fn with the type and a reader of the fields of each event
+// from the position, and returns the position after the last event.
+func (c *chunk) events(pos int, fn func(typeID int64, r *reader) error) (int, error) {
+	for pos < len(c.buf) {
+		r := c.reader(pos)
+		size, err := r.int()
+		if err != nil {
+			return pos, err
+		}
+		if size <= 0 || int64(pos)+size > int64(len(c.buf)) {
+			return pos, errTruncated
+		}
+		end := pos + int(size)
+		typeID, err := r.long()
+		if err != nil {
+			return pos, err
+		}
+		r.buf = c.buf[:end]
+		if err := fn(typeID, r); err != nil {
+			return pos, err
+		}
+		pos = end
+	}
+	return pos, nil
+}
+
+func (c *chunk) parseMetadata(r *reader) error {
+	if _, err := r.int(); err != nil {
+		return err
+	}
+	typeID, err := r.long()
+	if err != nil {
+		return err
+	}
+	if typeID != typeMetadata {
+		return fmt.Errorf("unexpected event type %d", typeID)
+	}
+	// start time, duration and metadata id
+	for i := 0; i < 3; i++ {
+		if _, err := r.long(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	names := make([]string, n)
+	for i := range names {
+		s, err := r.string()
+		if err != nil {
+			return err
+		}
+		names[i], _ = s.(string)
+	}
+
+	root, err := parseElement(r, names, 0)
+	if err != nil {
+		return err
+	}
+	c.addClasses(root)
+	return nil
+}
+
+func parseElement(r *reader, names []string, depth int) (*element, error) {
+	if depth > 32 {
+		return nil, errors.New("metadata too deep")
+	}
+	index := func() (string, error) {
+		i, err := r.int()
+		if err != nil {
+			return "", err
+		}
+		if i < 0 || i >= int64(len(names)) {
+			return "", fmt.Errorf("invalid string index %d", i)
+		}
+		return names[i], nil
+	}
+
+	name, err := index()
+	if err != nil {
+		return nil, err
+	}
+	e := &element{name: name, attributes: make(map[string]string)}
+	n, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		key, err := index()
+		if err != nil {
+			return nil, err
+		}
+		value, err := index()
+		if err != nil {
+			return nil, err
+		}
+		e.attributes[key] = value
+	}
+	n, err = r.count()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		child, err := parseElement(r, names, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		e.children = append(e.children, child)
+	}
+	return e, nil
+}
+
+// addClasses adds the classes of the metadata tree.
+func (c *chunk) addClasses(e *element) {
+	if e.name != "class" {
+		for _, child := range e.children {
+			c.addClasses(child)
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(e.attributes["id"], 10, 64)
+	if err != nil {
+		return
+	}
+	cl := &class{id: id, name: e.attributes["name"]}
+	for _, child := range e.children {
+		if child.name != "field" {
+			continue
+		}
+		typeID, err := strconv.ParseInt(child.attributes["class"], 10, 64)
+		if err != nil {
+			continue
+		}
+		cl.fields = append(cl.fields, &field{
+			name:     child.attributes["name"],
+			typeID:   typeID,
+			pooled:   child.attributes["constantPool"] == "true",
+			repeated: child.attributes["dimension"] == "1",
+		})
+	}
+	c.classes[id] = cl
+	c.byName[cl.name] = cl
+}
+
+func (c *chunk) parseConstantPool(r *reader) error {
+	// start time, duration and delta to the previous constant pool
+	for i := 0; i < 3; i++ {
+		if _, err := r.long(); err != nil {
+			return err
+		}
+	}
+	// flush or checkpoint type
+	if _, err := r.byte(); err != nil {
+		return err
+	}
+
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		typeID, err := r.long()
+		if err != nil {
+			return err
+		}
+		cl, ok := c.classes[typeID]
+		if !ok {
+			return fmt.Errorf("unknown constant pool type %d", typeID)
+		}
+		pool, ok := c.pools[typeID]
+		if !ok {
+			pool = make(map[int64]interface{})
+			c.pools[typeID] = pool
+		}
+		count, err := r.count()
+		if err != nil {
+			return err
+		}
+		for j := 0; j < count; j++ {
+			key, err := r.long()
+			if err != nil {
+				return err
+			}
+			v, err := c.value(r, cl, 0)
+			if err != nil {
+				return err
+			}
+			pool[key] = v
+		}
+	}
+	return nil
+}
+
+// value reads a value of the class: a primitive value, or the values of the
+// fields by name.
+func (c *chunk) value(r *reader, cl *class, depth int) (interface{}, error) {
+	if depth > 32 {
+		return nil, errors.New("value too deep")
+	}
+	switch cl.name {
+	case "boolean":
+		b, err := r.byte()
+		return b != 0, err
+	case "byte":
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case "char", "short":
+		return r.integer(2)
+	case "int":
+		return r.int()
+	case "long":
+		return r.long()
+	case "float":
+		v, err := r.fixed(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case "double":
+		v, err := r.fixed(8)
+		return math.Float64frombits(v), err
+	case "java.lang.String":
+		return r.string()
+	}
+
+	values := make(map[string]interface{}, len(cl.fields))
+	for _, f := range cl.fields {
+		n := 1
+		if f.repeated {
+			var err error
+			if n, err = r.count(); err != nil {
+				return nil, err
+			}
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := c.fieldValue(r, f, depth)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", cl.name, f.name, err)
+			}
+			items = append(items, v)
+		}
+		if f.repeated {
+			values[f.name] = items
+		} else {
+			values[f.name] = items[0]
+		}
+	}
+	return values, nil
+}
+
+func (c *chunk) fieldValue(r *reader, f *field, depth int) (interface{}, error) {
+	if f.pooled {
+		key, err := r.long()
+		return poolRef{typeID: f.typeID, key: key}, err
+	}
+	cl, ok := c.classes[f.typeID]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %d", f.typeID)
+	}
+	return c.value(r, cl, depth+1)
+}
+
+// resolve returns the value of a constant pool reference.
+func (c *chunk) resolve(v interface{}) interface{} {
+	for i := 0; i < 8; i++ {
+		switch ref := v.(type) {
+		case poolRef:
+			v = c.pools[ref.typeID][ref.key]
+		case poolString:
+			if cl, ok := c.byName["java.lang.String"]; ok {
+				v = c.pools[cl.id][int64(ref)]
+			} else {
+				return nil
+			}
+		default:
+			return v
+		}
+	}
+	return nil
+}
+
+// get returns the value of the path of fields of an event, resolving the
+// constant pool references.
+func (c *chunk) get(v interface{}, path ...string) interface{} {
+	v = c.resolve(v)
+	for _, name := range path {
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = c.resolve(values[name])
+	}
+	return v
+}
+
+func (c *chunk) getInt(v interface{}, path ...string) int64 {
+	i, _ := c.get(v, path...).(int64)
+	return i
+}
+
+func (c *chunk) getString(v interface{}, path ...string) string {
+	s, _ := c.get(v, path...).(string)
+	return s
+}
+
+// className returns the name of a java.lang.Class of an event.
+func (c *chunk) className(v interface{}, name string) string {
+	s := c.getString(v, name, "name", "string")
+	return strings.Replace(s, "/", ".", -1)
+}
+
+// duration converts ticks to a duration.
+func (c *chunk) duration(ticks int64) time.Duration {
+	return time.Duration(float64(ticks) * float64(time.Second) / float64(c.header.ticksPerSecond))
+}
+
+// time converts a tick count to the time.
+func (c *chunk) time(ticks int64) time.Time {
+	return time.Unix(0, c.header.startNanos).Add(c.duration(ticks - c.header.startTicks))
+}
@@ -0,0 +1,292 @@
+package jfr
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Chunk files of the Flight Recorder repositories.  The JVMs are started
+  ## with a recording and the repository, each JVM writes its chunks in a
+  ## directory of the repository named after the start time and the pid:
+  ##   -XX:StartFlightRecording -XX:FlightRecorderOptions:repository=/var/lib/jfr
+  ## Glob patterns are supported, with ** matching any directories.
+  files = ["/var/lib/jfr/*/*.jfr"]
+
+  ## Read the events already in the chunks when Telegraf starts, instead of
+  ## the events written afterwards.
+  # from_beginning = false
+
+  ## Add the class of the allocated objects and of the contended locks as the
+  ## class tag of the allocation and lock metrics.  The number of series
+  ## grows with the number of classes.
+  # class_tags = false
+`
+
+// JFR reads the events of the Java Flight Recorder from the chunk files of
+// its repository.
+type JFR struct {
+	Files         []string `toml:"files"`
+	FromBeginning bool     `toml:"from_beginning"`
+	ClassTags     bool     `toml:"class_tags"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	globs []*globpath.GlobPath
+	// chunks are the read chunks by path.
+	chunks      map[string]*chunkState
+	initialized bool
+}
+
+// chunkState is the size of a chunk and the position of the next event to
+// read.
+type chunkState struct {
+	size   int64
+	offset int
+}
+
+func (j *JFR) Description() string {
+	return "Read allocation, GC and lock contention events of the Java Flight Recorder"
+}
+
+func (j *JFR) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *JFR) Init() error {
+	if len(j.Files) == 0 {
+		return fmt.Errorf("no files configured")
+	}
+	for _, file := range j.Files {
+		g, err := globpath.Compile(file)
+		if err != nil {
+			return fmt.Errorf("invalid files %q: %v", file, err)
+		}
+		j.globs = append(j.globs, g)
+	}
+	j.chunks = make(map[string]*chunkState)
+	return nil
+}
+
+func (j *JFR) Gather(acc telegraf.Accumulator) error {
+	series := make(aggregator)
+	matched := make(map[string]bool)
+	for _, g := range j.globs {
+		for _, path := range g.Match() {
+			if matched[path] {
+				continue
+			}
+			matched[path] = true
+			if err := j.gatherChunk(acc, series, path); err != nil {
+				acc.AddError(fmt.Errorf("%s: %v", path, err))
+			}
+		}
+	}
+	for path := range j.chunks {
+		if !matched[path] {
+			delete(j.chunks, path)
+		}
+	}
+	j.initialized = true
+
+	series.add(acc)
+	return nil
+}
+
+func (j *JFR) gatherChunk(acc telegraf.Accumulator, series aggregator, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The chunks are only read when the JVM wrote events since the last
+	// read.
+	buf := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return err
+	}
+	h, err := parseHeader(buf)
+	if err == errNotReady {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	state, ok := j.chunks[path]
+	if ok && h.size == state.size {
+		return nil
+	}
+
+	rest, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	c, err := parseChunk(append(buf, rest...))
+	if err == errNotReady {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		state = &chunkState{offset: headerSize}
+		j.chunks[path] = state
+		if !j.initialized && !j.FromBeginning {
+			state.offset = len(c.buf)
+		}
+	}
+	state.size = h.size
+
+	tags := map[string]string{
+		"recording": filepath.Base(filepath.Dir(path)),
+	}
+	state.offset, err = c.events(state.offset, func(typeID int64, r *reader) error {
+		cl, ok := c.classes[typeID]
+		if !ok || !handled[cl.name] {
+			return nil
+		}
+		v, err := c.value(r, cl, 0)
+		if err != nil {
+			return fmt.Errorf("%s: %v", cl.name, err)
+		}
+		j.addEvent(acc, series, c, cl.name, v, tags)
+		return nil
+	})
+	return err
+}
+
+// handled are the events converted to metrics.
+var handled = map[string]bool{
+	"jdk.GarbageCollection":           true,
+	"jdk.ObjectAllocationSample":      true,
+	"jdk.ObjectAllocationInNewTLAB":   true,
+	"jdk.ObjectAllocationOutsideTLAB": true,
+	"jdk.JavaMonitorEnter":            true,
+	"jdk.ThreadPark":                  true,
+}
+
+func (j *JFR) addEvent(acc telegraf.Accumulator, series aggregator, c *chunk, name string, v interface{}, tags map[string]string) {
+	switch name {
+	case "jdk.GarbageCollection":
+		gcTags := copyTags(tags)
+		gcTags["name"] = c.getString(v, "name", "name")
+		gcTags["cause"] = c.getString(v, "cause", "cause")
+		acc.AddFields("jfr_gc",
+			map[string]interface{}{
+				"gc_id":            c.getInt(v, "gcId"),
+				"duration_ns":      c.duration(c.getInt(v, "duration")).Nanoseconds(),
+				"sum_of_pauses_ns": c.duration(c.getInt(v, "sumOfPauses")).Nanoseconds(),
+				"longest_pause_ns": c.duration(c.getInt(v, "longestPause")).Nanoseconds(),
+			},
+			gcTags,
+			c.time(c.getInt(v, "startTime")),
+		)
+	case "jdk.ObjectAllocationSample":
+		s := series.get("jfr_allocation", j.classTags(tags, c.className(v, "objectClass")))
+		s.sum("sample_bytes", c.getInt(v, "weight"))
+		s.sum("samples", 1)
+	case "jdk.ObjectAllocationInNewTLAB":
+		s := series.get("jfr_allocation", j.classTags(tags, c.className(v, "objectClass")))
+		s.sum("tlab_bytes", c.getInt(v, "tlabSize"))
+		s.sum("tlab_allocations", 1)
+	case "jdk.ObjectAllocationOutsideTLAB":
+		s := series.get("jfr_allocation", j.classTags(tags, c.className(v, "objectClass")))
+		s.sum("outside_tlab_bytes", c.getInt(v, "allocationSize"))
+		s.sum("outside_tlab_allocations", 1)
+	case "jdk.JavaMonitorEnter":
+		j.addLock(series, c, v, tags, "monitor_enter", c.className(v, "monitorClass"))
+	case "jdk.ThreadPark":
+		j.addLock(series, c, v, tags, "thread_park", c.className(v, "parkedClass"))
+	}
+}
+
+func (j *JFR) addLock(series aggregator, c *chunk, v interface{}, tags map[string]string, event, class string) {
+	lockTags := j.classTags(tags, class)
+	lockTags["event"] = event
+	duration := c.duration(c.getInt(v, "duration")).Nanoseconds()
+
+	s := series.get("jfr_lock", lockTags)
+	s.sum("count", 1)
+	s.sum("duration_ns", duration)
+	s.max("max_duration_ns", duration)
+}
+
+// classTags returns the tags with the class tag if enabled.
+func (j *JFR) classTags(tags map[string]string, class string) map[string]string {
+	tags = copyTags(tags)
+	if j.ClassTags && class != "" {
+		tags["class"] = class
+	}
+	return tags
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags)+2)
+	for k, v := range tags {
+		copied[k] = v
+	}
+	return copied
+}
+
+// aggregator sums the events of an interval by measurement and tags.
+type aggregator map[string]*aggregate
+
+type aggregate struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]interface{}
+}
+
+func (a aggregator) get(measurement string, tags map[string]string) *aggregate {
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		keys = append(keys, k+"="+v)
+	}
+	sort.Strings(keys)
+	id := measurement + "," + strings.Join(keys, ",")
+
+	s, ok := a[id]
+	if !ok {
+		s = &aggregate{
+			measurement: measurement,
+			tags:        tags,
+			fields:      make(map[string]interface{}),
+		}
+		a[id] = s
+	}
+	return s
+}
+
+func (a aggregator) add(acc telegraf.Accumulator) {
+	for _, s := range a {
+		acc.AddFields(s.measurement, s.fields, s.tags)
+	}
+}
+
+func (s *aggregate) sum(name string, v int64) {
+	sum, _ := s.fields[name].(int64)
+	s.fields[name] = sum + v
+}
+
+func (s *aggregate) max(name string, v int64) {
+	if max, ok := s.fields[name].(int64); !ok || v > max {
+		s.fields[name] = v
+	}
+}
+
+func init() {
+	inputs.Add("jfr", func() telegraf.Input {
+		return &JFR{}
+	})
+}
@@ -0,0 +1,413 @@
+package jfr
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// encoder encodes the values of a chunk with compressed integers.
+type encoder []byte
+
+func (e encoder) varint(v int64) encoder {
+	u := uint64(v)
+	for u >= 0x80 {
+		e = append(e, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(e, byte(u))
+}
+
+func (e encoder) string(s string) encoder {
+	e = append(e, 3)
+	e = e.varint(int64(len(s)))
+	return append(e, s...)
+}
+
+// event encodes an event, its size being padded to 4 bytes as the JVM does.
+func event(typeID int64, body encoder) encoder {
+	b := encoder{}.varint(typeID)
+	b = append(b, body...)
+	size := 4 + len(b)
+	e := encoder{byte(size) | 0x80, byte(size>>7) | 0x80, byte(size>>14) | 0x80, byte(size >> 21)}
+	return append(e, b...)
+}
+
+// metadata encodes the metadata of the classes, as the elements of a tree.
+type metadata struct {
+	names []string
+}
+
+func (m *metadata) index(s string) int64 {
+	for i, name := range m.names {
+		if name == s {
+			return int64(i)
+		}
+	}
+	m.names = append(m.names, s)
+	return int64(len(m.names) - 1)
+}
+
+func (m *metadata) element(name string, attributes []string, children ...encoder) encoder {
+	e := encoder{}.varint(m.index(name)).varint(int64(len(attributes) / 2))
+	for _, a := range attributes {
+		e = e.varint(m.index(a))
+	}
+	e = e.varint(int64(len(children)))
+	for _, child := range children {
+		e = append(e, child...)
+	}
+	return e
+}
+
+func (m *metadata) class(id, name string, fields ...encoder) encoder {
+	return m.element("class", []string{"id", id, "name", name}, fields...)
+}
+
+func (m *metadata) field(name, class string, pooled bool) encoder {
+	attributes := []string{"name", name, "class", class}
+	if pooled {
+		attributes = append(attributes, "constantPool", "true")
+	}
+	return m.element("field", attributes)
+}
+
+func (m *metadata) event() encoder {
+	classes := []encoder{
+		m.class("4", "long"),
+		m.class("5", "int"),
+		m.class("7", "java.lang.String"),
+		m.class("20", "jdk.types.Symbol", m.field("string", "7", false)),
+		m.class("21", "java.lang.Class", m.field("name", "20", true)),
+		m.class("22", "jdk.types.GCName", m.field("name", "7", false)),
+		m.class("23", "jdk.types.GCCause", m.field("cause", "7", false)),
+		m.class("30", "java.lang.Thread", m.field("javaName", "7", false)),
+		m.class("100", "jdk.GarbageCollection",
+			m.field("startTime", "4", false),
+			m.field("duration", "4", false),
+			m.field("eventThread", "30", true),
+			m.field("gcId", "5", false),
+			m.field("name", "22", true),
+			m.field("cause", "23", true),
+			m.field("sumOfPauses", "4", false),
+			m.field("longestPause", "4", false)),
+		m.class("101", "jdk.ObjectAllocationSample",
+			m.field("startTime", "4", false),
+			m.field("eventThread", "30", true),
+			m.field("objectClass", "21", true),
+			m.field("weight", "4", false)),
+		m.class("102", "jdk.JavaMonitorEnter",
+			m.field("startTime", "4", false),
+			m.field("duration", "4", false),
+			m.field("eventThread", "30", true),
+			m.field("monitorClass", "21", true),
+			m.field("previousOwner", "30", true),
+			m.field("address", "4", false)),
+		m.class("103", "jdk.ThreadPark",
+			m.field("startTime", "4", false),
+			m.field("duration", "4", false),
+			m.field("parkedClass", "21", true),
+			m.field("timeout", "4", false)),
+		m.class("104", "jdk.CPULoad",
+			m.field("startTime", "4", false),
+			m.field("machineTotal", "4", false)),
+		m.class("105", "jdk.ObjectAllocationInNewTLAB",
+			m.field("startTime", "4", false),
+			m.field("objectClass", "21", true),
+			m.field("allocationSize", "4", false),
+			m.field("tlabSize", "4", false)),
+	}
+	root := m.element("root", nil,
+		m.element("metadata", nil, classes...),
+		m.element("region", []string{"gmtOffset", "0"}))
+
+	body := encoder{}.varint(0).varint(0).varint(1).varint(int64(len(m.names)))
+	for _, name := range m.names {
+		body = body.string(name)
+	}
+	return event(typeMetadata, append(body, root...))
+}
+
+// constantPool encodes the constant pools referenced by the events.
+func constantPool() encoder {
+	symbol := func(s string) encoder { return encoder{}.string(s) }
+	body := encoder{}.varint(0).varint(0).varint(0)
+	body = append(body, 1)
+	body = body.varint(5)
+	// java.lang.String
+	body = body.varint(7).varint(1).varint(1).string("G1 Young Generation")
+	// jdk.types.Symbol
+	body = body.varint(20).varint(2).
+		varint(1).append(symbol("java/lang/Object")).
+		varint(2).append(symbol("java/util/concurrent/locks/ReentrantLock$NonfairSync"))
+	// java.lang.Class
+	body = body.varint(21).varint(2).varint(1).varint(1).varint(2).varint(2)
+	// jdk.types.GCName, its name being a string of the string pool
+	body = body.varint(22).varint(1).varint(1)
+	body = append(body, 2)
+	body = body.varint(1)
+	// jdk.types.GCCause
+	body = body.varint(23).varint(1).varint(1).string("G1 Evacuation Pause")
+	return event(typeConstantPool, body)
+}
+
+func (e encoder) append(b encoder) encoder {
+	return append(e, b...)
+}
+
+const (
+	startTicks     = 1000
+	ticksPerSecond = 1000000
+)
+
+var startTime = time.Date(2020, 5, 14, 12, 0, 0, 0, time.UTC)
+
+func events() []encoder {
+	return []encoder{
+		// GC of 5ms at 1s, with pauses of 3ms.
+		event(100, encoder{}.varint(startTicks+ticksPerSecond).varint(5000).varint(1).
+			varint(7).varint(1).varint(1).varint(3000).varint(2000)),
+		event(101, encoder{}.varint(startTicks).varint(1).varint(1).varint(4096)),
+		event(101, encoder{}.varint(startTicks).varint(1).varint(1).varint(1024)),
+		event(104, encoder{}.varint(startTicks).varint(1)),
+		event(102, encoder{}.varint(startTicks).varint(20000).varint(1).varint(2).varint(2).varint(0x7f00)),
+		event(102, encoder{}.varint(startTicks).varint(30000).varint(1).varint(2).varint(2).varint(0x7f00)),
+		event(103, encoder{}.varint(startTicks).varint(1000).varint(2).varint(0)),
+	}
+}
+
+// writeChunk writes a chunk of the events flushed by the JVM, each flush
+// writing the events followed by their constant pool, and by the metadata on
+// the first flush.
+func writeChunk(t *testing.T, path string, flushes ...[]encoder) {
+	buf := make(encoder, headerSize)
+	var metadataAt int
+	for i, events := range flushes {
+		for _, e := range events {
+			buf = append(buf, e...)
+		}
+		buf = append(buf, constantPool()...)
+		if i == 0 {
+			metadataAt = len(buf)
+			buf = append(buf, (&metadata{}).event()...)
+		}
+	}
+
+	copy(buf, magic)
+	binary.BigEndian.PutUint16(buf[4:], 2)
+	binary.BigEndian.PutUint64(buf[8:], uint64(len(buf)))
+	binary.BigEndian.PutUint64(buf[24:], uint64(metadataAt))
+	binary.BigEndian.PutUint64(buf[32:], uint64(startTime.UnixNano()))
+	binary.BigEndian.PutUint64(buf[48:], startTicks)
+	binary.BigEndian.PutUint64(buf[56:], ticksPerSecond)
+	buf[67] = flagCompressedInts
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, buf, 0644))
+}
+
+func newJFR(t *testing.T, dir string) *JFR {
+	j := &JFR{
+		Files: []string{filepath.Join(dir, "*", "*.jfr")},
+		Log:   testutil.Logger{},
+	}
+	require.NoError(t, j.Init())
+	return j
+}
+
+func TestGather(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jfr")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeChunk(t, filepath.Join(dir, "2020_05_14_12_00_00_1234", "chunk.jfr"), events())
+
+	j := newJFR(t, dir)
+	j.FromBeginning = true
+	j.ClassTags = true
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(j.Gather))
+
+	recording := "2020_05_14_12_00_00_1234"
+	expected := []telegraf.Metric{
+		testutil.MustMetric("jfr_gc",
+			map[string]string{
+				"recording": recording,
+				"name":      "G1 Young Generation",
+				"cause":     "G1 Evacuation Pause",
+			},
+			map[string]interface{}{
+				"gc_id":            int64(7),
+				"duration_ns":      int64(5000000),
+				"sum_of_pauses_ns": int64(3000000),
+				"longest_pause_ns": int64(2000000),
+			},
+			startTime.Add(time.Second),
+		),
+		testutil.MustMetric("jfr_allocation",
+			map[string]string{"recording": recording, "class": "java.lang.Object"},
+			map[string]interface{}{
+				"sample_bytes": int64(5120),
+				"samples":      int64(2),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("jfr_lock",
+			map[string]string{
+				"recording": recording,
+				"class":     "java.util.concurrent.locks.ReentrantLock$NonfairSync",
+				"event":     "monitor_enter",
+			},
+			map[string]interface{}{
+				"count":           int64(2),
+				"duration_ns":     int64(50000000),
+				"max_duration_ns": int64(30000000),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("jfr_lock",
+			map[string]string{
+				"recording": recording,
+				"class":     "java.util.concurrent.locks.ReentrantLock$NonfairSync",
+				"event":     "thread_park",
+			},
+			map[string]interface{}{
+				"count":           int64(1),
+				"duration_ns":     int64(1000000),
+				"max_duration_ns": int64(1000000),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	actual := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected[:1], actual[:1])
+	testutil.RequireMetricsEqual(t, expected[1:], actual[1:], testutil.SortMetrics(), testutil.IgnoreTime())
+
+	// The chunk is unchanged, its events are only read once.
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(j.Gather))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestNewEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jfr")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "2020_05_14_12_00_00_1234", "chunk.jfr")
+	writeChunk(t, path, events())
+
+	j := newJFR(t, dir)
+
+	// The events written before the start are skipped.
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(j.Gather))
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	// The JVM flushed new events.
+	writeChunk(t, path, events(), []encoder{
+		event(105, encoder{}.varint(startTicks).varint(1).varint(24).varint(65536)),
+		event(101, encoder{}.varint(startTicks).varint(1).varint(1).varint(512)),
+	})
+	require.NoError(t, acc.GatherError(j.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("jfr_allocation",
+			map[string]string{"recording": "2020_05_14_12_00_00_1234"},
+			map[string]interface{}{
+				"sample_bytes":     int64(512),
+				"samples":          int64(1),
+				"tlab_bytes":       int64(65536),
+				"tlab_allocations": int64(1),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	// New chunks are read from the beginning.
+	acc.ClearMetrics()
+	writeChunk(t, filepath.Join(dir, "2020_05_14_12_00_00_1234", "chunk2.jfr"), events()[:1])
+	require.NoError(t, acc.GatherError(j.Gather))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, "jfr_gc", acc.GetTelegrafMetrics()[0].Name())
+}
+
+func TestChunkNotReady(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jfr")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "2020_05_14_12_00_00_1234", "chunk.jfr")
+	writeChunk(t, path, events())
+
+	buf, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	buf[64] = stateUpdating
+	require.NoError(t, ioutil.WriteFile(path, buf, 0644))
+
+	j := newJFR(t, dir)
+	j.FromBeginning = true
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(j.Gather))
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	// The header was updated.
+	buf[64] = 0
+	require.NoError(t, ioutil.WriteFile(path, buf, 0644))
+	require.NoError(t, acc.GatherError(j.Gather))
+	require.Len(t, acc.GetTelegrafMetrics(), 4)
+}
+
+func TestInvalidChunk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jfr")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "recording", "chunk.jfr")
+	writeChunk(t, path, events())
+
+	buf, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, buf[:headerSize+10], 0644))
+
+	j := newJFR(t, dir)
+	j.FromBeginning = true
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(j.Gather))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a chunk, but long enough for a header of a chunk of the flight recorder"), 0644))
+	acc.Errors = nil
+	require.Error(t, acc.GatherError(j.Gather))
+}
+
+func TestReader(t *testing.T) {
+	r := &reader{buf: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, compressedInts: true}
+	v, err := r.long()
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), v)
+
+	r = &reader{buf: []byte{0xff, 0xfe, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a}}
+	v, err = r.integer(2)
+	require.NoError(t, err)
+	require.Equal(t, int64(-2), v)
+	v, err = r.long()
+	require.NoError(t, err)
+	require.Equal(t, int64(42), v)
+	_, err = r.int()
+	require.Equal(t, errTruncated, err)
+
+	r = &reader{buf: []byte{4, 2, 0x48, 0x69, 5, 1, 0xe9}, compressedInts: true}
+	s, err := r.string()
+	require.NoError(t, err)
+	require.Equal(t, "Hi", s)
+	s, err = r.string()
+	require.NoError(t, err)
+	require.Equal(t, "é", s)
+}
@@ -261,6 +261,11 @@ func formatReadUrl(configUrl, username, password string) (string, error) {
 	}
 
 	readUrl.Path = path.Join(parsedUrl.Path, "read")
-	readUrl.Query().Add("ignoreErrors", "true")
+
+	// Errors of single reads are reported in their status, so a missing mbean
+	// does not fail the whole bulk request.
+	query := url.Values{}
+	query.Set("ignoreErrors", "true")
+	readUrl.RawQuery = query.Encode()
 	return readUrl.String(), nil
 }
@@ -8,9 +8,13 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
 )
 
+var pki = testutil.NewPKI("../../../testutil/pki")
+
 func TestJolokia2_ClientAuthRequest(t *testing.T) {
 	var username string
 	var password string
@@ -127,3 +131,85 @@ func TestJolokia2_ClientProxyAuthRequest(t *testing.T) {
 		t.Errorf("Expected proxy target password %s, but was %s", expect, target["password"])
 	}
 }
+
+func TestJolokia2_ClientBulkRequest(t *testing.T) {
+	var posts int
+	var ignoreErrors string
+	var requests []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		ignoreErrors = r.URL.Query().Get("ignoreErrors")
+
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &requests)
+		if err != nil {
+			t.Error(err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	plugin := setupPlugin(t, fmt.Sprintf(`
+		[jolokia2_agent]
+			urls = ["%s/jolokia"]
+		[[jolokia2_agent.metric]]
+			name  = "runtime"
+			mbean = "java.lang:type=Runtime"
+			paths = ["Uptime", "StartTime"]
+		[[jolokia2_agent.metric]]
+			name  = "heap"
+			mbean = "java.lang:type=Memory"
+			paths = ["HeapMemoryUsage/used", "HeapMemoryUsage/max"]
+	`, server.URL))
+
+	var acc testutil.Accumulator
+	plugin.Gather(&acc)
+
+	require.Equal(t, 1, posts)
+	require.Equal(t, "true", ignoreErrors)
+	require.Len(t, requests, 3)
+}
+
+func TestJolokia2_ClientCertificate(t *testing.T) {
+	var requests []map[string]interface{}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &requests)
+		if err != nil {
+			t.Error(err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "[]")
+	}))
+	serverConfig := &tls.ServerConfig{
+		TLSCert:           pki.ServerCertPath(),
+		TLSKey:            pki.ServerKeyPath(),
+		TLSAllowedCACerts: []string{pki.CACertPath()},
+	}
+	tlsConfig, err := serverConfig.TLSConfig()
+	require.NoError(t, err)
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	plugin := setupPlugin(t, fmt.Sprintf(`
+		[jolokia2_agent]
+			urls = ["%s/jolokia"]
+			tls_ca = "%s"
+			tls_cert = "%s"
+			tls_key = "%s"
+		[[jolokia2_agent.metric]]
+			name  = "hello"
+			mbean = "hello:foo=bar"
+	`, server.URL, pki.CACertPath(), pki.ClientCertPath(), pki.ClientKeyPath()))
+
+	var acc testutil.Accumulator
+	plugin.Gather(&acc)
+
+	require.Empty(t, acc.Errors)
+	require.Len(t, requests, 1)
+}
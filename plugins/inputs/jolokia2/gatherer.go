@@ -33,8 +33,8 @@ func (g *Gatherer) Gather(client *Client, acc telegraf.Accumulator) error {
 		tags = map[string]string{"jolokia_agent_url": client.URL}
 	}
 
-	requests := makeReadRequests(g.metrics)
-	responses, err := client.read(requests)
+	// All the reads are sent in a single bulk request.
+	responses, err := client.read(g.requests)
 	if err != nil {
 		return err
 	}
@@ -192,49 +192,68 @@ func tagSetsMatch(a, b map[string]string) bool {
 }
 
 // makeReadRequests creates ReadRequest objects from metrics definitions.
+// The reads of several metrics for the same mbean, attributes and path are
+// only requested once, the response matches all the metrics.
 func makeReadRequests(metrics []Metric) []ReadRequest {
 	var requests []ReadRequest
 	for _, metric := range metrics {
+		requests = append(requests, makeMetricReadRequests(metric)...)
+	}
 
-		if len(metric.Paths) == 0 {
-			requests = append(requests, ReadRequest{
-				Mbean:      metric.Mbean,
-				Attributes: []string{},
-			})
-		} else {
-			attributes := make(map[string][]string)
+	seen := make(map[string]bool, len(requests))
+	unique := requests[:0]
+	for _, request := range requests {
+		key := request.Mbean + "\x00" + strings.Join(request.Attributes, "\x00") + "\x00" + request.Path
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, request)
+	}
+	return unique
+}
 
-			for _, path := range metric.Paths {
-				segments := strings.Split(path, "/")
-				attribute := segments[0]
+// makeMetricReadRequests creates the ReadRequest objects of a metric.
+func makeMetricReadRequests(metric Metric) []ReadRequest {
+	if len(metric.Paths) == 0 {
+		return []ReadRequest{{
+			Mbean:      metric.Mbean,
+			Attributes: []string{},
+		}}
+	}
 
-				if _, ok := attributes[attribute]; !ok {
-					attributes[attribute] = make([]string, 0)
-				}
+	var requests []ReadRequest
+	attributes := make(map[string][]string)
 
-				if len(segments) > 1 {
-					paths := attributes[attribute]
-					attributes[attribute] = append(paths, strings.Join(segments[1:], "/"))
-				}
-			}
+	for _, path := range metric.Paths {
+		segments := strings.Split(path, "/")
+		attribute := segments[0]
 
-			rootAttributes := findRequestAttributesWithoutPaths(attributes)
-			if len(rootAttributes) > 0 {
-				requests = append(requests, ReadRequest{
-					Mbean:      metric.Mbean,
-					Attributes: rootAttributes,
-				})
-			}
+		if _, ok := attributes[attribute]; !ok {
+			attributes[attribute] = make([]string, 0)
+		}
 
-			for _, deepAttribute := range findRequestAttributesWithPaths(attributes) {
-				for _, path := range attributes[deepAttribute] {
-					requests = append(requests, ReadRequest{
-						Mbean:      metric.Mbean,
-						Attributes: []string{deepAttribute},
-						Path:       path,
-					})
-				}
-			}
+		if len(segments) > 1 {
+			paths := attributes[attribute]
+			attributes[attribute] = append(paths, strings.Join(segments[1:], "/"))
+		}
+	}
+
+	rootAttributes := findRequestAttributesWithoutPaths(attributes)
+	if len(rootAttributes) > 0 {
+		requests = append(requests, ReadRequest{
+			Mbean:      metric.Mbean,
+			Attributes: rootAttributes,
+		})
+	}
+
+	for _, deepAttribute := range findRequestAttributesWithPaths(attributes) {
+		for _, path := range attributes[deepAttribute] {
+			requests = append(requests, ReadRequest{
+				Mbean:      metric.Mbean,
+				Attributes: []string{deepAttribute},
+				Path:       path,
+			})
 		}
 	}
 
@@ -102,3 +102,24 @@ func TestJolokia2_makeReadRequests(t *testing.T) {
 		}
 	}
 }
+
+func TestJolokia2_makeReadRequestsDeduplicated(t *testing.T) {
+	metrics := []Metric{
+		{Name: "uptime", Mbean: "java.lang:type=Runtime", Paths: []string{"Uptime"}},
+		{Name: "runtime", Mbean: "java.lang:type=Runtime", Paths: []string{"Uptime"}},
+		{Name: "heap", Mbean: "java.lang:type=Memory", Paths: []string{"HeapMemoryUsage/used"}},
+		{Name: "heap_used", Mbean: "java.lang:type=Memory", Paths: []string{"HeapMemoryUsage/used"}},
+	}
+
+	assert.Equal(t, []ReadRequest{
+		{
+			Mbean:      "java.lang:type=Runtime",
+			Attributes: []string{"Uptime"},
+		},
+		{
+			Mbean:      "java.lang:type=Memory",
+			Attributes: []string{"HeapMemoryUsage"},
+			Path:       "used",
+		},
+	}, makeReadRequests(metrics))
+}
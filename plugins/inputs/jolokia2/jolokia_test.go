@@ -748,6 +748,103 @@ func TestJolokia2_ProxyTargets(t *testing.T) {
 	})
 }
 
+func TestJolokia2_KeyTag(t *testing.T) {
+	config := `
+	[jolokia2_agent]
+		urls = ["%s"]
+
+	[[jolokia2_agent.metric]]
+		name    = "gc_memory"
+		mbean   = "java.lang:type=GarbageCollector,name=G1 Young Generation"
+		paths   = ["LastGcInfo/memoryUsageAfterGc"]
+		key_tag = "pool"`
+
+	response := `[{
+		"request": {
+			"mbean": "java.lang:type=GarbageCollector,name=G1 Young Generation",
+			"attribute": "LastGcInfo",
+			"path": "memoryUsageAfterGc",
+			"type": "read"
+		},
+		"value": {
+			"G1 Eden Space": {
+				"used": 0,
+				"max": -1
+			},
+			"G1 Old Gen": {
+				"used": 1048576,
+				"max": 2147483648
+			}
+		},
+		"status": 200
+	}]`
+
+	server := setupServer(http.StatusOK, response)
+	defer server.Close()
+	plugin := setupPlugin(t, fmt.Sprintf(config, server.URL))
+
+	var acc testutil.Accumulator
+	assert.NoError(t, plugin.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "gc_memory", map[string]interface{}{
+		"LastGcInfo.memoryUsageAfterGc.used": 0.0,
+		"LastGcInfo.memoryUsageAfterGc.max":  -1.0,
+	}, map[string]string{
+		"pool":              "G1 Eden Space",
+		"jolokia_agent_url": server.URL,
+	})
+	acc.AssertContainsTaggedFields(t, "gc_memory", map[string]interface{}{
+		"LastGcInfo.memoryUsageAfterGc.used": 1048576.0,
+		"LastGcInfo.memoryUsageAfterGc.max":  2147483648.0,
+	}, map[string]string{
+		"pool":              "G1 Old Gen",
+		"jolokia_agent_url": server.URL,
+	})
+}
+
+func TestJolokia2_FlattenArrays(t *testing.T) {
+	config := `
+	[jolokia2_agent]
+		urls = ["%s"]
+
+	[[jolokia2_agent.metric]]
+		name  = "array_skipped"
+		mbean = "array_value:foo=bar"
+		paths = ["Values"]
+
+	[[jolokia2_agent.metric]]
+		name           = "array_flattened"
+		mbean          = "array_value:foo=bar"
+		paths          = ["Values"]
+		flatten_arrays = true`
+
+	response := `[{
+		"request": {
+			"mbean": "array_value:foo=bar",
+			"attribute": "Values",
+			"type": "read"
+		},
+		"value": [1, 2, {"count": 3}],
+		"status": 200
+	}]`
+
+	server := setupServer(http.StatusOK, response)
+	defer server.Close()
+	plugin := setupPlugin(t, fmt.Sprintf(config, server.URL))
+
+	var acc testutil.Accumulator
+	assert.NoError(t, plugin.Gather(&acc))
+
+	assert.False(t, acc.HasMeasurement("array_skipped"))
+	acc.AssertContainsTaggedFields(t, "array_flattened", map[string]interface{}{
+		"Values.0":       1.0,
+		"Values.1":       2.0,
+		"Values.2.count": 3.0,
+	}, map[string]string{
+		"jolokia_agent_url": server.URL,
+	})
+}
+
 func TestFillFields(t *testing.T) {
 	complex := map[string]interface{}{"Value": []interface{}{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}}
 	var scalar interface{}
@@ -13,6 +13,8 @@ type MetricConfig struct {
 	FieldSeparator *string
 	TagPrefix      *string
 	TagKeys        []string
+	KeyTag         string
+	FlattenArrays  bool
 }
 
 // A Metric represents a specification for a
@@ -28,16 +30,26 @@ type Metric struct {
 	TagPrefix      string
 	TagKeys        []string
 
+	// KeyTag is the tag of the keys of the composite values, each key of
+	// the value read for the mbean is split into its own point.
+	KeyTag string
+
+	// FlattenArrays adds the elements of the arrays as fields named by
+	// their index, instead of skipping the arrays.
+	FlattenArrays bool
+
 	mbeanDomain     string
 	mbeanProperties []string
 }
 
 func NewMetric(config MetricConfig, defaultFieldPrefix, defaultFieldSeparator, defaultTagPrefix string) Metric {
 	metric := Metric{
-		Name:    config.Name,
-		Mbean:   config.Mbean,
-		Paths:   config.Paths,
-		TagKeys: config.TagKeys,
+		Name:          config.Name,
+		Mbean:         config.Mbean,
+		Paths:         config.Paths,
+		TagKeys:       config.TagKeys,
+		KeyTag:        config.KeyTag,
+		FlattenArrays: config.FlattenArrays,
 	}
 
 	if config.FieldName != nil {
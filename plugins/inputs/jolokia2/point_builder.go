@@ -2,6 +2,7 @@ package jolokia2
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -33,16 +34,36 @@ func (pb *pointBuilder) Build(mbean string, value interface{}) []point {
 		value = map[string]interface{}{mbean: value}
 	}
 
+	points := make([]point, 0)
+
+	// The value of a pattern is a map of the matching mbeans, anything else
+	// has no point.
 	valueMap, ok := value.(map[string]interface{})
-	if !ok { // FIXME: log it and move on.
-		panic(fmt.Sprintf("There should be a map here for %s!\n", mbean))
+	if !ok {
+		return points
 	}
 
-	points := make([]point, 0)
 	for mbean, value := range valueMap {
+		tags := pb.extractTags(mbean)
+
+		if keyValues, ok := pb.keyValues(value); ok {
+			for key, keyValue := range keyValues {
+				keyTags := make(map[string]string, len(tags)+1)
+				for k, v := range tags {
+					keyTags[k] = v
+				}
+				keyTags[pb.metric.KeyTag] = key
+
+				points = append(points, point{
+					Tags:   keyTags,
+					Fields: pb.extractFields(mbean, keyValue),
+				})
+			}
+			continue
+		}
 
 		points = append(points, point{
-			Tags:   pb.extractTags(mbean),
+			Tags:   tags,
 			Fields: pb.extractFields(mbean, value),
 		})
 	}
@@ -50,6 +71,17 @@ func (pb *pointBuilder) Build(mbean string, value interface{}) []point {
 	return compactPoints(points)
 }
 
+// keyValues returns the composite value split by key, when the metric has a
+// key tag and reads a single attribute or path.
+func (pb *pointBuilder) keyValues(value interface{}) (map[string]interface{}, bool) {
+	if pb.metric.KeyTag == "" || len(pb.objectAttributes) > 1 {
+		return nil, false
+	}
+
+	valueMap, ok := value.(map[string]interface{})
+	return valueMap, ok
+}
+
 // extractTags generates the map of tags for a given mbean name/pattern.
 func (pb *pointBuilder) extractTags(mbean string) map[string]string {
 	propertyMap := makePropertyMap(mbean)
@@ -155,13 +187,22 @@ func (pb *pointBuilder) formatFieldName(attribute, path string) string {
 // fillFields recurses into the supplied value object, generating a named field
 // for every value it discovers.
 func (pb *pointBuilder) fillFields(name string, value interface{}, fieldMap map[string]interface{}) {
+	if values, ok := value.([]interface{}); ok {
+		if !pb.metric.FlattenArrays {
+			return
+		}
+
+		// the elements are keyed by their index
+		valueMap := make(map[string]interface{}, len(values))
+		for i, innerValue := range values {
+			valueMap[strconv.Itoa(i)] = innerValue
+		}
+		value = valueMap
+	}
+
 	if valueMap, ok := value.(map[string]interface{}); ok {
 		// keep going until we get to something that is not a map
 		for key, innerValue := range valueMap {
-			if _, ok := innerValue.([]interface{}); ok {
-				continue
-			}
-
 			var innerName string
 			if name == "" {
 				innerName = pb.metric.FieldPrefix + key
@@ -175,10 +216,6 @@ func (pb *pointBuilder) fillFields(name string, value interface{}, fieldMap map[
 		return
 	}
 
-	if _, ok := value.([]interface{}); ok {
-		return
-	}
-
 	if pb.metric.FieldName != "" {
 		name = pb.metric.FieldName
 		if prefix := pb.metric.FieldPrefix; prefix != "" {
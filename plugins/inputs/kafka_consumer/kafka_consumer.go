@@ -2,6 +2,7 @@ package kafka_consumer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -49,6 +50,19 @@ const sampleConfig = `
   ## SASL protocol version.  When connecting to Azure EventHub set to 0.
   # sasl_version = 1
 
+  ## SASL mechanism, "PLAIN" or "OAUTHBEARER".  With OAUTHBEARER the token is
+  ## obtained from the token URL with the OAuth2 client credentials grant, and
+  ## a new token is requested once it expires.
+  # sasl_mechanism = "PLAIN"
+  # sasl_oauth_token_url = "https://identityprovider/oauth2/v1/token"
+  # sasl_oauth_client_id = "clientid"
+  # sasl_oauth_client_secret = "secret"
+  # sasl_oauth_scopes = ["kafka"]
+  ## SASL extensions of the OAUTHBEARER token, such as the logicalCluster and
+  ## identityPoolId of Confluent Cloud.
+  # [inputs.kafka_consumer.sasl_extensions]
+  #   logicalCluster = "lkc-abc123"
+
   ## Name of the consumer group.
   # consumer_group = "telegraf_metrics_consumers"
 
@@ -72,6 +86,11 @@ const sampleConfig = `
   ## waiting until the next flush_interval.
   # max_undelivered_messages = 1000
 
+  ## Report the lag of the consumer group on the claimed partitions, the
+  ## high watermark minus the committed offset, in the internal metrics
+  ## (internal_kafka_consumer).
+  # consumer_lag = false
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -103,6 +122,15 @@ type KafkaConsumer struct {
 	SASLUsername           string   `toml:"sasl_username"`
 	SASLVersion            *int     `toml:"sasl_version"`
 
+	SASLMechanism         string            `toml:"sasl_mechanism"`
+	SASLOAuthTokenURL     string            `toml:"sasl_oauth_token_url"`
+	SASLOAuthClientID     string            `toml:"sasl_oauth_client_id"`
+	SASLOAuthClientSecret string            `toml:"sasl_oauth_client_secret"`
+	SASLOAuthScopes       []string          `toml:"sasl_oauth_scopes"`
+	SASLExtensions        map[string]string `toml:"sasl_extensions"`
+
+	ConsumerLag bool `toml:"consumer_lag"`
+
 	EnableTLS *bool `toml:"enable_tls"`
 	tls.ClientConfig
 
@@ -111,6 +139,7 @@ type KafkaConsumer struct {
 	ConsumerCreator ConsumerGroupCreator `toml:"-"`
 	consumer        ConsumerGroup
 	config          *sarama.Config
+	lag             *consumerLag
 
 	parser parsers.Parser
 	wg     sync.WaitGroup
@@ -188,11 +217,31 @@ func (k *KafkaConsumer) Init() error {
 		}
 	}
 
-	if k.SASLUsername != "" && k.SASLPassword != "" {
-		config.Net.SASL.User = k.SASLUsername
-		config.Net.SASL.Password = k.SASLPassword
+	switch strings.ToUpper(k.SASLMechanism) {
+	case "", sarama.SASLTypePlaintext:
+		if k.SASLUsername != "" && k.SASLPassword != "" {
+			config.Net.SASL.User = k.SASLUsername
+			config.Net.SASL.Password = k.SASLPassword
+			config.Net.SASL.Enable = true
+		}
+	case sarama.SASLTypeOAuth:
+		if k.SASLOAuthTokenURL == "" || k.SASLOAuthClientID == "" || k.SASLOAuthClientSecret == "" {
+			return errors.New("sasl_oauth_token_url, sasl_oauth_client_id and sasl_oauth_client_secret are required by OAUTHBEARER")
+		}
+		config.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		config.Net.SASL.TokenProvider = kafka.NewOAuthTokenProvider(
+			k.SASLOAuthTokenURL,
+			k.SASLOAuthClientID,
+			k.SASLOAuthClientSecret,
+			k.SASLOAuthScopes,
+			k.SASLExtensions,
+		)
 		config.Net.SASL.Enable = true
+	default:
+		return fmt.Errorf("invalid SASL mechanism %q", k.SASLMechanism)
+	}
 
+	if config.Net.SASL.Enable {
 		version, err := kafka.SASLVersion(config.Version, k.SASLVersion)
 		if err != nil {
 			return err
@@ -230,6 +279,10 @@ func (k *KafkaConsumer) Init() error {
 		k.ConsumerCreator = &SaramaCreator{}
 	}
 
+	if k.ConsumerLag {
+		k.lag = newConsumerLag(k.ConsumerGroup)
+	}
+
 	k.config = config
 	return nil
 }
@@ -256,6 +309,7 @@ func (k *KafkaConsumer) Start(acc telegraf.Accumulator) error {
 			handler := NewConsumerGroupHandler(acc, k.MaxUndeliveredMessages, k.parser)
 			handler.MaxMessageLen = k.MaxMessageLen
 			handler.TopicTag = k.TopicTag
+			handler.lag = k.lag
 			err := k.consumer.Consume(ctx, k.Topics, handler)
 			if err != nil {
 				acc.AddError(err)
@@ -279,6 +333,9 @@ func (k *KafkaConsumer) Start(acc telegraf.Accumulator) error {
 }
 
 func (k *KafkaConsumer) Gather(acc telegraf.Accumulator) error {
+	if k.lag != nil {
+		k.lag.update()
+	}
 	return nil
 }
 
@@ -312,6 +369,7 @@ type ConsumerGroupHandler struct {
 	acc    telegraf.TrackingAccumulator
 	sem    semaphore
 	parser parsers.Parser
+	lag    *consumerLag
 	wg     sync.WaitGroup
 	cancel context.CancelFunc
 
@@ -359,6 +417,7 @@ func (h *ConsumerGroupHandler) onDelivery(track telegraf.DeliveryInfo) {
 
 	if track.Delivered() {
 		msg.session.MarkMessage(msg.message, "")
+		h.lag.mark(msg.message)
 	}
 
 	delete(h.undelivered, track.ID())
@@ -384,6 +443,7 @@ func (h *ConsumerGroupHandler) release() {
 func (h *ConsumerGroupHandler) Handle(session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) error {
 	if h.MaxMessageLen != 0 && len(msg.Value) > h.MaxMessageLen {
 		session.MarkMessage(msg, "")
+		h.lag.mark(msg)
 		h.release()
 		return fmt.Errorf("message exceeds max_message_len (actual %d, max %d)",
 			len(msg.Value), h.MaxMessageLen)
@@ -413,6 +473,9 @@ func (h *ConsumerGroupHandler) Handle(session sarama.ConsumerGroupSession, msg *
 func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	ctx := session.Context()
 
+	h.lag.claim(claim)
+	defer h.lag.release(claim)
+
 	for {
 		err := h.Reserve(ctx)
 		if err != nil {
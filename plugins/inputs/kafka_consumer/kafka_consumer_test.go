@@ -111,6 +111,40 @@ func TestInit(t *testing.T) {
 			},
 			initError: true,
 		},
+		{
+			name: "sasl oauthbearer",
+			plugin: &KafkaConsumer{
+				SASLMechanism:         "OAUTHBEARER",
+				SASLOAuthTokenURL:     "https://idp/oauth2/token",
+				SASLOAuthClientID:     "telegraf",
+				SASLOAuthClientSecret: "secret",
+				SASLExtensions:        map[string]string{"logicalCluster": "lkc-abc123"},
+				Log:                   testutil.Logger{},
+			},
+			check: func(t *testing.T, plugin *KafkaConsumer) {
+				require.True(t, plugin.config.Net.SASL.Enable)
+				require.Equal(t, sarama.SASLMechanism(sarama.SASLTypeOAuth), plugin.config.Net.SASL.Mechanism)
+				require.NotNil(t, plugin.config.Net.SASL.TokenProvider)
+			},
+		},
+		{
+			name: "sasl oauthbearer without token url",
+			plugin: &KafkaConsumer{
+				SASLMechanism:         "OAUTHBEARER",
+				SASLOAuthClientID:     "telegraf",
+				SASLOAuthClientSecret: "secret",
+				Log:                   testutil.Logger{},
+			},
+			initError: true,
+		},
+		{
+			name: "invalid sasl mechanism",
+			plugin: &KafkaConsumer{
+				SASLMechanism: "GSSAPI",
+				Log:           testutil.Logger{},
+			},
+			initError: true,
+		},
 		{
 			name: "default tls without tls config",
 			plugin: &KafkaConsumer{
@@ -219,23 +253,27 @@ func (s *FakeConsumerGroupSession) Context() context.Context {
 }
 
 type FakeConsumerGroupClaim struct {
-	messages chan *sarama.ConsumerMessage
+	messages      chan *sarama.ConsumerMessage
+	topic         string
+	partition     int32
+	initialOffset int64
+	highWatermark int64
 }
 
 func (c *FakeConsumerGroupClaim) Topic() string {
-	panic("not implemented")
+	return c.topic
 }
 
 func (c *FakeConsumerGroupClaim) Partition() int32 {
-	panic("not implemented")
+	return c.partition
 }
 
 func (c *FakeConsumerGroupClaim) InitialOffset() int64 {
-	panic("not implemented")
+	return c.initialOffset
 }
 
 func (c *FakeConsumerGroupClaim) HighWaterMarkOffset() int64 {
-	panic("not implemented")
+	return c.highWatermark
 }
 
 func (c *FakeConsumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage {
@@ -394,3 +432,57 @@ func TestConsumerGroupHandler_Handle(t *testing.T) {
 		})
 	}
 }
+
+func TestConsumerLag(t *testing.T) {
+	lag := newConsumerLag("telegraf_metrics_consumers")
+	claim := &FakeConsumerGroupClaim{
+		topic:         "telegraf",
+		partition:     3,
+		initialOffset: 10,
+		highWatermark: 15,
+	}
+	lag.claim(claim)
+	p := lag.partitions[topicPartition{"telegraf", 3}]
+	require.NotNil(t, p)
+	require.Equal(t, map[string]string{
+		"consumer_group": "telegraf_metrics_consumers",
+		"topic":          "telegraf",
+		"partition":      "3",
+	}, p.lag.Tags())
+
+	lag.update()
+	require.Equal(t, int64(5), p.lag.Get())
+	require.Equal(t, int64(15), p.highWatermark.Get())
+	require.Equal(t, int64(10), p.offset.Get())
+
+	// The messages are committed out of order.
+	lag.mark(&sarama.ConsumerMessage{Topic: "telegraf", Partition: 3, Offset: 12})
+	lag.mark(&sarama.ConsumerMessage{Topic: "telegraf", Partition: 3, Offset: 11})
+	lag.mark(&sarama.ConsumerMessage{Topic: "telegraf", Partition: 1, Offset: 100})
+	claim.highWatermark = 20
+	lag.update()
+	require.Equal(t, int64(7), p.lag.Get())
+	require.Equal(t, int64(13), p.offset.Get())
+
+	lag.release(claim)
+	require.Equal(t, int64(0), p.lag.Get())
+	require.Empty(t, lag.partitions)
+}
+
+func TestConsumerLagNoCommittedOffset(t *testing.T) {
+	lag := newConsumerLag("telegraf_metrics_consumers")
+	claim := &FakeConsumerGroupClaim{
+		topic:         "metrics",
+		initialOffset: sarama.OffsetOldest,
+		highWatermark: 15,
+	}
+	lag.claim(claim)
+	p := lag.partitions[topicPartition{"metrics", 0}]
+
+	lag.update()
+	require.Equal(t, int64(0), p.highWatermark.Get())
+
+	lag.mark(&sarama.ConsumerMessage{Topic: "metrics", Offset: 4})
+	lag.update()
+	require.Equal(t, int64(10), p.lag.Get())
+}
@@ -0,0 +1,121 @@
+package kafka_consumer
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// partitionLag is the committed offset and the claim of a partition.
+type partitionLag struct {
+	claim sarama.ConsumerGroupClaim
+	// committed is the offset of the next message to consume, or negative
+	// until a message is committed.
+	committed int64
+
+	lag           selfstat.Stat
+	highWatermark selfstat.Stat
+	offset        selfstat.Stat
+}
+
+// consumerLag tracks the partitions claimed by the consumer group, the lag of
+// a partition being its high watermark minus the committed offset.  The
+// methods do nothing on a nil consumerLag.
+type consumerLag struct {
+	group string
+
+	sync.Mutex
+	partitions map[topicPartition]*partitionLag
+}
+
+func newConsumerLag(group string) *consumerLag {
+	return &consumerLag{
+		group:      group,
+		partitions: make(map[topicPartition]*partitionLag),
+	}
+}
+
+// claim starts tracking a claimed partition.
+func (l *consumerLag) claim(claim sarama.ConsumerGroupClaim) {
+	if l == nil {
+		return
+	}
+
+	tags := map[string]string{
+		"consumer_group": l.group,
+		"topic":          claim.Topic(),
+		"partition":      strconv.Itoa(int(claim.Partition())),
+	}
+	p := &partitionLag{
+		claim:         claim,
+		committed:     claim.InitialOffset(),
+		lag:           selfstat.Register("kafka_consumer", "lag", tags),
+		highWatermark: selfstat.Register("kafka_consumer", "high_watermark", tags),
+		offset:        selfstat.Register("kafka_consumer", "committed_offset", tags),
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	l.partitions[topicPartition{claim.Topic(), claim.Partition()}] = p
+}
+
+// release stops tracking a partition no longer claimed, its lag is reset as
+// it is now reported by the consumer claiming it.
+func (l *consumerLag) release(claim sarama.ConsumerGroupClaim) {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	key := topicPartition{claim.Topic(), claim.Partition()}
+	if p, ok := l.partitions[key]; ok && p.claim == claim {
+		p.lag.Set(0)
+		delete(l.partitions, key)
+	}
+}
+
+// mark records the offset of a committed message.
+func (l *consumerLag) mark(msg *sarama.ConsumerMessage) {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if p, ok := l.partitions[topicPartition{msg.Topic, msg.Partition}]; ok && msg.Offset+1 > p.committed {
+		p.committed = msg.Offset + 1
+	}
+}
+
+// update sets the lag of the partitions from their current high watermark.
+func (l *consumerLag) update() {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	for _, p := range l.partitions {
+		// The initial offset is negative if the group has not committed an
+		// offset yet, and the high watermark until a fetch is answered.
+		highWatermark := p.claim.HighWaterMarkOffset()
+		if p.committed < 0 || highWatermark <= 0 {
+			continue
+		}
+		lag := highWatermark - p.committed
+		if lag < 0 {
+			lag = 0
+		}
+		p.lag.Set(lag)
+		p.highWatermark.Set(highWatermark)
+		p.offset.Set(p.committed)
+	}
+}
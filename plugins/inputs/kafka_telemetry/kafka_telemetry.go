@@ -0,0 +1,240 @@
+package kafka_telemetry
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// defaultMaxBodySize is the default maximum request body size, in bytes.
+const defaultMaxBodySize = 32 * 1024 * 1024
+
+// kafkaPrefix is the prefix of the names of the metrics of the Kafka clients.
+const kafkaPrefix = "org.apache.kafka."
+
+// KafkaTelemetry is an input plugin receiving the client telemetry pushed to
+// the Kafka brokers (KIP-714) and forwarded as OTLP metrics.
+type KafkaTelemetry struct {
+	ServiceAddress string            `toml:"service_address"`
+	Path           string            `toml:"path"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	WriteTimeout   internal.Duration `toml:"write_timeout"`
+	MaxBodySize    internal.Size     `toml:"max_body_size"`
+	tlsint.ServerConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	wg       sync.WaitGroup
+	listener net.Listener
+	acc      telegraf.Accumulator
+}
+
+const sampleConfig = `
+  ## Address and port to host the OTLP/HTTP listener on, the client
+  ## telemetry plugin of the brokers exports the metrics to this address.
+  service_address = ":4318"
+
+  ## Path of the OTLP metrics.
+  # path = "/v1/metrics"
+
+  ## Maximum duration before timing out read of the request
+  # read_timeout = "10s"
+  ## Maximum duration before timing out write of the response
+  # write_timeout = "10s"
+
+  ## Maximum allowed http request body size in bytes.
+  # max_body_size = "32MiB"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+`
+
+func (k *KafkaTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *KafkaTelemetry) Description() string {
+	return "Receive the telemetry pushed by Kafka clients to the brokers (KIP-714)"
+}
+
+func (k *KafkaTelemetry) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Start starts the http listener service.
+func (k *KafkaTelemetry) Start(acc telegraf.Accumulator) error {
+	if k.MaxBodySize.Size == 0 {
+		k.MaxBodySize.Size = defaultMaxBodySize
+	}
+	if k.ReadTimeout.Duration < time.Second {
+		k.ReadTimeout.Duration = time.Second * 10
+	}
+	if k.WriteTimeout.Duration < time.Second {
+		k.WriteTimeout.Duration = time.Second * 10
+	}
+
+	k.acc = acc
+
+	tlsConf, err := k.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:         k.ServiceAddress,
+		Handler:      k,
+		ReadTimeout:  k.ReadTimeout.Duration,
+		WriteTimeout: k.WriteTimeout.Duration,
+		TLSConfig:    tlsConf,
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", k.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", k.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+	k.listener = listener
+
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		server.Serve(k.listener)
+	}()
+
+	k.Log.Infof("Listening on %s", listener.Addr().String())
+
+	return nil
+}
+
+// Stop cleans up all resources
+func (k *KafkaTelemetry) Stop() {
+	k.listener.Close()
+	k.wg.Wait()
+}
+
+func (k *KafkaTelemetry) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != k.Path {
+		http.NotFound(res, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.ContentLength > k.MaxBodySize.Size {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" && ct != "application/x-protobuf" {
+		http.Error(res, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		var err error
+		body, err = gzip.NewReader(req.Body)
+		if err != nil {
+			k.Log.Debug(err.Error())
+			http.Error(res, "bad request", http.StatusBadRequest)
+			return
+		}
+		defer body.Close()
+	}
+
+	body = http.MaxBytesReader(res, body, k.MaxBodySize.Size)
+	buf, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	resources, err := decodeMetricsData(buf)
+	if err != nil {
+		k.Log.Debugf("Decode error: %s", err.Error())
+		http.Error(res, "bad request", http.StatusBadRequest)
+		return
+	}
+	k.addMetrics(resources)
+
+	// The response is an empty ExportMetricsServiceResponse.
+	res.Header().Set("Content-Type", "application/x-protobuf")
+	res.WriteHeader(http.StatusOK)
+}
+
+// addMetrics adds a metric for each data point, tagged with the attributes
+// of the client instance and of the data point.
+func (k *KafkaTelemetry) addMetrics(resources []*resourceMetrics) {
+	now := time.Now()
+	for _, rm := range resources {
+		for _, m := range rm.metrics {
+			measurement, field := metricName(m.name)
+			for _, p := range m.points {
+				if p.value == nil {
+					continue
+				}
+
+				tags := make(map[string]string, len(rm.attributes)+len(p.attributes))
+				for key, value := range rm.attributes {
+					tags[key] = value
+				}
+				for key, value := range p.attributes {
+					tags[key] = value
+				}
+				fields := map[string]interface{}{field: p.value}
+
+				t := now
+				if p.timestamp != 0 {
+					t = time.Unix(0, int64(p.timestamp))
+				}
+
+				if m.monotonic {
+					k.acc.AddCounter(measurement, fields, tags, t)
+				} else {
+					k.acc.AddGauge(measurement, fields, tags, t)
+				}
+			}
+		}
+	}
+}
+
+// metricName returns the measurement and field of the metric, the group of
+// the Kafka metric names is the measurement and the rest of the name the
+// field: "org.apache.kafka.producer.record.queue.time.avg" is the
+// "record_queue_time_avg" field of "kafka_producer".
+func metricName(name string) (string, string) {
+	name = strings.TrimPrefix(name, kafkaPrefix)
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) < 2 {
+		return "kafka_telemetry", parts[0]
+	}
+	return "kafka_" + parts[0], strings.Replace(parts[1], ".", "_", -1)
+}
+
+func init() {
+	inputs.Add("kafka_telemetry", func() telegraf.Input {
+		return &KafkaTelemetry{
+			ServiceAddress: ":4318",
+			Path:           "/v1/metrics",
+		}
+	})
+}
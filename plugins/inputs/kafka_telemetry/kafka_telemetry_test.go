@@ -0,0 +1,222 @@
+package kafka_telemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKafkaTelemetry() *KafkaTelemetry {
+	return &KafkaTelemetry{
+		Log:            testutil.Logger{},
+		ServiceAddress: "localhost:0",
+		Path:           "/v1/metrics",
+	}
+}
+
+// Helpers encoding the protobuf fields of the test messages.
+
+func uvarint(v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutUvarint(buf, v)]
+}
+
+func pbKey(field, wireType int) []byte {
+	return uvarint(uint64(field<<3 | wireType))
+}
+
+func pbBytes(field int, parts ...[]byte) []byte {
+	b := bytes.Join(parts, nil)
+	return bytes.Join([][]byte{pbKey(field, wireBytes), uvarint(uint64(len(b))), b}, nil)
+}
+
+func pbString(field int, s string) []byte {
+	return pbBytes(field, []byte(s))
+}
+
+func pbVarint(field int, v uint64) []byte {
+	return append(pbKey(field, wireVarint), uvarint(v)...)
+}
+
+func pbFixed64(field int, v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, v)
+	return append(pbKey(field, wireFixed64), buf...)
+}
+
+func pbAttribute(field int, key, value string) []byte {
+	return pbBytes(field, pbString(1, key), pbBytes(2, pbString(1, value)))
+}
+
+func testMetricsData(ts time.Time) []byte {
+	gauge := pbBytes(2,
+		pbString(1, "org.apache.kafka.producer.record.queue.time.avg"),
+		pbString(3, "ms"),
+		pbBytes(5, pbBytes(1,
+			pbFixed64(3, uint64(ts.UnixNano())),
+			pbFixed64(4, math.Float64bits(4.5)),
+			pbAttribute(7, "topic", "events"),
+		)),
+	)
+	sum := pbBytes(2,
+		pbString(1, "org.apache.kafka.consumer.fetch.manager.fetch.total"),
+		pbBytes(7,
+			pbBytes(1,
+				pbFixed64(3, uint64(ts.UnixNano())),
+				pbFixed64(6, 42),
+				pbBytes(7, pbString(1, "node.id"), pbBytes(2, pbVarint(3, 1))),
+			),
+			pbVarint(2, 2),
+			pbVarint(3, 1),
+		),
+	)
+
+	return pbBytes(1,
+		pbBytes(1,
+			pbAttribute(1, "client_id", "producer-1"),
+			pbAttribute(1, "client_software_name", "apache-kafka-java"),
+		),
+		pbBytes(2, pbBytes(1, pbString(1, "kafka")), gauge, sum),
+	)
+}
+
+func testExpected(ts time.Time) []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"kafka_producer",
+			map[string]string{
+				"client_id":            "producer-1",
+				"client_software_name": "apache-kafka-java",
+				"topic":                "events",
+			},
+			map[string]interface{}{"record_queue_time_avg": 4.5},
+			ts,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"kafka_consumer",
+			map[string]string{
+				"client_id":            "producer-1",
+				"client_software_name": "apache-kafka-java",
+				"node.id":              "1",
+			},
+			map[string]interface{}{"fetch_manager_fetch_total": int64(42)},
+			ts,
+			telegraf.Counter,
+		),
+	}
+}
+
+func TestDecodeMetricsData(t *testing.T) {
+	ts := time.Unix(0, 1574265600000000000)
+	resources, err := decodeMetricsData(testMetricsData(ts))
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	require.Equal(t, "producer-1", resources[0].attributes["client_id"])
+	require.Len(t, resources[0].metrics, 2)
+	require.False(t, resources[0].metrics[0].monotonic)
+	require.True(t, resources[0].metrics[1].monotonic)
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	buf := testMetricsData(time.Now())
+	_, err := decodeMetricsData(buf[:len(buf)-3])
+	require.Error(t, err)
+}
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		name        string
+		measurement string
+		field       string
+	}{
+		{"org.apache.kafka.producer.record.queue.time.avg", "kafka_producer", "record_queue_time_avg"},
+		{"org.apache.kafka.consumer.poll.idle.ratio.avg", "kafka_consumer", "poll_idle_ratio_avg"},
+		{"uptime", "kafka_telemetry", "uptime"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			measurement, field := metricName(tt.name)
+			require.Equal(t, tt.measurement, measurement)
+			require.Equal(t, tt.field, field)
+		})
+	}
+}
+
+func TestWriteHTTP(t *testing.T) {
+	listener := newTestKafkaTelemetry()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	ts := time.Unix(0, 1574265600000000000)
+	resp, err := http.Post("http://"+listener.listener.Addr().String()+"/v1/metrics",
+		"application/x-protobuf", bytes.NewBuffer(testMetricsData(ts)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testutil.RequireMetricsEqual(t, testExpected(ts), acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestWriteHTTPGzipped(t *testing.T) {
+	listener := newTestKafkaTelemetry()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	ts := time.Unix(0, 1574265600000000000)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(testMetricsData(ts))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req, err := http.NewRequest("POST", "http://"+listener.listener.Addr().String()+"/v1/metrics", &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testutil.RequireMetricsEqual(t, testExpected(ts), acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestWriteHTTPBadRequest(t *testing.T) {
+	listener := newTestKafkaTelemetry()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	addr := "http://" + listener.listener.Addr().String()
+
+	resp, err := http.Post(addr+"/v1/metrics", "application/x-protobuf", bytes.NewBufferString("\x0a\xff"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(addr+"/v1/metrics", "application/json", bytes.NewBufferString("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Post(addr+"/write", "application/x-protobuf", bytes.NewBuffer(nil))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
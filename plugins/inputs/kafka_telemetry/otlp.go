@@ -0,0 +1,323 @@
+package kafka_telemetry
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// The pushed telemetry is an OpenTelemetry MetricsData message, KIP-714
+// clients only send gauges and sums so only the fields of these metrics are
+// decoded and the other fields are skipped.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// resourceMetrics are the metrics of a client instance.
+type resourceMetrics struct {
+	attributes map[string]string
+	metrics    []*otlpMetric
+}
+
+type otlpMetric struct {
+	name      string
+	monotonic bool
+	points    []*dataPoint
+}
+
+type dataPoint struct {
+	attributes map[string]string
+	timestamp  uint64
+	value      interface{}
+}
+
+// wireReader reads the fields of a protobuf message.
+type wireReader struct {
+	buf []byte
+}
+
+// next returns the number and wire type of the next field, or false at the
+// end of the message.
+func (r *wireReader) next() (int, int, bool, error) {
+	if len(r.buf) == 0 {
+		return 0, 0, false, nil
+	}
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return int(key >> 3), int(key & 7), true, nil
+}
+
+func (r *wireReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *wireReader) fixed64() (uint64, error) {
+	if len(r.buf) < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v, nil
+}
+
+func (r *wireReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.buf)) < n {
+		return nil, errTruncated
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *wireReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		if len(r.buf) < 4 {
+			return errTruncated
+		}
+		r.buf = r.buf[4:]
+	default:
+		return errors.New("unsupported protobuf wire type " + strconv.Itoa(wireType))
+	}
+	return err
+}
+
+// forEach calls fn with the fields of the message, the fields not consumed
+// by fn are skipped.
+func forEach(buf []byte, fn func(r *wireReader, field, wireType int) (bool, error)) error {
+	r := &wireReader{buf: buf}
+	for {
+		field, wireType, ok, err := r.next()
+		if err != nil || !ok {
+			return err
+		}
+		consumed, err := fn(r, field, wireType)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			if err := r.skip(wireType); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// decodeMetricsData decodes a MetricsData message, which has the layout of
+// the ExportMetricsServiceRequest of the OTLP exporters.
+func decodeMetricsData(buf []byte) ([]*resourceMetrics, error) {
+	var resources []*resourceMetrics
+	err := forEach(buf, func(r *wireReader, field, wireType int) (bool, error) {
+		if field != 1 || wireType != wireBytes {
+			return false, nil
+		}
+		b, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		rm, err := decodeResourceMetrics(b)
+		if err != nil {
+			return true, err
+		}
+		resources = append(resources, rm)
+		return true, nil
+	})
+	return resources, err
+}
+
+func decodeResourceMetrics(buf []byte) (*resourceMetrics, error) {
+	rm := &resourceMetrics{attributes: map[string]string{}}
+	err := forEach(buf, func(r *wireReader, field, wireType int) (bool, error) {
+		if wireType != wireBytes {
+			return false, nil
+		}
+		switch field {
+		case 1: // resource
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			return true, forEach(b, func(r *wireReader, field, wireType int) (bool, error) {
+				if field != 1 || wireType != wireBytes {
+					return false, nil
+				}
+				return true, decodeAttribute(r, rm.attributes)
+			})
+		case 2: // scope_metrics
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			return true, forEach(b, func(r *wireReader, field, wireType int) (bool, error) {
+				if field != 2 || wireType != wireBytes {
+					return false, nil
+				}
+				b, err := r.bytes()
+				if err != nil {
+					return true, err
+				}
+				m, err := decodeMetric(b)
+				if err != nil {
+					return true, err
+				}
+				rm.metrics = append(rm.metrics, m)
+				return true, nil
+			})
+		}
+		return false, nil
+	})
+	return rm, err
+}
+
+func decodeMetric(buf []byte) (*otlpMetric, error) {
+	m := &otlpMetric{}
+	err := forEach(buf, func(r *wireReader, field, wireType int) (bool, error) {
+		if wireType != wireBytes {
+			return false, nil
+		}
+		switch field {
+		case 1: // name
+			b, err := r.bytes()
+			m.name = string(b)
+			return true, err
+		case 5, 7: // gauge, sum
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			return true, forEach(b, func(r *wireReader, field, wireType int) (bool, error) {
+				switch {
+				case field == 1 && wireType == wireBytes:
+					b, err := r.bytes()
+					if err != nil {
+						return true, err
+					}
+					p, err := decodeDataPoint(b)
+					if err != nil {
+						return true, err
+					}
+					m.points = append(m.points, p)
+					return true, nil
+				case field == 3 && wireType == wireVarint: // is_monotonic
+					v, err := r.varint()
+					m.monotonic = v != 0
+					return true, err
+				}
+				return false, nil
+			})
+		}
+		return false, nil
+	})
+	return m, err
+}
+
+func decodeDataPoint(buf []byte) (*dataPoint, error) {
+	p := &dataPoint{attributes: map[string]string{}}
+	err := forEach(buf, func(r *wireReader, field, wireType int) (bool, error) {
+		switch {
+		case field == 7 && wireType == wireBytes: // attributes
+			return true, decodeAttribute(r, p.attributes)
+		case field == 3 && wireType == wireFixed64: // time_unix_nano
+			v, err := r.fixed64()
+			p.timestamp = v
+			return true, err
+		case field == 4 && wireType == wireFixed64: // as_double
+			v, err := r.fixed64()
+			p.value = math.Float64frombits(v)
+			return true, err
+		case field == 6 && wireType == wireFixed64: // as_int
+			v, err := r.fixed64()
+			p.value = int64(v)
+			return true, err
+		}
+		return false, nil
+	})
+	return p, err
+}
+
+// decodeAttribute decodes a KeyValue message into the attributes, the
+// scalar values are formatted as strings and the others are skipped.
+func decodeAttribute(r *wireReader, attributes map[string]string) error {
+	b, err := r.bytes()
+	if err != nil {
+		return err
+	}
+
+	var key, value string
+	var ok bool
+	err = forEach(b, func(r *wireReader, field, wireType int) (bool, error) {
+		if wireType != wireBytes {
+			return false, nil
+		}
+		switch field {
+		case 1: // key
+			b, err := r.bytes()
+			key = string(b)
+			return true, err
+		case 2: // value
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			value, ok, err = decodeAnyValue(b)
+			return true, err
+		}
+		return false, nil
+	})
+	if err == nil && ok && key != "" {
+		attributes[key] = value
+	}
+	return err
+}
+
+func decodeAnyValue(buf []byte) (string, bool, error) {
+	var value string
+	var ok bool
+	err := forEach(buf, func(r *wireReader, field, wireType int) (bool, error) {
+		switch {
+		case field == 1 && wireType == wireBytes: // string_value
+			b, err := r.bytes()
+			value, ok = string(b), true
+			return true, err
+		case field == 2 && wireType == wireVarint: // bool_value
+			v, err := r.varint()
+			value, ok = strconv.FormatBool(v != 0), true
+			return true, err
+		case field == 3 && wireType == wireVarint: // int_value
+			v, err := r.varint()
+			value, ok = strconv.FormatInt(int64(v), 10), true
+			return true, err
+		case field == 4 && wireType == wireFixed64: // double_value
+			v, err := r.fixed64()
+			value, ok = strconv.FormatFloat(math.Float64frombits(v), 'f', -1, 64), true
+			return true, err
+		}
+		return false, nil
+	})
+	return value, ok, err
+}
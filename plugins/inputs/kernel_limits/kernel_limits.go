@@ -0,0 +1,387 @@
+package kernel_limits
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Collect the inotify instances and watches of each user against the
+  ## per-user limits.  The file descriptors of the processes of the other
+  ## users are only readable as root.
+  # per_user = true
+
+  ## Collect the open files of the processes against their limit, grouped
+  ## by process name.
+  # per_process = false
+
+  ## Process names to collect, globs are supported.  All processes are
+  ## collected by default.
+  # processes = []
+`
+
+// KernelLimits gathers the usage of the resources limited by the kernel.
+type KernelLimits struct {
+	PerUser    bool     `toml:"per_user"`
+	PerProcess bool     `toml:"per_process"`
+	Processes  []string `toml:"processes"`
+
+	procPath      string
+	processFilter filter.Filter
+}
+
+func (k *KernelLimits) Description() string {
+	return "Gather the saturation of file handles, pids, inotify watches, entropy and other kernel limits"
+}
+
+func (k *KernelLimits) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *KernelLimits) Init() error {
+	var err error
+	k.processFilter, err = filter.Compile(k.Processes)
+	return err
+}
+
+// process is the usage of the file descriptors of a process.
+type process struct {
+	uid              string
+	name             string
+	openFiles        uint64
+	openFilesLimit   uint64
+	inotifyInstances uint64
+	inotifyWatches   uint64
+}
+
+func (k *KernelLimits) Gather(acc telegraf.Accumulator) error {
+	limits := make(map[string][2]uint64)
+
+	sys := filepath.Join(k.procPath, "sys")
+	if values, err := readUints(filepath.Join(sys, "fs", "file-nr")); err == nil && len(values) == 3 {
+		// The second value is the number of allocated but unused handles,
+		// which is always 0 since Linux 2.6
+		limits["file_handles"] = [2]uint64{values[0] - values[1], values[2]}
+	}
+	if values, err := readUints(filepath.Join(sys, "fs", "aio-nr")); err == nil {
+		if max, err := readUints(filepath.Join(sys, "fs", "aio-max-nr")); err == nil {
+			limits["aio_requests"] = [2]uint64{values[0], max[0]}
+		}
+	}
+	if tasks, err := readTasks(filepath.Join(k.procPath, "loadavg")); err == nil {
+		if max, err := readUints(filepath.Join(sys, "kernel", "pid_max")); err == nil {
+			limits["pids"] = [2]uint64{tasks, max[0]}
+		}
+		if max, err := readUints(filepath.Join(sys, "kernel", "threads-max")); err == nil {
+			limits["threads"] = [2]uint64{tasks, max[0]}
+		}
+	}
+	if values, err := readUints(filepath.Join(sys, "kernel", "random", "entropy_avail")); err == nil {
+		if max, err := readUints(filepath.Join(sys, "kernel", "random", "poolsize")); err == nil {
+			limits["entropy"] = [2]uint64{values[0], max[0]}
+		}
+	}
+
+	if k.PerUser || k.PerProcess {
+		processes, err := k.readProcesses()
+		if err != nil {
+			acc.AddError(err)
+		}
+		if k.PerUser {
+			k.addUsers(acc, processes, limits)
+		}
+		if k.PerProcess {
+			k.addProcesses(acc, processes)
+		}
+	}
+
+	for name, limit := range limits {
+		fields := map[string]interface{}{
+			"used": limit[0],
+			"max":  limit[1],
+		}
+		if limit[1] > 0 {
+			fields["used_percent"] = 100 * float64(limit[0]) / float64(limit[1])
+		}
+		acc.AddGauge("kernel_limits", fields, map[string]string{"limit": name})
+	}
+	return nil
+}
+
+// addUsers adds the inotify usage of each user, and the usage of the user
+// closest to the limits to the system limits.
+func (k *KernelLimits) addUsers(acc telegraf.Accumulator, processes []*process, limits map[string][2]uint64) {
+	inotify := filepath.Join(k.procPath, "sys", "fs", "inotify")
+	maxInstances, err := readUints(filepath.Join(inotify, "max_user_instances"))
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+	maxWatches, err := readUints(filepath.Join(inotify, "max_user_watches"))
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+
+	users := make(map[string]*process)
+	for _, p := range processes {
+		u, ok := users[p.uid]
+		if !ok {
+			u = &process{uid: p.uid}
+			users[p.uid] = u
+		}
+		u.inotifyInstances += p.inotifyInstances
+		u.inotifyWatches += p.inotifyWatches
+	}
+
+	var mostInstances, mostWatches uint64
+	for uid, u := range users {
+		if u.inotifyInstances == 0 {
+			continue
+		}
+		if u.inotifyInstances > mostInstances {
+			mostInstances = u.inotifyInstances
+		}
+		if u.inotifyWatches > mostWatches {
+			mostWatches = u.inotifyWatches
+		}
+
+		fields := map[string]interface{}{
+			"inotify_instances":     u.inotifyInstances,
+			"inotify_instances_max": maxInstances[0],
+			"inotify_watches":       u.inotifyWatches,
+			"inotify_watches_max":   maxWatches[0],
+		}
+		if maxInstances[0] > 0 {
+			fields["inotify_instances_used_percent"] = 100 * float64(u.inotifyInstances) / float64(maxInstances[0])
+		}
+		if maxWatches[0] > 0 {
+			fields["inotify_watches_used_percent"] = 100 * float64(u.inotifyWatches) / float64(maxWatches[0])
+		}
+		acc.AddGauge("kernel_limits_user", fields, map[string]string{"uid": uid})
+	}
+
+	limits["inotify_instances"] = [2]uint64{mostInstances, maxInstances[0]}
+	limits["inotify_watches"] = [2]uint64{mostWatches, maxWatches[0]}
+}
+
+// addProcesses adds the open files of the processes grouped by name, with
+// the usage of the process of the group closest to its limit.
+func (k *KernelLimits) addProcesses(acc telegraf.Accumulator, processes []*process) {
+	type group struct {
+		processes uint64
+		openFiles uint64
+		worst     *process
+	}
+
+	groups := make(map[string]*group)
+	for _, p := range processes {
+		if k.processFilter != nil && !k.processFilter.Match(p.name) {
+			continue
+		}
+		g, ok := groups[p.name]
+		if !ok {
+			g = &group{}
+			groups[p.name] = g
+		}
+		g.processes++
+		g.openFiles += p.openFiles
+		if g.worst == nil || usage(p.openFiles, p.openFilesLimit) > usage(g.worst.openFiles, g.worst.openFilesLimit) {
+			g.worst = p
+		}
+	}
+
+	for name, g := range groups {
+		fields := map[string]interface{}{
+			"processes":            g.processes,
+			"open_files":           g.openFiles,
+			"max_open_files":       g.worst.openFiles,
+			"max_open_files_limit": g.worst.openFilesLimit,
+		}
+		if g.worst.openFilesLimit > 0 {
+			fields["max_open_files_used_percent"] = usage(g.worst.openFiles, g.worst.openFilesLimit)
+		}
+		acc.AddGauge("kernel_limits_process", fields, map[string]string{"process": name})
+	}
+}
+
+func usage(used, limit uint64) float64 {
+	if limit == 0 {
+		return 0
+	}
+	return 100 * float64(used) / float64(limit)
+}
+
+// readProcesses returns the file descriptor usage of the processes, the
+// processes exiting while being read are skipped.
+func (k *KernelLimits) readProcesses() ([]*process, error) {
+	d, err := os.Open(k.procPath)
+	if err != nil {
+		return nil, err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []*process
+	for _, name := range names {
+		if _, err := strconv.Atoi(name); err != nil {
+			continue
+		}
+		p, err := k.readProcess(filepath.Join(k.procPath, name))
+		if err != nil {
+			continue
+		}
+		processes = append(processes, p)
+	}
+	return processes, nil
+}
+
+func (k *KernelLimits) readProcess(dir string) (*process, error) {
+	p := &process{}
+	status, err := ioutil.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		switch parts[0] {
+		case "Name:":
+			p.name = parts[1]
+		case "Uid:":
+			p.uid = parts[1]
+		}
+	}
+
+	fdDir := filepath.Join(dir, "fd")
+	d, err := os.Open(fdDir)
+	if err != nil {
+		// The file descriptors of the processes of other users
+		return p, nil
+	}
+	fds, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return p, nil
+	}
+	p.openFiles = uint64(len(fds))
+
+	if k.PerProcess {
+		p.openFilesLimit, _ = readOpenFilesLimit(filepath.Join(dir, "limits"))
+	}
+	if k.PerUser {
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd))
+			if err != nil || target != "anon_inode:inotify" {
+				continue
+			}
+			p.inotifyInstances++
+			p.inotifyWatches += countInotifyWatches(filepath.Join(dir, "fdinfo", fd))
+		}
+	}
+	return p, nil
+}
+
+// countInotifyWatches returns the number of watches of the fdinfo of an
+// inotify instance, which has a line starting with "inotify wd:" for each
+// watch.
+func countInotifyWatches(path string) uint64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	var watches uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "inotify wd:") {
+			watches++
+		}
+	}
+	return watches
+}
+
+// readOpenFilesLimit returns the soft limit of the open files of the limits
+// file, which has a line like "Max open files  1024  524288  files".
+func readOpenFilesLimit(path string) (uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		parts := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(parts) == 0 || parts[0] == "unlimited" {
+			return 0, nil
+		}
+		return strconv.ParseUint(parts[0], 10, 64)
+	}
+	return 0, fmt.Errorf("no open files limit in %s", path)
+}
+
+// readTasks returns the number of tasks of the loadavg file, which has the
+// number of running and total tasks as fourth value, such as "2/843".
+func readTasks(path string) (uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	parts := strings.Fields(string(content))
+	if len(parts) < 4 {
+		return 0, fmt.Errorf("invalid %s", path)
+	}
+	tasks := strings.SplitN(parts[3], "/", 2)
+	if len(tasks) != 2 {
+		return 0, fmt.Errorf("invalid %s", path)
+	}
+	return strconv.ParseUint(tasks[1], 10, 64)
+}
+
+func readUints(path string) ([]uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var values []uint64
+	for _, s := range strings.Fields(string(content)) {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s failed: %v", path, err)
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	return values, nil
+}
+
+func init() {
+	inputs.Add("kernel_limits", func() telegraf.Input {
+		procPath := "/proc"
+		if hostProc := os.Getenv("HOST_PROC"); hostProc != "" {
+			procPath = hostProc
+		}
+		return &KernelLimits{
+			PerUser:  true,
+			procPath: procPath,
+		}
+	})
+}
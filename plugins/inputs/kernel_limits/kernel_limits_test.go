@@ -0,0 +1,172 @@
+package kernel_limits
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const limits = `Limit                     Soft Limit           Hard Limit           Units
+Max processes             63458                63458                processes
+Max open files            %s                 524288               files
+`
+
+const inotifyFdinfo = `pos:	0
+flags:	00
+mnt_id:	15
+ino:	1057
+inotify wd:2 ino:2 sdev:800001 mask:fc6 ignored_mask:0 fhandle-bytes:8 fhandle-type:1 f_handle:020000000000000000000000
+inotify wd:1 ino:1 sdev:800001 mask:fc6 ignored_mask:0 fhandle-bytes:8 fhandle-type:1 f_handle:010000000000000000000000
+`
+
+func write(t *testing.T, root, path, content string) {
+	path = filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func symlink(t *testing.T, root, path, target string) {
+	path = filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.Symlink(target, path))
+}
+
+func writeProcess(t *testing.T, root, pid, name, uid, openFilesLimit string, files []string) {
+	write(t, root, pid+"/status", "Name:\t"+name+"\nUmask:\t0022\nState:\tS (sleeping)\nUid:\t"+uid+"\t"+uid+"\t"+uid+"\t"+uid+"\n")
+	write(t, root, pid+"/limits", fmt.Sprintf(limits, openFilesLimit))
+	for i, target := range files {
+		fd := strconv.Itoa(i)
+		symlink(t, root, pid+"/fd/"+fd, target)
+		write(t, root, pid+"/fdinfo/"+fd, inotifyFdinfo)
+	}
+}
+
+func TestGather(t *testing.T) {
+	root, err := ioutil.TempDir("", "kernel_limits")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	write(t, root, "sys/fs/file-nr", "9024\t0\t1000000\n")
+	write(t, root, "sys/fs/aio-nr", "0\n")
+	write(t, root, "sys/fs/aio-max-nr", "65536\n")
+	write(t, root, "sys/fs/inotify/max_user_instances", "128\n")
+	write(t, root, "sys/fs/inotify/max_user_watches", "8192\n")
+	write(t, root, "sys/kernel/pid_max", "32768\n")
+	write(t, root, "sys/kernel/threads-max", "126916\n")
+	write(t, root, "sys/kernel/random/entropy_avail", "256\n")
+	write(t, root, "sys/kernel/random/poolsize", "256\n")
+	write(t, root, "loadavg", "0.08 0.12 0.10 2/1024 12345\n")
+
+	writeProcess(t, root, "1", "systemd", "0", "1024", []string{"/dev/null", "anon_inode:inotify", "socket:[1234]"})
+	writeProcess(t, root, "100", "nginx", "33", "1024", []string{"/dev/null", "socket:[1]", "socket:[2]", "socket:[3]"})
+	writeProcess(t, root, "101", "nginx", "33", "8", []string{"/dev/null", "socket:[4]"})
+	writeProcess(t, root, "200", "code", "1000", "unlimited", []string{"anon_inode:inotify", "anon_inode:inotify"})
+
+	plugin := &KernelLimits{PerUser: true, PerProcess: true, procPath: root}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	limit := func(name string, used, max uint64) telegraf.Metric {
+		return testutil.MustMetric("kernel_limits",
+			map[string]string{"limit": name},
+			map[string]interface{}{
+				"used":         used,
+				"max":          max,
+				"used_percent": 100 * float64(used) / float64(max),
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		)
+	}
+	user := func(uid string, instances, watches uint64) telegraf.Metric {
+		return testutil.MustMetric("kernel_limits_user",
+			map[string]string{"uid": uid},
+			map[string]interface{}{
+				"inotify_instances":              instances,
+				"inotify_instances_max":          uint64(128),
+				"inotify_instances_used_percent": 100 * float64(instances) / 128,
+				"inotify_watches":                watches,
+				"inotify_watches_max":            uint64(8192),
+				"inotify_watches_used_percent":   100 * float64(watches) / 8192,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		)
+	}
+
+	expected := []telegraf.Metric{
+		limit("file_handles", 9024, 1000000),
+		limit("aio_requests", 0, 65536),
+		limit("pids", 1024, 32768),
+		limit("threads", 1024, 126916),
+		limit("entropy", 256, 256),
+		limit("inotify_instances", 2, 128),
+		limit("inotify_watches", 4, 8192),
+		user("0", 1, 2),
+		user("1000", 2, 4),
+		testutil.MustMetric("kernel_limits_process",
+			map[string]string{"process": "systemd"},
+			map[string]interface{}{
+				"processes":                   uint64(1),
+				"open_files":                  uint64(3),
+				"max_open_files":              uint64(3),
+				"max_open_files_limit":        uint64(1024),
+				"max_open_files_used_percent": 100 * 3.0 / 1024,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("kernel_limits_process",
+			map[string]string{"process": "nginx"},
+			map[string]interface{}{
+				"processes":                   uint64(2),
+				"open_files":                  uint64(6),
+				"max_open_files":              uint64(2),
+				"max_open_files_limit":        uint64(8),
+				"max_open_files_used_percent": 25.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("kernel_limits_process",
+			map[string]string{"process": "code"},
+			map[string]interface{}{
+				"processes":            uint64(1),
+				"open_files":           uint64(2),
+				"max_open_files":       uint64(2),
+				"max_open_files_limit": uint64(0),
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherProcessFilter(t *testing.T) {
+	root, err := ioutil.TempDir("", "kernel_limits")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	writeProcess(t, root, "100", "nginx", "33", "1024", []string{"/dev/null"})
+	writeProcess(t, root, "200", "code", "1000", "1024", []string{"/dev/null"})
+
+	plugin := &KernelLimits{PerProcess: true, Processes: []string{"ng*"}, procPath: root}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, "nginx", acc.GetTelegrafMetrics()[0].Tags()["process"])
+}
@@ -0,0 +1,239 @@
+package kinesis_consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+const (
+	// shardRefreshInterval is how often the shard list is checked for new
+	// shards created by resharding the stream.
+	shardRefreshInterval = time.Minute
+
+	// subscribeRetryInterval is the delay before retrying a failed
+	// subscription; a shard can only be subscribed to once every 5 seconds.
+	subscribeRetryInterval = 5 * time.Second
+)
+
+// fanOut reads the stream using an enhanced fan-out consumer, which pushes
+// records to the client over a dedicated throughput allocation per shard.
+// Each shard is read by its own subscription, records are checkpointed using
+// the same delivery tracking as the polling consumer.
+func (k *KinesisConsumer) fanOut(ctx context.Context, client *kinesis.Kinesis) error {
+	consumerARN, err := k.registerConsumer(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	shards := make(map[string]bool)
+	for {
+		shardIDs, err := k.listShards(ctx, client)
+		if err != nil {
+			k.Log.Errorf("Unable to list shards: %v", err)
+		}
+
+		for _, shardID := range shardIDs {
+			if shards[shardID] {
+				continue
+			}
+			shards[shardID] = true
+
+			k.wg.Add(1)
+			go func(shardID string) {
+				defer k.wg.Done()
+				k.subscribe(ctx, client, consumerARN, shardID)
+			}(shardID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(shardRefreshInterval):
+		}
+	}
+}
+
+// registerConsumer registers the enhanced fan-out consumer with the stream,
+// if it is not yet registered, and waits for it to become active.
+func (k *KinesisConsumer) registerConsumer(ctx context.Context, client *kinesis.Kinesis) (string, error) {
+	summary, err := client.DescribeStreamSummaryWithContext(ctx, &kinesis.DescribeStreamSummaryInput{
+		StreamName: aws.String(k.StreamName),
+	})
+	if err != nil {
+		return "", err
+	}
+	streamARN := summary.StreamDescriptionSummary.StreamARN
+
+	_, err = client.RegisterStreamConsumerWithContext(ctx, &kinesis.RegisterStreamConsumerInput{
+		ConsumerName: aws.String(k.EnhancedFanOutConsumer),
+		StreamARN:    streamARN,
+	})
+	if err != nil {
+		// The consumer is already registered, possibly by another instance.
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != kinesis.ErrCodeResourceInUseException {
+			return "", err
+		}
+	}
+
+	for {
+		desc, err := client.DescribeStreamConsumerWithContext(ctx, &kinesis.DescribeStreamConsumerInput{
+			ConsumerName: aws.String(k.EnhancedFanOutConsumer),
+			StreamARN:    streamARN,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		if aws.StringValue(desc.ConsumerDescription.ConsumerStatus) == kinesis.ConsumerStatusActive {
+			return aws.StringValue(desc.ConsumerDescription.ConsumerARN), nil
+		}
+
+		k.Log.Debugf("Waiting for consumer %q to become active", k.EnhancedFanOutConsumer)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (k *KinesisConsumer) listShards(ctx context.Context, client *kinesis.Kinesis) ([]string, error) {
+	var shardIDs []string
+	input := &kinesis.ListShardsInput{
+		StreamName: aws.String(k.StreamName),
+	}
+	for {
+		out, err := client.ListShardsWithContext(ctx, input)
+		if err != nil {
+			return shardIDs, err
+		}
+
+		for _, shard := range out.Shards {
+			shardIDs = append(shardIDs, aws.StringValue(shard.ShardId))
+		}
+
+		if out.NextToken == nil {
+			return shardIDs, nil
+		}
+		// The stream name must not be set when continuing a listing.
+		input = &kinesis.ListShardsInput{
+			NextToken: out.NextToken,
+		}
+	}
+}
+
+// subscribe reads the shard until it is closed or the context is cancelled.
+// Subscriptions expire after 5 minutes and are renewed from the last
+// received record.
+func (k *KinesisConsumer) subscribe(ctx context.Context, client *kinesis.Kinesis, consumerARN, shardID string) {
+	sequenceNumber, err := k.checkpoint.Get(k.StreamName, shardID)
+	if err != nil {
+		k.Log.Errorf("Unable to get checkpoint for shard %q: %v", shardID, err)
+	}
+
+	for {
+		position := &kinesis.StartingPosition{
+			Type: aws.String(k.ShardIteratorType),
+		}
+		if sequenceNumber != "" {
+			position.Type = aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber)
+			position.SequenceNumber = aws.String(sequenceNumber)
+		}
+
+		out, err := client.SubscribeToShardWithContext(ctx, &kinesis.SubscribeToShardInput{
+			ConsumerARN:      aws.String(consumerARN),
+			ShardId:          aws.String(shardID),
+			StartingPosition: position,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			k.Log.Errorf("Unable to subscribe to shard %q: %v", shardID, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(subscribeRetryInterval):
+			}
+			continue
+		}
+
+		var closed bool
+		sequenceNumber, closed = k.readEvents(ctx, out.EventStream, shardID, sequenceNumber)
+		if closed {
+			k.Log.Debugf("Shard %q is closed", shardID)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+// readEvents processes the events of a subscription, it returns the last
+// sequence number received and whether the end of the shard was reached.
+func (k *KinesisConsumer) readEvents(
+	ctx context.Context,
+	stream *kinesis.SubscribeToShardEventStream,
+	shardID string,
+	sequenceNumber string,
+) (string, bool) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-done:
+		}
+	}()
+	defer stream.Close()
+
+	for event := range stream.Events() {
+		e, ok := event.(*kinesis.SubscribeToShardEvent)
+		if !ok {
+			continue
+		}
+
+		for _, r := range e.Records {
+			select {
+			case <-ctx.Done():
+				return sequenceNumber, false
+			case k.sem <- struct{}{}:
+			}
+
+			// Record the checkpoint so it can be stored once the metrics
+			// have been delivered.
+			err := k.Set(k.StreamName, shardID, aws.StringValue(r.SequenceNumber))
+			if err != nil {
+				<-k.sem
+				k.acc.AddError(err)
+				continue
+			}
+
+			err = k.onRecord(k.acc, r.Data, aws.StringValue(r.SequenceNumber))
+			if err != nil {
+				<-k.sem
+				k.acc.AddError(err)
+			}
+			sequenceNumber = aws.StringValue(r.SequenceNumber)
+		}
+
+		if e.ContinuationSequenceNumber == nil {
+			return sequenceNumber, true
+		}
+	}
+
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		k.Log.Errorf("Error reading shard %q: %v", shardID, err)
+	}
+	return sequenceNumber, false
+}
@@ -36,6 +36,7 @@ type (
 		EndpointURL            string    `toml:"endpoint_url"`
 		StreamName             string    `toml:"streamname"`
 		ShardIteratorType      string    `toml:"shard_iterator_type"`
+		EnhancedFanOutConsumer string    `toml:"enhanced_fan_out_consumer"`
 		DynamoDB               *DynamoDB `toml:"checkpoint_dynamodb"`
 		MaxUndeliveredMessages int       `toml:"max_undelivered_messages"`
 
@@ -102,6 +103,12 @@ var sampleConfig = `
   ## Shard iterator type (only 'TRIM_HORIZON' and 'LATEST' currently supported)
   # shard_iterator_type = "TRIM_HORIZON"
 
+  ## Name of an enhanced fan-out consumer to read the stream with.  When set,
+  ## the consumer is registered with the stream if it does not exist and
+  ## records are pushed to Telegraf using a dedicated throughput of 2MB/s per
+  ## shard, instead of being polled.
+  # enhanced_fan_out_consumer = ""
+
   ## Maximum messages to read from the broker that have not been written by an
   ## output.  For best throughput set based on the number of metrics within
   ## each message and the size of the output's metric_batch_size.
@@ -175,17 +182,19 @@ func (k *KinesisConsumer) connect(ac telegraf.Accumulator) error {
 		}
 	}
 
-	cons, err := consumer.New(
-		k.StreamName,
-		consumer.WithClient(client),
-		consumer.WithShardIteratorType(k.ShardIteratorType),
-		consumer.WithCheckpoint(k),
-	)
-	if err != nil {
-		return err
-	}
+	if k.EnhancedFanOutConsumer == "" {
+		cons, err := consumer.New(
+			k.StreamName,
+			consumer.WithClient(client),
+			consumer.WithShardIteratorType(k.ShardIteratorType),
+			consumer.WithCheckpoint(k),
+		)
+		if err != nil {
+			return err
+		}
 
-	k.cons = cons
+		k.cons = cons
+	}
 
 	k.acc = ac.WithTracking(k.MaxUndeliveredMessages)
 	k.records = make(map[telegraf.TrackingID]string, k.MaxUndeliveredMessages)
@@ -201,6 +210,18 @@ func (k *KinesisConsumer) connect(ac telegraf.Accumulator) error {
 		k.onDelivery(ctx)
 	}()
 
+	if k.EnhancedFanOutConsumer != "" {
+		k.wg.Add(1)
+		go func() {
+			defer k.wg.Done()
+			err := k.fanOut(ctx, client)
+			if err != nil {
+				k.Log.Errorf("Enhanced fan-out consumer encountered an error: %s", err.Error())
+			}
+		}()
+		return nil
+	}
+
 	k.wg.Add(1)
 	go func() {
 		defer k.wg.Done()
@@ -239,14 +260,18 @@ func (k *KinesisConsumer) Start(ac telegraf.Accumulator) error {
 }
 
 func (k *KinesisConsumer) onMessage(acc telegraf.TrackingAccumulator, r *consumer.Record) error {
-	metrics, err := k.parser.Parse(r.Data)
+	return k.onRecord(acc, r.Data, *r.SequenceNumber)
+}
+
+func (k *KinesisConsumer) onRecord(acc telegraf.TrackingAccumulator, data []byte, sequenceNumber string) error {
+	metrics, err := k.parser.Parse(data)
 	if err != nil {
 		return err
 	}
 
 	k.recordsTex.Lock()
 	id := acc.AddTrackingMetricGroup(metrics)
-	k.records[id] = *r.SequenceNumber
+	k.records[id] = sequenceNumber
 	k.recordsTex.Unlock()
 
 	return nil
@@ -308,7 +333,8 @@ func (k *KinesisConsumer) Stop() {
 }
 
 func (k *KinesisConsumer) Gather(acc telegraf.Accumulator) error {
-	if k.cons == nil {
+	// The enhanced fan-out consumer reconnects on its own.
+	if k.cons == nil && k.EnhancedFanOutConsumer == "" {
 		return k.connect(acc)
 	}
 	k.lastSeqNum = maxSeq
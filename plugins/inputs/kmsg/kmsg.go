@@ -0,0 +1,408 @@
+package kmsg
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const sampleConfig = `
+  ## Read the messages already in the kernel log buffer when starting,
+  ## instead of only the new messages.
+  # from_beginning = false
+
+  ## Emit the kernel messages up to the priority as kmsg metrics, one of
+  ## "emerg", "alert", "crit", "err", "warning", "notice", "info" or
+  ## "debug".  Set messages to false to only emit the events.
+  # messages = true
+  # max_priority = "warning"
+
+  ## Events to detect in the kernel messages, whatever their priority:
+  ## "oom_kill", "hardware_error" (MCE and EDAC) and "segfault".
+  # events = ["oom_kill", "hardware_error", "segfault"]
+
+  ## Maximum number of metrics emitted per second, the other messages are
+  ## dropped and counted.  0 means unlimited.
+  # rate_limit = 100
+`
+
+var now = time.Now
+
+var priorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console",
+	"solaris-cron", "local0", "local1", "local2", "local3", "local4",
+	"local5", "local6", "local7",
+}
+
+// Kmsg reads the kernel log from /dev/kmsg.
+type Kmsg struct {
+	FromBeginning bool     `toml:"from_beginning"`
+	Messages      bool     `toml:"messages"`
+	MaxPriority   string   `toml:"max_priority"`
+	Events        []string `toml:"events"`
+	RateLimit     int      `toml:"rate_limit"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	path        string
+	procPath    string
+	maxPriority int
+	events      map[string]bool
+	bootTime    time.Time
+
+	mu       sync.Mutex
+	records  uint64
+	emitted  uint64
+	dropped  uint64
+	second   time.Time
+	inSecond int
+
+	oomKills map[string]map[string]string
+
+	acc  telegraf.Accumulator
+	file *os.File
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (k *Kmsg) Description() string {
+	return "Read the kernel log and emit OOM kill, hardware error and segfault events"
+}
+
+func (k *Kmsg) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *Kmsg) Init() error {
+	k.maxPriority = -1
+	for i, p := range priorities {
+		if p == k.MaxPriority {
+			k.maxPriority = i
+		}
+	}
+	if k.maxPriority < 0 {
+		return fmt.Errorf("unknown priority %q", k.MaxPriority)
+	}
+
+	k.events = make(map[string]bool)
+	for _, event := range k.Events {
+		switch event {
+		case "oom_kill", "hardware_error", "segfault":
+			k.events[event] = true
+		default:
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	k.oomKills = make(map[string]map[string]string)
+	return nil
+}
+
+// Gather adds the statistics of the reader.
+func (k *Kmsg) Gather(acc telegraf.Accumulator) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	acc.AddCounter("kmsg_stats", map[string]interface{}{
+		"records": k.records,
+		"emitted": k.emitted,
+		"dropped": k.dropped,
+	}, nil)
+	return nil
+}
+
+// record is a message of the kernel log, with the lines of its dictionary
+// such as "SUBSYSTEM=pci".
+type record struct {
+	priority  int
+	facility  int
+	seq       uint64
+	timestamp time.Duration
+	message   string
+	dict      map[string]string
+}
+
+// parseRecord parses a record of /dev/kmsg, which has a header such as
+// "6,339,5140900,-;" followed by the message and the dictionary lines
+// starting with a space.
+func parseRecord(lines []string) (*record, error) {
+	i := strings.IndexByte(lines[0], ';')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid record %q", lines[0])
+	}
+	header := strings.Split(lines[0][:i], ",")
+	if len(header) < 3 {
+		return nil, fmt.Errorf("invalid record header %q", lines[0][:i])
+	}
+	prefix, err := strconv.Atoi(header[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid record priority %q", header[0])
+	}
+	seq, err := strconv.ParseUint(header[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record sequence %q", header[1])
+	}
+	usec, err := strconv.ParseInt(header[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record timestamp %q", header[2])
+	}
+
+	r := &record{
+		priority:  prefix & 7,
+		facility:  prefix >> 3,
+		seq:       seq,
+		timestamp: time.Duration(usec) * time.Microsecond,
+		message:   lines[0][i+1:],
+	}
+	for _, line := range lines[1:] {
+		kv := strings.SplitN(strings.TrimPrefix(line, " "), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if r.dict == nil {
+			r.dict = make(map[string]string)
+		}
+		r.dict[kv[0]] = kv[1]
+	}
+	return r, nil
+}
+
+// splitRecords splits the data read into the lines of the records, the
+// dictionary lines start with a space.
+func splitRecords(data string) [][]string {
+	var records [][]string
+	for _, line := range strings.Split(data, "\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] == ' ' && len(records) > 0 {
+			records[len(records)-1] = append(records[len(records)-1], line)
+			continue
+		}
+		records = append(records, []string{line})
+	}
+	return records
+}
+
+// handle emits the metrics of the record.
+func (k *Kmsg) handle(r *record) {
+	k.mu.Lock()
+	k.records++
+	k.mu.Unlock()
+
+	t := k.bootTime.Add(r.timestamp)
+	if k.bootTime.IsZero() {
+		t = now()
+	}
+
+	if k.Messages && r.priority <= k.maxPriority {
+		tags := map[string]string{
+			"priority": priorities[r.priority],
+			"facility": facility(r.facility),
+		}
+		for key, tag := range map[string]string{"SUBSYSTEM": "subsystem", "DEVICE": "device"} {
+			if v, ok := r.dict[key]; ok {
+				tags[tag] = v
+			}
+		}
+		k.add("kmsg", map[string]interface{}{
+			"message": r.message,
+			"seq":     r.seq,
+		}, tags, t)
+	}
+
+	if k.events["oom_kill"] {
+		k.handleOOMKill(r.message, t)
+	}
+	if k.events["hardware_error"] {
+		if tags, fields := parseHardwareError(r.message); tags != nil {
+			k.add("kmsg_hardware_error", fields, tags, t)
+		}
+	}
+	if k.events["segfault"] {
+		if tags, fields := parseSegfault(r.message); tags != nil {
+			k.add("kmsg_segfault", fields, tags, t)
+		}
+	}
+}
+
+// add adds the metric unless the rate limit is reached.
+func (k *Kmsg) add(measurement string, fields map[string]interface{}, tags map[string]string, t time.Time) {
+	k.mu.Lock()
+	if k.RateLimit > 0 {
+		second := now().Truncate(time.Second)
+		if !second.Equal(k.second) {
+			k.second = second
+			k.inSecond = 0
+		}
+		if k.inSecond >= k.RateLimit {
+			k.dropped++
+			k.mu.Unlock()
+			return
+		}
+		k.inSecond++
+	}
+	k.emitted++
+	k.mu.Unlock()
+
+	k.acc.AddFields(measurement, fields, tags, t)
+}
+
+func facility(f int) string {
+	if f < len(facilities) {
+		return facilities[f]
+	}
+	return strconv.Itoa(f)
+}
+
+var (
+	oomKillRegex   = regexp.MustCompile(`^oom-kill:(.*)$`)
+	oomKilledRegex = regexp.MustCompile(`^(Memory cgroup out of memory|Out of memory)(?: \([^)]*\))?: Kill(?:ed)? process (\d+) \((.*?)\)(.*)$`)
+	oomValueRegex  = regexp.MustCompile(`([\w-]+):(-?\d+)(kB)?`)
+)
+
+// handleOOMKill emits the OOM kill of the messages, such as
+// "Out of memory: Killed process 1234 (stress) total-vm:...", with the
+// constraint and memory cgroup of the preceding "oom-kill:" message.
+func (k *Kmsg) handleOOMKill(message string, t time.Time) {
+	if m := oomKillRegex.FindStringSubmatch(message); m != nil {
+		info := make(map[string]string)
+		for _, kv := range strings.Split(m[1], ",") {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) == 2 {
+				info[parts[0]] = parts[1]
+			}
+		}
+		if pid, ok := info["pid"]; ok {
+			k.oomKills[pid] = info
+		}
+		return
+	}
+
+	m := oomKilledRegex.FindStringSubmatch(message)
+	if m == nil {
+		return
+	}
+	pid, _ := strconv.ParseInt(m[2], 10, 64)
+	tags := map[string]string{
+		"process": m[3],
+		"cgroup":  strconv.FormatBool(m[1] == "Memory cgroup out of memory"),
+	}
+	if info, ok := k.oomKills[m[2]]; ok {
+		delete(k.oomKills, m[2])
+		if c, ok := info["constraint"]; ok {
+			tags["constraint"] = strings.ToLower(strings.TrimPrefix(c, "CONSTRAINT_"))
+		}
+		if memcg, ok := info["task_memcg"]; ok {
+			tags["memcg"] = memcg
+		}
+	}
+
+	fields := map[string]interface{}{"pid": pid}
+	for _, v := range oomValueRegex.FindAllStringSubmatch(m[4], -1) {
+		value, err := strconv.ParseInt(v[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		name := strings.ToLower(strings.Replace(v[1], "-", "_", -1))
+		if v[3] == "kB" {
+			name += "_bytes"
+			value *= 1024
+		}
+		fields[name] = value
+	}
+	k.add("kmsg_oom_kill", fields, tags, t)
+}
+
+var (
+	mceRegex  = regexp.MustCompile(`\[Hardware Error\]: (.*)$`)
+	mceCPU    = regexp.MustCompile(`CPU (\d+)`)
+	mceBank   = regexp.MustCompile(`Bank (\d+)`)
+	edacRegex = regexp.MustCompile(`^EDAC (MC\d+): (\d+) (CE|UE) (.*)$`)
+)
+
+// parseHardwareError returns the hardware error of the message, either a
+// machine check such as "mce: [Hardware Error]: CPU 0: Machine Check: 0 Bank
+// 5: be00000000800400" or a memory error such as "EDAC MC0: 1 CE memory read
+// error on DIMM_A1".
+func parseHardwareError(message string) (map[string]string, map[string]interface{}) {
+	if m := edacRegex.FindStringSubmatch(message); m != nil {
+		count, _ := strconv.ParseInt(m[2], 10, 64)
+		return map[string]string{
+				"source":     "edac",
+				"controller": m[1],
+				"type":       strings.ToLower(m[3]),
+			}, map[string]interface{}{
+				"count":   count,
+				"message": m[4],
+			}
+	}
+
+	if m := mceRegex.FindStringSubmatch(message); m != nil {
+		source := "mce"
+		// Errors reported by the firmware, such as "{1}[Hardware Error]:"
+		if strings.HasPrefix(message, "{") {
+			source = "ghes"
+		}
+		tags := map[string]string{"source": source}
+		if cpu := mceCPU.FindStringSubmatch(m[1]); cpu != nil {
+			tags["cpu"] = cpu[1]
+		}
+		if bank := mceBank.FindStringSubmatch(m[1]); bank != nil {
+			tags["bank"] = bank[1]
+		}
+		return tags, map[string]interface{}{
+			"count":   int64(1),
+			"message": m[1],
+		}
+	}
+	return nil, nil
+}
+
+var (
+	segfaultRegex = regexp.MustCompile(`^(.+)\[(\d+)\]: segfault at ([0-9a-f]+) ip ([0-9a-f]+) sp ([0-9a-f]+) error (\d+)(?: in ([^\[\s]+))?`)
+	trapRegex     = regexp.MustCompile(`^traps: (.+)\[(\d+)\] (general protection fault|trap [\w ]+?) ip:([0-9a-f]+) sp:([0-9a-f]+) error:([0-9a-f]+)(?: in ([^\[\s]+))?`)
+)
+
+// parseSegfault returns the crash of a user process of the message, such as
+// "stress[1234]: segfault at 0 ip 00007f5d sp 00007ffd error 4 in
+// libc.so.6[7f5d+1b1000]".
+func parseSegfault(message string) (map[string]string, map[string]interface{}) {
+	var typ, process, pid, address, ip, code, module string
+	if m := segfaultRegex.FindStringSubmatch(message); m != nil {
+		typ = "segfault"
+		process, pid, address, ip, code, module = m[1], m[2], m[3], m[4], m[6], m[7]
+	} else if m := trapRegex.FindStringSubmatch(message); m != nil {
+		typ = strings.Replace(m[3], " ", "_", -1)
+		process, pid, ip, code, module = m[1], m[2], m[4], m[6], m[7]
+	} else {
+		return nil, nil
+	}
+
+	tags := map[string]string{
+		"type":    typ,
+		"process": process,
+	}
+	if module != "" {
+		tags["module"] = module
+	}
+	// The error code is printed in hexadecimal
+	pidValue, _ := strconv.ParseInt(pid, 10, 64)
+	errorCode, _ := strconv.ParseInt(code, 16, 64)
+	fields := map[string]interface{}{
+		"pid":        pidValue,
+		"ip":         ip,
+		"error_code": errorCode,
+	}
+	if address != "" {
+		fields["address"] = address
+	}
+	return tags, fields
+}
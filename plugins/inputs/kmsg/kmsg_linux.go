@@ -0,0 +1,118 @@
+// +build linux
+
+package kmsg
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// maxRecordSize is the size of the buffer to read a record, the reads fail
+// with EINVAL if the buffer is smaller than the record.
+const maxRecordSize = 8192
+
+func (k *Kmsg) Start(acc telegraf.Accumulator) error {
+	f, err := os.Open(k.path)
+	if err != nil {
+		return err
+	}
+	if !k.FromBeginning {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	// The timestamps of the records are relative to the boot
+	if uptime, err := readUptime(filepath.Join(k.procPath, "uptime")); err == nil {
+		k.bootTime = now().Add(-uptime)
+	}
+
+	k.acc = acc
+	k.file = f
+	k.done = make(chan struct{})
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		k.read(f)
+	}()
+	return nil
+}
+
+func (k *Kmsg) Stop() {
+	// Closing the file interrupts the pending read
+	close(k.done)
+	k.file.Close()
+	k.wg.Wait()
+}
+
+// read reads the records until the file is closed, each read of /dev/kmsg
+// returns one record.
+func (k *Kmsg) read(f *os.File) {
+	buf := make([]byte, maxRecordSize)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			select {
+			case <-k.done:
+				return
+			default:
+			}
+			// The records were overwritten before being read
+			if pathErr, ok := err.(*os.PathError); ok && pathErr.Err == syscall.EPIPE {
+				continue
+			}
+			if err != io.EOF {
+				k.acc.AddError(err)
+			}
+			return
+		}
+
+		for _, lines := range splitRecords(string(buf[:n])) {
+			r, err := parseRecord(lines)
+			if err != nil {
+				k.Log.Debug(err.Error())
+				continue
+			}
+			k.handle(r)
+		}
+	}
+}
+
+func readUptime(path string) (time.Duration, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(strings.Fields(string(b) + " 0")[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func init() {
+	inputs.Add("kmsg", func() telegraf.Input {
+		procPath := "/proc"
+		if hostProc := os.Getenv("HOST_PROC"); hostProc != "" {
+			procPath = hostProc
+		}
+		return &Kmsg{
+			Messages:    true,
+			MaxPriority: "warning",
+			Events:      []string{"oom_kill", "hardware_error", "segfault"},
+			RateLimit:   100,
+			path:        "/dev/kmsg",
+			procPath:    procPath,
+		}
+	})
+}
@@ -0,0 +1,174 @@
+// +build linux
+
+package kmsg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	plugin := &Kmsg{
+		FromBeginning: true,
+		Messages:      true,
+		MaxPriority:   "warning",
+		Events:        []string{"oom_kill", "hardware_error", "segfault"},
+		Log:           testutil.Logger{},
+		path:          "testdata/kmsg",
+		procPath:      "testdata",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	acc.Wait(9)
+	plugin.Stop()
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("kmsg",
+			map[string]string{
+				"priority":  "warning",
+				"facility":  "kern",
+				"subsystem": "scsi",
+				"device":    "+scsi:0:0:0:0",
+			},
+			map[string]interface{}{
+				"message": "ata1.00: failed command: READ FPDMA QUEUED",
+				"seq":     uint64(340),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("kmsg",
+			map[string]string{"priority": "err", "facility": "kern"},
+			map[string]interface{}{
+				"message": "Memory cgroup out of memory: Killed process 4242 (stress) total-vm:7244kB, anon-rss:4096kB, file-rss:0kB, shmem-rss:0kB, UID:1000 pgtables:44kB oom_score_adj:0",
+				"seq":     uint64(342),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("kmsg_oom_kill",
+			map[string]string{
+				"process":    "stress",
+				"cgroup":     "true",
+				"constraint": "memcg",
+				"memcg":      "/system.slice/app.service",
+			},
+			map[string]interface{}{
+				"pid":             int64(4242),
+				"total_vm_bytes":  int64(7417856),
+				"anon_rss_bytes":  int64(4194304),
+				"file_rss_bytes":  int64(0),
+				"shmem_rss_bytes": int64(0),
+				"uid":             int64(1000),
+				"pgtables_bytes":  int64(45056),
+				"oom_score_adj":   int64(0),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("kmsg",
+			map[string]string{"priority": "emerg", "facility": "kern"},
+			map[string]interface{}{
+				"message": "mce: [Hardware Error]: CPU 2: Machine Check: 0 Bank 5: be00000000800400",
+				"seq":     uint64(343),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("kmsg_hardware_error",
+			map[string]string{"source": "mce", "cpu": "2", "bank": "5"},
+			map[string]interface{}{
+				"count":   int64(1),
+				"message": "CPU 2: Machine Check: 0 Bank 5: be00000000800400",
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("kmsg",
+			map[string]string{"priority": "warning", "facility": "kern"},
+			map[string]interface{}{
+				"message": "EDAC MC0: 1 CE memory read error on CPU_SrcID#0_Ha#0_Chan#1_DIMM#0 (channel:1 slot:0 page:0x1f0a offset:0x0 grain:32 syndrome:0x0)",
+				"seq":     uint64(344),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("kmsg_hardware_error",
+			map[string]string{"source": "edac", "controller": "MC0", "type": "ce"},
+			map[string]interface{}{
+				"count":   int64(1),
+				"message": "memory read error on CPU_SrcID#0_Ha#0_Chan#1_DIMM#0 (channel:1 slot:0 page:0x1f0a offset:0x0 grain:32 syndrome:0x0)",
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("kmsg_segfault",
+			map[string]string{"type": "segfault", "process": "crash", "module": "libc.so.6"},
+			map[string]interface{}{
+				"pid":        int64(1234),
+				"address":    "0",
+				"ip":         "00007f5d2a3b1c2d",
+				"error_code": int64(4),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("kmsg_segfault",
+			map[string]string{"type": "general_protection_fault", "process": "worker", "module": "worker"},
+			map[string]interface{}{
+				"pid":        int64(5678),
+				"ip":         "55d0c2a1b2c3",
+				"error_code": int64(0),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	// The timestamps are relative to the boot
+	require.WithinDuration(t, time.Now().Add(-1000500*time.Millisecond+5141*time.Millisecond), acc.GetTelegrafMetrics()[0].Time(), time.Second)
+}
+
+func TestRateLimit(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Unix(1600000000, 0) }
+
+	plugin := &Kmsg{
+		FromBeginning: true,
+		Messages:      true,
+		MaxPriority:   "debug",
+		RateLimit:     3,
+		Log:           testutil.Logger{},
+		path:          "testdata/kmsg",
+		procPath:      "testdata",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	plugin.wg.Wait()
+	plugin.Stop()
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			testutil.MustMetric("kmsg_stats",
+				map[string]string{},
+				map[string]interface{}{
+					"records": uint64(8),
+					"emitted": uint64(3),
+					"dropped": uint64(5),
+				},
+				time.Unix(0, 0),
+				telegraf.Counter,
+			),
+		},
+		acc.GetTelegrafMetrics(),
+		testutil.IgnoreTime(),
+	)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	require.Error(t, (&Kmsg{MaxPriority: "warn"}).Init())
+	require.Error(t, (&Kmsg{MaxPriority: "info", Events: []string{"panic"}}).Init())
+}
@@ -0,0 +1,24 @@
+// +build !linux
+
+package kmsg
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (k *Kmsg) Start(acc telegraf.Accumulator) error {
+	k.Log.Warn("Current platform is not supported")
+	return nil
+}
+
+func (k *Kmsg) Stop() {
+}
+
+func init() {
+	inputs.Add("kmsg", func() telegraf.Input {
+		return &Kmsg{
+			MaxPriority: "warning",
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package login_security
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	// sshFailed matches the failed authentications of sshd, such as
+	// "Failed password for invalid user admin from 10.0.0.1 port 4242 ssh2".
+	sshFailed = regexp.MustCompile(`\bFailed \S+ for (?:invalid user )?.* from (\S+) port \d+`)
+	// sshInvalidUser matches the connections of unknown users.
+	sshInvalidUser = regexp.MustCompile(`\bInvalid user .* from \S+`)
+	// sshAccepted matches the successful authentications.
+	sshAccepted = regexp.MustCompile(`\bAccepted \S+ for .* from \S+ port \d+`)
+	// repeated matches the lines of syslog for repeated messages.
+	repeated = regexp.MustCompile(`\bmessage repeated (\d+) times`)
+)
+
+// authLog follows an authentication log, counting the login attempts of
+// sshd since Telegraf started.
+type authLog struct {
+	path string
+
+	info   os.FileInfo
+	offset int64
+
+	failed      int64
+	invalidUser int64
+	accepted    int64
+
+	lastFailed int64
+	lastGather time.Time
+}
+
+func (a *authLog) gather(acc telegraf.Accumulator, now time.Time) error {
+	file, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case a.info == nil:
+		// The log is followed from its end when first read.
+		a.offset = info.Size()
+	case !os.SameFile(a.info, info) || info.Size() < a.offset:
+		// The log was rotated or truncated.
+		a.offset = 0
+	}
+	a.info = info
+
+	if _, err := file.Seek(a.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	sources := make(map[string]bool)
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// A partial line is read again on the next gather.
+			break
+		}
+		a.offset += int64(len(line))
+		a.parse(line, sources)
+	}
+
+	fields := map[string]interface{}{
+		"failed":       a.failed,
+		"invalid_user": a.invalidUser,
+		"accepted":     a.accepted,
+	}
+	if !a.lastGather.IsZero() {
+		fields["failed_sources"] = len(sources)
+		if minutes := now.Sub(a.lastGather).Minutes(); minutes > 0 {
+			fields["failures_per_minute"] = float64(a.failed-a.lastFailed) / minutes
+		}
+	}
+	acc.AddFields("login_security_ssh", fields, map[string]string{"file": a.path}, now)
+
+	a.lastFailed = a.failed
+	a.lastGather = now
+	return nil
+}
+
+// parse counts the login attempt of the line, the addresses of the failures
+// are added to the sources.
+func (a *authLog) parse(line string, sources map[string]bool) {
+	if !strings.Contains(line, "sshd[") && !strings.Contains(line, "sshd:") {
+		return
+	}
+
+	count := int64(1)
+	if m := repeated.FindStringSubmatch(line); m != nil {
+		count, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+
+	if m := sshFailed.FindStringSubmatch(line); m != nil {
+		a.failed += count
+		sources[m[1]] = true
+	} else if sshInvalidUser.MatchString(line) {
+		a.invalidUser += count
+	} else if sshAccepted.MatchString(line) {
+		a.accepted += count
+	}
+}
@@ -0,0 +1,156 @@
+package login_security
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	// fail2banEnd terminates the commands and responses on the socket.
+	fail2banEnd = []byte("<F2B_END_COMMAND>")
+	// fail2banClose closes the connection to the server.
+	fail2banClose = []byte("<F2B_CLOSE_COMMAND>")
+)
+
+// fail2banFields are the fields of the statistics of the status of a jail.
+var fail2banFields = map[string]string{
+	"Currently failed": "currently_failed",
+	"Total failed":     "total_failed",
+	"Currently banned": "currently_banned",
+	"Total banned":     "total_banned",
+}
+
+func (l *LoginSecurity) gatherFail2ban(acc telegraf.Accumulator) error {
+	status, err := l.fail2ban("status")
+	if err != nil {
+		return err
+	}
+
+	var jails []string
+	for _, jail := range strings.Split(fmt.Sprint(statusValue(status, "Jail list")), ",") {
+		if jail = strings.TrimSpace(jail); jail != "" {
+			jails = append(jails, jail)
+		}
+	}
+
+	banned := make(map[string]map[string]bool)
+	for _, jail := range jails {
+		status, err := l.fail2ban("status", jail)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		for _, section := range []string{"Filter", "Actions"} {
+			for _, item := range pairs(statusValue(status, section)) {
+				if field, ok := fail2banFields[item.name]; ok {
+					if v, ok := item.value.(int64); ok {
+						fields[field] = v
+					}
+				}
+			}
+		}
+
+		// The bans are the addresses banned since the previous gather.
+		addresses := make(map[string]bool)
+		if list, ok := statusValue(statusValue(status, "Actions"), "Banned IP list").([]interface{}); ok {
+			for _, address := range list {
+				addresses[fmt.Sprint(address)] = true
+			}
+		}
+		banned[jail] = addresses
+		if previous, ok := l.banned[jail]; ok {
+			var bans int64
+			for address := range addresses {
+				if !previous[address] {
+					bans++
+				}
+			}
+			fields["new_bans"] = bans
+		}
+
+		acc.AddFields("login_security_fail2ban", fields, map[string]string{"jail": jail})
+	}
+	l.banned = banned
+	return nil
+}
+
+// fail2ban sends a command to the fail2ban server and returns the result.
+func (l *LoginSecurity) fail2ban(args ...string) (interface{}, error) {
+	conn, err := net.DialTimeout("unix", l.Fail2banSocket, l.Timeout.Duration)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(l.Timeout.Duration))
+
+	request := append(pickleCommand(args...), fail2banEnd...)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	var response []byte
+	buf := make([]byte, 4096)
+	for !bytes.HasSuffix(response, fail2banEnd) {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fail2ban response: %v", err)
+		}
+		response = append(response, buf[:n]...)
+	}
+	conn.Write(append(fail2banClose, fail2banEnd...))
+
+	v, err := unpickle(bytes.TrimSuffix(response, fail2banEnd))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding fail2ban response to %q: %v",
+			strings.Join(args, " "), err)
+	}
+
+	// The response is the return code and the result, or the exception.
+	result, ok := v.([]interface{})
+	if !ok || len(result) != 2 {
+		return nil, fmt.Errorf("invalid fail2ban response to %q", strings.Join(args, " "))
+	}
+	if code, ok := result[0].(int64); !ok || code != 0 {
+		return nil, fmt.Errorf("fail2ban error on %q: %v", strings.Join(args, " "), result[1])
+	}
+	return result[1], nil
+}
+
+type statusPair struct {
+	name  string
+	value interface{}
+}
+
+// pairs returns the name and value pairs of the status of fail2ban, such as
+// [("Currently failed", 1), ("Total failed", 5)].
+func pairs(status interface{}) []statusPair {
+	items, _ := status.([]interface{})
+	var result []statusPair
+	for _, item := range items {
+		pair, ok := item.([]interface{})
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		if name, ok := pair[0].(string); ok {
+			result = append(result, statusPair{name, pair[1]})
+		}
+	}
+	return result
+}
+
+// statusValue returns the value of the name in the pairs of the status.
+func statusValue(status interface{}, name string) interface{} {
+	for _, pair := range pairs(status) {
+		if pair.name == name {
+			return pair.value
+		}
+	}
+	return nil
+}
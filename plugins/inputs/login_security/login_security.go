@@ -0,0 +1,80 @@
+package login_security
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// LoginSecurity gathers the SSH login attempts from the authentication logs
+// and the statistics of the jails of fail2ban.
+type LoginSecurity struct {
+	Fail2banSocket string            `toml:"fail2ban_socket"`
+	AuthLogFiles   []string          `toml:"auth_log_files"`
+	Timeout        internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	logs map[string]*authLog
+	// banned addresses of each jail at the previous gather.
+	banned map[string]map[string]bool
+}
+
+var sampleConfig = `
+  ## Socket of the fail2ban server, the jails are not gathered when empty.
+  ## Reading the socket requires the permissions of the user of fail2ban.
+  # fail2ban_socket = "/var/run/fail2ban/fail2ban.sock"
+
+  ## Authentication logs of sshd, "/var/log/secure" on Red Hat based
+  ## distributions.  The logs are followed from the end when Telegraf starts,
+  ## the login attempts are not gathered when empty.
+  # auth_log_files = ["/var/log/auth.log"]
+
+  ## Timeout of the requests to the fail2ban server.
+  # timeout = "5s"
+`
+
+func (l *LoginSecurity) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *LoginSecurity) Description() string {
+	return "Gather SSH login attempts and the statistics of the fail2ban jails"
+}
+
+func (l *LoginSecurity) Init() error {
+	l.logs = make(map[string]*authLog)
+	for _, file := range l.AuthLogFiles {
+		l.logs[file] = &authLog{path: file}
+	}
+	l.banned = make(map[string]map[string]bool)
+	return nil
+}
+
+func (l *LoginSecurity) Gather(acc telegraf.Accumulator) error {
+	now := time.Now()
+	for _, log := range l.logs {
+		if err := log.gather(acc, now); err != nil {
+			acc.AddError(err)
+		}
+	}
+
+	if l.Fail2banSocket != "" {
+		if err := l.gatherFail2ban(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("login_security", func() telegraf.Input {
+		return &LoginSecurity{
+			Fail2banSocket: "/var/run/fail2ban/fail2ban.sock",
+			AuthLogFiles:   []string{"/var/log/auth.log"},
+			Timeout:        internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package login_security
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// The responses of the fail2ban server, pickled by Python with the protocol
+// 5 of Python 3.8 and the protocol 2 of Python 2.
+const (
+	statusPickle = "80059544000000000000004b005d94288c0e4e756d626572206f66206a61696c944b0286948c094a61696c206c697374948c15737368642c206e67696e782d687474702d617574689486946586942e"
+	sshdPickle   = "800595ce000000000000004b005d94288c0646696c746572945d94288c1043757272656e746c79206661696c6564944b0186948c0c546f74616c206661696c6564944b0586948c0946696c65206c697374945d948c112f7661722f6c6f672f617574682e6c6f67946186946586948c07416374696f6e73945d94288c1043757272656e746c792062616e6e6564944b0286948c0c546f74616c2062616e6e6564944b0386948c0e42616e6e6564204950206c697374945d94288c0831302e302e302e31948c0831302e302e302e32946586946586946586942e"
+	nginxPickle  = "80024b005d710028580600000046696c74657271015d710228581000000043757272656e746c79206661696c656471034b00867104580c000000546f74616c206661696c656471054a70110100867106580f0000004a6f75726e616c206d61746368657371075d71088671096586710a5807000000416374696f6e73710b5d710c28581000000043757272656e746c792062616e6e6564710d4b0086710e580c000000546f74616c2062616e6e6564710f4b00867110580e00000042616e6e6564204950206c69737471115d711286711365867114658671152e"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestUnpickle(t *testing.T) {
+	// pickle.dumps({'a': [1, -2, 2**40, -(2**40), 1.5, True, None, b'x']}, 5)
+	v, err := unpickle(decodeHex(t, "80059532000000000000007d948c0161945d94284b014afeffffff8a060000000000018a060000000000ff473ff8000000000000884e4301789465732e"))
+	require.NoError(t, err)
+	require.Equal(t, map[interface{}]interface{}{
+		"a": []interface{}{int64(1), int64(-2), int64(1 << 40), int64(-1 << 40), 1.5, true, nil, "x"},
+	}, v)
+
+	v, err = unpickle(decodeHex(t, statusPickle))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{
+		int64(0),
+		[]interface{}{
+			[]interface{}{"Number of jail", int64(2)},
+			[]interface{}{"Jail list", "sshd, nginx-http-auth"},
+		},
+	}, v)
+}
+
+func TestPickleCommand(t *testing.T) {
+	// pickle.dumps(['status', 'sshd'], 2)
+	expected, err := unpickle(decodeHex(t, "80025d710028580600000073746174757371015804000000737368647102652e"))
+	require.NoError(t, err)
+
+	v, err := unpickle(pickleCommand("status", "sshd"))
+	require.NoError(t, err)
+	require.Equal(t, expected, v)
+}
+
+func TestUnpickleInvalid(t *testing.T) {
+	for _, data := range []string{"", "8002", "80025d", "80028c05616263", "800263"} {
+		_, err := unpickle(decodeHex(t, data))
+		require.Error(t, err, data)
+	}
+}
+
+// fail2banServer serves the pickled responses to the commands.
+func fail2banServer(t *testing.T, socket string, responses map[string][]byte) net.Listener {
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var request []byte
+				buf := make([]byte, 1024)
+				for !bytes.HasSuffix(request, fail2banEnd) {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					request = append(request, buf[:n]...)
+				}
+				command, err := unpickle(bytes.TrimSuffix(request, fail2banEnd))
+				if err != nil {
+					return
+				}
+				response, ok := responses[fmt.Sprint(command)]
+				if !ok {
+					return
+				}
+				conn.Write(append(response, fail2banEnd...))
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestGatherFail2ban(t *testing.T) {
+	dir, err := ioutil.TempDir("", "login_security")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "fail2ban.sock")
+	responses := map[string][]byte{
+		"[status]":                 decodeHex(t, statusPickle),
+		"[status sshd]":            decodeHex(t, sshdPickle),
+		"[status nginx-http-auth]": decodeHex(t, nginxPickle),
+	}
+	listener := fail2banServer(t, socket, responses)
+	defer listener.Close()
+
+	l := &LoginSecurity{
+		Fail2banSocket: socket,
+		Timeout:        internal.Duration{Duration: time.Second},
+	}
+	require.NoError(t, l.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(l.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"login_security_fail2ban",
+			map[string]string{"jail": "sshd"},
+			map[string]interface{}{
+				"currently_failed": int64(1),
+				"total_failed":     int64(5),
+				"currently_banned": int64(2),
+				"total_banned":     int64(3),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"login_security_fail2ban",
+			map[string]string{"jail": "nginx-http-auth"},
+			map[string]interface{}{
+				"currently_failed": int64(0),
+				"total_failed":     int64(70000),
+				"currently_banned": int64(0),
+				"total_banned":     int64(0),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(),
+		testutil.SortMetrics(), testutil.IgnoreTime())
+
+	// The addresses banned since the previous gather are the new bans.
+	l.banned["sshd"] = map[string]bool{"10.0.0.1": true}
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(l.Gather))
+	for _, m := range acc.GetTelegrafMetrics() {
+		bans, ok := m.GetField("new_bans")
+		require.True(t, ok)
+		if jail, _ := m.GetTag("jail"); jail == "sshd" {
+			require.Equal(t, int64(1), bans)
+		} else {
+			require.Equal(t, int64(0), bans)
+		}
+	}
+}
+
+func TestGatherFail2banError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "login_security")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := &LoginSecurity{
+		Fail2banSocket: filepath.Join(dir, "fail2ban.sock"),
+		Timeout:        internal.Duration{Duration: time.Second},
+	}
+	require.NoError(t, l.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(l.Gather))
+}
+
+const authLogLines = `May 14 10:00:01 web01 sshd[1001]: Invalid user admin from 10.0.0.1 port 50000
+May 14 10:00:03 web01 sshd[1001]: Failed password for invalid user admin from 10.0.0.1 port 50000 ssh2
+May 14 10:00:05 web01 sshd[1002]: Failed password for root from 10.0.0.2 port 50001 ssh2
+May 14 10:00:06 web01 sshd[1002]: message repeated 2 times: [ Failed password for root from 10.0.0.2 port 50001 ssh2]
+May 14 10:00:07 web01 sudo: pam_unix(sudo:auth): authentication failure; logname= uid=1000 euid=0 tty=/dev/pts/0 ruser=bob rhost=  user=bob
+May 14 10:00:10 web01 sshd[1003]: Accepted publickey for bob from 10.0.0.3 port 50002 ssh2: RSA SHA256:abc
+`
+
+func TestGatherAuthLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "login_security")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "auth.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("May 14 09:00:00 web01 sshd[1000]: Failed password for root from 10.0.0.9 port 50000 ssh2\n"), 0644))
+
+	l := &LoginSecurity{AuthLogFiles: []string{path}}
+	require.NoError(t, l.Init())
+
+	// The log is followed from its end.
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(l.Gather))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric(
+			"login_security_ssh",
+			map[string]string{"file": path},
+			map[string]interface{}{
+				"failed":       int64(0),
+				"invalid_user": int64(0),
+				"accepted":     int64(0),
+			},
+			time.Unix(0, 0),
+		),
+	}, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(authLogLines + "May 14 10:00:11 web01 sshd[1004]: Failed password")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l.logs[path].lastGather = time.Now().Add(-time.Minute)
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(l.Gather))
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	fields := metrics[0].Fields()
+	require.Equal(t, int64(4), fields["failed"])
+	require.Equal(t, int64(1), fields["invalid_user"])
+	require.Equal(t, int64(1), fields["accepted"])
+	require.Equal(t, int64(2), fields["failed_sources"])
+	require.InDelta(t, 4.0, fields["failures_per_minute"], 0.1)
+
+	// The log is read from its start once rotated, the partial line being
+	// dropped with the old log.
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, ioutil.WriteFile(path, []byte("May 14 10:01:00 web01 sshd[1005]: Failed publickey for bob from 10.0.0.4 port 50003 ssh2\n"), 0644))
+
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(l.Gather))
+	metrics = acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, int64(5), metrics[0].Fields()["failed"])
+}
@@ -0,0 +1,361 @@
+package login_security
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+)
+
+// The fail2ban server exchanges Python pickles.  The commands are pickled as
+// lists of strings, and the responses are decoded from the subset of the
+// pickle opcodes used for lists, tuples, dicts, strings and numbers.
+
+const (
+	opMark            = '('
+	opStop            = '.'
+	opNone            = 'N'
+	opBinInt          = 'J'
+	opBinInt1         = 'K'
+	opBinInt2         = 'M'
+	opBinFloat        = 'G'
+	opBinString       = 'T'
+	opShortBinString  = 'U'
+	opBinUnicode      = 'X'
+	opBinBytes        = 'B'
+	opShortBinBytes   = 'C'
+	opEmptyList       = ']'
+	opAppend          = 'a'
+	opAppends         = 'e'
+	opEmptyTuple      = ')'
+	opTuple           = 't'
+	opEmptyDict       = '}'
+	opSetItem         = 's'
+	opSetItems        = 'u'
+	opBinPut          = 'q'
+	opLongBinPut      = 'r'
+	opBinGet          = 'h'
+	opLongBinGet      = 'j'
+	opProto           = 0x80
+	opTuple1          = 0x85
+	opTuple2          = 0x86
+	opTuple3          = 0x87
+	opNewTrue         = 0x88
+	opNewFalse        = 0x89
+	opLong1           = 0x8a
+	opShortBinUnicode = 0x8c
+	opBinUnicode8     = 0x8d
+	opMemoize         = 0x94
+	opFrame           = 0x95
+)
+
+// pickleList is a list being decoded, it is appended to after being put in
+// the memo.
+type pickleList struct {
+	items []interface{}
+}
+
+// pickleMark marks the start of the items of a tuple, list or dict on the
+// stack.
+type pickleMark struct{}
+
+// pickleCommand returns the pickle of the list of strings of a command.
+func pickleCommand(args ...string) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{opProto, 2, opEmptyList, opMark})
+	for _, arg := range args {
+		var size [4]byte
+		binary.LittleEndian.PutUint32(size[:], uint32(len(arg)))
+		buf.WriteByte(opBinUnicode)
+		buf.Write(size[:])
+		buf.WriteString(arg)
+	}
+	buf.Write([]byte{opAppends, opStop})
+	return buf.Bytes()
+}
+
+// unpickle decodes a pickle.  Tuples and lists are decoded to slices, dicts
+// to maps, strings and bytes to strings and integers to int64.
+func unpickle(data []byte) (interface{}, error) {
+	r := bytes.NewReader(data)
+	var stack []interface{}
+	memo := make(map[int]interface{})
+
+	pop := func() (interface{}, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("pickle stack underflow")
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v, nil
+	}
+	popMark := func() ([]interface{}, error) {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if _, ok := stack[i].(pickleMark); ok {
+				items := append([]interface{}{}, stack[i+1:]...)
+				stack = stack[:i]
+				return items, nil
+			}
+		}
+		return nil, errors.New("pickle mark not found")
+	}
+	top := func() (interface{}, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("pickle stack underflow")
+		}
+		return stack[len(stack)-1], nil
+	}
+	read := func(n uint64) ([]byte, error) {
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	readUint := func(n int) (uint64, error) {
+		b, err := read(uint64(n))
+		if err != nil {
+			return 0, err
+		}
+		var v uint64
+		for i := n - 1; i >= 0; i-- {
+			v = v<<8 | uint64(b[i])
+		}
+		return v, nil
+	}
+	setItems := func(dict interface{}, items []interface{}) error {
+		m, ok := dict.(map[interface{}]interface{})
+		if !ok || len(items)%2 != 0 {
+			return errors.New("invalid pickle dict")
+		}
+		for i := 0; i < len(items); i += 2 {
+			m[items[i]] = items[i+1]
+		}
+		return nil
+	}
+
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		switch op {
+		case opStop:
+			v, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			return resolve(v), nil
+		case opProto:
+			if _, err := r.ReadByte(); err != nil {
+				return nil, io.ErrUnexpectedEOF
+			}
+		case opFrame:
+			if _, err := readUint(8); err != nil {
+				return nil, err
+			}
+		case opMark:
+			stack = append(stack, pickleMark{})
+		case opNone:
+			stack = append(stack, nil)
+		case opNewTrue:
+			stack = append(stack, true)
+		case opNewFalse:
+			stack = append(stack, false)
+		case opBinInt1, opBinInt2:
+			n := 1
+			if op == opBinInt2 {
+				n = 2
+			}
+			v, err := readUint(n)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(v))
+		case opBinInt:
+			v, err := readUint(4)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(int32(v)))
+		case opLong1:
+			n, err := readUint(1)
+			if err != nil {
+				return nil, err
+			}
+			b, err := read(n)
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeLong(b)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, v)
+		case opBinFloat:
+			b, err := read(8)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, math.Float64frombits(binary.BigEndian.Uint64(b)))
+		case opShortBinUnicode, opShortBinString, opShortBinBytes, opBinUnicode,
+			opBinString, opBinBytes, opBinUnicode8:
+			n := 4
+			switch op {
+			case opShortBinUnicode, opShortBinString, opShortBinBytes:
+				n = 1
+			case opBinUnicode8:
+				n = 8
+			}
+			size, err := readUint(n)
+			if err != nil {
+				return nil, err
+			}
+			b, err := read(size)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, string(b))
+		case opEmptyList:
+			stack = append(stack, &pickleList{})
+		case opAppend, opAppends:
+			var items []interface{}
+			if op == opAppend {
+				v, err := pop()
+				if err != nil {
+					return nil, err
+				}
+				items = []interface{}{v}
+			} else if items, err = popMark(); err != nil {
+				return nil, err
+			}
+			v, err := top()
+			if err != nil {
+				return nil, err
+			}
+			list, ok := v.(*pickleList)
+			if !ok {
+				return nil, errors.New("pickle append to a non-list")
+			}
+			list.items = append(list.items, items...)
+		case opEmptyTuple:
+			stack = append(stack, []interface{}{})
+		case opTuple:
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, items)
+		case opTuple1, opTuple2, opTuple3:
+			n := int(op-opTuple1) + 1
+			if len(stack) < n {
+				return nil, errors.New("pickle stack underflow")
+			}
+			items := append([]interface{}{}, stack[len(stack)-n:]...)
+			stack = append(stack[:len(stack)-n], items)
+		case opEmptyDict:
+			stack = append(stack, map[interface{}]interface{}{})
+		case opSetItem, opSetItems:
+			var items []interface{}
+			if op == opSetItem {
+				if len(stack) < 2 {
+					return nil, errors.New("pickle stack underflow")
+				}
+				items = append([]interface{}{}, stack[len(stack)-2:]...)
+				stack = stack[:len(stack)-2]
+			} else if items, err = popMark(); err != nil {
+				return nil, err
+			}
+			v, err := top()
+			if err != nil {
+				return nil, err
+			}
+			if err := setItems(v, items); err != nil {
+				return nil, err
+			}
+		case opBinPut, opLongBinPut, opMemoize:
+			v, err := top()
+			if err != nil {
+				return nil, err
+			}
+			index := len(memo)
+			if op != opMemoize {
+				n := 1
+				if op == opLongBinPut {
+					n = 4
+				}
+				i, err := readUint(n)
+				if err != nil {
+					return nil, err
+				}
+				index = int(i)
+			}
+			memo[index] = v
+		case opBinGet, opLongBinGet:
+			n := 1
+			if op == opLongBinGet {
+				n = 4
+			}
+			i, err := readUint(n)
+			if err != nil {
+				return nil, err
+			}
+			v, ok := memo[int(i)]
+			if !ok {
+				return nil, fmt.Errorf("pickle memo %d not found", i)
+			}
+			stack = append(stack, v)
+		default:
+			return nil, fmt.Errorf("unsupported pickle opcode 0x%02x", op)
+		}
+	}
+}
+
+// decodeLong decodes a little-endian two's complement integer.
+func decodeLong(b []byte) (int64, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	v := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	if !v.IsInt64() {
+		return 0, errors.New("pickle integer overflows int64")
+	}
+	return v.Int64(), nil
+}
+
+// resolve replaces the lists being decoded by their items.
+func resolve(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *pickleList:
+		items := make([]interface{}, len(v.items))
+		for i, item := range v.items {
+			items[i] = resolve(item)
+		}
+		return items
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = resolve(item)
+		}
+		return items
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, item := range v {
+			m[k] = resolve(item)
+		}
+		return m
+	}
+	return v
+}
@@ -0,0 +1,101 @@
+package lorawan
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// lppType is a data type of the Cayenne Low Power Payload, the value of the
+// type is made of big-endian integers of size bytes, one by component,
+// multiplied by the scale.
+type lppType struct {
+	name       string
+	size       int
+	signed     bool
+	scale      float64
+	components []string
+}
+
+var lppTypes = map[byte]lppType{
+	0:   {name: "digital_input", size: 1, scale: 1},
+	1:   {name: "digital_output", size: 1, scale: 1},
+	2:   {name: "analog_input", size: 2, signed: true, scale: 0.01},
+	3:   {name: "analog_output", size: 2, signed: true, scale: 0.01},
+	100: {name: "generic", size: 4, scale: 1},
+	101: {name: "illuminance", size: 2, scale: 1},
+	102: {name: "presence", size: 1, scale: 1},
+	103: {name: "temperature", size: 2, signed: true, scale: 0.1},
+	104: {name: "humidity", size: 1, scale: 0.5},
+	113: {name: "accelerometer", size: 2, signed: true, scale: 0.001, components: []string{"x", "y", "z"}},
+	115: {name: "barometer", size: 2, scale: 0.1},
+	116: {name: "voltage", size: 2, scale: 0.01},
+	117: {name: "current", size: 2, scale: 0.001},
+	118: {name: "frequency", size: 4, scale: 1},
+	120: {name: "percentage", size: 1, scale: 1},
+	121: {name: "altitude", size: 2, signed: true, scale: 1},
+	125: {name: "concentration", size: 2, scale: 1},
+	128: {name: "power", size: 2, scale: 1},
+	130: {name: "distance", size: 4, scale: 0.001},
+	131: {name: "energy", size: 4, scale: 0.001},
+	132: {name: "direction", size: 2, scale: 1},
+	133: {name: "unix_time", size: 4, scale: 1},
+	134: {name: "gyrometer", size: 2, signed: true, scale: 0.01, components: []string{"x", "y", "z"}},
+	135: {name: "colour", size: 1, scale: 1, components: []string{"r", "g", "b"}},
+	136: {name: "gps", size: 3, signed: true, components: []string{"latitude", "longitude", "altitude"}},
+	142: {name: "switch", size: 1, scale: 1},
+}
+
+// gpsScales are the scales of the components of the GPS location.
+var gpsScales = []float64{0.0001, 0.0001, 0.01}
+
+// decodeCayenneLPP adds the values of a Cayenne Low Power Payload to the
+// fields, named by the type and the channel of the values such as
+// "temperature_1" or "accelerometer_2_x".
+func decodeCayenneLPP(fields map[string]interface{}, payload []byte) error {
+	for len(payload) > 0 {
+		if len(payload) < 2 {
+			return fmt.Errorf("cayennelpp: truncated payload")
+		}
+		channel, typ := payload[0], payload[1]
+		t, ok := lppTypes[typ]
+		if !ok {
+			return fmt.Errorf("cayennelpp: unknown type %d on channel %d", typ, channel)
+		}
+		payload = payload[2:]
+
+		components := t.components
+		if components == nil {
+			components = []string{""}
+		}
+		if len(payload) < t.size*len(components) {
+			return fmt.Errorf("cayennelpp: truncated %s on channel %d", t.name, channel)
+		}
+
+		name := t.name + "_" + strconv.Itoa(int(channel))
+		for i, component := range components {
+			var v uint64
+			for _, b := range payload[:t.size] {
+				v = v<<8 | uint64(b)
+			}
+			payload = payload[t.size:]
+
+			value := float64(v)
+			if t.signed {
+				// Sign extension of the integer of size bytes.
+				shift := uint(64 - 8*t.size)
+				value = float64(int64(v<<shift) >> shift)
+			}
+			scale := t.scale
+			if typ == 136 {
+				scale = gpsScales[i]
+			}
+
+			key := name
+			if component != "" {
+				key += "_" + component
+			}
+			fields[key] = value * scale
+		}
+	}
+	return nil
+}
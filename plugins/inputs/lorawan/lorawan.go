@@ -0,0 +1,342 @@
+package lorawan
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## MQTT broker of the integration of the network server, the format is
+  ## scheme://host:port and the scheme is tcp, ssl or ws.
+  servers = ["tcp://127.0.0.1:1883"]
+
+  ## Network server publishing the uplink events, "chirpstack" (v3 or v4) or
+  ## "ttn" for The Things Stack v3.
+  network_server = "chirpstack"
+
+  ## Topics of the uplink events, by default "application/+/device/+/event/up"
+  ## for ChirpStack and "v3/+/devices/+/up" for The Things Stack.
+  # topics = []
+
+  ## Decoder of the payload of the uplinks:
+  ##   network    - the payload decoded by the codec of the network server,
+  ##                such as a JavaScript codec of the device profile
+  ##   cayennelpp - the payload in the Cayenne Low Power Payload format
+  ##   none       - the payload is not decoded
+  # payload_decoder = "network"
+
+  ## Report the radio metadata of the uplinks in the lorawan_uplink metric.
+  # uplink_metadata = true
+
+  ## QoS of the subscriptions, 0, 1 or 2.
+  # qos = 0
+
+  ## Connection timeout of the broker.
+  # connection_timeout = "30s"
+
+  ## If unset, a random client ID will be generated.
+  # client_id = ""
+
+  ## Username and password of the broker; the Things Stack uses the
+  ## application ID with its tenant, such as "app1@ttn", and an API key.
+  # username = ""
+  # password = ""
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+var defaultTopics = map[string][]string{
+	"chirpstack": {"application/+/device/+/event/up"},
+	"ttn":        {"v3/+/devices/+/up"},
+}
+
+type Client interface {
+	Connect() mqtt.Token
+	SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token
+	AddRoute(topic string, callback mqtt.MessageHandler)
+	Disconnect(quiesce uint)
+}
+
+type ClientFactory func(o *mqtt.ClientOptions) Client
+
+// LoRaWAN consumes the uplink events of the devices of a LoRaWAN network
+// server.
+type LoRaWAN struct {
+	Servers           []string          `toml:"servers"`
+	NetworkServer     string            `toml:"network_server"`
+	Topics            []string          `toml:"topics"`
+	PayloadDecoder    string            `toml:"payload_decoder"`
+	UplinkMetadata    bool              `toml:"uplink_metadata"`
+	QoS               int               `toml:"qos"`
+	ConnectionTimeout internal.Duration `toml:"connection_timeout"`
+	ClientID          string            `toml:"client_id"`
+	Username          string            `toml:"username"`
+	Password          string            `toml:"password"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	clientFactory ClientFactory
+	client        Client
+	opts          *mqtt.ClientOptions
+	acc           telegraf.Accumulator
+
+	sync.Mutex
+	connected bool
+}
+
+func (l *LoRaWAN) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *LoRaWAN) Description() string {
+	return "Consume the uplinks of the devices of a ChirpStack or The Things Stack network server"
+}
+
+func (l *LoRaWAN) Init() error {
+	topics, ok := defaultTopics[l.NetworkServer]
+	if !ok {
+		return fmt.Errorf("invalid network_server %q", l.NetworkServer)
+	}
+	if len(l.Topics) == 0 {
+		l.Topics = topics
+	}
+
+	switch l.PayloadDecoder {
+	case "":
+		l.PayloadDecoder = "network"
+	case "network", "cayennelpp", "none":
+	default:
+		return fmt.Errorf("invalid payload_decoder %q", l.PayloadDecoder)
+	}
+
+	if l.QoS > 2 || l.QoS < 0 {
+		return fmt.Errorf("qos value must be 0, 1, or 2: %d", l.QoS)
+	}
+
+	if len(l.Servers) == 0 {
+		return fmt.Errorf("no servers configured")
+	}
+
+	opts, err := l.createOpts()
+	if err != nil {
+		return err
+	}
+	l.opts = opts
+	return nil
+}
+
+func (l *LoRaWAN) Start(acc telegraf.Accumulator) error {
+	l.acc = acc
+	l.client = l.clientFactory(l.opts)
+	for _, topic := range l.Topics {
+		l.client.AddRoute(topic, l.recvMessage)
+	}
+
+	if err := l.connect(); err != nil {
+		acc.AddError(err)
+	}
+	return nil
+}
+
+func (l *LoRaWAN) connect() error {
+	token := l.client.Connect()
+	if token.Wait() && token.Error() != nil {
+		return token.Error()
+	}
+	l.Log.Infof("Connected %v", l.Servers)
+
+	l.Lock()
+	l.connected = true
+	l.Unlock()
+
+	topics := make(map[string]byte)
+	for _, topic := range l.Topics {
+		topics[topic] = byte(l.QoS)
+	}
+	token = l.client.SubscribeMultiple(topics, l.recvMessage)
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("subscription error: topics: %s: %v", strings.Join(l.Topics, ","), token.Error())
+	}
+	return nil
+}
+
+func (l *LoRaWAN) onConnectionLost(c mqtt.Client, err error) {
+	l.acc.AddError(fmt.Errorf("connection lost: %v", err))
+	l.Log.Debugf("Disconnected %v", l.Servers)
+
+	l.Lock()
+	l.connected = false
+	l.Unlock()
+}
+
+func (l *LoRaWAN) recvMessage(c mqtt.Client, msg mqtt.Message) {
+	var u *uplink
+	var err error
+	switch l.NetworkServer {
+	case "chirpstack":
+		u, err = parseChirpStack(msg.Payload())
+	case "ttn":
+		u, err = parseTTN(msg.Payload())
+	}
+	if err != nil {
+		l.acc.AddError(fmt.Errorf("topic %s: %v", msg.Topic(), err))
+		return
+	}
+	if err := l.addUplink(u); err != nil {
+		l.acc.AddError(fmt.Errorf("device %s: %v", u.devEUI, err))
+	}
+}
+
+// addUplink adds the telemetry decoded from the payload of an uplink and
+// its radio metadata.
+func (l *LoRaWAN) addUplink(u *uplink) error {
+	tags := map[string]string{
+		"network_server": l.NetworkServer,
+		"application":    u.application,
+		"device":         u.device,
+		"dev_eui":        u.devEUI,
+	}
+	if u.timestamp.IsZero() {
+		u.timestamp = time.Now()
+	}
+
+	if l.UplinkMetadata {
+		fields := map[string]interface{}{
+			"f_cnt":    int64(u.fCnt),
+			"f_port":   int64(u.fPort),
+			"gateways": int64(len(u.gateways)),
+		}
+		if u.frequency != 0 {
+			fields["frequency"] = int64(u.frequency)
+		}
+		if u.spreadingFactor != 0 {
+			fields["spreading_factor"] = int64(u.spreadingFactor)
+		}
+		if u.bandwidth != 0 {
+			fields["bandwidth"] = int64(u.bandwidth)
+		}
+		// The radio quality is the one of the gateway receiving the uplink
+		// best.
+		if len(u.gateways) > 0 {
+			best := u.gateways[0]
+			for _, g := range u.gateways[1:] {
+				if g.rssi > best.rssi {
+					best = g
+				}
+			}
+			fields["rssi"] = best.rssi
+			fields["snr"] = best.snr
+			fields["gateway"] = best.id
+		}
+		l.acc.AddFields("lorawan_uplink", fields, tags, u.timestamp)
+	}
+
+	fields := make(map[string]interface{})
+	switch l.PayloadDecoder {
+	case "network":
+		if u.object == nil {
+			return nil
+		}
+		flatten(fields, "", u.object)
+	case "cayennelpp":
+		if len(u.payload) == 0 {
+			return nil
+		}
+		if err := decodeCayenneLPP(fields, u.payload); err != nil {
+			return err
+		}
+	case "none":
+		return nil
+	}
+	if len(fields) > 0 {
+		l.acc.AddFields("lorawan", fields, tags, u.timestamp)
+	}
+	return nil
+}
+
+func (l *LoRaWAN) Stop() {
+	l.Lock()
+	defer l.Unlock()
+	if l.connected {
+		l.Log.Debugf("Disconnecting %v", l.Servers)
+		l.client.Disconnect(200)
+		l.connected = false
+	}
+}
+
+func (l *LoRaWAN) Gather(acc telegraf.Accumulator) error {
+	l.Lock()
+	connected := l.connected
+	l.Unlock()
+	if !connected {
+		l.Log.Debugf("Connecting %v", l.Servers)
+		return l.connect()
+	}
+	return nil
+}
+
+func (l *LoRaWAN) createOpts() (*mqtt.ClientOptions, error) {
+	opts := mqtt.NewClientOptions()
+	opts.ConnectTimeout = l.ConnectionTimeout.Duration
+
+	if l.ClientID == "" {
+		opts.SetClientID("Telegraf-LoRaWAN-" + internal.RandomString(5))
+	} else {
+		opts.SetClientID(l.ClientID)
+	}
+
+	tlsCfg, err := l.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		opts.SetTLSConfig(tlsCfg)
+	}
+
+	if l.Username != "" {
+		opts.SetUsername(l.Username)
+	}
+	if l.Password != "" {
+		opts.SetPassword(l.Password)
+	}
+
+	for _, server := range l.Servers {
+		opts.AddBroker(server)
+	}
+	opts.SetAutoReconnect(false)
+	opts.SetKeepAlive(60 * time.Second)
+	opts.SetCleanSession(true)
+	opts.SetConnectionLostHandler(l.onConnectionLost)
+	return opts, nil
+}
+
+func New(factory ClientFactory) *LoRaWAN {
+	return &LoRaWAN{
+		Servers:           []string{"tcp://127.0.0.1:1883"},
+		UplinkMetadata:    true,
+		ConnectionTimeout: internal.Duration{Duration: 30 * time.Second},
+		clientFactory:     factory,
+	}
+}
+
+func init() {
+	inputs.Add("lorawan", func() telegraf.Input {
+		return New(func(o *mqtt.ClientOptions) Client {
+			return mqtt.NewClient(o)
+		})
+	})
+}
@@ -0,0 +1,336 @@
+package lorawan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type FakeClient struct {
+	handler    mqtt.MessageHandler
+	subscribed map[string]byte
+}
+
+func (c *FakeClient) Connect() mqtt.Token {
+	return &FakeToken{}
+}
+
+func (c *FakeClient) SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.subscribed = filters
+	c.handler = callback
+	return &FakeToken{}
+}
+
+func (c *FakeClient) AddRoute(topic string, callback mqtt.MessageHandler) {
+}
+
+func (c *FakeClient) Disconnect(quiesce uint) {
+}
+
+type FakeToken struct {
+}
+
+func (t *FakeToken) Wait() bool {
+	return true
+}
+
+func (t *FakeToken) WaitTimeout(time.Duration) bool {
+	return true
+}
+
+func (t *FakeToken) Error() error {
+	return nil
+}
+
+type Message struct {
+	topic   string
+	payload string
+}
+
+func (m *Message) Duplicate() bool {
+	panic("not implemented")
+}
+
+func (m *Message) Qos() byte {
+	panic("not implemented")
+}
+
+func (m *Message) Retained() bool {
+	panic("not implemented")
+}
+
+func (m *Message) Topic() string {
+	return m.topic
+}
+
+func (m *Message) MessageID() uint16 {
+	panic("not implemented")
+}
+
+func (m *Message) Payload() []byte {
+	return []byte(m.payload)
+}
+
+func (m *Message) Ack() {
+	panic("not implemented")
+}
+
+// startPlugin starts the plugin with a fake client, whose handler receives
+// the messages.
+func startPlugin(t *testing.T, l *LoRaWAN, acc *testutil.Accumulator) *FakeClient {
+	client := &FakeClient{}
+	l.clientFactory = func(o *mqtt.ClientOptions) Client {
+		return client
+	}
+	l.Log = testutil.Logger{}
+	require.NoError(t, l.Init())
+	require.NoError(t, l.Start(acc))
+	require.NotNil(t, client.handler)
+	return client
+}
+
+func TestChirpStackV4(t *testing.T) {
+	l := New(nil)
+	l.NetworkServer = "chirpstack"
+
+	var acc testutil.Accumulator
+	client := startPlugin(t, l, &acc)
+	defer l.Stop()
+	require.Equal(t, map[string]byte{"application/+/device/+/event/up": 0}, client.subscribed)
+
+	client.handler(nil, &Message{
+		topic: "application/b1a2/device/0101010101010101/event/up",
+		payload: `{
+			"deduplicationId": "3ac7e3c4-4401-4b8d-9386-a5c902f9202d",
+			"time": "2020-05-14T12:00:00.123Z",
+			"deviceInfo": {
+				"applicationId": "b1a2",
+				"applicationName": "greenhouse",
+				"deviceName": "sensor1",
+				"devEui": "0101010101010101",
+				"tags": {"room": "1"}
+			},
+			"fCnt": 42,
+			"fPort": 2,
+			"data": "A2cBEAVnAP8=",
+			"object": {"temperature": 27.2, "battery": {"voltage": 3.6, "low": false}, "status": "ok"},
+			"rxInfo": [
+				{"gatewayId": "0303030303030303", "rssi": -90, "snr": 2.5},
+				{"gatewayId": "0404040404040404", "rssi": -57, "snr": 10}
+			],
+			"txInfo": {"frequency": 868100000, "modulation": {"lora": {"bandwidth": 125000, "spreadingFactor": 7}}}
+		}`,
+	})
+	require.Empty(t, acc.Errors)
+
+	tags := map[string]string{
+		"network_server": "chirpstack",
+		"application":    "greenhouse",
+		"device":         "sensor1",
+		"dev_eui":        "0101010101010101",
+	}
+	timestamp := time.Date(2020, 5, 14, 12, 0, 0, 123000000, time.UTC)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"lorawan_uplink",
+			tags,
+			map[string]interface{}{
+				"f_cnt":            int64(42),
+				"f_port":           int64(2),
+				"gateways":         int64(2),
+				"frequency":        int64(868100000),
+				"spreading_factor": int64(7),
+				"bandwidth":        int64(125000),
+				"rssi":             -57.0,
+				"snr":              10.0,
+				"gateway":          "0404040404040404",
+			},
+			timestamp,
+		),
+		testutil.MustMetric(
+			"lorawan",
+			tags,
+			map[string]interface{}{
+				"temperature":     27.2,
+				"battery_voltage": 3.6,
+				"battery_low":     false,
+				"status":          "ok",
+			},
+			timestamp,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestChirpStackV3CayenneLPP(t *testing.T) {
+	l := New(nil)
+	l.NetworkServer = "chirpstack"
+	l.PayloadDecoder = "cayennelpp"
+	l.UplinkMetadata = false
+
+	var acc testutil.Accumulator
+	client := startPlugin(t, l, &acc)
+	defer l.Stop()
+
+	// The bytes of the json_v3 marshaler are encoded in base64.
+	client.handler(nil, &Message{
+		topic: "application/1/device/0102030405060708/rx",
+		payload: `{
+			"applicationID": "1",
+			"applicationName": "greenhouse",
+			"deviceName": "sensor2",
+			"devEUI": "AQIDBAUGBwg=",
+			"rxInfo": [{"gatewayID": "AwMDAwMDAwM=", "rssi": -48, "loRaSNR": 9}],
+			"txInfo": {"frequency": 868100000, "dr": 5},
+			"fCnt": 10,
+			"fPort": 5,
+			"data": "A2cBEAVnAP8="
+		}`,
+	})
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"lorawan",
+			map[string]string{
+				"network_server": "chirpstack",
+				"application":    "greenhouse",
+				"device":         "sensor2",
+				"dev_eui":        "0102030405060708",
+			},
+			map[string]interface{}{
+				"temperature_3": 27.200000000000003,
+				"temperature_5": 25.5,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestTTN(t *testing.T) {
+	l := New(nil)
+	l.NetworkServer = "ttn"
+
+	var acc testutil.Accumulator
+	client := startPlugin(t, l, &acc)
+	defer l.Stop()
+	require.Equal(t, map[string]byte{"v3/+/devices/+/up": 0}, client.subscribed)
+
+	client.handler(nil, &Message{
+		topic: "v3/app1@ttn/devices/dev1/up",
+		payload: `{
+			"end_device_ids": {
+				"device_id": "dev1",
+				"application_ids": {"application_id": "app1"},
+				"dev_eui": "0004A30B001C0530",
+				"dev_addr": "00BCB929"
+			},
+			"received_at": "2020-05-14T12:00:00Z",
+			"uplink_message": {
+				"f_port": 1,
+				"f_cnt": 7,
+				"frm_payload": "AYgGdl/ylgoAA+g=",
+				"decoded_payload": {"readings": [1, 2]},
+				"rx_metadata": [{"gateway_ids": {"gateway_id": "gw1"}, "rssi": -35, "snr": 9.25}],
+				"settings": {
+					"data_rate": {"lora": {"bandwidth": 125000, "spreading_factor": 9}},
+					"frequency": "868300000"
+				}
+			}
+		}`,
+	})
+	require.Empty(t, acc.Errors)
+
+	tags := map[string]string{
+		"network_server": "ttn",
+		"application":    "app1",
+		"device":         "dev1",
+		"dev_eui":        "0004A30B001C0530",
+	}
+	timestamp := time.Date(2020, 5, 14, 12, 0, 0, 0, time.UTC)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"lorawan_uplink",
+			tags,
+			map[string]interface{}{
+				"f_cnt":            int64(7),
+				"f_port":           int64(1),
+				"gateways":         int64(1),
+				"frequency":        int64(868300000),
+				"spreading_factor": int64(9),
+				"bandwidth":        int64(125000),
+				"rssi":             -35.0,
+				"snr":              9.25,
+				"gateway":          "gw1",
+			},
+			timestamp,
+		),
+		testutil.MustMetric(
+			"lorawan",
+			tags,
+			map[string]interface{}{"readings_0": 1.0, "readings_1": 2.0},
+			timestamp,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestInvalidMessage(t *testing.T) {
+	l := New(nil)
+	l.NetworkServer = "ttn"
+
+	var acc testutil.Accumulator
+	client := startPlugin(t, l, &acc)
+	defer l.Stop()
+
+	client.handler(nil, &Message{topic: "v3/app1@ttn/devices/dev1/up", payload: `{"end_device_ids": {}}`})
+	client.handler(nil, &Message{topic: "v3/app1@ttn/devices/dev1/up", payload: `{`})
+	require.Len(t, acc.Errors, 2)
+	require.Contains(t, acc.Errors[0].Error(), "not an uplink message")
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestDecodeCayenneLPP(t *testing.T) {
+	fields := make(map[string]interface{})
+	require.NoError(t, decodeCayenneLPP(fields, []byte{
+		0x01, 0x67, 0xff, 0xd7, // temperature -4.1
+		0x06, 0x71, 0x04, 0xd2, 0xfb, 0x2e, 0x00, 0x00, // accelerometer 1.234, -1.234, 0
+		0x01, 0x88, 0x06, 0x76, 0x5f, 0xf2, 0x96, 0x0a, 0x00, 0x03, 0xe8, // gps
+		0x02, 0x68, 0x61, // humidity 48.5
+	}))
+	require.InDelta(t, -4.1, fields["temperature_1"], 1e-9)
+	require.InDelta(t, 1.234, fields["accelerometer_6_x"], 1e-9)
+	require.InDelta(t, -1.234, fields["accelerometer_6_y"], 1e-9)
+	require.InDelta(t, 0.0, fields["accelerometer_6_z"], 1e-9)
+	require.InDelta(t, 42.3519, fields["gps_1_latitude"], 1e-9)
+	require.InDelta(t, -87.9094, fields["gps_1_longitude"], 1e-9)
+	require.InDelta(t, 10.0, fields["gps_1_altitude"], 1e-9)
+	require.InDelta(t, 48.5, fields["humidity_2"], 1e-9)
+
+	require.Error(t, decodeCayenneLPP(fields, []byte{0x01, 0x67, 0xff}))
+	require.Error(t, decodeCayenneLPP(fields, []byte{0x01, 0xfe, 0x00}))
+}
+
+func TestInit(t *testing.T) {
+	l := New(nil)
+	l.Log = testutil.Logger{}
+	require.Error(t, l.Init())
+
+	l.NetworkServer = "loriot"
+	require.Error(t, l.Init())
+
+	l.NetworkServer = "ttn"
+	l.PayloadDecoder = "javascript"
+	require.Error(t, l.Init())
+
+	l.PayloadDecoder = ""
+	l.Topics = []string{"v3/app1@ttn/devices/+/up"}
+	require.NoError(t, l.Init())
+	require.Equal(t, "network", l.PayloadDecoder)
+	require.Equal(t, []string{"v3/app1@ttn/devices/+/up"}, l.Topics)
+}
@@ -0,0 +1,256 @@
+package lorawan
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// uplink is an uplink event of a device, whatever its network server.
+type uplink struct {
+	application string
+	device      string
+	devEUI      string
+	timestamp   time.Time
+
+	fCnt            uint32
+	fPort           uint32
+	frequency       uint64
+	spreadingFactor uint32
+	bandwidth       uint32
+	gateways        []gateway
+
+	payload []byte
+	// object is the payload decoded by the network server.
+	object map[string]interface{}
+}
+
+type gateway struct {
+	id   string
+	rssi float64
+	snr  float64
+}
+
+// chirpStackUplink is the JSON uplink event of the MQTT integration of
+// ChirpStack, the fields of v3 and of v4.
+type chirpStackUplink struct {
+	// v3
+	ApplicationID   string `json:"applicationID"`
+	ApplicationName string `json:"applicationName"`
+	DeviceName      string `json:"deviceName"`
+	DevEUI          string `json:"devEUI"`
+	PublishedAt     string `json:"publishedAt"`
+
+	// v4
+	DeviceInfo *struct {
+		ApplicationID   string `json:"applicationId"`
+		ApplicationName string `json:"applicationName"`
+		DeviceName      string `json:"deviceName"`
+		DevEUI          string `json:"devEui"`
+	} `json:"deviceInfo"`
+	Time string `json:"time"`
+
+	FCnt   uint32 `json:"fCnt"`
+	FPort  uint32 `json:"fPort"`
+	Data   []byte `json:"data"`
+	RxInfo []struct {
+		// gatewayID of v3 and gatewayId of v4.
+		GatewayID string  `json:"gatewayID"`
+		RSSI      float64 `json:"rssi"`
+		LoRaSNR   float64 `json:"loRaSNR"`
+		SNR       float64 `json:"snr"`
+	} `json:"rxInfo"`
+	TxInfo struct {
+		Frequency          uint64 `json:"frequency"`
+		LoRaModulationInfo *struct {
+			Bandwidth       uint32 `json:"bandwidth"`
+			SpreadingFactor uint32 `json:"spreadingFactor"`
+		} `json:"loRaModulationInfo"`
+		Modulation *struct {
+			LoRa *struct {
+				Bandwidth       uint32 `json:"bandwidth"`
+				SpreadingFactor uint32 `json:"spreadingFactor"`
+			} `json:"lora"`
+		} `json:"modulation"`
+	} `json:"txInfo"`
+	Object map[string]interface{} `json:"object"`
+}
+
+func parseChirpStack(buf []byte) (*uplink, error) {
+	var e chirpStackUplink
+	if err := json.Unmarshal(buf, &e); err != nil {
+		return nil, err
+	}
+
+	u := &uplink{
+		fCnt:      e.FCnt,
+		fPort:     e.FPort,
+		frequency: e.TxInfo.Frequency,
+		payload:   e.Data,
+		object:    e.Object,
+	}
+	timestamp := e.PublishedAt
+	if d := e.DeviceInfo; d != nil {
+		u.application = d.ApplicationName
+		if u.application == "" {
+			u.application = d.ApplicationID
+		}
+		u.device = d.DeviceName
+		u.devEUI = d.DevEUI
+		timestamp = e.Time
+	} else {
+		u.application = e.ApplicationName
+		if u.application == "" {
+			u.application = e.ApplicationID
+		}
+		u.device = e.DeviceName
+		u.devEUI = eui(e.DevEUI)
+	}
+	if u.devEUI == "" {
+		return nil, errors.New("no device EUI in the uplink")
+	}
+	if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+		u.timestamp = t
+	}
+
+	if m := e.TxInfo.LoRaModulationInfo; m != nil {
+		// The bandwidth of v3 is in kHz.
+		u.bandwidth = m.Bandwidth * 1000
+		u.spreadingFactor = m.SpreadingFactor
+	} else if m := e.TxInfo.Modulation; m != nil && m.LoRa != nil {
+		u.bandwidth = m.LoRa.Bandwidth
+		u.spreadingFactor = m.LoRa.SpreadingFactor
+	}
+
+	for _, r := range e.RxInfo {
+		g := gateway{id: eui(r.GatewayID), rssi: r.RSSI, snr: r.SNR}
+		if r.LoRaSNR != 0 {
+			g.snr = r.LoRaSNR
+		}
+		u.gateways = append(u.gateways, g)
+	}
+	return u, nil
+}
+
+// ttnUplink is the JSON uplink message of the MQTT server of The Things
+// Stack v3.
+type ttnUplink struct {
+	EndDeviceIDs struct {
+		DeviceID       string `json:"device_id"`
+		ApplicationIDs struct {
+			ApplicationID string `json:"application_id"`
+		} `json:"application_ids"`
+		DevEUI string `json:"dev_eui"`
+	} `json:"end_device_ids"`
+	ReceivedAt    string `json:"received_at"`
+	UplinkMessage *struct {
+		FPort          uint32                 `json:"f_port"`
+		FCnt           uint32                 `json:"f_cnt"`
+		FRMPayload     []byte                 `json:"frm_payload"`
+		DecodedPayload map[string]interface{} `json:"decoded_payload"`
+		RxMetadata     []struct {
+			GatewayIDs struct {
+				GatewayID string `json:"gateway_id"`
+			} `json:"gateway_ids"`
+			RSSI float64 `json:"rssi"`
+			SNR  float64 `json:"snr"`
+		} `json:"rx_metadata"`
+		Settings struct {
+			DataRate struct {
+				LoRa *struct {
+					Bandwidth       uint32 `json:"bandwidth"`
+					SpreadingFactor uint32 `json:"spreading_factor"`
+				} `json:"lora"`
+			} `json:"data_rate"`
+			// The frequency is a string, as the 64 bits integers of the
+			// protocol buffers in JSON.
+			Frequency string `json:"frequency"`
+		} `json:"settings"`
+	} `json:"uplink_message"`
+}
+
+func parseTTN(buf []byte) (*uplink, error) {
+	var e ttnUplink
+	if err := json.Unmarshal(buf, &e); err != nil {
+		return nil, err
+	}
+	m := e.UplinkMessage
+	if m == nil {
+		return nil, errors.New("not an uplink message")
+	}
+
+	u := &uplink{
+		application: e.EndDeviceIDs.ApplicationIDs.ApplicationID,
+		device:      e.EndDeviceIDs.DeviceID,
+		devEUI:      e.EndDeviceIDs.DevEUI,
+		fCnt:        m.FCnt,
+		fPort:       m.FPort,
+		payload:     m.FRMPayload,
+		object:      m.DecodedPayload,
+	}
+	if u.devEUI == "" {
+		return nil, errors.New("no device EUI in the uplink")
+	}
+	if t, err := time.Parse(time.RFC3339Nano, e.ReceivedAt); err == nil {
+		u.timestamp = t
+	}
+	if m.Settings.Frequency != "" {
+		frequency, err := strconv.ParseUint(m.Settings.Frequency, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid frequency %q", m.Settings.Frequency)
+		}
+		u.frequency = frequency
+	}
+	if lora := m.Settings.DataRate.LoRa; lora != nil {
+		u.bandwidth = lora.Bandwidth
+		u.spreadingFactor = lora.SpreadingFactor
+	}
+	for _, r := range m.RxMetadata {
+		u.gateways = append(u.gateways, gateway{id: r.GatewayIDs.GatewayID, rssi: r.RSSI, snr: r.SNR})
+	}
+	return u, nil
+}
+
+// eui returns an EUI in hexadecimal, the EUI of the "json_v3" marshaler of
+// ChirpStack v3 being encoded in base64.
+func eui(s string) string {
+	if len(s) == 16 {
+		if _, err := hex.DecodeString(s); err == nil {
+			return s
+		}
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == 8 {
+		return hex.EncodeToString(b)
+	}
+	return s
+}
+
+// flatten adds the values of a decoded payload to the fields, the keys of
+// the nested objects and the indexes of the arrays joined by underscores.
+func flatten(fields map[string]interface{}, prefix string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			flatten(fields, key, value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			key := strconv.Itoa(i)
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			flatten(fields, key, value)
+		}
+	case float64, string, bool:
+		if prefix != "" {
+			fields[prefix] = v
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package macos
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Collect power, thermal and fan metrics with powermetrics, which must
+  ## run as root.
+  # powermetrics = true
+
+  ## Samplers of powermetrics.  The "smc" sampler, reporting fan speeds and
+  ## die temperatures, is only available on Intel processors.
+  # samplers = ["cpu_power", "gpu_power", "thermal"]
+
+  ## Duration powermetrics samples over.
+  # sample_duration = "1s"
+
+  ## Run powermetrics with sudo, which must be configured to not ask for a
+  ## password.
+  # use_sudo = false
+
+  ## Collect battery health metrics from the IOKit registry.
+  # battery = true
+
+  ## Timeout for the commands to complete.
+  # timeout = "5s"
+`
+
+const (
+	defaultSampleDuration = time.Second
+	defaultTimeout        = 5 * time.Second
+)
+
+// MacOS gathers hardware metrics of macOS systems.
+type MacOS struct {
+	Powermetrics   bool              `toml:"powermetrics"`
+	Samplers       []string          `toml:"samplers"`
+	SampleDuration internal.Duration `toml:"sample_duration"`
+	UseSudo        bool              `toml:"use_sudo"`
+	Battery        bool              `toml:"battery"`
+	Timeout        internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+func (m *MacOS) Description() string {
+	return "Gather power, thermal, fan and battery metrics of macOS systems"
+}
+
+func (m *MacOS) SampleConfig() string {
+	return sampleConfig
+}
+
+// parsePowermetrics adds the metrics of the plist output of powermetrics.
+func parsePowermetrics(acc telegraf.Accumulator, out []byte) error {
+	// Samples are separated by NUL bytes
+	out = bytes.Trim(out, "\x00 \n")
+	if i := bytes.IndexByte(out, 0); i >= 0 {
+		out = out[:i]
+	}
+
+	v, err := parsePlist(out)
+	if err != nil {
+		return fmt.Errorf("parsing powermetrics output failed: %v", err)
+	}
+	sample, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected powermetrics output")
+	}
+
+	power := make(map[string]interface{})
+	if processor, ok := sample["processor"].(map[string]interface{}); ok {
+		addNumbers(power, processor, "")
+	}
+	if gpu, ok := sample["gpu"].(map[string]interface{}); ok {
+		addNumbers(power, gpu, "gpu_")
+	}
+	if len(power) > 0 {
+		acc.AddGauge("macos_power", power, nil)
+	}
+
+	thermal := make(map[string]interface{})
+	if pressure, ok := sample["thermal_pressure"].(string); ok {
+		thermal["pressure"] = pressure
+	}
+	if smc, ok := sample["smc"].(map[string]interface{}); ok {
+		addNumbers(thermal, smc, "")
+	}
+	if len(thermal) > 0 {
+		acc.AddGauge("macos_thermal", thermal, nil)
+	}
+	return nil
+}
+
+// addNumbers adds the numeric values of the dict as floats, prefixing the
+// keys not starting with the prefix.
+func addNumbers(fields, dict map[string]interface{}, prefix string) {
+	for k, v := range dict {
+		if !strings.HasPrefix(k, prefix) {
+			k = prefix + k
+		}
+		switch v := v.(type) {
+		case int64:
+			fields[k] = float64(v)
+		case float64:
+			fields[k] = v
+		}
+	}
+}
+
+// parseBattery adds the metrics of the AppleSmartBattery entries of the
+// plist output of ioreg.
+func parseBattery(acc telegraf.Accumulator, out []byte) error {
+	v, err := parsePlist(out)
+	if err != nil {
+		return fmt.Errorf("parsing ioreg output failed: %v", err)
+	}
+	batteries, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected ioreg output")
+	}
+
+	for _, b := range batteries {
+		battery, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		tags := make(map[string]string)
+		if serial, ok := battery["Serial"].(string); ok {
+			tags["serial"] = serial
+		}
+
+		fields := make(map[string]interface{})
+		for key, field := range map[string]string{
+			"CycleCount":     "cycle_count",
+			"DesignCapacity": "design_capacity_mah",
+			"Voltage":        "voltage_mv",
+			"Amperage":       "amperage_ma",
+		} {
+			if v, ok := battery[key].(int64); ok {
+				fields[field] = v
+			}
+		}
+
+		// On Apple Silicon the capacities are percentages, the raw keys
+		// contain the capacities in mAh.
+		maxCapacity, ok := battery["AppleRawMaxCapacity"].(int64)
+		if !ok {
+			maxCapacity, ok = battery["MaxCapacity"].(int64)
+		}
+		if ok {
+			fields["max_capacity_mah"] = maxCapacity
+		}
+		currentCapacity, ok := battery["AppleRawCurrentCapacity"].(int64)
+		if !ok {
+			currentCapacity, ok = battery["CurrentCapacity"].(int64)
+		}
+		if ok {
+			fields["current_capacity_mah"] = currentCapacity
+		}
+
+		if design, ok := fields["design_capacity_mah"].(int64); ok && design > 0 {
+			fields["health_percent"] = float64(maxCapacity) / float64(design) * 100
+		}
+		if maxCapacity > 0 {
+			fields["charge_percent"] = float64(currentCapacity) / float64(maxCapacity) * 100
+		}
+
+		// The temperature is in hundredths of degrees Celsius
+		if temp, ok := battery["Temperature"].(int64); ok {
+			fields["temperature_celsius"] = float64(temp) / 100
+		}
+
+		for key, field := range map[string]string{
+			"IsCharging":        "charging",
+			"ExternalConnected": "external_connected",
+			"FullyCharged":      "fully_charged",
+		} {
+			if v, ok := battery[key].(bool); ok {
+				fields[field] = v
+			}
+		}
+
+		if len(fields) > 0 {
+			acc.AddGauge("macos_battery", fields, tags)
+		}
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("macos", func() telegraf.Input {
+		return &MacOS{
+			Powermetrics:   true,
+			Samplers:       []string{"cpu_power", "gpu_power", "thermal"},
+			SampleDuration: internal.Duration{Duration: defaultSampleDuration},
+			Battery:        true,
+			Timeout:        internal.Duration{Duration: defaultTimeout},
+		}
+	})
+}
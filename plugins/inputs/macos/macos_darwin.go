@@ -0,0 +1,62 @@
+// +build darwin
+
+package macos
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+var execCommand = exec.Command // execCommand is used to mock commands in tests.
+
+func (m *MacOS) Gather(acc telegraf.Accumulator) error {
+	if m.Powermetrics {
+		out, err := m.powermetrics()
+		if err == nil {
+			err = parsePowermetrics(acc, out)
+		}
+		if err != nil {
+			acc.AddError(err)
+		}
+	}
+
+	if m.Battery {
+		out, err := m.run("ioreg", "-r", "-n", "AppleSmartBattery", "-a")
+		if err == nil {
+			err = parseBattery(acc, out)
+		}
+		if err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func (m *MacOS) powermetrics() ([]byte, error) {
+	args := []string{
+		"-n", "1",
+		"-i", strconv.FormatInt(int64(m.SampleDuration.Duration/time.Millisecond), 10),
+		"-f", "plist",
+		"--samplers", strings.Join(m.Samplers, ","),
+	}
+	if m.UseSudo {
+		return m.run("sudo", append([]string{"-n", "powermetrics"}, args...)...)
+	}
+	return m.run("powermetrics", args...)
+}
+
+func (m *MacOS) run(name string, args ...string) ([]byte, error) {
+	cmd := execCommand(name, args...)
+	out, err := internal.CombinedOutputTimeout(cmd, m.Timeout.Duration+m.SampleDuration.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s",
+			strings.Join(cmd.Args, " "), err, string(out))
+	}
+	return out, nil
+}
@@ -0,0 +1,16 @@
+// +build !darwin
+
+package macos
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+func (m *MacOS) Init() error {
+	m.Log.Warn("Current platform is not supported")
+	return nil
+}
+
+func (m *MacOS) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
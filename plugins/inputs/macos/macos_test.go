@@ -0,0 +1,119 @@
+package macos
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePowermetrics(t *testing.T) {
+	out, err := ioutil.ReadFile("testdata/powermetrics.plist")
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	require.NoError(t, parsePowermetrics(&acc, out))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"macos_power",
+			map[string]string{},
+			map[string]interface{}{
+				"ane_energy":     0.0,
+				"cpu_energy":     123.0,
+				"gpu_energy":     4.0,
+				"ane_power":      0.0,
+				"cpu_power":      122.5,
+				"gpu_power":      4.0,
+				"combined_power": 126.5,
+				"gpu_freq_hz":    389.0,
+				"gpu_idle_ratio": 0.96,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"macos_thermal",
+			map[string]string{},
+			map[string]interface{}{
+				"pressure": "Nominal",
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestParsePowermetricsSMC(t *testing.T) {
+	out := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>smc</key>
+	<dict>
+		<key>fan</key><real>1798.5</real>
+		<key>cpu_die</key><real>52.1</real>
+		<key>gpu_die</key><integer>47</integer>
+	</dict>
+</dict>
+</plist>`)
+
+	var acc testutil.Accumulator
+	require.NoError(t, parsePowermetrics(&acc, out))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"macos_thermal",
+			map[string]string{},
+			map[string]interface{}{
+				"fan":     1798.5,
+				"cpu_die": 52.1,
+				"gpu_die": 47.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestParseBattery(t *testing.T) {
+	out, err := ioutil.ReadFile("testdata/battery.plist")
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	require.NoError(t, parseBattery(&acc, out))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"macos_battery",
+			map[string]string{"serial": "F8Y1234567890"},
+			map[string]interface{}{
+				"cycle_count":          int64(112),
+				"design_capacity_mah":  int64(5501),
+				"max_capacity_mah":     int64(5103),
+				"current_capacity_mah": int64(4187),
+				"voltage_mv":           int64(12553),
+				"amperage_ma":          int64(-666),
+				"health_percent":       float64(5103) / 5501 * 100,
+				"charge_percent":       float64(4187) / 5103 * 100,
+				"temperature_celsius":  30.12,
+				"charging":             false,
+				"external_connected":   false,
+				"fully_charged":        false,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestParseInvalid(t *testing.T) {
+	var acc testutil.Accumulator
+	require.Error(t, parsePowermetrics(&acc, []byte("powermetrics must be invoked as the superuser")))
+	require.Error(t, parseBattery(&acc, []byte("<plist><dict></dict></plist>")))
+}
@@ -0,0 +1,114 @@
+package macos
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parsePlist decodes an XML property list into maps, slices, strings,
+// int64, float64 and bool values.
+func parsePlist(data []byte) (interface{}, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("plist has no value")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return plistValue(d, start)
+		}
+	}
+}
+
+// plistValue decodes the value of the element.
+func plistValue(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		return plistDict(d)
+	case "array":
+		return plistArray(d)
+	case "true":
+		return true, d.Skip()
+	case "false":
+		return false, d.Skip()
+	}
+
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+
+	switch start.Name.Local {
+	case "integer":
+		if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return v, nil
+		}
+		// Unsigned values larger than int64, such as -1 written as uint64
+		v, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return int64(v), nil
+	case "real":
+		return strconv.ParseFloat(text, 64)
+	default:
+		return text, nil
+	}
+}
+
+func plistDict(d *xml.Decoder) (map[string]interface{}, error) {
+	dict := make(map[string]interface{})
+	var key string
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "key" {
+				if err := d.DecodeElement(&key, &t); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			v, err := plistValue(d, t)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		case xml.EndElement:
+			return dict, nil
+		}
+	}
+}
+
+func plistArray(d *xml.Decoder) ([]interface{}, error) {
+	var array []interface{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := plistValue(d, t)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, v)
+		case xml.EndElement:
+			return array, nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package nfsclient
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+var (
+	cifsSessionsRegex   = regexp.MustCompile(`^CIFS Session: (\d+)`)
+	cifsSharesRegex     = regexp.MustCompile(`^Share \(unique mount targets\): (\d+)`)
+	cifsReconnectsRegex = regexp.MustCompile(`^(\d+) session (\d+) share reconnects`)
+	cifsVfsRegex        = regexp.MustCompile(`^Total vfs operations: (\d+) maximum at one time: (\d+)`)
+	cifsShareRegex      = regexp.MustCompile(`^\d+\) (\S+)`)
+	cifsSMBsRegex       = regexp.MustCompile(`^SMBs: (\d+)`)
+	cifsBytesRegex      = regexp.MustCompile(`^Bytes read: (\d+)\s+Bytes written: (\d+)`)
+	cifsOpenRegex       = regexp.MustCompile(`^Open files: (\d+) total \(local\), (\d+) open on server`)
+	cifsCommandRegex    = regexp.MustCompile(`^(\w+): (\d+) (?:total|sent) (\d+) failed`)
+)
+
+// gatherCIFS adds the statistics of the CIFS client of /proc/fs/cifs/Stats,
+// which has the global statistics followed by a section for each share
+// starting with a line like "1) \\server\share".
+func gatherCIFS(acc telegraf.Accumulator, r io.Reader) error {
+	fields := make(map[string]interface{})
+	var share string
+	var shareFields map[string]interface{}
+
+	addShare := func() {
+		if share != "" && len(shareFields) > 0 {
+			acc.AddFields("cifs_share", shareFields, map[string]string{"share": share})
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := cifsShareRegex.FindStringSubmatch(line); m != nil {
+			addShare()
+			share = m[1]
+			shareFields = make(map[string]interface{})
+			continue
+		}
+
+		if share == "" {
+			if m := cifsSessionsRegex.FindStringSubmatch(line); m != nil {
+				addUints(fields, m[1:], "sessions")
+			} else if m := cifsSharesRegex.FindStringSubmatch(line); m != nil {
+				addUints(fields, m[1:], "shares")
+			} else if m := cifsReconnectsRegex.FindStringSubmatch(line); m != nil {
+				addUints(fields, m[1:], "session_reconnects", "share_reconnects")
+			} else if m := cifsVfsRegex.FindStringSubmatch(line); m != nil {
+				addUints(fields, m[1:], "vfs_operations", "vfs_operations_max")
+			}
+			continue
+		}
+
+		if m := cifsSMBsRegex.FindStringSubmatch(line); m != nil {
+			addUints(shareFields, m[1:], "smbs")
+		} else if m := cifsBytesRegex.FindStringSubmatch(line); m != nil {
+			addUints(shareFields, m[1:], "read_bytes", "write_bytes")
+		} else if m := cifsOpenRegex.FindStringSubmatch(line); m != nil {
+			addUints(shareFields, m[1:], "open_files", "server_open_files")
+		} else if m := cifsCommandRegex.FindStringSubmatch(line); m != nil {
+			// IOCTLs would become "i_octls"
+			name := internal.SnakeCase(strings.Replace(m[1], "IOCTL", "Ioctl", -1))
+			addUints(shareFields, m[2:], name+"_total", name+"_failed")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	addShare()
+
+	if len(fields) > 0 {
+		acc.AddFields("cifs", fields, nil)
+	}
+	return nil
+}
+
+// addUints adds the values matched by a regular expression, which only
+// match numbers.
+func addUints(fields map[string]interface{}, values []string, names ...string) {
+	for i, name := range names {
+		v, err := strconv.ParseUint(values[i], 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[name] = v
+	}
+}
@@ -0,0 +1,155 @@
+package nfsclient
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// mountBytesFields are the fields of the "bytes:" line of mountstats.
+var mountBytesFields = []string{
+	"read_bytes",
+	"write_bytes",
+	"direct_read_bytes",
+	"direct_write_bytes",
+	"server_read_bytes",
+	"server_write_bytes",
+	"read_pages",
+	"write_pages",
+}
+
+// mountOpFields are the fields of the per-op statistics of mountstats, the
+// errors are reported since Linux 5.3.
+var mountOpFields = []string{
+	"ops",
+	"transmissions",
+	"timeouts",
+	"bytes_sent",
+	"bytes_received",
+	"queue_time_ms",
+	"rtt_ms",
+	"execute_time_ms",
+	"errors",
+}
+
+// gatherMountstats adds the statistics of the NFS mounts of mountstats,
+// which has a section for each mount starting with a line like
+// "device server:/export mounted on /mnt with fstype nfs4 statvers=1.1".
+func (n *NFSClient) gatherMountstats(acc telegraf.Accumulator, r io.Reader) error {
+	var tags map[string]string
+	var age int64
+	var inOps bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "device" {
+			tags = n.mountTags(fields)
+			age = 0
+			inOps = false
+			continue
+		}
+		if tags == nil {
+			continue
+		}
+
+		switch {
+		case fields[0] == "age:" && len(fields) > 1:
+			age, _ = strconv.ParseInt(fields[1], 10, 64)
+		case fields[0] == "bytes:":
+			values, err := parseUints(fields[1:], len(mountBytesFields))
+			if err != nil {
+				return fmt.Errorf("parsing bytes of %s failed: %v", tags["mountpoint"], err)
+			}
+			mountFields := map[string]interface{}{"age_seconds": age}
+			for i, name := range mountBytesFields {
+				mountFields[name] = values[i]
+			}
+			acc.AddFields("nfs_mount", mountFields, tags)
+		case fields[0] == "per-op":
+			inOps = true
+		case inOps && strings.HasSuffix(fields[0], ":"):
+			op := strings.TrimSuffix(fields[0], ":")
+			if n.operationFilter != nil && !n.operationFilter.Match(op) {
+				continue
+			}
+			// The errors are missing on older kernels
+			count := len(mountOpFields)
+			if len(fields)-1 < count {
+				count--
+			}
+			values, err := parseUints(fields[1:], count)
+			if err != nil {
+				return fmt.Errorf("parsing %s of %s failed: %v", op, tags["mountpoint"], err)
+			}
+			if values[0] == 0 {
+				continue
+			}
+
+			opTags := make(map[string]string, len(tags)+1)
+			for k, v := range tags {
+				opTags[k] = v
+			}
+			opTags["operation"] = op
+			opFields := make(map[string]interface{}, count)
+			for i := 0; i < count; i++ {
+				opFields[mountOpFields[i]] = values[i]
+			}
+			acc.AddCounter("nfs_ops", opFields, opTags)
+		}
+	}
+	return scanner.Err()
+}
+
+// mountTags returns the tags of a NFS mount of the device line, or nil if
+// the mount is not a NFS mount or is filtered.
+func (n *NFSClient) mountTags(fields []string) map[string]string {
+	// device server:/export mounted on /mnt with fstype nfs4 statvers=1.1
+	if len(fields) < 8 || fields[2] != "mounted" || fields[6] != "fstype" {
+		return nil
+	}
+	fstype := fields[7]
+	if fstype != "nfs" && fstype != "nfs4" {
+		return nil
+	}
+	mountpoint := fields[4]
+	if n.mountFilter != nil && !n.mountFilter.Match(mountpoint) {
+		return nil
+	}
+
+	tags := map[string]string{
+		"mountpoint": mountpoint,
+		"fstype":     fstype,
+	}
+	if i := strings.Index(fields[1], ":/"); i >= 0 {
+		tags["server"] = fields[1][:i]
+		tags["export"] = fields[1][i+1:]
+	} else {
+		tags["server"] = fields[1]
+	}
+	return tags
+}
+
+// parseUints parses the first count values.
+func parseUints(values []string, count int) ([]uint64, error) {
+	if len(values) < count {
+		return nil, fmt.Errorf("expected %d values, got %d", count, len(values))
+	}
+	result := make([]uint64, count)
+	for i := 0; i < count; i++ {
+		v, err := strconv.ParseUint(values[i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
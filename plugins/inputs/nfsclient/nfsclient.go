@@ -0,0 +1,104 @@
+package nfsclient
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Mount points to collect, globs are supported.  All NFS mounts are
+  ## collected by default.
+  # include_mounts = []
+  # exclude_mounts = []
+
+  ## NFS operations to collect per mount, such as "READ" and "WRITE", globs
+  ## are supported.  All operations which have been called are collected by
+  ## default.
+  # include_operations = []
+
+  ## Collect the statistics of the NFS server.
+  # server = false
+
+  ## Collect the statistics of the CIFS/SMB client.
+  # cifs = false
+`
+
+// NFSClient gathers the statistics of the NFS client mounts, the NFS server
+// and the CIFS client.
+type NFSClient struct {
+	IncludeMounts     []string `toml:"include_mounts"`
+	ExcludeMounts     []string `toml:"exclude_mounts"`
+	IncludeOperations []string `toml:"include_operations"`
+	Server            bool     `toml:"server"`
+	CIFS              bool     `toml:"cifs"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	procPath        string
+	mountFilter     filter.Filter
+	operationFilter filter.Filter
+}
+
+func (n *NFSClient) Description() string {
+	return "Gather NFS client per-mount statistics, NFS server statistics and CIFS client statistics"
+}
+
+func (n *NFSClient) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NFSClient) Init() error {
+	var err error
+	n.mountFilter, err = filter.NewIncludeExcludeFilter(n.IncludeMounts, n.ExcludeMounts)
+	if err != nil {
+		return err
+	}
+	n.operationFilter, err = filter.Compile(n.IncludeOperations)
+	return err
+}
+
+func (n *NFSClient) Gather(acc telegraf.Accumulator) error {
+	if err := n.gatherFile(acc, filepath.Join(n.procPath, "self", "mountstats"), n.gatherMountstats); err != nil {
+		acc.AddError(err)
+	}
+	if n.Server {
+		if err := n.gatherFile(acc, filepath.Join(n.procPath, "net", "rpc", "nfsd"), gatherNfsd); err != nil {
+			acc.AddError(err)
+		}
+	}
+	if n.CIFS {
+		if err := n.gatherFile(acc, filepath.Join(n.procPath, "fs", "cifs", "Stats"), gatherCIFS); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// gatherFile gathers the statistics of the file, which is missing if the
+// module is not loaded.
+func (n *NFSClient) gatherFile(acc telegraf.Accumulator, path string, gather func(telegraf.Accumulator, io.Reader) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gather(acc, f)
+}
+
+func init() {
+	inputs.Add("nfsclient", func() telegraf.Input {
+		procPath := "/proc"
+		if hostProc := os.Getenv("HOST_PROC"); hostProc != "" {
+			procPath = hostProc
+		}
+		return &NFSClient{procPath: procPath}
+	})
+}
@@ -0,0 +1,276 @@
+package nfsclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherMountstats(t *testing.T) {
+	plugin := &NFSClient{procPath: "testdata"}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	dataTags := map[string]string{
+		"server":     "fileserver",
+		"export":     "/export/data",
+		"mountpoint": "/mnt/data",
+		"fstype":     "nfs4",
+	}
+	homeTags := map[string]string{
+		"server":     "server2",
+		"export":     "/home",
+		"mountpoint": "/home",
+		"fstype":     "nfs",
+	}
+	opTags := func(tags map[string]string, op string) map[string]string {
+		result := map[string]string{"operation": op}
+		for k, v := range tags {
+			result[k] = v
+		}
+		return result
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("nfs_mount", dataTags,
+			map[string]interface{}{
+				"age_seconds":        int64(3600),
+				"read_bytes":         uint64(4096),
+				"write_bytes":        uint64(8192),
+				"direct_read_bytes":  uint64(0),
+				"direct_write_bytes": uint64(0),
+				"server_read_bytes":  uint64(4096),
+				"server_write_bytes": uint64(8192),
+				"read_pages":         uint64(1),
+				"write_pages":        uint64(2),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("nfs_ops", opTags(dataTags, "NULL"),
+			map[string]interface{}{
+				"ops":             uint64(1),
+				"transmissions":   uint64(1),
+				"timeouts":        uint64(0),
+				"bytes_sent":      uint64(44),
+				"bytes_received":  uint64(24),
+				"queue_time_ms":   uint64(0),
+				"rtt_ms":          uint64(0),
+				"execute_time_ms": uint64(0),
+				"errors":          uint64(0),
+			},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric("nfs_ops", opTags(dataTags, "READ"),
+			map[string]interface{}{
+				"ops":             uint64(10),
+				"transmissions":   uint64(10),
+				"timeouts":        uint64(0),
+				"bytes_sent":      uint64(1880),
+				"bytes_received":  uint64(5176),
+				"queue_time_ms":   uint64(2),
+				"rtt_ms":          uint64(35),
+				"execute_time_ms": uint64(38),
+				"errors":          uint64(0),
+			},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric("nfs_ops", opTags(dataTags, "WRITE"),
+			map[string]interface{}{
+				"ops":             uint64(20),
+				"transmissions":   uint64(21),
+				"timeouts":        uint64(1),
+				"bytes_sent":      uint64(10280),
+				"bytes_received":  uint64(3200),
+				"queue_time_ms":   uint64(5),
+				"rtt_ms":          uint64(110),
+				"execute_time_ms": uint64(120),
+				"errors":          uint64(1),
+			},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric("nfs_mount", homeTags,
+			map[string]interface{}{
+				"age_seconds":        int64(60),
+				"read_bytes":         uint64(100),
+				"write_bytes":        uint64(200),
+				"direct_read_bytes":  uint64(0),
+				"direct_write_bytes": uint64(0),
+				"server_read_bytes":  uint64(100),
+				"server_write_bytes": uint64(200),
+				"read_pages":         uint64(0),
+				"write_pages":        uint64(0),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("nfs_ops", opTags(homeTags, "GETATTR"),
+			map[string]interface{}{
+				"ops":             uint64(5),
+				"transmissions":   uint64(5),
+				"timeouts":        uint64(0),
+				"bytes_sent":      uint64(600),
+				"bytes_received":  uint64(560),
+				"queue_time_ms":   uint64(0),
+				"rtt_ms":          uint64(3),
+				"execute_time_ms": uint64(4),
+			},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherMountstatsFilter(t *testing.T) {
+	plugin := &NFSClient{
+		ExcludeMounts:     []string{"/home"},
+		IncludeOperations: []string{"READ", "WRITE"},
+		procPath:          "testdata",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	var ops []string
+	for _, m := range acc.GetTelegrafMetrics() {
+		require.Equal(t, "/mnt/data", m.Tags()["mountpoint"])
+		if m.Name() == "nfs_ops" {
+			ops = append(ops, m.Tags()["operation"])
+		}
+	}
+	require.ElementsMatch(t, []string{"READ", "WRITE"}, ops)
+}
+
+func TestGatherNfsd(t *testing.T) {
+	plugin := &NFSClient{
+		ExcludeMounts: []string{"*"},
+		Server:        true,
+		procPath:      "testdata",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	op := func(version, name string, calls uint64) telegraf.Metric {
+		return testutil.MustMetric("nfsd_ops",
+			map[string]string{"version": version, "operation": name},
+			map[string]interface{}{"calls": calls},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		)
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("nfsd",
+			map[string]string{},
+			map[string]interface{}{
+				"reply_cache_hits":    uint64(0),
+				"reply_cache_misses":  uint64(6),
+				"reply_cache_nocache": uint64(18622),
+				"read_bytes":          uint64(579210),
+				"write_bytes":         uint64(6574),
+				"threads":             uint64(8),
+				"net_count":           uint64(18628),
+				"net_udp":             uint64(0),
+				"net_tcp":             uint64(18628),
+				"net_tcp_connections": uint64(6),
+				"rpc_calls":           uint64(18628),
+				"rpc_bad_calls":       uint64(0),
+				"rpc_bad_format":      uint64(0),
+				"rpc_bad_auth":        uint64(0),
+				"rpc_bad_client":      uint64(0),
+			},
+			time.Unix(0, 0),
+		),
+		op("3", "null", 2),
+		op("3", "getattr", 112),
+		op("3", "lookup", 2719),
+		op("3", "access", 111),
+		op("3", "readdir", 27),
+		op("3", "readdirplus", 216),
+		op("3", "fsinfo", 2),
+		op("3", "pathconf", 1),
+		op("4", "null", 2),
+		op("4", "compound", 10853),
+		op("4", "access", 1098),
+		op("4", "close", 2),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherCIFS(t *testing.T) {
+	plugin := &NFSClient{
+		ExcludeMounts: []string{"*"},
+		CIFS:          true,
+		procPath:      "testdata",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cifs",
+			map[string]string{},
+			map[string]interface{}{
+				"sessions":           uint64(1),
+				"shares":             uint64(2),
+				"session_reconnects": uint64(0),
+				"share_reconnects":   uint64(1),
+				"vfs_operations":     uint64(20),
+				"vfs_operations_max": uint64(2),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cifs_share",
+			map[string]string{"share": `\\fileserver\IPC$`},
+			map[string]interface{}{
+				"smbs":                 uint64(8),
+				"read_bytes":           uint64(0),
+				"write_bytes":          uint64(0),
+				"open_files":           uint64(0),
+				"server_open_files":    uint64(0),
+				"tree_connects_total":  uint64(1),
+				"tree_connects_failed": uint64(0),
+				"ioctls_total":         uint64(1),
+				"ioctls_failed":        uint64(0),
+				"oplock_breaks_total":  uint64(0),
+				"oplock_breaks_failed": uint64(0),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cifs_share",
+			map[string]string{"share": `\\fileserver\share`},
+			map[string]interface{}{
+				"smbs":                     uint64(48),
+				"read_bytes":               uint64(1024),
+				"write_bytes":              uint64(2048),
+				"open_files":               uint64(1),
+				"server_open_files":        uint64(1),
+				"tree_connects_total":      uint64(1),
+				"tree_connects_failed":     uint64(0),
+				"creates_total":            uint64(10),
+				"creates_failed":           uint64(1),
+				"reads_total":              uint64(2),
+				"reads_failed":             uint64(0),
+				"writes_total":             uint64(3),
+				"writes_failed":            uint64(0),
+				"query_directories_total":  uint64(2),
+				"query_directories_failed": uint64(0),
+				"oplock_breaks_total":      uint64(0),
+				"oplock_breaks_failed":     uint64(0),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
@@ -0,0 +1,136 @@
+package nfsclient
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// nfsdLineFields are the fields of the lines of the nfsd statistics.
+var nfsdLineFields = map[string][]string{
+	"rc":  {"reply_cache_hits", "reply_cache_misses", "reply_cache_nocache"},
+	"io":  {"read_bytes", "write_bytes"},
+	"th":  {"threads"},
+	"net": {"net_count", "net_udp", "net_tcp", "net_tcp_connections"},
+	"rpc": {"rpc_calls", "rpc_bad_calls", "rpc_bad_format", "rpc_bad_auth", "rpc_bad_client"},
+}
+
+var nfsd3Operations = []string{
+	"null", "getattr", "setattr", "lookup", "access", "readlink", "read",
+	"write", "create", "mkdir", "symlink", "mknod", "remove", "rmdir",
+	"rename", "link", "readdir", "readdirplus", "fsstat", "fsinfo",
+	"pathconf", "commit",
+}
+
+var nfsd4Procedures = []string{"null", "compound"}
+
+// nfsd4Operations are the NFSv4 operations by operation number, the first
+// three numbers are unused.
+var nfsd4Operations = []string{
+	"", "", "", "access", "close", "commit", "create", "delegpurge",
+	"delegreturn", "getattr", "getfh", "link", "lock", "lockt", "locku",
+	"lookup", "lookupp", "nverify", "open", "openattr", "open_confirm",
+	"open_downgrade", "putfh", "putpubfh", "putrootfh", "read", "readdir",
+	"readlink", "remove", "rename", "renew", "restorefh", "savefh",
+	"secinfo", "setattr", "setclientid", "setclientid_confirm", "verify",
+	"write", "release_lockowner", "backchannel_ctl", "bind_conn_to_session",
+	"exchange_id", "create_session", "destroy_session", "free_stateid",
+	"get_dir_delegation", "getdeviceinfo", "getdevicelist", "layoutcommit",
+	"layoutget", "layoutreturn", "secinfo_no_name", "sequence", "set_ssv",
+	"test_stateid", "want_delegation", "destroy_clientid",
+	"reclaim_complete", "allocate", "copy", "copy_notify", "deallocate",
+	"io_advise", "layouterror", "layoutstats", "offload_cancel",
+	"offload_status", "read_plus", "seek", "write_same", "clone",
+	"getxattr", "setxattr", "listxattrs", "removexattr",
+}
+
+// gatherNfsd adds the statistics of the NFS server of /proc/net/rpc/nfsd.
+func gatherNfsd(acc telegraf.Accumulator, r io.Reader) error {
+	fields := make(map[string]interface{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case "proc3":
+			if err := addNfsdOperations(acc, "3", nfsd3Operations, parts[1:]); err != nil {
+				return err
+			}
+		case "proc4":
+			if err := addNfsdOperations(acc, "4", nfsd4Procedures, parts[1:]); err != nil {
+				return err
+			}
+		case "proc4ops":
+			if err := addNfsdOperations(acc, "4", nfsd4Operations, parts[1:]); err != nil {
+				return err
+			}
+		default:
+			names, ok := nfsdLineFields[parts[0]]
+			if !ok {
+				continue
+			}
+			for i, name := range names {
+				if i+1 >= len(parts) {
+					break
+				}
+				v, err := strconv.ParseUint(parts[i+1], 10, 64)
+				if err != nil {
+					return fmt.Errorf("parsing %s of nfsd statistics failed: %v", name, err)
+				}
+				fields[name] = v
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(fields) > 0 {
+		acc.AddFields("nfsd", fields, nil)
+	}
+	return nil
+}
+
+// addNfsdOperations adds the call counts of a procN line, which starts with
+// the number of counts.
+func addNfsdOperations(acc telegraf.Accumulator, version string, names []string, values []string) error {
+	count, err := strconv.Atoi(values[0])
+	if err != nil {
+		return fmt.Errorf("parsing nfsd statistics failed: %v", err)
+	}
+	values = values[1:]
+	if count < len(values) {
+		values = values[:count]
+	}
+
+	for i, value := range values {
+		calls, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing nfsd statistics failed: %v", err)
+		}
+		if calls == 0 {
+			continue
+		}
+
+		name := "op" + strconv.Itoa(i)
+		if i < len(names) {
+			if names[i] == "" {
+				continue
+			}
+			name = names[i]
+		}
+		acc.AddCounter("nfsd_ops",
+			map[string]interface{}{"calls": calls},
+			map[string]string{"version": version, "operation": name},
+		)
+	}
+	return nil
+}
@@ -36,11 +36,13 @@ const (
 	httpLocationZonesPath = "http/location_zones"
 	httpUpstreamsPath     = "http/upstreams"
 	httpCachesPath        = "http/caches"
+	httpKeyvalsPath       = "http/keyvals"
 
 	resolverZonesPath = "resolvers"
 
 	streamServerZonesPath = "stream/server_zones"
 	streamUpstreamsPath   = "stream/upstreams"
+	streamKeyvalsPath     = "stream/keyvals"
 )
 
 var sampleConfig = `
@@ -29,6 +29,8 @@ func (n *NginxPlusApi) gatherMetrics(addr *url.URL, acc telegraf.Accumulator) {
 	addError(acc, n.gatherHttpCachesMetrics(addr, acc))
 	addError(acc, n.gatherStreamServerZonesMetrics(addr, acc))
 	addError(acc, n.gatherStreamUpstreamsMetrics(addr, acc))
+	addError(acc, n.gatherKeyvalsMetrics(addr, acc, httpKeyvalsPath, "nginx_plus_api_http_keyvals"))
+	addError(acc, n.gatherKeyvalsMetrics(addr, acc, streamKeyvalsPath, "nginx_plus_api_stream_keyvals"))
 
 	if n.ApiVersion >= 5 {
 		addError(acc, n.gatherHttpLocationZonesMetrics(addr, acc))
@@ -559,6 +561,40 @@ func (n *NginxPlusApi) gatherStreamUpstreamsMetrics(addr *url.URL, acc telegraf.
 	return nil
 }
 
+// gatherKeyvalsMetrics reports the number of keys of the keyval zones, the
+// keys and values themselves being arbitrary.
+func (n *NginxPlusApi) gatherKeyvalsMetrics(addr *url.URL, acc telegraf.Accumulator, path string, measurement string) error {
+	body, err := n.gatherUrl(addr, path)
+	if err != nil {
+		return err
+	}
+
+	var keyvals Keyvals
+
+	if err := json.Unmarshal(body, &keyvals); err != nil {
+		return err
+	}
+
+	tags := getTags(addr)
+
+	for zoneName, zone := range keyvals {
+		zoneTags := map[string]string{}
+		for k, v := range tags {
+			zoneTags[k] = v
+		}
+		zoneTags["zone"] = zoneName
+		acc.AddFields(
+			measurement,
+			map[string]interface{}{
+				"keys": len(zone),
+			},
+			zoneTags,
+		)
+	}
+
+	return nil
+}
+
 func getTags(addr *url.URL) map[string]string {
 	h := addr.Host
 	host, port, err := net.SplitHostPort(h)
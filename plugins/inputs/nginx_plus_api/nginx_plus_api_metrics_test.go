@@ -519,6 +519,16 @@ const streamServerZonesPayload = `
 }
 `
 
+const keyvalsPayload = `
+{
+  "one": {
+    "arg1": "value1",
+    "arg2": "value2"
+  },
+  "two": {}
+}
+`
+
 func TestGatherProcessesMetrics(t *testing.T) {
 	ts, n := prepareEndpoint(t, processesPath, defaultApiVersion, processesPayload)
 	defer ts.Close()
@@ -1202,6 +1212,49 @@ func TestGatherStreamServerZonesMetrics(t *testing.T) {
 		})
 }
 
+func TestGatherKeyvalsMetrics(t *testing.T) {
+	for _, tt := range []struct {
+		path        string
+		measurement string
+	}{
+		{httpKeyvalsPath, "nginx_plus_api_http_keyvals"},
+		{streamKeyvalsPath, "nginx_plus_api_stream_keyvals"},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			ts, n := prepareEndpoint(t, tt.path, defaultApiVersion, keyvalsPayload)
+			defer ts.Close()
+
+			var acc testutil.Accumulator
+			addr, host, port := prepareAddr(t, ts)
+
+			require.NoError(t, n.gatherKeyvalsMetrics(addr, &acc, tt.path, tt.measurement))
+
+			acc.AssertContainsTaggedFields(
+				t,
+				tt.measurement,
+				map[string]interface{}{
+					"keys": int(2),
+				},
+				map[string]string{
+					"source": host,
+					"port":   port,
+					"zone":   "one",
+				})
+			acc.AssertContainsTaggedFields(
+				t,
+				tt.measurement,
+				map[string]interface{}{
+					"keys": int(0),
+				},
+				map[string]string{
+					"source": host,
+					"port":   port,
+					"zone":   "two",
+				})
+		})
+	}
+}
+
 func TestUnavailableEndpoints(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -157,3 +157,5 @@ type HttpCaches map[string]struct { // added in version 2
 	Expired     ExtendedHitStats `json:"expired"`
 	Bypass      ExtendedHitStats `json:"bypass"`
 }
+
+type Keyvals map[string]map[string]string
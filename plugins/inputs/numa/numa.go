@@ -0,0 +1,250 @@
+package numa
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Collect the memory usage of each NUMA node.
+  # node_memory = true
+
+  ## Collect the allocation counters of each NUMA node, such as numa_hit and
+  ## numa_miss.
+  # node_stats = true
+
+  ## Collect the utilization of the hugepage pools, of the system and of each
+  ## NUMA node.
+  # hugepages = true
+`
+
+// NUMA gathers NUMA node and hugepage statistics from sysfs.
+type NUMA struct {
+	NodeMemory bool `toml:"node_memory"`
+	NodeStats  bool `toml:"node_stats"`
+	Hugepages  bool `toml:"hugepages"`
+
+	sysPath string
+}
+
+func (n *NUMA) Description() string {
+	return "Gather NUMA node memory and allocation statistics and hugepage pool utilization"
+}
+
+func (n *NUMA) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NUMA) Gather(acc telegraf.Accumulator) error {
+	nodes, err := filepath.Glob(filepath.Join(n.sysPath, "devices", "system", "node", "node[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range nodes {
+		node := strings.TrimPrefix(filepath.Base(dir), "node")
+		tags := map[string]string{"node": node}
+
+		if n.NodeMemory {
+			fields, err := readMeminfo(filepath.Join(dir, "meminfo"))
+			if err != nil {
+				acc.AddError(err)
+			} else {
+				acc.AddGauge("numa_memory", fields, tags)
+			}
+		}
+
+		if n.NodeStats {
+			fields, err := readNumastat(filepath.Join(dir, "numastat"))
+			if err != nil {
+				acc.AddError(err)
+			} else {
+				acc.AddCounter("numa_stat", fields, tags)
+			}
+		}
+
+		if n.Hugepages {
+			n.gatherHugepages(acc, filepath.Join(dir, "hugepages"), tags)
+		}
+	}
+
+	if n.Hugepages {
+		n.gatherHugepages(acc, filepath.Join(n.sysPath, "kernel", "mm", "hugepages"), nil)
+	}
+	return nil
+}
+
+// readMeminfo returns the memory usage in bytes of the node meminfo file,
+// which has lines like "Node 0 MemTotal:       16314140 kB".
+func readMeminfo(path string) (map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 4 || parts[0] != "Node" {
+			continue
+		}
+		key := strings.TrimSuffix(parts[2], ":")
+		// The hugepage pools are reported by the hugepages measurement
+		if strings.HasPrefix(key, "HugePages_") {
+			continue
+		}
+
+		value, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s of %s failed: %v", key, path, err)
+		}
+		if len(parts) > 4 && parts[4] == "kB" {
+			value *= 1024
+		}
+		fields[meminfoField(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	total, ok1 := fields["total"].(int64)
+	used, ok2 := fields["used"].(int64)
+	if ok1 && ok2 && total > 0 {
+		fields["used_percent"] = 100 * float64(used) / float64(total)
+	}
+	return fields, nil
+}
+
+// meminfoField returns the field name of the meminfo key, such as
+// "active_anon" for "Active(anon)".
+func meminfoField(key string) string {
+	switch key {
+	case "MemTotal":
+		return "total"
+	case "MemFree":
+		return "free"
+	case "MemUsed":
+		return "used"
+	}
+	key = strings.Replace(key, "_", "", -1)
+	key = strings.Replace(key, "(", "_", -1)
+	key = strings.Replace(key, ")", "", -1)
+	return internal.SnakeCase(key)
+}
+
+// readNumastat returns the counters of the node numastat file, which has
+// lines like "numa_hit 123456".
+func readNumastat(path string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	for _, line := range strings.Split(string(content), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s of %s failed: %v", parts[0], path, err)
+		}
+		fields[parts[0]] = value
+	}
+	return fields, nil
+}
+
+// gatherHugepages adds the utilization of the hugepage pools in the
+// directory, which has a hugepages-<size>kB directory for each page size.
+func (n *NUMA) gatherHugepages(acc telegraf.Accumulator, dir string, nodeTags map[string]string) {
+	pools, err := filepath.Glob(filepath.Join(dir, "hugepages-*kB"))
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+
+	for _, pool := range pools {
+		size := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(pool), "hugepages-"), "kB")
+		sizeKB, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		tags := map[string]string{"size": pageSize(sizeKB)}
+		for k, v := range nodeTags {
+			tags[k] = v
+		}
+
+		fields := make(map[string]interface{})
+		for file, field := range map[string]string{
+			"nr_hugepages":            "total",
+			"free_hugepages":          "free",
+			"surplus_hugepages":       "surplus",
+			"resv_hugepages":          "reserved",
+			"nr_overcommit_hugepages": "overcommit",
+		} {
+			content, err := ioutil.ReadFile(filepath.Join(pool, file))
+			if err != nil {
+				continue
+			}
+			value, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+			if err != nil {
+				acc.AddError(fmt.Errorf("parsing %s of %s failed: %v", file, pool, err))
+				continue
+			}
+			fields[field] = value
+		}
+
+		total, ok1 := fields["total"].(int64)
+		free, ok2 := fields["free"].(int64)
+		if !ok1 || !ok2 {
+			continue
+		}
+		fields["used"] = total - free
+		fields["total_bytes"] = total * sizeKB * 1024
+		fields["free_bytes"] = free * sizeKB * 1024
+		if total > 0 {
+			fields["used_percent"] = 100 * float64(total-free) / float64(total)
+		}
+		acc.AddGauge("hugepages", fields, tags)
+	}
+}
+
+// pageSize formats the page size in kilobytes, such as "2M" for 2048.
+func pageSize(kb int64) string {
+	switch {
+	case kb >= 1024*1024 && kb%(1024*1024) == 0:
+		return strconv.FormatInt(kb/(1024*1024), 10) + "G"
+	case kb >= 1024 && kb%1024 == 0:
+		return strconv.FormatInt(kb/1024, 10) + "M"
+	default:
+		return strconv.FormatInt(kb, 10) + "K"
+	}
+}
+
+func init() {
+	inputs.Add("numa", func() telegraf.Input {
+		sysPath := "/sys"
+		if hostSys := os.Getenv("HOST_SYS"); hostSys != "" {
+			sysPath = hostSys
+		}
+		return &NUMA{
+			NodeMemory: true,
+			NodeStats:  true,
+			Hugepages:  true,
+			sysPath:    sysPath,
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package numa
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const node0Meminfo = `Node 0 MemTotal:       16314140 kB
+Node 0 MemFree:         4078536 kB
+Node 0 MemUsed:        12235604 kB
+Node 0 Active(anon):    2097152 kB
+Node 0 NFS_Unstable:          0 kB
+Node 0 HugePages_Total:     512
+Node 0 HugePages_Free:      256
+`
+
+const node0Numastat = `numa_hit 123456789
+numa_miss 1024
+numa_foreign 2048
+interleave_hit 4096
+local_node 123450000
+other_node 6789
+`
+
+func write(t *testing.T, root, path, content string) {
+	path = filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestGather(t *testing.T) {
+	root, err := ioutil.TempDir("", "numa")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	write(t, root, "devices/system/node/node0/meminfo", node0Meminfo)
+	write(t, root, "devices/system/node/node0/numastat", node0Numastat)
+	write(t, root, "devices/system/node/node0/hugepages/hugepages-2048kB/nr_hugepages", "512\n")
+	write(t, root, "devices/system/node/node0/hugepages/hugepages-2048kB/free_hugepages", "256\n")
+	write(t, root, "devices/system/node/node0/hugepages/hugepages-2048kB/surplus_hugepages", "0\n")
+	write(t, root, "devices/system/node/node0/hugepages/hugepages-1048576kB/nr_hugepages", "4\n")
+	write(t, root, "devices/system/node/node0/hugepages/hugepages-1048576kB/free_hugepages", "1\n")
+	write(t, root, "devices/system/node/node0/hugepages/hugepages-1048576kB/surplus_hugepages", "0\n")
+	write(t, root, "devices/system/node/possible", "0\n")
+	write(t, root, "kernel/mm/hugepages/hugepages-2048kB/nr_hugepages", "512\n")
+	write(t, root, "kernel/mm/hugepages/hugepages-2048kB/free_hugepages", "256\n")
+	write(t, root, "kernel/mm/hugepages/hugepages-2048kB/surplus_hugepages", "0\n")
+	write(t, root, "kernel/mm/hugepages/hugepages-2048kB/resv_hugepages", "16\n")
+	write(t, root, "kernel/mm/hugepages/hugepages-2048kB/nr_overcommit_hugepages", "0\n")
+
+	plugin := &NUMA{NodeMemory: true, NodeStats: true, Hugepages: true, sysPath: root}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"numa_memory",
+			map[string]string{"node": "0"},
+			map[string]interface{}{
+				"total":        int64(16314140 * 1024),
+				"free":         int64(4078536 * 1024),
+				"used":         int64(12235604 * 1024),
+				"active_anon":  int64(2097152 * 1024),
+				"nfs_unstable": int64(0),
+				"used_percent": 100 * 12235604.0 / 16314140.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"numa_stat",
+			map[string]string{"node": "0"},
+			map[string]interface{}{
+				"numa_hit":       int64(123456789),
+				"numa_miss":      int64(1024),
+				"numa_foreign":   int64(2048),
+				"interleave_hit": int64(4096),
+				"local_node":     int64(123450000),
+				"other_node":     int64(6789),
+			},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"hugepages",
+			map[string]string{"node": "0", "size": "2M"},
+			map[string]interface{}{
+				"total":        int64(512),
+				"free":         int64(256),
+				"used":         int64(256),
+				"surplus":      int64(0),
+				"total_bytes":  int64(512 * 2048 * 1024),
+				"free_bytes":   int64(256 * 2048 * 1024),
+				"used_percent": 50.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"hugepages",
+			map[string]string{"node": "0", "size": "1G"},
+			map[string]interface{}{
+				"total":        int64(4),
+				"free":         int64(1),
+				"used":         int64(3),
+				"surplus":      int64(0),
+				"total_bytes":  int64(4 * 1024 * 1024 * 1024),
+				"free_bytes":   int64(1024 * 1024 * 1024),
+				"used_percent": 75.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"hugepages",
+			map[string]string{"size": "2M"},
+			map[string]interface{}{
+				"total":        int64(512),
+				"free":         int64(256),
+				"used":         int64(256),
+				"surplus":      int64(0),
+				"reserved":     int64(16),
+				"overcommit":   int64(0),
+				"total_bytes":  int64(512 * 2048 * 1024),
+				"free_bytes":   int64(256 * 2048 * 1024),
+				"used_percent": 50.0,
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherDisabled(t *testing.T) {
+	root, err := ioutil.TempDir("", "numa")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	write(t, root, "devices/system/node/node0/meminfo", node0Meminfo)
+	write(t, root, "devices/system/node/node0/numastat", node0Numastat)
+
+	plugin := &NUMA{NodeStats: true, sysPath: root}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "numa_stat", acc.Metrics[0].Measurement)
+}
+
+func TestPageSize(t *testing.T) {
+	require.Equal(t, "64K", pageSize(64))
+	require.Equal(t, "2M", pageSize(2048))
+	require.Equal(t, "32M", pageSize(32768))
+	require.Equal(t, "1G", pageSize(1048576))
+	require.Equal(t, "16G", pageSize(16777216))
+}
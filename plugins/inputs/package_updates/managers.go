@@ -0,0 +1,233 @@
+package package_updates
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dpkgTimeLayout is the layout of the times of the dpkg log, in local time.
+const dpkgTimeLayout = "2006-01-02 15:04:05"
+
+// gatherApt counts the packages upgraded by a simulated dist-upgrade, the
+// security updates being those from a security origin such as
+// "Debian-Security:10/stable" or "Ubuntu:18.04/bionic-security".
+func (p *PackageUpdates) gatherApt() (*updates, error) {
+	out, code, err := p.run(p.Timeout.Duration, p.UseSudo, "apt-get", "--simulate", "--quiet", "dist-upgrade")
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("apt-get exited with status %d", code)
+	}
+
+	u := &updates{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Inst ") {
+			continue
+		}
+		u.updates++
+		if strings.Contains(strings.ToLower(line), "security") {
+			u.security++
+		}
+	}
+
+	reboot := p.exists("/var/run/reboot-required")
+	u.rebootRequired = &reboot
+	u.lastUpdate = p.lastDpkgUpgrade()
+	return u, nil
+}
+
+// lastDpkgUpgrade returns the time of the last upgrade of a package in the
+// dpkg log, or in the previous log once rotated.
+func (p *PackageUpdates) lastDpkgUpgrade() time.Time {
+	for _, name := range []string{"/var/log/dpkg.log", "/var/log/dpkg.log.1"} {
+		data, err := ioutil.ReadFile(p.path(name))
+		if err != nil {
+			continue
+		}
+
+		var last time.Time
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 3 || fields[2] != "upgrade" {
+				continue
+			}
+			t, err := time.ParseInLocation(dpkgTimeLayout, fields[0]+" "+fields[1], time.Local)
+			if err == nil && t.After(last) {
+				last = t
+			}
+		}
+		if !last.IsZero() {
+			return last
+		}
+	}
+	return time.Time{}
+}
+
+// gatherYum counts the packages of "check-update" and the packages of the
+// security advisories of dnf and yum.
+func (p *PackageUpdates) gatherYum() (*updates, error) {
+	binary := p.PackageManager
+	out, code, err := p.run(p.Timeout.Duration, p.UseSudo, binary, "-q", "check-update")
+	if err != nil {
+		return nil, err
+	}
+	// check-update exits with 100 when updates are available.
+	if code != 0 && code != 100 {
+		return nil, fmt.Errorf("%s check-update exited with status %d", binary, code)
+	}
+
+	u := &updates{updates: countCheckUpdate(out)}
+
+	args := []string{"-q", "updateinfo", "list", "--security"}
+	if p.PackageManager == managerYum {
+		args = []string{"-q", "updateinfo", "list", "security"}
+	}
+	out, code, err = p.run(p.Timeout.Duration, p.UseSudo, binary, args...)
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("%s updateinfo exited with status %d", binary, code)
+	}
+	u.security = countSecurityPackages(out)
+
+	// needs-restarting exits with 1 when a reboot is required, it is not
+	// reported when the command is not installed.
+	restart := []string{"needs-restarting", "-r"}
+	if p.PackageManager == managerDnf {
+		restart = []string{"dnf", "needs-restarting", "-r"}
+	}
+	if _, code, err := p.run(p.Timeout.Duration, p.UseSudo, restart[0], restart[1:]...); err == nil && code <= 1 {
+		reboot := code == 1
+		u.rebootRequired = &reboot
+	}
+
+	u.lastUpdate = p.lastRpmInstall()
+	return u, nil
+}
+
+// countCheckUpdate counts the packages of the output of check-update, the
+// lines of the packages with long names are wrapped.
+func countCheckUpdate(out []byte) int {
+	count := 0
+	var pending []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Obsoleting Packages") {
+			break
+		}
+		fields := append(pending, strings.Fields(line)...)
+		pending = nil
+		switch {
+		case len(fields) == 3 && strings.Contains(fields[0], "."):
+			count++
+		case len(fields) == 1 && strings.Contains(fields[0], "."):
+			pending = fields
+		}
+	}
+	return count
+}
+
+// countSecurityPackages counts the packages of the output of updateinfo
+// list, such as "RHSA-2020:1234 Important/Sec. kernel-3.10.0.x86_64".
+func countSecurityPackages(out []byte) int {
+	packages := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 {
+			packages[fields[2]] = true
+		}
+	}
+	return len(packages)
+}
+
+// lastRpmInstall returns the time of the last package installed.
+func (p *PackageUpdates) lastRpmInstall() time.Time {
+	out, code, err := p.run(p.Timeout.Duration, false, "rpm", "-qa", "--queryformat", "%{INSTALLTIME}\n")
+	if err != nil || code != 0 {
+		return time.Time{}
+	}
+
+	var last int64
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		t, err := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 64)
+		if err == nil && t > last {
+			last = t
+		}
+	}
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(last, 0)
+}
+
+// zypperUpdates is the XML output of the updates of zypper.
+type zypperUpdates struct {
+	Updates []struct {
+		Kind     string `xml:"kind,attr"`
+		Category string `xml:"category,attr"`
+	} `xml:"update-status>update-list>update"`
+}
+
+// gatherZypper counts the package updates and the security patches of
+// zypper.
+func (p *PackageUpdates) gatherZypper() (*updates, error) {
+	u := &updates{}
+
+	updates, err := p.zypper("list-updates")
+	if err != nil {
+		return nil, err
+	}
+	for _, update := range updates.Updates {
+		if update.Kind == "package" {
+			u.updates++
+		}
+	}
+
+	patches, err := p.zypper("list-patches", "--category", "security")
+	if err != nil {
+		return nil, err
+	}
+	for _, patch := range patches.Updates {
+		if patch.Kind == "patch" && patch.Category == "security" {
+			u.security++
+		}
+	}
+
+	reboot := p.exists("/run/reboot-needed")
+	u.rebootRequired = &reboot
+	u.lastUpdate = p.lastRpmInstall()
+	return u, nil
+}
+
+func (p *PackageUpdates) zypper(args ...string) (*zypperUpdates, error) {
+	args = append([]string{"--non-interactive", "--quiet", "--xmlout"}, args...)
+	out, code, err := p.run(p.Timeout.Duration, p.UseSudo, "zypper", args...)
+	if err != nil {
+		return nil, err
+	}
+	// zypper exits with 100 when updates are needed, and 101 for security
+	// updates.
+	if code != 0 && code != 100 && code != 101 {
+		return nil, fmt.Errorf("zypper %s exited with status %d", args[3], code)
+	}
+
+	var updates zypperUpdates
+	if err := xml.Unmarshal(out, &updates); err != nil {
+		return nil, fmt.Errorf("error parsing the output of zypper: %v", err)
+	}
+	return &updates, nil
+}
@@ -0,0 +1,201 @@
+package package_updates
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	managerApt    = "apt"
+	managerDnf    = "dnf"
+	managerYum    = "yum"
+	managerZypper = "zypper"
+)
+
+// managerBinaries are the binaries looked up to detect the package manager,
+// in order.
+var managerBinaries = []struct {
+	manager string
+	binary  string
+}{
+	{managerApt, "apt-get"},
+	{managerDnf, "dnf"},
+	{managerYum, "yum"},
+	{managerZypper, "zypper"},
+}
+
+// runner runs a command and returns its output and exit code, the error is
+// set when the command could not be run.
+type runner func(timeout time.Duration, useSudo bool, command string, args ...string) ([]byte, int, error)
+
+// PackageUpdates gathers the pending updates of the packages of the host and
+// whether it needs a reboot.
+type PackageUpdates struct {
+	PackageManager string            `toml:"package_manager"`
+	UseSudo        bool              `toml:"use_sudo"`
+	Timeout        internal.Duration `toml:"timeout"`
+
+	run      runner
+	lookPath func(file string) (string, error)
+	// root is the prefix of the files read, for tests.
+	root string
+	now  func() time.Time
+}
+
+// updates is the state of the packages of the host.
+type updates struct {
+	updates        int
+	security       int
+	rebootRequired *bool
+	lastUpdate     time.Time
+}
+
+var sampleConfig = `
+  ## Checking the updates may take a while and load the repositories, gather
+  ## them less often than the other inputs.
+  interval = "1h"
+
+  ## Package manager, one of "apt", "dnf", "yum" or "zypper", detected from
+  ## the binaries in the PATH when empty.
+  # package_manager = ""
+
+  ## Run the package manager with sudo.
+  # use_sudo = false
+
+  ## Timeout of the commands.
+  # timeout = "5m"
+`
+
+func (p *PackageUpdates) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PackageUpdates) Description() string {
+	return "Read the pending package updates and reboot required state of the host"
+}
+
+func (p *PackageUpdates) Init() error {
+	switch p.PackageManager {
+	case managerApt, managerDnf, managerYum, managerZypper:
+		return nil
+	case "":
+	default:
+		return fmt.Errorf("unknown package manager %q", p.PackageManager)
+	}
+
+	for _, m := range managerBinaries {
+		if _, err := p.lookPath(m.binary); err == nil {
+			p.PackageManager = m.manager
+			return nil
+		}
+	}
+	return fmt.Errorf("no package manager found, install one of apt, dnf, yum or zypper")
+}
+
+func (p *PackageUpdates) Gather(acc telegraf.Accumulator) error {
+	var u *updates
+	var err error
+	switch p.PackageManager {
+	case managerApt:
+		u, err = p.gatherApt()
+	case managerDnf, managerYum:
+		u, err = p.gatherYum()
+	case managerZypper:
+		u, err = p.gatherZypper()
+	}
+	if err != nil {
+		return err
+	}
+
+	tags := map[string]string{"package_manager": p.PackageManager}
+	release := p.osRelease()
+	if id := release["ID"]; id != "" {
+		tags["os"] = id
+	}
+	if version := release["VERSION_ID"]; version != "" {
+		tags["os_version"] = version
+	}
+
+	fields := map[string]interface{}{
+		"updates":          u.updates,
+		"security_updates": u.security,
+	}
+	if u.rebootRequired != nil {
+		fields["reboot_required"] = *u.rebootRequired
+	}
+	if !u.lastUpdate.IsZero() {
+		fields["last_update"] = u.lastUpdate.Unix()
+		fields["days_since_last_update"] = p.now().Sub(u.lastUpdate).Hours() / 24
+	}
+	acc.AddFields("package_updates", fields, tags)
+	return nil
+}
+
+// path returns the path of the file of the host.
+func (p *PackageUpdates) path(name string) string {
+	return filepath.Join(p.root, name)
+}
+
+// exists returns whether the file of the host exists.
+func (p *PackageUpdates) exists(name string) bool {
+	_, err := os.Stat(p.path(name))
+	return err == nil
+}
+
+// osRelease returns the variables of the os-release file.
+func (p *PackageUpdates) osRelease() map[string]string {
+	release := make(map[string]string)
+	data, err := ioutil.ReadFile(p.path("/etc/os-release"))
+	if err != nil {
+		return release
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(parts) == 2 {
+			release[parts[0]] = strings.Trim(parts[1], `"'`)
+		}
+	}
+	return release
+}
+
+func execRunner(timeout time.Duration, useSudo bool, command string, args ...string) ([]byte, int, error) {
+	cmd := exec.Command(command, args...)
+	if useSudo {
+		cmd = exec.Command("sudo", append([]string{"-n", command}, args...)...)
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := internal.RunTimeout(cmd, timeout)
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return out.Bytes(), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("error running %s: %v", command, err)
+	}
+	return out.Bytes(), 0, nil
+}
+
+func init() {
+	inputs.Add("package_updates", func() telegraf.Input {
+		return &PackageUpdates{
+			Timeout:  internal.Duration{Duration: 5 * time.Minute},
+			run:      execRunner,
+			lookPath: exec.LookPath,
+			now:      time.Now,
+		}
+	})
+}
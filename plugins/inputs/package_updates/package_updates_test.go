@@ -0,0 +1,273 @@
+package package_updates
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const aptOutput = `NOTE: This is only a simulation!
+      apt-get needs root privileges for real execution.
+Reading package lists...
+Building dependency tree...
+Calculating upgrade...
+The following packages will be upgraded:
+  libssl1.1 openssl tzdata
+3 upgraded, 0 newly installed, 0 to remove and 0 not upgraded.
+Inst libssl1.1 [1.1.1d-0+deb10u2] (1.1.1d-0+deb10u3 Debian-Security:10/stable [amd64])
+Inst openssl [1.1.1d-0+deb10u2] (1.1.1d-0+deb10u3 Debian-Security:10/stable [amd64])
+Inst tzdata [2019c-0+deb10u1] (2020a-0+deb10u1 Debian:10.4/stable-updates [all])
+Conf libssl1.1 (1.1.1d-0+deb10u3 Debian-Security:10/stable [amd64])
+Conf openssl (1.1.1d-0+deb10u3 Debian-Security:10/stable [amd64])
+Conf tzdata (2020a-0+deb10u1 Debian:10.4/stable-updates [all])
+`
+
+const dpkgLog = `2020-05-01 06:25:01 startup archives unpack
+2020-05-01 06:25:02 upgrade curl:amd64 7.64.0-4 7.64.0-4+deb10u1
+2020-05-01 06:25:03 status installed curl:amd64 7.64.0-4+deb10u1
+2020-05-10 08:00:00 install htop:amd64 <none> 2.2.0-1+b1
+`
+
+const dnfCheckUpdate = `
+kernel.x86_64                         4.18.0-193.1.2.el8_2             BaseOS
+openssl-libs.x86_64                   1:1.1.1c-15.el8                  BaseOS
+python3-very-long-package-name-for-wrapping.noarch
+                                      1.0-2.el8                        AppStream
+`
+
+const dnfSecurity = `RHSA-2020:2102 Important/Sec. kernel-4.18.0-193.1.2.el8_2.x86_64
+RHSA-2020:2102 Important/Sec. kernel-core-4.18.0-193.1.2.el8_2.x86_64
+RHSA-2020:1234 Moderate/Sec.  kernel-4.18.0-193.1.2.el8_2.x86_64
+`
+
+const zypperUpdatesXML = `<?xml version='1.0'?>
+<stream>
+<update-status version="0.6">
+<update-list>
+<update name="curl" edition="7.66.0-4.3.1" arch="x86_64" kind="package"/>
+<update name="libopenssl1_1" edition="1.1.1d-2.20.1" arch="x86_64" kind="package"/>
+</update-list>
+</update-status>
+</stream>`
+
+const zypperPatchesXML = `<?xml version='1.0'?>
+<stream>
+<update-status version="0.6">
+<update-list>
+<update name="SUSE-2020-1234" edition="1" arch="noarch" kind="patch" category="security" severity="important"/>
+</update-list>
+</update-status>
+</stream>`
+
+type command struct {
+	out  string
+	code int
+	err  error
+}
+
+var now = time.Date(2020, 5, 14, 12, 0, 0, 0, time.UTC)
+
+func newPackageUpdates(t *testing.T, manager string, commands map[string]command, files map[string]string) (*PackageUpdates, func()) {
+	root, err := ioutil.TempDir("", "package_updates")
+	require.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	p := &PackageUpdates{
+		PackageManager: manager,
+		run: func(timeout time.Duration, useSudo bool, name string, args ...string) ([]byte, int, error) {
+			c, ok := commands[name+" "+strings.Join(args, " ")]
+			if !ok {
+				return nil, 0, errors.New("executable file not found")
+			}
+			return []byte(c.out), c.code, c.err
+		},
+		root: root,
+		now:  func() time.Time { return now },
+	}
+	require.NoError(t, p.Init())
+	return p, func() { os.RemoveAll(root) }
+}
+
+func TestApt(t *testing.T) {
+	p, cleanup := newPackageUpdates(t, "apt",
+		map[string]command{
+			"apt-get --simulate --quiet dist-upgrade": {out: aptOutput},
+		},
+		map[string]string{
+			"/etc/os-release":          "ID=debian\nVERSION_ID=\"10\"\n",
+			"/var/run/reboot-required": "*** System restart required ***\n",
+			"/var/log/dpkg.log":        dpkgLog,
+		})
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	lastUpdate := time.Date(2020, 5, 1, 6, 25, 2, 0, time.Local)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"package_updates",
+			map[string]string{
+				"package_manager": "apt",
+				"os":              "debian",
+				"os_version":      "10",
+			},
+			map[string]interface{}{
+				"updates":                3,
+				"security_updates":       2,
+				"reboot_required":        true,
+				"last_update":            lastUpdate.Unix(),
+				"days_since_last_update": now.Sub(lastUpdate).Hours() / 24,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestDnf(t *testing.T) {
+	p, cleanup := newPackageUpdates(t, "dnf",
+		map[string]command{
+			"dnf -q check-update":                    {out: dnfCheckUpdate, code: 100},
+			"dnf -q updateinfo list --security":      {out: dnfSecurity},
+			"dnf needs-restarting -r":                {code: 0},
+			"rpm -qa --queryformat %{INSTALLTIME}\n": {out: "1589000000\n1589400000\n1588000000\n"},
+		},
+		map[string]string{
+			"/etc/os-release": "NAME=\"CentOS Linux\"\nID=\"centos\"\nVERSION_ID=\"8\"\n",
+		})
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"package_updates",
+			map[string]string{
+				"package_manager": "dnf",
+				"os":              "centos",
+				"os_version":      "8",
+			},
+			map[string]interface{}{
+				"updates":                3,
+				"security_updates":       2,
+				"reboot_required":        false,
+				"last_update":            int64(1589400000),
+				"days_since_last_update": float64(now.Unix()-1589400000) / 86400,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestYumWithoutNeedsRestarting(t *testing.T) {
+	p, cleanup := newPackageUpdates(t, "yum",
+		map[string]command{
+			"yum -q check-update":                    {code: 0},
+			"yum -q updateinfo list security":        {},
+			"rpm -qa --queryformat %{INSTALLTIME}\n": {out: "1589400000\n"},
+		},
+		nil)
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"package_updates",
+			map[string]string{"package_manager": "yum"},
+			map[string]interface{}{
+				"updates":                0,
+				"security_updates":       0,
+				"last_update":            int64(1589400000),
+				"days_since_last_update": float64(now.Unix()-1589400000) / 86400,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestYumError(t *testing.T) {
+	p, cleanup := newPackageUpdates(t, "yum",
+		map[string]command{
+			"yum -q check-update": {code: 1},
+		},
+		nil)
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(p.Gather))
+}
+
+func TestZypper(t *testing.T) {
+	p, cleanup := newPackageUpdates(t, "zypper",
+		map[string]command{
+			"zypper --non-interactive --quiet --xmlout list-updates":                     {out: zypperUpdatesXML, code: 100},
+			"zypper --non-interactive --quiet --xmlout list-patches --category security": {out: zypperPatchesXML, code: 101},
+		},
+		map[string]string{
+			"/etc/os-release":    "ID=\"sles\"\nVERSION_ID=\"15.1\"\n",
+			"/run/reboot-needed": "",
+		})
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"package_updates",
+			map[string]string{
+				"package_manager": "zypper",
+				"os":              "sles",
+				"os_version":      "15.1",
+			},
+			map[string]interface{}{
+				"updates":          2,
+				"security_updates": 1,
+				"reboot_required":  true,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	p := &PackageUpdates{
+		lookPath: func(file string) (string, error) {
+			if file == "dnf" || file == "yum" {
+				return "/usr/bin/" + file, nil
+			}
+			return "", errors.New("not found")
+		},
+	}
+	require.NoError(t, p.Init())
+	require.Equal(t, "dnf", p.PackageManager)
+
+	p = &PackageUpdates{
+		lookPath: func(file string) (string, error) {
+			return "", errors.New("not found")
+		},
+	}
+	require.Error(t, p.Init())
+
+	p = &PackageUpdates{PackageManager: "pacman"}
+	require.Error(t, p.Init())
+}
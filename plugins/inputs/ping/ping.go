@@ -15,6 +15,8 @@ import (
 	"github.com/glinton/ping"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/discovery"
+	"github.com/influxdata/telegraf/internal/k8sdiscovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -57,6 +59,18 @@ type Ping struct {
 	// Whether to resolve addresses using ipv6 or not.
 	IPv6 bool
 
+	// Discovery of the hosts from Kubernetes pods
+	URLsFromKubernetes *k8sdiscovery.Config `toml:"urls_from_kubernetes"`
+
+	// Discovery of the hosts from files, DNS SRV records, Consul and
+	// Kubernetes services
+	Discovery *discovery.Config `toml:"discovery"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	discovery *k8sdiscovery.Discovery
+	targets   *discovery.Discovery
+
 	// host ping function
 	pingHost HostPinger
 
@@ -111,12 +125,86 @@ const sampleConfig = `
 
   ## Use only IPv6 addresses when resolving a hostname.
   # ipv6 = false
+
+  ## Ping the IPs of the Kubernetes pods with the "prometheus.io/scrape"
+  ## annotation set to "true".  The annotations and labels of the pods are
+  ## added as tags.
+  # [inputs.ping.urls_from_kubernetes]
+  #   ## Prefix of the annotations.
+  #   annotation_prefix = "prometheus.io"
+  #   ## Restricts the discovery to a namespace, all namespaces by default.
+  #   namespace = ""
+  #   ## Kubernetes config file, used when not running in a pod.
+  #   kube_config = "/path/to/kubernetes.config"
+  #   ## Annotations and labels not added as tags.
+  #   exclude_annotations = []
+  #   exclude_labels = []
+  #   ## Interval of the full resync of the pods with the API server.
+  #   resync_interval = "5m"
+
+  ## Ping the hosts discovered from files, DNS SRV records, Consul services
+  ## or Kubernetes services.  The hosts are refreshed periodically, without
+  ## reloading the configuration.
+  # [inputs.ping.discovery]
+  #   ## Files with one host per line, followed by optional "key=value" tags.
+  #   ## Lines starting with "#" are skipped.
+  #   files = ["/etc/telegraf/ping_targets"]
+  #   ## Names of DNS SRV records.
+  #   dns_srv = ["_http._tcp.example.org"]
+  #   ## Interval of the refresh of the files, records and Consul services.
+  #   refresh_interval = "1m"
+  #
+  #   ## Instances of Consul services, the healthy ones unless failing ones
+  #   ## are included.
+  #   [inputs.ping.discovery.consul]
+  #     address = "localhost:8500"
+  #     services = ["web"]
+  #     # tag = ""
+  #     # datacenter = ""
+  #     # token = ""
+  #     # include_failing = false
+  #
+  #   ## Kubernetes services with the "prometheus.io/scrape" annotation set
+  #   ## to "true", at their cluster IP.
+  #   [inputs.ping.discovery.kubernetes]
+  #     role = "service"
+  #     # namespace = ""
+  #     # kube_config = "/path/to/kubernetes.config"
 `
 
 func (*Ping) SampleConfig() string {
 	return sampleConfig
 }
 
+// Start starts the discovery of the Kubernetes pods and of the hosts if
+// enabled in the configuration.
+func (p *Ping) Start(_ telegraf.Accumulator) error {
+	if p.URLsFromKubernetes != nil {
+		p.discovery = k8sdiscovery.New(*p.URLsFromKubernetes, p.Log)
+		if err := p.discovery.Start(); err != nil {
+			return err
+		}
+	}
+
+	if p.Discovery != nil {
+		p.targets = discovery.New(*p.Discovery, p.Log)
+		if err := p.targets.Start(); err != nil {
+			p.Stop()
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Ping) Stop() {
+	if p.discovery != nil {
+		p.discovery.Stop()
+	}
+	if p.targets != nil {
+		p.targets.Stop()
+	}
+}
+
 func (p *Ping) Gather(acc telegraf.Accumulator) error {
 	if p.Interface != "" && p.listenAddr == "" {
 		p.listenAddr = getAddr(p.Interface)
@@ -130,17 +218,20 @@ func (p *Ping) Gather(acc telegraf.Accumulator) error {
 			continue
 		}
 
-		p.wg.Add(1)
-		go func(host string) {
-			defer p.wg.Done()
+		p.ping(host, acc)
+	}
 
-			switch p.Method {
-			case "native":
-				p.pingToURLNative(host, acc)
-			default:
-				p.pingToURL(host, acc)
-			}
-		}(host)
+	// The pods are pinged by IP, there is no name to resolve
+	if p.discovery != nil {
+		for _, t := range p.discovery.Targets() {
+			p.ping(t.Address, t.Accumulator(acc))
+		}
+	}
+
+	if p.targets != nil {
+		for _, t := range p.targets.Targets() {
+			p.ping(t.Host, t.Accumulator(acc))
+		}
 	}
 
 	p.wg.Wait()
@@ -148,6 +239,21 @@ func (p *Ping) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// ping pings the host in a goroutine of the wait group.
+func (p *Ping) ping(host string, acc telegraf.Accumulator) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		switch p.Method {
+		case "native":
+			p.pingToURLNative(host, acc)
+		default:
+			p.pingToURL(host, acc)
+		}
+	}()
+}
+
 func getAddr(iface string) string {
 	if addr := net.ParseIP(iface); addr != nil {
 		return addr.String()
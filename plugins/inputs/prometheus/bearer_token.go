@@ -0,0 +1,64 @@
+package prometheus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bearerTokenRefreshInterval is how often the token file is read again even
+// when its modification time did not change.
+const bearerTokenRefreshInterval = time.Minute
+
+// bearerTokenFile caches the token of a file, such as the projected service
+// account token of a pod, which the kubelet rotates before it expires.
+type bearerTokenFile struct {
+	path string
+
+	lock    sync.Mutex
+	token   string
+	modTime time.Time
+	readAt  time.Time
+	now     func() time.Time
+}
+
+func newBearerTokenFile(path string) *bearerTokenFile {
+	return &bearerTokenFile{path: path, now: time.Now}
+}
+
+// Token returns the token of the file, read again when the file was modified
+// or after the refresh interval.  The last token read is returned if the file
+// cannot be read anymore.
+func (b *bearerTokenFile) Token() (string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.now()
+	info, err := os.Stat(b.path)
+	if err == nil && b.token != "" && info.ModTime().Equal(b.modTime) &&
+		now.Sub(b.readAt) < bearerTokenRefreshInterval {
+		return b.token, nil
+	}
+
+	var token []byte
+	if err == nil {
+		token, err = ioutil.ReadFile(b.path)
+	}
+	if err != nil {
+		if b.token != "" {
+			return b.token, nil
+		}
+		return "", err
+	}
+
+	b.token = strings.TrimSpace(string(token))
+	if b.token == "" {
+		return "", fmt.Errorf("bearer token file %q is empty", b.path)
+	}
+	b.modTime = info.ModTime()
+	b.readAt = now
+	return b.token, nil
+}
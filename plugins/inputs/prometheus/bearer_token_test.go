@@ -0,0 +1,80 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBearerTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bearer_token")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+
+	now := time.Now()
+	b := newBearerTokenFile(path)
+	b.now = func() time.Time { return now }
+
+	token, err := b.Token()
+	require.NoError(t, err)
+	require.Equal(t, "first", token)
+
+	// A rotated token is read when the modification time changes.
+	require.NoError(t, ioutil.WriteFile(path, []byte("second"), 0600))
+	require.NoError(t, os.Chtimes(path, now, now.Add(time.Second)))
+	token, err = b.Token()
+	require.NoError(t, err)
+	require.Equal(t, "second", token)
+
+	// The last token is kept when the file disappears.
+	require.NoError(t, os.Remove(path))
+	now = now.Add(2 * bearerTokenRefreshInterval)
+	token, err = b.Token()
+	require.NoError(t, err)
+	require.Equal(t, "second", token)
+}
+
+func TestBearerTokenFileRefresh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bearer_token")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	modTime := time.Now().Add(-time.Hour)
+	require.NoError(t, ioutil.WriteFile(path, []byte("first"), 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	now := time.Now()
+	b := newBearerTokenFile(path)
+	b.now = func() time.Time { return now }
+
+	token, err := b.Token()
+	require.NoError(t, err)
+	require.Equal(t, "first", token)
+
+	// Rewritten with the same modification time, the token is cached until
+	// the refresh interval has passed.
+	require.NoError(t, ioutil.WriteFile(path, []byte("second"), 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	token, err = b.Token()
+	require.NoError(t, err)
+	require.Equal(t, "first", token)
+
+	now = now.Add(bearerTokenRefreshInterval)
+	token, err = b.Token()
+	require.NoError(t, err)
+	require.Equal(t, "second", token)
+}
+
+func TestBearerTokenFileMissing(t *testing.T) {
+	b := newBearerTokenFile("/nonexistent/token")
+	_, err := b.Token()
+	require.Error(t, err)
+}
@@ -0,0 +1,238 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/influxdata/telegraf"
+	"gopkg.in/fsnotify.v1"
+)
+
+// fileSDRefreshInterval is the interval of the reload of the target files,
+// in case a change was not notified.
+const fileSDRefreshInterval = 5 * time.Minute
+
+// fileSDGroup is a group of targets of a target file, in the format of the
+// file_sd_configs of Prometheus.
+type fileSDGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// fileSD keeps the targets of the target files, reloaded when the files
+// change.
+type fileSD struct {
+	patterns []string
+	log      telegraf.Logger
+
+	lock sync.Mutex
+	// targets by file
+	targets map[string][]URLAndAddress
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newFileSD(patterns []string, log telegraf.Logger) *fileSD {
+	return &fileSD{
+		patterns: patterns,
+		log:      log,
+		targets:  make(map[string][]URLAndAddress),
+	}
+}
+
+// Start loads the files and watches their directories, the directories are
+// watched rather than the files since the files are usually replaced.
+func (f *fileSD) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dirs := make(map[string]bool)
+	for _, pattern := range f.patterns {
+		dir := filepath.Dir(pattern)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("watching %q failed: %v", dir, err)
+		}
+	}
+	f.watcher = watcher
+
+	f.reload()
+
+	f.done = make(chan struct{})
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(fileSDRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.done:
+				return
+			case event := <-watcher.Events:
+				if f.matches(event.Name) {
+					f.reload()
+				}
+			case err := <-watcher.Errors:
+				f.log.Errorf("Watching the target files failed: %v", err)
+			case <-ticker.C:
+				f.reload()
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops watching the files.
+func (f *fileSD) Stop() {
+	if f.done != nil {
+		close(f.done)
+		f.wg.Wait()
+		f.done = nil
+	}
+	if f.watcher != nil {
+		f.watcher.Close()
+		f.watcher = nil
+	}
+}
+
+// Targets returns the targets of all the files.
+func (f *fileSD) Targets() []URLAndAddress {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var targets []URLAndAddress
+	for _, fileTargets := range f.targets {
+		targets = append(targets, fileTargets...)
+	}
+	return targets
+}
+
+func (f *fileSD) matches(name string) bool {
+	for _, pattern := range f.patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reload reads the files matching the patterns.  The previous targets of a
+// file are kept when it is invalid, so a partially written file does not
+// drop its targets.
+func (f *fileSD) reload() {
+	targets := make(map[string][]URLAndAddress)
+
+	for _, pattern := range f.patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			f.log.Errorf("Invalid target file pattern %q: %v", pattern, err)
+			continue
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			fileTargets, err := readFileSD(file)
+			if err != nil {
+				f.log.Errorf("Reading target file %q failed: %v", file, err)
+				f.lock.Lock()
+				fileTargets = f.targets[file]
+				f.lock.Unlock()
+			}
+			targets[file] = fileTargets
+		}
+	}
+
+	f.lock.Lock()
+	f.targets = targets
+	f.lock.Unlock()
+}
+
+// readFileSD returns the targets of the JSON or YAML file.
+func readFileSD(file string) ([]URLAndAddress, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []fileSDGroup
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		err = json.Unmarshal(content, &groups)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(content, &groups)
+	default:
+		return nil, fmt.Errorf("unknown format, the extension must be .json, .yml or .yaml")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []URLAndAddress
+	for _, group := range groups {
+		groupTargets, err := fileSDTargets(group)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, groupTargets...)
+	}
+	return targets, nil
+}
+
+// fileSDTargets returns the targets of the group.  The "host:port" targets
+// are scraped with the "__scheme__", "__metrics_path__" and
+// "__scrape_timeout__" labels, the other labels not starting with "__" are
+// tags.
+func fileSDTargets(group fileSDGroup) ([]URLAndAddress, error) {
+	scheme := "http"
+	path := "/metrics"
+	var timeout time.Duration
+	tags := make(map[string]string)
+	for k, v := range group.Labels {
+		switch {
+		case k == "__scheme__":
+			scheme = v
+		case k == "__metrics_path__":
+			path = v
+		case k == "__scrape_timeout__":
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid scrape timeout %q", v)
+			}
+			timeout = d
+		case !strings.HasPrefix(k, "__"):
+			tags[k] = v
+		}
+	}
+
+	targets := make([]URLAndAddress, 0, len(group.Targets))
+	for _, target := range group.Targets {
+		u, err := url.Parse(scheme + "://" + target + path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %v", target, err)
+		}
+		targets = append(targets, URLAndAddress{
+			URL:         u,
+			Address:     u.Hostname(),
+			OriginalURL: u,
+			Tags:        tags,
+			Timeout:     timeout,
+		})
+	}
+	return targets, nil
+}
@@ -0,0 +1,99 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const fileSDJSON = `[
+  {
+    "targets": ["10.0.0.1:9100", "10.0.0.2:9100"],
+    "labels": {"env": "prod", "job": "node"}
+  },
+  {
+    "targets": ["10.0.0.3:8443"],
+    "labels": {"__scheme__": "https", "__metrics_path__": "/probe", "__scrape_timeout__": "2s", "__meta_zone": "a"}
+  }
+]`
+
+func TestFileSDTargets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "targets.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(fileSDJSON), 0644))
+
+	targets, err := readFileSD(file)
+	require.NoError(t, err)
+	require.Len(t, targets, 3)
+
+	require.Equal(t, "http://10.0.0.1:9100/metrics", targets[0].URL.String())
+	require.Equal(t, "10.0.0.1", targets[0].Address)
+	require.Equal(t, map[string]string{"env": "prod", "job": "node"}, targets[0].Tags)
+	require.Equal(t, "http://10.0.0.2:9100/metrics", targets[1].URL.String())
+	require.Equal(t, "https://10.0.0.3:8443/probe", targets[2].URL.String())
+	require.Empty(t, targets[2].Tags)
+	require.Equal(t, time.Duration(0), targets[0].Timeout)
+	require.Equal(t, 2*time.Second, targets[2].Timeout)
+}
+
+func TestFileSDUnknownFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "targets.txt")
+	require.NoError(t, ioutil.WriteFile(file, []byte(fileSDJSON), 0644))
+
+	_, err = readFileSD(file)
+	require.Error(t, err)
+}
+
+func TestFileSDReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "targets.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(fileSDJSON), 0644))
+
+	f := newFileSD([]string{filepath.Join(dir, "*.json")}, testutil.Logger{})
+	f.reload()
+	require.Len(t, f.Targets(), 3)
+
+	// The targets of an invalid file are kept.
+	require.NoError(t, ioutil.WriteFile(file, []byte(`[{"targets": [`), 0644))
+	f.reload()
+	require.Len(t, f.Targets(), 3)
+
+	require.NoError(t, os.Remove(file))
+	f.reload()
+	require.Empty(t, f.Targets())
+}
+
+func TestFileSDWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := newFileSD([]string{filepath.Join(dir, "*.json")}, testutil.Logger{})
+	require.NoError(t, f.Start())
+	defer f.Stop()
+	require.Empty(t, f.Targets())
+
+	file := filepath.Join(dir, "targets.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(fileSDJSON), 0644))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(f.Targets()) != 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, f.Targets(), 3)
+}
@@ -0,0 +1,272 @@
+package prometheus
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// The fields of the native histograms in the Histogram message of the
+// protobuf format, unknown to the vendored client model.
+const (
+	fieldSampleCountFloat = 4
+	fieldSchema           = 5
+	fieldZeroThreshold    = 6
+	fieldZeroCount        = 7
+	fieldZeroCountFloat   = 8
+	fieldNegativeSpan     = 9
+	fieldNegativeDelta    = 10
+	fieldNegativeCount    = 11
+	fieldPositiveSpan     = 12
+	fieldPositiveDelta    = 13
+	fieldPositiveCount    = 14
+)
+
+// The wire types of the protobuf encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errInvalidProtobuf = errors.New("invalid protobuf encoding")
+
+// bucketSpan is a run of consecutive buckets of a native histogram, starting
+// offset buckets after the end of the previous span.
+type bucketSpan struct {
+	offset int32
+	length uint32
+}
+
+// nativeHistogram is a native histogram, with exponential buckets whose
+// resolution is given by the schema.  The counts of the integer histograms
+// are delta encoded, the float histograms have absolute counts.
+type nativeHistogram struct {
+	sampleCountFloat float64
+	schema           int32
+	zeroThreshold    float64
+	zeroCount        float64
+
+	negativeSpans  []bucketSpan
+	negativeDeltas []int64
+	negativeCounts []float64
+	positiveSpans  []bucketSpan
+	positiveDeltas []int64
+	positiveCounts []float64
+
+	native bool
+}
+
+// histogramBucket is a cumulative bucket, counting the observations lower or
+// equal to its upper bound.
+type histogramBucket struct {
+	upperBound float64
+	count      float64
+}
+
+// decodeNativeHistogram decodes the native histogram from the unrecognized
+// fields of a Histogram message, nil is returned when the histogram is not a
+// native histogram.
+func decodeNativeHistogram(b []byte) (*nativeHistogram, error) {
+	h := &nativeHistogram{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errInvalidProtobuf
+		}
+		b = b[n:]
+		field, wireType := key>>3, key&7
+
+		var value uint64
+		var data []byte
+		switch wireType {
+		case wireVarint:
+			value, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errInvalidProtobuf
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errInvalidProtobuf
+			}
+			value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errInvalidProtobuf
+			}
+			b = b[4:]
+			continue
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return nil, errInvalidProtobuf
+			}
+			data, b = b[n:n+int(length)], b[n+int(length):]
+		default:
+			return nil, errInvalidProtobuf
+		}
+
+		var err error
+		switch field {
+		case fieldSampleCountFloat:
+			h.sampleCountFloat = math.Float64frombits(value)
+		case fieldSchema:
+			h.schema = int32(zigzag(value))
+			h.native = true
+		case fieldZeroThreshold:
+			h.zeroThreshold = math.Float64frombits(value)
+			h.native = true
+		case fieldZeroCount:
+			h.zeroCount = float64(value)
+		case fieldZeroCountFloat:
+			h.zeroCount = math.Float64frombits(value)
+		case fieldNegativeSpan, fieldPositiveSpan:
+			var span bucketSpan
+			if span, err = decodeBucketSpan(data); err != nil {
+				return nil, err
+			}
+			if field == fieldNegativeSpan {
+				h.negativeSpans = append(h.negativeSpans, span)
+			} else {
+				h.positiveSpans = append(h.positiveSpans, span)
+			}
+			h.native = true
+		case fieldNegativeDelta:
+			h.negativeDeltas, err = appendSints(h.negativeDeltas, wireType, value, data)
+		case fieldPositiveDelta:
+			h.positiveDeltas, err = appendSints(h.positiveDeltas, wireType, value, data)
+		case fieldNegativeCount:
+			h.negativeCounts, err = appendDoubles(h.negativeCounts, wireType, value, data)
+		case fieldPositiveCount:
+			h.positiveCounts, err = appendDoubles(h.positiveCounts, wireType, value, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !h.native {
+		return nil, nil
+	}
+	return h, nil
+}
+
+func decodeBucketSpan(b []byte) (bucketSpan, error) {
+	var span bucketSpan
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 || key&7 != wireVarint {
+			return span, errInvalidProtobuf
+		}
+		b = b[n:]
+		value, n := binary.Uvarint(b)
+		if n <= 0 {
+			return span, errInvalidProtobuf
+		}
+		b = b[n:]
+
+		switch key >> 3 {
+		case 1:
+			span.offset = int32(zigzag(value))
+		case 2:
+			span.length = uint32(value)
+		}
+	}
+	return span, nil
+}
+
+// appendSints appends the zigzag encoded integers, packed or not.
+func appendSints(values []int64, wireType, value uint64, data []byte) ([]int64, error) {
+	if wireType == wireVarint {
+		return append(values, zigzag(value)), nil
+	}
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errInvalidProtobuf
+		}
+		values, data = append(values, zigzag(v)), data[n:]
+	}
+	return values, nil
+}
+
+// appendDoubles appends the doubles, packed or not.
+func appendDoubles(values []float64, wireType, value uint64, data []byte) ([]float64, error) {
+	if wireType == wireFixed64 {
+		return append(values, math.Float64frombits(value)), nil
+	}
+	if len(data)%8 != 0 {
+		return nil, errInvalidProtobuf
+	}
+	for ; len(data) > 0; data = data[8:] {
+		values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+	}
+	return values, nil
+}
+
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// buckets returns the cumulative buckets of the histogram by increasing upper
+// bound: the negative buckets, the zero bucket and the positive buckets.
+func (h *nativeHistogram) buckets() []histogramBucket {
+	var buckets []histogramBucket
+	var count float64
+
+	// The negative bucket of index i holds the observations between
+	// -bound(i) and -bound(i-1).
+	negative := spanCounts(h.negativeSpans, h.negativeDeltas, h.negativeCounts)
+	for i := len(negative) - 1; i >= 0; i-- {
+		count += negative[i].count
+		buckets = append(buckets, histogramBucket{upperBound: -h.bound(negative[i].index - 1), count: count})
+	}
+
+	count += h.zeroCount
+	buckets = append(buckets, histogramBucket{upperBound: h.zeroThreshold, count: count})
+
+	for _, b := range spanCounts(h.positiveSpans, h.positiveDeltas, h.positiveCounts) {
+		count += b.count
+		buckets = append(buckets, histogramBucket{upperBound: h.bound(b.index), count: count})
+	}
+	return buckets
+}
+
+// bound returns the upper bound of the positive bucket of the index, the
+// buckets growing by a factor of 2^(2^-schema).
+func (h *nativeHistogram) bound(index int32) float64 {
+	return math.Exp2(float64(index) * math.Exp2(-float64(h.schema)))
+}
+
+type indexedCount struct {
+	index int32
+	count float64
+}
+
+// spanCounts returns the counts of the buckets by increasing index, from the
+// deltas of the integer histograms or the counts of the float histograms.
+func spanCounts(spans []bucketSpan, deltas []int64, counts []float64) []indexedCount {
+	var result []indexedCount
+	var index int32
+	var current int64
+	i := 0
+	for _, span := range spans {
+		index += span.offset
+		for j := uint32(0); j < span.length; j, index, i = j+1, index+1, i+1 {
+			var count float64
+			switch {
+			case i < len(deltas):
+				current += deltas[i]
+				count = float64(current)
+			case i < len(counts):
+				count = counts[i]
+			default:
+				return result
+			}
+			result = append(result, indexedCount{index: index, count: count})
+		}
+	}
+	return result
+}
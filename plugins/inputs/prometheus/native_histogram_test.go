@@ -0,0 +1,109 @@
+package prometheus
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// protobuf encodes the fields of a message for the tests.
+type protobuf []byte
+
+func (p protobuf) varint(v uint64) protobuf {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(p, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+func (p protobuf) key(field, wireType uint64) protobuf {
+	return p.varint(field<<3 | wireType)
+}
+
+func (p protobuf) sint(field uint64, v int64) protobuf {
+	return p.key(field, wireVarint).varint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (p protobuf) uint(field uint64, v uint64) protobuf {
+	return p.key(field, wireVarint).varint(v)
+}
+
+func (p protobuf) double(field uint64, v float64) protobuf {
+	p = p.key(field, wireFixed64)
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+	return append(p, buf...)
+}
+
+func (p protobuf) bytes(field uint64, data protobuf) protobuf {
+	return append(p.key(field, wireBytes).varint(uint64(len(data))), data...)
+}
+
+func (p protobuf) span(field uint64, offset int32, length uint32) protobuf {
+	return p.bytes(field, protobuf{}.sint(1, int64(offset)).uint(2, uint64(length)))
+}
+
+func TestNativeHistogramBuckets(t *testing.T) {
+	deltas := protobuf{}
+	for _, d := range []int64{3, -1, 2} {
+		deltas = deltas.varint(uint64(d<<1) ^ uint64(d>>63))
+	}
+	b := protobuf{}.
+		sint(fieldSchema, 0).
+		double(fieldZeroThreshold, 0.001).
+		uint(fieldZeroCount, 2).
+		span(fieldNegativeSpan, 1, 1).
+		sint(fieldNegativeDelta, 1).
+		span(fieldPositiveSpan, 0, 2).
+		span(fieldPositiveSpan, 1, 1).
+		bytes(fieldPositiveDelta, deltas)
+
+	h, err := decodeNativeHistogram(b)
+	require.NoError(t, err)
+	require.NotNil(t, h)
+	require.Equal(t, []histogramBucket{
+		{upperBound: -1, count: 1},
+		{upperBound: 0.001, count: 3},
+		{upperBound: 1, count: 6},
+		{upperBound: 2, count: 8},
+		{upperBound: 8, count: 12},
+	}, h.buckets())
+}
+
+func TestNativeFloatHistogramBuckets(t *testing.T) {
+	counts := protobuf{}
+	for _, c := range []float64{1.5, 2.5} {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(c))
+		counts = append(counts, buf...)
+	}
+	b := protobuf{}.
+		double(fieldSampleCountFloat, 4).
+		sint(fieldSchema, 1).
+		span(fieldPositiveSpan, -1, 2).
+		bytes(fieldPositiveCount, counts)
+
+	h, err := decodeNativeHistogram(b)
+	require.NoError(t, err)
+	require.NotNil(t, h)
+	require.Equal(t, 4.0, h.sampleCountFloat)
+	require.Equal(t, []histogramBucket{
+		{upperBound: 0, count: 0},
+		{upperBound: math.Exp2(-0.5), count: 1.5},
+		{upperBound: 1, count: 4},
+	}, h.buckets())
+}
+
+func TestDecodeNativeHistogramClassic(t *testing.T) {
+	h, err := decodeNativeHistogram(nil)
+	require.NoError(t, err)
+	require.Nil(t, h)
+
+	// Unknown fields of later versions are skipped.
+	h, err = decodeNativeHistogram(protobuf{}.uint(15, 1))
+	require.NoError(t, err)
+	require.Nil(t, h)
+
+	_, err = decodeNativeHistogram(protobuf{}.key(fieldPositiveSpan, wireBytes).varint(10))
+	require.Error(t, err)
+}
@@ -0,0 +1,312 @@
+package prometheus
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const openMetricsMediaType = "application/openmetrics-text"
+
+// openMetricsAcceptHeader prefers the OpenMetrics format, the only one
+// holding exemplars, when the exemplars are collected.
+const openMetricsAcceptHeader = `application/openmetrics-text;version=1.0.0,application/openmetrics-text;version=0.0.1;q=0.75,text/plain;version=0.0.4;q=0.5,*/*;q=0.1`
+
+// exemplar is an exemplar of a sample of the OpenMetrics format.
+type exemplar struct {
+	// Name and labels of the sample.
+	series string
+	tags   map[string]string
+
+	labels map[string]string
+	value  float64
+	time   time.Time
+}
+
+func isOpenMetrics(header http.Header) bool {
+	mediatype, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediatype == openMetricsMediaType
+}
+
+// parseOpenMetrics converts the OpenMetrics format to the text format, and
+// returns the exemplars of the samples which the text format cannot hold.
+//
+// The counters are declared with the _total suffix of their samples, and the
+// _created samples are dropped.  The types without an equivalent in the text
+// format are left out, their samples being untyped.
+func parseOpenMetrics(buf []byte) ([]byte, []exemplar, error) {
+	var out bytes.Buffer
+	var exemplars []exemplar
+	types := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		// The HELP, UNIT and EOF lines are not used.
+		if strings.HasPrefix(line, "#") {
+			parts := strings.Fields(line)
+			if len(parts) != 4 || parts[1] != "TYPE" {
+				continue
+			}
+			name, typ := parts[2], parts[3]
+			types[name] = typ
+			switch typ {
+			case "counter":
+				fmt.Fprintf(&out, "# TYPE %s_total counter\n", name)
+			case "gauge", "summary", "histogram":
+				fmt.Fprintf(&out, "# TYPE %s %s\n", name, typ)
+			}
+			continue
+		}
+
+		name, rawLabels, rest, err := cutSample(line)
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.HasSuffix(name, "_created") {
+			switch types[strings.TrimSuffix(name, "_created")] {
+			case "counter", "summary", "histogram":
+				continue
+			}
+		}
+
+		var exemplarPart string
+		if i := strings.IndexByte(rest, '#'); i >= 0 {
+			rest, exemplarPart = rest[:i], rest[i+1:]
+		}
+		values := strings.Fields(rest)
+		if len(values) == 0 || len(values) > 2 {
+			return nil, nil, fmt.Errorf("invalid sample %q", line)
+		}
+
+		out.WriteString(name)
+		if rawLabels != "" {
+			out.WriteString("{" + rawLabels + "}")
+		}
+		out.WriteString(" " + values[0])
+		// The timestamps are in seconds, and in milliseconds in the text
+		// format.
+		if len(values) == 2 {
+			t, err := parseTimestamp(values[1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid timestamp of sample %q", line)
+			}
+			fmt.Fprintf(&out, " %d", t.UnixNano()/int64(time.Millisecond))
+		}
+		out.WriteByte('\n')
+
+		if exemplarPart != "" {
+			e, err := parseExemplar(exemplarPart)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid exemplar of sample %q: %s", line, err)
+			}
+			e.series = name
+			if e.tags, err = parseLabels(rawLabels); err != nil {
+				return nil, nil, fmt.Errorf("invalid sample %q: %s", line, err)
+			}
+			exemplars = append(exemplars, e)
+		}
+	}
+	return out.Bytes(), exemplars, scanner.Err()
+}
+
+// parseExemplar parses an exemplar such as `{trace_id="abc"} 1.5 1583329587.5`.
+func parseExemplar(s string) (exemplar, error) {
+	var e exemplar
+	rawLabels, rest, ok := cutLabels(strings.TrimSpace(s))
+	if !ok {
+		return e, fmt.Errorf("missing labels")
+	}
+	labels, err := parseLabels(rawLabels)
+	if err != nil {
+		return e, err
+	}
+	e.labels = labels
+
+	values := strings.Fields(rest)
+	if len(values) == 0 || len(values) > 2 {
+		return e, fmt.Errorf("invalid value")
+	}
+	if e.value, err = strconv.ParseFloat(values[0], 64); err != nil {
+		return e, err
+	}
+	if len(values) == 2 {
+		if e.time, err = parseTimestamp(values[1]); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// parseTimestamp parses a timestamp in seconds with a fraction.
+func parseTimestamp(s string) (time.Time, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))), nil
+}
+
+// cutSample splits a sample into its name, the labels between the braces and
+// the rest of the line.
+func cutSample(line string) (string, string, string, error) {
+	i := strings.IndexAny(line, "{ ")
+	if i <= 0 {
+		return "", "", "", fmt.Errorf("invalid sample %q", line)
+	}
+	if line[i] == ' ' {
+		return line[:i], "", line[i:], nil
+	}
+	rawLabels, rest, ok := cutLabels(line[i:])
+	if !ok {
+		return "", "", "", fmt.Errorf("invalid labels of sample %q", line)
+	}
+	return line[:i], rawLabels, rest, nil
+}
+
+// cutLabels splits s, starting with an opening brace, at the closing brace
+// outside of the quoted values.
+func cutLabels(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, "{") {
+		return "", "", false
+	}
+	inQuotes, escaped := false, false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == '}' && !inQuotes:
+			return s[1:i], s[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// parseLabels parses labels such as `a="1",b="2"`.
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || eq+1 >= len(s) || s[eq+1] != '"' {
+			return nil, fmt.Errorf("invalid labels %q", s)
+		}
+		name := strings.TrimSpace(s[:eq])
+
+		var value strings.Builder
+		i := eq + 2
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				if s[i] == 'n' {
+					value.WriteByte('\n')
+					continue
+				}
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, fmt.Errorf("unterminated value of label %q", name)
+		}
+		labels[name] = value.String()
+
+		s = strings.TrimSpace(s[i+1:])
+		s = strings.TrimSpace(strings.TrimPrefix(s, ","))
+	}
+	return labels, nil
+}
+
+// seriesKey identifies a series by name and tags.
+func seriesKey(name string, tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// addExemplars adds the exemplars as separate metrics, or as tags of the
+// metrics of their samples.
+func (p *Prometheus) addExemplars(metrics []telegraf.Metric, exemplars []exemplar) []telegraf.Metric {
+	switch p.Exemplars {
+	case "metric":
+		for _, e := range exemplars {
+			tags := make(map[string]string, len(e.tags)+len(e.labels))
+			for k, v := range e.tags {
+				tags[k] = v
+			}
+			for k, v := range e.labels {
+				tags[k] = v
+			}
+			t := e.time
+			if t.IsZero() {
+				t = time.Now()
+			}
+
+			var m telegraf.Metric
+			var err error
+			if p.MetricVersion == 2 {
+				m, err = metric.New("prometheus", tags, map[string]interface{}{e.series + "_exemplar": e.value}, t)
+			} else {
+				m, err = metric.New(e.series+"_exemplar", tags, map[string]interface{}{"value": e.value}, t)
+			}
+			if err == nil {
+				metrics = append(metrics, m)
+			}
+		}
+	case "tags":
+		// With metric_version = 1 the buckets of a histogram are the fields
+		// of a single metric named after the histogram, without le tag.
+		bySeries := make(map[string]exemplar, len(exemplars))
+		for _, e := range exemplars {
+			if p.MetricVersion != 2 && strings.HasSuffix(e.series, "_bucket") {
+				tags := make(map[string]string, len(e.tags))
+				for k, v := range e.tags {
+					if k != "le" {
+						tags[k] = v
+					}
+				}
+				bySeries[seriesKey(strings.TrimSuffix(e.series, "_bucket"), tags)] = e
+				continue
+			}
+			bySeries[seriesKey(e.series, e.tags)] = e
+		}
+
+		for _, m := range metrics {
+			names := []string{m.Name()}
+			if p.MetricVersion == 2 {
+				names = names[:0]
+				for field := range m.Fields() {
+					names = append(names, field)
+				}
+			}
+			for _, name := range names {
+				if e, ok := bySeries[seriesKey(name, m.Tags())]; ok {
+					for k, v := range e.labels {
+						m.AddTag(k, v)
+					}
+				}
+			}
+		}
+	}
+	return metrics
+}
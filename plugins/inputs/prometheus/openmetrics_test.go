@@ -0,0 +1,235 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleOpenMetrics = `# HELP http_requests Requests served.
+# TYPE http_requests counter
+http_requests_total{code="200"} 1027 1583329587.5 # {trace_id="4bf92f3577b34da6",span_id="00f067aa0ba902b7"} 1 1583329587.25
+http_requests_created{code="200"} 1583320000
+# TYPE request_duration_seconds histogram
+# UNIT request_duration_seconds seconds
+request_duration_seconds_bucket{le="0.5"} 8 # {trace_id="a{b}\"c"} 0.25
+request_duration_seconds_bucket{le="+Inf"} 10
+request_duration_seconds_sum 4.5
+request_duration_seconds_count 10
+# TYPE build info
+build_info{version="1.2.3"} 1
+# EOF
+`
+
+func TestParseOpenMetrics(t *testing.T) {
+	text, exemplars, err := parseOpenMetrics([]byte(sampleOpenMetrics))
+	require.NoError(t, err)
+
+	require.Equal(t, `# TYPE http_requests_total counter
+http_requests_total{code="200"} 1027 1583329587500
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.5"} 8
+request_duration_seconds_bucket{le="+Inf"} 10
+request_duration_seconds_sum 4.5
+request_duration_seconds_count 10
+build_info{version="1.2.3"} 1
+`, string(text))
+
+	require.Equal(t, []exemplar{
+		{
+			series: "http_requests_total",
+			tags:   map[string]string{"code": "200"},
+			labels: map[string]string{"trace_id": "4bf92f3577b34da6", "span_id": "00f067aa0ba902b7"},
+			value:  1,
+			time:   time.Unix(1583329587, 250000000),
+		},
+		{
+			series: "request_duration_seconds_bucket",
+			tags:   map[string]string{"le": "0.5"},
+			labels: map[string]string{"trace_id": `a{b}"c`},
+			value:  0.25,
+		},
+	}, exemplars)
+}
+
+func TestParseOpenMetricsInvalid(t *testing.T) {
+	for _, s := range []string{
+		`http_requests_total{code="200} 1`,
+		`http_requests_total 1 # trace_id="abc" 1`,
+		`http_requests_total 1 now`,
+	} {
+		_, _, err := parseOpenMetrics([]byte(s))
+		require.Error(t, err, s)
+	}
+}
+
+func TestAddExemplars(t *testing.T) {
+	exemplars := []exemplar{
+		{
+			series: "http_requests_total",
+			tags:   map[string]string{"code": "200"},
+			labels: map[string]string{"trace_id": "abc"},
+			value:  1,
+			time:   time.Unix(10, 0),
+		},
+		{
+			series: "request_duration_seconds_bucket",
+			tags:   map[string]string{"le": "0.5"},
+			labels: map[string]string{"trace_id": "def"},
+			value:  0.25,
+			time:   time.Unix(10, 0),
+		},
+	}
+
+	tests := []struct {
+		name          string
+		exemplars     string
+		metricVersion int
+		metrics       []telegraf.Metric
+		expected      []telegraf.Metric
+	}{
+		{
+			name:          "metric",
+			exemplars:     "metric",
+			metricVersion: 2,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("prometheus",
+					map[string]string{"code": "200", "trace_id": "abc"},
+					map[string]interface{}{"http_requests_total_exemplar": 1.0},
+					time.Unix(10, 0),
+				),
+				testutil.MustMetric("prometheus",
+					map[string]string{"le": "0.5", "trace_id": "def"},
+					map[string]interface{}{"request_duration_seconds_bucket_exemplar": 0.25},
+					time.Unix(10, 0),
+				),
+			},
+		},
+		{
+			name:          "metric version 1",
+			exemplars:     "metric",
+			metricVersion: 1,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("http_requests_total_exemplar",
+					map[string]string{"code": "200", "trace_id": "abc"},
+					map[string]interface{}{"value": 1.0},
+					time.Unix(10, 0),
+				),
+				testutil.MustMetric("request_duration_seconds_bucket_exemplar",
+					map[string]string{"le": "0.5", "trace_id": "def"},
+					map[string]interface{}{"value": 0.25},
+					time.Unix(10, 0),
+				),
+			},
+		},
+		{
+			name:          "tags",
+			exemplars:     "tags",
+			metricVersion: 2,
+			metrics: []telegraf.Metric{
+				testutil.MustMetric("prometheus",
+					map[string]string{"code": "200"},
+					map[string]interface{}{"http_requests_total": 1027.0},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("prometheus",
+					map[string]string{"code": "500"},
+					map[string]interface{}{"http_requests_total": 3.0},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("prometheus",
+					map[string]string{"le": "0.5"},
+					map[string]interface{}{"request_duration_seconds_bucket": 8.0},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("prometheus",
+					map[string]string{"code": "200", "trace_id": "abc"},
+					map[string]interface{}{"http_requests_total": 1027.0},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("prometheus",
+					map[string]string{"code": "500"},
+					map[string]interface{}{"http_requests_total": 3.0},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("prometheus",
+					map[string]string{"le": "0.5", "trace_id": "def"},
+					map[string]interface{}{"request_duration_seconds_bucket": 8.0},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name:          "tags version 1",
+			exemplars:     "tags",
+			metricVersion: 1,
+			metrics: []telegraf.Metric{
+				testutil.MustMetric("http_requests_total",
+					map[string]string{"code": "200"},
+					map[string]interface{}{"counter": 1027.0},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("request_duration_seconds",
+					map[string]string{},
+					map[string]interface{}{"0.5": 8.0, "count": 10.0, "sum": 4.5},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("http_requests_total",
+					map[string]string{"code": "200", "trace_id": "abc"},
+					map[string]interface{}{"counter": 1027.0},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("request_duration_seconds",
+					map[string]string{"trace_id": "def"},
+					map[string]interface{}{"0.5": 8.0, "count": 10.0, "sum": 4.5},
+					time.Unix(0, 0),
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Prometheus{
+				Exemplars:     tt.exemplars,
+				MetricVersion: tt.metricVersion,
+			}
+			actual := p.addExemplars(tt.metrics, exemplars)
+			testutil.RequireMetricsEqual(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestPrometheusGeneratesExemplars(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		fmt.Fprint(w, sampleOpenMetrics)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:           testutil.Logger{},
+		URLs:          []string{ts.URL},
+		MetricVersion: 2,
+		Exemplars:     "metric",
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	require.True(t, acc.HasFloatField("prometheus", "http_requests_total"))
+	require.True(t, acc.HasFloatField("prometheus", "http_requests_total_exemplar"))
+	require.True(t, acc.HasFloatField("prometheus", "request_duration_seconds_bucket_exemplar"))
+	require.False(t, acc.HasFloatField("prometheus", "http_requests_created"))
+}
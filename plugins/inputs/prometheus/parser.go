@@ -138,7 +138,8 @@ func makeBucketsV2(m *dto.Metric, tags map[string]string, metricName string, met
 	} else {
 		t = time.Now()
 	}
-	fields[metricName+"_count"] = float64(m.GetHistogram().GetSampleCount())
+	count, buckets := histogramBuckets(m.GetHistogram())
+	fields[metricName+"_count"] = count
 	fields[metricName+"_sum"] = float64(m.GetHistogram().GetSampleSum())
 
 	met, err := metric.New("prometheus", tags, fields, t, valueType(metricType))
@@ -146,11 +147,11 @@ func makeBucketsV2(m *dto.Metric, tags map[string]string, metricName string, met
 		metrics = append(metrics, met)
 	}
 
-	for _, b := range m.GetHistogram().Bucket {
+	for _, b := range buckets {
 		newTags := tags
 		fields = make(map[string]interface{})
-		newTags["le"] = fmt.Sprint(b.GetUpperBound())
-		fields[metricName+"_bucket"] = float64(b.GetCumulativeCount())
+		newTags["le"] = fmt.Sprint(b.upperBound)
+		fields[metricName+"_bucket"] = b.count
 
 		histogramMetric, err := metric.New("prometheus", newTags, fields, t, valueType(metricType))
 		if err == nil {
@@ -209,8 +210,9 @@ func Parse(buf []byte, header http.Header) ([]telegraf.Metric, error) {
 				fields["sum"] = float64(m.GetSummary().GetSampleSum())
 			} else if mf.GetType() == dto.MetricType_HISTOGRAM {
 				// histogram metric
-				fields = makeBuckets(m)
-				fields["count"] = float64(m.GetHistogram().GetSampleCount())
+				var count float64
+				fields, count = makeBuckets(m)
+				fields["count"] = count
 				fields["sum"] = float64(m.GetHistogram().GetSampleSum())
 
 			} else {
@@ -263,12 +265,45 @@ func makeQuantiles(m *dto.Metric) map[string]interface{} {
 }
 
 // Get Buckets  from histogram metric
-func makeBuckets(m *dto.Metric) map[string]interface{} {
+func makeBuckets(m *dto.Metric) (map[string]interface{}, float64) {
 	fields := make(map[string]interface{})
-	for _, b := range m.GetHistogram().Bucket {
-		fields[fmt.Sprint(b.GetUpperBound())] = float64(b.GetCumulativeCount())
+	count, buckets := histogramBuckets(m.GetHistogram())
+	for _, b := range buckets {
+		fields[fmt.Sprint(b.upperBound)] = b.count
 	}
-	return fields
+	return fields, count
+}
+
+// histogramBuckets returns the count and the cumulative buckets of the
+// histogram.  The histograms without classic buckets have their native
+// buckets converted to cumulative buckets, ending with the +Inf bucket.
+func histogramBuckets(h *dto.Histogram) (float64, []histogramBucket) {
+	if h == nil {
+		return 0, nil
+	}
+	count := float64(h.GetSampleCount())
+
+	buckets := make([]histogramBucket, 0, len(h.Bucket))
+	for _, b := range h.Bucket {
+		buckets = append(buckets, histogramBucket{
+			upperBound: b.GetUpperBound(),
+			count:      float64(b.GetCumulativeCount()),
+		})
+	}
+	if len(buckets) > 0 {
+		return count, buckets
+	}
+
+	native, err := decodeNativeHistogram(h.XXX_unrecognized)
+	if err != nil || native == nil {
+		return count, nil
+	}
+	// The float histograms have their count as a float.
+	if h.SampleCount == nil {
+		count = native.sampleCountFloat
+	}
+	buckets = native.buckets()
+	return count, append(buckets, histogramBucket{upperBound: math.Inf(1), count: count})
 }
 
 // Get labels from metric
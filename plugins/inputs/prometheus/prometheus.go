@@ -8,11 +8,15 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/discovery"
+	"github.com/influxdata/telegraf/internal/k8sdiscovery"
 	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
@@ -32,6 +36,7 @@ type Prometheus struct {
 	// Bearer Token authorization file path
 	BearerToken       string `toml:"bearer_token"`
 	BearerTokenString string `toml:"bearer_token_string"`
+	bearerToken       *bearerTokenFile
 
 	// Basic authentication credentials
 	Username string `toml:"username"`
@@ -39,10 +44,28 @@ type Prometheus struct {
 
 	ResponseTimeout internal.Duration `toml:"response_timeout"`
 
+	// Maximum number of targets scraped concurrently, one per target when 0
+	MaxConcurrentScrapes int `toml:"max_concurrent_scrapes"`
+
+	// Should we report the success and duration of the scrape of each target
+	ScrapeStatus bool `toml:"scrape_status"`
+
+	// Mark the series of the targets which disappeared as stale
+	StalenessMarkers string `toml:"staleness_markers"`
+	staleness        staleness
+
 	MetricVersion int `toml:"metric_version"`
 
 	URLTag string `toml:"url_tag"`
 
+	// Whether the exemplars of the OpenMetrics format are added as metrics
+	// or as tags
+	Exemplars string `toml:"exemplars"`
+
+	// Relabeling of the targets and of the scraped metrics
+	RelabelConfigs       []*RelabelConfig `toml:"relabel_configs"`
+	MetricRelabelConfigs []*RelabelConfig `toml:"metric_relabel_configs"`
+
 	tls.ClientConfig
 
 	Log telegraf.Logger
@@ -52,12 +75,58 @@ type Prometheus struct {
 	// Should we scrape Kubernetes services for prometheus annotations
 	MonitorPods        bool     `toml:"monitor_kubernetes_pods"`
 	PodNamespace       string   `toml:"monitor_kubernetes_pods_namespace"`
+	PodNode            string   `toml:"monitor_kubernetes_pods_node"`
+	PodAPIServerProxy  bool     `toml:"monitor_kubernetes_pods_api_proxy"`
+	PodMetadataTags    bool     `toml:"monitor_kubernetes_pods_metadata_tags"`
 	ExcludeAnnotations []string `toml:"monitor_kubernetes_pods_exclude_annotations"`
 	ExcludeLabels      []string `toml:"monitor_kubernetes_pods_exclude_labels"`
-	lock               sync.Mutex
-	kubernetesPods     map[string]URLAndAddress
-	cancel             context.CancelFunc
-	wg                 sync.WaitGroup
+	LabelSelector      string   `toml:"kubernetes_label_selector"`
+	FieldSelector      string   `toml:"kubernetes_field_selector"`
+
+	// Interval of the full resync of the discovered pods, services and
+	// endpoints with the API server
+	ResyncInterval internal.Duration `toml:"kubernetes_resync_interval"`
+
+	// Should we scrape Kubernetes services, or the endpoints of services,
+	// with prometheus annotations
+	MonitorServices  bool `toml:"monitor_kubernetes_services"`
+	MonitorEndpoints bool `toml:"monitor_kubernetes_endpoints"`
+
+	discoveries []*k8sdiscovery.Discovery
+
+	// Target files in the format of the file_sd_configs of Prometheus
+	FileSDFiles []string `toml:"file_sd_files"`
+	fileSD      *fileSD
+
+	// Should we scrape the instances of Consul services
+	ConsulDiscovery *ConsulServiceDiscovery `toml:"consul_service_discovery"`
+	consul          *discovery.Discovery
+
+	// Should we scrape the targets of HTTP service discovery endpoints
+	HTTPDiscovery *HTTPServiceDiscovery `toml:"http_service_discovery"`
+	httpSD        *discovery.Discovery
+}
+
+// ConsulServiceDiscovery is the configuration of the targets discovered from
+// the services of the Consul catalog.
+type ConsulServiceDiscovery struct {
+	discovery.ConsulConfig
+
+	// Scheme and path of the metrics of the instances.
+	MetricsScheme string `toml:"metrics_scheme"`
+	MetricsPath   string `toml:"metrics_path"`
+
+	// Interval of the refresh of the services.
+	QueryInterval internal.Duration `toml:"query_interval"`
+}
+
+// HTTPServiceDiscovery is the configuration of the targets discovered from
+// the endpoints of the Prometheus HTTP service discovery.
+type HTTPServiceDiscovery struct {
+	discovery.HTTPConfig
+
+	// Interval of the refresh of the targets.
+	RefreshInterval internal.Duration `toml:"refresh_interval"`
 }
 
 var sampleConfig = `
@@ -76,6 +145,13 @@ var sampleConfig = `
   ## Url tag name (tag containing scrapped url. optional, default is "url")
   # url_tag = "scrapeUrl"
 
+  ## Collect the exemplars of the OpenMetrics format, requesting this format
+  ## from the targets: "metric" adds them as separate metrics named after their
+  ## sample with the "_exemplar" suffix and tagged with the labels of the
+  ## exemplar, "tags" adds the labels of the exemplar, such as trace_id and
+  ## span_id, as tags of the metric of their sample.
+  # exemplars = ""
+
   ## An array of Kubernetes services to scrape metrics from.
   # kubernetes_services = ["http://my-service-dns.my-namespace:9100/metrics"]
 
@@ -88,16 +164,56 @@ var sampleConfig = `
   ##     set this to 'https' & most likely set the tls config.
   ## - prometheus.io/path: If the metrics path is not /metrics, define it with this annotation.
   ## - prometheus.io/port: If port is not 9102 use this annotation
+  ## - prometheus.io/param_<name>: Add the <name> query parameter to the URL
   # monitor_kubernetes_pods = true
   ## Restricts Kubernetes monitoring to a single namespace
   ##   ex: monitor_kubernetes_pods_namespace = "default"
   # monitor_kubernetes_pods_namespace = ""
+  ## Restricts Kubernetes monitoring to the pods scheduled on a node, such as
+  ## the node of the agent when running as a DaemonSet with the NODE_NAME
+  ## environment variable set from the spec.nodeName field.
+  ##   ex: monitor_kubernetes_pods_node = "$NODE_NAME"
+  # monitor_kubernetes_pods_node = ""
+  ## Scrape the pods through the proxy of the API server with the credentials
+  ## of the kubeconfig, when Telegraf runs outside of the cluster and the pod
+  ## IPs are not routable.
+  # monitor_kubernetes_pods_api_proxy = false
+  ## Add the node of the pods and the kind and name of their controller, such
+  ## as a ReplicaSet or DaemonSet, as the node_name, controller_kind and
+  ## controller_name tags, next to the pod_name and namespace tags.
+  # monitor_kubernetes_pods_metadata_tags = false
   ## If given, excludes the following annotations being added to metric tags
   # monitor_kubernetes_pods_exclude_annotations = ["some.namespace/annotation"]
   ## If given, excludes the following labels being added to metric tags
   # monitor_kubernetes_pods_exclude_labels = ["some-label-name"]
+  ## Only scrape the pods matching the label and field selectors, the field
+  ## selector is combined with the node above.
+  # kubernetes_label_selector = "app=metrics"
+  # kubernetes_field_selector = "status.phase=Running"
+  ## Interval of the full list of the pods, services and endpoints which
+  ## reconciles the targets with the API server, removing the targets of
+  ## missed delete events.
+  # kubernetes_resync_interval = "5m"
+
+  ## Scrape Kubernetes services with the prometheus annotations above, at
+  ## their cluster IP and first port unless annotated otherwise.  The
+  ## namespace and exclude settings of the pods also apply to services.
+  # monitor_kubernetes_services = false
+  ## Scrape each ready endpoint of the Kubernetes services with the
+  ## prometheus annotations above, following the endpoint IPs as they change.
+  # monitor_kubernetes_endpoints = false
+
+  ## Target files in the format of the file_sd_configs of Prometheus: JSON or
+  ## YAML lists of groups of "host:port" targets with labels, the labels are
+  ## added as tags.  The "__scheme__" and "__metrics_path__" labels set the
+  ## scheme and path of the targets.  The files are reloaded when they change,
+  ## the last path element may contain a glob.
+  # file_sd_files = ["/etc/telegraf/targets/*.json"]
 
   ## Use bearer token for authorization. ('bearer_token' takes priority)
+  ## The token file is read again when it changes, in a cluster use the token
+  ## of the service account of the pod:
+  ##   bearer_token = "/var/run/secrets/kubernetes.io/serviceaccount/token"
   # bearer_token = "/path/to/bearer/token"
   ## OR
   # bearer_token_string = "abc_123"
@@ -110,12 +226,81 @@ var sampleConfig = `
   ## Specify timeout duration for slower prometheus clients (default is 3s)
   # response_timeout = "3s"
 
+  ## Maximum number of targets scraped concurrently, by default all targets
+  ## are scraped at once.
+  # max_concurrent_scrapes = 0
+
+  ## Report the success, duration and number of metrics of the scrape of each
+  ## target in the prometheus_scrape measurement.
+  # scrape_status = false
+
+  ## Mark the series of a target as stale once the target disappears, such
+  ## as a deleted pod, so that the end of the series can be told apart from a
+  ## flat line: "nan" adds the series a last time with the staleness marker
+  ## of Prometheus as the value of its fields, "absent" adds it with a single
+  ## stale field set to true.
+  # staleness_markers = ""
+
   ## Optional TLS Config
   # tls_ca = /path/to/cafile
   # tls_cert = /path/to/certfile
   # tls_key = /path/to/keyfile
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
+
+  ## Scrape the instances of Consul services, the healthy ones unless failing
+  ## ones are included.  All the services of the catalog having the tags are
+  ## scraped when no services are given.  The services are refreshed
+  ## periodically, without reloading the configuration.
+  # [inputs.prometheus.consul_service_discovery]
+  #   address = "localhost:8500"
+  #   # services = ["node-exporter"]
+  #   ## Tags the instances must all have.
+  #   # tags = ["metrics"]
+  #   # datacenter = ""
+  #   # token = ""
+  #   # include_failing = false
+  #   ## Scheme and path of the metrics of the instances.
+  #   # metrics_scheme = "http"
+  #   # metrics_path = "/metrics"
+  #   ## Interval of the refresh of the services.
+  #   # query_interval = "1m"
+  #   ## Metadata of the services added as tags, by metadata key to tag name.
+  #   # [inputs.prometheus.consul_service_discovery.meta_tags]
+  #   #   version = "service_version"
+
+  ## Scrape the targets of Prometheus HTTP service discovery endpoints,
+  ## returning JSON lists of groups of "host:port" targets with labels.  The
+  ## labels are added as tags, the "__scheme__" and "__metrics_path__" labels
+  ## set the scheme and path of the targets.  The targets are refreshed
+  ## periodically, without reloading the configuration.
+  # [inputs.prometheus.http_service_discovery]
+  #   urls = ["http://sd.example.org/targets"]
+  #   ## Interval of the refresh of the targets.
+  #   # refresh_interval = "1m"
+  #   ## Timeout of the requests to the endpoints.
+  #   # timeout = "5s"
+  #   ## Optional TLS Config
+  #   # tls_ca = "/etc/telegraf/ca.pem"
+  #   # tls_cert = "/etc/telegraf/cert.pem"
+  #   # tls_key = "/etc/telegraf/key.pem"
+  #   # insecure_skip_verify = false
+
+  ## Relabeling of the targets before they are scraped, and of the scraped
+  ## metrics, with the rules of the relabel_configs and metric_relabel_configs
+  ## of Prometheus.  The actions are replace, keep, drop, labeldrop and
+  ## labelkeep.  The tags of the targets are relabeled along with the
+  ## "__address__", "__scheme__" and "__metrics_path__" labels of the URL
+  ## scraped.  The tags of the metrics are relabeled along with the "__name__"
+  ## label of the measurement, or of the field with metric_version = 2.
+  # [[inputs.prometheus.relabel_configs]]
+  #   source_labels = ["namespace"]
+  #   regex = "kube-system"
+  #   action = "drop"
+  # [[inputs.prometheus.metric_relabel_configs]]
+  #   source_labels = ["__name__"]
+  #   regex = "go_.*"
+  #   action = "drop"
 `
 
 func (p *Prometheus) SampleConfig() string {
@@ -130,6 +315,26 @@ func (p *Prometheus) Init() error {
 	if p.MetricVersion != 2 {
 		p.Log.Warnf("Use of deprecated configuration: 'metric_version = 1'; please update to 'metric_version = 2'")
 	}
+	switch p.Exemplars {
+	case "", "metric", "tags":
+	default:
+		return fmt.Errorf("invalid exemplars %q", p.Exemplars)
+	}
+	switch p.StalenessMarkers {
+	case "", staleMarkersNaN, staleMarkersAbsent:
+	default:
+		return fmt.Errorf("invalid staleness_markers %q", p.StalenessMarkers)
+	}
+	for _, c := range p.RelabelConfigs {
+		if err := c.init(); err != nil {
+			return err
+		}
+	}
+	for _, c := range p.MetricRelabelConfigs {
+		if err := c.init(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -159,6 +364,11 @@ type URLAndAddress struct {
 	URL         *url.URL
 	Address     string
 	Tags        map[string]string
+	// Transport overrides the transport of the client, such as for the
+	// targets scraped through the API server proxy.
+	Transport http.RoundTripper
+	// Timeout of the scrape of the target, the response timeout when zero
+	Timeout time.Duration
 }
 
 func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
@@ -172,11 +382,50 @@ func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
 		allURLs[URL.String()] = URLAndAddress{URL: URL, OriginalURL: URL}
 	}
 
-	p.lock.Lock()
-	defer p.lock.Unlock()
-	// loop through all pods scraped via the prometheus annotation on the pods
-	for k, v := range p.kubernetesPods {
-		allURLs[k] = v
+	// loop through all the targets of the target files
+	if p.fileSD != nil {
+		for _, t := range p.fileSD.Targets() {
+			allURLs[t.URL.String()] = t
+		}
+	}
+
+	// loop through all the instances of the Consul services
+	if p.consul != nil {
+		for _, t := range p.consul.Targets() {
+			allURLs[t.URL.String()] = URLAndAddress{
+				URL:         t.URL,
+				Address:     t.Host,
+				OriginalURL: t.URL,
+				Tags:        t.Tags,
+			}
+		}
+	}
+
+	// loop through all the targets of the HTTP service discovery
+	if p.httpSD != nil {
+		for _, t := range p.httpSD.Targets() {
+			allURLs[t.URL.String()] = URLAndAddress{
+				URL:         t.URL,
+				Address:     t.Host,
+				OriginalURL: t.URL,
+				Tags:        t.Tags,
+			}
+		}
+	}
+
+	// loop through all pods, services and endpoints scraped via the
+	// prometheus annotations
+	for _, d := range p.discoveries {
+		for _, t := range d.Targets() {
+			allURLs[t.URL.String()] = URLAndAddress{
+				URL:         t.URL,
+				Address:     t.Address,
+				OriginalURL: t.URL,
+				Tags:        t.Tags,
+				Transport:   t.Transport,
+				Timeout:     t.Timeout,
+			}
+		}
 	}
 
 	for _, service := range p.KubernetesServices {
@@ -199,7 +448,49 @@ func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
 			}
 		}
 	}
-	return allURLs, nil
+
+	if len(p.RelabelConfigs) == 0 {
+		return allURLs, nil
+	}
+	relabeledURLs := make(map[string]URLAndAddress, len(allURLs))
+	for _, u := range allURLs {
+		if u, ok := p.relabelTarget(u); ok {
+			relabeledURLs[u.URL.String()] = u
+		}
+	}
+	return relabeledURLs, nil
+}
+
+// relabelTarget applies the relabel_configs to the tags and URL of the
+// target, false is returned when the target is dropped.
+func (p *Prometheus) relabelTarget(u URLAndAddress) (URLAndAddress, bool) {
+	labels := make(map[string]string, len(u.Tags)+3)
+	for k, v := range u.Tags {
+		labels[k] = v
+	}
+	labels[addressLabel] = u.URL.Host
+	labels[schemeLabel] = u.URL.Scheme
+	labels[metricsPathLabel] = u.URL.Path
+	if !relabel(labels, p.RelabelConfigs) {
+		return u, false
+	}
+
+	if labels[addressLabel] != u.URL.Host || labels[schemeLabel] != u.URL.Scheme || labels[metricsPathLabel] != u.URL.Path {
+		URL := *u.URL
+		URL.Host = labels[addressLabel]
+		URL.Scheme = labels[schemeLabel]
+		URL.Path = labels[metricsPathLabel]
+		u.URL = &URL
+		u.OriginalURL = &URL
+	}
+
+	u.Tags = make(map[string]string, len(labels))
+	for k, v := range labels {
+		if !strings.HasPrefix(k, "__") {
+			u.Tags[k] = v
+		}
+	}
+	return u, true
 }
 
 // Reads stats from all configured servers accumulates stats.
@@ -212,20 +503,40 @@ func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
 		}
 		p.client = client
 	}
-
-	var wg sync.WaitGroup
+	if p.BearerToken != "" && p.bearerToken == nil {
+		p.bearerToken = newBearerTokenFile(p.BearerToken)
+	}
 
 	allURLs, err := p.GetAllURLs()
 	if err != nil {
 		return err
 	}
-	for _, URL := range allURLs {
+	if p.StalenessMarkers != "" {
+		p.addStaleMarkers(allURLs, acc)
+	}
+
+	// The targets are scraped by a pool of workers, one per target unless
+	// limited.
+	workers := len(allURLs)
+	if p.MaxConcurrentScrapes > 0 && p.MaxConcurrentScrapes < workers {
+		workers = p.MaxConcurrentScrapes
+	}
+
+	targets := make(chan URLAndAddress)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
-		go func(serviceURL URLAndAddress) {
+		go func() {
 			defer wg.Done()
-			acc.AddError(p.gatherURL(serviceURL, acc))
-		}(URL)
+			for u := range targets {
+				p.scrape(u, acc)
+			}
+		}()
 	}
+	for _, u := range allURLs {
+		targets <- u
+	}
+	close(targets)
 
 	wg.Wait()
 
@@ -243,22 +554,19 @@ func (p *Prometheus) createHTTPClient() (*http.Client, error) {
 			TLSClientConfig:   tlsCfg,
 			DisableKeepAlives: true,
 		},
-		Timeout: p.ResponseTimeout.Duration,
 	}
 
 	return client, nil
 }
 
-func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error {
+// gatherURL adds the metrics of the target and returns their number.
+func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) (int, error) {
 	var req *http.Request
 	var err error
 	var uClient *http.Client
 	var metrics []telegraf.Metric
 	if u.URL.Scheme == "unix" {
-		path := u.URL.Query().Get("path")
-		if path == "" {
-			path = "/metrics"
-		}
+		socket, path := unixSocketPath(u.URL)
 		req, err = http.NewRequest("GET", "http://localhost"+path, nil)
 
 		// ignore error because it's been handled before getting here
@@ -268,11 +576,10 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 				TLSClientConfig:   tlsCfg,
 				DisableKeepAlives: true,
 				Dial: func(network, addr string) (net.Conn, error) {
-					c, err := net.Dial("unix", u.URL.Path)
+					c, err := net.Dial("unix", socket)
 					return c, err
 				},
 			},
-			Timeout: p.ResponseTimeout.Duration,
 		}
 	} else {
 		if u.URL.Path == "" {
@@ -281,14 +588,40 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 		req, err = http.NewRequest("GET", u.URL.String(), nil)
 	}
 
-	req.Header.Add("Accept", acceptHeader)
+	if err != nil {
+		return 0, err
+	}
 
-	if p.BearerToken != "" {
-		token, err := ioutil.ReadFile(p.BearerToken)
+	// The timeout of the target covers the request and the reading of the
+	// body, so a slow target does not delay the others.
+	timeout := p.ResponseTimeout.Duration
+	if u.Timeout > 0 {
+		timeout = u.Timeout
+	}
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	if p.Exemplars != "" {
+		req.Header.Add("Accept", openMetricsAcceptHeader)
+	} else {
+		req.Header.Add("Accept", acceptHeader)
+	}
+
+	// The targets with their own transport carry the credentials of the
+	// API server, which must not be replaced.
+	if u.Transport != nil {
+		uClient = &http.Client{
+			Transport: u.Transport,
+		}
+	} else if p.bearerToken != nil {
+		token, err := p.bearerToken.Token()
 		if err != nil {
-			return err
+			return 0, err
 		}
-		req.Header.Set("Authorization", "Bearer "+string(token))
+		req.Header.Set("Authorization", "Bearer "+token)
 	} else if p.BearerTokenString != "" {
 		req.Header.Set("Authorization", "Bearer "+p.BearerTokenString)
 	} else if p.Username != "" || p.Password != "" {
@@ -296,23 +629,31 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 	}
 
 	var resp *http.Response
-	if u.URL.Scheme != "unix" {
+	if uClient == nil {
 		resp, err = p.client.Do(req)
 	} else {
 		resp, err = uClient.Do(req)
 	}
 	if err != nil {
-		return fmt.Errorf("error making HTTP request to %s: %s", u.URL, err)
+		return 0, fmt.Errorf("error making HTTP request to %s: %s", u.URL, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%s returned HTTP status %s", u.URL, resp.Status)
+		return 0, fmt.Errorf("%s returned HTTP status %s", u.URL, resp.Status)
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("error reading body: %s", err)
+		return 0, fmt.Errorf("error reading body: %s", err)
+	}
+
+	var exemplars []exemplar
+	if isOpenMetrics(resp.Header) {
+		body, exemplars, err = parseOpenMetrics(body)
+		if err != nil {
+			return 0, fmt.Errorf("error reading OpenMetrics for %s: %s", u.URL, err)
+		}
 	}
 
 	if p.MetricVersion == 2 {
@@ -322,63 +663,253 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 	}
 
 	if err != nil {
-		return fmt.Errorf("error reading metrics for %s: %s",
+		return 0, fmt.Errorf("error reading metrics for %s: %s",
 			u.URL, err)
 	}
+	metrics = p.addExemplars(metrics, exemplars)
 
+	var scraped []scrapedSeries
 	for _, metric := range metrics {
 		tags := metric.Tags()
+		for k, v := range p.targetTags(u) {
+			tags[k] = v
+		}
+
+		for _, s := range p.relabelMetric(metric.Name(), tags, metric.Fields()) {
+			addSeries(acc, metric.Type(), s.name, s.fields, s.tags, metric.Time())
+			if p.StalenessMarkers != "" {
+				fields := make([]string, 0, len(s.fields))
+				for k := range s.fields {
+					fields = append(fields, k)
+				}
+				scraped = append(scraped, scrapedSeries{name: s.name, tags: s.tags, fields: fields, tp: metric.Type()})
+			}
+		}
+	}
+
+	if p.StalenessMarkers != "" {
+		p.staleness.set(u.URL.String(), scraped)
+	}
+	return len(metrics), nil
+}
+
+// unixSocketPath returns the socket and the HTTP path of a unix URL, the path
+// being either the path query parameter or following the socket after a
+// colon, as in "unix:///run/app.sock:/metrics".
+func unixSocketPath(u *url.URL) (string, string) {
+	socket := u.Path
+	path := u.Query().Get("path")
+	if i := strings.Index(socket, ":/"); i >= 0 {
+		if path == "" {
+			path = socket[i+1:]
+		}
+		socket = socket[:i]
+	}
+	if path == "" {
+		path = "/metrics"
+	}
+	return socket, path
+}
+
+// targetTags returns the tags of the target added to its metrics.
+func (p *Prometheus) targetTags(u URLAndAddress) map[string]string {
+	tags := make(map[string]string, len(u.Tags)+2)
+	if p.URLTag != "" {
 		// strip user and password from URL
-		u.OriginalURL.User = nil
-		if p.URLTag != "" {
-			tags[p.URLTag] = u.OriginalURL.String()
+		original := *u.OriginalURL
+		original.User = nil
+		tags[p.URLTag] = original.String()
+	}
+	if u.Address != "" {
+		tags["address"] = u.Address
+	}
+	for k, v := range u.Tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// scrape gathers the metrics of the target, with the status of the scrape
+// when enabled.
+func (p *Prometheus) scrape(u URLAndAddress, acc telegraf.Accumulator) {
+	start := time.Now()
+	count, err := p.gatherURL(u, acc)
+	acc.AddError(err)
+	if !p.ScrapeStatus {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"success":  err == nil,
+		"duration": time.Since(start).Seconds(),
+	}
+	if err == nil {
+		fields["metrics"] = count
+	}
+	acc.AddFields("prometheus_scrape", fields, p.targetTags(u), start)
+}
+
+// series is a metric as relabeled by the metric_relabel_configs.
+type series struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// relabelMetric applies the metric_relabel_configs to the metric.  With
+// metric_version = 2 the name of a series is the field, each field is
+// relabeled on its own and the fields are grouped back by tags.
+func (p *Prometheus) relabelMetric(name string, tags map[string]string, fields map[string]interface{}) []series {
+	if len(p.MetricRelabelConfigs) == 0 {
+		return []series{{name: name, tags: tags, fields: fields}}
+	}
+
+	relabelName := func(name string) (string, map[string]string, bool) {
+		labels := make(map[string]string, len(tags)+1)
+		for k, v := range tags {
+			labels[k] = v
 		}
-		if u.Address != "" {
-			tags["address"] = u.Address
+		labels[nameLabel] = name
+		if !relabel(labels, p.MetricRelabelConfigs) {
+			return "", nil, false
 		}
-		for k, v := range u.Tags {
-			tags[k] = v
+		if n := labels[nameLabel]; n != "" {
+			name = n
 		}
+		delete(labels, nameLabel)
+		return name, labels, true
+	}
 
-		switch metric.Type() {
-		case telegraf.Counter:
-			acc.AddCounter(metric.Name(), metric.Fields(), tags, metric.Time())
-		case telegraf.Gauge:
-			acc.AddGauge(metric.Name(), metric.Fields(), tags, metric.Time())
-		case telegraf.Summary:
-			acc.AddSummary(metric.Name(), metric.Fields(), tags, metric.Time())
-		case telegraf.Histogram:
-			acc.AddHistogram(metric.Name(), metric.Fields(), tags, metric.Time())
-		default:
-			acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+	if p.MetricVersion != 2 {
+		name, labels, ok := relabelName(name)
+		if !ok {
+			return nil
 		}
+		return []series{{name: name, tags: labels, fields: fields}}
 	}
 
-	return nil
+	var relabeled []series
+	for field, value := range fields {
+		field, labels, ok := relabelName(field)
+		if !ok {
+			continue
+		}
+		merged := false
+		for _, s := range relabeled {
+			if reflect.DeepEqual(s.tags, labels) {
+				s.fields[field] = value
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			relabeled = append(relabeled, series{
+				name:   name,
+				tags:   labels,
+				fields: map[string]interface{}{field: value},
+			})
+		}
+	}
+	return relabeled
 }
 
 // Start will start the Kubernetes scraping if enabled in the configuration
 func (p *Prometheus) Start(a telegraf.Accumulator) error {
+	if len(p.FileSDFiles) > 0 {
+		p.fileSD = newFileSD(p.FileSDFiles, p.Log)
+		if err := p.fileSD.Start(); err != nil {
+			return err
+		}
+	}
+
+	if c := p.ConsulDiscovery; c != nil {
+		path := c.MetricsPath
+		if path == "" {
+			path = "/metrics"
+		}
+		p.consul = discovery.New(discovery.Config{
+			Consul:          &c.ConsulConfig,
+			Scheme:          c.MetricsScheme,
+			Path:            path,
+			RefreshInterval: c.QueryInterval,
+		}, p.Log)
+		if err := p.consul.Start(); err != nil {
+			p.Stop()
+			return err
+		}
+	}
+
+	if c := p.HTTPDiscovery; c != nil {
+		p.httpSD = discovery.New(discovery.Config{
+			HTTP:            &c.HTTPConfig,
+			Path:            "/metrics",
+			RefreshInterval: c.RefreshInterval,
+		}, p.Log)
+		if err := p.httpSD.Start(); err != nil {
+			p.Stop()
+			return err
+		}
+	}
+
 	if p.MonitorPods {
-		var ctx context.Context
-		ctx, p.cancel = context.WithCancel(context.Background())
-		return p.start(ctx)
+		p.discoveries = append(p.discoveries, p.newDiscovery(k8sdiscovery.RolePod, "9102"))
+	}
+	if p.MonitorServices {
+		p.discoveries = append(p.discoveries, p.newDiscovery(k8sdiscovery.RoleService, ""))
+	}
+	if p.MonitorEndpoints {
+		p.discoveries = append(p.discoveries, p.newDiscovery(k8sdiscovery.RoleEndpoints, ""))
+	}
+
+	for _, d := range p.discoveries {
+		if err := d.Start(); err != nil {
+			p.Stop()
+			return err
+		}
 	}
 	return nil
 }
 
+// newDiscovery returns a discovery of the role, objects without the port
+// annotation are scraped at the port, or their first port when empty.
+func (p *Prometheus) newDiscovery(role, port string) *k8sdiscovery.Discovery {
+	return k8sdiscovery.New(k8sdiscovery.Config{
+		Role:               role,
+		Port:               port,
+		Path:               "/metrics",
+		Namespace:          p.PodNamespace,
+		NodeName:           p.PodNode,
+		KubeConfig:         p.KubeConfig,
+		ExcludeAnnotations: p.ExcludeAnnotations,
+		ExcludeLabels:      p.ExcludeLabels,
+		LabelSelector:      p.LabelSelector,
+		FieldSelector:      p.FieldSelector,
+		APIServerProxy:     p.PodAPIServerProxy && role == k8sdiscovery.RolePod,
+		PodMetadataTags:    p.PodMetadataTags,
+		ResyncInterval:     p.ResyncInterval,
+	}, p.Log)
+}
+
 func (p *Prometheus) Stop() {
-	if p.MonitorPods {
-		p.cancel()
+	if p.fileSD != nil {
+		p.fileSD.Stop()
+	}
+	if p.consul != nil {
+		p.consul.Stop()
+	}
+	if p.httpSD != nil {
+		p.httpSD.Stop()
+	}
+	for _, d := range p.discoveries {
+		d.Stop()
 	}
-	p.wg.Wait()
+	p.discoveries = nil
 }
 
 func init() {
 	inputs.Add("prometheus", func() telegraf.Input {
 		return &Prometheus{
 			ResponseTimeout: internal.Duration{Duration: time.Second * 3},
-			kubernetesPods:  map[string]URLAndAddress{},
 			URLTag:          "url",
 		}
 	})
@@ -2,12 +2,20 @@ package prometheus
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -169,3 +177,265 @@ func TestPrometheusGeneratesGaugeMetricsV2(t *testing.T) {
 	assert.True(t, acc.TagValue("prometheus", "url") == ts.URL+"/metrics")
 	assert.True(t, acc.HasTimestamp("prometheus", time.Unix(1490802350, 0)))
 }
+
+func TestPrometheusMetricRelabelV2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:           testutil.Logger{},
+		URLs:          []string{ts.URL},
+		MetricVersion: 2,
+		MetricRelabelConfigs: []*RelabelConfig{
+			{SourceLabels: []string{"__name__"}, Regex: "go_gc_.*", Action: "drop"},
+			{SourceLabels: []string{"label"}, Regex: "(.+)", TargetLabel: "kind", Replacement: "test_$1"},
+			{Regex: "label", Action: "labeldrop"},
+		},
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	assert.True(t, acc.HasFloatField("prometheus", "go_goroutines"))
+	assert.False(t, acc.HasFloatField("prometheus", "go_gc_duration_seconds_sum"))
+	assert.False(t, acc.HasTag("prometheus", "label"))
+	assert.Equal(t, "test_value", acc.TagSetValue("prometheus", "kind"))
+}
+
+func TestPrometheusTargetRelabel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/custom" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, sampleGaugeTextFormat)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:           testutil.Logger{},
+		URLs:          []string{ts.URL, "http://dropped.example.org:9100"},
+		URLTag:        "url",
+		MetricVersion: 2,
+		RelabelConfigs: []*RelabelConfig{
+			{SourceLabels: []string{"__address__"}, Regex: "dropped.*", Action: "drop"},
+			{TargetLabel: "__metrics_path__", Replacement: "/custom"},
+			{SourceLabels: []string{"__address__"}, Regex: "(.*):.*", TargetLabel: "host"},
+		},
+	}
+	require.NoError(t, p.Init())
+
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	assert.True(t, acc.HasFloatField("prometheus", "go_goroutines"))
+	assert.Equal(t, ts.URL+"/custom", acc.TagValue("prometheus", "url"))
+	assert.Equal(t, "127.0.0.1", acc.TagValue("prometheus", "host"))
+}
+
+func TestPrometheusMaxConcurrentScrapes(t *testing.T) {
+	var lock sync.Mutex
+	var running, maxRunning int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, sampleGaugeTextFormat)
+
+		lock.Lock()
+		running--
+		lock.Unlock()
+	}))
+	defer ts.Close()
+
+	var urls []string
+	for i := 0; i < 6; i++ {
+		urls = append(urls, fmt.Sprintf("%s/metrics?target=%d", ts.URL, i))
+	}
+	p := &Prometheus{
+		Log:                  testutil.Logger{},
+		URLs:                 urls,
+		MaxConcurrentScrapes: 2,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+	require.Len(t, acc.GetTelegrafMetrics(), 6)
+	require.True(t, maxRunning <= 2, "%d concurrent scrapes", maxRunning)
+}
+
+func TestPrometheusTargetTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	p := &Prometheus{
+		Log:             testutil.Logger{},
+		ResponseTimeout: internal.Duration{Duration: 5 * time.Second},
+	}
+	u, err := url.Parse(slow.URL)
+	require.NoError(t, err)
+
+	start := time.Now()
+	var acc testutil.Accumulator
+	p.client, err = p.createHTTPClient()
+	require.NoError(t, err)
+	_, err = p.gatherURL(URLAndAddress{URL: u, OriginalURL: u, Timeout: 50 * time.Millisecond}, &acc)
+	require.Error(t, err)
+	require.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestUnixSocketPath(t *testing.T) {
+	tests := []struct {
+		url    string
+		socket string
+		path   string
+	}{
+		{"unix:///run/app.sock", "/run/app.sock", "/metrics"},
+		{"unix:///run/app.sock?path=/custom", "/run/app.sock", "/custom"},
+		{"unix:///run/app.sock:/custom/metrics", "/run/app.sock", "/custom/metrics"},
+		{"unix:///run/app.sock:/custom?path=/other", "/run/app.sock", "/other"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		require.NoError(t, err)
+		socket, path := unixSocketPath(u)
+		require.Equal(t, tt.socket, socket, tt.url)
+		require.Equal(t, tt.path, path, tt.url)
+	}
+}
+
+func TestPrometheusGeneratesMetricsUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prometheus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "metrics.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer l.Close()
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/custom/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+
+	p := &Prometheus{
+		Log:  testutil.Logger{},
+		URLs: []string{"unix://" + socket + ":/custom/metrics"},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+	require.True(t, acc.HasFloatField("test_metric", "value"))
+}
+
+func TestPrometheusStalenessMarkers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "# TYPE up gauge\nup 1\n")
+	}))
+	defer ts.Close()
+
+	for _, mode := range []string{"nan", "absent"} {
+		p := &Prometheus{
+			Log:              testutil.Logger{},
+			URLs:             []string{ts.URL + "/a", ts.URL + "/b"},
+			URLTag:           "url",
+			MetricVersion:    2,
+			StalenessMarkers: mode,
+		}
+		require.NoError(t, p.Init())
+
+		var acc testutil.Accumulator
+		require.NoError(t, p.Gather(&acc))
+		require.Len(t, acc.Metrics, 2)
+
+		// The series of the target which disappeared are marked once.
+		p.URLs = []string{ts.URL + "/a"}
+		acc.ClearMetrics()
+		require.NoError(t, p.Gather(&acc))
+		require.Len(t, acc.Metrics, 2)
+
+		var stale []*testutil.Metric
+		for _, m := range acc.Metrics {
+			if m.Tags["url"] == ts.URL+"/b" {
+				stale = append(stale, m)
+			}
+		}
+		require.Len(t, stale, 1, mode)
+		if mode == "nan" {
+			require.Len(t, stale[0].Fields, 1)
+			require.Equal(t, math.Float64bits(staleNaN), math.Float64bits(stale[0].Fields["up"].(float64)))
+			require.Equal(t, telegraf.Gauge, stale[0].Type)
+		} else {
+			require.Equal(t, map[string]interface{}{"stale": true}, stale[0].Fields)
+		}
+
+		acc.ClearMetrics()
+		require.NoError(t, p.Gather(&acc))
+		require.Len(t, acc.Metrics, 1)
+	}
+
+	require.Error(t, (&Prometheus{Log: testutil.Logger{}, StalenessMarkers: "zero"}).Init())
+}
+
+func TestPrometheusScrapeStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:          testutil.Logger{},
+		URLs:         []string{ts.URL + "/metrics", ts.URL + "/missing"},
+		URLTag:       "url",
+		ScrapeStatus: true,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+
+	var scrapes []*testutil.Metric
+	for _, m := range acc.Metrics {
+		if m.Measurement == "prometheus_scrape" {
+			scrapes = append(scrapes, m)
+		}
+	}
+	require.Len(t, scrapes, 2)
+	for _, m := range scrapes {
+		require.Contains(t, m.Fields, "duration")
+		switch m.Tags["url"] {
+		case ts.URL + "/metrics":
+			require.Equal(t, true, m.Fields["success"])
+			require.Equal(t, 3, m.Fields["metrics"])
+		case ts.URL + "/missing":
+			require.Equal(t, false, m.Fields["success"])
+			require.NotContains(t, m.Fields, "metrics")
+		default:
+			t.Fatalf("unexpected url %q", m.Tags["url"])
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The labels of the targets which are not added as tags, but set the URL
+// scraped, and the label of the name of the metrics.
+const (
+	addressLabel     = "__address__"
+	schemeLabel      = "__scheme__"
+	metricsPathLabel = "__metrics_path__"
+	nameLabel        = "__name__"
+)
+
+// RelabelConfig is a rule of the relabel_configs and metric_relabel_configs
+// of Prometheus.
+type RelabelConfig struct {
+	SourceLabels []string `toml:"source_labels"`
+	Separator    string   `toml:"separator"`
+	Regex        string   `toml:"regex"`
+	TargetLabel  string   `toml:"target_label"`
+	Replacement  string   `toml:"replacement"`
+	Action       string   `toml:"action"`
+
+	regex *regexp.Regexp
+}
+
+func (c *RelabelConfig) init() error {
+	if c.Action == "" {
+		c.Action = "replace"
+	}
+	if c.Separator == "" {
+		c.Separator = ";"
+	}
+	if c.Regex == "" {
+		c.Regex = "(.*)"
+	}
+	if c.Replacement == "" {
+		c.Replacement = "$1"
+	}
+
+	switch c.Action {
+	case "replace":
+		if c.TargetLabel == "" {
+			return fmt.Errorf("relabel action %q needs a target_label", c.Action)
+		}
+	case "keep", "drop":
+		if len(c.SourceLabels) == 0 {
+			return fmt.Errorf("relabel action %q needs source_labels", c.Action)
+		}
+	case "labeldrop", "labelkeep":
+	default:
+		return fmt.Errorf("unknown relabel action %q", c.Action)
+	}
+
+	// The regex matches the whole value, as in Prometheus.
+	regex, err := regexp.Compile("^(?:" + c.Regex + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid relabel regex %q: %s", c.Regex, err)
+	}
+	c.regex = regex
+	return nil
+}
+
+// relabel applies the rules to the labels in order, false is returned when
+// the labels are dropped.  An empty label set by a replacement is removed.
+func relabel(labels map[string]string, configs []*RelabelConfig) bool {
+	for _, c := range configs {
+		values := make([]string, 0, len(c.SourceLabels))
+		for _, name := range c.SourceLabels {
+			values = append(values, labels[name])
+		}
+		value := strings.Join(values, c.Separator)
+
+		switch c.Action {
+		case "replace":
+			match := c.regex.FindStringSubmatchIndex(value)
+			if match == nil {
+				continue
+			}
+			target := string(c.regex.ExpandString(nil, c.Replacement, value, match))
+			if target == "" {
+				delete(labels, c.TargetLabel)
+				continue
+			}
+			labels[c.TargetLabel] = target
+		case "keep":
+			if !c.regex.MatchString(value) {
+				return false
+			}
+		case "drop":
+			if c.regex.MatchString(value) {
+				return false
+			}
+		case "labeldrop":
+			for name := range labels {
+				if c.regex.MatchString(name) {
+					delete(labels, name)
+				}
+			}
+		case "labelkeep":
+			for name := range labels {
+				if !c.regex.MatchString(name) {
+					delete(labels, name)
+				}
+			}
+		}
+	}
+	return true
+}
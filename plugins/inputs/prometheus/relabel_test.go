@@ -0,0 +1,124 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		configs  []*RelabelConfig
+		labels   map[string]string
+		expected map[string]string
+		keep     bool
+	}{
+		{
+			name: "replace",
+			configs: []*RelabelConfig{{
+				SourceLabels: []string{"namespace", "pod_name"},
+				Regex:        "(.+);(.+)",
+				TargetLabel:  "instance",
+				Replacement:  "$1/$2",
+			}},
+			labels:   map[string]string{"namespace": "default", "pod_name": "web-0"},
+			expected: map[string]string{"namespace": "default", "pod_name": "web-0", "instance": "default/web-0"},
+			keep:     true,
+		},
+		{
+			name: "replace without match",
+			configs: []*RelabelConfig{{
+				SourceLabels: []string{"namespace"},
+				Regex:        "kube-.*",
+				TargetLabel:  "system",
+				Replacement:  "true",
+			}},
+			labels:   map[string]string{"namespace": "default"},
+			expected: map[string]string{"namespace": "default"},
+			keep:     true,
+		},
+		{
+			name: "replace with empty value removes the label",
+			configs: []*RelabelConfig{{
+				SourceLabels: []string{"missing"},
+				TargetLabel:  "namespace",
+			}},
+			labels:   map[string]string{"namespace": "default"},
+			expected: map[string]string{},
+			keep:     true,
+		},
+		{
+			name: "regex matches the whole value",
+			configs: []*RelabelConfig{{
+				SourceLabels: []string{"namespace"},
+				Regex:        "kube",
+				Action:       "drop",
+			}},
+			labels:   map[string]string{"namespace": "kube-system"},
+			expected: map[string]string{"namespace": "kube-system"},
+			keep:     true,
+		},
+		{
+			name: "drop",
+			configs: []*RelabelConfig{{
+				SourceLabels: []string{"namespace"},
+				Regex:        "kube-.*",
+				Action:       "drop",
+			}},
+			labels: map[string]string{"namespace": "kube-system"},
+			keep:   false,
+		},
+		{
+			name: "keep",
+			configs: []*RelabelConfig{{
+				SourceLabels: []string{"app"},
+				Regex:        "web|api",
+				Action:       "keep",
+			}},
+			labels: map[string]string{"app": "worker"},
+			keep:   false,
+		},
+		{
+			name: "labeldrop",
+			configs: []*RelabelConfig{{
+				Regex:  "controller_.*",
+				Action: "labeldrop",
+			}},
+			labels:   map[string]string{"app": "web", "controller_revision_hash": "abc"},
+			expected: map[string]string{"app": "web"},
+			keep:     true,
+		},
+		{
+			name: "labelkeep",
+			configs: []*RelabelConfig{{
+				Regex:  "app|__.*",
+				Action: "labelkeep",
+			}},
+			labels:   map[string]string{"app": "web", "pod_template_hash": "abc", "__name__": "up"},
+			expected: map[string]string{"app": "web", "__name__": "up"},
+			keep:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range tt.configs {
+				require.NoError(t, c.init())
+			}
+			keep := relabel(tt.labels, tt.configs)
+			require.Equal(t, tt.keep, keep)
+			if keep {
+				require.Equal(t, tt.expected, tt.labels)
+			}
+		})
+	}
+}
+
+func TestRelabelConfigInit(t *testing.T) {
+	require.Error(t, (&RelabelConfig{Action: "hashmod"}).init())
+	require.Error(t, (&RelabelConfig{}).init())
+	require.Error(t, (&RelabelConfig{Action: "keep"}).init())
+	require.Error(t, (&RelabelConfig{TargetLabel: "a", Regex: "("}).init())
+	require.NoError(t, (&RelabelConfig{Action: "labeldrop", Regex: "a"}).init())
+}
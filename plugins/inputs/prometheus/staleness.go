@@ -0,0 +1,96 @@
+package prometheus
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// staleMarkersNaN sets the fields of the series to the staleness
+	// marker of Prometheus.
+	staleMarkersNaN = "nan"
+	// staleMarkersAbsent adds the series with a single stale field.
+	staleMarkersAbsent = "absent"
+)
+
+// staleNaN is the NaN value Prometheus uses to mark a series as stale,
+// distinct from the NaN values of the samples.
+var staleNaN = math.Float64frombits(0x7ff0000000000002)
+
+// scrapedSeries is a series added by the last successful scrape of a target.
+type scrapedSeries struct {
+	name   string
+	tags   map[string]string
+	fields []string
+	tp     telegraf.ValueType
+}
+
+// staleness keeps the series of the targets, to mark them stale once their
+// target disappears, such as a deleted pod.
+type staleness struct {
+	lock   sync.Mutex
+	series map[string][]scrapedSeries
+}
+
+// set replaces the series of the target.
+func (s *staleness) set(target string, series []scrapedSeries) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.series == nil {
+		s.series = make(map[string][]scrapedSeries)
+	}
+	s.series[target] = series
+}
+
+// removeGone removes the targets which are not in the given targets and
+// returns their series.
+func (s *staleness) removeGone(targets map[string]URLAndAddress) []scrapedSeries {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var gone []scrapedSeries
+	for target, series := range s.series {
+		if _, ok := targets[target]; !ok {
+			gone = append(gone, series...)
+			delete(s.series, target)
+		}
+	}
+	return gone
+}
+
+// addStaleMarkers adds the staleness markers of the series of the targets
+// which disappeared since the last gather.
+func (p *Prometheus) addStaleMarkers(targets map[string]URLAndAddress, acc telegraf.Accumulator) {
+	now := time.Now()
+	for _, s := range p.staleness.removeGone(targets) {
+		fields := make(map[string]interface{}, len(s.fields))
+		tp := s.tp
+		if p.StalenessMarkers == staleMarkersAbsent {
+			fields["stale"] = true
+			tp = telegraf.Untyped
+		} else {
+			for _, field := range s.fields {
+				fields[field] = staleNaN
+			}
+		}
+		addSeries(acc, tp, s.name, fields, s.tags, now)
+	}
+}
+
+// addSeries adds the fields to the accumulator as a metric of the type.
+func addSeries(acc telegraf.Accumulator, tp telegraf.ValueType, name string, fields map[string]interface{}, tags map[string]string, t time.Time) {
+	switch tp {
+	case telegraf.Counter:
+		acc.AddCounter(name, fields, tags, t)
+	case telegraf.Gauge:
+		acc.AddGauge(name, fields, tags, t)
+	case telegraf.Summary:
+		acc.AddSummary(name, fields, tags, t)
+	case telegraf.Histogram:
+		acc.AddHistogram(name, fields, tags, t)
+	default:
+		acc.AddFields(name, fields, tags, t)
+	}
+}
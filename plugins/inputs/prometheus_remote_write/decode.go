@@ -0,0 +1,457 @@
+package prometheus_remote_write
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Protocol buffer wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// series is a decoded time series of a remote write request.
+type series struct {
+	labels     map[string]string
+	samples    []sample
+	valueType  telegraf.ValueType
+	histograms int
+	exemplars  int
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+// Prometheus metric types, the values are the same in the metadata of
+// remote write v1 and v2.
+var metricTypes = map[uint64]telegraf.ValueType{
+	1: telegraf.Counter,
+	2: telegraf.Gauge,
+	3: telegraf.Histogram,
+	4: telegraf.Histogram,
+	5: telegraf.Summary,
+}
+
+// protoReader reads the fields of an encoded protocol buffer message.
+type protoReader struct {
+	buf []byte
+}
+
+func (r *protoReader) done() bool {
+	return len(r.buf) == 0
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+// key returns the field number and wire type of the next field.
+func (r *protoReader) key() (int, int, error) {
+	v, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(v >> 3), int(v & 7), nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	l, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.buf)) < l {
+		return nil, errTruncated
+	}
+	b := r.buf[:l]
+	r.buf = r.buf[l:]
+	return b, nil
+}
+
+func (r *protoReader) fixed64() (uint64, error) {
+	if len(r.buf) < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v, nil
+}
+
+func (r *protoReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		if len(r.buf) < 4 {
+			return errTruncated
+		}
+		r.buf = r.buf[4:]
+	default:
+		return fmt.Errorf("unsupported wire type %d", wireType)
+	}
+	return err
+}
+
+// decodeSample decodes a Sample message, which is the same in v1 and v2.
+func decodeSample(b []byte) (sample, error) {
+	var s sample
+	r := &protoReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.key()
+		if err != nil {
+			return s, err
+		}
+		switch {
+		case field == 1 && wireType == wireFixed64:
+			v, err := r.fixed64()
+			if err != nil {
+				return s, err
+			}
+			s.value = math.Float64frombits(v)
+		case field == 2 && wireType == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return s, err
+			}
+			s.timestamp = int64(v)
+		default:
+			if err := r.skip(wireType); err != nil {
+				return s, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// decodeV1 decodes a prometheus.WriteRequest message.
+func decodeV1(b []byte) ([]*series, error) {
+	var result []*series
+	types := make(map[string]telegraf.ValueType)
+
+	r := &protoReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wireType == wireBytes:
+			msg, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			s, err := decodeV1Series(msg)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, s)
+		case field == 3 && wireType == wireBytes:
+			msg, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			name, valueType, err := decodeV1Metadata(msg)
+			if err != nil {
+				return nil, err
+			}
+			types[name] = valueType
+		default:
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, s := range result {
+		if t, ok := types[s.labels["__name__"]]; ok {
+			s.valueType = t
+		}
+	}
+	return result, nil
+}
+
+func decodeV1Series(b []byte) (*series, error) {
+	s := &series{
+		labels:    make(map[string]string),
+		valueType: telegraf.Untyped,
+	}
+
+	r := &protoReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wireType == wireBytes:
+			msg, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			name, value, err := decodeV1Label(msg)
+			if err != nil {
+				return nil, err
+			}
+			s.labels[name] = value
+		case field == 2 && wireType == wireBytes:
+			msg, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			smpl, err := decodeSample(msg)
+			if err != nil {
+				return nil, err
+			}
+			s.samples = append(s.samples, smpl)
+		case field == 3 && wireType == wireBytes:
+			s.exemplars++
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		case field == 4 && wireType == wireBytes:
+			s.histograms++
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		default:
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func decodeV1Label(b []byte) (string, string, error) {
+	var name, value string
+	r := &protoReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.key()
+		if err != nil {
+			return "", "", err
+		}
+		if wireType != wireBytes || (field != 1 && field != 2) {
+			if err := r.skip(wireType); err != nil {
+				return "", "", err
+			}
+			continue
+		}
+
+		v, err := r.bytes()
+		if err != nil {
+			return "", "", err
+		}
+		if field == 1 {
+			name = string(v)
+		} else {
+			value = string(v)
+		}
+	}
+	return name, value, nil
+}
+
+func decodeV1Metadata(b []byte) (string, telegraf.ValueType, error) {
+	var name string
+	valueType := telegraf.Untyped
+
+	r := &protoReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.key()
+		if err != nil {
+			return "", valueType, err
+		}
+		switch {
+		case field == 1 && wireType == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return "", valueType, err
+			}
+			if t, ok := metricTypes[v]; ok {
+				valueType = t
+			}
+		case field == 2 && wireType == wireBytes:
+			v, err := r.bytes()
+			if err != nil {
+				return "", valueType, err
+			}
+			name = string(v)
+		default:
+			if err := r.skip(wireType); err != nil {
+				return "", valueType, err
+			}
+		}
+	}
+	return name, valueType, nil
+}
+
+// decodeV2 decodes an io.prometheus.write.v2.Request message.  The label
+// names and values of the series reference the symbols table of the request.
+func decodeV2(b []byte) ([]*series, error) {
+	var symbols []string
+	var encoded [][]byte
+
+	r := &protoReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 4 && wireType == wireBytes:
+			v, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			symbols = append(symbols, string(v))
+		case field == 5 && wireType == wireBytes:
+			// The symbols may follow the series, decode them afterwards.
+			msg, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, msg)
+		default:
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := make([]*series, 0, len(encoded))
+	for _, msg := range encoded {
+		s, err := decodeV2Series(msg, symbols)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func decodeV2Series(b []byte, symbols []string) (*series, error) {
+	s := &series{
+		labels:    make(map[string]string),
+		valueType: telegraf.Untyped,
+	}
+
+	var refs []uint64
+	r := &protoReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wireType == wireBytes:
+			// Packed label references
+			packed, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			pr := &protoReader{buf: packed}
+			for !pr.done() {
+				ref, err := pr.varint()
+				if err != nil {
+					return nil, err
+				}
+				refs = append(refs, ref)
+			}
+		case field == 1 && wireType == wireVarint:
+			ref, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		case field == 2 && wireType == wireBytes:
+			msg, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			smpl, err := decodeSample(msg)
+			if err != nil {
+				return nil, err
+			}
+			s.samples = append(s.samples, smpl)
+		case field == 3 && wireType == wireBytes:
+			s.histograms++
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		case field == 4 && wireType == wireBytes:
+			s.exemplars++
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		case field == 5 && wireType == wireBytes:
+			msg, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			s.valueType, err = decodeV2Metadata(msg)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(refs)%2 != 0 {
+		return nil, errors.New("odd number of label references")
+	}
+	for i := 0; i < len(refs); i += 2 {
+		if refs[i] >= uint64(len(symbols)) || refs[i+1] >= uint64(len(symbols)) {
+			return nil, fmt.Errorf("label reference out of range of %d symbols", len(symbols))
+		}
+		s.labels[symbols[refs[i]]] = symbols[refs[i+1]]
+	}
+	return s, nil
+}
+
+func decodeV2Metadata(b []byte) (telegraf.ValueType, error) {
+	valueType := telegraf.Untyped
+
+	r := &protoReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.key()
+		if err != nil {
+			return valueType, err
+		}
+		if field == 1 && wireType == wireVarint {
+			v, err := r.varint()
+			if err != nil {
+				return valueType, err
+			}
+			if t, ok := metricTypes[v]; ok {
+				valueType = t
+			}
+			continue
+		}
+		if err := r.skip(wireType); err != nil {
+			return valueType, err
+		}
+	}
+	return valueType, nil
+}
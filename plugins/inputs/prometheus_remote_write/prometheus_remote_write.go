@@ -0,0 +1,361 @@
+package prometheus_remote_write
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultMaxBodySize is the default maximum request body size, in bytes.
+// if the request body is over this size, we will return an HTTP 413 error.
+// 32 MB
+const defaultMaxBodySize = 32 * 1024 * 1024
+
+const (
+	protoV1 = "prometheus.WriteRequest"
+	protoV2 = "io.prometheus.write.v2.Request"
+
+	measurement = "prometheus_remote_write"
+)
+
+// PrometheusRemoteWrite is an input plugin implementing the receiving side
+// of the Prometheus remote write protocol.
+type PrometheusRemoteWrite struct {
+	ServiceAddress string            `toml:"service_address"`
+	Path           string            `toml:"path"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	WriteTimeout   internal.Duration `toml:"write_timeout"`
+	MaxBodySize    internal.Size     `toml:"max_body_size"`
+	BasicUsername  string            `toml:"basic_username"`
+	BasicPassword  string            `toml:"basic_password"`
+	TenantHeader   string            `toml:"tenant_header"`
+	TenantTag      string            `toml:"tenant_tag"`
+	tlsint.ServerConfig
+
+	Log telegraf.Logger
+
+	port     int
+	wg       sync.WaitGroup
+	listener net.Listener
+	zstd     *zstd.Decoder
+	acc      telegraf.Accumulator
+}
+
+const sampleConfig = `
+  ## Address and port to host the remote write receiver on.
+  service_address = ":9090"
+
+  ## Path to listen to, the remote_write url of Prometheus should point to
+  ## it, for example "http://telegraf:9090/api/v1/write".
+  # path = "/api/v1/write"
+
+  ## maximum duration before timing out read of the request
+  # read_timeout = "10s"
+  ## maximum duration before timing out write of the response
+  # write_timeout = "10s"
+
+  ## Maximum allowed size of the decompressed request body in bytes.
+  # max_body_size = "32MB"
+
+  ## Name of a request header identifying the tenant, for example the
+  ## "X-Scope-OrgID" header set by Prometheus agents writing to a multi-tenant
+  ## backend.  If set, the header value is added to the metrics as tenant_tag.
+  # tenant_header = ""
+  # tenant_tag = "tenant"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+
+  ## Optional username and password to accept for HTTP basic authentication.
+  ## You probably want to make sure you have TLS configured above for this.
+  # basic_username = "foobar"
+  # basic_password = "barfoo"
+`
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Receive metrics from Prometheus remote write"
+}
+
+func (p *PrometheusRemoteWrite) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Start starts the remote write receiver.
+func (p *PrometheusRemoteWrite) Start(acc telegraf.Accumulator) error {
+	if p.MaxBodySize.Size == 0 {
+		p.MaxBodySize.Size = defaultMaxBodySize
+	}
+
+	if p.ReadTimeout.Duration < time.Second {
+		p.ReadTimeout.Duration = time.Second * 10
+	}
+	if p.WriteTimeout.Duration < time.Second {
+		p.WriteTimeout.Duration = time.Second * 10
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return err
+	}
+	p.zstd = decoder
+
+	p.acc = acc
+
+	tlsConf, err := p.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:         p.ServiceAddress,
+		Handler:      p,
+		ReadTimeout:  p.ReadTimeout.Duration,
+		WriteTimeout: p.WriteTimeout.Duration,
+		TLSConfig:    tlsConf,
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", p.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", p.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+	p.listener = listener
+	p.port = listener.Addr().(*net.TCPAddr).Port
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		server.Serve(p.listener)
+	}()
+
+	p.Log.Infof("Listening on %s", listener.Addr().String())
+
+	return nil
+}
+
+// Stop cleans up all resources
+func (p *PrometheusRemoteWrite) Stop() {
+	p.listener.Close()
+	p.wg.Wait()
+	p.zstd.Close()
+}
+
+func (p *PrometheusRemoteWrite) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != p.Path {
+		http.NotFound(res, req)
+		return
+	}
+
+	if !p.authenticate(req) {
+		http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		http.Error(res, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p.serveWrite(res, req)
+}
+
+func (p *PrometheusRemoteWrite) serveWrite(res http.ResponseWriter, req *http.Request) {
+	proto, err := protoMessage(req.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// The body is read up to the compressed size limit; the compression
+	// ratio of remote write requests is well below the limit.
+	body, err := ioutil.ReadAll(http.MaxBytesReader(res, req.Body, p.MaxBodySize.Size))
+	if err != nil {
+		http.Error(res, "http: request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	data, err := p.decompress(req.Header.Get("Content-Encoding"), body)
+	if err != nil {
+		p.Log.Debugf("Decompression error: %v", err)
+		http.Error(res, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var timeseries []*series
+	switch proto {
+	case protoV2:
+		timeseries, err = decodeV2(data)
+	default:
+		timeseries, err = decodeV1(data)
+	}
+	if err != nil {
+		p.Log.Debugf("Decode error: %v", err)
+		http.Error(res, fmt.Sprintf("decoding %s: %v", proto, err), http.StatusBadRequest)
+		return
+	}
+
+	var tenant string
+	if p.TenantHeader != "" {
+		tenant = req.Header.Get(p.TenantHeader)
+	}
+
+	var samples, histograms, exemplars int
+	for _, s := range timeseries {
+		samples += p.addSeries(s, tenant)
+		histograms += s.histograms
+		exemplars += s.exemplars
+	}
+	if histograms > 0 || exemplars > 0 {
+		p.Log.Debugf("Dropped %d native histograms and %d exemplars", histograms, exemplars)
+	}
+
+	if proto == protoV2 {
+		// Native histograms and exemplars are not converted to metrics and
+		// are reported as not written.
+		res.Header().Set("X-Prometheus-Remote-Write-Samples-Written", strconv.Itoa(samples))
+		res.Header().Set("X-Prometheus-Remote-Write-Histograms-Written", "0")
+		res.Header().Set("X-Prometheus-Remote-Write-Exemplars-Written", "0")
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// protoMessage returns the protobuf message of the request according to the
+// content type, requests without a proto parameter are remote write v1.
+func protoMessage(contentType string) (string, error) {
+	if contentType == "" {
+		return protoV1, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+	if mediaType != "application/x-protobuf" {
+		return "", fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	switch proto := params["proto"]; proto {
+	case "", protoV1:
+		return protoV1, nil
+	case protoV2:
+		return protoV2, nil
+	default:
+		return "", fmt.Errorf("unsupported proto message %q", proto)
+	}
+}
+
+func (p *PrometheusRemoteWrite) decompress(encoding string, body []byte) ([]byte, error) {
+	var data []byte
+	var err error
+	switch encoding {
+	case "", "snappy":
+		var n int
+		n, err = snappy.DecodedLen(body)
+		if err != nil {
+			return nil, err
+		}
+		if int64(n) > p.MaxBodySize.Size {
+			return nil, fmt.Errorf("decompressed body of %d bytes too large", n)
+		}
+		data, err = snappy.Decode(nil, body)
+	case "zstd":
+		data, err = p.zstd.DecodeAll(body, nil)
+		if err == nil && int64(len(data)) > p.MaxBodySize.Size {
+			return nil, fmt.Errorf("decompressed body of %d bytes too large", len(data))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	return data, err
+}
+
+// addSeries adds a metric for each sample of the series, returning the
+// number of samples added.  The metric name is used as field name and the
+// remaining labels as tags.
+func (p *PrometheusRemoteWrite) addSeries(s *series, tenant string) int {
+	name, ok := s.labels["__name__"]
+	if !ok || name == "" {
+		return 0
+	}
+
+	tags := make(map[string]string, len(s.labels))
+	for k, v := range s.labels {
+		if k == "__name__" {
+			continue
+		}
+		tags[k] = v
+	}
+	if tenant != "" {
+		tags[p.TenantTag] = tenant
+	}
+
+	var count int
+	for _, smpl := range s.samples {
+		if math.IsNaN(smpl.value) || math.IsInf(smpl.value, 0) {
+			continue
+		}
+
+		fields := map[string]interface{}{
+			name: smpl.value,
+		}
+		t := time.Unix(0, smpl.timestamp*int64(time.Millisecond))
+		m, err := metric.New(measurement, tags, fields, t, s.valueType)
+		if err != nil {
+			p.acc.AddError(err)
+			continue
+		}
+		p.acc.AddMetric(m)
+		count++
+	}
+	return count
+}
+
+func (p *PrometheusRemoteWrite) authenticate(req *http.Request) bool {
+	if p.BasicUsername == "" || p.BasicPassword == "" {
+		return true
+	}
+
+	reqUsername, reqPassword, ok := req.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(reqUsername), []byte(p.BasicUsername)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(reqPassword), []byte(p.BasicPassword)) == 1
+}
+
+func init() {
+	inputs.Add("prometheus_remote_write", func() telegraf.Input {
+		return &PrometheusRemoteWrite{
+			ServiceAddress: ":9090",
+			Path:           "/api/v1/write",
+			TenantTag:      "tenant",
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// Helpers to encode protocol buffer messages
+
+func appendKey(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendKey(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func encodeSample(value float64, timestamp int64) []byte {
+	var b []byte
+	b = appendKey(b, 1, wireFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(value))
+	b = append(b, buf[:]...)
+	b = appendKey(b, 2, wireVarint)
+	return appendVarint(b, uint64(timestamp))
+}
+
+func encodeV1Request() []byte {
+	label := func(name, value string) []byte {
+		return appendBytes(appendBytes(nil, 1, []byte(name)), 2, []byte(value))
+	}
+
+	var ts []byte
+	ts = appendBytes(ts, 1, label("__name__", "http_requests_total"))
+	ts = appendBytes(ts, 1, label("job", "api"))
+	ts = appendBytes(ts, 2, encodeSample(42, 1000))
+	ts = appendBytes(ts, 2, encodeSample(43, 2000))
+
+	var md []byte
+	md = appendKey(md, 1, wireVarint)
+	md = appendVarint(md, 1)
+	md = appendBytes(md, 2, []byte("http_requests_total"))
+
+	var req []byte
+	req = appendBytes(req, 1, ts)
+	req = appendBytes(req, 3, md)
+	return req
+}
+
+func encodeV2Request() []byte {
+	symbols := []string{"", "__name__", "temperature", "room", "kitchen"}
+
+	var refs []byte
+	for _, ref := range []uint64{1, 2, 3, 4} {
+		refs = appendVarint(refs, ref)
+	}
+
+	var md []byte
+	md = appendKey(md, 1, wireVarint)
+	md = appendVarint(md, 2)
+
+	var ts []byte
+	ts = appendBytes(ts, 1, refs)
+	ts = appendBytes(ts, 2, encodeSample(21.5, 3000))
+	ts = appendBytes(ts, 2, encodeSample(math.NaN(), 4000))
+	ts = appendBytes(ts, 3, []byte{})
+	ts = appendBytes(ts, 5, md)
+
+	var req []byte
+	for _, s := range symbols {
+		req = appendBytes(req, 4, []byte(s))
+	}
+	req = appendBytes(req, 5, ts)
+	return req
+}
+
+func newTestPlugin() *PrometheusRemoteWrite {
+	return &PrometheusRemoteWrite{
+		Log:            testutil.Logger{},
+		ServiceAddress: "localhost:0",
+		Path:           "/api/v1/write",
+		TenantTag:      "tenant",
+	}
+}
+
+func post(t *testing.T, p *PrometheusRemoteWrite, contentType, encoding string, body []byte, headers map[string]string) *http.Response {
+	url := fmt.Sprintf("http://localhost:%d/api/v1/write", p.port)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestWriteV1(t *testing.T) {
+	p := newTestPlugin()
+	p.TenantHeader = "X-Scope-OrgID"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	resp := post(t, p, "application/x-protobuf", "snappy", snappy.Encode(nil, encodeV1Request()),
+		map[string]string{"X-Scope-OrgID": "team-a"})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"prometheus_remote_write",
+			map[string]string{"job": "api", "tenant": "team-a"},
+			map[string]interface{}{"http_requests_total": 42.0},
+			time.Unix(1, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"prometheus_remote_write",
+			map[string]string{"job": "api", "tenant": "team-a"},
+			map[string]interface{}{"http_requests_total": 43.0},
+			time.Unix(2, 0),
+			telegraf.Counter,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestWriteV2Zstd(t *testing.T) {
+	p := newTestPlugin()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	body := encoder.EncodeAll(encodeV2Request(), nil)
+
+	resp := post(t, p, "application/x-protobuf;proto=io.prometheus.write.v2.Request", "zstd", body, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "1", resp.Header.Get("X-Prometheus-Remote-Write-Samples-Written"))
+	require.Equal(t, "0", resp.Header.Get("X-Prometheus-Remote-Write-Histograms-Written"))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"prometheus_remote_write",
+			map[string]string{"room": "kitchen"},
+			map[string]interface{}{"temperature": 21.5},
+			time.Unix(3, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestWriteErrors(t *testing.T) {
+	p := newTestPlugin()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        []byte
+		status      int
+	}{
+		{
+			name:        "unknown proto",
+			contentType: "application/x-protobuf;proto=io.prometheus.write.v3.Request",
+			body:        snappy.Encode(nil, encodeV1Request()),
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "unknown encoding",
+			contentType: "application/x-protobuf",
+			encoding:    "gzip",
+			body:        encodeV1Request(),
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "truncated message",
+			contentType: "application/x-protobuf",
+			body:        snappy.Encode(nil, encodeV1Request()[:10]),
+			status:      http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := post(t, p, tt.contentType, tt.encoding, tt.body, nil)
+			require.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestDecodeV2LabelOutOfRange(t *testing.T) {
+	var refs []byte
+	refs = appendVarint(refs, 1)
+	refs = appendVarint(refs, 5)
+
+	var ts []byte
+	ts = appendBytes(ts, 1, refs)
+
+	var req []byte
+	req = appendBytes(req, 4, []byte(""))
+	req = appendBytes(req, 4, []byte("__name__"))
+	req = appendBytes(req, 5, ts)
+
+	_, err := decodeV2(req)
+	require.Error(t, err)
+}
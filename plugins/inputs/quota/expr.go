@@ -0,0 +1,449 @@
+package quota
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tidwall/gjson"
+)
+
+// env contains the response an expression is evaluated against.
+type env struct {
+	body    []byte
+	headers http.Header
+}
+
+// node is a node of a compiled expression, the values are float64, []float64
+// or string.
+type node interface {
+	eval(e *env) (interface{}, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(*env) (interface{}, error) {
+	return float64(n), nil
+}
+
+type stringNode string
+
+func (n stringNode) eval(*env) (interface{}, error) {
+	return string(n), nil
+}
+
+// pathNode is a GJSON path in the response body.
+type pathNode string
+
+func (n pathNode) eval(e *env) (interface{}, error) {
+	return jsonValue(e.body, string(n))
+}
+
+func jsonValue(body []byte, path string) (interface{}, error) {
+	result := gjson.GetBytes(body, path)
+	if !result.Exists() {
+		return nil, fmt.Errorf("path %q not found", path)
+	}
+	if result.IsArray() {
+		var values []float64
+		for _, r := range result.Array() {
+			v, err := resultNumber(r)
+			if err != nil {
+				return nil, fmt.Errorf("path %q: %v", path, err)
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+
+	v, err := resultNumber(result)
+	if err != nil {
+		return nil, fmt.Errorf("path %q: %v", path, err)
+	}
+	return v, nil
+}
+
+func resultNumber(r gjson.Result) (float64, error) {
+	switch r.Type {
+	case gjson.Number:
+		return r.Num, nil
+	case gjson.True:
+		return 1, nil
+	case gjson.False:
+		return 0, nil
+	case gjson.String:
+		return strconv.ParseFloat(strings.TrimSpace(r.Str), 64)
+	default:
+		return 0, fmt.Errorf("%q is not a number", r.Raw)
+	}
+}
+
+type unaryNode struct {
+	operand node
+}
+
+func (n *unaryNode) eval(e *env) (interface{}, error) {
+	v, err := evalNumber(n.operand, e)
+	if err != nil {
+		return nil, err
+	}
+	return -v, nil
+}
+
+type binaryNode struct {
+	op          byte
+	left, right node
+}
+
+func (n *binaryNode) eval(e *env) (interface{}, error) {
+	l, err := evalNumber(n.left, e)
+	if err != nil {
+		return nil, err
+	}
+	r, err := evalNumber(n.right, e)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	default:
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(l, r), nil
+	}
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+var functions = map[string]func(e *env, args []interface{}) (interface{}, error){
+	"json": func(e *env, args []interface{}) (interface{}, error) {
+		path, ok := args[0].(string)
+		if len(args) != 1 || !ok {
+			return nil, fmt.Errorf("json expects a path")
+		}
+		return jsonValue(e.body, path)
+	},
+	"header": func(e *env, args []interface{}) (interface{}, error) {
+		name, ok := args[0].(string)
+		if len(args) != 1 || !ok {
+			return nil, fmt.Errorf("header expects a header name")
+		}
+		value := e.headers.Get(name)
+		if value == "" {
+			return nil, fmt.Errorf("header %q not found", name)
+		}
+		return strconv.ParseFloat(strings.TrimSpace(value), 64)
+	},
+	"sum": func(e *env, args []interface{}) (interface{}, error) {
+		values, err := numbers(args)
+		if err != nil {
+			return nil, err
+		}
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum, nil
+	},
+	"count": func(e *env, args []interface{}) (interface{}, error) {
+		values, err := numbers(args)
+		if err != nil {
+			return nil, err
+		}
+		return float64(len(values)), nil
+	},
+	"min": func(e *env, args []interface{}) (interface{}, error) {
+		values, err := numbers(args)
+		if err != nil || len(values) == 0 {
+			return nil, fmt.Errorf("min expects at least one number")
+		}
+		min := values[0]
+		for _, v := range values[1:] {
+			min = math.Min(min, v)
+		}
+		return min, nil
+	},
+	"max": func(e *env, args []interface{}) (interface{}, error) {
+		values, err := numbers(args)
+		if err != nil || len(values) == 0 {
+			return nil, fmt.Errorf("max expects at least one number")
+		}
+		max := values[0]
+		for _, v := range values[1:] {
+			max = math.Max(max, v)
+		}
+		return max, nil
+	},
+}
+
+// numbers flattens the numbers and lists of the arguments.
+func numbers(args []interface{}) ([]float64, error) {
+	var values []float64
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case float64:
+			values = append(values, v)
+		case []float64:
+			values = append(values, v...)
+		default:
+			return nil, fmt.Errorf("%q is not a number", v)
+		}
+	}
+	return values, nil
+}
+
+func (n *callNode) eval(e *env) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s expects arguments", n.name)
+	}
+	return functions[n.name](e, args)
+}
+
+// evalNumber evaluates the node and requires the value to be a number.
+func evalNumber(n node, e *env) (float64, error) {
+	v, err := n.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case []float64:
+		return 0, fmt.Errorf("expected a number but got a list, use sum, count, min or max")
+	default:
+		return 0, fmt.Errorf("expected a number but got %q", v)
+	}
+}
+
+// Expression is a compiled arithmetic expression.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Eval returns the value of the expression for the response.
+func (x *Expression) Eval(body []byte, headers http.Header) (float64, error) {
+	v, err := evalNumber(x.root, &env{body: body, headers: headers})
+	if err != nil {
+		return 0, fmt.Errorf("evaluating %q failed: %v", x.source, err)
+	}
+	return v, nil
+}
+
+// Compile parses the expression.
+func Compile(source string) (*Expression, error) {
+	p := &parser{input: source}
+	p.next()
+	root, err := p.expr()
+	if err == nil && p.token.kind != tokenEOF {
+		err = fmt.Errorf("unexpected %q at position %d", p.token.text, p.token.pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compiling %q failed: %v", source, err)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+const (
+	tokenEOF = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+	tokenInvalid
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// parser is a recursive descent parser of expressions, the operators "*",
+// "/" and "%" take precedence over "+" and "-".  The operands are numbers,
+// quoted strings, paths, function calls, negations and parenthesized
+// expressions.
+type parser struct {
+	input string
+	pos   int
+	token token
+}
+
+func isPathChar(r byte) bool {
+	return r == '_' || r == '.' || r == '#' || r == '@' ||
+		unicode.IsLetter(rune(r)) || unicode.IsDigit(rune(r))
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.token = token{kind: tokenEOF, pos: start}
+		return
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c >= '0' && c <= '9':
+		for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		p.token = token{kind: tokenNumber, text: p.input[start:p.pos], pos: start}
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(p.input[p.pos+1:], c)
+		if end < 0 {
+			p.token = token{kind: tokenInvalid, text: p.input[start:], pos: start}
+			p.pos = len(p.input)
+			return
+		}
+		p.pos += end + 2
+		p.token = token{kind: tokenString, text: p.input[start+1 : p.pos-1], pos: start}
+	case isPathChar(c):
+		for p.pos < len(p.input) && isPathChar(p.input[p.pos]) {
+			p.pos++
+		}
+		p.token = token{kind: tokenIdent, text: p.input[start:p.pos], pos: start}
+	case strings.IndexByte("+-*/%(),", c) >= 0:
+		p.pos++
+		p.token = token{kind: tokenOperator, text: string(c), pos: start}
+	default:
+		p.pos++
+		p.token = token{kind: tokenInvalid, text: string(c), pos: start}
+	}
+}
+
+func (p *parser) isOperator(ops string) bool {
+	return p.token.kind == tokenOperator && strings.Contains(ops, p.token.text)
+}
+
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+-") {
+		op := p.token.text[0]
+		p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*/%") {
+		op := p.token.text[0]
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.isOperator("-") {
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.token
+	switch t.kind {
+	case tokenNumber:
+		p.next()
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return numberNode(v), nil
+	case tokenString:
+		p.next()
+		return stringNode(t.text), nil
+	case tokenIdent:
+		p.next()
+		if !p.isOperator("(") {
+			return pathNode(t.text), nil
+		}
+		if _, ok := functions[t.text]; !ok {
+			return nil, fmt.Errorf("unknown function %q at position %d", t.text, t.pos)
+		}
+		p.next()
+
+		call := &callNode{name: t.text}
+		for !p.isOperator(")") {
+			if len(call.args) > 0 {
+				if !p.isOperator(",") {
+					return nil, fmt.Errorf("expected \",\" at position %d", p.token.pos)
+				}
+				p.next()
+			}
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+		}
+		p.next()
+		return call, nil
+	case tokenOperator:
+		if t.text == "(" {
+			p.next()
+			n, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOperator(")") {
+				return nil, fmt.Errorf("expected \")\" at position %d", p.token.pos)
+			}
+			p.next()
+			return n, nil
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
@@ -0,0 +1,98 @@
+package quota
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testBody = `{
+  "resources": {
+    "core": {"limit": 5000, "remaining": 4000, "reset": 1577836800},
+    "search": {"limit": 30, "remaining": "10"}
+  },
+  "licenses": [
+    {"name": "a", "seats": 10, "used": 4},
+    {"name": "b", "seats": 20, "used": 6}
+  ],
+  "enabled": true
+}`
+
+func TestEval(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("X-RateLimit-Limit", "60")
+
+	tests := []struct {
+		expr     string
+		expected float64
+	}{
+		{"resources.core.limit", 5000},
+		{"resources.core.limit - resources.core.remaining", 1000},
+		{"resources.search.limit - resources.search.remaining", 20},
+		{"(resources.core.limit - resources.core.remaining) / resources.core.limit * 100", 20},
+		{"-resources.core.limit + 1", -4999},
+		{"7 % 4 + 2 * 3", 9},
+		{"sum(licenses.#.used)", 10},
+		{"sum(licenses.#.seats, 5)", 35},
+		{"count(licenses.#.seats)", 2},
+		{"min(licenses.#.seats)", 10},
+		{"max(licenses.#.used, 3)", 6},
+		{"licenses.#", 2},
+		{`json("resources.core.reset")`, 1577836800},
+		{`header('x-ratelimit-limit') - 1`, 59},
+		{"enabled", 1},
+		{"1.5", 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			x, err := Compile(tt.expr)
+			require.NoError(t, err)
+			v, err := x.Eval([]byte(testBody), headers)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	tests := []string{
+		"resources.missing",
+		"licenses.#.used",
+		"licenses.#.name",
+		"resources.core.limit / 0",
+		`header("X-Missing")`,
+		`sum("text")`,
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			x, err := Compile(expr)
+			require.NoError(t, err)
+			_, err = x.Eval([]byte(testBody), http.Header{})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"unknown(1)",
+		"sum(1 2)",
+		`"unterminated`,
+		"1 & 2",
+		"1..2",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Compile(expr)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Thresholds of the used percentage setting the status of the quotas.
+  # warning_percent = 80.0
+  # critical_percent = 95.0
+
+  ## Endpoints returning the usage and limits of quotas as JSON.
+  [[inputs.quota.endpoint]]
+    url = "https://api.github.com/rate_limit"
+
+    ## HTTP method and entity-body of the request.
+    # method = "GET"
+    # body = ""
+
+    ## Optional HTTP headers
+    # headers = {"Authorization" = "token secret"}
+
+    ## Optional HTTP Basic Auth Credentials
+    # username = "username"
+    # password = "pa$$word"
+
+    ## Amount of time allowed to complete the HTTP request
+    # timeout = "5s"
+
+    ## Optional TLS Config
+    # tls_ca = "/etc/telegraf/ca.pem"
+    # tls_cert = "/etc/telegraf/cert.pem"
+    # tls_key = "/etc/telegraf/key.pem"
+    ## Use TLS but skip chain & host verification
+    # insecure_skip_verify = false
+
+    ## Quotas evaluated against the response.  The expressions support
+    ## numbers, GJSON paths of the body, arithmetic operators and the
+    ## functions json("path"), header("name"), sum, count, min and max.
+    [[inputs.quota.endpoint.quota]]
+      name = "github_core"
+      used = "resources.core.limit - resources.core.remaining"
+      limit = "resources.core.limit"
+
+      ## Optional time the usage is reset, in seconds since the epoch.
+      # reset = "resources.core.reset"
+
+      ## Additional tags of the quota.
+      # [inputs.quota.endpoint.quota.tags]
+      #   service = "github"
+`
+
+// Quota is a quota evaluated against the response of an endpoint.
+type Quota struct {
+	Name  string            `toml:"name"`
+	Used  string            `toml:"used"`
+	Limit string            `toml:"limit"`
+	Reset string            `toml:"reset"`
+	Tags  map[string]string `toml:"tags"`
+
+	used  *Expression
+	limit *Expression
+	reset *Expression
+}
+
+// Endpoint is an HTTP endpoint returning JSON.
+type Endpoint struct {
+	URL      string            `toml:"url"`
+	Method   string            `toml:"method"`
+	Body     string            `toml:"body"`
+	Headers  map[string]string `toml:"headers"`
+	Username string            `toml:"username"`
+	Password string            `toml:"password"`
+	Timeout  internal.Duration `toml:"timeout"`
+	Quotas   []*Quota          `toml:"quota"`
+	tls.ClientConfig
+
+	client *http.Client
+}
+
+// QuotaTracker is an input plugin tracking the usage of quotas and licenses.
+type QuotaTracker struct {
+	WarningPercent  float64     `toml:"warning_percent"`
+	CriticalPercent float64     `toml:"critical_percent"`
+	Endpoints       []*Endpoint `toml:"endpoint"`
+}
+
+func (q *QuotaTracker) Description() string {
+	return "Track the usage of quotas and licenses reported by HTTP endpoints"
+}
+
+func (q *QuotaTracker) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *QuotaTracker) Init() error {
+	if q.WarningPercent > q.CriticalPercent {
+		return errors.New("warning_percent must not be greater than critical_percent")
+	}
+
+	for _, e := range q.Endpoints {
+		if err := e.init(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Endpoint) init() error {
+	if e.URL == "" {
+		return errors.New("url must be set")
+	}
+	if e.Method == "" {
+		e.Method = http.MethodGet
+	}
+	if e.Timeout.Duration == 0 {
+		e.Timeout.Duration = 5 * time.Second
+	}
+
+	tlsCfg, err := e.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	e.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: e.Timeout.Duration,
+	}
+
+	for _, quota := range e.Quotas {
+		if quota.Name == "" {
+			return fmt.Errorf("quota of %q has no name", e.URL)
+		}
+		if quota.Used == "" || quota.Limit == "" {
+			return fmt.Errorf("quota %q: used and limit must be set", quota.Name)
+		}
+
+		if quota.used, err = Compile(quota.Used); err != nil {
+			return fmt.Errorf("quota %q: %v", quota.Name, err)
+		}
+		if quota.limit, err = Compile(quota.Limit); err != nil {
+			return fmt.Errorf("quota %q: %v", quota.Name, err)
+		}
+		if quota.Reset != "" {
+			if quota.reset, err = Compile(quota.Reset); err != nil {
+				return fmt.Errorf("quota %q: %v", quota.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (q *QuotaTracker) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, e := range q.Endpoints {
+		wg.Add(1)
+		go func(e *Endpoint) {
+			defer wg.Done()
+			if err := q.gatherEndpoint(acc, e); err != nil {
+				acc.AddError(fmt.Errorf("[url=%s]: %v", e.URL, err))
+			}
+		}(e)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (q *QuotaTracker) gatherEndpoint(acc telegraf.Accumulator, e *Endpoint) error {
+	request, err := http.NewRequest(e.Method, e.URL, strings.NewReader(e.Body))
+	if err != nil {
+		return err
+	}
+	for k, v := range e.Headers {
+		if strings.ToLower(k) == "host" {
+			request.Host = v
+		} else {
+			request.Header.Add(k, v)
+		}
+	}
+	if e.Username != "" || e.Password != "" {
+		request.SetBasicAuth(e.Username, e.Password)
+	}
+
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d (%s)", response.StatusCode, http.StatusText(response.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	for _, quota := range e.Quotas {
+		fields, err := q.evaluate(quota, body, response.Header)
+		if err != nil {
+			acc.AddError(fmt.Errorf("[url=%s] quota %q: %v", e.URL, quota.Name, err))
+			continue
+		}
+
+		tags := map[string]string{"quota": quota.Name}
+		for k, v := range quota.Tags {
+			tags[k] = v
+		}
+		acc.AddGauge("quota", fields, tags)
+	}
+	return nil
+}
+
+// evaluate returns the fields of the quota.
+func (q *QuotaTracker) evaluate(quota *Quota, body []byte, headers http.Header) (map[string]interface{}, error) {
+	used, err := quota.used.Eval(body, headers)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := quota.limit.Eval(body, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{
+		"used":      used,
+		"limit":     limit,
+		"available": math.Max(limit-used, 0),
+	}
+
+	status := "ok"
+	if limit > 0 {
+		percent := used / limit * 100
+		fields["used_percent"] = percent
+		switch {
+		case percent >= q.CriticalPercent:
+			status = "critical"
+		case percent >= q.WarningPercent:
+			status = "warning"
+		}
+	} else if used > 0 {
+		// Any usage exceeds an empty quota
+		status = "critical"
+	}
+	fields["status"] = status
+	fields["status_code"] = statusCodes[status]
+
+	if quota.reset != nil {
+		reset, err := quota.reset.Eval(body, headers)
+		if err != nil {
+			return nil, err
+		}
+		fields["reset_time"] = int64(reset)
+	}
+	return fields, nil
+}
+
+var statusCodes = map[string]int64{
+	"ok":       0,
+	"warning":  1,
+	"critical": 2,
+}
+
+func init() {
+	inputs.Add("quota", func() telegraf.Input {
+		return &QuotaTracker{
+			WarningPercent:  80,
+			CriticalPercent: 95,
+		}
+	})
+}
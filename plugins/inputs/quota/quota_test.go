@@ -0,0 +1,168 @@
+package quota
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "token secret", r.Header.Get("Authorization"))
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Write([]byte(testBody))
+	}))
+	defer ts.Close()
+
+	plugin := &QuotaTracker{
+		WarningPercent:  80,
+		CriticalPercent: 95,
+		Endpoints: []*Endpoint{
+			{
+				URL:     ts.URL,
+				Headers: map[string]string{"Authorization": "token secret"},
+				Quotas: []*Quota{
+					{
+						Name:  "core",
+						Used:  "resources.core.limit - resources.core.remaining",
+						Limit: "resources.core.limit",
+						Reset: "resources.core.reset",
+						Tags:  map[string]string{"service": "github"},
+					},
+					{
+						Name:  "licenses",
+						Used:  "sum(licenses.#.used)",
+						Limit: "sum(licenses.#.seats) / 3",
+					},
+					{
+						Name:  "rate",
+						Used:  `header("X-RateLimit-Limit") - header("X-RateLimit-Remaining")`,
+						Limit: `header("X-RateLimit-Limit")`,
+					},
+					{
+						Name:  "invalid",
+						Used:  "resources.missing",
+						Limit: "1",
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"quota",
+			map[string]string{"quota": "core", "service": "github"},
+			map[string]interface{}{
+				"used":         1000.0,
+				"limit":        5000.0,
+				"available":    4000.0,
+				"used_percent": 20.0,
+				"status":       "ok",
+				"status_code":  int64(0),
+				"reset_time":   int64(1577836800),
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"quota",
+			map[string]string{"quota": "licenses"},
+			map[string]interface{}{
+				"used":         10.0,
+				"limit":        10.0,
+				"available":    0.0,
+				"used_percent": 100.0,
+				"status":       "critical",
+				"status_code":  int64(2),
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"quota",
+			map[string]string{"quota": "rate"},
+			map[string]interface{}{
+				"used":         60.0,
+				"limit":        60.0,
+				"available":    0.0,
+				"used_percent": 100.0,
+				"status":       "critical",
+				"status_code":  int64(2),
+			},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherStatus(t *testing.T) {
+	plugin := &QuotaTracker{WarningPercent: 80, CriticalPercent: 95}
+
+	tests := []struct {
+		used, limit string
+		status      string
+	}{
+		{"79", "100", "ok"},
+		{"80", "100", "warning"},
+		{"95", "100", "critical"},
+		{"0", "0", "ok"},
+		{"1", "0", "critical"},
+	}
+	for _, tt := range tests {
+		quota := &Quota{Name: "test", Used: tt.used, Limit: tt.limit}
+		e := &Endpoint{URL: "http://localhost", Quotas: []*Quota{quota}}
+		require.NoError(t, e.init())
+
+		fields, err := plugin.evaluate(quota, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, tt.status, fields["status"])
+	}
+}
+
+func TestGatherStatusCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	plugin := &QuotaTracker{
+		WarningPercent:  80,
+		CriticalPercent: 95,
+		Endpoints: []*Endpoint{
+			{URL: ts.URL, Quotas: []*Quota{{Name: "core", Used: "1", Limit: "2"}}},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestInit(t *testing.T) {
+	plugin := &QuotaTracker{WarningPercent: 90, CriticalPercent: 80}
+	require.Error(t, plugin.Init())
+
+	plugin = &QuotaTracker{
+		WarningPercent:  80,
+		CriticalPercent: 95,
+		Endpoints: []*Endpoint{
+			{URL: "http://localhost", Quotas: []*Quota{{Name: "core", Used: "1 +", Limit: "2"}}},
+		},
+	}
+	require.Error(t, plugin.Init())
+}
@@ -0,0 +1,249 @@
+package rapl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Zones to collect, such as "package", "core", "uncore", "dram" or
+  ## "psys", globs are supported.  All zones are collected by default.
+  # zones = []
+`
+
+var now = time.Now
+
+// reading is the last energy counter value of a zone.
+type reading struct {
+	energy uint64
+	total  float64
+	time   time.Time
+}
+
+// zone is a power domain with an energy counter in microjoules.
+type zone struct {
+	key      string
+	tags     map[string]string
+	energy   uint64
+	maxRange uint64
+}
+
+// RAPL gathers the energy consumption of the processors from the Intel
+// RAPL powercap interface and the AMD energy hwmon driver.
+type RAPL struct {
+	Zones []string `toml:"zones"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sysPath    string
+	zoneFilter filter.Filter
+	last       map[string]*reading
+	warned     bool
+}
+
+func (r *RAPL) Description() string {
+	return "Gather the energy consumption and power of the processors from Intel RAPL and AMD energy counters"
+}
+
+func (r *RAPL) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RAPL) Init() error {
+	var err error
+	r.zoneFilter, err = filter.Compile(r.Zones)
+	if err != nil {
+		return err
+	}
+	r.last = make(map[string]*reading)
+	return nil
+}
+
+func (r *RAPL) Gather(acc telegraf.Accumulator) error {
+	zones, err := r.raplZones()
+	if err != nil {
+		return err
+	}
+	amdZones, err := r.amdZones()
+	if err != nil {
+		return err
+	}
+	zones = append(zones, amdZones...)
+
+	t := now()
+	for _, z := range zones {
+		if r.zoneFilter != nil && !r.zoneFilter.Match(z.tags["zone"]) {
+			continue
+		}
+
+		last, ok := r.last[z.key]
+		if !ok {
+			last = &reading{energy: z.energy, total: float64(z.energy) / 1e6, time: t}
+			r.last[z.key] = last
+			acc.AddCounter("rapl", map[string]interface{}{"energy_joules": last.total}, z.tags, t)
+			continue
+		}
+
+		// The counters wrap around at their maximum range
+		delta := z.energy - last.energy
+		if z.energy < last.energy {
+			if z.maxRange == 0 {
+				delta = 0
+			} else {
+				delta = z.maxRange - last.energy + z.energy
+			}
+		}
+		joules := float64(delta) / 1e6
+
+		fields := map[string]interface{}{
+			"energy_joules": last.total + joules,
+		}
+		if elapsed := t.Sub(last.time).Seconds(); elapsed > 0 {
+			fields["power_watts"] = joules / elapsed
+		}
+		acc.AddCounter("rapl", fields, z.tags, t)
+
+		last.energy = z.energy
+		last.total += joules
+		last.time = t
+	}
+	return nil
+}
+
+// raplZones returns the zones of the intel-rapl powercap control type, the
+// packages are top level zones with the core, uncore and dram subzones.
+func (r *RAPL) raplZones() ([]*zone, error) {
+	dirs, err := filepath.Glob(filepath.Join(r.sysPath, "class", "powercap", "intel-rapl:*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []*zone
+	for _, dir := range dirs {
+		name, err := readString(filepath.Join(dir, "name"))
+		if err != nil {
+			continue
+		}
+		energy, err := readUint(filepath.Join(dir, "energy_uj"))
+		if err != nil {
+			if os.IsPermission(err) && !r.warned {
+				r.Log.Warnf("Reading %s requires root privileges", dir)
+				r.warned = true
+			}
+			continue
+		}
+		maxRange, _ := readUint(filepath.Join(dir, "max_energy_range_uj"))
+
+		tags := map[string]string{"source": "intel-rapl"}
+		id := strings.Split(strings.TrimPrefix(filepath.Base(dir), "intel-rapl:"), ":")
+		parent := name
+		if len(id) > 1 {
+			// Subzones belong to the package of their parent zone
+			parent, _ = readString(filepath.Join(filepath.Dir(dir), "intel-rapl:"+id[0], "name"))
+		}
+		if strings.HasPrefix(parent, "package-") {
+			tags["package"] = strings.TrimPrefix(parent, "package-")
+		}
+		if strings.HasPrefix(name, "package-") {
+			name = "package"
+		}
+		tags["zone"] = name
+
+		zones = append(zones, &zone{
+			key:      dir,
+			tags:     tags,
+			energy:   energy,
+			maxRange: maxRange,
+		})
+	}
+	return zones, nil
+}
+
+// amdZones returns the zones of the amd_energy hwmon driver, the labels are
+// "Esocket<n>" for the packages and "Ecore<n>" for the cores.
+func (r *RAPL) amdZones() ([]*zone, error) {
+	dirs, err := filepath.Glob(filepath.Join(r.sysPath, "class", "hwmon", "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []*zone
+	for _, dir := range dirs {
+		if name, _ := readString(filepath.Join(dir, "name")); name != "amd_energy" {
+			continue
+		}
+
+		labels, err := filepath.Glob(filepath.Join(dir, "energy*_label"))
+		if err != nil {
+			return nil, err
+		}
+		for _, labelPath := range labels {
+			label, err := readString(labelPath)
+			if err != nil {
+				continue
+			}
+			inputPath := strings.TrimSuffix(labelPath, "_label") + "_input"
+			energy, err := readUint(inputPath)
+			if err != nil {
+				continue
+			}
+
+			tags := map[string]string{"source": "amd_energy"}
+			switch {
+			case strings.HasPrefix(label, "Esocket"):
+				tags["zone"] = "package"
+				tags["package"] = trimNumber(strings.TrimPrefix(label, "Esocket"))
+			case strings.HasPrefix(label, "Ecore"):
+				tags["zone"] = "core"
+				tags["core"] = trimNumber(strings.TrimPrefix(label, "Ecore"))
+			default:
+				tags["zone"] = label
+			}
+
+			zones = append(zones, &zone{key: inputPath, tags: tags, energy: energy})
+		}
+	}
+	return zones, nil
+}
+
+// trimNumber removes the leading zeros of the number.
+func trimNumber(s string) string {
+	if n, err := strconv.Atoi(s); err == nil {
+		return strconv.Itoa(n)
+	}
+	return s
+}
+
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("rapl", func() telegraf.Input {
+		sysPath := "/sys"
+		if hostSys := os.Getenv("HOST_SYS"); hostSys != "" {
+			sysPath = hostSys
+		}
+		return &RAPL{sysPath: sysPath}
+	})
+}
@@ -0,0 +1,99 @@
+package rapl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, root, path, content string) {
+	path = filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content+"\n"), 0644))
+}
+
+func raplMetric(tags map[string]string, fields map[string]interface{}, tm time.Time) telegraf.Metric {
+	return testutil.MustMetric("rapl", tags, fields, tm, telegraf.Counter)
+}
+
+func TestGatherIntel(t *testing.T) {
+	root, err := ioutil.TempDir("", "rapl")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	write(t, root, "class/powercap/intel-rapl:0/name", "package-0")
+	write(t, root, "class/powercap/intel-rapl:0/energy_uj", "1000000")
+	write(t, root, "class/powercap/intel-rapl:0/max_energy_range_uj", "262143328850")
+	write(t, root, "class/powercap/intel-rapl:0:0/name", "core")
+	write(t, root, "class/powercap/intel-rapl:0:0/energy_uj", "262143000000")
+	write(t, root, "class/powercap/intel-rapl:0:0/max_energy_range_uj", "262143328850")
+	write(t, root, "class/powercap/intel-rapl:1/name", "psys")
+	write(t, root, "class/powercap/intel-rapl:1/energy_uj", "5000000")
+
+	start := time.Unix(100, 0)
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	plugin := &RAPL{sysPath: root, Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	// The core counter wraps around
+	write(t, root, "class/powercap/intel-rapl:0/energy_uj", "21000000")
+	write(t, root, "class/powercap/intel-rapl:0:0/energy_uj", "9671150")
+	write(t, root, "class/powercap/intel-rapl:1/energy_uj", "15000000")
+	now = func() time.Time { return start.Add(10 * time.Second) }
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+
+	packageTags := map[string]string{"source": "intel-rapl", "zone": "package", "package": "0"}
+	coreTags := map[string]string{"source": "intel-rapl", "zone": "core", "package": "0"}
+	psysTags := map[string]string{"source": "intel-rapl", "zone": "psys"}
+	expected := []telegraf.Metric{
+		raplMetric(packageTags, map[string]interface{}{"energy_joules": 21.0, "power_watts": 2.0}, start.Add(10*time.Second)),
+		raplMetric(coreTags, map[string]interface{}{"energy_joules": 262153.0, "power_watts": 1.0}, start.Add(10*time.Second)),
+		raplMetric(psysTags, map[string]interface{}{"energy_joules": 15.0, "power_watts": 1.0}, start.Add(10*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestGatherAMD(t *testing.T) {
+	root, err := ioutil.TempDir("", "rapl")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	write(t, root, "class/hwmon/hwmon0/name", "k10temp")
+	write(t, root, "class/hwmon/hwmon3/name", "amd_energy")
+	write(t, root, "class/hwmon/hwmon3/energy1_label", "Ecore000")
+	write(t, root, "class/hwmon/hwmon3/energy1_input", "2000000")
+	write(t, root, "class/hwmon/hwmon3/energy65_label", "Esocket0")
+	write(t, root, "class/hwmon/hwmon3/energy65_input", "30000000")
+
+	start := time.Unix(100, 0)
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	plugin := &RAPL{sysPath: root, Zones: []string{"package"}, Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		raplMetric(
+			map[string]string{"source": "amd_energy", "zone": "package", "package": "0"},
+			map[string]interface{}{"energy_joules": 30.0},
+			start,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
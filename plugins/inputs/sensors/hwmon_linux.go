@@ -0,0 +1,233 @@
+// +build linux
+
+package sensors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// hwmonAttrRegex matches the attribute files of the hwmon sysfs interface,
+// such as "temp1_input".
+var hwmonAttrRegex = regexp.MustCompile(`^([a-z]+)([0-9]+)_([a-z_]+)$`)
+
+// hwmonScale is the divisor of the values of each sensor type, the sysfs
+// interface uses milli and micro units.
+var hwmonScale = map[string]float64{
+	"temp":     1000,
+	"in":       1000,
+	"curr":     1000,
+	"humidity": 1000,
+	"power":    1000000,
+	"energy":   1000000,
+}
+
+// hwmonFeature is a sensor of a chip, such as "temp1".
+type hwmonFeature struct {
+	name   string
+	typ    string
+	index  int
+	label  string
+	values map[string]float64
+}
+
+// gatherHwmon reads the sensors of the chips of the hwmon sysfs interface,
+// the metrics match those of the sensors command without a configuration.
+func (s *Sensors) gatherHwmon(acc telegraf.Accumulator) error {
+	dirs, err := filepath.Glob(filepath.Join(s.sysPath, "class", "hwmon", "hwmon*"))
+	if err != nil {
+		return err
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return hwmonIndex(dirs[i]) < hwmonIndex(dirs[j])
+	})
+
+	for _, dir := range dirs {
+		chip, features, err := readHwmonChip(dir)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		if chip == "" || !s.matchChip(chip) {
+			continue
+		}
+
+		for _, f := range features {
+			feature := f.name
+			if f.label != "" {
+				feature = snake(f.label)
+			}
+			tags := map[string]string{
+				"chip":    chip,
+				"feature": feature,
+			}
+
+			fields := make(map[string]interface{}, len(f.values))
+			for attr, value := range f.values {
+				fieldName := f.name + "_" + attr
+				if s.RemoveNumbers {
+					fieldName = numberRegp.ReplaceAllString(fieldName, "")
+				}
+				fields[fieldName] = value
+			}
+			if len(fields) > 0 {
+				acc.AddFields("sensors", fields, tags)
+			}
+		}
+	}
+	return nil
+}
+
+func hwmonIndex(dir string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "hwmon"))
+	return n
+}
+
+// readHwmonChip returns the chip name and the sensors of the hwmon device.
+func readHwmonChip(dir string) (string, []*hwmonFeature, error) {
+	// Older drivers have the attributes in the device directory
+	attrDir := dir
+	name, err := readHwmonString(filepath.Join(dir, "name"))
+	if err != nil {
+		attrDir = filepath.Join(dir, "device")
+		name, err = readHwmonString(filepath.Join(attrDir, "name"))
+		if err != nil {
+			return "", nil, nil
+		}
+	}
+
+	files, err := ioutil.ReadDir(attrDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	features := make(map[string]*hwmonFeature)
+	for _, file := range files {
+		match := hwmonAttrRegex.FindStringSubmatch(file.Name())
+		if match == nil || file.IsDir() {
+			continue
+		}
+		typ, index, attr := match[1], match[2], match[3]
+
+		key := typ + index
+		f, ok := features[key]
+		if !ok {
+			i, _ := strconv.Atoi(index)
+			f = &hwmonFeature{name: key, typ: typ, index: i, values: make(map[string]float64)}
+			features[key] = f
+		}
+
+		path := filepath.Join(attrDir, file.Name())
+		if attr == "label" {
+			f.label, _ = readHwmonString(path)
+			continue
+		}
+
+		// Write only and failing attributes are skipped
+		raw, err := readHwmonString(path)
+		if err != nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			continue
+		}
+		if scale, ok := hwmonScale[typ]; ok && scaled(attr) {
+			value /= scale
+		}
+		f.values[attr] = value
+	}
+
+	sorted := make([]*hwmonFeature, 0, len(features))
+	for _, f := range features {
+		sorted = append(sorted, f)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].typ != sorted[j].typ {
+			return sorted[i].typ < sorted[j].typ
+		}
+		return sorted[i].index < sorted[j].index
+	})
+
+	return hwmonChipName(dir, name), sorted, nil
+}
+
+// scaled returns false for the attributes that are flags or enumerations
+// rather than measurements.
+func scaled(attr string) bool {
+	if strings.HasSuffix(attr, "alarm") {
+		return false
+	}
+	switch attr {
+	case "beep", "type", "enable", "fault", "mode":
+		return false
+	}
+	return true
+}
+
+// hwmonChipName returns the name of the chip as shown by lm-sensors, made of
+// the driver name, the bus and the address of the device.
+func hwmonChipName(dir, name string) string {
+	device, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+	if err != nil {
+		return name + "-virtual-0"
+	}
+	subsystem, err := os.Readlink(filepath.Join(device, "subsystem"))
+	if err != nil {
+		return name + "-virtual-0"
+	}
+	id := filepath.Base(device)
+
+	switch filepath.Base(subsystem) {
+	case "pci":
+		// 0000:00:18.3
+		var domain, bus, slot, fn int
+		if _, err := fmt.Sscanf(id, "%x:%x:%x.%x", &domain, &bus, &slot, &fn); err == nil {
+			return fmt.Sprintf("%s-pci-%04x", name, (domain<<16)+(bus<<8)+(slot<<3)+fn)
+		}
+	case "i2c":
+		// 1-002d
+		var bus, addr int
+		if _, err := fmt.Sscanf(id, "%d-%x", &bus, &addr); err == nil {
+			return fmt.Sprintf("%s-i2c-%d-%02x", name, bus, addr)
+		}
+	case "spi":
+		// spi0.1
+		var bus, addr int
+		if _, err := fmt.Sscanf(id, "spi%d.%d", &bus, &addr); err == nil {
+			return fmt.Sprintf("%s-spi-%d-%x", name, bus, addr)
+		}
+	case "platform", "of_platform":
+		// coretemp.0 or it87.656
+		addr := 0
+		if i := strings.LastIndexByte(id, '.'); i >= 0 {
+			addr, _ = strconv.Atoi(id[i+1:])
+		}
+		return fmt.Sprintf("%s-isa-%04x", name, addr)
+	case "acpi":
+		return name + "-acpi-0"
+	case "hid":
+		// 0003:046D:C52B.0001
+		var bus, vendor, product, addr int
+		if _, err := fmt.Sscanf(id, "%x:%x:%x.%x", &bus, &vendor, &product, &addr); err == nil {
+			return fmt.Sprintf("%s-hid-%d-%x", name, bus, addr)
+		}
+	}
+	return name + "-virtual-0"
+}
+
+func readHwmonString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,130 @@
+// +build linux
+
+package sensors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// sysfs creates a hwmon sysfs tree with a coretemp, a k10temp and a virtual
+// acpitz chip.
+func sysfs(t *testing.T) string {
+	root, err := ioutil.TempDir("", "sensors")
+	require.NoError(t, err)
+
+	write := func(path, content string) {
+		path = filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content+"\n"), 0644))
+	}
+	link := func(target, path string) {
+		path = filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.Symlink(filepath.Join(root, target), path))
+	}
+
+	write("devices/platform/coretemp.0/hwmon/hwmon1/name", "coretemp")
+	write("devices/platform/coretemp.0/hwmon/hwmon1/temp1_label", "Physical id 0")
+	write("devices/platform/coretemp.0/hwmon/hwmon1/temp1_input", "77000")
+	write("devices/platform/coretemp.0/hwmon/hwmon1/temp1_max", "82000")
+	write("devices/platform/coretemp.0/hwmon/hwmon1/temp1_crit", "92000")
+	write("devices/platform/coretemp.0/hwmon/hwmon1/temp1_crit_alarm", "0")
+	write("devices/platform/coretemp.0/hwmon/hwmon1/temp2_label", "Core 0")
+	write("devices/platform/coretemp.0/hwmon/hwmon1/temp2_input", "75000")
+	link("bus/platform", "devices/platform/coretemp.0/subsystem")
+	link("devices/platform/coretemp.0", "devices/platform/coretemp.0/hwmon/hwmon1/device")
+	link("devices/platform/coretemp.0/hwmon/hwmon1", "class/hwmon/hwmon1")
+
+	write("devices/pci0000:00/0000:00:18.3/hwmon/hwmon2/name", "k10temp")
+	write("devices/pci0000:00/0000:00:18.3/hwmon/hwmon2/temp1_input", "29125")
+	write("devices/pci0000:00/0000:00:18.3/hwmon/hwmon2/in0_input", "1250")
+	write("devices/pci0000:00/0000:00:18.3/hwmon/hwmon2/fan1_input", "2029")
+	link("bus/pci", "devices/pci0000:00/0000:00:18.3/subsystem")
+	link("devices/pci0000:00/0000:00:18.3", "devices/pci0000:00/0000:00:18.3/hwmon/hwmon2/device")
+	link("devices/pci0000:00/0000:00:18.3/hwmon/hwmon2", "class/hwmon/hwmon2")
+
+	write("devices/virtual/thermal/thermal_zone0/hwmon0/name", "acpitz")
+	write("devices/virtual/thermal/thermal_zone0/hwmon0/temp1_input", "8300")
+	write("devices/virtual/thermal/thermal_zone0/hwmon0/temp1_crit", "31300")
+	link("devices/virtual/thermal/thermal_zone0/hwmon0", "class/hwmon/hwmon0")
+
+	return root
+}
+
+func sensorsMetric(chip, feature string, fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric(
+		"sensors",
+		map[string]string{"chip": chip, "feature": feature},
+		fields,
+		time.Unix(0, 0),
+	)
+}
+
+func TestGatherHwmon(t *testing.T) {
+	root := sysfs(t)
+	defer os.RemoveAll(root)
+
+	s := &Sensors{RemoveNumbers: true, sysPath: root}
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		sensorsMetric("acpitz-virtual-0", "temp1", map[string]interface{}{
+			"temp_input": 8.3,
+			"temp_crit":  31.3,
+		}),
+		sensorsMetric("coretemp-isa-0000", "physical_id_0", map[string]interface{}{
+			"temp_input":      77.0,
+			"temp_max":        82.0,
+			"temp_crit":       92.0,
+			"temp_crit_alarm": 0.0,
+		}),
+		sensorsMetric("coretemp-isa-0000", "core_0", map[string]interface{}{
+			"temp_input": 75.0,
+		}),
+		sensorsMetric("k10temp-pci-00c3", "fan1", map[string]interface{}{
+			"fan_input": 2029.0,
+		}),
+		sensorsMetric("k10temp-pci-00c3", "in0", map[string]interface{}{
+			"in_input": 1.25,
+		}),
+		sensorsMetric("k10temp-pci-00c3", "temp1", map[string]interface{}{
+			"temp_input": 29.125,
+		}),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherHwmonFilter(t *testing.T) {
+	root := sysfs(t)
+	defer os.RemoveAll(root)
+
+	s := &Sensors{Chips: []string{"k10temp-*"}, sysPath: root}
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		sensorsMetric("k10temp-pci-00c3", "fan1", map[string]interface{}{
+			"fan1_input": 2029.0,
+		}),
+		sensorsMetric("k10temp-pci-00c3", "in0", map[string]interface{}{
+			"in0_input": 1.25,
+		}),
+		sensorsMetric("k10temp-pci-00c3", "temp1", map[string]interface{}{
+			"temp1_input": 29.125,
+		}),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
@@ -1,9 +1,8 @@
-// +build linux
+// +build linux freebsd openbsd
 
 package sensors
 
 import (
-	"errors"
 	"fmt"
 	"os/exec"
 	"regexp"
@@ -12,8 +11,8 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 var (
@@ -25,11 +24,15 @@ var (
 type Sensors struct {
 	RemoveNumbers bool              `toml:"remove_numbers"`
 	Timeout       internal.Duration `toml:"timeout"`
+	Chips         []string          `toml:"chips"`
+	UseLmSensors  bool              `toml:"use_lm_sensors"`
 	path          string
+	sysPath       string
+	chipFilter    filter.Filter
 }
 
 func (*Sensors) Description() string {
-	return "Monitor sensors, requires lm-sensors package"
+	return "Monitor sensors using hwmon on Linux and sysctl on the BSDs"
 }
 
 func (*Sensors) SampleConfig() string {
@@ -38,87 +41,109 @@ func (*Sensors) SampleConfig() string {
   ## If true, a field name like 'temp1_input' will be changed to 'temp_input'.
   # remove_numbers = true
 
+  ## Chips to collect, such as "coretemp-isa-0000", globs are supported.
+  ## All chips are collected by default.
+  # chips = []
+
+  ## On Linux the sensors are read from the hwmon sysfs interface.  Set to
+  ## true to run the sensors command of the lm-sensors package instead,
+  ## which applies the labels and computations of its configuration.
+  # use_lm_sensors = false
+
   ## Timeout is the maximum amount of time that the sensors command can run.
   # timeout = "5s"
 `
 
 }
 
-func (s *Sensors) Gather(acc telegraf.Accumulator) error {
-	if len(s.path) == 0 {
-		return errors.New("sensors not found: verify that lm-sensors package is installed and that sensors is in your PATH")
-	}
+func (s *Sensors) Init() error {
+	var err error
+	s.chipFilter, err = filter.Compile(s.Chips)
+	return err
+}
 
-	return s.parse(acc)
+// matchChip returns true if the chip is collected.
+func (s *Sensors) matchChip(chip string) bool {
+	return s.chipFilter == nil || s.chipFilter.Match(chip)
 }
 
-// parse forks the command:
-//     sensors -u -A
-// and parses the output to add it to the telegraf.Accumulator.
-func (s *Sensors) parse(acc telegraf.Accumulator) error {
-	tags := map[string]string{}
-	fields := map[string]interface{}{}
-	chip := ""
-	cmd := execCommand(s.path, "-A", "-u")
-	out, err := internal.CombinedOutputTimeout(cmd, s.Timeout.Duration)
-	if err != nil {
-		return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
-	}
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	for _, line := range lines {
-		if len(line) == 0 {
-			acc.AddFields("sensors", fields, tags)
-			chip = ""
-			tags = map[string]string{}
-			fields = map[string]interface{}{}
-			continue
-		}
-		if len(chip) == 0 {
-			chip = line
-			tags["chip"] = chip
+// snake converts string to snake case
+func snake(input string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(input), " ", "_", -1))
+}
+
+
+// parseSysctl parses the sensors in the output of sysctl on the BSDs:
+// the hw.sensors tree of OpenBSD, such as
+// "hw.sensors.cpu0.temp0=45.00 degC", and the temperatures of FreeBSD, such
+// as "dev.cpu.0.temperature=45.0C".
+func (s *Sensors) parseSysctl(acc telegraf.Accumulator, out []byte) error {
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) != 2 {
 			continue
 		}
-		if !strings.HasPrefix(line, "  ") {
-			if len(tags) > 1 {
-				acc.AddFields("sensors", fields, tags)
+		key, value := parts[0], strings.TrimSpace(parts[1])
+
+		var chip, feature, fieldName string
+		switch {
+		case strings.HasPrefix(key, "hw.sensors."):
+			path := strings.Split(strings.TrimPrefix(key, "hw.sensors."), ".")
+			if len(path) != 2 {
+				continue
 			}
-			fields = map[string]interface{}{}
-			tags = map[string]string{
-				"chip":    chip,
-				"feature": strings.TrimRight(snake(line), ":"),
+			chip, feature = path[0], path[1]
+			fieldName = feature + "_input"
+
+			// Values have a unit and an optional description, the status
+			// of sensors such as drives is not numeric.
+			if i := strings.IndexByte(value, ' '); i >= 0 {
+				value = value[:i]
 			}
-		} else {
-			splitted := strings.Split(line, ":")
-			fieldName := strings.TrimSpace(splitted[0])
-			if s.RemoveNumbers {
-				fieldName = numberRegp.ReplaceAllString(fieldName, "")
+		case strings.HasSuffix(key, ".temperature"):
+			// dev.cpu.0.temperature or hw.acpi.thermal.tz0.temperature
+			path := strings.Split(strings.TrimSuffix(key, ".temperature"), ".")
+			if len(path) < 2 {
+				continue
 			}
-			fieldValue, err := strconv.ParseFloat(strings.TrimSpace(splitted[1]), 64)
-			if err != nil {
-				return err
+			chip = path[len(path)-1]
+			if _, err := strconv.Atoi(chip); err == nil {
+				chip = path[len(path)-2] + chip
 			}
-			fields[fieldName] = fieldValue
+			feature = "temperature"
+			fieldName = "temp_input"
+			value = strings.TrimSuffix(value, "C")
+		default:
+			continue
 		}
+
+		if !s.matchChip(chip) {
+			continue
+		}
+
+		fieldValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		if s.RemoveNumbers {
+			fieldName = numberRegp.ReplaceAllString(fieldName, "")
+		}
+
+		tags := map[string]string{
+			"chip":    chip,
+			"feature": feature,
+		}
+		acc.AddFields("sensors", map[string]interface{}{fieldName: fieldValue}, tags)
 	}
-	acc.AddFields("sensors", fields, tags)
 	return nil
 }
 
-// snake converts string to snake case
-func snake(input string) string {
-	return strings.ToLower(strings.Replace(strings.TrimSpace(input), " ", "_", -1))
-}
-
-func init() {
-	s := Sensors{
-		RemoveNumbers: true,
-		Timeout:       defaultTimeout,
-	}
-	path, _ := exec.LookPath("sensors")
-	if len(path) > 0 {
-		s.path = path
+// sysctl runs sysctl with the names and returns its output.
+func (s *Sensors) sysctl(names ...string) ([]byte, error) {
+	cmd := execCommand(s.path, names...)
+	out, err := internal.CombinedOutputTimeout(cmd, s.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
 	}
-	inputs.Add("sensors", func() telegraf.Input {
-		return &s
-	})
+	return out, nil
 }
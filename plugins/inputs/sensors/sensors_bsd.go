@@ -0,0 +1,46 @@
+// +build freebsd openbsd
+
+package sensors
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (s *Sensors) Gather(acc telegraf.Accumulator) error {
+	if len(s.path) == 0 {
+		return errors.New("sysctl not found in your PATH")
+	}
+
+	// On FreeBSD the temperatures require the coretemp or amdtemp and acpi
+	// thermal drivers, -i ignores the missing names and -e separates the names
+	// and values with "=".
+	names := []string{"-e", "-i", "dev.cpu", "hw.acpi.thermal"}
+	if runtime.GOOS == "openbsd" {
+		names = []string{"hw.sensors"}
+	}
+
+	out, err := s.sysctl(names...)
+	if err != nil {
+		return err
+	}
+	return s.parseSysctl(acc, out)
+}
+
+func init() {
+	inputs.Add("sensors", func() telegraf.Input {
+		s := &Sensors{
+			RemoveNumbers: true,
+			Timeout:       defaultTimeout,
+		}
+		path, _ := exec.LookPath("sysctl")
+		if len(path) > 0 {
+			s.path = path
+		}
+		return s
+	})
+}
@@ -0,0 +1,104 @@
+// +build linux
+
+package sensors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (s *Sensors) Gather(acc telegraf.Accumulator) error {
+	if !s.UseLmSensors {
+		return s.gatherHwmon(acc)
+	}
+
+	if len(s.path) == 0 {
+		return errors.New("sensors not found: verify that lm-sensors package is installed and that sensors is in your PATH")
+	}
+
+	return s.parse(acc)
+}
+
+// parse forks the command:
+//     sensors -u -A
+// and parses the output to add it to the telegraf.Accumulator.
+func (s *Sensors) parse(acc telegraf.Accumulator) error {
+	tags := map[string]string{}
+	fields := map[string]interface{}{}
+	chip := ""
+	cmd := execCommand(s.path, "-A", "-u")
+	out, err := internal.CombinedOutputTimeout(cmd, s.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	for _, line := range lines {
+		if len(line) == 0 {
+			s.add(acc, fields, tags)
+			chip = ""
+			tags = map[string]string{}
+			fields = map[string]interface{}{}
+			continue
+		}
+		if len(chip) == 0 {
+			chip = line
+			tags["chip"] = chip
+			continue
+		}
+		if !strings.HasPrefix(line, "  ") {
+			if len(tags) > 1 {
+				s.add(acc, fields, tags)
+			}
+			fields = map[string]interface{}{}
+			tags = map[string]string{
+				"chip":    chip,
+				"feature": strings.TrimRight(snake(line), ":"),
+			}
+		} else {
+			splitted := strings.Split(line, ":")
+			fieldName := strings.TrimSpace(splitted[0])
+			if s.RemoveNumbers {
+				fieldName = numberRegp.ReplaceAllString(fieldName, "")
+			}
+			fieldValue, err := strconv.ParseFloat(strings.TrimSpace(splitted[1]), 64)
+			if err != nil {
+				return err
+			}
+			fields[fieldName] = fieldValue
+		}
+	}
+	s.add(acc, fields, tags)
+	return nil
+}
+
+func (s *Sensors) add(acc telegraf.Accumulator, fields map[string]interface{}, tags map[string]string) {
+	if s.matchChip(tags["chip"]) {
+		acc.AddFields("sensors", fields, tags)
+	}
+}
+
+func init() {
+	inputs.Add("sensors", func() telegraf.Input {
+		s := &Sensors{
+			RemoveNumbers: true,
+			Timeout:       defaultTimeout,
+			sysPath:       "/sys",
+		}
+		if hostSys := os.Getenv("HOST_SYS"); hostSys != "" {
+			s.sysPath = hostSys
+		}
+		path, _ := exec.LookPath("sensors")
+		if len(path) > 0 {
+			s.path = path
+		}
+		return s
+	})
+}
@@ -1,3 +0,0 @@
-// +build !linux
-
-package sensors
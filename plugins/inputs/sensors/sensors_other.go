@@ -0,0 +1,3 @@
+// +build !linux,!freebsd,!openbsd
+
+package sensors
@@ -0,0 +1,66 @@
+// +build linux freebsd openbsd
+
+package sensors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func sensorMetric(chip, feature, field string, value float64) telegraf.Metric {
+	return testutil.MustMetric(
+		"sensors",
+		map[string]string{"chip": chip, "feature": feature},
+		map[string]interface{}{field: value},
+		time.Unix(0, 0),
+	)
+}
+
+func TestParseSysctlOpenBSD(t *testing.T) {
+	out := `hw.sensors.cpu0.temp0=45.00 degC
+hw.sensors.acpitz0.temp0=27.80 degC (zone temperature)
+hw.sensors.it0.fan1=2029 RPM
+hw.sensors.it0.volt0=1.25 VDC (VCORE_A)
+hw.sensors.acpiac0.indicator0=On (power supply)
+hw.sensors.softraid0.drive0=online (sd1), OK
+`
+	s := &Sensors{RemoveNumbers: true}
+	var acc testutil.Accumulator
+	require.NoError(t, s.parseSysctl(&acc, []byte(out)))
+
+	expected := []telegraf.Metric{
+		sensorMetric("cpu0", "temp0", "temp_input", 45),
+		sensorMetric("acpitz0", "temp0", "temp_input", 27.8),
+		sensorMetric("it0", "fan1", "fan_input", 2029),
+		sensorMetric("it0", "volt0", "volt_input", 1.25),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	s.RemoveNumbers = false
+	acc.ClearMetrics()
+	require.NoError(t, s.parseSysctl(&acc, []byte(out)))
+	require.Equal(t, sensorMetric("it0", "fan1", "fan1_input", 2029).Fields(), acc.GetTelegrafMetrics()[2].Fields())
+}
+
+func TestParseSysctlFreeBSD(t *testing.T) {
+	out := `dev.cpu.0.%desc=ACPI CPU
+dev.cpu.0.temperature=45.0C
+dev.cpu.1.temperature=47.5C
+hw.acpi.thermal.tz0.temperature=29.9C
+hw.acpi.thermal.tz0.active=-1
+`
+	s := &Sensors{RemoveNumbers: true}
+	var acc testutil.Accumulator
+	require.NoError(t, s.parseSysctl(&acc, []byte(out)))
+
+	expected := []telegraf.Metric{
+		sensorMetric("cpu0", "temperature", "temp_input", 45),
+		sensorMetric("cpu1", "temperature", "temp_input", 47.5),
+		sensorMetric("tz0", "temperature", "temp_input", 29.9),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
@@ -15,6 +15,7 @@ func TestGatherDefault(t *testing.T) {
 	s := Sensors{
 		RemoveNumbers: true,
 		Timeout:       defaultTimeout,
+		UseLmSensors:  true,
 		path:          "sensors",
 	}
 	// overwriting exec commands with mock commands
@@ -156,6 +157,7 @@ func TestGatherNotRemoveNumbers(t *testing.T) {
 	s := Sensors{
 		RemoveNumbers: false,
 		Timeout:       defaultTimeout,
+		UseLmSensors:  true,
 		path:          "sensors",
 	}
 	// overwriting exec commands with mock commands
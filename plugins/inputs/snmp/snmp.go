@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -43,6 +44,16 @@ const sampleConfig = `
   ## The GETBULK max-repetitions parameter.
   # max_repetitions = 10
 
+  ## Number of agents gathered concurrently, 0 to gather all agents at once.
+  # workers = 0
+
+  ## Maximum number of connections to each agent, the tables of an agent are
+  ## walked concurrently over up to this number of connections.
+  # max_agent_connections = 1
+
+  ## Report the time taken to gather each agent in the snmp_agent metric.
+  # agent_stats = false
+
   ## SNMPv3 authentication and encryption options.
   ##
   ## Security Name.
@@ -55,7 +66,10 @@ const sampleConfig = `
   # sec_level = "authNoPriv"
   ## Context Name.
   # context_name = ""
-  ## Privacy protocol used for encrypted messages; one of "DES", "AES" or "".
+  ## Privacy protocol used for encrypted messages; one of "DES", "AES",
+  ## "AES192", "AES192C", "AES256", "AES256C" or "".  The "C" variants are
+  ## the Reeder key extension used by Cisco devices, the others the
+  ## Blumenthal key extension.
   # priv_protocol = ""
   ## Privacy password used for encrypted messages.
   # priv_password = ""
@@ -109,6 +123,13 @@ type Snmp struct {
 	// Parameters for Version 2 & 3
 	MaxRepetitions uint8 `toml:"max_repetitions"`
 
+	// Number of agents gathered concurrently, 0 for all of them.
+	Workers int `toml:"workers"`
+	// Maximum number of connections to each agent.
+	MaxAgentConnections int `toml:"max_agent_connections"`
+	// Report the gather time of each agent.
+	AgentStats bool `toml:"agent_stats"`
+
 	// Parameters for Version 3
 	ContextName string `toml:"context_name"`
 	// Values: "noAuthNoPriv", "authNoPriv", "authPriv"
@@ -117,7 +138,8 @@ type Snmp struct {
 	// Values: "MD5", "SHA", "". Default: ""
 	AuthProtocol string `toml:"auth_protocol"`
 	AuthPassword string `toml:"auth_password"`
-	// Values: "DES", "AES", "". Default: ""
+	// Values: "DES", "AES", "AES192", "AES192C", "AES256", "AES256C", "".
+	// Default: ""
 	PrivProtocol string `toml:"priv_protocol"`
 	PrivPassword string `toml:"priv_password"`
 	EngineID     string `toml:"-"`
@@ -132,7 +154,7 @@ type Snmp struct {
 	Name   string  // deprecated in 1.14; use name_override
 	Fields []Field `toml:"field"`
 
-	connectionCache []snmpConnection
+	connectionPools []*connectionPool
 	initialized     bool
 }
 
@@ -141,7 +163,13 @@ func (s *Snmp) init() error {
 		return nil
 	}
 
-	s.connectionCache = make([]snmpConnection, len(s.Agents))
+	if s.MaxAgentConnections < 1 {
+		s.MaxAgentConnections = 1
+	}
+	s.connectionPools = make([]*connectionPool, len(s.Agents))
+	for i := range s.connectionPools {
+		s.connectionPools[i] = newConnectionPool(s.MaxAgentConnections)
+	}
 
 	for i := range s.Tables {
 		if err := s.Tables[i].init(); err != nil {
@@ -331,6 +359,8 @@ func init() {
 			Timeout:        internal.Duration{Duration: 5 * time.Second},
 			Version:        2,
 			Community:      "public",
+
+			MaxAgentConnections: 1,
 		}
 	})
 }
@@ -353,38 +383,98 @@ func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	workers := s.Workers
+	if workers <= 0 || workers > len(s.Agents) {
+		workers = len(s.Agents)
+	}
+
+	agents := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range agents {
+				s.gatherAgent(acc, i)
+			}
+		}()
+	}
+	for i := range s.Agents {
+		agents <- i
+	}
+	close(agents)
+	wg.Wait()
+
+	return nil
+}
+
+// gatherAgent retrieves the fields and the tables of an agent.  The tables
+// are walked concurrently over the connections of the pool of the agent.
+func (s *Snmp) gatherAgent(acc telegraf.Accumulator, i int) {
+	agent := s.Agents[i]
+	start := time.Now()
+
+	var errorCount int64
+	addError := func(err error) {
+		atomic.AddInt64(&errorCount, 1)
+		acc.AddError(err)
+	}
+
+	gs, err := s.getConnection(i)
+	if err != nil {
+		addError(Errorf(err, "agent %s", agent))
+		s.gatherStats(acc, agent, start, errorCount)
+		return
+	}
+	host := gs.Host()
+
+	// First is the top-level fields. We treat the fields as table prefixes with an empty index.
+	t := Table{
+		Name:   s.Name,
+		Fields: s.Fields,
+	}
+	topTags := map[string]string{}
+	if err := s.gatherTable(acc, gs, t, topTags, false); err != nil {
+		addError(Errorf(err, "agent %s", agent))
+	}
+	s.releaseConnection(i, gs)
+
+	// Now is the real tables, the top-level tags are only read from here on.
 	var wg sync.WaitGroup
-	for i, agent := range s.Agents {
+	for _, t := range s.Tables {
 		wg.Add(1)
-		go func(i int, agent string) {
+		go func(t Table) {
 			defer wg.Done()
 			gs, err := s.getConnection(i)
 			if err != nil {
-				acc.AddError(Errorf(err, "agent %s", agent))
+				addError(Errorf(err, "agent %s", agent))
 				return
 			}
+			defer s.releaseConnection(i, gs)
 
-			// First is the top-level fields. We treat the fields as table prefixes with an empty index.
-			t := Table{
-				Name:   s.Name,
-				Fields: s.Fields,
+			if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
+				addError(Errorf(err, "agent %s: gathering table %s", agent, t.Name))
 			}
-			topTags := map[string]string{}
-			if err := s.gatherTable(acc, gs, t, topTags, false); err != nil {
-				acc.AddError(Errorf(err, "agent %s", agent))
-			}
-
-			// Now is the real tables.
-			for _, t := range s.Tables {
-				if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
-					acc.AddError(Errorf(err, "agent %s: gathering table %s", agent, t.Name))
-				}
-			}
-		}(i, agent)
+		}(t)
 	}
 	wg.Wait()
 
-	return nil
+	s.gatherStats(acc, host, start, errorCount)
+}
+
+// gatherStats adds the snmp_agent metric with the time taken to gather an
+// agent, if enabled.
+func (s *Snmp) gatherStats(acc telegraf.Accumulator, host string, start time.Time, errorCount int64) {
+	if !s.AgentStats {
+		return
+	}
+	acc.AddFields("snmp_agent",
+		map[string]interface{}{
+			"gather_time_ns": time.Since(start).Nanoseconds(),
+			"errors":         errorCount,
+		},
+		map[string]string{"agent_host": host},
+		start)
 }
 
 func (s *Snmp) gatherTable(acc telegraf.Accumulator, gs snmpConnection, t Table, topTags map[string]string, walk bool) error {
@@ -596,19 +686,62 @@ func (gsw gosnmpWrapper) Get(oids []string) (*gosnmp.SnmpPacket, error) {
 	return nil, err
 }
 
-// getConnection creates a snmpConnection (*gosnmp.GoSNMP) object and caches the
-// result using `agentIndex` as the cache key.  This is done to allow multiple
-// connections to a single address.  It is an error to use a connection in
-// more than one goroutine.
+// connectionPool holds the idle connections of an agent.  Up to the
+// capacity of tokens connections are in use at once.
+type connectionPool struct {
+	tokens chan struct{}
+
+	sync.Mutex
+	idle []snmpConnection
+}
+
+func newConnectionPool(size int) *connectionPool {
+	return &connectionPool{tokens: make(chan struct{}, size)}
+}
+
+// getConnection returns an idle connection from the pool of the agent at
+// `agentIndex`, or creates one if there is none and the pool is not full.  It
+// blocks while all the connections of the agent are in use.  The pools are
+// keyed by index to allow multiple connections to a single address.  It is an
+// error to use a connection in more than one goroutine, it must be returned to
+// the pool with releaseConnection when done.
 func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
-	if gs := s.connectionCache[idx]; gs != nil {
+	pool := s.connectionPools[idx]
+	pool.tokens <- struct{}{}
+
+	pool.Lock()
+	if n := len(pool.idle); n > 0 {
+		gs := pool.idle[n-1]
+		pool.idle = pool.idle[:n-1]
+		pool.Unlock()
 		return gs, nil
 	}
+	pool.Unlock()
+
+	gs, err := s.newConnection(idx)
+	if err != nil {
+		<-pool.tokens
+		return nil, err
+	}
+	return gs, nil
+}
+
+// releaseConnection returns a connection obtained with getConnection to the
+// pool of its agent.
+func (s *Snmp) releaseConnection(idx int, gs snmpConnection) {
+	pool := s.connectionPools[idx]
+	pool.Lock()
+	pool.idle = append(pool.idle, gs)
+	pool.Unlock()
+	<-pool.tokens
+}
 
+// newConnection creates a snmpConnection (*gosnmp.GoSNMP) object for the
+// agent at `agentIndex`.
+func (s *Snmp) newConnection(idx int) (snmpConnection, error) {
 	agent := s.Agents[idx]
 
 	gs := gosnmpWrapper{&gosnmp.GoSNMP{}}
-	s.connectionCache[idx] = gs
 
 	if !strings.Contains(agent, "://") {
 		agent = "udp://" + agent
@@ -703,6 +836,14 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 			sp.PrivacyProtocol = gosnmp.DES
 		case "aes":
 			sp.PrivacyProtocol = gosnmp.AES
+		case "aes192":
+			sp.PrivacyProtocol = gosnmp.AES192
+		case "aes192c":
+			sp.PrivacyProtocol = gosnmp.AES192C
+		case "aes256":
+			sp.PrivacyProtocol = gosnmp.AES256
+		case "aes256c":
+			sp.PrivacyProtocol = gosnmp.AES256C
 		case "":
 			sp.PrivacyProtocol = gosnmp.NoPriv
 		default:
@@ -88,13 +88,14 @@ func TestSampleConfig(t *testing.T) {
 	require.NoError(t, err)
 
 	expected := &Snmp{
-		Agents:         []string{"udp://127.0.0.1:161"},
-		Timeout:        internal.Duration{Duration: 5 * time.Second},
-		Version:        2,
-		Community:      "public",
-		MaxRepetitions: 10,
-		Retries:        3,
-		Name:           "snmp",
+		Agents:              []string{"udp://127.0.0.1:161"},
+		Timeout:             internal.Duration{Duration: 5 * time.Second},
+		Version:             2,
+		Community:           "public",
+		MaxRepetitions:      10,
+		MaxAgentConnections: 1,
+		Retries:             3,
+		Name:                "snmp",
 	}
 	require.Equal(t, expected, conf)
 }
@@ -335,6 +336,44 @@ func TestGetSNMPConnection_v3(t *testing.T) {
 	assert.EqualValues(t, 2, sp.AuthoritativeEngineTime)
 }
 
+func TestGetSNMPConnection_v3_privProtocols(t *testing.T) {
+	for protocol, expected := range map[string]gosnmp.SnmpV3PrivProtocol{
+		"AES192":  gosnmp.AES192,
+		"AES192C": gosnmp.AES192C,
+		"AES256":  gosnmp.AES256,
+		"AES256C": gosnmp.AES256C,
+	} {
+		s := &Snmp{
+			Agents:       []string{"1.2.3.4"},
+			Version:      3,
+			ContextName:  "mycontext",
+			SecLevel:     "authPriv",
+			SecName:      "myuser",
+			AuthProtocol: "sha",
+			AuthPassword: "password123",
+			PrivProtocol: protocol,
+			PrivPassword: "321drowssap",
+		}
+		require.NoError(t, s.init())
+
+		gsc, err := s.getConnection(0)
+		require.NoError(t, err)
+		gs := gsc.(gosnmpWrapper)
+		assert.Equal(t, "mycontext", gs.ContextName)
+		sp := gs.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		assert.Equal(t, expected, sp.PrivacyProtocol, protocol)
+	}
+
+	s := &Snmp{
+		Agents:       []string{"1.2.3.4"},
+		Version:      3,
+		PrivProtocol: "AES128C",
+	}
+	require.NoError(t, s.init())
+	_, err := s.getConnection(0)
+	require.Error(t, err)
+}
+
 func TestGetSNMPConnection_caching(t *testing.T) {
 	s := &Snmp{
 		Agents: []string{"1.2.3.4", "1.2.3.5", "1.2.3.5"},
@@ -343,6 +382,7 @@ func TestGetSNMPConnection_caching(t *testing.T) {
 	require.NoError(t, err)
 	gs1, err := s.getConnection(0)
 	require.NoError(t, err)
+	s.releaseConnection(0, gs1)
 	gs2, err := s.getConnection(0)
 	require.NoError(t, err)
 	gs3, err := s.getConnection(1)
@@ -354,6 +394,34 @@ func TestGetSNMPConnection_caching(t *testing.T) {
 	assert.False(t, gs3 == gs4)
 }
 
+func TestGetSNMPConnection_pool(t *testing.T) {
+	s := &Snmp{
+		Agents:              []string{"1.2.3.4"},
+		MaxAgentConnections: 2,
+	}
+	err := s.init()
+	require.NoError(t, err)
+	gs1, err := s.getConnection(0)
+	require.NoError(t, err)
+	gs2, err := s.getConnection(0)
+	require.NoError(t, err)
+	assert.False(t, gs1 == gs2)
+
+	// The pool is full until a connection is released.
+	got := make(chan snmpConnection)
+	go func() {
+		gs, _ := s.getConnection(0)
+		got <- gs
+	}()
+	select {
+	case <-got:
+		t.Fatal("got a connection from a full pool")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.releaseConnection(0, gs2)
+	assert.True(t, <-got == gs2)
+}
+
 func TestGosnmpWrapper_walk_retry(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping test due to random failures.")
@@ -607,8 +675,8 @@ func TestGather(t *testing.T) {
 			},
 		},
 
-		connectionCache: []snmpConnection{
-			tsc,
+		connectionPools: []*connectionPool{
+			newTestConnectionPool(tsc),
 		},
 		initialized: true,
 	}
@@ -654,8 +722,8 @@ func TestGather_host(t *testing.T) {
 			},
 		},
 
-		connectionCache: []snmpConnection{
-			tsc,
+		connectionPools: []*connectionPool{
+			newTestConnectionPool(tsc),
 		},
 		initialized: true,
 	}
@@ -669,6 +737,91 @@ func TestGather_host(t *testing.T) {
 	assert.Equal(t, "baz", m.Tags["host"])
 }
 
+func TestGather_concurrent(t *testing.T) {
+	s := &Snmp{
+		Agents:              []string{"TestGather1", "TestGather2", "TestGather3"},
+		Workers:             2,
+		MaxAgentConnections: 2,
+		AgentStats:          true,
+		Name:                "mytable",
+		Fields: []Field{
+			{
+				Name:  "myfield1",
+				Oid:   ".1.0.0.1.1",
+				IsTag: true,
+			},
+			{
+				Name: "myfield2",
+				Oid:  ".1.0.0.1.2",
+			},
+		},
+		Tables: []Table{
+			{
+				Name:        "myOtherTable",
+				InheritTags: []string{"myfield1"},
+				Fields: []Field{
+					{
+						Name: "myOtherField",
+						Oid:  ".1.0.0.0.1.5",
+					},
+				},
+			},
+			{
+				Name: "myThirdTable",
+				Fields: []Field{
+					{
+						Name: "myThirdField",
+						Oid:  ".1.0.0.0.1.2",
+					},
+				},
+			},
+		},
+
+		connectionPools: []*connectionPool{
+			newTestConnectionPool(tsc, tsc),
+			newTestConnectionPool(tsc, tsc),
+			newTestConnectionPool(&testSNMPConnection{host: "empty"}),
+		},
+		initialized: true,
+	}
+	acc := &testutil.Accumulator{}
+
+	require.NoError(t, s.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	for _, measurement := range []string{"mytable", "myOtherTable"} {
+		require.Equal(t, 2, countMeasurement(acc, measurement), measurement)
+	}
+	require.Equal(t, 6, countMeasurement(acc, "myThirdTable"))
+	require.Equal(t, 3, countMeasurement(acc, "snmp_agent"))
+	for _, m := range acc.Metrics {
+		if m.Measurement == "myOtherTable" {
+			assert.Equal(t, "baz", m.Tags["myfield1"])
+		}
+		if m.Measurement == "snmp_agent" {
+			assert.Contains(t, []string{"tsc", "empty"}, m.Tags["agent_host"])
+			assert.EqualValues(t, 0, m.Fields["errors"])
+			assert.Contains(t, m.Fields, "gather_time_ns")
+		}
+	}
+}
+
+func newTestConnectionPool(conns ...snmpConnection) *connectionPool {
+	p := newConnectionPool(len(conns))
+	p.idle = conns
+	return p
+}
+
+func countMeasurement(acc *testutil.Accumulator, measurement string) int {
+	var n int
+	for _, m := range acc.Metrics {
+		if m.Measurement == measurement {
+			n++
+		}
+	}
+	return n
+}
+
 func TestFieldConvert(t *testing.T) {
 	testTable := []struct {
 		input    interface{}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"net"
 	"os/exec"
 	"strconv"
@@ -31,11 +33,27 @@ type mibEntry struct {
 type SnmpTrap struct {
 	ServiceAddress string            `toml:"service_address"`
 	Timeout        internal.Duration `toml:"timeout"`
+	Version        string            `toml:"version"`
+
+	// Settings for version 3
+	// Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+	SecLevel string `toml:"sec_level"`
+	SecName  string `toml:"sec_name"`
+	// Values: "MD5", "SHA", "". Default: ""
+	AuthProtocol string `toml:"auth_protocol"`
+	AuthPassword string `toml:"auth_password"`
+	// Values: "DES", "AES", "AES192", "AES192C", "AES256", "AES256C", "".
+	// Default: ""
+	PrivProtocol string `toml:"priv_protocol"`
+	PrivPassword string `toml:"priv_password"`
 
 	acc      telegraf.Accumulator
-	listener *gosnmp.TrapListener
+	params   *gosnmp.GoSNMP
+	conn     *net.UDPConn
+	handler  handler
 	timeFunc func() time.Time
-	errCh    chan error
+	done     chan struct{}
+	wg       sync.WaitGroup
 
 	makeHandlerWrapper func(handler) handler
 
@@ -58,6 +76,24 @@ var sampleConfig = `
   # service_address = "udp://:162"
   ## Timeout running snmptranslate command
   # timeout = "5s"
+  ## Version of the notifications; one of "1", "2c" or "3".  With version 3
+  ## the notifications of the other versions are received as well.
+  # version = "2c"
+  ## SNMPv3 authentication and encryption options.
+  ##
+  ## Security Name.
+  # sec_name = "myuser"
+  ## Authentication protocol; one of "MD5", "SHA" or "".
+  # auth_protocol = "MD5"
+  ## Authentication password.
+  # auth_password = "pass"
+  ## Security Level; one of "noAuthNoPriv", "authNoPriv", or "authPriv".
+  # sec_level = "authNoPriv"
+  ## Privacy protocol used for encrypted messages; one of "DES", "AES",
+  ## "AES192", "AES192C", "AES256", "AES256C" or "".
+  # priv_protocol = ""
+  ## Privacy password used for encrypted messages.
+  # priv_password = ""
 `
 
 func (s *SnmpTrap) SampleConfig() string {
@@ -78,6 +114,7 @@ func init() {
 			timeFunc:       time.Now,
 			ServiceAddress: "udp://:162",
 			Timeout:        defaultTimeout,
+			Version:        "2c",
 		}
 	})
 }
@@ -96,18 +133,87 @@ func realExecCmd(Timeout internal.Duration, arg0 string, args ...string) ([]byte
 func (s *SnmpTrap) Init() error {
 	s.cache = map[string]mibEntry{}
 	s.execCmd = realExecCmd
+
+	s.params = &gosnmp.GoSNMP{
+		Version: gosnmp.Version2c,
+		MaxOids: gosnmp.MaxOids,
+	}
+	switch s.Version {
+	case "1", "2c", "":
+	case "3":
+		if err := s.initV3(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown version %q", s.Version)
+	}
+	return nil
+}
+
+// initV3 sets the user security model parameters used to authenticate and
+// decrypt the notifications of version 3.
+func (s *SnmpTrap) initV3() error {
+	s.params.Version = gosnmp.Version3
+	s.params.SecurityModel = gosnmp.UserSecurityModel
+
+	switch strings.ToLower(s.SecLevel) {
+	case "noauthnopriv", "":
+		s.params.MsgFlags = gosnmp.NoAuthNoPriv
+	case "authnopriv":
+		s.params.MsgFlags = gosnmp.AuthNoPriv
+	case "authpriv":
+		s.params.MsgFlags = gosnmp.AuthPriv
+	default:
+		return fmt.Errorf("unknown security level %q", s.SecLevel)
+	}
+
+	sp := &gosnmp.UsmSecurityParameters{
+		UserName:                 s.SecName,
+		AuthenticationPassphrase: s.AuthPassword,
+		PrivacyPassphrase:        s.PrivPassword,
+		// The packets are decoded without connecting, which is where
+		// gosnmp sets the logger of the security parameters.
+		Logger: log.New(ioutil.Discard, "", 0),
+	}
+	switch strings.ToLower(s.AuthProtocol) {
+	case "md5":
+		sp.AuthenticationProtocol = gosnmp.MD5
+	case "sha":
+		sp.AuthenticationProtocol = gosnmp.SHA
+	case "":
+		sp.AuthenticationProtocol = gosnmp.NoAuth
+	default:
+		return fmt.Errorf("unknown authentication protocol %q", s.AuthProtocol)
+	}
+	switch strings.ToLower(s.PrivProtocol) {
+	case "des":
+		sp.PrivacyProtocol = gosnmp.DES
+	case "aes":
+		sp.PrivacyProtocol = gosnmp.AES
+	case "aes192":
+		sp.PrivacyProtocol = gosnmp.AES192
+	case "aes192c":
+		sp.PrivacyProtocol = gosnmp.AES192C
+	case "aes256":
+		sp.PrivacyProtocol = gosnmp.AES256
+	case "aes256c":
+		sp.PrivacyProtocol = gosnmp.AES256C
+	case "":
+		sp.PrivacyProtocol = gosnmp.NoPriv
+	default:
+		return fmt.Errorf("unknown privacy protocol %q", s.PrivProtocol)
+	}
+	s.params.SecurityParameters = sp
 	return nil
 }
 
 func (s *SnmpTrap) Start(acc telegraf.Accumulator) error {
 	s.acc = acc
-	s.listener = gosnmp.NewTrapListener()
-	s.listener.OnNewTrap = makeTrapHandler(s)
-	s.listener.Params = gosnmp.Default
+	s.handler = makeTrapHandler(s)
 
 	// wrap the handler, used in unit tests
 	if nil != s.makeHandlerWrapper {
-		s.listener.OnNewTrap = s.makeHandlerWrapper(s.listener.OnNewTrap)
+		s.handler = s.makeHandlerWrapper(s.handler)
 	}
 
 	split := strings.SplitN(s.ServiceAddress, "://", 2)
@@ -118,36 +224,88 @@ func (s *SnmpTrap) Start(acc telegraf.Accumulator) error {
 	protocol := split[0]
 	addr := split[1]
 
-	// gosnmp.TrapListener currently supports udp only.  For forward
-	// compatibility, require udp in the service address
+	// Only udp is supported.  For forward compatibility, require udp in
+	// the service address
 	if protocol != "udp" {
 		return fmt.Errorf("unknown protocol '%s' in '%s'", protocol, s.ServiceAddress)
 	}
 
-	// If (*TrapListener).Listen immediately returns an error we need
-	// to return it from this function.  Use a channel to get it here
-	// from the goroutine.  Buffer one in case Listen returns after
-	// Listening but before our Close is called.
-	s.errCh = make(chan error, 1)
-	go func() {
-		s.errCh <- s.listener.Listen(addr)
-	}()
-
-	select {
-	case <-s.listener.Listening():
-		s.Log.Infof("Listening on %s", s.ServiceAddress)
-	case err := <-s.errCh:
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	s.conn, err = net.ListenUDP("udp", udpAddr)
+	if err != nil {
 		return err
 	}
+	s.Log.Infof("Listening on %s", s.ServiceAddress)
+
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.listen()
+	}()
 
 	return nil
 }
 
 func (s *SnmpTrap) Stop() {
-	s.listener.Close()
-	err := <-s.errCh
-	if nil != err {
-		s.Log.Errorf("Error stopping trap listener %v", err)
+	close(s.done)
+	s.conn.Close()
+	s.wg.Wait()
+}
+
+// listen receives the notifications until the listener is stopped.
+func (s *SnmpTrap) listen() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.Log.Errorf("Error reading notification: %v", err)
+			continue
+		}
+
+		packet := s.params.UnmarshalTrap(buf[:n])
+		if packet == nil {
+			s.Log.Debugf("Invalid notification from %s", addr.IP)
+			continue
+		}
+		if packet.PDUType == gosnmp.InformRequest {
+			s.acknowledge(packet, addr)
+		}
+		s.handler(packet, addr)
+	}
+}
+
+// acknowledge answers an inform request with a response of the same request
+// id and variables, so that the sender stops resending it.  The informs of
+// version 3 are not acknowledged, this requires the sender to discover the
+// engine of the listener.
+func (s *SnmpTrap) acknowledge(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+	if packet.Version == gosnmp.Version3 {
+		return
+	}
+
+	response := &gosnmp.SnmpPacket{
+		Version:   packet.Version,
+		Community: packet.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: packet.RequestID,
+		Variables: packet.Variables,
+	}
+	msg, err := response.MarshalMsg()
+	if err != nil {
+		s.Log.Errorf("Error encoding response to inform from %s: %v", addr.IP, err)
+		return
+	}
+	if _, err := s.conn.WriteToUDP(msg, addr); err != nil {
+		s.Log.Errorf("Error sending response to inform from %s: %v", addr.IP, err)
 	}
 }
 
@@ -337,3 +337,170 @@ func TestReceiveTrap(t *testing.T) {
 	}
 
 }
+
+// startTrapListener starts the plugin on the port with the oids of the test
+// preloaded, the channel receives a value for each handled notification.
+func startTrapListener(t *testing.T, s *SnmpTrap, port int, acc telegraf.Accumulator) <-chan int {
+	received := make(chan int, 1)
+	s.ServiceAddress = "udp://:" + strconv.Itoa(port)
+	s.makeHandlerWrapper = func(f handler) handler {
+		return func(p *gosnmp.SnmpPacket, a *net.UDPAddr) {
+			f(p, a)
+			received <- 0
+		}
+	}
+	s.timeFunc = func() time.Time {
+		return time.Unix(456456456, 456)
+	}
+	s.Log = testutil.Logger{}
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Start(acc))
+
+	s.load(".1.3.6.1.6.3.1.1.4.1.0", mibEntry{"SNMPv2-MIB", "snmpTrapOID.0"})
+	s.load(".1.3.6.1.6.3.1.1.5.1", mibEntry{"SNMPv2-MIB", "coldStart"})
+	s.load(".1.3.6.1.2.1.1.3.0", mibEntry{"UNUSED_MIB_NAME", "sysUpTimeInstance"})
+	s.execCmd = fakeExecCmd
+	return received
+}
+
+func TestReceiveInform(t *testing.T) {
+	const port = 12400
+
+	var acc testutil.Accumulator
+	s := &SnmpTrap{}
+	received := startTrapListener(t, s, port, &acc)
+	defer s.Stop()
+
+	inform := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		PDUType:   gosnmp.InformRequest,
+		RequestID: 42,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(123)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.1"},
+		},
+	}
+	msg, err := inform.MarshalMsg()
+	require.NoError(t, err)
+
+	conn, err := net.Dial("udp", "127.0.0.1:"+strconv.Itoa(port))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(msg)
+	require.NoError(t, err)
+
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for inform to be received")
+	}
+
+	// The inform is acknowledged with a response of the same request id.
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	response := (&gosnmp.GoSNMP{}).UnmarshalTrap(buf[:n])
+	require.NotNil(t, response)
+	require.Equal(t, gosnmp.GetResponse, response.PDUType)
+	require.Equal(t, uint32(42), response.RequestID)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"snmp_trap",
+			map[string]string{
+				"oid":     ".1.3.6.1.6.3.1.1.5.1",
+				"name":    "coldStart",
+				"mib":     "SNMPv2-MIB",
+				"version": "2c",
+				"source":  "127.0.0.1",
+			},
+			map[string]interface{}{
+				"sysUpTimeInstance": uint(123),
+			},
+			time.Unix(456456456, 456),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestReceiveTrapV3(t *testing.T) {
+	const port = 12401
+
+	var acc testutil.Accumulator
+	s := &SnmpTrap{
+		Version:      "3",
+		SecLevel:     "authPriv",
+		SecName:      "myuser",
+		AuthProtocol: "SHA",
+		AuthPassword: "password123",
+		PrivProtocol: "AES256C",
+		PrivPassword: "321drowssap",
+	}
+	received := startTrapListener(t, s, port, &acc)
+	defer s.Stop()
+
+	sender := &gosnmp.GoSNMP{
+		Target:        "127.0.0.1",
+		Port:          port,
+		Version:       gosnmp.Version3,
+		Timeout:       2 * time.Second,
+		Retries:       3,
+		MaxOids:       gosnmp.MaxOids,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.AuthPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:                 "myuser",
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: "password123",
+			PrivacyProtocol:          gosnmp.AES256C,
+			PrivacyPassphrase:        "321drowssap",
+			AuthoritativeEngineBoots: 1,
+			AuthoritativeEngineTime:  1,
+			AuthoritativeEngineID:    string([]byte{0x80, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04}),
+		},
+	}
+	require.NoError(t, sender.Connect())
+	defer sender.Conn.Close()
+
+	_, err := sender.SendTrap(gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(123)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.1"},
+		},
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for trap to be received")
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"snmp_trap",
+			map[string]string{
+				"oid":     ".1.3.6.1.6.3.1.1.5.1",
+				"name":    "coldStart",
+				"mib":     "SNMPv2-MIB",
+				"version": "3",
+				"source":  "127.0.0.1",
+			},
+			map[string]interface{}{
+				"sysUpTimeInstance": uint(123),
+			},
+			time.Unix(456456456, 456),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestInitV3(t *testing.T) {
+	require.Error(t, (&SnmpTrap{Version: "4"}).Init())
+	require.Error(t, (&SnmpTrap{Version: "3", SecLevel: "none"}).Init())
+	require.Error(t, (&SnmpTrap{Version: "3", AuthProtocol: "SHA512"}).Init())
+	require.Error(t, (&SnmpTrap{Version: "3", PrivProtocol: "AES128C"}).Init())
+	require.NoError(t, (&SnmpTrap{Version: "3", SecLevel: "authNoPriv", AuthProtocol: "MD5"}).Init())
+}
@@ -0,0 +1,10 @@
+package sql
+
+import (
+	// Register the supported drivers
+	_ "github.com/ClickHouse/clickhouse-go"
+	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/stdlib"
+	_ "github.com/snowflakedb/gosnowflake"
+)
@@ -0,0 +1,212 @@
+package sql
+
+import (
+	dbsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver returns the rows registered for a query.
+type fakeDriver struct{}
+
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+var fakeResults = map[string]fakeResult{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	result, ok := fakeResults[query]
+	if !ok {
+		return nil, errors.New("unknown query")
+	}
+	return &fakeStmt{result: result}, nil
+}
+
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	result fakeResult
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return 0 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{result: s.result}, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	index  int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.index])
+	r.index++
+	return nil
+}
+
+func init() {
+	dbsql.Register("fake", fakeDriver{})
+	driverNames["fake"] = "fake"
+}
+
+func newTestSQL(queries ...*Query) *SQL {
+	return &SQL{
+		Driver:  "fake",
+		DSN:     "fake",
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		Queries: queries,
+	}
+}
+
+func TestGather(t *testing.T) {
+	fakeResults["SELECT * FROM stats"] = fakeResult{
+		columns: []string{"time", "host", "usage", "count", "state", "ok"},
+		rows: [][]driver.Value{
+			{int64(1500000000), []byte("a"), []byte("1.5"), int64(3), "running", int64(1)},
+			{int64(1500000001), []byte("b"), float64(2.5), nil, "stopped", int64(0)},
+		},
+	}
+
+	plugin := newTestSQL(&Query{
+		Query:             "SELECT * FROM stats",
+		Measurement:       "stats",
+		TimeColumn:        "time",
+		TagColumnsInclude: []string{"host"},
+		FieldColumnsFloat: []string{"usage"},
+		FieldColumnsBool:  []string{"ok"},
+	})
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"stats",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"usage": 1.5,
+				"count": int64(3),
+				"state": "running",
+				"ok":    true,
+			},
+			time.Unix(1500000000, 0),
+		),
+		testutil.MustMetric(
+			"stats",
+			map[string]string{"host": "b"},
+			map[string]interface{}{
+				"usage": 2.5,
+				"state": "stopped",
+				"ok":    false,
+			},
+			time.Unix(1500000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestGatherMeasurementColumn(t *testing.T) {
+	fakeResults["SELECT name, value FROM stats"] = fakeResult{
+		columns: []string{"name", "value", "label"},
+		rows: [][]driver.Value{
+			{"cpu", []byte("42"), "x"},
+			{"mem", []byte("43"), "y"},
+		},
+	}
+
+	plugin := newTestSQL(&Query{
+		Query:               "SELECT name, value FROM stats",
+		MeasurementColumn:   "name",
+		FieldColumnsInclude: []string{"value"},
+		FieldColumnsInt:     []string{"value"},
+	})
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": int64(42)}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": int64(43)}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherConversionError(t *testing.T) {
+	fakeResults["SELECT value FROM invalid"] = fakeResult{
+		columns: []string{"value"},
+		rows: [][]driver.Value{
+			{[]byte("abc")},
+			{[]byte("1")},
+		},
+	}
+
+	plugin := newTestSQL(&Query{
+		Query:           "SELECT value FROM invalid",
+		FieldColumnsInt: []string{"value"},
+	})
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+}
+
+func TestInit(t *testing.T) {
+	plugin := newTestSQL(&Query{
+		Query:             "SELECT 1",
+		FieldColumnsFloat: []string{"value"},
+		FieldColumnsInt:   []string{"value"},
+	})
+	require.Error(t, plugin.Init())
+
+	plugin = newTestSQL(&Query{})
+	require.Error(t, plugin.Init())
+
+	plugin = &SQL{Driver: "oracle", DSN: "fake"}
+	require.Error(t, plugin.Init())
+
+	plugin = newTestSQL(&Query{Query: "SELECT 1"})
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "sql", plugin.Queries[0].Measurement)
+	require.Equal(t, "unix", plugin.Queries[0].TimeFormat)
+}
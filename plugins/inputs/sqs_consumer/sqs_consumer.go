@@ -0,0 +1,363 @@
+package sqs_consumer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const (
+	defaultMaxUndeliveredMessages = 1000
+
+	// maxBatchSize is the largest number of messages SQS will receive or
+	// delete in a single request.
+	maxBatchSize = 10
+)
+
+type client interface {
+	ReceiveMessageWithContext(aws.Context, *sqs.ReceiveMessageInput, ...request.Option) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageBatchWithContext(aws.Context, *sqs.DeleteMessageBatchInput, ...request.Option) (*sqs.DeleteMessageBatchOutput, error)
+}
+
+type SQSConsumer struct {
+	Region                 string            `toml:"region"`
+	AccessKey              string            `toml:"access_key"`
+	SecretKey              string            `toml:"secret_key"`
+	RoleARN                string            `toml:"role_arn"`
+	Profile                string            `toml:"profile"`
+	Filename               string            `toml:"shared_credential_file"`
+	Token                  string            `toml:"token"`
+	EndpointURL            string            `toml:"endpoint_url"`
+	QueueURL               string            `toml:"queue_url"`
+	MaxNumberOfMessages    int64             `toml:"max_number_of_messages"`
+	WaitTime               internal.Duration `toml:"wait_time"`
+	VisibilityTimeout      internal.Duration `toml:"visibility_timeout"`
+	DeleteInterval         internal.Duration `toml:"delete_interval"`
+	MaxUndeliveredMessages int               `toml:"max_undelivered_messages"`
+
+	Log telegraf.Logger
+
+	client  client
+	parser  parsers.Parser
+	acc     telegraf.TrackingAccumulator
+	sem     chan struct{}
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	handles map[telegraf.TrackingID]string
+}
+
+var sampleConfig = `
+  ## Amazon REGION of the SQS queue.
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+  # role_arn = ""
+  # profile = ""
+  # shared_credential_file = ""
+
+  ## Endpoint to make request against, the correct endpoint is automatically
+  ## determined and this option should only be set if you wish to override the
+  ## default.
+  ##   ex: endpoint_url = "http://localhost:9324"
+  # endpoint_url = ""
+
+  ## URL of the SQS queue to consume from.
+  queue_url = "https://sqs.us-east-1.amazonaws.com/123456789012/telegraf"
+
+  ## Maximum number of messages returned by each receive request, between 1
+  ## and 10.
+  # max_number_of_messages = 10
+
+  ## Duration to wait for messages to arrive before a receive request
+  ## returns, up to 20s.  Setting this above 0s enables long polling.
+  # wait_time = "20s"
+
+  ## Duration received messages are hidden from other consumers.  Messages
+  ## which are not written by an output within this time are received again.
+  ## If unset the visibility timeout of the queue is used.
+  # visibility_timeout = "0s"
+
+  ## Interval at which messages written by an output are deleted from the
+  ## queue.  Messages are deleted in batches of up to 10, a full batch is
+  ## deleted immediately.
+  # delete_interval = "1s"
+
+  ## Maximum messages to read from the queue that have not been written by an
+  ## output.  For best throughput set based on the number of metrics within
+  ## each message and the size of the output's metric_batch_size.
+  ##
+  ## For example, if each message from the queue contains 10 metrics and the
+  ## output metric_batch_size is 1000, setting this to 100 will ensure that a
+  ## full batch is collected and the write is triggered immediately without
+  ## waiting until the next flush_interval.
+  # max_undelivered_messages = 1000
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+func (s *SQSConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SQSConsumer) Description() string {
+	return "Read metrics from AWS SQS"
+}
+
+func (s *SQSConsumer) SetParser(parser parsers.Parser) {
+	s.parser = parser
+}
+
+func (s *SQSConsumer) Init() error {
+	if s.QueueURL == "" {
+		return errors.New("queue_url must be configured")
+	}
+
+	if s.MaxNumberOfMessages < 1 || s.MaxNumberOfMessages > maxBatchSize {
+		return errors.New("max_number_of_messages must be between 1 and 10")
+	}
+
+	if s.WaitTime.Duration > 20*time.Second {
+		return errors.New("wait_time must not be greater than 20s")
+	}
+
+	if s.DeleteInterval.Duration <= 0 {
+		return errors.New("delete_interval must be greater than 0s")
+	}
+	return nil
+}
+
+func (s *SQSConsumer) Start(acc telegraf.Accumulator) error {
+	if s.client == nil {
+		credentialConfig := &internalaws.CredentialConfig{
+			Region:      s.Region,
+			AccessKey:   s.AccessKey,
+			SecretKey:   s.SecretKey,
+			RoleARN:     s.RoleARN,
+			Profile:     s.Profile,
+			Filename:    s.Filename,
+			Token:       s.Token,
+			EndpointURL: s.EndpointURL,
+		}
+		s.client = sqs.New(credentialConfig.Credentials())
+	}
+
+	s.acc = acc.WithTracking(s.MaxUndeliveredMessages)
+	s.sem = make(chan struct{}, s.MaxUndeliveredMessages)
+	s.handles = make(map[telegraf.TrackingID]string, s.MaxUndeliveredMessages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.onDelivery(ctx)
+	}()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.receive(ctx)
+	}()
+
+	return nil
+}
+
+// receive polls the queue until the context is cancelled.
+func (s *SQSConsumer) receive(ctx context.Context) {
+	for {
+		// Only request as many messages as can be accepted without exceeding
+		// max_undelivered_messages.
+		select {
+		case <-ctx.Done():
+			return
+		case s.sem <- struct{}{}:
+		}
+		count := int64(1)
+	acquire:
+		for count < s.MaxNumberOfMessages {
+			select {
+			case s.sem <- struct{}{}:
+				count++
+			default:
+				break acquire
+			}
+		}
+
+		input := &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.QueueURL),
+			MaxNumberOfMessages: aws.Int64(count),
+			WaitTimeSeconds:     aws.Int64(int64(s.WaitTime.Duration.Seconds())),
+		}
+		if s.VisibilityTimeout.Duration > 0 {
+			input.VisibilityTimeout = aws.Int64(int64(s.VisibilityTimeout.Duration.Seconds()))
+		}
+
+		out, err := s.client.ReceiveMessageWithContext(ctx, input)
+		if err != nil {
+			s.release(int(count))
+			if ctx.Err() != nil {
+				return
+			}
+			s.acc.AddError(err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		// Release the slots for messages that were not returned.
+		s.release(int(count) - len(out.Messages))
+
+		for _, msg := range out.Messages {
+			err := s.onMessage(msg)
+			if err != nil {
+				// Discard the message from the queue; will never be able to
+				// process this message.
+				s.deleteMessages(ctx, []string{aws.StringValue(msg.ReceiptHandle)})
+				s.release(1)
+				s.acc.AddError(err)
+			}
+		}
+	}
+}
+
+func (s *SQSConsumer) release(n int) {
+	for i := 0; i < n; i++ {
+		<-s.sem
+	}
+}
+
+func (s *SQSConsumer) onMessage(msg *sqs.Message) error {
+	metrics, err := s.parser.Parse([]byte(aws.StringValue(msg.Body)))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	id := s.acc.AddTrackingMetricGroup(metrics)
+	s.handles[id] = aws.StringValue(msg.ReceiptHandle)
+	s.mu.Unlock()
+	return nil
+}
+
+// onDelivery collects the receipt handles of delivered messages and deletes
+// them from the queue in batches.
+func (s *SQSConsumer) onDelivery(ctx context.Context) {
+	ticker := time.NewTicker(s.DeleteInterval.Duration)
+	defer ticker.Stop()
+
+	pending := make([]string, 0, maxBatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			// Delete messages that have been delivered so they are not
+			// received again after restart.
+			s.deleteMessages(context.Background(), pending)
+			return
+		case <-ticker.C:
+			s.deleteMessages(ctx, pending)
+			pending = pending[:0]
+		case info := <-s.acc.Delivered():
+			s.mu.Lock()
+			handle, ok := s.handles[info.ID()]
+			if !ok {
+				s.mu.Unlock()
+				continue
+			}
+			delete(s.handles, info.ID())
+			s.mu.Unlock()
+			<-s.sem
+
+			if !info.Delivered() {
+				// The message becomes visible again once the visibility
+				// timeout expires and will be received again.
+				s.Log.Debug("Metric group failed to process")
+				continue
+			}
+
+			pending = append(pending, handle)
+			if len(pending) == maxBatchSize {
+				s.deleteMessages(ctx, pending)
+				pending = pending[:0]
+			}
+		}
+	}
+}
+
+func (s *SQSConsumer) deleteMessages(ctx context.Context, handles []string) {
+	if len(handles) == 0 {
+		return
+	}
+
+	entries := make([]*sqs.DeleteMessageBatchRequestEntry, 0, len(handles))
+	for i, handle := range handles {
+		entries = append(entries, &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: aws.String(handle),
+		})
+	}
+
+	out, err := s.client.DeleteMessageBatchWithContext(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(s.QueueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		s.Log.Errorf("Unable to delete messages: %v", err)
+		return
+	}
+
+	for _, entry := range out.Failed {
+		s.Log.Errorf("Unable to delete message %s: %s", aws.StringValue(entry.Id), aws.StringValue(entry.Message))
+	}
+}
+
+func (s *SQSConsumer) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// All gathering is done in the Start function
+func (s *SQSConsumer) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("sqs_consumer", func() telegraf.Input {
+		return &SQSConsumer{
+			MaxNumberOfMessages:    maxBatchSize,
+			WaitTime:               internal.Duration{Duration: 20 * time.Second},
+			DeleteInterval:         internal.Duration{Duration: time.Second},
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
+	})
+}
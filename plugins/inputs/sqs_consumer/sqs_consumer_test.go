@@ -0,0 +1,121 @@
+package sqs_consumer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	sync.Mutex
+	messages []*sqs.Message
+	deleted  []string
+}
+
+func (c *mockClient) ReceiveMessageWithContext(ctx aws.Context, in *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	c.Lock()
+	n := int(aws.Int64Value(in.MaxNumberOfMessages))
+	if n > len(c.messages) {
+		n = len(c.messages)
+	}
+	msgs := c.messages[:n]
+	c.messages = c.messages[n:]
+	c.Unlock()
+
+	if len(msgs) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+}
+
+func (c *mockClient) DeleteMessageBatchWithContext(ctx aws.Context, in *sqs.DeleteMessageBatchInput, _ ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+	for _, entry := range in.Entries {
+		c.deleted = append(c.deleted, aws.StringValue(entry.ReceiptHandle))
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (c *mockClient) Deleted() []string {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string(nil), c.deleted...)
+}
+
+func newTestSQSConsumer(client client) *SQSConsumer {
+	parser, _ := parsers.NewInfluxParser()
+	s := &SQSConsumer{
+		QueueURL:               "https://sqs.us-east-1.amazonaws.com/123456789012/telegraf",
+		MaxNumberOfMessages:    maxBatchSize,
+		DeleteInterval:         internal.Duration{Duration: time.Second},
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		Log:                    testutil.Logger{},
+		client:                 client,
+	}
+	s.SetParser(parser)
+	return s
+}
+
+func TestInit(t *testing.T) {
+	s := newTestSQSConsumer(nil)
+	require.NoError(t, s.Init())
+
+	s.QueueURL = ""
+	require.Error(t, s.Init())
+
+	s = newTestSQSConsumer(nil)
+	s.MaxNumberOfMessages = 11
+	require.Error(t, s.Init())
+
+	s = newTestSQSConsumer(nil)
+	s.WaitTime.Duration = time.Minute
+	require.Error(t, s.Init())
+}
+
+func TestReceive(t *testing.T) {
+	client := &mockClient{
+		messages: []*sqs.Message{
+			{
+				Body:          aws.String("cpu value=42 0\n"),
+				ReceiptHandle: aws.String("first"),
+			},
+			{
+				Body:          aws.String("not line protocol"),
+				ReceiptHandle: aws.String("invalid"),
+			},
+		},
+	}
+
+	s := newTestSQSConsumer(client)
+	require.NoError(t, s.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	acc.Wait(1)
+	acc.WaitError(1)
+
+	expected := []*testutil.Metric{
+		{
+			Measurement: "cpu",
+			Tags:        map[string]string{},
+			Fields:      map[string]interface{}{"value": 42.0},
+			Time:        time.Unix(0, 0),
+		},
+	}
+	require.Equal(t, expected, acc.Metrics)
+
+	// Messages that cannot be parsed are removed from the queue.
+	require.Equal(t, []string{"invalid"}, client.Deleted())
+}
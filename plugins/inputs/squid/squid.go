@@ -0,0 +1,347 @@
+package squid
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type Squid struct {
+	Urls            []string          `toml:"urls"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	ResponseTimeout internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  ## An array of URLs of the cache manager of Squid, the counters and info
+  ## pages are read below these URLs.
+  urls = ["http://localhost:3128/squid-internal-mgr"]
+
+  ## Credentials of the cache manager, the password is one of the
+  ## cachemgr_passwd of squid.conf.
+  # username = "telegraf"
+  # password = "mypassword"
+
+  ## Maximum time to receive response.
+  # response_timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (s *Squid) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Squid) Description() string {
+	return "Read Squid counters and cache statistics from the cache manager"
+}
+
+func (s *Squid) Init() error {
+	if len(s.Urls) == 0 {
+		s.Urls = []string{"http://localhost:3128/squid-internal-mgr"}
+	}
+	if s.ResponseTimeout.Duration < time.Second {
+		s.ResponseTimeout.Duration = time.Second * 5
+	}
+
+	tlsCfg, err := s.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	s.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: s.ResponseTimeout.Duration,
+	}
+	return nil
+}
+
+func (s *Squid) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, u := range s.Urls {
+		addr, err := url.Parse(u)
+		if err != nil {
+			acc.AddError(fmt.Errorf("Unable to parse address '%s': %s", u, err))
+			continue
+		}
+
+		wg.Add(1)
+		go func(addr *url.URL) {
+			defer wg.Done()
+			acc.AddError(s.gatherURL(addr, acc))
+		}(addr)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *Squid) gatherURL(addr *url.URL, acc telegraf.Accumulator) error {
+	fields := make(map[string]interface{})
+
+	var servers, serviceTimes map[string]map[string]interface{}
+	err := s.get(addr, "counters", func(r io.Reader) error {
+		var err error
+		servers, err = parseCounters(r, fields)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = s.get(addr, "info", func(r io.Reader) error {
+		var err error
+		serviceTimes, err = parseInfo(r, fields)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	tags := getTags(addr)
+	acc.AddFields("squid", fields, tags)
+	addTagged(acc, "squid_server", "protocol", servers, tags)
+	addTagged(acc, "squid_service_time", "type", serviceTimes, tags)
+	return nil
+}
+
+// addTagged adds the fields by value of the tag.
+func addTagged(acc telegraf.Accumulator, measurement, tag string, fields map[string]map[string]interface{}, tags map[string]string) {
+	for value, f := range fields {
+		t := map[string]string{tag: value}
+		for k, v := range tags {
+			t[k] = v
+		}
+		acc.AddFields(measurement, f, t)
+	}
+}
+
+// get requests a page of the cache manager.
+func (s *Squid) get(addr *url.URL, page string, parse func(io.Reader) error) error {
+	u := strings.TrimSuffix(addr.String(), "/") + "/" + page
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return fmt.Errorf("error on new request to %s: %s", u, err)
+	}
+	if s.Username != "" || s.Password != "" {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error on request to %s: %s", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+	if err := parse(resp.Body); err != nil {
+		return fmt.Errorf("error parsing %s: %s", u, err)
+	}
+	return nil
+}
+
+// parseCounters parses the counters page, made of "name = value" lines.  The
+// counters of the servers are returned by protocol, the others are added to
+// the fields.
+func parseCounters(r io.Reader, fields map[string]interface{}) (map[string]map[string]interface{}, error) {
+	servers := make(map[string]map[string]interface{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		// The sample time is followed by the date.
+		value, ok := parseNumber(strings.Fields(parts[1]))
+		if !ok || name == "sample_time" {
+			continue
+		}
+
+		if strings.HasPrefix(name, "server.") {
+			names := strings.SplitN(name, ".", 3)
+			if len(names) != 3 {
+				continue
+			}
+			if _, ok := servers[names[1]]; !ok {
+				servers[names[1]] = make(map[string]interface{})
+			}
+			servers[names[1]][names[2]] = value
+			continue
+		}
+		fields[strings.Replace(name, ".", "_", -1)] = value
+	}
+	return servers, scanner.Err()
+}
+
+// parseInfo parses the info page, made of "label: value" lines grouped in
+// sections.  The median service times are returned by type, the other values
+// are added to the fields.
+func parseInfo(r io.Reader, fields map[string]interface{}) (map[string]map[string]interface{}, error) {
+	serviceTimes := make(map[string]map[string]interface{})
+	inServiceTimes := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "\t") {
+			inServiceTimes = strings.HasPrefix(line, "Median Service Times")
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		label := strings.TrimSpace(parts[0])
+		values := strings.Fields(strings.Replace(parts[1], ",", " ", -1))
+
+		switch {
+		case inServiceTimes:
+			// HTTP Requests (All):   0.00000  0.00000
+			if len(values) != 2 {
+				continue
+			}
+			median5, ok5 := parseNumber(values[:1])
+			median60, ok60 := parseNumber(values[1:])
+			if ok5 && ok60 {
+				serviceTimes[snakeCase(label)] = map[string]interface{}{
+					"median_5min":  toFloat(median5),
+					"median_60min": toFloat(median60),
+				}
+			}
+		case strings.HasSuffix(label, "Hit Ratios"):
+			// Request Hit Ratios:	5min: 12.5%, 60min: 10.0%
+			name := snakeCase(strings.TrimSuffix(label, "s"))
+			for i := 0; i+1 < len(values); i += 2 {
+				if v, ok := parsePercent(values[i+1]); ok {
+					fields[name+"_"+strings.TrimSuffix(values[i], ":")] = v
+				}
+			}
+		case strings.HasSuffix(label, "capacity"):
+			// Storage Swap capacity:	 0.2% used, 99.8% free
+			if len(values) >= 2 && values[1] == "used" {
+				if v, ok := parsePercent(values[0]); ok {
+					fields[snakeCase(label)+"_used"] = v
+				}
+			}
+		case strings.HasSuffix(label, "size"):
+			// Storage Swap size:	1280 KB
+			if len(values) == 2 && values[1] == "KB" {
+				if v, ok := parseNumber(values[:1]); ok {
+					fields[snakeCase(label)+"_kb"] = v
+				}
+			}
+		default:
+			if name, ok := infoFields[label]; ok {
+				if v, ok := parseNumber(values); ok {
+					fields[name] = v
+				}
+			}
+		}
+	}
+	return serviceTimes, scanner.Err()
+}
+
+// infoFields are the fields of the single value lines of the info page.
+var infoFields = map[string]string{
+	"Number of clients accessing cache":    "clients",
+	"Maximum number of file descriptors":   "file_descriptors_max",
+	"Number of file desc currently in use": "file_descriptors_used",
+	"Number of HTTP requests received":     "http_requests_received",
+	"Mean Object Size":                     "mean_object_size_kb",
+}
+
+// parseNumber parses the first value as an integer or a float.
+func parseNumber(values []string) (interface{}, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+	if i, err := strconv.ParseInt(values[0], 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(values[0], 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+func parsePercent(value string) (float64, bool) {
+	if !strings.HasSuffix(value, "%") {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	return f, err == nil
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// snakeCase converts a label such as "HTTP Requests (All)" to a name such as
+// "http_requests_all".
+func snakeCase(label string) string {
+	var b strings.Builder
+	sep := false
+	for _, r := range strings.ToLower(label) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if sep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			sep = false
+			continue
+		}
+		sep = true
+	}
+	return b.String()
+}
+
+// Get tag(s) for the squid plugin
+func getTags(addr *url.URL) map[string]string {
+	h := addr.Host
+	host, port, err := net.SplitHostPort(h)
+	if err != nil {
+		host = addr.Host
+		if addr.Scheme == "http" {
+			port = "80"
+		} else if addr.Scheme == "https" {
+			port = "443"
+		} else {
+			port = ""
+		}
+	}
+	return map[string]string{"server": host, "port": port}
+}
+
+func init() {
+	inputs.Add("squid", func() telegraf.Input {
+		return &Squid{}
+	})
+}
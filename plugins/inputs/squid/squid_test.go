@@ -0,0 +1,211 @@
+package squid
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const countersOutput = `sample_time = 1583252125.123456 (Tue, 03 Mar 2020 16:15:25 GMT)
+client_http.requests = 1200
+client_http.hits = 300
+client_http.errors = 2
+client_http.kbytes_in = 512
+client_http.kbytes_out = 20480
+client_http.hit_kbytes_out = 4096
+server.all.requests = 900
+server.all.errors = 1
+server.all.kbytes_in = 16384
+server.all.kbytes_out = 450
+server.http.requests = 880
+server.http.errors = 1
+server.http.kbytes_in = 16000
+server.http.kbytes_out = 440
+server.ftp.requests = 20
+server.ftp.errors = 0
+server.ftp.kbytes_in = 384
+server.ftp.kbytes_out = 10
+cpu_time = 12.345678
+`
+
+const infoOutput = `Squid Object Cache: Version 4.10
+Build Info:
+Service Name: squid
+Start Time:	Tue, 03 Mar 2020 12:00:00 GMT
+Current Time:	Tue, 03 Mar 2020 16:15:25 GMT
+Connection information for squid:
+	Number of clients accessing cache:	5
+	Number of HTTP requests received:	1200
+	Average HTTP requests per minute since start:	4.7
+Cache information for squid:
+	Hits as % of all requests:	5min: 25.0%, 60min: 22.5%
+	Hits as % of bytes sent:	5min: 20.0%, 60min: 18.0%
+	Request Hit Ratios:	5min: 25.0%, 60min: 22.5%
+	Byte Hit Ratios:	5min: 20.0%, 60min: 18.0%
+	Request Memory Hit Ratios:	5min: 10.0%, 60min: 9.0%
+	Request Disk Hit Ratios:	5min: 15.0%, 60min: 13.5%
+	Storage Swap size:	102400 KB
+	Storage Swap capacity:	10.0% used, 90.0% free
+	Storage Mem size:	2048 KB
+	Storage Mem capacity:	 0.8% used, 99.2% free
+	Mean Object Size:	22.13 KB
+Median Service Times (seconds)  5 min    60 min:
+	HTTP Requests (All):   0.04519  0.03829
+	Cache Misses:          0.09736  0.08265
+	Cache Hits:            0.00091  0.00091
+	Near Hits:             0.00000  0.00000
+	Not-Modified Replies:  0.00000  0.00000
+	DNS Lookups:           0.00190  0.00190
+	ICP Queries:           0.00000  0.00000
+Resource usage for squid:
+	UP Time:	15325.123 seconds
+	CPU Time:	12.345 seconds
+File descriptor usage for squid:
+	Maximum number of file descriptors:   1024
+	Largest file desc currently in use:     20
+	Number of file desc currently in use:   15
+`
+
+func TestGather(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "telegraf" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/squid-internal-mgr/counters":
+			fmt.Fprint(w, countersOutput)
+		case "/squid-internal-mgr/info":
+			fmt.Fprint(w, infoOutput)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	addr, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	tags := getTags(addr)
+	withTag := func(k, v string) map[string]string {
+		t := map[string]string{k: v}
+		for k, v := range tags {
+			t[k] = v
+		}
+		return t
+	}
+
+	plugin := &Squid{
+		Urls:     []string{ts.URL + "/squid-internal-mgr"},
+		Username: "telegraf",
+		Password: "secret",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	serviceTime := func(typ string, median5, median60 float64) telegraf.Metric {
+		return testutil.MustMetric("squid_service_time",
+			withTag("type", typ),
+			map[string]interface{}{
+				"median_5min":  median5,
+				"median_60min": median60,
+			},
+			time.Unix(0, 0),
+		)
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("squid",
+			tags,
+			map[string]interface{}{
+				"client_http_requests":           int64(1200),
+				"client_http_hits":               int64(300),
+				"client_http_errors":             int64(2),
+				"client_http_kbytes_in":          int64(512),
+				"client_http_kbytes_out":         int64(20480),
+				"client_http_hit_kbytes_out":     int64(4096),
+				"cpu_time":                       12.345678,
+				"clients":                        int64(5),
+				"http_requests_received":         int64(1200),
+				"request_hit_ratio_5min":         25.0,
+				"request_hit_ratio_60min":        22.5,
+				"byte_hit_ratio_5min":            20.0,
+				"byte_hit_ratio_60min":           18.0,
+				"request_memory_hit_ratio_5min":  10.0,
+				"request_memory_hit_ratio_60min": 9.0,
+				"request_disk_hit_ratio_5min":    15.0,
+				"request_disk_hit_ratio_60min":   13.5,
+				"storage_swap_size_kb":           int64(102400),
+				"storage_swap_capacity_used":     10.0,
+				"storage_mem_size_kb":            int64(2048),
+				"storage_mem_capacity_used":      0.8,
+				"mean_object_size_kb":            22.13,
+				"file_descriptors_max":           int64(1024),
+				"file_descriptors_used":          int64(15),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("squid_server",
+			withTag("protocol", "all"),
+			map[string]interface{}{
+				"requests":   int64(900),
+				"errors":     int64(1),
+				"kbytes_in":  int64(16384),
+				"kbytes_out": int64(450),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("squid_server",
+			withTag("protocol", "http"),
+			map[string]interface{}{
+				"requests":   int64(880),
+				"errors":     int64(1),
+				"kbytes_in":  int64(16000),
+				"kbytes_out": int64(440),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("squid_server",
+			withTag("protocol", "ftp"),
+			map[string]interface{}{
+				"requests":   int64(20),
+				"errors":     int64(0),
+				"kbytes_in":  int64(384),
+				"kbytes_out": int64(10),
+			},
+			time.Unix(0, 0),
+		),
+		serviceTime("http_requests_all", 0.04519, 0.03829),
+		serviceTime("cache_misses", 0.09736, 0.08265),
+		serviceTime("cache_hits", 0.00091, 0.00091),
+		serviceTime("near_hits", 0, 0),
+		serviceTime("not_modified_replies", 0, 0),
+		serviceTime("dns_lookups", 0.0019, 0.0019),
+		serviceTime("icp_queries", 0, 0),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	plugin := &Squid{
+		Urls: []string{ts.URL + "/squid-internal-mgr"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(plugin.Gather))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
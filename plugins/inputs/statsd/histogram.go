@@ -0,0 +1,133 @@
+package statsd
+
+import (
+	"math"
+	"sort"
+)
+
+const defaultHistogramPrecision = 2
+
+// Histogram records values in logarithmically sized buckets, similar to an
+// HDR histogram, so that the memory used is bounded by the dynamic range of
+// the values instead of the number of values.  Quantiles are calculated with
+// a relative error of at most 10^-precision.
+//
+// The counts of values less than or equal to each of the configured bucket
+// bounds are kept exactly.
+type Histogram struct {
+	gamma     float64
+	logGamma  float64
+	positive  map[int]uint64
+	negative  map[int]uint64
+	zero      uint64
+	count     uint64
+	bounds    []float64
+	cumCounts []uint64
+}
+
+// NewHistogram returns a histogram with the given number of significant
+// decimal digits and bucket bounds.
+func NewHistogram(precision int, bounds []float64) *Histogram {
+	if precision <= 0 {
+		precision = defaultHistogramPrecision
+	}
+	alpha := math.Pow(10, -float64(precision))
+	gamma := (1 + alpha) / (1 - alpha)
+
+	sorted := make([]float64, len(bounds))
+	copy(sorted, bounds)
+	sort.Float64s(sorted)
+
+	return &Histogram{
+		gamma:     gamma,
+		logGamma:  math.Log(gamma),
+		positive:  make(map[int]uint64),
+		negative:  make(map[int]uint64),
+		bounds:    sorted,
+		cumCounts: make([]uint64, len(sorted)),
+	}
+}
+
+func (h *Histogram) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / h.logGamma))
+}
+
+// value returns the representative value of the bucket with the given index,
+// which is within the relative error of all values in the bucket.
+func (h *Histogram) value(i int) float64 {
+	return 2 * math.Pow(h.gamma, float64(i)) / (h.gamma + 1)
+}
+
+func (h *Histogram) AddValue(v float64) {
+	h.count++
+
+	for i := len(h.bounds) - 1; i >= 0 && v <= h.bounds[i]; i-- {
+		h.cumCounts[i]++
+	}
+
+	switch {
+	case v > 0:
+		h.positive[h.index(v)]++
+	case v < 0:
+		h.negative[h.index(-v)]++
+	default:
+		h.zero++
+	}
+}
+
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Buckets returns the bucket bounds and the cumulative number of values less
+// than or equal to each bound.
+func (h *Histogram) Buckets() ([]float64, []uint64) {
+	return h.bounds, h.cumCounts
+}
+
+// Percentile returns the estimated value of the nth percentile.
+func (h *Histogram) Percentile(n float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	if n > 100 {
+		n = 100
+	}
+
+	// Use the same rank as RunningStats for the exact values.
+	rank := uint64(clamp(float64(h.count)*n/float64(100), 0, int(h.count)-1))
+
+	var seen uint64
+	if len(h.negative) > 0 {
+		keys := sortedKeys(h.negative)
+		for i := len(keys) - 1; i >= 0; i-- {
+			seen += h.negative[keys[i]]
+			if seen > rank {
+				return -h.value(keys[i])
+			}
+		}
+	}
+
+	seen += h.zero
+	if seen > rank {
+		return 0
+	}
+
+	keys := sortedKeys(h.positive)
+	for _, k := range keys {
+		seen += h.positive[k]
+		if seen > rank {
+			return h.value(k)
+		}
+	}
+	return h.value(keys[len(keys)-1])
+}
+
+func sortedKeys(m map[int]uint64) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
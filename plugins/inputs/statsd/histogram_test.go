@@ -0,0 +1,52 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram_Percentile(t *testing.T) {
+	h := NewHistogram(2, nil)
+	for i := 1; i <= 1000; i++ {
+		h.AddValue(float64(i))
+	}
+
+	require.Equal(t, uint64(1000), h.Count())
+	require.InEpsilon(t, 501.0, h.Percentile(50), 0.02)
+	require.InEpsilon(t, 901.0, h.Percentile(90), 0.02)
+	require.InEpsilon(t, 1000.0, h.Percentile(100), 0.02)
+	require.InEpsilon(t, 1.0, h.Percentile(0), 0.02)
+}
+
+func TestHistogram_NegativeAndZero(t *testing.T) {
+	h := NewHistogram(3, nil)
+	for _, v := range []float64{-10, -1, 0, 0, 5} {
+		h.AddValue(v)
+	}
+
+	require.InEpsilon(t, -10.0, h.Percentile(0), 0.002)
+	require.InEpsilon(t, -1.0, h.Percentile(20), 0.002)
+	require.Equal(t, 0.0, h.Percentile(50))
+	require.InEpsilon(t, 5.0, h.Percentile(100), 0.002)
+}
+
+func TestHistogram_Buckets(t *testing.T) {
+	h := NewHistogram(2, []float64{100, 1, 10})
+	for _, v := range []float64{0.5, 1, 2, 10, 50, 500} {
+		h.AddValue(v)
+	}
+
+	bounds, counts := h.Buckets()
+	require.Equal(t, []float64{1, 10, 100}, bounds)
+	require.Equal(t, []uint64{2, 4, 5}, counts)
+}
+
+// Test that the memory used is bounded by the range of the values
+func TestHistogram_BoundedBuckets(t *testing.T) {
+	h := NewHistogram(2, nil)
+	for i := 0; i < 100000; i++ {
+		h.AddValue(float64(i%1000 + 1))
+	}
+	require.True(t, len(h.positive) < 400)
+}
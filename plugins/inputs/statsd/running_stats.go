@@ -24,6 +24,9 @@ type RunningStats struct {
 	perc      []float64
 	PercLimit int
 
+	// Histogram used instead of perc to calculate percentiles if set.
+	Hist *Histogram
+
 	sum float64
 
 	lower float64
@@ -45,7 +48,9 @@ func (rs *RunningStats) AddValue(v float64) {
 		if rs.PercLimit == 0 {
 			rs.PercLimit = defaultPercentileLimit
 		}
-		rs.perc = make([]float64, 0, rs.PercLimit)
+		if rs.Hist == nil {
+			rs.perc = make([]float64, 0, rs.PercLimit)
+		}
 	}
 
 	// These are used for the running mean and variance
@@ -63,7 +68,9 @@ func (rs *RunningStats) AddValue(v float64) {
 		rs.lower = v
 	}
 
-	if len(rs.perc) < rs.PercLimit {
+	if rs.Hist != nil {
+		rs.Hist.AddValue(v)
+	} else if len(rs.perc) < rs.PercLimit {
 		rs.perc = append(rs.perc, v)
 	} else {
 		// Reached limit, choose random index to overwrite in the percentile array
@@ -104,6 +111,10 @@ func (rs *RunningStats) Percentile(n float64) float64 {
 		n = 100
 	}
 
+	if rs.Hist != nil {
+		return rs.Hist.Percentile(n)
+	}
+
 	if !rs.sorted {
 		sort.Float64s(rs.perc)
 		rs.sorted = true
@@ -50,6 +50,12 @@ type Statsd struct {
 	Percentiles     []internal.Number
 	PercentileLimit int
 
+	// TimingHistogram enables recording timings in histograms instead of
+	// keeping a limited number of samples for the percentiles.
+	TimingHistogram          bool              `toml:"timing_histogram"`
+	TimingHistogramPrecision int               `toml:"timing_histogram_precision"`
+	TimingHistogramBuckets   []internal.Number `toml:"timing_histogram_buckets"`
+
 	DeleteGauges   bool
 	DeleteCounters bool
 	DeleteSets     bool
@@ -241,6 +247,17 @@ const sampleConfig = `
   ## calculation of percentiles. Raising this limit increases the accuracy
   ## of percentiles but also increases the memory usage and cpu time.
   percentile_limit = 1000
+
+  ## Record timing/histogram values in HDR-style histograms instead of
+  ## sampling them, bounding the memory usage under high rates of timings.
+  ## Percentiles are calculated with a relative error of at most
+  ## 10^-timing_histogram_precision and percentile_limit is ignored.
+  # timing_histogram = false
+  # timing_histogram_precision = 2
+
+  ## Upper bounds of buckets counting the values less than or equal to the
+  ## bound, added as "<bound>_bucket" fields when timing_histogram is enabled.
+  # timing_histogram_buckets = [10.0, 100.0, 1000.0]
 `
 
 func (_ *Statsd) SampleConfig() string {
@@ -272,6 +289,13 @@ func (s *Statsd) Gather(acc telegraf.Accumulator) error {
 				name := fmt.Sprintf("%s%v_percentile", prefix, percentile.Value)
 				fields[name] = stats.Percentile(percentile.Value)
 			}
+			if stats.Hist != nil {
+				bounds, counts := stats.Hist.Buckets()
+				for i, bound := range bounds {
+					name := fmt.Sprintf("%s%v_bucket", prefix, bound)
+					fields[name] = int64(counts[i])
+				}
+			}
 		}
 
 		acc.AddFields(m.name, fields, m.tags, now)
@@ -726,6 +750,15 @@ func parseKeyValue(keyvalue string) (string, string) {
 	return key, val
 }
 
+// histogramBounds returns the configured bucket bounds of timing histograms.
+func (s *Statsd) histogramBounds() []float64 {
+	bounds := make([]float64, 0, len(s.TimingHistogramBuckets))
+	for _, b := range s.TimingHistogramBuckets {
+		bounds = append(bounds, b.Value)
+	}
+	return bounds
+}
+
 // aggregate takes in a metric. It then
 // aggregates and caches the current value(s). It does not deal with the
 // Delete* options, because those are dealt with in the Gather function.
@@ -748,6 +781,9 @@ func (s *Statsd) aggregate(m metric) {
 			field = RunningStats{
 				PercLimit: s.PercentileLimit,
 			}
+			if s.TimingHistogram {
+				field.Hist = NewHistogram(s.TimingHistogramPrecision, s.histogramBounds())
+			}
 		}
 		if m.samplerate > 0 {
 			for i := 0; i < int(1.0/m.samplerate); i++ {
@@ -437,6 +437,41 @@ func TestParse_Timings(t *testing.T) {
 	acc.AssertContainsFields(t, "test_timing", valid)
 }
 
+func TestParse_TimingHistogram(t *testing.T) {
+	s := NewTestStatsd()
+	s.Percentiles = []internal.Number{{Value: 50.0}, {Value: 90.0}}
+	s.TimingHistogram = true
+	s.TimingHistogramBuckets = []internal.Number{{Value: 10.0}, {Value: 1.0}}
+	acc := &testutil.Accumulator{}
+
+	validLines := []string{
+		"test.timing:1|ms",
+		"test.timing:11|ms",
+		"test.timing:1|ms",
+		"test.timing:1|ms",
+		"test.timing:1|ms",
+	}
+
+	for _, line := range validLines {
+		err := s.parseStatsdLine(line)
+		if err != nil {
+			t.Errorf("Parsing line %s should not have resulted in an error\n", line)
+		}
+	}
+
+	s.Gather(acc)
+
+	m, ok := acc.Get("test_timing")
+	require.True(t, ok)
+	require.Equal(t, int64(5), m.Fields["count"])
+	require.Equal(t, float64(3), m.Fields["mean"])
+	require.Equal(t, float64(11), m.Fields["upper"])
+	require.Equal(t, int64(4), m.Fields["1_bucket"])
+	require.Equal(t, int64(4), m.Fields["10_bucket"])
+	require.InEpsilon(t, 1.0, m.Fields["50_percentile"], 0.02)
+	require.InEpsilon(t, 11.0, m.Fields["90_percentile"], 0.02)
+}
+
 func TestParseScientificNotation(t *testing.T) {
 	s := NewTestStatsd()
 	sciNotationLines := []string{
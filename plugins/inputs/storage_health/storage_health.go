@@ -0,0 +1,324 @@
+package storage_health
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+const sampleConfig = `
+  ## Collect the MD RAID arrays of /proc/mdstat.
+  # mdstat = true
+
+  ## Collect the thin pools and caches of device-mapper, such as LVM thin
+  ## pools and LVM caches, with "dmsetup status", which must run as root.
+  # device_mapper = false
+
+  ## Run dmsetup with sudo, which must be configured to not ask for a
+  ## password.
+  # use_sudo = false
+
+  ## Timeout for dmsetup to complete.
+  # timeout = "5s"
+`
+
+// StorageHealth gathers the health of the MD RAID arrays and of the
+// device-mapper thin pools and caches.
+type StorageHealth struct {
+	Mdstat       bool              `toml:"mdstat"`
+	DeviceMapper bool              `toml:"device_mapper"`
+	UseSudo      bool              `toml:"use_sudo"`
+	Timeout      internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	procPath string
+	dmsetup  func(timeout internal.Duration, useSudo bool) ([]byte, error)
+}
+
+func (s *StorageHealth) Description() string {
+	return "Gather the health of MD RAID arrays, LVM thin pools and dm-cache devices"
+}
+
+func (s *StorageHealth) SampleConfig() string {
+	return sampleConfig
+}
+
+var (
+	mdHeaderRegex = regexp.MustCompile(`^(md\S+)\s*:\s*(\S+)\s*(.*)$`)
+	mdStatusRegex = regexp.MustCompile(`(\d+) blocks.*\[(\d+)/(\d+)\]\s+\[([U_]+)\]`)
+	mdBlocksRegex = regexp.MustCompile(`^\s*(\d+) blocks`)
+	mdSyncRegex   = regexp.MustCompile(`(resync|recovery|reshape|check|repair)\s*=\s*([\d.]+)%\s*\(\d+/\d+\)\s*finish=([\d.]+)min\s*speed=(\d+)K/sec`)
+	mdDelayRegex  = regexp.MustCompile(`(resync|recovery|reshape|check|repair)\s*=\s*(DELAYED|PENDING)`)
+)
+
+// mdArray is an array of /proc/mdstat.
+type mdArray struct {
+	device string
+	state  string
+	level  string
+	fields map[string]interface{}
+}
+
+// parseMdstat returns the arrays of the content of /proc/mdstat.
+func parseMdstat(data []byte) []*mdArray {
+	var arrays []*mdArray
+	var array *mdArray
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := mdHeaderRegex.FindStringSubmatch(line); match != nil {
+			array = &mdArray{
+				device: match[1],
+				state:  match[2],
+				fields: map[string]interface{}{
+					"active":       match[2] == "active",
+					"sync_action":  "idle",
+					"sync_percent": 100.0,
+				},
+			}
+			arrays = append(arrays, array)
+			parseMdMembers(array, strings.Fields(match[3]))
+			continue
+		}
+		if array == nil || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if match := mdStatusRegex.FindStringSubmatch(line); match != nil {
+			blocks, _ := strconv.ParseInt(match[1], 10, 64)
+			total, _ := strconv.ParseInt(match[2], 10, 64)
+			active, _ := strconv.ParseInt(match[3], 10, 64)
+			array.fields["size_bytes"] = blocks * 1024
+			array.fields["disks_total"] = total
+			array.fields["disks_active"] = active
+			array.fields["disks_down"] = int64(strings.Count(match[4], "_"))
+			array.fields["degraded"] = active < total
+		} else if match := mdBlocksRegex.FindStringSubmatch(line); match != nil {
+			// Linear and raid0 arrays have no redundancy status
+			blocks, _ := strconv.ParseInt(match[1], 10, 64)
+			array.fields["size_bytes"] = blocks * 1024
+		}
+
+		if match := mdSyncRegex.FindStringSubmatch(line); match != nil {
+			percent, _ := strconv.ParseFloat(match[2], 64)
+			finish, _ := strconv.ParseFloat(match[3], 64)
+			speed, _ := strconv.ParseInt(match[4], 10, 64)
+			array.fields["sync_action"] = match[1]
+			array.fields["sync_percent"] = percent
+			array.fields["sync_finish_seconds"] = int64(finish * 60)
+			array.fields["sync_speed_bytes"] = speed * 1024
+		} else if match := mdDelayRegex.FindStringSubmatch(line); match != nil {
+			array.fields["sync_action"] = match[1]
+			array.fields["sync_percent"] = 0.0
+		}
+	}
+	return arrays
+}
+
+// parseMdMembers sets the level and counts the member states of the array
+// header, such as "raid1 sdb1[1] sda1[0](F)".
+func parseMdMembers(array *mdArray, parts []string) {
+	var failed, spare int64
+	for _, part := range parts {
+		switch {
+		case strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")"):
+			// "(auto-read-only)" or "(read-only)"
+			array.fields["read_only"] = true
+		case strings.Contains(part, "["):
+			if strings.HasSuffix(part, "(F)") {
+				failed++
+			} else if strings.HasSuffix(part, "(S)") {
+				spare++
+			}
+		case array.level == "":
+			array.level = part
+		}
+	}
+	array.fields["disks_failed"] = failed
+	array.fields["disks_spare"] = spare
+}
+
+// dmTarget is a thin-pool or cache target of the dmsetup status output.
+type dmTarget struct {
+	device string
+	target string
+	fields map[string]interface{}
+}
+
+// parseDmsetup returns the thin-pool and cache targets of the output of
+// "dmsetup status", which has lines like
+// "vg-pool-tpool: 0 209715200 thin-pool 1 1234/16384 5678/102400 - rw ...".
+func parseDmsetup(data []byte) ([]*dmTarget, error) {
+	var targets []*dmTarget
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 5 || !strings.HasSuffix(parts[0], ":") {
+			continue
+		}
+		t := &dmTarget{
+			device: strings.TrimSuffix(parts[0], ":"),
+			target: parts[3],
+		}
+
+		var err error
+		switch t.target {
+		case "thin-pool":
+			t.fields, err = parseThinPool(parts[4:])
+		case "cache":
+			t.fields, err = parseCache(parts[4:])
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing status of %s failed: %v", t.device, err)
+		}
+		targets = append(targets, t)
+	}
+	return targets, scanner.Err()
+}
+
+// parseThinPool parses the status of a thin-pool target:
+// <transaction id> <used metadata blocks>/<total metadata blocks>
+// <used data blocks>/<total data blocks> <held metadata root>
+// ro|rw|out_of_data_space [no_]discard_passdown [error|queue]_if_no_space
+// needs_check|- [metadata_low_watermark]
+func parseThinPool(values []string) (map[string]interface{}, error) {
+	if values[0] == "Fail" || values[0] == "Error" {
+		return map[string]interface{}{"failed": true}, nil
+	}
+	if len(values) < 5 {
+		return nil, fmt.Errorf("unexpected thin-pool status %q", strings.Join(values, " "))
+	}
+
+	fields := map[string]interface{}{"failed": false}
+	transactionID, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	fields["transaction_id"] = transactionID
+	if err := addUsage(fields, "metadata", values[1]); err != nil {
+		return nil, err
+	}
+	if err := addUsage(fields, "data", values[2]); err != nil {
+		return nil, err
+	}
+	fields["mode"] = values[4]
+	if len(values) > 7 {
+		fields["needs_check"] = values[7] == "needs_check"
+	}
+	return fields, nil
+}
+
+// parseCache parses the status of a cache target:
+// <metadata block size> <used metadata blocks>/<total metadata blocks>
+// <cache block size> <used cache blocks>/<total cache blocks> <read hits>
+// <read misses> <write hits> <write misses> <demotions> <promotions>
+// <dirty> <#features> <features>* <#core args> <core args>* <policy name>
+// <#policy args> <policy args>* <metadata mode> <needs_check>
+func parseCache(values []string) (map[string]interface{}, error) {
+	if values[0] == "Fail" || values[0] == "Error" {
+		return map[string]interface{}{"failed": true}, nil
+	}
+	if len(values) < 11 {
+		return nil, fmt.Errorf("unexpected cache status %q", strings.Join(values, " "))
+	}
+
+	fields := map[string]interface{}{"failed": false}
+	if err := addUsage(fields, "metadata", values[1]); err != nil {
+		return nil, err
+	}
+	if err := addUsage(fields, "cache", values[3]); err != nil {
+		return nil, err
+	}
+
+	counters := make([]int64, 7)
+	for i, name := range []string{"read_hits", "read_misses", "write_hits", "write_misses", "demotions", "promotions", "dirty"} {
+		v, err := strconv.ParseInt(values[4+i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = v
+		counters[i] = v
+	}
+	if reads := counters[0] + counters[1]; reads > 0 {
+		fields["read_hit_percent"] = 100 * float64(counters[0]) / float64(reads)
+	}
+	if writes := counters[2] + counters[3]; writes > 0 {
+		fields["write_hit_percent"] = 100 * float64(counters[2]) / float64(writes)
+	}
+
+	// The features, core arguments and policy arguments are prefixed with
+	// their count
+	features, rest, ok := countedArgs(values[11:])
+	if !ok {
+		return fields, nil
+	}
+	for _, feature := range features {
+		switch feature {
+		case "writeback", "writethrough", "passthrough":
+			fields["io_mode"] = feature
+		}
+	}
+	_, rest, ok = countedArgs(rest)
+	if !ok || len(rest) == 0 {
+		return fields, nil
+	}
+	fields["policy"] = rest[0]
+	_, rest, ok = countedArgs(rest[1:])
+	if !ok {
+		return fields, nil
+	}
+	if len(rest) > 0 {
+		fields["mode"] = rest[0]
+	}
+	if len(rest) > 1 {
+		fields["needs_check"] = rest[1] == "needs_check"
+	}
+	return fields, nil
+}
+
+// countedArgs splits the arguments prefixed with their count from the rest
+// of the values.
+func countedArgs(values []string) ([]string, []string, bool) {
+	if len(values) == 0 {
+		return nil, nil, false
+	}
+	n, err := strconv.Atoi(values[0])
+	if err != nil || n < 0 || 1+n > len(values) {
+		return nil, nil, false
+	}
+	return values[1 : 1+n], values[1+n:], true
+}
+
+// addUsage adds the used, total and used percent fields of a usage such as
+// "1234/16384".
+func addUsage(fields map[string]interface{}, prefix, usage string) error {
+	parts := strings.SplitN(usage, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("unexpected %s usage %q", prefix, usage)
+	}
+	used, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	total, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	fields[prefix+"_used_blocks"] = used
+	fields[prefix+"_total_blocks"] = total
+	if total > 0 {
+		fields[prefix+"_used_percent"] = 100 * float64(used) / float64(total)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// +build linux
+
+package storage_health
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (s *StorageHealth) Gather(acc telegraf.Accumulator) error {
+	if s.Mdstat {
+		if err := s.gatherMdstat(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	if s.DeviceMapper {
+		if err := s.gatherDeviceMapper(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func (s *StorageHealth) gatherMdstat(acc telegraf.Accumulator) error {
+	data, err := ioutil.ReadFile(filepath.Join(s.procPath, "mdstat"))
+	if err != nil {
+		// The md driver is not loaded
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, array := range parseMdstat(data) {
+		tags := map[string]string{
+			"device": array.device,
+			"state":  array.state,
+		}
+		if array.level != "" {
+			tags["level"] = array.level
+		}
+		acc.AddGauge("md_raid", array.fields, tags)
+	}
+	return nil
+}
+
+func (s *StorageHealth) gatherDeviceMapper(acc telegraf.Accumulator) error {
+	out, err := s.dmsetup(s.Timeout, s.UseSudo)
+	if err != nil {
+		return err
+	}
+
+	targets, err := parseDmsetup(out)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		tags := map[string]string{"device": t.device}
+		switch t.target {
+		case "thin-pool":
+			acc.AddGauge("dm_thin_pool", t.fields, tags)
+		case "cache":
+			acc.AddFields("dm_cache", t.fields, tags)
+		}
+	}
+	return nil
+}
+
+func runDmsetup(timeout internal.Duration, useSudo bool) ([]byte, error) {
+	bin, err := exec.LookPath("dmsetup")
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"status"}
+	if useSudo {
+		args = append([]string{"-n", bin}, args...)
+		bin = "sudo"
+	}
+
+	cmd := exec.Command(bin, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, timeout.Duration); err != nil {
+		return nil, fmt.Errorf("running dmsetup failed: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+func init() {
+	inputs.Add("storage_health", func() telegraf.Input {
+		procPath := "/proc"
+		if hostProc := os.Getenv("HOST_PROC"); hostProc != "" {
+			procPath = hostProc
+		}
+		return &StorageHealth{
+			Mdstat:   true,
+			Timeout:  internal.Duration{Duration: 5 * time.Second},
+			procPath: procPath,
+			dmsetup:  runDmsetup,
+		}
+	})
+}
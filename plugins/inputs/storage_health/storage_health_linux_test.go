@@ -0,0 +1,199 @@
+// +build linux
+
+package storage_health
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherMdstat(t *testing.T) {
+	plugin := &StorageHealth{Mdstat: true, procPath: "testdata"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("md_raid",
+			map[string]string{"device": "md1", "state": "active", "level": "raid1"},
+			map[string]interface{}{
+				"active":       true,
+				"size_bytes":   int64(1048512 * 1024),
+				"disks_total":  int64(2),
+				"disks_active": int64(2),
+				"disks_down":   int64(0),
+				"disks_failed": int64(0),
+				"disks_spare":  int64(0),
+				"degraded":     false,
+				"sync_action":  "idle",
+				"sync_percent": 100.0,
+			},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric("md_raid",
+			map[string]string{"device": "md0", "state": "active", "level": "raid5"},
+			map[string]interface{}{
+				"active":              true,
+				"size_bytes":          int64(3906764800 * 1024),
+				"disks_total":         int64(3),
+				"disks_active":        int64(2),
+				"disks_down":          int64(1),
+				"disks_failed":        int64(1),
+				"disks_spare":         int64(1),
+				"degraded":            true,
+				"sync_action":         "recovery",
+				"sync_percent":        12.6,
+				"sync_finish_seconds": int64(10212),
+				"sync_speed_bytes":    int64(167116 * 1024),
+			},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric("md_raid",
+			map[string]string{"device": "md2", "state": "active", "level": "raid1"},
+			map[string]interface{}{
+				"active":       true,
+				"read_only":    true,
+				"size_bytes":   int64(524224 * 1024),
+				"disks_total":  int64(2),
+				"disks_active": int64(2),
+				"disks_down":   int64(0),
+				"disks_failed": int64(0),
+				"disks_spare":  int64(0),
+				"degraded":     false,
+				"sync_action":  "resync",
+				"sync_percent": 0.0,
+			},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric("md_raid",
+			map[string]string{"device": "md3", "state": "active", "level": "raid0"},
+			map[string]interface{}{
+				"active":       true,
+				"size_bytes":   int64(2096128 * 1024),
+				"disks_failed": int64(0),
+				"disks_spare":  int64(0),
+				"sync_action":  "idle",
+				"sync_percent": 100.0,
+			},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric("md_raid",
+			map[string]string{"device": "md4", "state": "inactive"},
+			map[string]interface{}{
+				"active":       false,
+				"size_bytes":   int64(1048512 * 1024),
+				"disks_failed": int64(0),
+				"disks_spare":  int64(1),
+				"sync_action":  "idle",
+				"sync_percent": 100.0,
+			},
+			time.Unix(0, 0), telegraf.Gauge),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherMdstatMissing(t *testing.T) {
+	plugin := &StorageHealth{Mdstat: true, procPath: "testdata/missing"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Empty(t, acc.Metrics)
+}
+
+func TestGatherDeviceMapper(t *testing.T) {
+	out, err := ioutil.ReadFile("testdata/dmsetup")
+	require.NoError(t, err)
+
+	plugin := &StorageHealth{
+		DeviceMapper: true,
+		dmsetup: func(internal.Duration, bool) ([]byte, error) {
+			return out, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("dm_thin_pool",
+			map[string]string{"device": "vg0-pool-tpool"},
+			map[string]interface{}{
+				"failed":                false,
+				"transaction_id":        int64(3),
+				"metadata_used_blocks":  int64(1234),
+				"metadata_total_blocks": int64(16384),
+				"metadata_used_percent": 100 * 1234.0 / 16384.0,
+				"data_used_blocks":      int64(92160),
+				"data_total_blocks":     int64(102400),
+				"data_used_percent":     90.0,
+				"mode":                  "rw",
+				"needs_check":           false,
+			},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric("dm_thin_pool",
+			map[string]string{"device": "vg0-broken-tpool"},
+			map[string]interface{}{"failed": true},
+			time.Unix(0, 0), telegraf.Gauge),
+		testutil.MustMetric("dm_cache",
+			map[string]string{"device": "vg0-data"},
+			map[string]interface{}{
+				"failed":                false,
+				"metadata_used_blocks":  int64(1018),
+				"metadata_total_blocks": int64(1501122),
+				"metadata_used_percent": 100 * 1018.0 / 1501122.0,
+				"cache_used_blocks":     int64(7),
+				"cache_total_blocks":    int64(464962),
+				"cache_used_percent":    100 * 7.0 / 464962.0,
+				"read_hits":             int64(139),
+				"read_misses":           int64(352643),
+				"write_hits":            int64(15),
+				"write_misses":          int64(46),
+				"demotions":             int64(0),
+				"promotions":            int64(7),
+				"dirty":                 int64(0),
+				"read_hit_percent":      100 * 139.0 / 352782.0,
+				"write_hit_percent":     100 * 15.0 / 61.0,
+				"io_mode":               "writeback",
+				"policy":                "smq",
+				"mode":                  "rw",
+				"needs_check":           false,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("dm_cache",
+			map[string]string{"device": "vg0-old"},
+			map[string]interface{}{
+				"failed":                false,
+				"metadata_used_blocks":  int64(72352),
+				"metadata_total_blocks": int64(1310720),
+				"metadata_used_percent": 100 * 72352.0 / 1310720.0,
+				"cache_used_blocks":     int64(26),
+				"cache_total_blocks":    int64(24327168),
+				"cache_used_percent":    100 * 26.0 / 24327168.0,
+				"read_hits":             int64(2409),
+				"read_misses":           int64(286),
+				"write_hits":            int64(265),
+				"write_misses":          int64(524682),
+				"demotions":             int64(0),
+				"promotions":            int64(0),
+				"dirty":                 int64(0),
+				"read_hit_percent":      100 * 2409.0 / 2695.0,
+				"write_hit_percent":     100 * 265.0 / 524947.0,
+				"io_mode":               "writethrough",
+				"policy":                "smq",
+				"mode":                  "ro",
+				"needs_check":           true,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestParseDmsetupInvalid(t *testing.T) {
+	_, err := parseDmsetup([]byte("vg0-pool-tpool: 0 209715200 thin-pool 3 1234 92160/102400 - rw\n"))
+	require.Error(t, err)
+}
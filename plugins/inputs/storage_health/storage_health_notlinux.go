@@ -0,0 +1,23 @@
+// +build !linux
+
+package storage_health
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (s *StorageHealth) Init() error {
+	s.Log.Warn("Current platform is not supported")
+	return nil
+}
+
+func (s *StorageHealth) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("storage_health", func() telegraf.Input {
+		return &StorageHealth{}
+	})
+}
@@ -0,0 +1,78 @@
+// +build linux
+
+package tc
+
+import (
+	"bytes"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Commands of the bpf system call
+const (
+	bpfProgGetFDByID  = 13
+	bpfObjGetInfoByFD = 15
+)
+
+// Offsets of the struct bpf_prog_info fields, the run counters were added
+// in Linux 5.1.
+const (
+	bpfProgInfoName      = 64
+	bpfProgInfoRunTimeNs = 192
+	bpfProgInfoRunCnt    = 200
+	sizeofBpfProgInfo    = 208
+)
+
+// bpfProgInfo is the information of a loaded BPF program.
+type bpfProgInfo struct {
+	name     string
+	runTime  uint64
+	runCount uint64
+	hasStats bool
+}
+
+// progInfo returns the information of the BPF program, which requires the
+// CAP_SYS_ADMIN capability.
+func progInfo(id uint32) (*bpfProgInfo, error) {
+	getFD := struct {
+		progID    uint32
+		nextID    uint32
+		openFlags uint32
+	}{progID: id}
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, bpfProgGetFDByID, uintptr(unsafe.Pointer(&getFD)), unsafe.Sizeof(getFD))
+	if errno != 0 {
+		return nil, errno
+	}
+	defer unix.Close(int(fd))
+
+	// Older kernels accept a larger info as long as the tail is zero
+	info := make([]byte, sizeofBpfProgInfo)
+	getInfo := struct {
+		fd      uint32
+		infoLen uint32
+		info    uint64
+	}{
+		fd:      uint32(fd),
+		infoLen: uint32(len(info)),
+		info:    uint64(uintptr(unsafe.Pointer(&info[0]))),
+	}
+	_, _, errno = unix.Syscall(unix.SYS_BPF, bpfObjGetInfoByFD, uintptr(unsafe.Pointer(&getInfo)), unsafe.Sizeof(getInfo))
+	runtime.KeepAlive(info)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	name := info[bpfProgInfoName : bpfProgInfoName+16]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	prog := &bpfProgInfo{name: string(name)}
+	if getInfo.infoLen >= sizeofBpfProgInfo {
+		prog.runTime = nativeEndian.Uint64(info[bpfProgInfoRunTimeNs:])
+		prog.runCount = nativeEndian.Uint64(info[bpfProgInfoRunCnt:])
+		prog.hasStats = true
+	}
+	return prog, nil
+}
@@ -0,0 +1,233 @@
+// +build linux
+
+package tc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Netlink constants of the linux/rtnetlink.h, linux/gen_stats.h and
+// linux/if_link.h headers missing from the syscall package
+const (
+	tcaKind   = 1
+	tcaStats  = 3
+	tcaStats2 = 7
+
+	tcaStatsBasic = 1
+	tcaStatsQueue = 3
+
+	iflaXDP          = 43
+	iflaXDPAttached  = 2
+	iflaXDPProgID    = 4
+	iflaXDPDrvProgID = 5
+	iflaXDPSkbProgID = 6
+	iflaXDPHwProgID  = 7
+
+	nlaTypeMask = 0x3FFF
+
+	sizeofTcmsg     = 20
+	sizeofIfInfomsg = syscall.SizeofIfInfomsg
+)
+
+var nativeEndian binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	i := uint16(1)
+	if (*[2]byte)(unsafe.Pointer(&i))[0] == 0 {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// netlinkDump sends a dump request of the message type with the header to
+// the route netlink socket and returns the payloads of the replies.
+func netlinkDump(typ uint16, header []byte) ([][]byte, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	sa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
+	if err := syscall.Bind(fd, sa); err != nil {
+		return nil, err
+	}
+
+	req := make([]byte, syscall.NLMSG_HDRLEN+len(header))
+	nativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	nativeEndian.PutUint16(req[4:6], typ)
+	nativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	nativeEndian.PutUint32(req[8:12], 1)
+	copy(req[syscall.NLMSG_HDRLEN:], header)
+	if err := syscall.Sendto(fd, req, 0, sa); err != nil {
+		return nil, err
+	}
+
+	var payloads [][]byte
+	buf := make([]byte, 8*os.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return payloads, nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return nil, fmt.Errorf("invalid netlink error message")
+				}
+				if errno := int32(nativeEndian.Uint32(m.Data[:4])); errno != 0 {
+					return nil, syscall.Errno(-errno)
+				}
+				return payloads, nil
+			}
+			// The buffer is reused for the next messages
+			payloads = append(payloads, append([]byte(nil), m.Data...))
+		}
+	}
+}
+
+// parseAttrs returns the netlink attributes of the data by type.
+func parseAttrs(data []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(data) >= syscall.SizeofRtAttr {
+		length := int(nativeEndian.Uint16(data[0:2]))
+		typ := nativeEndian.Uint16(data[2:4]) & nlaTypeMask
+		if length < syscall.SizeofRtAttr || length > len(data) {
+			break
+		}
+		attrs[typ] = data[syscall.SizeofRtAttr:length]
+
+		length = (length + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if length > len(data) {
+			break
+		}
+		data = data[length:]
+	}
+	return attrs
+}
+
+// tcStats are the statistics of a qdisc or class.
+type tcStats struct {
+	bytes      uint64
+	packets    uint64
+	drops      uint64
+	overlimits uint64
+	requeues   uint64
+	qlen       uint64
+	backlog    uint64
+}
+
+// tcObject is a qdisc or class of a RTM_NEWQDISC or RTM_NEWTCLASS message.
+type tcObject struct {
+	ifindex int32
+	handle  uint32
+	parent  uint32
+	kind    string
+	stats   *tcStats
+}
+
+// parseTcmsg parses the tcmsg header and attributes of the message.
+func parseTcmsg(data []byte) (*tcObject, error) {
+	if len(data) < sizeofTcmsg {
+		return nil, fmt.Errorf("tc message too short: %d bytes", len(data))
+	}
+	obj := &tcObject{
+		ifindex: int32(nativeEndian.Uint32(data[4:8])),
+		handle:  nativeEndian.Uint32(data[8:12]),
+		parent:  nativeEndian.Uint32(data[12:16]),
+	}
+
+	attrs := parseAttrs(data[sizeofTcmsg:])
+	obj.kind = strings.TrimRight(string(attrs[tcaKind]), "\x00")
+
+	if stats2, ok := attrs[tcaStats2]; ok {
+		obj.stats = &tcStats{}
+		nested := parseAttrs(stats2)
+		// struct gnet_stats_basic
+		if basic := nested[tcaStatsBasic]; len(basic) >= 12 {
+			obj.stats.bytes = nativeEndian.Uint64(basic[0:8])
+			obj.stats.packets = uint64(nativeEndian.Uint32(basic[8:12]))
+		}
+		// struct gnet_stats_queue
+		if queue := nested[tcaStatsQueue]; len(queue) >= 20 {
+			obj.stats.qlen = uint64(nativeEndian.Uint32(queue[0:4]))
+			obj.stats.backlog = uint64(nativeEndian.Uint32(queue[4:8]))
+			obj.stats.drops = uint64(nativeEndian.Uint32(queue[8:12]))
+			obj.stats.requeues = uint64(nativeEndian.Uint32(queue[12:16]))
+			obj.stats.overlimits = uint64(nativeEndian.Uint32(queue[16:20]))
+		}
+	} else if stats := attrs[tcaStats]; len(stats) >= 36 {
+		// struct tc_stats of older kernels
+		obj.stats = &tcStats{
+			bytes:      nativeEndian.Uint64(stats[0:8]),
+			packets:    uint64(nativeEndian.Uint32(stats[8:12])),
+			drops:      uint64(nativeEndian.Uint32(stats[12:16])),
+			overlimits: uint64(nativeEndian.Uint32(stats[16:20])),
+			qlen:       uint64(nativeEndian.Uint32(stats[28:32])),
+			backlog:    uint64(nativeEndian.Uint32(stats[32:36])),
+		}
+	}
+	return obj, nil
+}
+
+// xdpProgram is a XDP program attached to an interface.
+type xdpProgram struct {
+	ifindex int32
+	mode    string
+	id      uint32
+}
+
+// xdpModes are the names of the XDP_ATTACHED values.
+var xdpModes = map[uint8]string{
+	1: "drv",
+	2: "skb",
+	3: "hw",
+}
+
+// parseIfInfomsg returns the XDP programs of the RTM_NEWLINK message.
+func parseIfInfomsg(data []byte) ([]*xdpProgram, error) {
+	if len(data) < sizeofIfInfomsg {
+		return nil, fmt.Errorf("link message too short: %d bytes", len(data))
+	}
+	ifindex := int32(nativeEndian.Uint32(data[4:8]))
+
+	attrs := parseAttrs(data[sizeofIfInfomsg:])
+	xdp, ok := attrs[iflaXDP]
+	if !ok {
+		return nil, nil
+	}
+	nested := parseAttrs(xdp)
+	attached := nested[iflaXDPAttached]
+	if len(attached) < 1 || attached[0] == 0 {
+		return nil, nil
+	}
+
+	// Programs can be attached in several modes at the same time
+	var programs []*xdpProgram
+	for typ, mode := range map[uint16]string{
+		iflaXDPDrvProgID: "drv",
+		iflaXDPSkbProgID: "skb",
+		iflaXDPHwProgID:  "hw",
+	} {
+		if id := nested[typ]; len(id) >= 4 {
+			programs = append(programs, &xdpProgram{ifindex: ifindex, mode: mode, id: nativeEndian.Uint32(id)})
+		}
+	}
+	if len(programs) == 0 {
+		if id := nested[iflaXDPProgID]; len(id) >= 4 {
+			programs = append(programs, &xdpProgram{ifindex: ifindex, mode: xdpModes[attached[0]], id: nativeEndian.Uint32(id)})
+		}
+	}
+	return programs, nil
+}
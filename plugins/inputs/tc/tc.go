@@ -0,0 +1,65 @@
+package tc
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+)
+
+const sampleConfig = `
+  ## Interfaces to collect, globs are supported.  All interfaces are collected
+  ## by default.
+  # interfaces = []
+
+  ## Collect the statistics of the classes of classful qdiscs, such as htb
+  ## and hfsc.
+  # classes = true
+
+  ## Collect the XDP programs attached to the interfaces.  The run counters
+  ## of the programs require root privileges and the kernel.bpf_stats_enabled
+  ## sysctl.
+  # xdp = true
+`
+
+// TC gathers the traffic control qdisc and class statistics and the XDP
+// programs of the network interfaces.
+type TC struct {
+	Interfaces []string `toml:"interfaces"`
+	Classes    bool     `toml:"classes"`
+	XDP        bool     `toml:"xdp"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	filter filter.Filter
+	warned bool
+}
+
+func (t *TC) Description() string {
+	return "Gather traffic control qdisc and class statistics and XDP program counters"
+}
+
+func (t *TC) SampleConfig() string {
+	return sampleConfig
+}
+
+// Special handles of the tc_common.h header
+const (
+	handleRoot    = 0xFFFFFFFF
+	handleIngress = 0xFFFFFFF1
+)
+
+// formatHandle formats the handle as shown by tc, such as "1:10" or "8001:".
+func formatHandle(handle uint32) string {
+	switch handle {
+	case handleRoot:
+		return "root"
+	case handleIngress:
+		return "ingress"
+	}
+	major, minor := handle>>16, handle&0xFFFF
+	if minor == 0 {
+		return fmt.Sprintf("%x:", major)
+	}
+	return fmt.Sprintf("%x:%x", major, minor)
+}
@@ -0,0 +1,154 @@
+// +build linux
+
+package tc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (t *TC) Init() error {
+	var err error
+	t.filter, err = filter.Compile(t.Interfaces)
+	return err
+}
+
+func (t *TC) Gather(acc telegraf.Accumulator) error {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return err
+	}
+	names := make(map[int32]string, len(ifaces))
+	for _, iface := range ifaces {
+		if t.filter != nil && !t.filter.Match(iface.Name) {
+			continue
+		}
+		names[int32(iface.Index)] = iface.Name
+	}
+
+	qdiscs, err := netlinkDump(syscall.RTM_GETQDISC, make([]byte, sizeofTcmsg))
+	if err != nil {
+		return fmt.Errorf("dumping qdiscs failed: %v", err)
+	}
+	addTcObjects(acc, "tc_qdisc", "handle", qdiscs, names)
+
+	if t.Classes {
+		// Classes can only be dumped for a single interface
+		for index, name := range names {
+			header := make([]byte, sizeofTcmsg)
+			nativeEndian.PutUint32(header[4:8], uint32(index))
+			classes, err := netlinkDump(syscall.RTM_GETTCLASS, header)
+			if err != nil {
+				acc.AddError(fmt.Errorf("dumping classes of %s failed: %v", name, err))
+				continue
+			}
+			addTcObjects(acc, "tc_class", "classid", classes, names)
+		}
+	}
+
+	if t.XDP {
+		if err := t.gatherXDP(acc, names); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// addTcObjects adds the statistics of the qdiscs or classes of the messages.
+func addTcObjects(acc telegraf.Accumulator, measurement, handleTag string, msgs [][]byte, names map[int32]string) {
+	for _, data := range msgs {
+		obj, err := parseTcmsg(data)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		name, ok := names[obj.ifindex]
+		if !ok || obj.stats == nil {
+			continue
+		}
+
+		tags := map[string]string{
+			"interface": name,
+			"kind":      obj.kind,
+			handleTag:   formatHandle(obj.handle),
+			"parent":    formatHandle(obj.parent),
+		}
+		fields := map[string]interface{}{
+			"bytes":           obj.stats.bytes,
+			"packets":         obj.stats.packets,
+			"drops":           obj.stats.drops,
+			"overlimits":      obj.stats.overlimits,
+			"requeues":        obj.stats.requeues,
+			"backlog_bytes":   obj.stats.backlog,
+			"backlog_packets": obj.stats.qlen,
+		}
+		acc.AddFields(measurement, fields, tags)
+	}
+}
+
+// gatherXDP adds the XDP programs attached to the interfaces.
+func (t *TC) gatherXDP(acc telegraf.Accumulator, names map[int32]string) error {
+	links, err := netlinkDump(syscall.RTM_GETLINK, make([]byte, sizeofIfInfomsg))
+	if err != nil {
+		return fmt.Errorf("dumping links failed: %v", err)
+	}
+
+	for _, data := range links {
+		programs, err := parseIfInfomsg(data)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		for _, prog := range programs {
+			name, ok := names[prog.ifindex]
+			if !ok {
+				continue
+			}
+
+			tags := map[string]string{
+				"interface": name,
+				"mode":      prog.mode,
+			}
+			fields := map[string]interface{}{
+				"prog_id": int64(prog.id),
+			}
+
+			info, err := progInfo(prog.id)
+			switch {
+			case err == nil:
+				if info.name != "" {
+					tags["program"] = info.name
+				}
+				if info.hasStats {
+					fields["run_count"] = info.runCount
+					fields["run_time_ns"] = info.runTime
+				}
+			case os.IsPermission(err):
+				if !t.warned {
+					t.Log.Warn("Reading the XDP program counters requires the CAP_SYS_ADMIN capability")
+					t.warned = true
+				}
+			default:
+				acc.AddError(fmt.Errorf("reading XDP program %d of %s failed: %v", prog.id, name, err))
+			}
+
+			acc.AddFields("xdp", fields, tags)
+		}
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("tc", func() telegraf.Input {
+		return &TC{
+			Classes: true,
+			XDP:     true,
+		}
+	})
+}
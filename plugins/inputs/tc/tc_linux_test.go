@@ -0,0 +1,129 @@
+// +build linux
+
+package tc
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// attr encodes a netlink attribute.
+func attr(typ uint16, data []byte) []byte {
+	b := make([]byte, 4, 4+len(data)+3)
+	nativeEndian.PutUint16(b[0:2], uint16(4+len(data)))
+	nativeEndian.PutUint16(b[2:4], typ)
+	b = append(b, data...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func u32(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		nativeEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+func tcmsg(ifindex int32, handle, parent uint32, attrs ...[]byte) []byte {
+	b := make([]byte, sizeofTcmsg)
+	nativeEndian.PutUint32(b[4:8], uint32(ifindex))
+	nativeEndian.PutUint32(b[8:12], handle)
+	nativeEndian.PutUint32(b[12:16], parent)
+	for _, a := range attrs {
+		b = append(b, a...)
+	}
+	return b
+}
+
+func TestParseTcmsg(t *testing.T) {
+	basic := make([]byte, 16)
+	nativeEndian.PutUint64(basic[0:8], 123456789)
+	nativeEndian.PutUint32(basic[8:12], 4321)
+	queue := u32(3, 4500, 17, 2, 99)
+
+	stats2 := append(attr(tcaStatsBasic, basic), attr(tcaStatsQueue, queue)...)
+	data := tcmsg(2, 0x10000, handleRoot,
+		attr(tcaKind, []byte("htb\x00")),
+		attr(tcaStats2|0x8000, stats2),
+	)
+
+	obj, err := parseTcmsg(data)
+	require.NoError(t, err)
+	require.Equal(t, &tcObject{
+		ifindex: 2,
+		handle:  0x10000,
+		parent:  handleRoot,
+		kind:    "htb",
+		stats: &tcStats{
+			bytes:      123456789,
+			packets:    4321,
+			drops:      17,
+			overlimits: 99,
+			requeues:   2,
+			qlen:       3,
+			backlog:    4500,
+		},
+	}, obj)
+}
+
+func TestParseTcmsgLegacyStats(t *testing.T) {
+	stats := make([]byte, 36)
+	nativeEndian.PutUint64(stats[0:8], 1000)
+	copy(stats[8:], u32(10, 1, 2, 0, 0, 5, 600))
+
+	obj, err := parseTcmsg(tcmsg(3, 0x10010, 0x10001,
+		attr(tcaKind, []byte("fq_codel\x00")),
+		attr(tcaStats, stats),
+	))
+	require.NoError(t, err)
+	require.Equal(t, "fq_codel", obj.kind)
+	require.Equal(t, &tcStats{bytes: 1000, packets: 10, drops: 1, overlimits: 2, qlen: 5, backlog: 600}, obj.stats)
+}
+
+func TestParseTcmsgShort(t *testing.T) {
+	_, err := parseTcmsg(make([]byte, 8))
+	require.Error(t, err)
+}
+
+func TestParseIfInfomsg(t *testing.T) {
+	header := make([]byte, sizeofIfInfomsg)
+	nativeEndian.PutUint32(header[4:8], 4)
+
+	// A program attached in driver mode
+	xdp := append(attr(iflaXDPAttached, []byte{1}), attr(iflaXDPProgID, u32(42))...)
+	data := append(append([]byte(nil), header...), attr(syscall.IFLA_IFNAME, []byte("eth0\x00"))...)
+	data = append(data, attr(iflaXDP|0x8000, xdp)...)
+
+	programs, err := parseIfInfomsg(data)
+	require.NoError(t, err)
+	require.Equal(t, []*xdpProgram{{ifindex: 4, mode: "drv", id: 42}}, programs)
+
+	// Programs attached in several modes
+	xdp = append(attr(iflaXDPAttached, []byte{4}), attr(iflaXDPSkbProgID, u32(7))...)
+	data = append(append([]byte(nil), header...), attr(iflaXDP|0x8000, xdp)...)
+
+	programs, err = parseIfInfomsg(data)
+	require.NoError(t, err)
+	require.Equal(t, []*xdpProgram{{ifindex: 4, mode: "skb", id: 7}}, programs)
+
+	// No program attached
+	xdp = attr(iflaXDPAttached, []byte{0})
+	data = append(append([]byte(nil), header...), attr(iflaXDP|0x8000, xdp)...)
+
+	programs, err = parseIfInfomsg(data)
+	require.NoError(t, err)
+	require.Empty(t, programs)
+}
+
+func TestFormatHandle(t *testing.T) {
+	require.Equal(t, "root", formatHandle(handleRoot))
+	require.Equal(t, "ingress", formatHandle(handleIngress))
+	require.Equal(t, "0:", formatHandle(0))
+	require.Equal(t, "8001:", formatHandle(0x80010000))
+	require.Equal(t, "1:10", formatHandle(0x10010))
+}
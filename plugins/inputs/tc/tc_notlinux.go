@@ -0,0 +1,23 @@
+// +build !linux
+
+package tc
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func (t *TC) Init() error {
+	t.Log.Warn("Current platform is not supported")
+	return nil
+}
+
+func (t *TC) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("tc", func() telegraf.Input {
+		return &TC{}
+	})
+}
@@ -0,0 +1,259 @@
+package trafficserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	transactionCountsPrefix    = "proxy.process.http.transaction_counts."
+	transactionTotaltimePrefix = "proxy.process.http.transaction_totaltime."
+)
+
+var defaultStats = []string{"proxy.process.http.*", "proxy.process.cache*"}
+
+type TrafficServer struct {
+	Urls            []string          `toml:"urls"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	Stats           []string          `toml:"stats"`
+	ResponseTimeout internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	filter filter.Filter
+	client *http.Client
+}
+
+var sampleConfig = `
+  ## An array of URLs of the stats_over_http plugin of Traffic Server.
+  urls = ["http://localhost/_stats"]
+
+  ## Credentials for basic HTTP authentication.
+  # username = "myuser"
+  # password = "mypassword"
+
+  ## Stats to include in the trafficserver measurement, glob patterns are
+  ## supported.  The "proxy.process." prefix is removed from the field names.
+  # stats = ["proxy.process.http.*", "proxy.process.cache*"]
+
+  ## Maximum time to receive response.
+  # response_timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (t *TrafficServer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *TrafficServer) Description() string {
+	return "Read Apache Traffic Server stats (stats_over_http)"
+}
+
+func (t *TrafficServer) Init() error {
+	if len(t.Urls) == 0 {
+		t.Urls = []string{"http://localhost/_stats"}
+	}
+	if len(t.Stats) == 0 {
+		t.Stats = defaultStats
+	}
+	if t.ResponseTimeout.Duration < time.Second {
+		t.ResponseTimeout.Duration = time.Second * 5
+	}
+
+	var err error
+	t.filter, err = filter.Compile(t.Stats)
+	if err != nil {
+		return fmt.Errorf("invalid stats: %v", err)
+	}
+
+	tlsCfg, err := t.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	t.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: t.ResponseTimeout.Duration,
+	}
+	return nil
+}
+
+func (t *TrafficServer) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, u := range t.Urls {
+		addr, err := url.Parse(u)
+		if err != nil {
+			acc.AddError(fmt.Errorf("Unable to parse address '%s': %s", u, err))
+			continue
+		}
+
+		wg.Add(1)
+		go func(addr *url.URL) {
+			defer wg.Done()
+			acc.AddError(t.gatherURL(addr, acc))
+		}(addr)
+	}
+	wg.Wait()
+	return nil
+}
+
+// statsResponse is the response of stats_over_http, the values being strings
+// or numbers depending on the version.
+type statsResponse struct {
+	Global map[string]interface{} `json:"global"`
+}
+
+func (t *TrafficServer) gatherURL(addr *url.URL, acc telegraf.Accumulator) error {
+	req, err := http.NewRequest("GET", addr.String(), nil)
+	if err != nil {
+		return fmt.Errorf("error on new request to %s: %s", addr.String(), err)
+	}
+	if t.Username != "" || t.Password != "" {
+		req.SetBasicAuth(t.Username, t.Password)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error on request to %s: %s", addr.String(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", addr.String(), resp.Status)
+	}
+
+	var stats statsResponse
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&stats); err != nil {
+		return fmt.Errorf("error decoding response from %s: %s", addr.String(), err)
+	}
+
+	tags := getTags(addr)
+	values := make(map[string]interface{}, len(stats.Global))
+	for name, raw := range stats.Global {
+		if v, ok := parseValue(raw); ok {
+			values[name] = v
+		}
+	}
+
+	fields := make(map[string]interface{})
+	transactions := make(map[string]map[string]interface{})
+	for name, v := range values {
+		switch {
+		case strings.HasPrefix(name, transactionCountsPrefix):
+			transaction(transactions, strings.TrimPrefix(name, transactionCountsPrefix))["count"] = v
+		case strings.HasPrefix(name, transactionTotaltimePrefix):
+			transaction(transactions, strings.TrimPrefix(name, transactionTotaltimePrefix))["total_time"] = v
+		case t.filter.Match(name):
+			fields[strings.TrimPrefix(name, "proxy.process.")] = v
+		}
+	}
+
+	hits, okHits := toFloat(values["proxy.process.cache_total_hits"])
+	misses, okMisses := toFloat(values["proxy.process.cache_total_misses"])
+	if okHits && okMisses && hits+misses > 0 {
+		fields["cache_hit_ratio"] = hits / (hits + misses)
+	}
+	used, okUsed := toFloat(values["proxy.process.cache.bytes_used"])
+	total, okTotal := toFloat(values["proxy.process.cache.bytes_total"])
+	if okUsed && okTotal && total > 0 {
+		fields["cache_utilization"] = used / total
+	}
+
+	if len(fields) > 0 {
+		acc.AddFields("trafficserver", fields, tags)
+	}
+	for result, fields := range transactions {
+		transactionTags := map[string]string{"result": result}
+		for k, v := range tags {
+			transactionTags[k] = v
+		}
+		acc.AddFields("trafficserver_transactions", fields, transactionTags)
+	}
+	return nil
+}
+
+func transaction(transactions map[string]map[string]interface{}, result string) map[string]interface{} {
+	fields, ok := transactions[result]
+	if !ok {
+		fields = make(map[string]interface{})
+		transactions[result] = fields
+	}
+	return fields
+}
+
+// parseValue parses a stat value as an integer or else as a float, the
+// version string and the other non numeric stats are skipped.
+func parseValue(raw interface{}) (interface{}, bool) {
+	var s string
+	switch v := raw.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, false
+	}
+
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// Get tag(s) for the trafficserver plugin
+func getTags(addr *url.URL) map[string]string {
+	h := addr.Host
+	host, port, err := net.SplitHostPort(h)
+	if err != nil {
+		host = addr.Host
+		if addr.Scheme == "http" {
+			port = "80"
+		} else if addr.Scheme == "https" {
+			port = "443"
+		} else {
+			port = ""
+		}
+	}
+	return map[string]string{"server": host, "port": port}
+}
+
+func init() {
+	inputs.Add("trafficserver", func() telegraf.Input {
+		return &TrafficServer{}
+	})
+}
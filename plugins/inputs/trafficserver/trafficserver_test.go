@@ -0,0 +1,129 @@
+package trafficserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const statsOutput = `{ "global": {
+"proxy.process.http.completed_requests": "1234",
+"proxy.process.http.incoming_requests": "1300",
+"proxy.process.http.transaction_counts.hit_fresh": "800",
+"proxy.process.http.transaction_totaltime.hit_fresh": "4.000000",
+"proxy.process.http.transaction_counts.miss_cold": "200",
+"proxy.process.http.transaction_totaltime.miss_cold": "30.500000",
+"proxy.process.cache_total_hits": "800",
+"proxy.process.cache_total_misses": "200",
+"proxy.process.cache.bytes_used": 268435456,
+"proxy.process.cache.bytes_total": 1073741824,
+"proxy.process.net.connections_currently_open": "12",
+"proxy.node.version.manager.short": "9.1.0",
+"server": "9.1.0"
+ }
+}
+`
+
+func TestGather(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "telegraf" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, statsOutput)
+	}))
+	defer ts.Close()
+
+	addr, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	tags := getTags(addr)
+
+	plugin := &TrafficServer{
+		Urls:     []string{ts.URL},
+		Username: "telegraf",
+		Password: "secret",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("trafficserver",
+			tags,
+			map[string]interface{}{
+				"http.completed_requests": int64(1234),
+				"http.incoming_requests":  int64(1300),
+				"cache_total_hits":        int64(800),
+				"cache_total_misses":      int64(200),
+				"cache.bytes_used":        int64(268435456),
+				"cache.bytes_total":       int64(1073741824),
+				"cache_hit_ratio":         0.8,
+				"cache_utilization":       0.25,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("trafficserver_transactions",
+			map[string]string{"server": tags["server"], "port": tags["port"], "result": "hit_fresh"},
+			map[string]interface{}{
+				"count":      int64(800),
+				"total_time": 4.0,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("trafficserver_transactions",
+			map[string]string{"server": tags["server"], "port": tags["port"], "result": "miss_cold"},
+			map[string]interface{}{
+				"count":      int64(200),
+				"total_time": 30.5,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, statsOutput)
+	}))
+	defer ts.Close()
+
+	plugin := &TrafficServer{
+		Urls:  []string{ts.URL},
+		Stats: []string{"proxy.process.net.*"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	acc.AssertContainsFields(t, "trafficserver", map[string]interface{}{
+		"net.connections_currently_open": int64(12),
+		"cache_hit_ratio":                0.8,
+		"cache_utilization":              0.25,
+	})
+}
+
+func TestGatherError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	plugin := &TrafficServer{
+		Urls: []string{ts.URL},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(plugin.Gather))
+}
@@ -194,6 +194,11 @@ func (s *Unbound) Gather(acc telegraf.Accumulator) error {
 
 	}
 
+	addRatios(fields, "total_")
+	for _, threadFields := range fieldsThreads {
+		addRatios(threadFields, "")
+	}
+
 	acc.AddFields("unbound", fields, nil)
 
 	if s.ThreadAsTag && len(fieldsThreads) > 0 {
@@ -206,6 +211,21 @@ func (s *Unbound) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// addRatios adds the ratio of the queries answered from the cache, and the
+// ratio of the cache hits which triggered a prefetch.
+func addRatios(fields map[string]interface{}, prefix string) {
+	queries, _ := fields[prefix+"num_queries"].(float64)
+	hits, okHits := fields[prefix+"num_cachehits"].(float64)
+	prefetch, okPrefetch := fields[prefix+"num_prefetch"].(float64)
+
+	if okHits && queries > 0 {
+		fields[prefix+"cache_hit_ratio"] = hits / queries
+	}
+	if okPrefetch && hits > 0 {
+		fields[prefix+"prefetch_ratio"] = prefetch / hits
+	}
+}
+
 func init() {
 	inputs.Add("unbound", func() telegraf.Input {
 		return &Unbound{
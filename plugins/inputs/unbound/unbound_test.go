@@ -30,7 +30,7 @@ func TestParseFullOutput(t *testing.T) {
 	assert.True(t, acc.HasMeasurement("unbound"))
 
 	assert.Len(t, acc.Metrics, 1)
-	assert.Equal(t, acc.NFields(), 63)
+	assert.Equal(t, acc.NFields(), 65)
 
 	acc.AssertContainsFields(t, "unbound", parsedFullOutput)
 }
@@ -49,7 +49,7 @@ func TestParseFullOutputThreadAsTag(t *testing.T) {
 	assert.True(t, acc.HasMeasurement("unbound_threads"))
 
 	assert.Len(t, acc.Metrics, 2)
-	assert.Equal(t, acc.NFields(), 63)
+	assert.Equal(t, acc.NFields(), 67)
 
 	acc.AssertContainsFields(t, "unbound", parsedFullOutputThreadAsTagMeasurementUnbound)
 	acc.AssertContainsFields(t, "unbound_threads", parsedFullOutputThreadAsTagMeasurementUnboundThreads)
@@ -73,6 +73,8 @@ var parsedFullOutput = map[string]interface{}{
 	"total_num_cachehits":              float64(11489288),
 	"total_num_cachemiss":              float64(418308),
 	"total_num_prefetch":               float64(0),
+	"total_cache_hit_ratio":            float64(11489288) / float64(11907596),
+	"total_prefetch_ratio":             float64(0),
 	"total_num_recursivereplies":       float64(418308),
 	"total_requestlist_avg":            float64(0.400229),
 	"total_requestlist_max":            float64(11),
@@ -126,6 +128,8 @@ var parsedFullOutputThreadAsTagMeasurementUnboundThreads = map[string]interface{
 	"num_cachehits":            float64(11489288),
 	"num_cachemiss":            float64(418308),
 	"num_prefetch":             float64(0),
+	"cache_hit_ratio":          float64(11489288) / float64(11907596),
+	"prefetch_ratio":           float64(0),
 	"num_recursivereplies":     float64(418308),
 	"requestlist_avg":          float64(0.400229),
 	"requestlist_max":          float64(11),
@@ -141,6 +145,8 @@ var parsedFullOutputThreadAsTagMeasurementUnbound = map[string]interface{}{
 	"total_num_cachehits":            float64(11489288),
 	"total_num_cachemiss":            float64(418308),
 	"total_num_prefetch":             float64(0),
+	"total_cache_hit_ratio":          float64(11489288) / float64(11907596),
+	"total_prefetch_ratio":           float64(0),
 	"total_num_recursivereplies":     float64(418308),
 	"total_requestlist_avg":          float64(0.400229),
 	"total_requestlist_max":          float64(11),
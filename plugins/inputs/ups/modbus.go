@@ -0,0 +1,204 @@
+package ups
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultModbusPort = "502"
+
+	// modbusReadHoldingRegisters is the function code of the requests.
+	modbusReadHoldingRegisters = 0x03
+)
+
+// ModbusConfig is a UPS polled over Modbus TCP.
+type ModbusConfig struct {
+	Name      string     `toml:"name"`
+	Address   string     `toml:"address"`
+	SlaveID   uint8      `toml:"slave_id"`
+	Device    string     `toml:"device"`
+	Registers []Register `toml:"register"`
+
+	// statusRegister is the bit field of the status of the UPS, if the map
+	// of the device has one.
+	statusRegister *Register
+	address        string
+}
+
+// Register is a value of the register map of a device.  The value is
+// reported as a float, multiplied by the scale if not 0.
+type Register struct {
+	Name    string  `toml:"name"`
+	Address uint16  `toml:"address"`
+	Type    string  `toml:"type"`
+	Scale   float64 `toml:"scale"`
+}
+
+// apcStatusRegister is the UPSStatus_BF register of the Modbus map of the
+// APC Smart-UPS, apcStatusBits are the status tokens of NUT of its bits.
+var (
+	apcStatusRegister = Register{Name: "status", Address: 0, Type: "uint32"}
+	apcStatusBits     = map[uint]string{1: "OL", 2: "OB", 3: "BYPASS", 4: "OFF", 5: "ALARM"}
+)
+
+// apcRegisters are the registers of the Modbus map of the APC Smart-UPS,
+// named as the variables of NUT.
+var apcRegisters = []Register{
+	{Name: "battery_runtime", Address: 128, Type: "uint32", Scale: 1},
+	{Name: "battery_charge", Address: 130, Type: "uint16", Scale: 1.0 / 512},
+	{Name: "battery_voltage", Address: 131, Type: "int16", Scale: 1.0 / 32},
+	{Name: "battery_temperature", Address: 135, Type: "int16", Scale: 1.0 / 128},
+	{Name: "ups_load", Address: 136, Type: "uint16", Scale: 1.0 / 256},
+	{Name: "output_current", Address: 140, Type: "uint16", Scale: 1.0 / 32},
+	{Name: "output_voltage", Address: 142, Type: "uint16", Scale: 1.0 / 64},
+	{Name: "output_frequency", Address: 144, Type: "uint16", Scale: 1.0 / 128},
+	{Name: "input_voltage", Address: 151, Type: "uint16", Scale: 1.0 / 64},
+}
+
+func (c *ModbusConfig) init() error {
+	if c.Name == "" {
+		return errors.New("name must be set")
+	}
+	address := c.Address
+	if !strings.Contains(address, "://") {
+		address = "tcp://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "tcp" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultModbusPort
+	}
+	c.address = net.JoinHostPort(u.Hostname(), port)
+
+	switch c.Device {
+	case "":
+		if len(c.Registers) == 0 {
+			return errors.New("registers must be set without device")
+		}
+	case "apc":
+		c.statusRegister = &apcStatusRegister
+		c.Registers = append(apcRegisters[:len(apcRegisters):len(apcRegisters)], c.Registers...)
+	default:
+		return fmt.Errorf("unknown device %q", c.Device)
+	}
+	for _, r := range c.Registers {
+		if r.Name == "" {
+			return errors.New("register name must be set")
+		}
+		if registerCount(r.Type) == 0 {
+			return fmt.Errorf("register %s: unknown type %q", r.Name, r.Type)
+		}
+	}
+	return nil
+}
+
+// registerCount returns the number of 16 bits registers of a type, 0 if the
+// type is unknown.
+func registerCount(typ string) uint16 {
+	switch typ {
+	case "uint16", "int16":
+		return 1
+	case "uint32", "int32":
+		return 2
+	}
+	return 0
+}
+
+// modbusClient is a connection to a Modbus TCP server.
+type modbusClient struct {
+	conn        net.Conn
+	slaveID     uint8
+	transaction uint16
+}
+
+func dialModbus(address string, slaveID uint8, timeout time.Duration) (*modbusClient, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &modbusClient{conn: conn, slaveID: slaveID}, nil
+}
+
+func (c *modbusClient) Close() error {
+	return c.conn.Close()
+}
+
+// readRegister reads the holding registers of a register and returns its
+// value, unscaled.
+func (c *modbusClient) readRegister(r Register) (int64, error) {
+	count := registerCount(r.Type)
+	data, err := c.readHoldingRegisters(r.Address, count)
+	if err != nil {
+		return 0, err
+	}
+	switch r.Type {
+	case "uint16":
+		return int64(binary.BigEndian.Uint16(data)), nil
+	case "int16":
+		return int64(int16(binary.BigEndian.Uint16(data))), nil
+	case "uint32":
+		return int64(binary.BigEndian.Uint32(data)), nil
+	default:
+		return int64(int32(binary.BigEndian.Uint32(data))), nil
+	}
+}
+
+func (c *modbusClient) readHoldingRegisters(address, count uint16) ([]byte, error) {
+	c.transaction++
+
+	// The header is the transaction, the protocol 0, the length of the rest
+	// of the request and the unit, followed by the function and its data.
+	req := make([]byte, 12)
+	binary.BigEndian.PutUint16(req[0:], c.transaction)
+	binary.BigEndian.PutUint16(req[4:], 6)
+	req[6] = c.slaveID
+	req[7] = modbusReadHoldingRegisters
+	binary.BigEndian.PutUint16(req[8:], address)
+	binary.BigEndian.PutUint16(req[10:], count)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	if length < 3 || length > 256 {
+		return nil, fmt.Errorf("invalid length %d", length)
+	}
+	pdu := make([]byte, length-1)
+	if _, err := io.ReadFull(c.conn, pdu); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint16(header[0:]) != c.transaction {
+		return nil, errors.New("unexpected transaction")
+	}
+
+	switch {
+	case pdu[0] == modbusReadHoldingRegisters|0x80:
+		return nil, fmt.Errorf("register %d: exception %d", address, pdu[1])
+	case pdu[0] != modbusReadHoldingRegisters:
+		return nil, fmt.Errorf("unexpected function %d", pdu[0])
+	case int(pdu[1]) != 2*int(count) || len(pdu) != 2+int(pdu[1]):
+		return nil, fmt.Errorf("register %d: unexpected number of bytes", address)
+	}
+	return pdu[2:], nil
+}
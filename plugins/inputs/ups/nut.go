@@ -0,0 +1,194 @@
+package ups
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultNUTPort = "3493"
+
+// NUTConfig is a server of Network UPS Tools.
+type NUTConfig struct {
+	Address  string   `toml:"address"`
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	UPS      []string `toml:"ups"`
+}
+
+// nutClient is a connection to the upsd server of Network UPS Tools, which
+// answers the commands with lines of words.
+type nutClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// nutAddress returns the host and port of the address of a server.
+func nutAddress(address string) (string, error) {
+	if !strings.Contains(address, "://") {
+		address = "tcp://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "tcp" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultNUTPort
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+func dialNUT(address string, timeout time.Duration) (*nutClient, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &nutClient{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (c *nutClient) Close() error {
+	fmt.Fprint(c.conn, "LOGOUT\n")
+	return c.conn.Close()
+}
+
+func (c *nutClient) login(username, password string) error {
+	if _, err := c.command("USERNAME " + nutQuote(username)); err != nil {
+		return err
+	}
+	_, err := c.command("PASSWORD " + nutQuote(password))
+	return err
+}
+
+// command sends a command and returns the words of its single line answer.
+func (c *nutClient) command(cmd string) ([]string, error) {
+	if _, err := fmt.Fprint(c.conn, cmd+"\n"); err != nil {
+		return nil, err
+	}
+	return c.readLine()
+}
+
+func (c *nutClient) readLine() ([]string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	words, err := nutSplit(strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+	if len(words) > 0 && words[0] == "ERR" {
+		if len(words) > 1 {
+			return nil, fmt.Errorf("server error %s", words[1])
+		}
+		return nil, errors.New("server error")
+	}
+	return words, nil
+}
+
+// list sends a LIST command and returns the words of the lines of the list,
+// between "BEGIN LIST <query>" and "END LIST <query>".
+func (c *nutClient) list(query string) ([][]string, error) {
+	words, err := c.command("LIST " + query)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) < 2 || words[0] != "BEGIN" || words[1] != "LIST" {
+		return nil, fmt.Errorf("unexpected answer %q", strings.Join(words, " "))
+	}
+
+	var lines [][]string
+	for {
+		words, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(words) >= 2 && words[0] == "END" && words[1] == "LIST" {
+			return lines, nil
+		}
+		lines = append(lines, words)
+	}
+}
+
+// listUPS returns the names of the UPS of the server.
+func (c *nutClient) listUPS() ([]string, error) {
+	lines, err := c.list("UPS")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, words := range lines {
+		if len(words) >= 2 && words[0] == "UPS" {
+			names = append(names, words[1])
+		}
+	}
+	return names, nil
+}
+
+// listVars returns the variables of a UPS, such as "battery.charge".
+func (c *nutClient) listVars(ups string) (map[string]string, error) {
+	lines, err := c.list("VAR " + ups)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(lines))
+	for _, words := range lines {
+		if len(words) == 4 && words[0] == "VAR" && words[1] == ups {
+			vars[words[2]] = words[3]
+		}
+	}
+	return vars, nil
+}
+
+// nutSplit splits a line into words, the words with spaces are quoted and
+// the quotes and backslashes in quoted words are escaped with a backslash.
+func nutSplit(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, quoted, escaped := false, false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case r == ' ' && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted || escaped {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func nutQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
@@ -0,0 +1,347 @@
+package ups
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Timeout of the connections and of the requests to each server or UPS.
+  # timeout = "5s"
+
+  ## Servers of Network UPS Tools, the UPS and PDU of the servers are
+  ## gathered.
+  # [[inputs.ups.nut]]
+  #   address = "tcp://127.0.0.1:3493"
+  #   ## Credentials of a user of upsd.users, if the server requires a login.
+  #   # username = ""
+  #   # password = ""
+  #   ## Names of the UPS to gather, all the UPS of the server if empty.
+  #   # ups = []
+
+  ## UPS polled over Modbus TCP, such as APC Smart-UPS with a network
+  ## management card and Modbus enabled.
+  # [[inputs.ups.modbus]]
+  #   ## Name of the UPS, reported in the ups_name tag.
+  #   name = "rack1"
+  #   address = "tcp://192.168.1.10:502"
+  #   # slave_id = 1
+  #
+  #   ## Register map of the device, "apc" for the APC Smart-UPS.  Other
+  #   ## devices are polled with the registers of their Modbus map.
+  #   device = "apc"
+  #
+  #   ## Additional holding registers; type is one of "uint16", "int16",
+  #   ## "uint32" or "int32" and the value is multiplied by the scale.
+  #   # [[inputs.ups.modbus.register]]
+  #   #   name = "input_frequency"
+  #   #   address = 150
+  #   #   type = "uint16"
+  #   #   scale = 0.0078125
+`
+
+// statusFlags are the boolean fields of the status tokens of NUT.
+var statusFlags = map[string]string{
+	"OL":     "online",
+	"OB":     "on_battery",
+	"LB":     "low_battery",
+	"RB":     "replace_battery",
+	"OVER":   "overload",
+	"BYPASS": "bypass",
+}
+
+// statusEvents are the events of the status tokens of NUT, the other tokens
+// are reported lowercased.
+var statusEvents = map[string]string{
+	"OL":    "online",
+	"OB":    "on_battery",
+	"LB":    "low_battery",
+	"RB":    "replace_battery",
+	"OVER":  "overload",
+	"FSD":   "forced_shutdown",
+	"CHRG":  "charging",
+	"CAL":   "calibration",
+	"ALARM": "alarm",
+}
+
+// UPS gathers the status of UPS and PDU from Network UPS Tools and Modbus.
+type UPS struct {
+	Timeout internal.Duration `toml:"timeout"`
+	NUT     []*NUTConfig      `toml:"nut"`
+	Modbus  []*ModbusConfig   `toml:"modbus"`
+
+	sync.Mutex
+	// statuses are the statuses of the last gather, by UPS.
+	statuses map[string]string
+}
+
+func (u *UPS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (u *UPS) Description() string {
+	return "Read the status of UPS and PDU from Network UPS Tools and Modbus"
+}
+
+func (u *UPS) Init() error {
+	if len(u.NUT) == 0 && len(u.Modbus) == 0 {
+		return errors.New("nut or modbus must be configured")
+	}
+	for _, n := range u.NUT {
+		if n.Address == "" {
+			n.Address = "tcp://127.0.0.1:" + defaultNUTPort
+		}
+		address, err := nutAddress(n.Address)
+		if err != nil {
+			return fmt.Errorf("nut %s: %v", n.Address, err)
+		}
+		n.Address = address
+	}
+	for _, m := range u.Modbus {
+		if m.SlaveID == 0 {
+			m.SlaveID = 1
+		}
+		if err := m.init(); err != nil {
+			return fmt.Errorf("modbus %s: %v", m.Address, err)
+		}
+	}
+	u.statuses = make(map[string]string)
+	return nil
+}
+
+func (u *UPS) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, n := range u.NUT {
+		wg.Add(1)
+		go func(n *NUTConfig) {
+			defer wg.Done()
+			if err := u.gatherNUT(acc, n); err != nil {
+				acc.AddError(fmt.Errorf("nut %s: %v", n.Address, err))
+			}
+		}(n)
+	}
+	for _, m := range u.Modbus {
+		wg.Add(1)
+		go func(m *ModbusConfig) {
+			defer wg.Done()
+			if err := u.gatherModbus(acc, m); err != nil {
+				acc.AddError(fmt.Errorf("modbus %s: %v", m.Address, err))
+			}
+		}(m)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (u *UPS) gatherNUT(acc telegraf.Accumulator, n *NUTConfig) error {
+	c, err := dialNUT(n.Address, u.Timeout.Duration)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if n.Username != "" {
+		if err := c.login(n.Username, n.Password); err != nil {
+			return fmt.Errorf("login failed: %v", err)
+		}
+	}
+
+	names := n.UPS
+	if len(names) == 0 {
+		names, err = c.listUPS()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		vars, err := c.listVars(name)
+		if err != nil {
+			acc.AddError(fmt.Errorf("nut %s: ups %s: %v", n.Address, name, err))
+			continue
+		}
+		tags := map[string]string{
+			"source":   "nut",
+			"server":   n.Address,
+			"ups_name": name,
+		}
+		u.gatherVars(acc, tags, vars)
+	}
+	return nil
+}
+
+// gatherVars adds the metrics of the variables of a UPS or PDU.  The
+// variables of the outlets, "outlet.<n>.<name>", are reported in a metric by
+// outlet.
+func (u *UPS) gatherVars(acc telegraf.Accumulator, tags map[string]string, vars map[string]string) {
+	for _, tag := range []string{"model", "serial"} {
+		if v, ok := vars["device."+tag]; ok {
+			tags[tag] = v
+		} else if v, ok := vars["ups."+tag]; ok {
+			tags[tag] = v
+		}
+	}
+
+	fields := make(map[string]interface{})
+	outlets := make(map[string]map[string]string)
+	for name, value := range vars {
+		if strings.HasPrefix(name, "outlet.") {
+			parts := strings.SplitN(name, ".", 3)
+			if len(parts) == 3 {
+				if _, err := strconv.Atoi(parts[1]); err == nil {
+					if outlets[parts[1]] == nil {
+						outlets[parts[1]] = make(map[string]string)
+					}
+					outlets[parts[1]][parts[2]] = value
+					continue
+				}
+			}
+		}
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			fields[strings.Replace(name, ".", "_", -1)] = v
+		}
+	}
+
+	now := time.Now()
+	if status, ok := vars["ups.status"]; ok {
+		u.addStatus(acc, tags, fields, status, now)
+	}
+	if len(fields) > 0 {
+		acc.AddFields("ups", fields, tags, now)
+	}
+
+	for outlet, vars := range outlets {
+		outletTags := map[string]string{
+			"source":   tags["source"],
+			"server":   tags["server"],
+			"ups_name": tags["ups_name"],
+			"outlet":   outlet,
+		}
+		if desc, ok := vars["desc"]; ok {
+			outletTags["description"] = desc
+		}
+		outletFields := make(map[string]interface{})
+		for name, value := range vars {
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				outletFields[strings.Replace(name, ".", "_", -1)] = v
+			}
+		}
+		if status, ok := vars["status"]; ok {
+			outletFields["status"] = status
+		}
+		if len(outletFields) > 0 {
+			acc.AddFields("ups_outlet", outletFields, outletTags, now)
+		}
+	}
+}
+
+func (u *UPS) gatherModbus(acc telegraf.Accumulator, m *ModbusConfig) error {
+	c, err := dialModbus(m.address, m.SlaveID, u.Timeout.Duration)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	tags := map[string]string{
+		"source":   "modbus",
+		"server":   m.address,
+		"ups_name": m.Name,
+	}
+	fields := make(map[string]interface{})
+	for _, r := range m.Registers {
+		value, err := c.readRegister(r)
+		if err != nil {
+			return err
+		}
+		scale := r.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		fields[r.Name] = float64(value) * scale
+	}
+
+	now := time.Now()
+	if m.statusRegister != nil {
+		bits, err := c.readRegister(*m.statusRegister)
+		if err != nil {
+			return err
+		}
+		var tokens []string
+		for bit, token := range apcStatusBits {
+			if bits&(1<<bit) != 0 {
+				tokens = append(tokens, token)
+			}
+		}
+		sort.Strings(tokens)
+		u.addStatus(acc, tags, fields, strings.Join(tokens, " "), now)
+	}
+	acc.AddFields("ups", fields, tags, now)
+	return nil
+}
+
+// addStatus adds the status and its flags to the fields of a UPS, and the
+// events of the status tokens set since the last gather.
+func (u *UPS) addStatus(acc telegraf.Accumulator, tags map[string]string, fields map[string]interface{}, status string, now time.Time) {
+	tokens := strings.Fields(status)
+	fields["status"] = status
+	for token, flag := range statusFlags {
+		fields[flag] = false
+		for _, t := range tokens {
+			if t == token {
+				fields[flag] = true
+			}
+		}
+	}
+
+	key := tags["source"] + "/" + tags["server"] + "/" + tags["ups_name"]
+	u.Lock()
+	previous, ok := u.statuses[key]
+	u.statuses[key] = status
+	u.Unlock()
+	if !ok || previous == status {
+		return
+	}
+
+	previousTokens := make(map[string]bool)
+	for _, t := range strings.Fields(previous) {
+		previousTokens[t] = true
+	}
+	for _, t := range tokens {
+		if previousTokens[t] {
+			continue
+		}
+		event, ok := statusEvents[t]
+		if !ok {
+			event = strings.ToLower(t)
+		}
+		acc.AddFields("ups_event",
+			map[string]interface{}{
+				"status":          status,
+				"previous_status": previous,
+			},
+			map[string]string{
+				"source":   tags["source"],
+				"server":   tags["server"],
+				"ups_name": tags["ups_name"],
+				"event":    event,
+			},
+			now)
+	}
+}
+
+func init() {
+	inputs.Add("ups", func() telegraf.Input {
+		return &UPS{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,379 @@
+package ups
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// nutLock guards the variables of the UPS of the NUT servers.
+var nutLock sync.Mutex
+
+// nutServer answers the commands of NUT with the variables of its UPS.
+func nutServer(t *testing.T, ups map[string]map[string]string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveNUT(conn, ups)
+		}
+	}()
+	return l
+}
+
+func serveNUT(conn net.Conn, ups map[string]map[string]string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		words, _ := nutSplit(strings.TrimSpace(line))
+		nutLock.Lock()
+		switch {
+		case len(words) == 2 && words[0] == "USERNAME":
+			fmt.Fprint(conn, "OK\n")
+		case len(words) == 2 && words[0] == "PASSWORD":
+			if words[1] != "secret" {
+				fmt.Fprint(conn, "ERR ACCESS-DENIED\n")
+				break
+			}
+			fmt.Fprint(conn, "OK\n")
+		case len(words) == 2 && words[0] == "LIST" && words[1] == "UPS":
+			fmt.Fprint(conn, "BEGIN LIST UPS\n")
+			for name := range ups {
+				fmt.Fprintf(conn, "UPS %s \"Description\"\n", name)
+			}
+			fmt.Fprint(conn, "END LIST UPS\n")
+		case len(words) == 3 && words[0] == "LIST" && words[1] == "VAR":
+			vars, ok := ups[words[2]]
+			if !ok {
+				fmt.Fprint(conn, "ERR UNKNOWN-UPS\n")
+				break
+			}
+			fmt.Fprintf(conn, "BEGIN LIST VAR %s\n", words[2])
+			for name, value := range vars {
+				fmt.Fprintf(conn, "VAR %s %s %s\n", words[2], name, nutQuote(value))
+			}
+			fmt.Fprintf(conn, "END LIST VAR %s\n", words[2])
+		case len(words) == 1 && words[0] == "LOGOUT":
+			fmt.Fprint(conn, "OK Goodbye\n")
+			nutLock.Unlock()
+			return
+		default:
+			fmt.Fprint(conn, "ERR UNKNOWN-COMMAND\n")
+		}
+		nutLock.Unlock()
+	}
+}
+
+func TestGatherNUT(t *testing.T) {
+	ups := map[string]map[string]string{
+		"ups1": {
+			"device.model":   "Smart-UPS 1500",
+			"device.serial":  "AS1234",
+			"battery.charge": "100",
+			"ups.load":       "23",
+			"ups.status":     "OL",
+		},
+		"pdu1": {
+			"device.model":     "Switched PDU",
+			"outlet.count":     "2",
+			"outlet.1.desc":    "Server \"A\"",
+			"outlet.1.current": "0.5",
+			"outlet.1.status":  "on",
+			"outlet.2.status":  "off",
+		},
+	}
+	l := nutServer(t, ups)
+	defer l.Close()
+
+	u := &UPS{
+		Timeout: internal.Duration{Duration: time.Second},
+		NUT:     []*NUTConfig{{Address: "tcp://" + l.Addr().String(), Username: "monuser", Password: "secret"}},
+	}
+	require.NoError(t, u.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(u.Gather))
+
+	server := l.Addr().String()
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"ups",
+			map[string]string{
+				"source":   "nut",
+				"server":   server,
+				"ups_name": "ups1",
+				"model":    "Smart-UPS 1500",
+				"serial":   "AS1234",
+			},
+			map[string]interface{}{
+				"battery_charge":  100.0,
+				"ups_load":        23.0,
+				"status":          "OL",
+				"online":          true,
+				"on_battery":      false,
+				"low_battery":     false,
+				"replace_battery": false,
+				"overload":        false,
+				"bypass":          false,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"ups",
+			map[string]string{
+				"source":   "nut",
+				"server":   server,
+				"ups_name": "pdu1",
+				"model":    "Switched PDU",
+			},
+			map[string]interface{}{"outlet_count": 2.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"ups_outlet",
+			map[string]string{
+				"source":      "nut",
+				"server":      server,
+				"ups_name":    "pdu1",
+				"outlet":      "1",
+				"description": "Server \"A\"",
+			},
+			map[string]interface{}{"current": 0.5, "status": "on"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"ups_outlet",
+			map[string]string{
+				"source":   "nut",
+				"server":   server,
+				"ups_name": "pdu1",
+				"outlet":   "2",
+			},
+			map[string]interface{}{"status": "off"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+
+	// The UPS goes on battery.
+	nutLock.Lock()
+	ups["ups1"]["ups.status"] = "OB DISCHRG LB"
+	nutLock.Unlock()
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(u.Gather))
+
+	var events []string
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "ups_event" {
+			event, _ := m.GetTag("event")
+			events = append(events, event)
+			status, _ := m.GetField("previous_status")
+			require.Equal(t, "OL", status)
+		}
+	}
+	require.ElementsMatch(t, []string{"on_battery", "dischrg", "low_battery"}, events)
+}
+
+func TestGatherNUTLoginFailed(t *testing.T) {
+	l := nutServer(t, nil)
+	defer l.Close()
+
+	u := &UPS{
+		Timeout: internal.Duration{Duration: time.Second},
+		NUT:     []*NUTConfig{{Address: l.Addr().String(), Username: "monuser", Password: "wrong"}},
+	}
+	require.NoError(t, u.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, u.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "ACCESS-DENIED")
+}
+
+func TestNUTSplit(t *testing.T) {
+	words, err := nutSplit(`VAR ups1 device.mfr "American \"Power\" Conversion \\ Corp."`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"VAR", "ups1", "device.mfr", `American "Power" Conversion \ Corp.`}, words)
+
+	words, err = nutSplit(`VAR ups1 ups.test.result ""`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"VAR", "ups1", "ups.test.result", ""}, words)
+
+	_, err = nutSplit(`VAR ups1 device.mfr "APC`)
+	require.Error(t, err)
+}
+
+// modbusServer answers the requests of the holding registers with the
+// registers.
+func modbusServer(t *testing.T, registers map[uint16]uint16) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveModbus(conn, registers)
+		}
+	}()
+	return l
+}
+
+func serveModbus(conn net.Conn, registers map[uint16]uint16) {
+	defer conn.Close()
+	for {
+		req := make([]byte, 12)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		address := binary.BigEndian.Uint16(req[8:])
+		count := binary.BigEndian.Uint16(req[10:])
+
+		resp := make([]byte, 9, 9+2*count)
+		copy(resp, req[:4])
+		resp[6] = req[6]
+		resp[7] = req[7]
+		for i := uint16(0); i < count; i++ {
+			value, ok := registers[address+i]
+			if !ok {
+				// Illegal data address.
+				resp[7] |= 0x80
+				resp[8] = 2
+				resp = resp[:9]
+				break
+			}
+			resp = append(resp, byte(value>>8), byte(value))
+		}
+		if resp[7] == req[7] {
+			resp[8] = byte(2 * count)
+		}
+		binary.BigEndian.PutUint16(resp[4:], uint16(len(resp)-6))
+		if _, err := conn.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func TestGatherModbusAPC(t *testing.T) {
+	registers := map[uint16]uint16{
+		0:   0, // UPSStatus_BF
+		1:   1 << 2,
+		128: 0, // RunTimeRemaining
+		129: 1800,
+		130: 100 * 512, // StateOfCharge_Pct
+		131: 27 * 32,   // BatteryVoltage
+		135: 0xff00,    // BatteryTemperature, -2 * 128
+		136: 25 * 256,  // OutputRealPowerPct
+		140: 3 * 32,
+		142: 230 * 64,
+		144: 50 * 128,
+		151: 231 * 64,
+		150: 50 * 128,
+	}
+	l := modbusServer(t, registers)
+	defer l.Close()
+
+	u := &UPS{
+		Timeout: internal.Duration{Duration: time.Second},
+		Modbus: []*ModbusConfig{{
+			Name:    "rack1",
+			Address: l.Addr().String(),
+			Device:  "apc",
+			Registers: []Register{
+				{Name: "input_frequency", Address: 150, Type: "uint16", Scale: 1.0 / 128},
+			},
+		}},
+	}
+	require.NoError(t, u.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(u.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"ups",
+			map[string]string{
+				"source":   "modbus",
+				"server":   l.Addr().String(),
+				"ups_name": "rack1",
+			},
+			map[string]interface{}{
+				"battery_runtime":     1800.0,
+				"battery_charge":      100.0,
+				"battery_voltage":     27.0,
+				"battery_temperature": -2.0,
+				"ups_load":            25.0,
+				"output_current":      3.0,
+				"output_voltage":      230.0,
+				"output_frequency":    50.0,
+				"input_voltage":       231.0,
+				"input_frequency":     50.0,
+				"status":              "OB",
+				"online":              false,
+				"on_battery":          true,
+				"low_battery":         false,
+				"replace_battery":     false,
+				"overload":            false,
+				"bypass":              false,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherModbusException(t *testing.T) {
+	l := modbusServer(t, map[uint16]uint16{})
+	defer l.Close()
+
+	u := &UPS{
+		Timeout: internal.Duration{Duration: time.Second},
+		Modbus: []*ModbusConfig{{
+			Name:      "eaton",
+			Address:   l.Addr().String(),
+			Registers: []Register{{Name: "ups_load", Address: 10, Type: "uint16"}},
+		}},
+	}
+	require.NoError(t, u.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, u.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "exception 2")
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&UPS{}).Init())
+	require.Error(t, (&UPS{NUT: []*NUTConfig{{Address: "udp://127.0.0.1"}}}).Init())
+	require.Error(t, (&UPS{Modbus: []*ModbusConfig{{Name: "ups", Address: "127.0.0.1"}}}).Init())
+	require.Error(t, (&UPS{Modbus: []*ModbusConfig{{Name: "ups", Address: "127.0.0.1", Device: "other"}}}).Init())
+	require.Error(t, (&UPS{Modbus: []*ModbusConfig{{
+		Name:      "ups",
+		Address:   "127.0.0.1",
+		Registers: []Register{{Name: "load", Type: "float"}},
+	}}}).Init())
+
+	u := &UPS{NUT: []*NUTConfig{{}}}
+	require.NoError(t, u.Init())
+	require.Equal(t, "127.0.0.1:3493", u.NUT[0].Address)
+}
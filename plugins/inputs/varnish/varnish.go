@@ -6,7 +6,9 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +29,8 @@ type Varnish struct {
 	InstanceName string
 	Timeout      internal.Duration
 
+	UseSharedMemory bool
+
 	filter filter.Filter
 	run    runner
 }
@@ -35,6 +39,10 @@ var defaultStats = []string{"MAIN.cache_hit", "MAIN.cache_miss", "MAIN.uptime"}
 var defaultBinary = "/usr/bin/varnishstat"
 var defaultTimeout = internal.Duration{Duration: time.Second}
 
+// stateDir is the parent of the working directories of the varnishd
+// instances.
+var stateDir = "/var/lib/varnish"
+
 var sampleConfig = `
   ## If running as a restricted user you can prepend sudo for additional access:
   #use_sudo = false
@@ -54,6 +62,15 @@ var sampleConfig = `
 
   ## Timeout for varnishstat command
   # timeout = "1s"
+
+  ## Read the counters from the shared memory of varnishd instead of running
+  ## varnishstat, without exec or sudo.  Varnish 6.0 or later is required and
+  ## the working directory of the instance must be readable, such as by the
+  ## varnish group.  The working directory is the instance_name if it is a
+  ## path, or /var/lib/varnish/<instance_name> with the host name as default
+  ## instance name.  The backend counters are reported in the varnish_backend
+  ## measurement, tagged with the vcl and the backend.
+  # use_shared_memory = false
 `
 
 func (s *Varnish) Description() string {
@@ -115,6 +132,10 @@ func (s *Varnish) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 
+	if s.UseSharedMemory {
+		return s.gatherSharedMemory(acc)
+	}
+
 	out, err := s.run(s.Binary, s.UseSudo, s.InstanceName, s.Timeout)
 	if err != nil {
 		return fmt.Errorf("error gathering metrics: %s", err)
@@ -168,6 +189,80 @@ func (s *Varnish) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// gatherSharedMemory reads the counters of the shared memory, the counters of
+// the backends being tagged with their vcl and name.
+func (s *Varnish) gatherSharedMemory(acc telegraf.Accumulator) error {
+	workdir, err := s.workdir()
+	if err != nil {
+		return err
+	}
+	counters, err := readVSM(workdir)
+	if err != nil {
+		return fmt.Errorf("error gathering metrics: %s", err)
+	}
+
+	sectionMap := make(map[string]map[string]interface{})
+	backends := make(map[string]map[string]interface{})
+	for _, c := range counters {
+		stat := c.ident + "." + c.name
+		if !s.filter.Match(stat) {
+			continue
+		}
+
+		parts := strings.SplitN(c.ident, ".", 2)
+		if parts[0] == "VBE" && len(parts) == 2 {
+			if _, ok := backends[parts[1]]; !ok {
+				backends[parts[1]] = make(map[string]interface{})
+			}
+			backends[parts[1]][c.name] = c.value
+			// The bits of happy are the results of the last health
+			// probes, the lowest one being the last probe.
+			if c.name == "happy" {
+				backends[parts[1]]["healthy"] = c.value&1 == 1
+			}
+			continue
+		}
+
+		section := parts[0]
+		field := strings.TrimPrefix(stat, section+".")
+		if _, ok := sectionMap[section]; !ok {
+			sectionMap[section] = make(map[string]interface{})
+		}
+		sectionMap[section][field] = c.value
+	}
+
+	for section, fields := range sectionMap {
+		acc.AddFields("varnish", fields, map[string]string{"section": section})
+	}
+	for backend, fields := range backends {
+		tags := map[string]string{"backend": backend}
+		// The backends are named after their vcl, unless their name has no
+		// vcl as for the backends of some vmods.
+		if parts := strings.SplitN(backend, ".", 2); len(parts) == 2 {
+			tags["vcl"] = parts[0]
+			tags["backend"] = parts[1]
+		}
+		acc.AddFields("varnish_backend", fields, tags)
+	}
+	return nil
+}
+
+// workdir returns the working directory of the varnishd instance.
+func (s *Varnish) workdir() (string, error) {
+	if filepath.IsAbs(s.InstanceName) {
+		return s.InstanceName, nil
+	}
+	name := s.InstanceName
+	if name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		name = hostname
+	}
+	return filepath.Join(stateDir, name), nil
+}
+
 func init() {
 	inputs.Add("varnish", func() telegraf.Input {
 		return &Varnish{
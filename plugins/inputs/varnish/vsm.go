@@ -0,0 +1,227 @@
+// +build !windows
+
+package varnish
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The shared memory of varnishd is in files of the working directory, the
+// manager and the child process each listing their segments in an index.
+var vsmDirs = []string{"_.vsm_mgt", "_.vsm_child"}
+
+const (
+	// vscClass are the segments of the counters, vscDocClass the segments
+	// of their JSON description.
+	vscClass    = "Stat"
+	vscDocClass = "StatDoc"
+)
+
+// segment is a segment of the shared memory listed in an index.
+type segment struct {
+	file   string
+	offset int64
+	length int64
+	class  string
+	ident  string
+}
+
+// counter is a counter of a segment, its name being the segment ident
+// followed by the counter name, such as "MAIN.uptime".
+type counter struct {
+	ident string
+	name  string
+	value uint64
+}
+
+// readIndex returns the segments of the index of the directory, the removed
+// segments are not returned.
+func readIndex(dir string) ([]segment, error) {
+	f, err := os.Open(filepath.Join(dir, "_.index"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var segments []segment
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 6 || (fields[0] != "+" && fields[0] != "-") {
+			continue
+		}
+		var seg segment
+		if _, err := fmt.Sscanf(fields[2]+" "+fields[3], "%d %d", &seg.offset, &seg.length); err != nil {
+			return nil, fmt.Errorf("invalid index line %q", scanner.Text())
+		}
+		seg.file, seg.class, seg.ident = fields[1], fields[4], fields[5]
+
+		if fields[0] == "+" {
+			segments = append(segments, seg)
+			continue
+		}
+		for i, s := range segments {
+			if s == seg {
+				segments = append(segments[:i], segments[i+1:]...)
+				break
+			}
+		}
+	}
+	return segments, scanner.Err()
+}
+
+// vscHead is the head of the counter and description segments.
+type vscHead struct {
+	ready      uint64
+	bodyOffset uint64
+	docID      uint64
+}
+
+// parseHead parses the head of a segment.  Varnish 6.0 heads are the ready
+// flag, the body offset and the doc id, later versions have a posted flag
+// after the ready flag: the body follows the head, so the offset tells the
+// layout.
+func parseHead(buf []byte) (*vscHead, error) {
+	// The segments are in the byte order of the host.
+	word := func(i int) uint64 {
+		return binary.LittleEndian.Uint64(buf[i*8:])
+	}
+	switch {
+	case len(buf) >= 32 && word(2) == 32:
+		return &vscHead{ready: word(0), bodyOffset: 32, docID: word(3)}, nil
+	case len(buf) >= 24 && word(1) == 24:
+		return &vscHead{ready: word(0), bodyOffset: 24, docID: word(2)}, nil
+	}
+	return nil, errors.New("invalid segment head")
+}
+
+// vscDoc is the description of the counters of a segment.
+type vscDoc struct {
+	Name     string             `json:"name"`
+	Elements map[string]vscElem `json:"elem"`
+}
+
+type vscElem struct {
+	Type  string      `json:"type"`
+	Index json.Number `json:"index"`
+}
+
+// readSegment reads the segment in the directory.
+func readSegment(dir string, seg segment) ([]byte, error) {
+	f, err := os.Open(filepath.Join(dir, seg.file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, seg.length)
+	if _, err := f.ReadAt(buf, seg.offset); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readVSM reads the counters of the shared memory of the working directory
+// of varnishd, sorted by ident.
+func readVSM(workdir string) ([]counter, error) {
+	var counters []counter
+	found := false
+	for _, name := range vsmDirs {
+		dir := filepath.Join(workdir, name)
+		segments, err := readIndex(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+
+		c, err := readCounters(dir, segments)
+		if err != nil {
+			return nil, err
+		}
+		counters = append(counters, c...)
+	}
+	if !found {
+		return nil, fmt.Errorf("no shared memory in %s, is varnishd running?", workdir)
+	}
+
+	sort.SliceStable(counters, func(i, j int) bool {
+		return counters[i].ident < counters[j].ident
+	})
+	return counters, nil
+}
+
+// readCounters reads the counters of the segments, with the descriptions of
+// the description segments.
+func readCounters(dir string, segments []segment) ([]counter, error) {
+	type body struct {
+		ident string
+		head  *vscHead
+		buf   []byte
+	}
+
+	docs := make(map[uint64]*vscDoc)
+	var bodies []body
+	for _, seg := range segments {
+		if seg.class != vscClass && seg.class != vscDocClass {
+			continue
+		}
+		buf, err := readSegment(dir, seg)
+		if err != nil {
+			return nil, err
+		}
+		head, err := parseHead(buf)
+		if err != nil {
+			return nil, fmt.Errorf("segment %s: %v", seg.ident, err)
+		}
+		// The segments are not ready while being created.
+		if head.ready == 0 {
+			continue
+		}
+
+		if seg.class == vscDocClass {
+			doc := buf[head.bodyOffset:]
+			if i := bytes.IndexByte(doc, 0); i >= 0 {
+				doc = doc[:i]
+			}
+			var d vscDoc
+			if err := json.Unmarshal(doc, &d); err != nil {
+				return nil, fmt.Errorf("segment %s: %v", seg.ident, err)
+			}
+			docs[head.docID] = &d
+			continue
+		}
+		bodies = append(bodies, body{ident: seg.ident, head: head, buf: buf[head.bodyOffset:]})
+	}
+
+	var counters []counter
+	for _, b := range bodies {
+		doc, ok := docs[b.head.docID]
+		if !ok {
+			continue
+		}
+		for name, elem := range doc.Elements {
+			index, err := elem.Index.Int64()
+			if err != nil || index < 0 || index+8 > int64(len(b.buf)) {
+				return nil, fmt.Errorf("segment %s: invalid index of %s", b.ident, name)
+			}
+			counters = append(counters, counter{
+				ident: b.ident,
+				name:  name,
+				value: binary.LittleEndian.Uint64(b.buf[index:]),
+			})
+		}
+	}
+	return counters, nil
+}
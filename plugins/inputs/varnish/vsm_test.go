@@ -0,0 +1,199 @@
+// +build !windows
+
+package varnish
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// vsmWriter writes the segments of a fake shared memory directory.
+type vsmWriter struct {
+	dir    string
+	posted bool
+	index  []string
+	docID  uint64
+}
+
+func newVSMWriter(t *testing.T, workdir, name string, posted bool) *vsmWriter {
+	dir := filepath.Join(workdir, name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	return &vsmWriter{dir: dir, posted: posted}
+}
+
+func (w *vsmWriter) head(ready, docID uint64) []byte {
+	words := []uint64{ready, 24, docID}
+	if w.posted {
+		words = []uint64{ready, 1, 32, docID}
+	}
+	buf := make([]byte, 8*len(words))
+	for i, word := range words {
+		binary.LittleEndian.PutUint64(buf[i*8:], word)
+	}
+	return buf
+}
+
+// add adds the counters of a segment and its description.
+func (w *vsmWriter) add(t *testing.T, ident string, ready uint64, names []string, values []uint64) {
+	w.docID++
+
+	var elems []string
+	for i, name := range names {
+		elems = append(elems, fmt.Sprintf(`"%s": {"type": "MAIN", "ctype": "uint64", "index": %d}`, name, i*8))
+	}
+	doc := fmt.Sprintf(`{"version": "1", "name": "%s", "elem": {%s}}`, ident, strings.Join(elems, ", "))
+	w.write(t, "StatDoc", ident, append(append(w.head(1, w.docID), doc...), 0, 0, 0))
+
+	body := w.head(ready, w.docID)
+	for _, v := range values {
+		body = append(body, make([]byte, 8)...)
+		binary.LittleEndian.PutUint64(body[len(body)-8:], v)
+	}
+	w.write(t, "Stat", ident, body)
+}
+
+func (w *vsmWriter) write(t *testing.T, class, ident string, buf []byte) {
+	file := fmt.Sprintf("_.%s.%d", class, len(w.index))
+	// The segments are written after a padding, as in the shared files of
+	// varnishd.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(w.dir, file), append(make([]byte, 16), buf...), 0644))
+	w.index = append(w.index, fmt.Sprintf("+ %s 16 %d %s %s", file, len(buf), class, ident))
+}
+
+func (w *vsmWriter) close(t *testing.T) {
+	index := "# 1234 5678\n" + strings.Join(w.index, "\n") + "\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(w.dir, "_.index"), []byte(index), 0644))
+}
+
+func newWorkdir(t *testing.T, posted bool) string {
+	workdir, err := ioutil.TempDir("", "varnish")
+	require.NoError(t, err)
+
+	mgt := newVSMWriter(t, workdir, "_.vsm_mgt", posted)
+	mgt.add(t, "MGT", 1, []string{"uptime", "child_start"}, []uint64{3600, 1})
+	mgt.close(t)
+
+	child := newVSMWriter(t, workdir, "_.vsm_child", posted)
+	child.add(t, "MAIN", 1, []string{"uptime", "cache_hit", "cache_miss"}, []uint64{3599, 42, 7})
+	child.add(t, "VBE.boot.default", 1, []string{"happy", "bereq_hdrbytes", "conn"}, []uint64{0xff, 1024, 2})
+	child.add(t, "VBE.boot.fallback", 1, []string{"happy", "conn"}, []uint64{0xfe, 0})
+	child.add(t, "VBE.boot.new", 0, []string{"happy"}, []uint64{1})
+	child.close(t)
+	return workdir
+}
+
+func TestGatherSharedMemory(t *testing.T) {
+	for _, posted := range []bool{false, true} {
+		t.Run(fmt.Sprintf("posted=%v", posted), func(t *testing.T) {
+			workdir := newWorkdir(t, posted)
+			defer os.RemoveAll(workdir)
+
+			v := &Varnish{
+				Stats:           []string{"*"},
+				InstanceName:    workdir,
+				UseSharedMemory: true,
+			}
+			var acc testutil.Accumulator
+			require.NoError(t, v.Gather(&acc))
+
+			expected := []telegraf.Metric{
+				testutil.MustMetric("varnish",
+					map[string]string{"section": "MGT"},
+					map[string]interface{}{"uptime": uint64(3600), "child_start": uint64(1)},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("varnish",
+					map[string]string{"section": "MAIN"},
+					map[string]interface{}{"uptime": uint64(3599), "cache_hit": uint64(42), "cache_miss": uint64(7)},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("varnish_backend",
+					map[string]string{"vcl": "boot", "backend": "default"},
+					map[string]interface{}{"happy": uint64(0xff), "healthy": true, "bereq_hdrbytes": uint64(1024), "conn": uint64(2)},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("varnish_backend",
+					map[string]string{"vcl": "boot", "backend": "fallback"},
+					map[string]interface{}{"happy": uint64(0xfe), "healthy": false, "conn": uint64(0)},
+					time.Unix(0, 0),
+				),
+			}
+			testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+		})
+	}
+}
+
+func TestGatherSharedMemoryFilter(t *testing.T) {
+	workdir := newWorkdir(t, true)
+	defer os.RemoveAll(workdir)
+
+	v := &Varnish{
+		Stats:           []string{"MAIN.cache_*", "VBE.*.happy"},
+		InstanceName:    workdir,
+		UseSharedMemory: true,
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, v.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("varnish",
+			map[string]string{"section": "MAIN"},
+			map[string]interface{}{"cache_hit": uint64(42), "cache_miss": uint64(7)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("varnish_backend",
+			map[string]string{"vcl": "boot", "backend": "default"},
+			map[string]interface{}{"happy": uint64(0xff), "healthy": true},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("varnish_backend",
+			map[string]string{"vcl": "boot", "backend": "fallback"},
+			map[string]interface{}{"happy": uint64(0xfe), "healthy": false},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestReadIndexRemoved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "varnish")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	index := `# 1234 5678
++ _.Stat.1 0 56 Stat MAIN
++ _.Stat.2 0 56 Stat VBE.boot.default
+- _.Stat.1 0 56 Stat MAIN
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "_.index"), []byte(index), 0644))
+
+	segments, err := readIndex(dir)
+	require.NoError(t, err)
+	require.Equal(t, []segment{
+		{file: "_.Stat.2", offset: 0, length: 56, class: "Stat", ident: "VBE.boot.default"},
+	}, segments)
+}
+
+func TestGatherSharedMemoryNotRunning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "varnish")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	v := &Varnish{
+		Stats:           []string{"*"},
+		InstanceName:    dir,
+		UseSharedMemory: true,
+	}
+	var acc testutil.Accumulator
+	require.Error(t, v.Gather(&acc))
+}
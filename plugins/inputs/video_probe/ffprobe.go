@@ -0,0 +1,191 @@
+package video_probe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// runFFprobe runs ffprobe and returns its output, it is replaced in tests.
+var runFFprobe = func(path string, timeout time.Duration, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := internal.RunTimeout(cmd, timeout); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecName    string `json:"codec_name"`
+		Width        int64  `json:"width"`
+		Height       int64  `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+	} `json:"streams"`
+	Packets []struct {
+		PTSTime string `json:"pts_time"`
+		Size    string `json:"size"`
+		Flags   string `json:"flags"`
+	} `json:"packets"`
+}
+
+// probeResult is the measure of the video of a stream.
+type probeResult struct {
+	codec     string
+	width     int64
+	height    int64
+	frameRate float64
+	// bitrate is the bitrate of the video in bits per second, zero if not
+	// enough packets were read.
+	bitrate float64
+	// keyframeInterval is the mean interval between keyframes, zero if less
+	// than two keyframes were read.
+	keyframeInterval time.Duration
+	keyframes        int
+}
+
+func (r *probeResult) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"keyframes": r.keyframes,
+	}
+	if r.width > 0 && r.height > 0 {
+		fields["width"] = r.width
+		fields["height"] = r.height
+	}
+	if r.frameRate > 0 {
+		fields["frame_rate"] = r.frameRate
+	}
+	if r.bitrate > 0 {
+		fields["bitrate"] = r.bitrate
+	}
+	if r.keyframeInterval > 0 {
+		fields["keyframe_interval"] = r.keyframeInterval.Seconds()
+	}
+	return fields
+}
+
+// probe reads the first video stream of the target for the probe duration.
+func (v *VideoProbe) probe(target, protocol string) (*probeResult, error) {
+	args := []string{
+		"-v", "error",
+		"-print_format", "json",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_name,width,height,avg_frame_rate:packet=pts_time,size,flags",
+		"-read_intervals", "%+" + strconv.FormatFloat(v.ProbeDuration.Duration.Seconds(), 'f', -1, 64),
+	}
+	if protocol == "rtsp" {
+		args = append(args, "-rtsp_transport", v.RTSPTransport)
+	}
+	args = append(args, target)
+
+	out, err := runFFprobe(v.FFprobePath, v.Timeout.Duration, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %v", err)
+	}
+	return parseFFprobe(out)
+}
+
+func parseFFprobe(out []byte) (*probeResult, error) {
+	var output ffprobeOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return nil, fmt.Errorf("error decoding ffprobe output: %v", err)
+	}
+	if len(output.Streams) == 0 {
+		return nil, errors.New("no video stream")
+	}
+	if len(output.Packets) == 0 {
+		return nil, errors.New("no video packet received")
+	}
+
+	s := output.Streams[0]
+	r := &probeResult{
+		codec:     s.CodecName,
+		width:     s.Width,
+		height:    s.Height,
+		frameRate: parseRate(s.AvgFrameRate),
+	}
+
+	var (
+		first, last    float64
+		hasPTS         bool
+		size           int64
+		firstKey       float64
+		lastKey        float64
+		keyframesOnPTS int
+	)
+	for _, p := range output.Packets {
+		if strings.HasPrefix(p.Flags, "K") {
+			r.keyframes++
+		}
+		if n, err := strconv.ParseInt(p.Size, 10, 64); err == nil {
+			size += n
+		}
+		pts, err := strconv.ParseFloat(p.PTSTime, 64)
+		if err != nil {
+			// The time is N/A for packets without timestamp.
+			continue
+		}
+		if !hasPTS || pts < first {
+			first = pts
+		}
+		if !hasPTS || pts > last {
+			last = pts
+		}
+		hasPTS = true
+
+		if strings.HasPrefix(p.Flags, "K") {
+			if keyframesOnPTS == 0 || pts < firstKey {
+				firstKey = pts
+			}
+			if keyframesOnPTS == 0 || pts > lastKey {
+				lastKey = pts
+			}
+			keyframesOnPTS++
+		}
+	}
+
+	// The last packet lasts one frame.
+	span := last - first
+	if r.frameRate > 0 {
+		span += 1 / r.frameRate
+	}
+	if hasPTS && span > 0 {
+		r.bitrate = float64(size*8) / span
+	}
+	if keyframesOnPTS > 1 {
+		interval := (lastKey - firstKey) / float64(keyframesOnPTS-1)
+		r.keyframeInterval = time.Duration(interval * float64(time.Second))
+	}
+	return r, nil
+}
+
+// parseRate parses a rate of ffprobe, such as "30000/1001", zero is returned
+// when it is unknown.
+func parseRate(s string) float64 {
+	parts := strings.SplitN(s, "/", 2)
+	num, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0
+	}
+	if len(parts) == 1 {
+		return num
+	}
+	den, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || den == 0 {
+		return 0
+	}
+	return num / den
+}
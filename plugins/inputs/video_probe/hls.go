@@ -0,0 +1,133 @@
+package video_probe
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// playlist is an HLS playlist, either a master playlist of variants or a
+// media playlist of segments.
+type playlist struct {
+	variants []variant
+
+	targetDuration int64
+	mediaSequence  int64
+	segments       []segment
+	endList        bool
+}
+
+type variant struct {
+	uri       string
+	bandwidth int64
+}
+
+type segment struct {
+	duration float64
+	// programDateTime is the time of the first sample of the segment, zero
+	// if the playlist has no EXT-X-PROGRAM-DATE-TIME tags.
+	programDateTime time.Time
+}
+
+var bandwidthRe = regexp.MustCompile(`(?:^|,)BANDWIDTH=(\d+)`)
+
+// parsePlaylist parses the tags of the playlist used to probe the stream.
+func parsePlaylist(r io.Reader) (*playlist, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "#EXTM3U" {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid playlist: missing #EXTM3U")
+	}
+
+	p := &playlist{}
+	var (
+		inVariant  bool
+		bandwidth  int64
+		inSegment  bool
+		duration   float64
+		dateTime   time.Time
+		lastDate   time.Time
+		lastLength float64
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "#") {
+			switch {
+			case inVariant:
+				p.variants = append(p.variants, variant{uri: line, bandwidth: bandwidth})
+				inVariant = false
+			case inSegment:
+				// Segments without a date follow the previous segment.
+				if dateTime.IsZero() && !lastDate.IsZero() {
+					dateTime = lastDate.Add(time.Duration(lastLength * float64(time.Second)))
+				}
+				p.segments = append(p.segments, segment{duration: duration, programDateTime: dateTime})
+				lastDate, lastLength = dateTime, duration
+				inSegment, dateTime = false, time.Time{}
+			}
+			continue
+		}
+
+		tag, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			tag, value = line[:i], line[i+1:]
+		}
+		var err error
+		switch tag {
+		case "#EXT-X-STREAM-INF":
+			inVariant, bandwidth = true, 0
+			if m := bandwidthRe.FindStringSubmatch(value); m != nil {
+				bandwidth, err = strconv.ParseInt(m[1], 10, 64)
+			}
+		case "#EXT-X-TARGETDURATION":
+			p.targetDuration, err = strconv.ParseInt(value, 10, 64)
+		case "#EXT-X-MEDIA-SEQUENCE":
+			p.mediaSequence, err = strconv.ParseInt(value, 10, 64)
+		case "#EXTINF":
+			inSegment = true
+			duration, err = strconv.ParseFloat(strings.SplitN(value, ",", 2)[0], 64)
+		case "#EXT-X-PROGRAM-DATE-TIME":
+			dateTime, err = time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				// The offset of the time zone may lack the colon.
+				dateTime, err = time.Parse("2006-01-02T15:04:05.999999999Z0700", value)
+			}
+		case "#EXT-X-DISCONTINUITY":
+			lastDate = time.Time{}
+		case "#EXT-X-ENDLIST":
+			p.endList = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid playlist tag %q: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// lag returns how far behind the time the end of the last segment is, false
+// is returned for ended playlists and playlists without dates.
+func (p *playlist) lag(now time.Time) (time.Duration, bool) {
+	if p.endList || len(p.segments) == 0 {
+		return 0, false
+	}
+	last := p.segments[len(p.segments)-1]
+	if last.programDateTime.IsZero() {
+		return 0, false
+	}
+	end := last.programDateTime.Add(time.Duration(last.duration * float64(time.Second)))
+	return now.Sub(end), true
+}
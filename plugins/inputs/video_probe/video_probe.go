@@ -0,0 +1,232 @@
+package video_probe
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Streams to probe.  RTSP and RTMP streams are given by their URL, HLS
+  ## streams by the http or https URL of their playlist.
+  urls = [
+    "rtsp://192.168.1.10:554/stream1",
+    "https://example.com/live/index.m3u8",
+  ]
+
+  ## Path to the ffprobe program of FFmpeg, looked up in the PATH if not set.
+  # ffprobe_path = "/usr/bin/ffprobe"
+
+  ## Duration of the stream read to measure the bitrate and the keyframe
+  ## interval.  It should span a few keyframes.
+  # probe_duration = "5s"
+
+  ## Timeout of the probe of a stream, including the opening of the stream.
+  # timeout = "20s"
+
+  ## Transport of the RTSP streams; one of "tcp" or "udp".
+  # rtsp_transport = "tcp"
+`
+
+// VideoProbe probes the availability, bitrate and keyframe interval of video
+// streams, and the segment lag of HLS streams.
+type VideoProbe struct {
+	URLs          []string          `toml:"urls"`
+	FFprobePath   string            `toml:"ffprobe_path"`
+	ProbeDuration internal.Duration `toml:"probe_duration"`
+	Timeout       internal.Duration `toml:"timeout"`
+	RTSPTransport string            `toml:"rtsp_transport"`
+
+	streams []*stream
+	client  *http.Client
+}
+
+type stream struct {
+	url      *url.URL
+	protocol string
+	// tag is the URL without the password, for the url tag.
+	tag string
+}
+
+func (v *VideoProbe) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *VideoProbe) Description() string {
+	return "Probe the health of RTSP, RTMP and HLS video streams"
+}
+
+func (v *VideoProbe) Init() error {
+	if len(v.URLs) == 0 {
+		return errors.New("no url configured")
+	}
+
+	switch v.RTSPTransport {
+	case "tcp", "udp":
+	case "":
+		v.RTSPTransport = "tcp"
+	default:
+		return fmt.Errorf("unknown rtsp_transport %q", v.RTSPTransport)
+	}
+
+	if v.FFprobePath == "" {
+		path, err := exec.LookPath("ffprobe")
+		if err != nil {
+			return errors.New("ffprobe not found, set ffprobe_path")
+		}
+		v.FFprobePath = path
+	}
+
+	v.streams = make([]*stream, 0, len(v.URLs))
+	for _, rawURL := range v.URLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("invalid url %q: %v", rawURL, err)
+		}
+
+		var protocol string
+		switch u.Scheme {
+		case "rtsp", "rtsps":
+			protocol = "rtsp"
+		case "rtmp", "rtmps":
+			protocol = "rtmp"
+		case "http", "https":
+			protocol = "hls"
+		default:
+			return fmt.Errorf("unsupported scheme %q of url %q", u.Scheme, rawURL)
+		}
+
+		tag := *u
+		if u.User != nil {
+			tag.User = url.User(u.User.Username())
+		}
+		v.streams = append(v.streams, &stream{url: u, protocol: protocol, tag: tag.String()})
+	}
+
+	v.client = &http.Client{Timeout: v.Timeout.Duration}
+	return nil
+}
+
+func (v *VideoProbe) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, s := range v.streams {
+		wg.Add(1)
+		go func(s *stream) {
+			defer wg.Done()
+			v.gatherStream(acc, s)
+		}(s)
+	}
+	wg.Wait()
+	return nil
+}
+
+// gatherStream probes the stream, the stream is reported unavailable when its
+// playlist or the probe fails.
+func (v *VideoProbe) gatherStream(acc telegraf.Accumulator, s *stream) {
+	tags := map[string]string{
+		"url":      s.tag,
+		"protocol": s.protocol,
+	}
+	fields := make(map[string]interface{})
+	now := time.Now()
+
+	target := s.url.String()
+	if s.protocol == "hls" {
+		media, err := v.gatherPlaylist(s.url, fields, now)
+		if err != nil {
+			acc.AddError(fmt.Errorf("%s: %v", s.tag, err))
+			acc.AddFields("video_probe", map[string]interface{}{"available": 0}, tags, now)
+			return
+		}
+		target = media
+	}
+
+	result, err := v.probe(target, s.protocol)
+	if err != nil {
+		acc.AddError(fmt.Errorf("%s: %v", s.tag, err))
+		acc.AddFields("video_probe", map[string]interface{}{"available": 0}, tags, now)
+		return
+	}
+
+	if result.codec != "" {
+		tags["codec"] = result.codec
+	}
+	fields["available"] = 1
+	for name, value := range result.fields() {
+		fields[name] = value
+	}
+	acc.AddFields("video_probe", fields, tags, now)
+}
+
+// gatherPlaylist adds the fields of the HLS playlist of the stream and
+// returns the URL of the media playlist to probe, the variant of the highest
+// bandwidth of a master playlist.
+func (v *VideoProbe) gatherPlaylist(u *url.URL, fields map[string]interface{}, now time.Time) (string, error) {
+	p, err := v.getPlaylist(u)
+	if err != nil {
+		return "", err
+	}
+
+	if len(p.variants) > 0 {
+		best := p.variants[0]
+		for _, variant := range p.variants[1:] {
+			if variant.bandwidth > best.bandwidth {
+				best = variant
+			}
+		}
+		ref, err := url.Parse(best.uri)
+		if err != nil {
+			return "", fmt.Errorf("invalid variant uri %q: %v", best.uri, err)
+		}
+		u = u.ResolveReference(ref)
+		if p, err = v.getPlaylist(u); err != nil {
+			return "", err
+		}
+		if len(p.variants) > 0 {
+			return "", errors.New("variant is a master playlist")
+		}
+		fields["variant_bandwidth"] = best.bandwidth
+	}
+
+	if len(p.segments) == 0 {
+		return "", errors.New("playlist without segments")
+	}
+	fields["segments"] = len(p.segments)
+	fields["target_duration"] = p.targetDuration
+	fields["media_sequence"] = p.mediaSequence
+	if lag, ok := p.lag(now); ok {
+		fields["segment_lag"] = lag.Seconds()
+	}
+	return u.String(), nil
+}
+
+func (v *VideoProbe) getPlaylist(u *url.URL) (*playlist, error) {
+	resp, err := v.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d for playlist %s", resp.StatusCode, strings.TrimPrefix(u.Path, "/"))
+	}
+	return parsePlaylist(resp.Body)
+}
+
+func init() {
+	inputs.Add("video_probe", func() telegraf.Input {
+		return &VideoProbe{
+			ProbeDuration: internal.Duration{Duration: 5 * time.Second},
+			Timeout:       internal.Duration{Duration: 20 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package vulnerabilities
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// trivyResult is the result of the scan of a target of an artifact, such as
+// the OS packages of an image.
+type trivyResult struct {
+	Target          string `json:"Target"`
+	Vulnerabilities []struct {
+		FixedVersion string `json:"FixedVersion"`
+		Severity     string `json:"Severity"`
+	} `json:"Vulnerabilities"`
+}
+
+// trivyReport is the report of Trivy 0.20 and later, the older versions
+// report the list of results only.
+type trivyReport struct {
+	SchemaVersion int           `json:"SchemaVersion"`
+	ArtifactName  string        `json:"ArtifactName"`
+	ArtifactType  string        `json:"ArtifactType"`
+	Results       []trivyResult `json:"Results"`
+}
+
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			Severity       string `json:"severity"`
+			FixedInVersion string `json:"fixedInVersion"`
+			Fix            struct {
+				Versions []string `json:"versions"`
+			} `json:"fix"`
+		} `json:"vulnerability"`
+	} `json:"matches"`
+	Source *struct {
+		Type   string          `json:"type"`
+		Target json.RawMessage `json:"target"`
+	} `json:"source"`
+}
+
+// parseReport returns the summary of a JSON report of Trivy or Grype.
+func parseReport(data []byte) (*summary, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty report")
+	}
+
+	// Older versions of Trivy report a list of results.
+	if data[0] == '[' {
+		var results []trivyResult
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, err
+		}
+		return trivySummary(&trivyReport{Results: results}), nil
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	if _, ok := keys["matches"]; ok {
+		var report grypeReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, err
+		}
+		return grypeSummary(&report), nil
+	}
+	_, hasResults := keys["Results"]
+	_, hasSchema := keys["SchemaVersion"]
+	if hasResults || hasSchema {
+		var report trivyReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, err
+		}
+		return trivySummary(&report), nil
+	}
+	return nil, errors.New("unknown report format")
+}
+
+func trivySummary(report *trivyReport) *summary {
+	s := &summary{
+		scanner: scannerTrivy,
+		target:  report.ArtifactName,
+		counts:  make(map[string]int),
+	}
+	switch report.ArtifactType {
+	case "container_image":
+		s.targetType = targetImage
+	case "filesystem":
+		s.targetType = targetFilesystem
+	}
+
+	for _, r := range report.Results {
+		// The target of the older reports is the target of the first
+		// result, the image followed by its OS such as
+		// "alpine:3.10 (alpine 3.10.2)".
+		if s.target == "" {
+			s.target = r.Target
+			if i := strings.Index(s.target, " ("); i > 0 {
+				s.target = s.target[:i]
+			}
+		}
+		for _, vuln := range r.Vulnerabilities {
+			s.counts[severity(vuln.Severity)]++
+			if vuln.FixedVersion != "" {
+				s.fixable++
+			}
+		}
+	}
+	return s
+}
+
+func grypeSummary(report *grypeReport) *summary {
+	s := &summary{
+		scanner: scannerGrype,
+		counts:  make(map[string]int),
+	}
+	if report.Source != nil {
+		switch report.Source.Type {
+		case "image":
+			s.targetType = targetImage
+			var target struct {
+				UserInput string `json:"userInput"`
+			}
+			if json.Unmarshal(report.Source.Target, &target) == nil {
+				s.target = target.UserInput
+			}
+		case "directory":
+			s.targetType = targetFilesystem
+			var target string
+			if json.Unmarshal(report.Source.Target, &target) == nil {
+				s.target = target
+			}
+		}
+	}
+
+	for _, m := range report.Matches {
+		vuln := m.Vulnerability
+		s.counts[severity(vuln.Severity)]++
+		if vuln.FixedInVersion != "" || len(vuln.Fix.Versions) > 0 {
+			s.fixable++
+		}
+	}
+	return s
+}
+
+// severity returns the field of the severity of a vulnerability.
+func severity(name string) string {
+	name = strings.ToLower(name)
+	for _, s := range severities {
+		if s == name {
+			return s
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,234 @@
+package vulnerabilities
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	scannerTrivy = "trivy"
+	scannerGrype = "grype"
+
+	targetImage      = "image"
+	targetFilesystem = "filesystem"
+)
+
+// severities are the severities reported as fields, the severities of the
+// scanners are mapped to them in lowercase.
+var severities = []string{"critical", "high", "medium", "low", "negligible", "unknown"}
+
+type runner func(timeout time.Duration, command string, args ...string) ([]byte, error)
+
+// Vulnerabilities gathers the number of vulnerabilities by severity of the
+// images and hosts scanned by Trivy or Grype.
+type Vulnerabilities struct {
+	Files       []string          `toml:"files"`
+	Scanner     string            `toml:"scanner"`
+	Binary      string            `toml:"binary"`
+	Images      []string          `toml:"images"`
+	Filesystems []string          `toml:"filesystems"`
+	TrivyServer string            `toml:"trivy_server"`
+	Timeout     internal.Duration `toml:"timeout"`
+
+	globs []*globpath.GlobPath
+	run   runner
+	now   func() time.Time
+}
+
+// summary is the number of vulnerabilities of a scanned target.
+type summary struct {
+	scanner    string
+	target     string
+	targetType string
+	counts     map[string]int
+	fixable    int
+}
+
+var sampleConfig = `
+  ## JSON reports of Trivy or Grype to read, the scanner is detected from the
+  ## content of the reports.  The paths may contain glob patterns.
+  files = ["/var/lib/trivy/reports/*.json"]
+
+  ## Scanner run to scan the images and filesystems below, "trivy" or
+  ## "grype", run from the PATH unless the binary is set.
+  # scanner = "trivy"
+  # binary = "/usr/local/bin/trivy"
+
+  ## Images and filesystems of the host scanned at each interval.  Scans are
+  ## expensive, set the interval of the plugin accordingly.
+  # images = ["docker.io/library/nginx:1.19"]
+  # filesystems = ["/"]
+
+  ## Address of a Trivy server to scan the images with in client mode, so the
+  ## vulnerability database is not downloaded by each host.
+  # trivy_server = "http://trivy.example.com:4954"
+
+  ## Timeout of a scan.
+  # timeout = "5m"
+`
+
+func (v *Vulnerabilities) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *Vulnerabilities) Description() string {
+	return "Read the number of vulnerabilities of images and hosts scanned by Trivy or Grype"
+}
+
+func (v *Vulnerabilities) Init() error {
+	switch v.Scanner {
+	case "":
+		v.Scanner = scannerTrivy
+	case scannerTrivy, scannerGrype:
+	default:
+		return fmt.Errorf("unknown scanner %q", v.Scanner)
+	}
+	if v.Binary == "" {
+		v.Binary = v.Scanner
+	}
+	if v.TrivyServer != "" && v.Scanner != scannerTrivy {
+		return fmt.Errorf("trivy_server requires the trivy scanner")
+	}
+
+	for _, file := range v.Files {
+		g, err := globpath.Compile(file)
+		if err != nil {
+			return fmt.Errorf("invalid file %q: %v", file, err)
+		}
+		v.globs = append(v.globs, g)
+	}
+	return nil
+}
+
+func (v *Vulnerabilities) Gather(acc telegraf.Accumulator) error {
+	for _, g := range v.globs {
+		for _, file := range g.Match() {
+			acc.AddError(v.gatherFile(acc, file))
+		}
+	}
+
+	// The scans run one after the other, to not load the host with several
+	// scans at once.
+	for _, image := range v.Images {
+		acc.AddError(v.gatherScan(acc, targetImage, image))
+	}
+	for _, path := range v.Filesystems {
+		acc.AddError(v.gatherScan(acc, targetFilesystem, path))
+	}
+	return nil
+}
+
+func (v *Vulnerabilities) gatherFile(acc telegraf.Accumulator, file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	s, err := parseReport(data)
+	if err != nil {
+		return fmt.Errorf("report %q: %v", file, err)
+	}
+	if s.target == "" {
+		s.target = file
+	}
+	v.add(acc, s, map[string]interface{}{
+		"report_age": v.now().Sub(info.ModTime()).Seconds(),
+	})
+	return nil
+}
+
+func (v *Vulnerabilities) gatherScan(acc telegraf.Accumulator, targetType, target string) error {
+	var args []string
+	switch v.Scanner {
+	case scannerTrivy:
+		command := "image"
+		if targetType == targetFilesystem {
+			command = "rootfs"
+		}
+		args = []string{command, "--quiet", "--format", "json"}
+		if v.TrivyServer != "" {
+			args = append(args, "--server", v.TrivyServer)
+		}
+		args = append(args, target)
+	case scannerGrype:
+		source := target
+		if targetType == targetFilesystem {
+			source = "dir:" + target
+		}
+		args = []string{source, "--quiet", "--output", "json"}
+	}
+
+	out, err := v.run(v.Timeout.Duration, v.Binary, args...)
+	if err != nil {
+		return fmt.Errorf("scan of %q: %v", target, err)
+	}
+	s, err := parseReport(out)
+	if err != nil {
+		return fmt.Errorf("scan of %q: %v", target, err)
+	}
+
+	// The target is tagged as configured rather than as resolved by the
+	// scanner, such as the digest of the image.
+	s.target = target
+	s.targetType = targetType
+	v.add(acc, s, map[string]interface{}{})
+	return nil
+}
+
+func (v *Vulnerabilities) add(acc telegraf.Accumulator, s *summary, fields map[string]interface{}) {
+	tags := map[string]string{
+		"scanner": s.scanner,
+		"target":  s.target,
+	}
+	if s.targetType != "" {
+		tags["target_type"] = s.targetType
+	}
+
+	total := 0
+	for _, severity := range severities {
+		fields[severity] = s.counts[severity]
+		total += s.counts[severity]
+	}
+	fields["total"] = total
+	fields["fixable"] = s.fixable
+	acc.AddFields("vulnerabilities", fields, tags, v.now())
+}
+
+func execRunner(timeout time.Duration, command string, args ...string) ([]byte, error) {
+	cmd := exec.Command(command, args...)
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := internal.RunTimeout(cmd, timeout); err != nil {
+		msg := bytes.TrimSpace(stderr.Bytes())
+		if len(msg) > 0 {
+			return nil, fmt.Errorf("error running %s: %v: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("error running %s: %v", command, err)
+	}
+	return out.Bytes(), nil
+}
+
+func init() {
+	inputs.Add("vulnerabilities", func() telegraf.Input {
+		return &Vulnerabilities{
+			Timeout: internal.Duration{Duration: 5 * time.Minute},
+			run:     execRunner,
+			now:     time.Now,
+		}
+	})
+}
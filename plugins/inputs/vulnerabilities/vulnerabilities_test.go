@@ -0,0 +1,303 @@
+package vulnerabilities
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const trivyReportJSON = `{
+  "SchemaVersion": 2,
+  "ArtifactName": "nginx:1.19",
+  "ArtifactType": "container_image",
+  "Results": [
+    {
+      "Target": "nginx:1.19 (debian 10.8)",
+      "Class": "os-pkgs",
+      "Type": "debian",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2021-3449", "PkgName": "openssl", "InstalledVersion": "1.1.1d-0+deb10u4", "FixedVersion": "1.1.1d-0+deb10u6", "Severity": "HIGH"},
+        {"VulnerabilityID": "CVE-2021-23840", "PkgName": "openssl", "InstalledVersion": "1.1.1d-0+deb10u4", "FixedVersion": "1.1.1d-0+deb10u5", "Severity": "CRITICAL"},
+        {"VulnerabilityID": "CVE-2019-1010022", "PkgName": "libc6", "InstalledVersion": "2.28-10", "Severity": "LOW"}
+      ]
+    },
+    {
+      "Target": "usr/share/app/package-lock.json",
+      "Class": "lang-pkgs",
+      "Type": "npm",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2020-28500", "PkgName": "lodash", "InstalledVersion": "4.17.15", "FixedVersion": "4.17.21", "Severity": "MEDIUM"}
+      ]
+    }
+  ]
+}`
+
+const trivyLegacyReportJSON = `[
+  {
+    "Target": "alpine:3.10 (alpine 3.10.2)",
+    "Type": "alpine",
+    "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2019-1549", "PkgName": "openssl", "FixedVersion": "1.1.1d-r0", "Severity": "MEDIUM"},
+      {"VulnerabilityID": "CVE-2019-1563", "PkgName": "openssl", "Severity": "UNKNOWN"}
+    ]
+  }
+]`
+
+const grypeReportJSON = `{
+  "matches": [
+    {
+      "vulnerability": {"id": "CVE-2021-3711", "severity": "Critical", "fix": {"versions": ["1.1.1l-r0"], "state": "fixed"}},
+      "artifact": {"name": "libssl1.1", "version": "1.1.1k-r0"}
+    },
+    {
+      "vulnerability": {"id": "CVE-2021-36159", "severity": "Negligible", "fix": {"versions": [], "state": "not-fixed"}},
+      "artifact": {"name": "apk-tools", "version": "2.12.5-r0"}
+    }
+  ],
+  "source": {
+    "type": "image",
+    "target": {"userInput": "alpine:3.14", "imageID": "sha256:14119a10abf4"}
+  }
+}`
+
+const grypeDirectoryReportJSON = `{
+  "matches": [
+    {
+      "vulnerability": {"id": "GHSA-jfh8-c2jp-5v3q", "severity": "High", "fixedInVersion": "2.15.0"},
+      "artifact": {"name": "log4j-core", "version": "2.14.1"}
+    }
+  ],
+  "source": {"type": "directory", "target": "/"}
+}`
+
+func counts(fields map[string]interface{}) map[string]interface{} {
+	for _, s := range severities {
+		if _, ok := fields[s]; !ok {
+			fields[s] = 0
+		}
+	}
+	return fields
+}
+
+func TestParseReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   string
+		expected *summary
+	}{
+		{
+			name:   "trivy",
+			report: trivyReportJSON,
+			expected: &summary{
+				scanner:    "trivy",
+				target:     "nginx:1.19",
+				targetType: "image",
+				counts:     map[string]int{"critical": 1, "high": 1, "medium": 1, "low": 1},
+				fixable:    3,
+			},
+		},
+		{
+			name:   "trivy legacy",
+			report: trivyLegacyReportJSON,
+			expected: &summary{
+				scanner: "trivy",
+				target:  "alpine:3.10",
+				counts:  map[string]int{"medium": 1, "unknown": 1},
+				fixable: 1,
+			},
+		},
+		{
+			name:   "grype",
+			report: grypeReportJSON,
+			expected: &summary{
+				scanner:    "grype",
+				target:     "alpine:3.14",
+				targetType: "image",
+				counts:     map[string]int{"critical": 1, "negligible": 1},
+				fixable:    1,
+			},
+		},
+		{
+			name:   "grype directory",
+			report: grypeDirectoryReportJSON,
+			expected: &summary{
+				scanner:    "grype",
+				target:     "/",
+				targetType: "filesystem",
+				counts:     map[string]int{"high": 1},
+				fixable:    1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseReport([]byte(tt.report))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, s)
+		})
+	}
+}
+
+func TestParseReportUnknown(t *testing.T) {
+	_, err := parseReport([]byte(`{"foo": "bar"}`))
+	require.Error(t, err)
+
+	_, err = parseReport([]byte(" \n"))
+	require.Error(t, err)
+}
+
+func TestGatherFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vulnerabilities")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Unix(1600000000, 0)
+	for name, report := range map[string]string{
+		"nginx.json":  trivyReportJSON,
+		"alpine.json": grypeReportJSON,
+		"broken.json": "{",
+	} {
+		file := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(file, []byte(report), 0644))
+		require.NoError(t, os.Chtimes(file, now, now.Add(-time.Hour)))
+	}
+
+	v := &Vulnerabilities{
+		Files: []string{filepath.Join(dir, "*.json")},
+		run: func(time.Duration, string, ...string) ([]byte, error) {
+			return nil, errors.New("unexpected scan")
+		},
+		now: func() time.Time { return now },
+	}
+	require.NoError(t, v.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, v.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "broken.json")
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"vulnerabilities",
+			map[string]string{"scanner": "trivy", "target": "nginx:1.19", "target_type": "image"},
+			counts(map[string]interface{}{
+				"critical":   1,
+				"high":       1,
+				"medium":     1,
+				"low":        1,
+				"total":      4,
+				"fixable":    3,
+				"report_age": 3600.0,
+			}),
+			now,
+		),
+		testutil.MustMetric(
+			"vulnerabilities",
+			map[string]string{"scanner": "grype", "target": "alpine:3.14", "target_type": "image"},
+			counts(map[string]interface{}{
+				"critical":   1,
+				"negligible": 1,
+				"total":      2,
+				"fixable":    1,
+				"report_age": 3600.0,
+			}),
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestGatherScans(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Vulnerabilities
+		report   string
+		expected []string
+	}{
+		{
+			name: "trivy",
+			plugin: &Vulnerabilities{
+				Images:      []string{"nginx:1.19"},
+				Filesystems: []string{"/"},
+				TrivyServer: "http://trivy:4954",
+			},
+			report: trivyReportJSON,
+			expected: []string{
+				"trivy image --quiet --format json --server http://trivy:4954 nginx:1.19",
+				"trivy rootfs --quiet --format json --server http://trivy:4954 /",
+			},
+		},
+		{
+			name: "grype",
+			plugin: &Vulnerabilities{
+				Scanner:     "grype",
+				Binary:      "/opt/grype",
+				Images:      []string{"nginx:1.19"},
+				Filesystems: []string{"/"},
+			},
+			report: grypeReportJSON,
+			expected: []string{
+				"/opt/grype nginx:1.19 --quiet --output json",
+				"/opt/grype dir:/ --quiet --output json",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commands []string
+			v := tt.plugin
+			v.run = func(timeout time.Duration, command string, args ...string) ([]byte, error) {
+				commands = append(commands, strings.Join(append([]string{command}, args...), " "))
+				return []byte(tt.report), nil
+			}
+			v.now = func() time.Time { return time.Unix(1600000000, 0) }
+			require.NoError(t, v.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, v.Gather(&acc))
+			require.Empty(t, acc.Errors)
+			require.Equal(t, tt.expected, commands)
+
+			// The targets are tagged as configured.
+			metrics := acc.GetTelegrafMetrics()
+			require.Len(t, metrics, 2)
+			require.Equal(t, map[string]string{"scanner": tt.plugin.Scanner, "target": "nginx:1.19", "target_type": "image"}, metrics[0].Tags())
+			require.Equal(t, map[string]string{"scanner": tt.plugin.Scanner, "target": "/", "target_type": "filesystem"}, metrics[1].Tags())
+		})
+	}
+}
+
+func TestGatherScanError(t *testing.T) {
+	v := &Vulnerabilities{
+		Images: []string{"nginx:1.19"},
+		run: func(time.Duration, string, ...string) ([]byte, error) {
+			return nil, errors.New("error running trivy: exit status 1: image not found")
+		},
+		now: time.Now,
+	}
+	require.NoError(t, v.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, v.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "nginx:1.19")
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&Vulnerabilities{Scanner: "clair"}).Init())
+	require.Error(t, (&Vulnerabilities{Scanner: "grype", TrivyServer: "http://trivy:4954"}).Init())
+
+	v := &Vulnerabilities{}
+	require.NoError(t, v.Init())
+	require.Equal(t, "trivy", v.Scanner)
+	require.Equal(t, "trivy", v.Binary)
+}
@@ -0,0 +1,196 @@
+package warehouse_cost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	bigqueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+	bigqueryScope    = "https://www.googleapis.com/auth/bigquery.readonly"
+)
+
+// bigqueryQuery sums the slots and bytes billed of the jobs by project, hour
+// and type of job.  The region and the lookback are validated.
+const bigqueryQuery = "SELECT project_id, UNIX_SECONDS(TIMESTAMP_TRUNC(creation_time, HOUR)), job_type, COUNT(*), " +
+	"IFNULL(SUM(total_slot_ms), 0), IFNULL(SUM(total_bytes_billed), 0) " +
+	"FROM `region-%s`.INFORMATION_SCHEMA.JOBS_BY_PROJECT " +
+	"WHERE creation_time >= TIMESTAMP_SUB(CURRENT_TIMESTAMP(), INTERVAL %d SECOND) " +
+	"GROUP BY 1, 2, 3"
+
+// BigQueryConfig is the project of the BigQuery jobs.
+type BigQueryConfig struct {
+	Project         string `toml:"project"`
+	Region          string `toml:"region"`
+	CredentialsFile string `toml:"credentials_file"`
+}
+
+type bigquerySource struct {
+	config   *BigQueryConfig
+	endpoint string
+	client   *http.Client
+}
+
+// queryResponse is the response of the jobs.query and jobs.getQueryResults
+// methods, the values of the rows are strings.
+type queryResponse struct {
+	JobComplete  bool `json:"jobComplete"`
+	JobReference struct {
+		JobID    string `json:"jobId"`
+		Location string `json:"location"`
+	} `json:"jobReference"`
+	PageToken string `json:"pageToken"`
+	Rows      []struct {
+		F []queryCell `json:"f"`
+	} `json:"rows"`
+}
+
+// queryCell is a value of a row, null values are empty.
+type queryCell struct {
+	V string `json:"v"`
+}
+
+func (b *bigquerySource) gather(ctx context.Context, acc telegraf.Accumulator, lookback time.Duration) error {
+	if b.client == nil {
+		client, err := b.newClient(ctx)
+		if err != nil {
+			return err
+		}
+		b.client = client
+	}
+
+	request := map[string]interface{}{
+		"query":        fmt.Sprintf(bigqueryQuery, b.config.Region, int64(lookback.Seconds())),
+		"useLegacySql": false,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		request["timeoutMs"] = time.Until(deadline).Nanoseconds() / int64(time.Millisecond)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	var resp queryResponse
+	u := b.endpoint + "/projects/" + url.PathEscape(b.config.Project) + "/queries"
+	if err := b.call(ctx, "POST", u, body, &resp); err != nil {
+		return err
+	}
+	if !resp.JobComplete {
+		return fmt.Errorf("query job %s did not complete before the timeout", resp.JobReference.JobID)
+	}
+
+	for {
+		for _, row := range resp.Rows {
+			if err := addJobsRow(acc, row.F); err != nil {
+				return err
+			}
+		}
+		if resp.PageToken == "" {
+			return nil
+		}
+
+		query := url.Values{
+			"pageToken": {resp.PageToken},
+			"location":  {resp.JobReference.Location},
+		}
+		u := b.endpoint + "/projects/" + url.PathEscape(b.config.Project) +
+			"/queries/" + url.PathEscape(resp.JobReference.JobID) + "?" + query.Encode()
+		resp = queryResponse{}
+		if err := b.call(ctx, "GET", u, nil, &resp); err != nil {
+			return err
+		}
+	}
+}
+
+// addJobsRow adds the metric of a row of the query, the columns are the
+// project, the hour, the job type, the number of jobs, the slot
+// milliseconds and the bytes billed.
+func addJobsRow(acc telegraf.Accumulator, row []queryCell) error {
+	if len(row) != 6 {
+		return fmt.Errorf("unexpected number of columns %d", len(row))
+	}
+
+	var numbers [4]int64
+	for i, column := range []int{1, 3, 4, 5} {
+		n, err := strconv.ParseInt(row[column].V, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value %q: %v", row[column].V, err)
+		}
+		numbers[i] = n
+	}
+
+	acc.AddFields(
+		"warehouse_cost_bigquery",
+		map[string]interface{}{
+			"jobs":         numbers[1],
+			"slot_ms":      numbers[2],
+			"bytes_billed": numbers[3],
+		},
+		map[string]string{
+			"project":  row[0].V,
+			"job_type": row[2].V,
+		},
+		time.Unix(numbers[0], 0),
+	)
+	return nil
+}
+
+func (b *bigquerySource) newClient(ctx context.Context) (*http.Client, error) {
+	var creds *google.Credentials
+	var err error
+	if b.config.CredentialsFile != "" {
+		data, err := ioutil.ReadFile(b.config.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		creds, err = google.CredentialsFromJSON(ctx, data, bigqueryScope)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		creds, err = google.FindDefaultCredentials(ctx, bigqueryScope)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find GCP Application Default Credentials: %v", err)
+		}
+	}
+	// The token source must outlive the context of the first gather.
+	return oauth2.NewClient(context.Background(), creds.TokenSource), nil
+}
+
+func (b *bigquerySource) call(ctx context.Context, method, u string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, result)
+}
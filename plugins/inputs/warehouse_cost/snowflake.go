@@ -0,0 +1,110 @@
+package warehouse_cost
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	_ "github.com/snowflakedb/gosnowflake" // register the snowflake driver
+)
+
+// driverName is the name of the registered database driver.
+var driverName = "snowflake"
+
+const snowflakeQuery = `SELECT WAREHOUSE_NAME, START_TIME, CREDITS_USED, CREDITS_USED_COMPUTE, CREDITS_USED_CLOUD_SERVICES
+FROM SNOWFLAKE.ACCOUNT_USAGE.WAREHOUSE_METERING_HISTORY
+WHERE START_TIME >= DATEADD(second, ?, CURRENT_TIMESTAMP())`
+
+// SnowflakeConfig is the Snowflake account of the warehouses.
+type SnowflakeConfig struct {
+	DSN        string `toml:"dsn"`
+	PrivateKey string `toml:"private_key"`
+}
+
+type snowflakeSource struct {
+	dsn string
+	db  *sql.DB
+}
+
+func newSnowflakeSource(config *SnowflakeConfig) (*snowflakeSource, error) {
+	if config.DSN == "" {
+		return nil, errors.New("dsn must be set")
+	}
+	s := &snowflakeSource{dsn: config.DSN}
+
+	if config.PrivateKey != "" {
+		key, err := readPrivateKey(config.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		separator := "?"
+		if strings.Contains(s.dsn, "?") {
+			separator = "&"
+		}
+		s.dsn += separator + "authenticator=SNOWFLAKE_JWT&privateKey=" + key
+	}
+	return s, nil
+}
+
+// readPrivateKey returns the private key encoded as expected by the driver.
+func readPrivateKey(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("no PEM data found in %q", path)
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("parsing private key %q failed: %v", path, err)
+	}
+	return base64.URLEncoding.EncodeToString(block.Bytes), nil
+}
+
+func (s *snowflakeSource) gather(ctx context.Context, acc telegraf.Accumulator, lookback time.Duration) error {
+	// The connections are kept open between the intervals.
+	if s.db == nil {
+		db, err := sql.Open(driverName, s.dsn)
+		if err != nil {
+			return err
+		}
+		s.db = db
+	}
+
+	rows, err := s.db.QueryContext(ctx, snowflakeQuery, -int64(lookback.Seconds()))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var warehouse string
+		var start time.Time
+		var credits, compute, cloudServices float64
+		if err := rows.Scan(&warehouse, &start, &credits, &compute, &cloudServices); err != nil {
+			return err
+		}
+		acc.AddFields(
+			"warehouse_cost_snowflake",
+			map[string]interface{}{
+				"credits_used":                credits,
+				"credits_used_compute":        compute,
+				"credits_used_cloud_services": cloudServices,
+			},
+			map[string]string{"warehouse": warehouse},
+			start,
+		)
+	}
+	return rows.Err()
+}
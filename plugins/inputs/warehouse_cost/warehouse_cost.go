@@ -0,0 +1,126 @@
+package warehouse_cost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Period of the usage queried at each interval.  The usage is reported by
+  ## hour, the hours already reported are reported again until they are out
+  ## of the period, as the billing views are updated with a delay of up to a
+  ## few hours.
+  # lookback = "24h"
+
+  ## Timeout of the queries.
+  # timeout = "1m"
+
+  ## Credits used by the warehouses of a Snowflake account, from the
+  ## SNOWFLAKE.ACCOUNT_USAGE.WAREHOUSE_METERING_HISTORY view.  The role of the
+  ## user must be granted the IMPORTED PRIVILEGES on the SNOWFLAKE database.
+  # [inputs.warehouse_cost.snowflake]
+  #   ## Data source name of the account, the options are described in
+  #   ## https://godoc.org/github.com/snowflakedb/gosnowflake.
+  #   dsn = "user:password@account?warehouse=wh&role=role"
+  #   ## Unencrypted PKCS#8 private key in PEM format for key pair
+  #   ## authentication, the dsn does not need a password then.
+  #   # private_key = "/etc/telegraf/snowflake_key.p8"
+
+  ## Slots and bytes billed of the BigQuery jobs of a project, from the
+  ## INFORMATION_SCHEMA.JOBS_BY_PROJECT view of the region.
+  # [inputs.warehouse_cost.bigquery]
+  #   project = "my-project"
+  #   ## Region of the jobs, such as "us", "eu" or "europe-west1".
+  #   # region = "us"
+  #   ## Filepath of the GCP credentials JSON file, if not set the
+  #   ## Application Default Credentials are used.
+  #   # credentials_file = "path/to/my/creds.json"
+`
+
+// WarehouseCost gathers the credits and slots consumed by the warehouses
+// of Snowflake and the jobs of BigQuery.
+type WarehouseCost struct {
+	Lookback  internal.Duration `toml:"lookback"`
+	Timeout   internal.Duration `toml:"timeout"`
+	Snowflake *SnowflakeConfig  `toml:"snowflake"`
+	BigQuery  *BigQueryConfig   `toml:"bigquery"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	snowflake *snowflakeSource
+	bigquery  *bigquerySource
+}
+
+var region = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+func (w *WarehouseCost) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *WarehouseCost) Description() string {
+	return "Read the credit and slot usage of Snowflake warehouses and BigQuery projects"
+}
+
+func (w *WarehouseCost) Init() error {
+	if w.Snowflake == nil && w.BigQuery == nil {
+		return errors.New("snowflake or bigquery must be configured")
+	}
+	if w.Lookback.Duration < time.Hour {
+		return errors.New("lookback must be at least 1h")
+	}
+
+	if w.Snowflake != nil {
+		s, err := newSnowflakeSource(w.Snowflake)
+		if err != nil {
+			return fmt.Errorf("snowflake: %v", err)
+		}
+		w.snowflake = s
+	}
+
+	if w.BigQuery != nil {
+		if w.BigQuery.Project == "" {
+			return errors.New("bigquery: project must be set")
+		}
+		if w.BigQuery.Region == "" {
+			w.BigQuery.Region = "us"
+		}
+		if !region.MatchString(w.BigQuery.Region) {
+			return fmt.Errorf("bigquery: invalid region %q", w.BigQuery.Region)
+		}
+		w.bigquery = &bigquerySource{config: w.BigQuery, endpoint: bigqueryEndpoint}
+	}
+	return nil
+}
+
+func (w *WarehouseCost) Gather(acc telegraf.Accumulator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.Timeout.Duration)
+	defer cancel()
+
+	if w.snowflake != nil {
+		if err := w.snowflake.gather(ctx, acc, w.Lookback.Duration); err != nil {
+			acc.AddError(fmt.Errorf("snowflake: %v", err))
+		}
+	}
+	if w.bigquery != nil {
+		if err := w.bigquery.gather(ctx, acc, w.Lookback.Duration); err != nil {
+			acc.AddError(fmt.Errorf("bigquery: %v", err))
+		}
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("warehouse_cost", func() telegraf.Input {
+		return &WarehouseCost{
+			Lookback: internal.Duration{Duration: 24 * time.Hour},
+			Timeout:  internal.Duration{Duration: time.Minute},
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package warehouse_cost
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver answers the queries with the rows of the metering history.
+type fakeDriver struct {
+	query string
+	args  []driver.Value
+	rows  [][]driver.Value
+}
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register("warehouse_cost_fake", fake)
+	driverName = "warehouse_cost_fake"
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return d, nil
+}
+
+func (d *fakeDriver) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{driver: d, query: query}, nil
+}
+
+func (d *fakeDriver) Close() error              { return nil }
+func (d *fakeDriver) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	driver *fakeDriver
+	query  string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.query = s.query
+	s.driver.args = args
+	return &fakeRows{rows: s.driver.rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"WAREHOUSE_NAME", "START_TIME", "CREDITS_USED", "CREDITS_USED_COMPUTE", "CREDITS_USED_CLOUD_SERVICES"}
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestGatherSnowflake(t *testing.T) {
+	hour := time.Date(2020, 5, 14, 2, 0, 0, 0, time.UTC)
+	fake.rows = [][]driver.Value{
+		{"LOADING", hour, "1.250000000", "1.000000000", "0.250000000"},
+		{"REPORTING", hour.Add(time.Hour), "0.500000000", "0.450000000", "0.050000000"},
+	}
+
+	w := &WarehouseCost{
+		Lookback:  internal.Duration{Duration: 24 * time.Hour},
+		Timeout:   internal.Duration{Duration: time.Second},
+		Snowflake: &SnowflakeConfig{DSN: "user:password@account"},
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, w.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(w.Gather))
+	require.Contains(t, fake.query, "WAREHOUSE_METERING_HISTORY")
+	require.Equal(t, []driver.Value{int64(-86400)}, fake.args)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"warehouse_cost_snowflake",
+			map[string]string{"warehouse": "LOADING"},
+			map[string]interface{}{
+				"credits_used":                1.25,
+				"credits_used_compute":        1.0,
+				"credits_used_cloud_services": 0.25,
+			},
+			hour,
+		),
+		testutil.MustMetric(
+			"warehouse_cost_snowflake",
+			map[string]string{"warehouse": "REPORTING"},
+			map[string]interface{}{
+				"credits_used":                0.5,
+				"credits_used_compute":        0.45,
+				"credits_used_cloud_services": 0.05,
+			},
+			hour.Add(time.Hour),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func bigqueryRow(values ...string) map[string]interface{} {
+	var f []map[string]interface{}
+	for _, v := range values {
+		f = append(f, map[string]interface{}{"v": v})
+	}
+	return map[string]interface{}{"f": f}
+}
+
+func TestGatherBigQuery(t *testing.T) {
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/projects/my-project/queries":
+			var request map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			query, _ = request["query"].(string)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jobComplete":  true,
+				"jobReference": map[string]string{"jobId": "job_1", "location": "US"},
+				"pageToken":    "page2",
+				"rows": []interface{}{
+					bigqueryRow("my-project", "1589421600", "QUERY", "12", "3600000", "10485760"),
+				},
+			})
+		case r.Method == "GET" && r.URL.Path == "/projects/my-project/queries/job_1":
+			if r.URL.Query().Get("pageToken") != "page2" || r.URL.Query().Get("location") != "US" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jobComplete":  true,
+				"jobReference": map[string]string{"jobId": "job_1", "location": "US"},
+				"rows": []interface{}{
+					bigqueryRow("my-project", "1589421600", "LOAD", "2", "60000", "0"),
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	w := &WarehouseCost{
+		Lookback: internal.Duration{Duration: 6 * time.Hour},
+		Timeout:  internal.Duration{Duration: 5 * time.Second},
+		BigQuery: &BigQueryConfig{Project: "my-project", Region: "us"},
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, w.Init())
+	w.bigquery.endpoint = ts.URL
+	w.bigquery.client = ts.Client()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(w.Gather))
+	require.Contains(t, query, "`region-us`.INFORMATION_SCHEMA.JOBS_BY_PROJECT")
+	require.Contains(t, query, "INTERVAL 21600 SECOND")
+
+	hour := time.Unix(1589421600, 0)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"warehouse_cost_bigquery",
+			map[string]string{"project": "my-project", "job_type": "QUERY"},
+			map[string]interface{}{"jobs": 12, "slot_ms": 3600000, "bytes_billed": 10485760},
+			hour,
+		),
+		testutil.MustMetric(
+			"warehouse_cost_bigquery",
+			map[string]string{"project": "my-project", "job_type": "LOAD"},
+			map[string]interface{}{"jobs": 2, "slot_ms": 60000, "bytes_billed": 0},
+			hour,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestGatherBigQueryIncomplete(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jobComplete": false, "jobReference": {"jobId": "job_2"}}`)
+	}))
+	defer ts.Close()
+
+	w := &WarehouseCost{
+		Lookback: internal.Duration{Duration: 24 * time.Hour},
+		Timeout:  internal.Duration{Duration: 5 * time.Second},
+		BigQuery: &BigQueryConfig{Project: "my-project", Region: "eu"},
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, w.Init())
+	w.bigquery.endpoint = ts.URL
+	w.bigquery.client = ts.Client()
+
+	var acc testutil.Accumulator
+	require.NoError(t, w.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "job_2")
+}
+
+func TestInit(t *testing.T) {
+	lookback := internal.Duration{Duration: 24 * time.Hour}
+	require.Error(t, (&WarehouseCost{Lookback: lookback}).Init())
+	require.Error(t, (&WarehouseCost{Lookback: lookback, Snowflake: &SnowflakeConfig{}}).Init())
+	require.Error(t, (&WarehouseCost{
+		Lookback:  internal.Duration{Duration: time.Minute},
+		Snowflake: &SnowflakeConfig{DSN: "user:password@account"},
+	}).Init())
+	require.Error(t, (&WarehouseCost{
+		Lookback: lookback,
+		BigQuery: &BigQueryConfig{Project: "my-project", Region: "us`; DROP"},
+	}).Init())
+}
@@ -0,0 +1,245 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const noaaURL = "https://api.weather.gov"
+
+// NOAAConfig is the configuration of the API of the National Weather
+// Service.
+type NOAAConfig struct {
+	UserAgent string `toml:"user_agent"`
+	URL       string `toml:"url"`
+}
+
+type noaa struct {
+	config *NOAAConfig
+	client *http.Client
+
+	sync.Mutex
+	// stations are the nearest observation stations of the locations, by
+	// name of location.
+	stations map[string]string
+}
+
+// quantity is a value of an observation, null when the station did not
+// measure it.
+type quantity struct {
+	Value    *float64 `json:"value"`
+	UnitCode string   `json:"unitCode"`
+}
+
+// metric returns the value in the metric units of the fields, false is
+// returned when it is null or of an unknown unit.
+func (q quantity) metric() (float64, bool) {
+	if q.Value == nil {
+		return 0, false
+	}
+	v := *q.Value
+	// The units are prefixed by their vocabulary, such as "wmoUnit:degC".
+	unit := q.UnitCode[strings.IndexByte(q.UnitCode, ':')+1:]
+	switch unit {
+	case "degC", "m", "m_s-1", "percent", "degree_(angle)":
+		return v, true
+	case "degF":
+		return (v - 32) * 5 / 9, true
+	case "km_h-1":
+		return v / 3.6, true
+	case "Pa":
+		return v / 100, true
+	}
+	return 0, false
+}
+
+type noaaObservation struct {
+	Properties struct {
+		Timestamp          string   `json:"timestamp"`
+		TextDescription    string   `json:"textDescription"`
+		Temperature        quantity `json:"temperature"`
+		Dewpoint           quantity `json:"dewpoint"`
+		RelativeHumidity   quantity `json:"relativeHumidity"`
+		WindDirection      quantity `json:"windDirection"`
+		WindSpeed          quantity `json:"windSpeed"`
+		WindGust           quantity `json:"windGust"`
+		BarometricPressure quantity `json:"barometricPressure"`
+		Visibility         quantity `json:"visibility"`
+	} `json:"properties"`
+}
+
+type noaaAlerts struct {
+	Features []struct {
+		Properties struct {
+			Event       string `json:"event"`
+			Severity    string `json:"severity"`
+			Headline    string `json:"headline"`
+			Description string `json:"description"`
+			Onset       string `json:"onset"`
+			Ends        string `json:"ends"`
+			Expires     string `json:"expires"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+func newNOAA(config *NOAAConfig, client *http.Client) (*noaa, error) {
+	if config.UserAgent == "" {
+		return nil, errors.New("user_agent must be set")
+	}
+	if config.URL == "" {
+		config.URL = noaaURL
+	}
+	return &noaa{config: config, client: client, stations: make(map[string]string)}, nil
+}
+
+func (n *noaa) gather(acc telegraf.Accumulator, location *Location) error {
+	station, err := n.station(location)
+	if err != nil {
+		return err
+	}
+
+	var obs noaaObservation
+	if err := n.get(n.url("/stations/"+url.PathEscape(station)+"/observations/latest"), &obs); err != nil {
+		return err
+	}
+	var alerts noaaAlerts
+	if err := n.get(n.url("/alerts/active?point="+url.QueryEscape(n.point(location))), &alerts); err != nil {
+		return err
+	}
+	now := time.Now()
+
+	p := obs.Properties
+	fields := map[string]interface{}{
+		"alerts": len(alerts.Features),
+	}
+	for name, q := range map[string]quantity{
+		"temperature":    p.Temperature,
+		"dew_point":      p.Dewpoint,
+		"humidity":       p.RelativeHumidity,
+		"wind_direction": p.WindDirection,
+		"wind_speed":     p.WindSpeed,
+		"wind_gust":      p.WindGust,
+		"pressure":       p.BarometricPressure,
+		"visibility":     p.Visibility,
+	} {
+		if v, ok := q.metric(); ok {
+			fields[name] = v
+		}
+	}
+	if p.TextDescription != "" {
+		fields["condition"] = p.TextDescription
+	}
+	tags := map[string]string{"provider": "noaa", "location": location.Name, "station": station}
+	tm := now
+	if p.Timestamp != "" {
+		if tm, err = time.Parse(time.RFC3339, p.Timestamp); err != nil {
+			return fmt.Errorf("invalid timestamp %q", p.Timestamp)
+		}
+	}
+	acc.AddFields("weather_conditions", fields, tags, tm)
+
+	list := make([]alert, 0, len(alerts.Features))
+	for _, f := range alerts.Features {
+		a := f.Properties
+		end := a.Ends
+		if end == "" {
+			end = a.Expires
+		}
+		list = append(list, alert{
+			event:       a.Event,
+			severity:    a.Severity,
+			headline:    a.Headline,
+			description: a.Description,
+			start:       parseAlertTime(a.Onset),
+			end:         parseAlertTime(end),
+		})
+	}
+	addAlerts(acc, "noaa", location, list, now)
+	return nil
+}
+
+// station returns the nearest observation station of the location, looked up
+// on the first gathering.
+func (n *noaa) station(location *Location) (string, error) {
+	n.Lock()
+	station, ok := n.stations[location.Name]
+	n.Unlock()
+	if ok {
+		return station, nil
+	}
+
+	var point struct {
+		Properties struct {
+			ObservationStations string `json:"observationStations"`
+		} `json:"properties"`
+	}
+	if err := n.get(n.url("/points/"+n.point(location)), &point); err != nil {
+		return "", err
+	}
+	if point.Properties.ObservationStations == "" {
+		return "", errors.New("no observation stations for the location")
+	}
+
+	var stations struct {
+		Features []struct {
+			Properties struct {
+				StationIdentifier string `json:"stationIdentifier"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := n.get(point.Properties.ObservationStations, &stations); err != nil {
+		return "", err
+	}
+	if len(stations.Features) == 0 || stations.Features[0].Properties.StationIdentifier == "" {
+		return "", errors.New("no observation stations for the location")
+	}
+
+	// The stations are sorted by distance to the location.
+	station = stations.Features[0].Properties.StationIdentifier
+	n.Lock()
+	n.stations[location.Name] = station
+	n.Unlock()
+	return station, nil
+}
+
+// point returns the coordinates of the location, with the 4 decimals the API
+// accepts.
+func (n *noaa) point(location *Location) string {
+	format := func(v float64) string {
+		return strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+	}
+	return format(location.Latitude) + "," + format(location.Longitude)
+}
+
+func (n *noaa) url(path string) string {
+	return strings.TrimRight(n.config.URL, "/") + path
+}
+
+func (n *noaa) get(u string, v interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", n.config.UserAgent)
+	req.Header.Set("Accept", "application/geo+json")
+	return getJSON(n.client, req, v)
+}
+
+// parseAlertTime parses the time of an alert, the zero time is returned when
+// it is missing or invalid.
+func parseAlertTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,127 @@
+package weather
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const openWeatherMapURL = "https://api.openweathermap.org"
+
+// OpenWeatherMapConfig is the account of OpenWeatherMap.
+type OpenWeatherMapConfig struct {
+	APIKey string `toml:"api_key"`
+	URL    string `toml:"url"`
+}
+
+type openWeatherMap struct {
+	config *OpenWeatherMapConfig
+	client *http.Client
+}
+
+// oneCall is the response of the One Call API, in metric units.
+type oneCall struct {
+	Current struct {
+		Dt         int64    `json:"dt"`
+		Temp       float64  `json:"temp"`
+		FeelsLike  float64  `json:"feels_like"`
+		Pressure   float64  `json:"pressure"`
+		Humidity   float64  `json:"humidity"`
+		DewPoint   float64  `json:"dew_point"`
+		Clouds     float64  `json:"clouds"`
+		Visibility *float64 `json:"visibility"`
+		WindSpeed  float64  `json:"wind_speed"`
+		WindDeg    float64  `json:"wind_deg"`
+		WindGust   *float64 `json:"wind_gust"`
+		Weather    []struct {
+			Description string `json:"description"`
+		} `json:"weather"`
+	} `json:"current"`
+	Alerts []struct {
+		Event       string `json:"event"`
+		Start       int64  `json:"start"`
+		End         int64  `json:"end"`
+		Description string `json:"description"`
+	} `json:"alerts"`
+}
+
+func newOpenWeatherMap(config *OpenWeatherMapConfig, client *http.Client) (*openWeatherMap, error) {
+	if config.APIKey == "" {
+		return nil, errors.New("api_key must be set")
+	}
+	if config.URL == "" {
+		config.URL = openWeatherMapURL
+	}
+	return &openWeatherMap{config: config, client: client}, nil
+}
+
+func (o *openWeatherMap) gather(acc telegraf.Accumulator, location *Location) error {
+	query := url.Values{
+		"lat":     []string{strconv.FormatFloat(location.Latitude, 'f', -1, 64)},
+		"lon":     []string{strconv.FormatFloat(location.Longitude, 'f', -1, 64)},
+		"units":   []string{"metric"},
+		"exclude": []string{"minutely,hourly,daily"},
+		"appid":   []string{o.config.APIKey},
+	}
+	req, err := http.NewRequest("GET", strings.TrimRight(o.config.URL, "/")+"/data/3.0/onecall?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	var resp oneCall
+	if err := getJSON(o.client, req, &resp); err != nil {
+		return err
+	}
+	now := time.Now()
+
+	c := resp.Current
+	fields := map[string]interface{}{
+		"temperature":    c.Temp,
+		"feels_like":     c.FeelsLike,
+		"humidity":       c.Humidity,
+		"pressure":       c.Pressure,
+		"dew_point":      c.DewPoint,
+		"cloudiness":     c.Clouds,
+		"wind_speed":     c.WindSpeed,
+		"wind_direction": c.WindDeg,
+		"alerts":         len(resp.Alerts),
+	}
+	if c.Visibility != nil {
+		fields["visibility"] = *c.Visibility
+	}
+	if c.WindGust != nil {
+		fields["wind_gust"] = *c.WindGust
+	}
+	if len(c.Weather) > 0 {
+		fields["condition"] = c.Weather[0].Description
+	}
+	tags := map[string]string{"provider": "openweathermap", "location": location.Name}
+	tm := now
+	if c.Dt != 0 {
+		tm = time.Unix(c.Dt, 0)
+	}
+	acc.AddFields("weather_conditions", fields, tags, tm)
+
+	alerts := make([]alert, 0, len(resp.Alerts))
+	for _, a := range resp.Alerts {
+		// The first line of the description is its headline.
+		headline := a.Description
+		if i := strings.IndexByte(headline, '\n'); i >= 0 {
+			headline = headline[:i]
+		}
+		alerts = append(alerts, alert{
+			event:       a.Event,
+			headline:    strings.TrimSpace(headline),
+			description: a.Description,
+			start:       time.Unix(a.Start, 0),
+			end:         time.Unix(a.End, 0),
+		})
+	}
+	addAlerts(acc, "openweathermap", location, alerts, now)
+	return nil
+}
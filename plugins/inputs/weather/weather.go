@@ -0,0 +1,210 @@
+package weather
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Timeout for HTTP requests.
+  # timeout = "10s"
+
+  ## Current conditions and alerts of the locations from the OpenWeatherMap
+  ## One Call API.
+  # [inputs.weather.openweathermap]
+  #   api_key = ""
+  #   # url = "https://api.openweathermap.org"
+
+  ## Latest observation of the nearest station and active alerts of the
+  ## locations from the API of the NOAA National Weather Service, for the
+  ## locations in the United States.
+  # [inputs.weather.noaa]
+  #   ## The API requires a User-Agent identifying the application, with a
+  #   ## contact such as an email address.
+  #   user_agent = "telegraf (ops@example.com)"
+  #   # url = "https://api.weather.gov"
+
+  ## Locations, such as datacenters or sites of sensors, by latitude and
+  ## longitude.
+  [[inputs.weather.location]]
+    name = "dc-east"
+    latitude = 39.04
+    longitude = -77.49
+
+  ## The APIs update the current conditions every 10 minutes to 1 hour.
+  interval = "10m"
+`
+
+// Location is a location of which the weather is gathered.
+type Location struct {
+	Name      string  `toml:"name"`
+	Latitude  float64 `toml:"latitude"`
+	Longitude float64 `toml:"longitude"`
+}
+
+// Weather gathers the current weather conditions and the alerts of
+// locations.
+type Weather struct {
+	Timeout        internal.Duration     `toml:"timeout"`
+	OpenWeatherMap *OpenWeatherMapConfig `toml:"openweathermap"`
+	NOAA           *NOAAConfig           `toml:"noaa"`
+	Locations      []*Location           `toml:"location"`
+
+	openWeatherMap *openWeatherMap
+	noaa           *noaa
+}
+
+func (w *Weather) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Weather) Description() string {
+	return "Read the current weather conditions and alerts of locations"
+}
+
+func (w *Weather) Init() error {
+	if len(w.Locations) == 0 {
+		return errors.New("no location configured")
+	}
+	for _, l := range w.Locations {
+		if l.Name == "" {
+			return errors.New("location without name")
+		}
+		if l.Latitude < -90 || l.Latitude > 90 || l.Longitude < -180 || l.Longitude > 180 {
+			return fmt.Errorf("location %s: invalid coordinates", l.Name)
+		}
+	}
+
+	client := &http.Client{Timeout: w.Timeout.Duration}
+
+	if w.OpenWeatherMap != nil {
+		o, err := newOpenWeatherMap(w.OpenWeatherMap, client)
+		if err != nil {
+			return fmt.Errorf("openweathermap: %v", err)
+		}
+		w.openWeatherMap = o
+	}
+	if w.NOAA != nil {
+		n, err := newNOAA(w.NOAA, client)
+		if err != nil {
+			return fmt.Errorf("noaa: %v", err)
+		}
+		w.noaa = n
+	}
+	if w.openWeatherMap == nil && w.noaa == nil {
+		return errors.New("openweathermap or noaa must be configured")
+	}
+	return nil
+}
+
+func (w *Weather) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+
+	for _, location := range w.Locations {
+		if w.openWeatherMap != nil {
+			wg.Add(1)
+			go func(location *Location) {
+				defer wg.Done()
+				if err := w.openWeatherMap.gather(acc, location); err != nil {
+					acc.AddError(fmt.Errorf("openweathermap: %s: %v", location.Name, err))
+				}
+			}(location)
+		}
+		if w.noaa != nil {
+			wg.Add(1)
+			go func(location *Location) {
+				defer wg.Done()
+				if err := w.noaa.gather(acc, location); err != nil {
+					acc.AddError(fmt.Errorf("noaa: %s: %v", location.Name, err))
+				}
+			}(location)
+		}
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// alert is a weather alert active at a location.
+type alert struct {
+	event       string
+	severity    string
+	headline    string
+	description string
+	start       time.Time
+	end         time.Time
+}
+
+// addAlerts adds the alerts of the location, with the time of the gathering
+// so that the alerts still active are reported at each interval.
+func addAlerts(acc telegraf.Accumulator, provider string, location *Location, alerts []alert, now time.Time) {
+	for _, a := range alerts {
+		tags := map[string]string{
+			"provider": provider,
+			"location": location.Name,
+			"event":    a.event,
+		}
+		if a.severity != "" {
+			tags["severity"] = a.severity
+		}
+		fields := map[string]interface{}{
+			"headline": a.headline,
+		}
+		if a.description != "" {
+			fields["description"] = a.description
+		}
+		if !a.start.IsZero() {
+			fields["start"] = a.start.UnixNano()
+		}
+		if !a.end.IsZero() {
+			fields["end"] = a.end.UnixNano()
+		}
+		acc.AddFields("weather_alert", fields, tags, now)
+	}
+}
+
+// statusError is the error of a response with an unexpected status.
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received status code %d: %s", e.code, e.body)
+}
+
+// getJSON decodes the JSON body of the response of the request.
+func getJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{code: resp.StatusCode, body: string(body)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("weather", func() telegraf.Input {
+		return &Weather{
+			Timeout: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherOpenWeatherMap(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/data/3.0/onecall" || q.Get("appid") != "secret" || q.Get("units") != "metric" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch q.Get("lat") + "," + q.Get("lon") {
+		case "39.04,-77.49":
+			fmt.Fprint(w, `{"lat":39.04,"lon":-77.49,"current":{"dt":1589457600,"temp":21.5,"feels_like":21.1,
+				"pressure":1014,"humidity":64,"dew_point":14.3,"clouds":75,"visibility":10000,"wind_speed":4.1,
+				"wind_deg":220,"wind_gust":7.2,"weather":[{"id":803,"main":"Clouds","description":"broken clouds"}]},
+				"alerts":[{"sender_name":"NWS Sterling","event":"Heat Advisory","start":1589457600,"end":1589490000,
+				"description":"...HEAT ADVISORY IN EFFECT...\nHeat index values up to 105."}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := &Weather{
+		Timeout:        internal.Duration{Duration: 5 * time.Second},
+		OpenWeatherMap: &OpenWeatherMapConfig{APIKey: "secret", URL: ts.URL},
+		Locations: []*Location{
+			{Name: "dc-east", Latitude: 39.04, Longitude: -77.49},
+			{Name: "dc-west", Latitude: 45.6, Longitude: -121.18},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "dc-west")
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"weather_conditions",
+			map[string]string{"provider": "openweathermap", "location": "dc-east"},
+			map[string]interface{}{
+				"temperature":    21.5,
+				"feels_like":     21.1,
+				"humidity":       64.0,
+				"pressure":       1014.0,
+				"dew_point":      14.3,
+				"cloudiness":     75.0,
+				"visibility":     10000.0,
+				"wind_speed":     4.1,
+				"wind_direction": 220.0,
+				"wind_gust":      7.2,
+				"condition":      "broken clouds",
+				"alerts":         1,
+			},
+			time.Unix(1589457600, 0),
+		),
+		testutil.MustMetric(
+			"weather_alert",
+			map[string]string{"provider": "openweathermap", "location": "dc-east", "event": "Heat Advisory"},
+			map[string]interface{}{
+				"headline":    "...HEAT ADVISORY IN EFFECT...",
+				"description": "...HEAT ADVISORY IN EFFECT...\nHeat index values up to 105.",
+				"start":       time.Unix(1589457600, 0).UnixNano(),
+				"end":         time.Unix(1589490000, 0).UnixNano(),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherNOAA(t *testing.T) {
+	var ts *httptest.Server
+	points := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "telegraf (ops@example.com)" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path + "?" + r.URL.RawQuery {
+		case "/points/39.0412,-77.4888?":
+			points++
+			fmt.Fprintf(w, `{"properties":{"gridId":"LWX","observationStations":"%s/gridpoints/LWX/83,71/stations"}}`, ts.URL)
+		case "/gridpoints/LWX/83,71/stations?":
+			fmt.Fprint(w, `{"features":[{"properties":{"stationIdentifier":"KIAD"}},{"properties":{"stationIdentifier":"KJYO"}}]}`)
+		case "/stations/KIAD/observations/latest?":
+			fmt.Fprint(w, `{"properties":{"timestamp":"2020-05-14T11:52:00+00:00","textDescription":"Mostly Cloudy",
+				"temperature":{"unitCode":"wmoUnit:degC","value":21.7},
+				"dewpoint":{"unitCode":"wmoUnit:degC","value":14.4},
+				"relativeHumidity":{"unitCode":"wmoUnit:percent","value":63.5},
+				"windDirection":{"unitCode":"wmoUnit:degree_(angle)","value":220},
+				"windSpeed":{"unitCode":"wmoUnit:km_h-1","value":18},
+				"windGust":{"unitCode":"wmoUnit:km_h-1","value":null},
+				"barometricPressure":{"unitCode":"wmoUnit:Pa","value":101420},
+				"visibility":{"unitCode":"wmoUnit:m","value":16090}}}`)
+		case "/alerts/active?point=39.0412%2C-77.4888":
+			fmt.Fprint(w, `{"features":[{"properties":{"event":"Heat Advisory","severity":"Moderate",
+				"headline":"Heat Advisory issued May 14 at 4:00AM EDT","description":"Heat index values up to 105.",
+				"onset":"2020-05-14T12:00:00-04:00","expires":"2020-05-14T20:00:00-04:00","ends":null}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := &Weather{
+		Timeout:   internal.Duration{Duration: 5 * time.Second},
+		NOAA:      &NOAAConfig{UserAgent: "telegraf (ops@example.com)", URL: ts.URL},
+		Locations: []*Location{{Name: "dc-east", Latitude: 39.04123, Longitude: -77.48876}},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	edt := time.FixedZone("EDT", -4*3600)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"weather_conditions",
+			map[string]string{"provider": "noaa", "location": "dc-east", "station": "KIAD"},
+			map[string]interface{}{
+				"temperature":    21.7,
+				"dew_point":      14.4,
+				"humidity":       63.5,
+				"wind_direction": 220.0,
+				"wind_speed":     5.0,
+				"pressure":       1014.2,
+				"visibility":     16090.0,
+				"condition":      "Mostly Cloudy",
+				"alerts":         1,
+			},
+			time.Date(2020, 5, 14, 11, 52, 0, 0, time.UTC),
+		),
+		testutil.MustMetric(
+			"weather_alert",
+			map[string]string{"provider": "noaa", "location": "dc-east", "event": "Heat Advisory", "severity": "Moderate"},
+			map[string]interface{}{
+				"headline":    "Heat Advisory issued May 14 at 4:00AM EDT",
+				"description": "Heat index values up to 105.",
+				"start":       time.Date(2020, 5, 14, 12, 0, 0, 0, edt).UnixNano(),
+				"end":         time.Date(2020, 5, 14, 20, 0, 0, 0, edt).UnixNano(),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+
+	// The station of the location is looked up once.
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, 1, points)
+}
+
+func TestInit(t *testing.T) {
+	locations := []*Location{{Name: "dc-east", Latitude: 39.04, Longitude: -77.49}}
+	require.Error(t, (&Weather{Locations: locations}).Init())
+	require.Error(t, (&Weather{
+		OpenWeatherMap: &OpenWeatherMapConfig{APIKey: "secret"},
+	}).Init())
+	require.Error(t, (&Weather{
+		OpenWeatherMap: &OpenWeatherMapConfig{},
+		Locations:      locations,
+	}).Init())
+	require.Error(t, (&Weather{
+		NOAA:      &NOAAConfig{},
+		Locations: locations,
+	}).Init())
+	require.Error(t, (&Weather{
+		NOAA:      &NOAAConfig{UserAgent: "telegraf"},
+		Locations: []*Location{{Name: "nowhere", Latitude: 91}},
+	}).Init())
+}
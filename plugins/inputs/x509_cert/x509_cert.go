@@ -15,6 +15,8 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/discovery"
+	"github.com/influxdata/telegraf/internal/k8sdiscovery"
 	_tls "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
@@ -34,6 +36,58 @@ const sampleConfig = `
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
   # tls_key = "/etc/telegraf/key.pem"
+
+  ## Read the certificates of the Kubernetes pods with the
+  ## "prometheus.io/scrape" annotation set to "true", at the pod IP and the
+  ## "prometheus.io/port" annotation.  The annotations and labels of the pods
+  ## are added as tags.
+  # [inputs.x509_cert.urls_from_kubernetes]
+  #   ## Prefix of the annotations.
+  #   annotation_prefix = "prometheus.io"
+  #   ## Scheme and port of the sources of the pods without the annotations.
+  #   scheme = "tcp"
+  #   port = "443"
+  #   ## Restricts the discovery to a namespace, all namespaces by default.
+  #   namespace = ""
+  #   ## Kubernetes config file, used when not running in a pod.
+  #   kube_config = "/path/to/kubernetes.config"
+  #   ## Annotations and labels not added as tags.
+  #   exclude_annotations = []
+  #   exclude_labels = []
+  #   ## Interval of the full resync of the pods with the API server.
+  #   resync_interval = "5m"
+
+  ## Read the certificates of the targets discovered from files, DNS SRV
+  ## records, Consul services or Kubernetes services.  The targets are
+  ## refreshed periodically, without reloading the configuration.
+  # [inputs.x509_cert.discovery]
+  #   ## Files with one target per line, as a URL or "host[:port]" followed
+  #   ## by optional "key=value" tags.  Lines starting with "#" are skipped.
+  #   files = ["/etc/telegraf/x509_targets"]
+  #   ## Names of DNS SRV records.
+  #   dns_srv = ["_https._tcp.example.org"]
+  #   ## Scheme and port of the targets without them.
+  #   scheme = "tcp"
+  #   port = "443"
+  #   ## Interval of the refresh of the files, records and Consul services.
+  #   refresh_interval = "1m"
+  #
+  #   ## Instances of Consul services, the healthy ones unless failing ones
+  #   ## are included.
+  #   [inputs.x509_cert.discovery.consul]
+  #     address = "localhost:8500"
+  #     services = ["web"]
+  #     # tag = ""
+  #     # datacenter = ""
+  #     # token = ""
+  #     # include_failing = false
+  #
+  #   ## Kubernetes services with the "prometheus.io/scrape" annotation set
+  #   ## to "true", at their cluster IP.
+  #   [inputs.x509_cert.discovery.kubernetes]
+  #     role = "service"
+  #     # namespace = ""
+  #     # kube_config = "/path/to/kubernetes.config"
 `
 const description = "Reads metrics from a SSL certificate"
 
@@ -44,6 +98,14 @@ type X509Cert struct {
 	ServerName string            `toml:"server_name"`
 	tlsCfg     *tls.Config
 	_tls.ClientConfig
+
+	URLsFromKubernetes *k8sdiscovery.Config `toml:"urls_from_kubernetes"`
+	Discovery          *discovery.Config    `toml:"discovery"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	discovery *k8sdiscovery.Discovery
+	targets   *discovery.Discovery
 }
 
 // Description returns description of the plugin.
@@ -187,6 +249,42 @@ func getTags(cert *x509.Certificate, location string) map[string]string {
 	return tags
 }
 
+// Start starts the discovery of the Kubernetes pods and of the targets if
+// enabled in the configuration.
+func (c *X509Cert) Start(_ telegraf.Accumulator) error {
+	if c.URLsFromKubernetes != nil {
+		c.discovery = k8sdiscovery.New(*c.URLsFromKubernetes, c.Log)
+		if err := c.discovery.Start(); err != nil {
+			return err
+		}
+	}
+
+	if c.Discovery != nil {
+		config := *c.Discovery
+		if config.Scheme == "" {
+			config.Scheme = "tcp"
+		}
+		if config.Port == "" {
+			config.Port = "443"
+		}
+		c.targets = discovery.New(config, c.Log)
+		if err := c.targets.Start(); err != nil {
+			c.Stop()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *X509Cert) Stop() {
+	if c.discovery != nil {
+		c.discovery.Stop()
+	}
+	if c.targets != nil {
+		c.targets.Stop()
+	}
+}
+
 // Gather adds metrics into the accumulator.
 func (c *X509Cert) Gather(acc telegraf.Accumulator) error {
 	now := time.Now()
@@ -197,52 +295,71 @@ func (c *X509Cert) Gather(acc telegraf.Accumulator) error {
 			acc.AddError(err)
 			return nil
 		}
+		c.gatherCerts(acc, u, location, now)
+	}
 
-		certs, err := c.getCert(u, c.Timeout.Duration*time.Second)
-		if err != nil {
-			acc.AddError(fmt.Errorf("cannot get SSL cert '%s': %s", location, err.Error()))
+	if c.discovery != nil {
+		for _, t := range c.discovery.Targets() {
+			// The URL of the target is shared, getCert changes its scheme
+			u := *t.URL
+			c.gatherCerts(t.Accumulator(acc), &u, u.String(), now)
 		}
+	}
 
-		for i, cert := range certs {
-			fields := getFields(cert, now)
-			tags := getTags(cert, location)
+	if c.targets != nil {
+		for _, t := range c.targets.Targets() {
+			u := *t.URL
+			c.gatherCerts(t.Accumulator(acc), &u, u.String(), now)
+		}
+	}
 
-			// The first certificate is the leaf/end-entity certificate which needs DNS
-			// name validation against the URL hostname.
-			opts := x509.VerifyOptions{
-				Intermediates: x509.NewCertPool(),
-			}
-			if i == 0 {
-				if c.ServerName == "" {
-					opts.DNSName = u.Hostname()
-				} else {
-					opts.DNSName = c.ServerName
-				}
-				for j, cert := range certs {
-					if j != 0 {
-						opts.Intermediates.AddCert(cert)
-					}
-				}
-			}
-			if c.tlsCfg.RootCAs != nil {
-				opts.Roots = c.tlsCfg.RootCAs
-			}
+	return nil
+}
+
+// gatherCerts adds the metrics of the certificates of the location.
+func (c *X509Cert) gatherCerts(acc telegraf.Accumulator, u *url.URL, location string, now time.Time) {
+	certs, err := c.getCert(u, c.Timeout.Duration*time.Second)
+	if err != nil {
+		acc.AddError(fmt.Errorf("cannot get SSL cert '%s': %s", location, err.Error()))
+	}
 
-			_, err = cert.Verify(opts)
-			if err == nil {
-				tags["verification"] = "valid"
-				fields["verification_code"] = 0
+	for i, cert := range certs {
+		fields := getFields(cert, now)
+		tags := getTags(cert, location)
+
+		// The first certificate is the leaf/end-entity certificate which needs DNS
+		// name validation against the URL hostname.
+		opts := x509.VerifyOptions{
+			Intermediates: x509.NewCertPool(),
+		}
+		if i == 0 {
+			if c.ServerName == "" {
+				opts.DNSName = u.Hostname()
 			} else {
-				tags["verification"] = "invalid"
-				fields["verification_code"] = 1
-				fields["verification_error"] = err.Error()
+				opts.DNSName = c.ServerName
 			}
+			for j, cert := range certs {
+				if j != 0 {
+					opts.Intermediates.AddCert(cert)
+				}
+			}
+		}
+		if c.tlsCfg.RootCAs != nil {
+			opts.Roots = c.tlsCfg.RootCAs
+		}
 
-			acc.AddFields("x509_cert", fields, tags)
+		_, err = cert.Verify(opts)
+		if err == nil {
+			tags["verification"] = "valid"
+			fields["verification_code"] = 0
+		} else {
+			tags["verification"] = "invalid"
+			fields["verification_code"] = 1
+			fields["verification_error"] = err.Error()
 		}
-	}
 
-	return nil
+		acc.AddFields("x509_cert", fields, tags)
+	}
 }
 
 func (c *X509Cert) Init() error {
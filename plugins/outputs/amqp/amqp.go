@@ -55,6 +55,8 @@ type AMQP struct {
 	Timeout            internal.Duration `toml:"timeout"`
 	UseBatchFormat     bool              `toml:"use_batch_format"`
 	ContentEncoding    string            `toml:"content_encoding"`
+	CompressionLevel   int               `toml:"compression_level"`
+	ConfirmPublish     bool              `toml:"confirm_publish"`
 	tls.ClientConfig
 
 	serializer   serializers.Serializer
@@ -123,6 +125,12 @@ var sampleConfig = `
   ##   One of "transient" or "persistent".
   # delivery_mode = "transient"
 
+  ## If true, the channel is put in confirm mode and each message must be
+  ## acknowledged by the broker before the write is considered successful.
+  ## Messages that are not confirmed within the timeout, or that are negatively
+  ## acknowledged, are retried on the next write.
+  # confirm_publish = false
+
   ## InfluxDB database added as a message header.
   ##   deprecated in 1.7; use the headers option
   # database = "telegraf"
@@ -151,14 +159,18 @@ var sampleConfig = `
   ## Recommended to set to true.
   # use_batch_format = false
 
-  ## Content encoding for message payloads, can be set to "gzip" to or
-  ## "identity" to apply no encoding.
+  ## Content encoding for message payloads, can be set to "gzip", "zstd" or
+  ## "snappy" to compress payloads or "identity" to apply no encoding.
   ##
   ## Please note that when use_batch_format = false each amqp message contains only
   ## a single metric, it is recommended to use compression with batch format
   ## for best results.
   # content_encoding = "identity"
 
+  ## Compression level of the "gzip" (1 to 9) and "zstd" (1 to 22) encodings,
+  ## 0 uses the default level of the encoding.
+  # compression_level = 0
+
   ## Data format to output.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -187,11 +199,12 @@ func (q *AMQP) Connect() error {
 		q.config = config
 	}
 
-	var err error
-	q.encoder, err = internal.NewContentEncoder(q.ContentEncoding)
+	encoder, err := internal.NewContentEncoder(q.ContentEncoding,
+		internal.WithCompressionLevel(q.CompressionLevel))
 	if err != nil {
 		return err
 	}
+	q.encoder = internal.NewStatsEncoder(encoder, "amqp", map[string]string{"exchange": q.Exchange})
 
 	q.client, err = q.connect(q.config)
 	if err != nil {
@@ -321,6 +334,7 @@ func (q *AMQP) makeClientConfig() (*ClientConfig, error) {
 		exchangePassive: q.ExchangePassive,
 		encoding:        q.ContentEncoding,
 		timeout:         q.Timeout.Duration,
+		confirmPublish:  q.ConfirmPublish,
 	}
 
 	switch q.ExchangeDurability {
@@ -138,6 +138,20 @@ func TestConnect(t *testing.T) {
 				require.NoError(t, err)
 			},
 		},
+		{
+			name: "confirm publish",
+			output: &AMQP{
+				ConfirmPublish: true,
+				connect: func(config *ClientConfig) (Client, error) {
+					return NewMockClient(), nil
+				},
+			},
+			errFunc: func(t *testing.T, output *AMQP, err error) {
+				config := output.config
+				require.True(t, config.confirmPublish)
+				require.NoError(t, err)
+			},
+		},
 		{
 			name: "url support",
 			output: &AMQP{
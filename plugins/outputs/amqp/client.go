@@ -25,12 +25,14 @@ type ClientConfig struct {
 	tlsConfig         *tls.Config
 	timeout           time.Duration
 	auth              []amqp.Authentication
+	confirmPublish    bool
 }
 
 type client struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	config  *ClientConfig
+	conn     *amqp.Connection
+	channel  *amqp.Channel
+	config   *ClientConfig
+	confirms chan amqp.Confirmation
 }
 
 // Connect opens a connection to one of the brokers at random
@@ -69,6 +71,14 @@ func Connect(config *ClientConfig) (*client, error) {
 	}
 	client.channel = channel
 
+	if config.confirmPublish {
+		err = channel.Confirm(false)
+		if err != nil {
+			return nil, fmt.Errorf("error setting channel to confirm mode: %v", err)
+		}
+		client.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+
 	err = client.DeclareExchange()
 	if err != nil {
 		return nil, err
@@ -111,9 +121,9 @@ func (c *client) DeclareExchange() error {
 }
 
 func (c *client) Publish(key string, body []byte) error {
-	// Note that since the channel is not in confirm mode, the absence of
-	// an error does not indicate successful delivery.
-	return c.channel.Publish(
+	// Note that unless the channel is in confirm mode, the absence of an
+	// error does not indicate successful delivery.
+	err := c.channel.Publish(
 		c.config.exchange, // exchange
 		key,               // routing key
 		false,             // mandatory
@@ -125,6 +135,40 @@ func (c *client) Publish(key string, body []byte) error {
 			Body:            body,
 			DeliveryMode:    c.config.deliveryMode,
 		})
+	if err != nil {
+		return err
+	}
+
+	if c.confirms == nil {
+		return nil
+	}
+	return c.waitConfirm()
+}
+
+// waitConfirm blocks until the broker confirms the last published message.
+func (c *client) waitConfirm() error {
+	var timeout <-chan time.Time
+	if c.config.timeout > 0 {
+		timer := time.NewTimer(c.config.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case confirm, ok := <-c.confirms:
+		if !ok {
+			return amqp.ErrClosed
+		}
+		if !confirm.Ack {
+			return fmt.Errorf("message %d was not acknowledged by the broker", confirm.DeliveryTag)
+		}
+		return nil
+	case <-timeout:
+		// The confirmation could still arrive later and would be mismatched
+		// with the next message, so the connection is discarded.
+		c.Close()
+		return errors.New("timeout waiting for publisher confirm")
+	}
 }
 
 func (c *client) Close() error {
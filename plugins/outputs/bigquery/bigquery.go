@@ -0,0 +1,343 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"google.golang.org/api/option"
+	gtransport "google.golang.org/api/transport/grpc"
+)
+
+const (
+	endpoint = "bigquerystorage.googleapis.com:443"
+	scope    = "https://www.googleapis.com/auth/bigquery"
+
+	// AppendRows requests are limited to 10MB, the remaining space is left
+	// for the encoding overhead.
+	maxRequestSize = 9 * 1024 * 1024
+)
+
+const sampleConfig = `
+  ## GCP project and dataset of the tables.
+  project = "my-project"
+  dataset = "telegraf"
+
+  ## Filepath of the GCP credentials JSON file, if not set the Application
+  ## Default Credentials are used.
+  # credentials_file = "path/to/my/creds.json"
+
+  ## Name of the table of each metric, {MEASUREMENT} is replaced by the
+  ## measurement name.  Characters not allowed in table names are replaced
+  ## with underscores.  The tables must exist, tags and fields without
+  ## column are dropped.
+  # table_name = "{MEASUREMENT}"
+
+  ## Name of the TIMESTAMP column containing the time of the metrics.
+  # timestamp_column = "timestamp"
+
+  ## Timeout for writing the metrics of a table.
+  # timeout = "30s"
+`
+
+type writeClient interface {
+	tableSchema(ctx context.Context, stream string) ([]tableField, error)
+	appendRows(ctx context.Context, stream string, requests []*appendRowsRequest) ([]string, error)
+	close() error
+}
+
+// table is the schema of a table with the supported columns.
+type table struct {
+	columns    []tableField
+	descriptor []byte
+
+	// missing contains the tags and fields without column, which are
+	// reported once.
+	missing map[string]bool
+}
+
+// BigQuery is an output plugin writing metrics to BigQuery tables with the
+// Storage Write API.
+type BigQuery struct {
+	Project         string            `toml:"project"`
+	Dataset         string            `toml:"dataset"`
+	CredentialsFile string            `toml:"credentials_file"`
+	TableName       string            `toml:"table_name"`
+	TimestampColumn string            `toml:"timestamp_column"`
+	Timeout         internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	client writeClient
+	tables map[string]*table
+}
+
+func (b *BigQuery) Description() string {
+	return "Write metrics to Google BigQuery tables using the Storage Write API"
+}
+
+func (b *BigQuery) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BigQuery) Init() error {
+	if b.Project == "" {
+		return errors.New("project must be set")
+	}
+	if b.Dataset == "" {
+		return errors.New("dataset must be set")
+	}
+	return nil
+}
+
+func (b *BigQuery) Connect() error {
+	opts := []option.ClientOption{
+		option.WithEndpoint(endpoint),
+		option.WithScopes(scope),
+		option.WithUserAgent(internal.ProductToken()),
+	}
+	if b.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(b.CredentialsFile))
+	}
+
+	conn, err := gtransport.Dial(context.Background(), opts...)
+	if err != nil {
+		return err
+	}
+	b.client = &storageClient{conn: conn}
+	b.tables = make(map[string]*table)
+	return nil
+}
+
+func (b *BigQuery) Close() error {
+	if b.client == nil {
+		return nil
+	}
+	return b.client.close()
+}
+
+func (b *BigQuery) Write(metrics []telegraf.Metric) error {
+	var order []string
+	tables := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		name := sanitize(strings.Replace(b.TableName, "{MEASUREMENT}", m.Name(), -1))
+		if _, ok := tables[name]; !ok {
+			order = append(order, name)
+		}
+		tables[name] = append(tables[name], m)
+	}
+
+	for _, name := range order {
+		if err := b.write(name, tables[name]); err != nil {
+			return fmt.Errorf("writing to table %q failed: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (b *BigQuery) write(name string, metrics []telegraf.Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout.Duration)
+	defer cancel()
+
+	stream := fmt.Sprintf("projects/%s/datasets/%s/tables/%s/streams/_default", b.Project, b.Dataset, name)
+
+	t, ok := b.tables[name]
+	if !ok {
+		fields, err := b.client.tableSchema(ctx, stream)
+		if err != nil {
+			return err
+		}
+		t = newTable(fields)
+		b.tables[name] = t
+	}
+
+	var requests []*appendRowsRequest
+	request := &appendRowsRequest{stream: stream, descriptor: t.descriptor}
+	size := request.size()
+	for _, m := range metrics {
+		row := b.encode(name, t, m)
+		if size+len(row) > maxRequestSize && len(request.rows) > 0 {
+			requests = append(requests, request)
+			request = &appendRowsRequest{stream: stream, descriptor: t.descriptor}
+			size = request.size()
+		}
+		request.rows = append(request.rows, row)
+		size += len(row) + rowOverhead
+	}
+	requests = append(requests, request)
+
+	rowErrors, err := b.client.appendRows(ctx, stream, requests)
+	if err != nil {
+		// The schema of the table might have changed
+		delete(b.tables, name)
+		return err
+	}
+
+	// Retrying invalid rows does not succeed, the metrics are dropped
+	for _, rowError := range rowErrors {
+		b.Log.Errorf("Dropping metrics of table %q, invalid %s", name, rowError)
+	}
+	return nil
+}
+
+// newTable returns the table with the columns of supported types.
+func newTable(fields []tableField) *table {
+	t := &table{missing: make(map[string]bool)}
+	for _, f := range fields {
+		switch f.typ {
+		case typeString, typeInt64, typeDouble, typeBool, typeTimestamp:
+			t.columns = append(t.columns, f)
+		}
+	}
+	t.descriptor = descriptor(t.columns)
+	return t
+}
+
+// encode returns the serialized row of the metric.
+func (b *BigQuery) encode(name string, t *table, m telegraf.Metric) []byte {
+	values := make(map[string]interface{}, len(m.TagList())+len(m.FieldList())+1)
+	for _, tag := range m.TagList() {
+		values[tag.Key] = tag.Value
+	}
+	for _, field := range m.FieldList() {
+		values[field.Key] = field.Value
+	}
+	values[b.TimestampColumn] = m.Time()
+
+	var row []byte
+	for i, c := range t.columns {
+		v, ok := values[c.name]
+		if !ok {
+			continue
+		}
+		delete(values, c.name)
+
+		var valid bool
+		row, valid = appendValue(row, i+1, c.typ, v)
+		if !valid {
+			b.Log.Debugf("Cannot convert value %v of %q to the type of the column", v, c.name)
+		}
+	}
+
+	for key := range values {
+		if !t.missing[key] {
+			b.Log.Warnf("Table %q has no column %q of a supported type, dropping its values", name, key)
+			t.missing[key] = true
+		}
+	}
+	return row
+}
+
+// appendValue appends the value converted to the type of the column, or
+// returns false if the value cannot be converted.
+func appendValue(b []byte, field int, typ int, v interface{}) ([]byte, bool) {
+	switch typ {
+	case typeString:
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case uint64:
+			s = strconv.FormatUint(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			s = strconv.FormatBool(v)
+		case time.Time:
+			s = v.UTC().Format(time.RFC3339Nano)
+		default:
+			return b, false
+		}
+		return appendBytes(b, field, []byte(s)), true
+	case typeInt64:
+		var i int64
+		switch v := v.(type) {
+		case int64:
+			i = v
+		case uint64:
+			if v > math.MaxInt64 {
+				v = math.MaxInt64
+			}
+			i = int64(v)
+		case float64:
+			i = int64(v)
+		case bool:
+			if v {
+				i = 1
+			}
+		default:
+			return b, false
+		}
+		return appendVarintField(b, field, uint64(i)), true
+	case typeDouble:
+		var f float64
+		switch v := v.(type) {
+		case float64:
+			f = v
+		case int64:
+			f = float64(v)
+		case uint64:
+			f = float64(v)
+		default:
+			return b, false
+		}
+		return appendDoubleField(b, field, f), true
+	case typeBool:
+		var x bool
+		switch v := v.(type) {
+		case bool:
+			x = v
+		case int64:
+			x = v != 0
+		case uint64:
+			x = v != 0
+		case float64:
+			x = v != 0
+		default:
+			return b, false
+		}
+		var i uint64
+		if x {
+			i = 1
+		}
+		return appendVarintField(b, field, i), true
+	case typeTimestamp:
+		t, ok := v.(time.Time)
+		if !ok {
+			return b, false
+		}
+		return appendVarintField(b, field, uint64(t.UnixNano()/int64(time.Microsecond))), true
+	}
+	return b, false
+}
+
+// sanitize replaces all characters not allowed in table names.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func init() {
+	outputs.Add("bigquery", func() telegraf.Output {
+		return &BigQuery{
+			TableName:       "{MEASUREMENT}",
+			TimestampColumn: "timestamp",
+			Timeout:         internal.Duration{Duration: 30 * time.Second},
+		}
+	})
+}
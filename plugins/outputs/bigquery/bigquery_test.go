@@ -0,0 +1,213 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct {
+	schemas   map[string][]tableField
+	requests  []*appendRowsRequest
+	rowErrors []string
+	err       error
+}
+
+func (c *fakeClient) tableSchema(ctx context.Context, stream string) ([]tableField, error) {
+	fields, ok := c.schemas[stream]
+	if !ok {
+		return nil, errors.New("table not found")
+	}
+	return fields, nil
+}
+
+func (c *fakeClient) appendRows(ctx context.Context, stream string, requests []*appendRowsRequest) ([]string, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.requests = append(c.requests, requests...)
+	return c.rowErrors, nil
+}
+
+func (c *fakeClient) close() error {
+	return nil
+}
+
+const cpuStream = "projects/project/datasets/telegraf/tables/cpu_total/streams/_default"
+
+var cpuSchema = []tableField{
+	{name: "timestamp", typ: typeTimestamp},
+	{name: "host", typ: typeString},
+	{name: "usage", typ: typeDouble},
+	{name: "count", typ: typeInt64},
+	{name: "ok", typ: typeBool},
+	{name: "location", typ: 11},
+}
+
+func newTestBigQuery(client *fakeClient) *BigQuery {
+	return &BigQuery{
+		Project:         "project",
+		Dataset:         "telegraf",
+		TableName:       "{MEASUREMENT}",
+		TimestampColumn: "timestamp",
+		Timeout:         internal.Duration{Duration: 5 * time.Second},
+		Log:             testutil.Logger{},
+		client:          client,
+		tables:          make(map[string]*table),
+	}
+}
+
+// decodeRow returns the values of a row by field number.
+func decodeRow(t *testing.T, row []byte) map[int]interface{} {
+	values := make(map[int]interface{})
+	for len(row) > 0 {
+		key, n := binary.Uvarint(row)
+		require.True(t, n > 0)
+		row = row[n:]
+
+		field := int(key >> 3)
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(row)
+			require.True(t, n > 0)
+			values[field] = v
+			row = row[n:]
+		case wireFixed64:
+			values[field] = math.Float64frombits(binary.LittleEndian.Uint64(row))
+			row = row[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(row)
+			require.True(t, n > 0)
+			values[field] = string(row[n : n+int(l)])
+			row = row[n+int(l):]
+		}
+	}
+	return values
+}
+
+func TestWrite(t *testing.T) {
+	client := &fakeClient{schemas: map[string][]tableField{cpuStream: cpuSchema}}
+	plugin := newTestBigQuery(client)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu.total",
+			map[string]string{"host": "a", "region": "east"},
+			map[string]interface{}{"usage": 1.5, "count": int64(3), "ok": true},
+			time.Unix(1, 500000),
+		),
+		testutil.MustMetric(
+			"cpu.total",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"usage": int64(2), "count": 4.7},
+			time.Unix(2, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Len(t, client.requests, 1)
+	request := client.requests[0]
+	require.Equal(t, cpuStream, request.stream)
+	require.Equal(t, descriptor(cpuSchema[:5]), request.descriptor)
+	require.Len(t, request.rows, 2)
+
+	require.Equal(t, map[int]interface{}{
+		1: uint64(1000500),
+		2: "a",
+		3: 1.5,
+		4: uint64(3),
+		5: uint64(1),
+	}, decodeRow(t, request.rows[0]))
+	require.Equal(t, map[int]interface{}{
+		1: uint64(2000000),
+		2: "b",
+		3: 2.0,
+		4: uint64(4),
+	}, decodeRow(t, request.rows[1]))
+}
+
+func TestWriteSplitRequests(t *testing.T) {
+	client := &fakeClient{schemas: map[string][]tableField{cpuStream: cpuSchema}}
+	plugin := newTestBigQuery(client)
+
+	host := strings.Repeat("x", 1024*1024)
+	var metrics []telegraf.Metric
+	for i := 0; i < 20; i++ {
+		metrics = append(metrics, testutil.MustMetric(
+			"cpu_total",
+			map[string]string{"host": host},
+			map[string]interface{}{"usage": 1.0},
+			time.Unix(0, 0),
+		))
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Len(t, client.requests, 3)
+	var rows int
+	for _, request := range client.requests {
+		require.True(t, request.size() <= maxRequestSize)
+		rows += len(request.rows)
+	}
+	require.Equal(t, 20, rows)
+}
+
+func TestWriteError(t *testing.T) {
+	client := &fakeClient{schemas: map[string][]tableField{cpuStream: cpuSchema}}
+	plugin := newTestBigQuery(client)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu_total", map[string]string{}, map[string]interface{}{"usage": 1.0}, time.Unix(0, 0)),
+	}
+
+	// The schema is requested again after errors
+	client.err = errors.New("unavailable")
+	require.Error(t, plugin.Write(metrics))
+	require.Empty(t, plugin.tables)
+
+	// Invalid rows are dropped
+	client.err = nil
+	client.rowErrors = []string{"row 0: invalid"}
+	require.NoError(t, plugin.Write(metrics))
+
+	// Missing tables fail the write
+	require.Error(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"used": 1.0}, time.Unix(0, 0)),
+	}))
+}
+
+func TestUnmarshal(t *testing.T) {
+	var field []byte
+	field = appendBytes(field, 1, []byte("host"))
+	field = appendVarintField(field, 2, typeString)
+	field = appendVarintField(field, 3, 1)
+	schema := appendBytes(nil, 1, field)
+	stream := appendBytes(nil, 1, []byte(cpuStream))
+	stream = appendBytes(stream, 5, schema)
+
+	ws := &writeStream{}
+	require.NoError(t, ws.Unmarshal(stream))
+	require.Equal(t, []tableField{{name: "host", typ: typeString}}, ws.fields)
+
+	status := appendVarintField(nil, 1, 3)
+	status = appendBytes(status, 2, []byte("invalid argument"))
+	rowError := appendVarintField(nil, 1, 2)
+	rowError = appendBytes(rowError, 3, []byte("bad value"))
+	response := appendBytes(nil, 2, status)
+	response = appendBytes(response, 4, rowError)
+
+	r := &appendRowsResponse{}
+	require.NoError(t, r.Unmarshal(response))
+	require.Equal(t, "code 3: invalid argument", r.err)
+	require.Equal(t, []string{"row 2: bad value"}, r.rowErrors)
+
+	require.Error(t, r.Unmarshal(response[:len(response)-1]))
+}
@@ -0,0 +1,361 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	methodGetWriteStream = "/google.cloud.bigquery.storage.v1.BigQueryWrite/GetWriteStream"
+	methodAppendRows     = "/google.cloud.bigquery.storage.v1.BigQueryWrite/AppendRows"
+
+	// The view of the write stream including the table schema
+	writeStreamViewFull = 2
+)
+
+// Protocol buffer wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Types of the table columns
+const (
+	typeString    = 1
+	typeInt64     = 2
+	typeDouble    = 3
+	typeBool      = 6
+	typeTimestamp = 7
+)
+
+// Types of the fields of protocol buffer descriptors
+const (
+	protoDouble = 1
+	protoInt64  = 3
+	protoBool   = 8
+	protoString = 9
+
+	labelOptional = 1
+)
+
+// The messages of the Storage Write API are encoded by hand to avoid
+// depending on the generated code of newer versions of the Google Cloud
+// modules, they implement the Marshaler and Unmarshaler interfaces used by
+// the gRPC codec.
+
+func appendKey(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendKey(b, field, wireVarint)
+	return appendVarint(b, v)
+}
+
+func appendDoubleField(b []byte, field int, v float64) []byte {
+	b = appendKey(b, field, wireFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	return append(b, buf[:]...)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendKey(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// readFields calls fn for each varint and length-delimited field of the
+// message and skips all other fields.
+func readFields(b []byte, fn func(field int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("truncated message")
+		}
+		b = b[n:]
+
+		field, wireType := int(key>>3), int(key&7)
+		switch wireType {
+		case wireVarint:
+			v, m := binary.Uvarint(b)
+			if m <= 0 {
+				return errors.New("truncated message")
+			}
+			if err := fn(field, v, nil); err != nil {
+				return err
+			}
+			n = m
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		case wireBytes:
+			l, m := binary.Uvarint(b)
+			if m <= 0 || uint64(len(b)-m) < l {
+				return errors.New("truncated message")
+			}
+			if err := fn(field, 0, b[m:m+int(l)]); err != nil {
+				return err
+			}
+			n = m + int(l)
+		default:
+			return fmt.Errorf("unsupported wire type %d", wireType)
+		}
+		if n > len(b) {
+			return errors.New("truncated message")
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// tableField is a column of a table.
+type tableField struct {
+	name string
+	typ  int
+}
+
+type getWriteStreamRequest struct {
+	name string
+}
+
+func (m *getWriteStreamRequest) Reset()         { *m = getWriteStreamRequest{} }
+func (m *getWriteStreamRequest) String() string { return "GetWriteStreamRequest" }
+func (m *getWriteStreamRequest) ProtoMessage()  {}
+
+func (m *getWriteStreamRequest) Marshal() ([]byte, error) {
+	b := appendBytes(nil, 1, []byte(m.name))
+	return appendVarintField(b, 3, writeStreamViewFull), nil
+}
+
+// writeStream contains the schema of the table of the stream.
+type writeStream struct {
+	fields []tableField
+}
+
+func (m *writeStream) Reset()         { *m = writeStream{} }
+func (m *writeStream) String() string { return "WriteStream" }
+func (m *writeStream) ProtoMessage()  {}
+
+func (m *writeStream) Unmarshal(b []byte) error {
+	return readFields(b, func(field int, _ uint64, data []byte) error {
+		if field != 5 {
+			return nil
+		}
+
+		// TableSchema message
+		return readFields(data, func(field int, _ uint64, data []byte) error {
+			if field != 1 {
+				return nil
+			}
+
+			// TableFieldSchema message
+			var f tableField
+			err := readFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					f.name = string(data)
+				case 2:
+					f.typ = int(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			m.fields = append(m.fields, f)
+			return nil
+		})
+	})
+}
+
+// appendRowsRequest appends serialized rows to a stream.
+type appendRowsRequest struct {
+	stream     string
+	descriptor []byte
+	rows       [][]byte
+}
+
+func (m *appendRowsRequest) Reset()         { *m = appendRowsRequest{} }
+func (m *appendRowsRequest) String() string { return "AppendRowsRequest" }
+func (m *appendRowsRequest) ProtoMessage()  {}
+
+func (m *appendRowsRequest) Marshal() ([]byte, error) {
+	schema := appendBytes(nil, 1, m.descriptor)
+
+	var rows []byte
+	for _, row := range m.rows {
+		rows = appendBytes(rows, 1, row)
+	}
+
+	data := appendBytes(nil, 1, schema)
+	data = appendBytes(data, 2, rows)
+
+	b := appendBytes(nil, 1, []byte(m.stream))
+	return appendBytes(b, 4, data), nil
+}
+
+// rowOverhead is the maximum size of the key and length of a row.
+const rowOverhead = binary.MaxVarintLen32 + 1
+
+// size returns the approximate size of the encoded request.
+func (m *appendRowsRequest) size() int {
+	size := len(m.stream) + len(m.descriptor) + 32
+	for _, row := range m.rows {
+		size += len(row) + rowOverhead
+	}
+	return size
+}
+
+// appendRowsResponse contains the errors of an append.
+type appendRowsResponse struct {
+	err       string
+	rowErrors []string
+}
+
+func (m *appendRowsResponse) Reset()         { *m = appendRowsResponse{} }
+func (m *appendRowsResponse) String() string { return "AppendRowsResponse" }
+func (m *appendRowsResponse) ProtoMessage()  {}
+
+func (m *appendRowsResponse) Unmarshal(b []byte) error {
+	return readFields(b, func(field int, _ uint64, data []byte) error {
+		switch field {
+		case 2:
+			// google.rpc.Status message
+			var code uint64
+			var message string
+			err := readFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					code = v
+				case 2:
+					message = string(data)
+				}
+				return nil
+			})
+			m.err = fmt.Sprintf("code %d: %s", code, message)
+			return err
+		case 4:
+			// RowError message
+			var index uint64
+			var message string
+			err := readFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					index = v
+				case 3:
+					message = string(data)
+				}
+				return nil
+			})
+			m.rowErrors = append(m.rowErrors, fmt.Sprintf("row %d: %s", index, message))
+			return err
+		}
+		return nil
+	})
+}
+
+// descriptor returns the encoded DescriptorProto of rows with the columns,
+// the field numbers are the indices of the columns plus one.
+func descriptor(columns []tableField) []byte {
+	b := appendBytes(nil, 1, []byte("row"))
+	for i, c := range columns {
+		var typ uint64
+		switch c.typ {
+		case typeString:
+			typ = protoString
+		case typeDouble:
+			typ = protoDouble
+		case typeBool:
+			typ = protoBool
+		default:
+			// Timestamps are microseconds since the epoch
+			typ = protoInt64
+		}
+
+		var field []byte
+		field = appendBytes(field, 1, []byte(c.name))
+		field = appendVarintField(field, 3, uint64(i+1))
+		field = appendVarintField(field, 4, labelOptional)
+		field = appendVarintField(field, 5, typ)
+		b = appendBytes(b, 2, field)
+	}
+	return b
+}
+
+// storageClient writes rows to tables using the Storage Write API.
+type storageClient struct {
+	conn *grpc.ClientConn
+}
+
+func (c *storageClient) close() error {
+	return c.conn.Close()
+}
+
+func withStream(ctx context.Context, stream string) context.Context {
+	// The stream is used to route the requests
+	return metadata.AppendToOutgoingContext(ctx, "x-goog-request-params", "write_stream="+url.QueryEscape(stream))
+}
+
+func (c *storageClient) tableSchema(ctx context.Context, stream string) ([]tableField, error) {
+	response := &writeStream{}
+	err := c.conn.Invoke(withStream(ctx, stream), methodGetWriteStream, &getWriteStreamRequest{name: stream}, response)
+	if err != nil {
+		return nil, err
+	}
+	return response.fields, nil
+}
+
+// appendRows sends the requests and returns the errors of invalid rows,
+// requests with invalid rows are rejected entirely.
+func (c *storageClient) appendRows(ctx context.Context, stream string, requests []*appendRowsRequest) ([]string, error) {
+	ctx, cancel := context.WithCancel(withStream(ctx, stream))
+	defer cancel()
+
+	desc := &grpc.StreamDesc{StreamName: "AppendRows", ServerStreams: true, ClientStreams: true}
+	s, err := c.conn.NewStream(ctx, desc, methodAppendRows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, request := range requests {
+		if err := s.SendMsg(request); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	var rowErrors []string
+	for range requests {
+		response := &appendRowsResponse{}
+		if err := s.RecvMsg(response); err != nil {
+			return nil, err
+		}
+		if len(response.rowErrors) > 0 {
+			rowErrors = append(rowErrors, response.rowErrors...)
+			continue
+		}
+		if response.err != "" {
+			return nil, fmt.Errorf("appending rows failed with %s", response.err)
+		}
+	}
+	return rowErrors, nil
+}
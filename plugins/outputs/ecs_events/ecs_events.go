@@ -0,0 +1,383 @@
+package ecs_events
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// ecsVersion is the version of the Elastic Common Schema of the events.
+const ecsVersion = "1.12.0"
+
+const defaultIndexName = "telegraf-events-%Y.%m.%d"
+
+var kinds = map[string]bool{
+	"alert":          true,
+	"event":          true,
+	"metric":         true,
+	"state":          true,
+	"pipeline_error": true,
+	"signal":         true,
+}
+
+var sampleConfig = `
+  ## URLs of the Elasticsearch or OpenSearch nodes, the events are written to
+  ## the first node available.
+  urls = ["http://localhost:9200"]
+
+  ## Index written to, the date specifiers are replaced from the time of the
+  ## events: %Y (year), %y (year, 2 digits), %m (month), %d (day of month),
+  ## %H (hour) and %V (ISO week).
+  # index_name = "telegraf-events-%Y.%m.%d"
+
+  ## Timeout of the requests.
+  # timeout = "5s"
+
+  ## HTTP basic authentication, or API key of Elasticsearch as
+  ## "<id>:<api key>".
+  # username = "telegraf"
+  # password = "mypassword"
+  # api_key = ""
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Rules mapping the metrics to events, the first rule matching the name
+  ## of a metric applies.  Metrics not matching any rule are not written.
+  [[outputs.ecs_events.rule]]
+    ## Measurements of the rule, supports globs.
+    measurement = "alert"
+
+    ## Values of event.kind, event.category and event.type.
+    kind = "alert"
+    category = ["host"]
+    type = ["info"]
+
+    ## Numeric event.severity.
+    # severity = 0
+
+    ## Tags or fields of the metrics setting event.action and message.
+    # action_key = "state"
+    # message_key = "message"
+
+  # [[outputs.ecs_events.rule]]
+  #   measurement = "login_security_*"
+  #   kind = "event"
+  #   category = ["authentication"]
+  #   type = ["info"]
+`
+
+// Rule maps the metrics of measurements to ECS events.
+type Rule struct {
+	Measurement string   `toml:"measurement"`
+	Kind        string   `toml:"kind"`
+	Category    []string `toml:"category"`
+	Type        []string `toml:"type"`
+	Severity    int      `toml:"severity"`
+	ActionKey   string   `toml:"action_key"`
+	MessageKey  string   `toml:"message_key"`
+
+	measurementFilter filter.Filter
+}
+
+// ECSEvents writes metrics as events of the Elastic Common Schema to an
+// Elasticsearch or OpenSearch index.
+type ECSEvents struct {
+	URLs      []string          `toml:"urls"`
+	IndexName string            `toml:"index_name"`
+	Timeout   internal.Duration `toml:"timeout"`
+	Username  string            `toml:"username"`
+	Password  string            `toml:"password"`
+	APIKey    string            `toml:"api_key"`
+	Rules     []*Rule           `toml:"rule"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+}
+
+func (e *ECSEvents) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *ECSEvents) Description() string {
+	return "Write metrics as ECS events to an Elasticsearch or OpenSearch index"
+}
+
+func (e *ECSEvents) Init() error {
+	if len(e.URLs) == 0 {
+		return errors.New("at least one url is required")
+	}
+	if e.IndexName == "" {
+		e.IndexName = defaultIndexName
+	}
+	if e.APIKey != "" && (e.Username != "" || e.Password != "") {
+		return errors.New("api_key and basic authentication are exclusive")
+	}
+	if len(e.Rules) == 0 {
+		return errors.New("at least one rule is required")
+	}
+
+	for _, r := range e.Rules {
+		if r.Measurement == "" {
+			return errors.New("rule measurement must be set")
+		}
+		if r.Kind == "" {
+			r.Kind = "event"
+		}
+		if !kinds[r.Kind] {
+			return fmt.Errorf("rule %q: unknown kind %q", r.Measurement, r.Kind)
+		}
+
+		var err error
+		r.measurementFilter, err = filter.Compile([]string{r.Measurement})
+		if err != nil {
+			return fmt.Errorf("rule %q: %v", r.Measurement, err)
+		}
+	}
+	return nil
+}
+
+func (e *ECSEvents) Connect() error {
+	tlsCfg, err := e.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	e.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: e.Timeout.Duration,
+	}
+	return nil
+}
+
+func (e *ECSEvents) Close() error {
+	return nil
+}
+
+func (e *ECSEvents) Write(metrics []telegraf.Metric) error {
+	var body bytes.Buffer
+	for _, m := range metrics {
+		r := e.rule(m)
+		if r == nil {
+			continue
+		}
+
+		doc, err := json.Marshal(event(r, m))
+		if err != nil {
+			e.Log.Errorf("Could not encode event of %q: %v", m.Name(), err)
+			continue
+		}
+
+		// The ID of the document is the hash of the event, so the events of
+		// a batch written again after a failure are not duplicated.
+		sum := sha256.Sum256(doc)
+		action, err := json.Marshal(map[string]interface{}{
+			"index": map[string]string{
+				"_index": indexName(e.IndexName, m.Time()),
+				"_id":    hex.EncodeToString(sum[:16]),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc)
+		body.WriteByte('\n')
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	for _, u := range e.URLs {
+		if err = e.bulk(u, body.Bytes()); err == nil {
+			return nil
+		}
+		e.Log.Warnf("Writing to %q failed: %v", u, err)
+	}
+	return err
+}
+
+// rule returns the first rule matching the metric.
+func (e *ECSEvents) rule(m telegraf.Metric) *Rule {
+	for _, r := range e.Rules {
+		if r.measurementFilter.Match(m.Name()) {
+			return r
+		}
+	}
+	return nil
+}
+
+// event returns the ECS document of the metric.  The "host" tag is the name
+// of the host and the other tags are labels, the fields are in the
+// telegraf.<measurement> object.
+func event(r *Rule, m telegraf.Metric) map[string]interface{} {
+	ev := map[string]interface{}{
+		"kind":    r.Kind,
+		"module":  "telegraf",
+		"dataset": "telegraf." + m.Name(),
+	}
+	if len(r.Category) > 0 {
+		ev["category"] = r.Category
+	}
+	if len(r.Type) > 0 {
+		ev["type"] = r.Type
+	}
+	if r.Severity != 0 {
+		ev["severity"] = r.Severity
+	}
+	if action, ok := lookup(m, r.ActionKey); ok {
+		ev["action"] = action
+	}
+
+	doc := map[string]interface{}{
+		"@timestamp": m.Time().UTC().Format(time.RFC3339Nano),
+		"ecs":        map[string]string{"version": ecsVersion},
+		"event":      ev,
+		"telegraf":   map[string]interface{}{m.Name(): m.Fields()},
+	}
+	if message, ok := lookup(m, r.MessageKey); ok {
+		doc["message"] = message
+	}
+
+	labels := make(map[string]string)
+	for k, v := range m.Tags() {
+		if k == "host" {
+			doc["host"] = map[string]string{"name": v}
+			continue
+		}
+		labels[k] = v
+	}
+	if len(labels) > 0 {
+		doc["labels"] = labels
+	}
+	return doc
+}
+
+// lookup returns the value of the tag, or else of the field, of the metric
+// as a string.
+func lookup(m telegraf.Metric, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	if v, ok := m.GetTag(key); ok {
+		return v, true
+	}
+	if v, ok := m.GetField(key); ok {
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+// indexName replaces the date specifiers of the index name from the time.
+func indexName(name string, t time.Time) string {
+	t = t.UTC()
+	_, week := t.ISOWeek()
+	return strings.NewReplacer(
+		"%Y", t.Format("2006"),
+		"%y", t.Format("06"),
+		"%m", t.Format("01"),
+		"%d", t.Format("02"),
+		"%H", t.Format("15"),
+		"%V", fmt.Sprintf("%02d", week),
+	).Replace(name)
+}
+
+// bulkResponse is the response of the bulk API, with the status of each
+// document when the write of some of them failed.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func (e *ECSEvents) bulk(u string, body []byte) error {
+	req, err := http.NewRequest("POST", strings.TrimRight(u, "/")+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+base64.StdEncoding.EncodeToString([]byte(e.APIKey)))
+	} else if e.Username != "" || e.Password != "" {
+		req.SetBasicAuth(e.Username, e.Password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("received status code %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	var result bulkResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid bulk response: %v", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	// The documents rejected are dropped, writing them again would fail the
+	// same way, unless the nodes are overloaded.
+	failed := 0
+	var reason string
+	for _, item := range result.Items {
+		for _, status := range item {
+			if status.Status == http.StatusTooManyRequests {
+				return errors.New("events rejected, too many requests")
+			}
+			if status.Status >= 300 {
+				failed++
+				reason = status.Error.Type + ": " + status.Error.Reason
+			}
+		}
+	}
+	e.Log.Errorf("%d events were rejected, last error %s", failed, reason)
+	return nil
+}
+
+func init() {
+	outputs.Add("ecs_events", func() telegraf.Output {
+		return &ECSEvents{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package ecs_events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkServer records the documents of the bulk requests and answers with the
+// response.
+type bulkServer struct {
+	actions  []map[string]map[string]string
+	docs     []map[string]interface{}
+	auth     string
+	status   int
+	response string
+}
+
+func (s *bulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.auth = r.Header.Get("Authorization")
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		scanner.Scan()
+		var doc map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.actions = append(s.actions, action)
+		s.docs = append(s.docs, doc)
+	}
+
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+	}
+	if s.response == "" {
+		fmt.Fprint(w, `{"took": 1, "errors": false, "items": []}`)
+		return
+	}
+	fmt.Fprint(w, s.response)
+}
+
+func newECSEvents(urls ...string) *ECSEvents {
+	return &ECSEvents{
+		URLs: urls,
+		Rules: []*Rule{
+			{
+				Measurement: "alert",
+				Kind:        "alert",
+				Category:    []string{"host"},
+				Type:        []string{"info"},
+				Severity:    3,
+				ActionKey:   "state",
+			},
+			{
+				Measurement: "login_security_*",
+				Category:    []string{"authentication"},
+				MessageKey:  "message",
+			},
+		},
+		Log: testutil.Logger{},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	server := &bulkServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	e := newECSEvents(ts.URL)
+	e.Username = "telegraf"
+	e.Password = "secret"
+	require.NoError(t, e.Init())
+	require.NoError(t, e.Connect())
+
+	now := time.Date(2020, 5, 14, 2, 0, 0, 0, time.UTC)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"alert",
+			map[string]string{"host": "web01", "alertname": "high_cpu", "severity": "warning"},
+			map[string]interface{}{"state": "firing", "value": 95.5},
+			now,
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "web01"},
+			map[string]interface{}{"usage_user": 95.5},
+			now,
+		),
+		testutil.MustMetric(
+			"login_security_ssh",
+			map[string]string{"host": "web01"},
+			map[string]interface{}{"failed": 12, "message": "12 failed logins"},
+			now,
+		),
+	}
+	require.NoError(t, e.Write(metrics))
+	require.Len(t, server.docs, 2)
+	require.Contains(t, server.auth, "Basic ")
+
+	require.Equal(t, "telegraf-events-2020.05.14", server.actions[0]["index"]["_index"])
+	require.Len(t, server.actions[0]["index"]["_id"], 32)
+	require.Equal(t, map[string]interface{}{
+		"@timestamp": "2020-05-14T02:00:00Z",
+		"ecs":        map[string]interface{}{"version": ecsVersion},
+		"event": map[string]interface{}{
+			"kind":     "alert",
+			"category": []interface{}{"host"},
+			"type":     []interface{}{"info"},
+			"severity": 3.0,
+			"action":   "firing",
+			"module":   "telegraf",
+			"dataset":  "telegraf.alert",
+		},
+		"host":   map[string]interface{}{"name": "web01"},
+		"labels": map[string]interface{}{"alertname": "high_cpu", "severity": "warning"},
+		"telegraf": map[string]interface{}{
+			"alert": map[string]interface{}{"state": "firing", "value": 95.5},
+		},
+	}, server.docs[0])
+
+	require.Equal(t, map[string]interface{}{
+		"@timestamp": "2020-05-14T02:00:00Z",
+		"ecs":        map[string]interface{}{"version": ecsVersion},
+		"event": map[string]interface{}{
+			"kind":     "event",
+			"category": []interface{}{"authentication"},
+			"module":   "telegraf",
+			"dataset":  "telegraf.login_security_ssh",
+		},
+		"host":    map[string]interface{}{"name": "web01"},
+		"message": "12 failed logins",
+		"telegraf": map[string]interface{}{
+			"login_security_ssh": map[string]interface{}{"failed": 12.0, "message": "12 failed logins"},
+		},
+	}, server.docs[1])
+
+	// The same events are written with the same IDs.
+	require.NoError(t, e.Write(metrics))
+	require.Len(t, server.docs, 4)
+	require.Equal(t, server.actions[0], server.actions[2])
+	require.Equal(t, server.actions[1], server.actions[3])
+}
+
+func TestWriteFailover(t *testing.T) {
+	down := httptest.NewServer(&bulkServer{status: http.StatusServiceUnavailable})
+	defer down.Close()
+	server := &bulkServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	e := newECSEvents(down.URL, ts.URL)
+	e.APIKey = "id:key"
+	require.NoError(t, e.Init())
+	require.NoError(t, e.Connect())
+
+	m := testutil.MustMetric("alert", map[string]string{}, map[string]interface{}{"state": "resolved"}, time.Now())
+	require.NoError(t, e.Write([]telegraf.Metric{m}))
+	require.Len(t, server.docs, 1)
+	require.Equal(t, "ApiKey aWQ6a2V5", server.auth)
+
+	ts.Close()
+	require.Error(t, e.Write([]telegraf.Metric{m}))
+}
+
+func TestWriteRejected(t *testing.T) {
+	server := &bulkServer{
+		response: `{"errors": true, "items": [{"index": {"status": 400, "error": {"type": "mapper_parsing_exception", "reason": "failed to parse"}}}]}`,
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	e := newECSEvents(ts.URL)
+	require.NoError(t, e.Init())
+	require.NoError(t, e.Connect())
+
+	m := testutil.MustMetric("alert", map[string]string{}, map[string]interface{}{"state": "firing"}, time.Now())
+	require.NoError(t, e.Write([]telegraf.Metric{m}))
+
+	server.response = `{"errors": true, "items": [{"index": {"status": 429, "error": {"type": "es_rejected_execution_exception"}}}]}`
+	require.Error(t, e.Write([]telegraf.Metric{m}))
+}
+
+func TestIndexName(t *testing.T) {
+	ts := time.Date(2021, 1, 3, 7, 0, 0, 0, time.UTC)
+	require.Equal(t, "events-2021.01.03.07", indexName("events-%Y.%m.%d.%H", ts))
+	require.Equal(t, "events-21-w53", indexName("events-%y-w%V", ts))
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&ECSEvents{Rules: []*Rule{{Measurement: "alert"}}}).Init())
+	require.Error(t, (&ECSEvents{URLs: []string{"http://localhost:9200"}}).Init())
+	require.Error(t, (&ECSEvents{
+		URLs:  []string{"http://localhost:9200"},
+		Rules: []*Rule{{Measurement: "alert", Kind: "incident"}},
+	}).Init())
+
+	e := newECSEvents("http://localhost:9200")
+	require.NoError(t, e.Init())
+	require.Equal(t, "telegraf-events-%Y.%m.%d", e.IndexName)
+	require.Equal(t, "event", e.Rules[1].Kind)
+}
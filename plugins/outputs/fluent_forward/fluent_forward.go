@@ -0,0 +1,255 @@
+package fluent_forward
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/common/forward"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const defaultTimeout = 5 * time.Second
+
+const sampleConfig = `
+  ## Address of the Fluentd or Fluent Bit forward input.
+  address = "localhost:24224"
+
+  ## Timeout for establishing the connection and writing the events.
+  # timeout = "5s"
+
+  ## Shared key used to authenticate to the server, if set the shared key
+  ## handshake is done after connecting.  The self_hostname is sent to the
+  ## server and defaults to the hostname of the system.
+  # shared_key = ""
+  # self_hostname = ""
+
+  ## The tag of the events is the measurement name with this prefix.
+  # tag_prefix = ""
+
+  ## Wait for the server to acknowledge each write.
+  # require_ack = false
+
+  ## Send the time of the events in seconds instead of the EventTime with
+  ## nanosecond precision, required by Fluentd versions before 0.14.
+  # time_as_integer = false
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// FluentForward is an output plugin sending metrics as events with the
+// Fluentd forward protocol.
+type FluentForward struct {
+	Address       string            `toml:"address"`
+	Timeout       internal.Duration `toml:"timeout"`
+	SharedKey     string            `toml:"shared_key"`
+	SelfHostname  string            `toml:"self_hostname"`
+	TagPrefix     string            `toml:"tag_prefix"`
+	RequireAck    bool              `toml:"require_ack"`
+	TimeAsInteger bool              `toml:"time_as_integer"`
+	tlsint.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	tlsConfig *tls.Config
+	conn      net.Conn
+	decoder   *forward.Decoder
+	encoder   forward.Encoder
+}
+
+func (f *FluentForward) Description() string {
+	return "Send metrics to Fluentd and Fluent Bit using the forward protocol"
+}
+
+func (f *FluentForward) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *FluentForward) Init() error {
+	if f.Address == "" {
+		return fmt.Errorf("address must be configured")
+	}
+
+	if f.SharedKey != "" && f.SelfHostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		f.SelfHostname = hostname
+	}
+
+	tlsConfig, err := f.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	f.tlsConfig = tlsConfig
+	return nil
+}
+
+func (f *FluentForward) Connect() error {
+	dialer := &net.Dialer{Timeout: f.Timeout.Duration}
+
+	var conn net.Conn
+	var err error
+	if f.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", f.Address, f.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", f.Address)
+	}
+	if err != nil {
+		return err
+	}
+
+	decoder := forward.NewDecoder(conn)
+	if f.SharedKey != "" {
+		f.setDeadline(conn)
+		if err := forward.ClientHandshake(conn, decoder, f.SelfHostname, f.SharedKey); err != nil {
+			conn.Close()
+			return fmt.Errorf("handshake with %q failed: %v", f.Address, err)
+		}
+	}
+
+	f.conn = conn
+	f.decoder = decoder
+	return nil
+}
+
+func (f *FluentForward) Close() error {
+	if f.conn == nil {
+		return nil
+	}
+	err := f.conn.Close()
+	f.conn = nil
+	return err
+}
+
+func (f *FluentForward) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	if f.conn == nil {
+		if err := f.Connect(); err != nil {
+			return err
+		}
+	}
+
+	// Events in forward mode share the tag, so the metrics are grouped by
+	// measurement.
+	var order []string
+	groups := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		if _, ok := groups[m.Name()]; !ok {
+			order = append(order, m.Name())
+		}
+		groups[m.Name()] = append(groups[m.Name()], m)
+	}
+
+	for _, name := range order {
+		if err := f.send(f.TagPrefix+name, groups[name]); err != nil {
+			// The state of the stream is unknown after an error.
+			f.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FluentForward) send(tag string, metrics []telegraf.Metric) error {
+	var chunk string
+	if f.RequireAck {
+		var err error
+		chunk, err = forward.NewChunkID()
+		if err != nil {
+			return err
+		}
+	}
+
+	e := &f.encoder
+	e.Reset()
+	e.EncodeArrayLen(3)
+	e.EncodeString(tag)
+	e.EncodeArrayLen(len(metrics))
+	for _, m := range metrics {
+		e.EncodeArrayLen(2)
+		if f.TimeAsInteger {
+			e.EncodeInt(m.Time().Unix())
+		} else {
+			e.EncodeEventTime(m.Time())
+		}
+		if err := e.Encode(record(m)); err != nil {
+			return err
+		}
+	}
+
+	if chunk != "" {
+		e.EncodeMapLen(2)
+		e.EncodeString("size")
+		e.EncodeInt(int64(len(metrics)))
+		e.EncodeString("chunk")
+		e.EncodeString(chunk)
+	} else {
+		e.EncodeMapLen(1)
+		e.EncodeString("size")
+		e.EncodeInt(int64(len(metrics)))
+	}
+
+	f.conn.SetWriteDeadline(time.Now().Add(f.Timeout.Duration))
+	if _, err := f.conn.Write(e.Bytes()); err != nil {
+		return err
+	}
+
+	if chunk == "" {
+		return nil
+	}
+
+	f.setDeadline(f.conn)
+	v, err := f.decoder.Decode()
+	if err != nil {
+		return fmt.Errorf("reading ack: %v", err)
+	}
+	ack, err := forward.ParseAck(v)
+	if err != nil {
+		return err
+	}
+	if ack != chunk {
+		return fmt.Errorf("received ack for chunk %q, expected %q", ack, chunk)
+	}
+	return nil
+}
+
+func (f *FluentForward) setDeadline(c net.Conn) {
+	c.SetReadDeadline(time.Now().Add(f.Timeout.Duration))
+}
+
+// record returns the record of the event, containing the tags and fields of
+// the metric.
+func record(m telegraf.Metric) map[string]interface{} {
+	r := make(map[string]interface{}, len(m.TagList())+len(m.FieldList()))
+	for _, tag := range m.TagList() {
+		r[tag.Key] = tag.Value
+	}
+	for _, field := range m.FieldList() {
+		r[field.Key] = field.Value
+	}
+	return r
+}
+
+func init() {
+	outputs.Add("fluent_forward", func() telegraf.Output {
+		return &FluentForward{
+			Address: "localhost:24224",
+			Timeout: internal.Duration{Duration: defaultTimeout},
+		}
+	})
+}
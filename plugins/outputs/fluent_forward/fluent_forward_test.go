@@ -0,0 +1,139 @@
+package fluent_forward
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/forward"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// server accepts a single connection and returns the received messages.
+func server(t *testing.T, sharedKey string, ack bool) (net.Listener, chan *forward.Message) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	messages := make(chan *forward.Message, 10)
+	go func() {
+		defer close(messages)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		d := forward.NewDecoder(conn)
+		if sharedKey != "" {
+			if err := forward.ServerHandshake(conn, d, "server", sharedKey); err != nil {
+				return
+			}
+		}
+		for {
+			v, err := d.Decode()
+			if err != nil {
+				return
+			}
+			msg, err := forward.ParseMessage(v)
+			if err != nil {
+				return
+			}
+			if ack && msg.Chunk != "" {
+				e := &forward.Encoder{}
+				forward.EncodeAck(e, msg.Chunk)
+				conn.Write(e.Bytes())
+			}
+			messages <- msg
+		}
+	}()
+	return listener, messages
+}
+
+func newTestPlugin(address string) *FluentForward {
+	return &FluentForward{
+		Address: address,
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		Log:     testutil.Logger{},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	listener, messages := server(t, "secret", true)
+	defer listener.Close()
+
+	plugin := newTestPlugin(listener.Addr().String())
+	plugin.SharedKey = "secret"
+	plugin.SelfHostname = "telegraf"
+	plugin.TagPrefix = "telegraf."
+	plugin.RequireAck = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"usage_idle": 99.5},
+			time.Unix(10, 20),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"used": uint64(42)},
+			time.Unix(10, 20),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{"usage_idle": 98.5},
+			time.Unix(11, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	msg := <-messages
+	require.Equal(t, "telegraf.cpu", msg.Tag)
+	require.NotEmpty(t, msg.Chunk)
+	require.Len(t, msg.Entries, 2)
+	require.True(t, time.Unix(10, 20).Equal(msg.Entries[0].Time))
+	require.Equal(t, map[string]interface{}{"host": "server01", "usage_idle": 99.5}, msg.Entries[0].Record)
+	require.Equal(t, map[string]interface{}{"host": "server02", "usage_idle": 98.5}, msg.Entries[1].Record)
+
+	msg = <-messages
+	require.Equal(t, "telegraf.mem", msg.Tag)
+	require.Len(t, msg.Entries, 1)
+	require.Equal(t, map[string]interface{}{"host": "server01", "used": int64(42)}, msg.Entries[0].Record)
+}
+
+func TestWriteTimeAsInteger(t *testing.T) {
+	listener, messages := server(t, "", false)
+	defer listener.Close()
+
+	plugin := newTestPlugin(listener.Addr().String())
+	plugin.TimeAsInteger = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{testutil.TestMetric(1.0)}))
+
+	msg := <-messages
+	require.Empty(t, msg.Chunk)
+	require.Len(t, msg.Entries, 1)
+	require.True(t, time.Unix(1257894000, 0).Equal(msg.Entries[0].Time))
+}
+
+func TestConnectSharedKeyMismatch(t *testing.T) {
+	listener, _ := server(t, "secret", false)
+	defer listener.Close()
+
+	plugin := newTestPlugin(listener.Addr().String())
+	plugin.SharedKey = "wrong"
+	plugin.SelfHostname = "telegraf"
+	require.NoError(t, plugin.Init())
+	require.Error(t, plugin.Connect())
+}
@@ -1,8 +1,10 @@
 package graphite
 
 import (
+	"bytes"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"math/rand"
@@ -22,7 +24,12 @@ type Graphite struct {
 	Prefix   string
 	Template string
 	Timeout  int
-	conns    []net.Conn
+	Protocol string
+	Routing  string
+	// conns are the connections to the servers, nil if not connected
+	conns     []net.Conn
+	ring      *hashRing
+	tlsConfig *tls.Config
 	tlsint.ClientConfig
 }
 
@@ -31,6 +38,20 @@ var sampleConfig = `
   ## If multiple endpoints are configured, output will be load balanced.
   ## Only one of the endpoints will be written to with each iteration.
   servers = ["localhost:2003"]
+
+  ## Protocol of the endpoints, "plaintext" or "pickle".  The pickle protocol
+  ## sends the metrics in batches, usually to port 2004 of carbon.
+  # protocol = "plaintext"
+
+  ## Routing of the metrics to the endpoints:
+  ##   random          - all metrics are written to a random endpoint, the
+  ##                     others are tried if it fails.
+  ##   consistent_hash - each metric is written to the endpoint chosen by a
+  ##                     consistent hash ring of its path, for multiple carbon
+  ##                     relays or caches.  If the endpoint fails, its metrics
+  ##                     are written to the next endpoint on the ring.
+  # routing = "random"
+
   ## Prefix metrics name
   prefix = ""
   ## Graphite output template
@@ -60,38 +81,66 @@ func (g *Graphite) Connect() error {
 		g.Servers = append(g.Servers, "localhost:2003")
 	}
 
+	switch g.Protocol {
+	case "":
+		g.Protocol = "plaintext"
+	case "plaintext", "pickle":
+	default:
+		return fmt.Errorf("invalid protocol %q", g.Protocol)
+	}
+
+	switch g.Routing {
+	case "":
+		g.Routing = "random"
+	case "random":
+	case "consistent_hash":
+		g.ring = newHashRing(g.Servers)
+	default:
+		return fmt.Errorf("invalid routing %q", g.Routing)
+	}
+
 	// Set tls config
 	tlsConfig, err := g.ClientConfig.TLSConfig()
 	if err != nil {
 		return err
 	}
+	g.tlsConfig = tlsConfig
 
-	// Get Connections
-	var conns []net.Conn
-	for _, server := range g.Servers {
-		// Dialer with timeout
-		d := net.Dialer{Timeout: time.Duration(g.Timeout) * time.Second}
+	// Get Connections, the servers not connected are retried on write
+	g.conns = make([]net.Conn, len(g.Servers))
+	for n := range g.Servers {
+		g.dial(n)
+	}
+	return nil
+}
 
-		// Get secure connection if tls config is set
-		var conn net.Conn
-		if tlsConfig != nil {
-			conn, err = tls.DialWithDialer(&d, "tcp", server, tlsConfig)
-		} else {
-			conn, err = d.Dial("tcp", server)
-		}
+// dial connects to the server at the index.
+func (g *Graphite) dial(n int) error {
+	// Dialer with timeout
+	d := net.Dialer{Timeout: time.Duration(g.Timeout) * time.Second}
 
-		if err == nil {
-			conns = append(conns, conn)
-		}
+	// Get secure connection if tls config is set
+	var conn net.Conn
+	var err error
+	if g.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(&d, "tcp", g.Servers[n], g.tlsConfig)
+	} else {
+		conn, err = d.Dial("tcp", g.Servers[n])
+	}
+	if err != nil {
+		return err
 	}
-	g.conns = conns
+
+	g.conns[n] = conn
 	return nil
 }
 
 func (g *Graphite) Close() error {
 	// Closing all connections
 	for _, conn := range g.conns {
-		conn.Close()
+		if conn != nil {
+			conn.Close()
+		}
 	}
 	return nil
 }
@@ -147,6 +196,14 @@ func (g *Graphite) Write(metrics []telegraf.Metric) error {
 		batch = append(batch, buf...)
 	}
 
+	if g.Routing == "consistent_hash" {
+		return g.route(bytes.SplitAfter(batch, []byte("\n")))
+	}
+
+	if g.Protocol == "pickle" {
+		batch = encodePickle(bytes.SplitAfter(batch, []byte("\n")))
+	}
+
 	err = g.send(batch)
 
 	// try to reconnect and retry to send
@@ -166,6 +223,9 @@ func (g *Graphite) send(batch []byte) error {
 	// Send data to a random server
 	p := rand.Perm(len(g.conns))
 	for _, n := range p {
+		if g.conns[n] == nil {
+			continue
+		}
 		if g.Timeout > 0 {
 			g.conns[n].SetWriteDeadline(time.Now().Add(time.Duration(g.Timeout) * time.Second))
 		}
@@ -186,6 +246,72 @@ func (g *Graphite) send(batch []byte) error {
 	return err
 }
 
+// route writes each line to the server of its path on the hash ring.  The
+// lines of a failing server are routed to the next servers on the ring,
+// until all servers failed.
+func (g *Graphite) route(lines [][]byte) error {
+	failed := make(map[int]bool)
+	for len(lines) > 0 {
+		groups := make(map[int][][]byte)
+		for _, line := range lines {
+			if len(line) == 0 {
+				continue
+			}
+			path := line
+			if i := bytes.IndexByte(line, ' '); i >= 0 {
+				path = line[:i]
+			}
+			n := g.ring.get(string(path), failed)
+			if n < 0 {
+				return errors.New("Could not write to any Graphite server in cluster\n")
+			}
+			groups[n] = append(groups[n], line)
+		}
+
+		lines = nil
+		for n, group := range groups {
+			if err := g.sendTo(n, group); err != nil {
+				log.Printf("E! Graphite: Error writing to %s, routing its metrics to the next server: %s", g.Servers[n], err)
+				failed[n] = true
+				lines = append(lines, group...)
+			}
+		}
+	}
+	return nil
+}
+
+// sendTo writes the lines to the server at the index, reconnecting once if
+// the write fails.
+func (g *Graphite) sendTo(n int, lines [][]byte) error {
+	var batch []byte
+	if g.Protocol == "pickle" {
+		batch = encodePickle(lines)
+	} else {
+		batch = bytes.Join(lines, nil)
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if g.conns[n] == nil {
+			if err = g.dial(n); err != nil {
+				continue
+			}
+		}
+
+		conn := g.conns[n]
+		if g.Timeout > 0 {
+			conn.SetWriteDeadline(time.Now().Add(time.Duration(g.Timeout) * time.Second))
+		}
+		checkEOF(conn)
+		if _, err = conn.Write(batch); err == nil {
+			return nil
+		}
+		conn.Close()
+		g.conns[n] = nil
+	}
+	return err
+}
+
 func init() {
 	outputs.Add("graphite", func() telegraf.Output {
 		return &Graphite{}
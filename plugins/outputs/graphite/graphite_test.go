@@ -2,8 +2,10 @@ package graphite
 
 import (
 	"bufio"
+	"fmt"
 	"net"
 	"net/textproto"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -158,6 +160,142 @@ func TestGraphiteOkWithTags(t *testing.T) {
 	g.Close()
 }
 
+func TestEncodePickle(t *testing.T) {
+	lines := [][]byte{
+		[]byte("a.b 3.5 1289430000\n"),
+		[]byte("invalid line\n"),
+	}
+	expected := "\x00\x00\x00\x1e" +
+		"\x80\x02](X\x03\x00\x00\x00a.bJ\xf0#\xdbLG@\f\x00\x00\x00\x00\x00\x00\x86\x86e."
+	assert.Equal(t, expected, string(encodePickle(lines)))
+	assert.Empty(t, encodePickle(lines[1:]))
+}
+
+func TestHashRing(t *testing.T) {
+	servers := []string{"a:2003", "b:2003", "c:2003"}
+	ring := newHashRing(servers)
+
+	counts := make([]int, len(servers))
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("host%d.cpu.usage", i)
+		n := ring.get(key, nil)
+		require.Equal(t, n, ring.get(key, nil))
+		counts[n]++
+
+		// Failed servers move their keys to another server only
+		other := ring.get(key, map[int]bool{(n + 1) % 3: true})
+		require.Equal(t, n, other)
+		next := ring.get(key, map[int]bool{n: true})
+		require.NotEqual(t, n, next)
+	}
+	for _, count := range counts {
+		assert.InDelta(t, 1000, count, 300)
+	}
+
+	assert.Equal(t, -1, ring.get("key", map[int]bool{0: true, 1: true, 2: true}))
+}
+
+func TestGraphiteConsistentHash(t *testing.T) {
+	servers := make([]string, 2)
+	received := make([]chan string, 2)
+	for i := range servers {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		servers[i] = listener.Addr().String()
+		received[i] = TCPServerLines(listener)
+	}
+
+	g := Graphite{
+		Servers:  servers,
+		Template: "measurement.field",
+		Routing:  "consistent_hash",
+	}
+	require.NoError(t, g.Connect())
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		m, _ := metric.New(
+			fmt.Sprintf("measurement%d", i),
+			map[string]string{},
+			map[string]interface{}{"value": float64(i)},
+			time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
+		)
+		metrics = append(metrics, m)
+	}
+	require.NoError(t, g.Write(metrics))
+	g.Close()
+
+	for i := range servers {
+		for line := range received[i] {
+			path := strings.Fields(line)[0]
+			assert.Equal(t, i, g.ring.get(path, nil), path)
+			metrics = metrics[1:]
+		}
+	}
+	assert.Empty(t, metrics)
+}
+
+func TestGraphiteConsistentHashFailover(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	received := TCPServerLines(listener)
+
+	// The first server is not listening
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed.Close()
+
+	g := Graphite{
+		Servers:  []string{closed.Addr().String(), listener.Addr().String()},
+		Template: "measurement.field",
+		Routing:  "consistent_hash",
+		Protocol: "pickle",
+	}
+	require.NoError(t, g.Connect())
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		m, _ := metric.New(
+			fmt.Sprintf("measurement%d", i),
+			map[string]string{},
+			map[string]interface{}{"value": float64(i)},
+			time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
+		)
+		metrics = append(metrics, m)
+	}
+	require.NoError(t, g.Write(metrics))
+	g.Close()
+
+	// The pickle messages are read as a single string
+	var data string
+	for line := range received {
+		data += line
+	}
+	for i := 0; i < 10; i++ {
+		assert.Contains(t, data, fmt.Sprintf("measurement%d", i))
+	}
+}
+
+// TCPServerLines returns the lines received by the listener on the first
+// connection, closing the channel when the connection is closed.
+func TCPServerLines(listener net.Listener) chan string {
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
 func TCPServer1(t *testing.T, wg *sync.WaitGroup) {
 	tcpServer, _ := net.Listen("tcp", "127.0.0.1:2003")
 	go func() {
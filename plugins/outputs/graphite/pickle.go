@@ -0,0 +1,112 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// pickleBatchSize is the maximum number of datapoints of a pickle message,
+// carbon rejects messages larger than 1MB.
+const pickleBatchSize = 500
+
+// Pickle protocol 2 opcodes
+const (
+	pickleProto      = 0x80
+	pickleEmptyList  = ']'
+	pickleMark       = '('
+	pickleAppends    = 'e'
+	pickleBinUnicode = 'X'
+	pickleBinInt     = 'J'
+	pickleLong1      = 0x8a
+	pickleBinFloat   = 'G'
+	pickleTuple2     = 0x86
+	pickleStop       = '.'
+)
+
+// encodePickle encodes the lines of the plaintext protocol as messages of
+// the pickle protocol, each a length prefixed pickled list of
+// (path, (timestamp, value)) tuples.  Invalid lines are skipped.
+func encodePickle(lines [][]byte) []byte {
+	var out bytes.Buffer
+	var msg bytes.Buffer
+	count := 0
+
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		msg.WriteByte(pickleAppends)
+		msg.WriteByte(pickleStop)
+
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(msg.Len()))
+		out.Write(size[:])
+		out.Write(msg.Bytes())
+		count = 0
+	}
+
+	for _, line := range lines {
+		fields := bytes.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		value, err := strconv.ParseFloat(string(fields[1]), 64)
+		if err != nil {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(string(fields[2]), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if count == 0 {
+			msg.Reset()
+			msg.Write([]byte{pickleProto, 2, pickleEmptyList, pickleMark})
+		}
+		writePickleString(&msg, fields[0])
+		writePickleInt(&msg, timestamp)
+		writePickleFloat(&msg, value)
+		msg.WriteByte(pickleTuple2)
+		msg.WriteByte(pickleTuple2)
+
+		count++
+		if count == pickleBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	return out.Bytes()
+}
+
+func writePickleString(buf *bytes.Buffer, s []byte) {
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(s)))
+	buf.WriteByte(pickleBinUnicode)
+	buf.Write(size[:])
+	buf.Write(s)
+}
+
+func writePickleInt(buf *bytes.Buffer, v int64) {
+	if v >= math.MinInt32 && v <= math.MaxInt32 {
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], uint32(int32(v)))
+		buf.WriteByte(pickleBinInt)
+		buf.Write(b[:])
+		return
+	}
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(v))
+	buf.Write([]byte{pickleLong1, 8})
+	buf.Write(b[:])
+}
+
+func writePickleFloat(buf *bytes.Buffer, v float64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+	buf.WriteByte(pickleBinFloat)
+	buf.Write(b[:])
+}
@@ -0,0 +1,65 @@
+package graphite
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// ringReplicas is the number of positions of each server on the ring.
+const ringReplicas = 100
+
+type ringPosition struct {
+	hash   uint32
+	server int
+}
+
+// hashRing is a consistent hash ring of the servers, so the metrics with the
+// same path are always written to the same server, and adding or removing a
+// server only moves the metrics of that server.
+type hashRing struct {
+	positions []ringPosition
+	servers   int
+}
+
+func newHashRing(servers []string) *hashRing {
+	r := &hashRing{servers: len(servers)}
+	for i, server := range servers {
+		for replica := 0; replica < ringReplicas; replica++ {
+			r.positions = append(r.positions, ringPosition{
+				hash:   ringHash(server + ":" + strconv.Itoa(replica)),
+				server: i,
+			})
+		}
+	}
+	sort.Slice(r.positions, func(i, j int) bool {
+		return r.positions[i].hash < r.positions[j].hash
+	})
+	return r
+}
+
+// get returns the index of the server of the key, the first server following
+// the key on the ring which is not excluded, or -1 if all are excluded.
+func (r *hashRing) get(key string, exclude map[int]bool) int {
+	if len(exclude) >= r.servers {
+		return -1
+	}
+
+	h := ringHash(key)
+	start := sort.Search(len(r.positions), func(i int) bool {
+		return r.positions[i].hash >= h
+	})
+	for i := 0; i < len(r.positions); i++ {
+		p := r.positions[(start+i)%len(r.positions)]
+		if !exclude[p.server] {
+			return p.server
+		}
+	}
+	return -1
+}
+
+func ringHash(key string) uint32 {
+	sum := md5.Sum([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
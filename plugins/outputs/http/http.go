@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -56,10 +55,14 @@ var sampleConfig = `
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
   # data_format = "influx"
 
-  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## HTTP Content-Encoding for write request body, can be set to "gzip",
+  ## "zstd" or "snappy" to compress body or "identity" to apply no encoding.
   # content_encoding = "identity"
 
+  ## Compression level of the "gzip" (1 to 9) and "zstd" (1 to 22) encodings,
+  ## 0 uses the default level of the encoding.
+  # compression_level = 0
+
   ## Additional HTTP headers
   # [outputs.http.headers]
   #   # Should be set manually to "application/json" for json data_format
@@ -83,11 +86,13 @@ type HTTP struct {
 	ClientSecret    string            `toml:"client_secret"`
 	TokenURL        string            `toml:"token_url"`
 	Scopes          []string          `toml:"scopes"`
-	ContentEncoding string            `toml:"content_encoding"`
+	ContentEncoding  string            `toml:"content_encoding"`
+	CompressionLevel int               `toml:"compression_level"`
 	tls.ClientConfig
 
 	client     *http.Client
 	serializer serializers.Serializer
+	encoder    internal.ContentEncoder
 }
 
 func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
@@ -135,6 +140,13 @@ func (h *HTTP) Connect() error {
 		h.Timeout.Duration = defaultClientTimeout
 	}
 
+	encoder, err := internal.NewContentEncoder(h.ContentEncoding,
+		internal.WithCompressionLevel(h.CompressionLevel))
+	if err != nil {
+		return err
+	}
+	h.encoder = internal.NewStatsEncoder(encoder, "http", map[string]string{"url": h.URL})
+
 	ctx := context.Background()
 	client, err := h.createClient(ctx)
 	if err != nil {
@@ -172,19 +184,12 @@ func (h *HTTP) Write(metrics []telegraf.Metric) error {
 }
 
 func (h *HTTP) write(reqBody []byte) error {
-	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
-
-	var err error
-	if h.ContentEncoding == "gzip" {
-		rc, err := internal.CompressWithGzip(reqBodyBuffer)
-		if err != nil {
-			return err
-		}
-		defer rc.Close()
-		reqBodyBuffer = rc
+	reqBody, err := h.encoder.Encode(reqBody)
+	if err != nil {
+		return err
 	}
 
-	req, err := http.NewRequest(h.Method, h.URL, reqBodyBuffer)
+	req, err := http.NewRequest(h.Method, h.URL, bytes.NewReader(reqBody))
 	if err != nil {
 		return err
 	}
@@ -195,8 +200,8 @@ func (h *HTTP) write(reqBody []byte) error {
 
 	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
 	req.Header.Set("Content-Type", defaultContentType)
-	if h.ContentEncoding == "gzip" {
-		req.Header.Set("Content-Encoding", "gzip")
+	if h.ContentEncoding != "" && h.ContentEncoding != "identity" {
+		req.Header.Set("Content-Encoding", h.ContentEncoding)
 	}
 	for k, v := range h.Headers {
 		if strings.ToLower(k) == "host" {
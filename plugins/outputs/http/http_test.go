@@ -1,7 +1,6 @@
 package http
 
 import (
-	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -258,6 +257,31 @@ func TestContentEncodingGzip(t *testing.T) {
 			},
 			expected: "gzip",
 		},
+		{
+			name: "gzip with compression level",
+			plugin: &HTTP{
+				URL:              u.String(),
+				ContentEncoding:  "gzip",
+				CompressionLevel: 9,
+			},
+			expected: "gzip",
+		},
+		{
+			name: "zstd",
+			plugin: &HTTP{
+				URL:             u.String(),
+				ContentEncoding: "zstd",
+			},
+			expected: "zstd",
+		},
+		{
+			name: "snappy",
+			plugin: &HTTP{
+				URL:             u.String(),
+				ContentEncoding: "snappy",
+			},
+			expected: "snappy",
+		},
 	}
 
 	for _, tt := range tests {
@@ -265,14 +289,12 @@ func TestContentEncodingGzip(t *testing.T) {
 			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				require.Equal(t, tt.expected, r.Header.Get("Content-Encoding"))
 
-				body := r.Body
-				var err error
-				if r.Header.Get("Content-Encoding") == "gzip" {
-					body, err = gzip.NewReader(r.Body)
-					require.NoError(t, err)
-				}
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
 
-				payload, err := ioutil.ReadAll(body)
+				decoder, err := internal.NewContentDecoder(r.Header.Get("Content-Encoding"))
+				require.NoError(t, err)
+				payload, err := decoder.Decode(body)
 				require.NoError(t, err)
 				require.Contains(t, string(payload), "cpu value=42")
 
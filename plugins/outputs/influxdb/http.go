@@ -92,6 +92,7 @@ type HTTPConfig struct {
 	Proxy                *url.URL
 	Headers              map[string]string
 	ContentEncoding      string
+	CompressionLevel     int
 	Database             string
 	DatabaseTag          string
 	ExcludeDatabaseTag   bool
@@ -125,6 +126,10 @@ func NewHTTPClient(config HTTPConfig) (*httpClient, error) {
 		config.Timeout = defaultRequestTimeout
 	}
 
+	if config.CompressionLevel < 0 || config.CompressionLevel > 9 {
+		return nil, fmt.Errorf("invalid gzip compression level %d", config.CompressionLevel)
+	}
+
 	userAgent := config.UserAgent
 	if userAgent == "" {
 		userAgent = "Telegraf/" + internal.Version()
@@ -414,7 +419,8 @@ func (c *httpClient) requestBodyReader(metrics []telegraf.Metric) (io.ReadCloser
 	reader := influx.NewReader(metrics, c.config.Serializer)
 
 	if c.config.ContentEncoding == "gzip" {
-		rc, err := internal.CompressWithGzip(reader)
+		rc, err := internal.CompressWithGzip(reader,
+			internal.WithCompressionLevel(c.config.CompressionLevel))
 		if err != nil {
 			return nil, err
 		}
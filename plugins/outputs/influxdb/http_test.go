@@ -599,6 +599,16 @@ func TestHTTP_WriteContentEncodingGzip(t *testing.T) {
 	require.NoError(t, err)
 	err = client.Write(ctx, metrics)
 	require.NoError(t, err)
+
+	config.CompressionLevel = 9
+	client, err = influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+	err = client.Write(ctx, metrics)
+	require.NoError(t, err)
+
+	config.CompressionLevel = 10
+	_, err = influxdb.NewHTTPClient(config)
+	require.Error(t, err)
 }
 
 func TestHTTP_UnixSocket(t *testing.T) {
@@ -46,8 +46,10 @@ type InfluxDB struct {
 	HTTPProxy            string            `toml:"http_proxy"`
 	HTTPHeaders          map[string]string `toml:"http_headers"`
 	ContentEncoding      string            `toml:"content_encoding"`
+	CompressionLevel     int               `toml:"compression_level"`
 	SkipDatabaseCreation bool              `toml:"skip_database_creation"`
 	InfluxUintSupport    bool              `toml:"influx_uint_support"`
+	InfluxDialect        string            `toml:"influx_dialect"`
 	tls.ClientConfig
 
 	Precision string // precision deprecated in 1.0; value is ignored
@@ -124,16 +126,29 @@ var sampleConfig = `
   ## compress body or "identity" to apply no encoding.
   # content_encoding = "identity"
 
+  ## Compression level of the "gzip" encoding, from 1 (fastest) to 9 (best
+  ## compression), 0 uses the default level.
+  # compression_level = 0
+
   ## When true, Telegraf will output unsigned integers as unsigned values,
   ## i.e.: "42u".  You will need a version of InfluxDB supporting unsigned
   ## integer values.  Enabling this option will result in field type errors if
   ## existing data has been written.
   # influx_uint_support = false
+
+  ## Line protocol dialect of the database, one of "influxdb", "greptimedb",
+  ## "opengemini" or "tdengine", for the databases compatible with the
+  ## InfluxDB write API.
+  # influx_dialect = "influxdb"
 `
 
 func (i *InfluxDB) Connect() error {
 	ctx := context.Background()
 
+	if _, err := influx.GetDialect(i.InfluxDialect); err != nil {
+		return err
+	}
+
 	urls := make([]string, 0, len(i.URLs))
 	urls = append(urls, i.URLs...)
 	if i.URL != "" {
@@ -258,6 +273,7 @@ func (i *InfluxDB) httpClient(ctx context.Context, url *url.URL, proxy *url.URL)
 		Password:             i.Password,
 		Proxy:                proxy,
 		ContentEncoding:      i.ContentEncoding,
+		CompressionLevel:     i.CompressionLevel,
 		Headers:              i.HTTPHeaders,
 		Database:             i.Database,
 		DatabaseTag:          i.DatabaseTag,
@@ -290,6 +306,9 @@ func (i *InfluxDB) newSerializer() *influx.Serializer {
 	if i.InfluxUintSupport {
 		serializer.SetFieldTypeSupport(influx.UintSupport)
 	}
+	if dialect, err := influx.GetDialect(i.InfluxDialect); err == nil {
+		serializer.SetDialect(dialect)
+	}
 
 	return serializer
 }
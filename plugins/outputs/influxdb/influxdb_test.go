@@ -112,6 +112,33 @@ func TestConnectUDPConfig(t *testing.T) {
 	require.NotNil(t, actual.Serializer)
 }
 
+func TestConnectDialect(t *testing.T) {
+	var actual *influxdb.UDPConfig
+
+	output := influxdb.InfluxDB{
+		URLs:          []string{"udp://localhost:8089"},
+		InfluxDialect: "tdengine",
+
+		CreateUDPClientF: func(config *influxdb.UDPConfig) (influxdb.Client, error) {
+			actual = config
+			return &MockClient{}, nil
+		},
+	}
+	output.Log = testutil.Logger{}
+
+	err := output.Connect()
+	require.NoError(t, err)
+
+	m := testutil.MustMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": int64(42)}, time.Unix(0, 0))
+	octets, err := actual.Serializer.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=42i64 0\n", string(octets))
+
+	output.InfluxDialect = "unknown"
+	require.Error(t, output.Connect())
+}
+
 func TestConnectHTTPConfig(t *testing.T) {
 	var actual *influxdb.HTTPConfig
 
@@ -1,6 +1,7 @@
 package influxdb_v2
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -14,11 +15,14 @@ import (
 	"net/url"
 	"path"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 type APIError struct {
@@ -34,6 +38,11 @@ func (e APIError) Error() string {
 	return e.Title
 }
 
+type BucketNotFoundError struct {
+	APIError
+	Bucket string
+}
+
 const (
 	defaultRequestTimeout = time.Second * 5
 	defaultMaxWait        = 10 // seconds
@@ -43,15 +52,18 @@ const (
 type HTTPConfig struct {
 	URL              *url.URL
 	Token            string
+	TokenFile        string
 	Organization     string
 	Bucket           string
 	BucketTag        string
 	ExcludeBucketTag bool
+	BucketRetention  time.Duration
 	Timeout          time.Duration
 	Headers          map[string]string
 	Proxy            *url.URL
 	UserAgent        string
 	ContentEncoding  string
+	CompressionLevel int
 	TLSConfig        *tls.Config
 
 	Serializer *influx.Serializer
@@ -59,17 +71,25 @@ type HTTPConfig struct {
 
 type httpClient struct {
 	ContentEncoding  string
+	CompressionLevel int
 	Timeout          time.Duration
 	Headers          map[string]string
 	Organization     string
 	Bucket           string
 	BucketTag        string
 	ExcludeBucketTag bool
+	BucketRetention  time.Duration
 
 	client     *http.Client
 	serializer *influx.Serializer
 	url        *url.URL
 	retryTime  time.Time
+	tokenFile  *tokenFile
+
+	BytesUncompressed selfstat.Stat
+	BytesSent         selfstat.Stat
+	CompressionRatio  selfstat.Stat
+	RetryQueueDepth   selfstat.Stat
 }
 
 func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
@@ -82,6 +102,10 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 		timeout = defaultRequestTimeout
 	}
 
+	if config.CompressionLevel < 0 || config.CompressionLevel > 9 {
+		return nil, fmt.Errorf("invalid gzip compression level %d", config.CompressionLevel)
+	}
+
 	userAgent := config.UserAgent
 	if userAgent == "" {
 		userAgent = "Telegraf/" + internal.Version()
@@ -89,7 +113,9 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 
 	var headers = make(map[string]string, len(config.Headers)+2)
 	headers["User-Agent"] = userAgent
-	headers["Authorization"] = "Token " + config.Token
+	if config.TokenFile == "" {
+		headers["Authorization"] = "Token " + config.Token
+	}
 	for k, v := range config.Headers {
 		headers[k] = v
 	}
@@ -127,6 +153,10 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 		return nil, fmt.Errorf("unsupported scheme %q", config.URL.Scheme)
 	}
 
+	tags := map[string]string{
+		"url": config.URL.String(),
+	}
+
 	client := &httpClient{
 		serializer: serializer,
 		client: &http.Client{
@@ -135,12 +165,22 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 		},
 		url:              config.URL,
 		ContentEncoding:  config.ContentEncoding,
+		CompressionLevel: config.CompressionLevel,
 		Timeout:          timeout,
 		Headers:          headers,
 		Organization:     config.Organization,
 		Bucket:           config.Bucket,
 		BucketTag:        config.BucketTag,
 		ExcludeBucketTag: config.ExcludeBucketTag,
+		BucketRetention:  config.BucketRetention,
+
+		BytesUncompressed: selfstat.Register("influxdb_v2", "write_bytes_uncompressed", tags),
+		BytesSent:         selfstat.Register("influxdb_v2", "write_bytes_sent", tags),
+		CompressionRatio:  selfstat.Register("influxdb_v2", "compression_ratio_percent", tags),
+		RetryQueueDepth:   selfstat.Register("influxdb_v2", "retry_queue_depth", tags),
+	}
+	if config.TokenFile != "" {
+		client.tokenFile = newTokenFile(config.TokenFile)
 	}
 	return client, nil
 }
@@ -167,7 +207,20 @@ func (g genericRespError) Error() string {
 	return errString
 }
 
+// Write sends the metrics to InfluxDB.  The number of metrics of a batch
+// that failed to be written, and will be retried by the output, is reported
+// as retry queue depth.
 func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error {
+	err := c.write(ctx, metrics)
+	if err != nil {
+		c.RetryQueueDepth.Set(int64(len(metrics)))
+		return err
+	}
+	c.RetryQueueDepth.Set(0)
+	return nil
+}
+
+func (c *httpClient) write(ctx context.Context, metrics []telegraf.Metric) error {
 	if c.retryTime.After(time.Now()) {
 		return errors.New("Retry time has not elapsed")
 	}
@@ -215,7 +268,8 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 		return err
 	}
 
-	reader, err := c.requestBodyReader(metrics)
+	var uncompressed, sent int64
+	reader, err := c.requestBodyReader(metrics, &uncompressed, &sent)
 	if err != nil {
 		return err
 	}
@@ -232,6 +286,8 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 	}
 	defer resp.Body.Close()
 
+	c.recordBytes(atomic.LoadInt64(&uncompressed), atomic.LoadInt64(&sent))
+
 	if resp.StatusCode == http.StatusNoContent {
 		return nil
 	}
@@ -249,6 +305,17 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 		return nil
 	case http.StatusUnauthorized, http.StatusForbidden:
 		return fmt.Errorf("failed to write metric: %s", desc)
+	case http.StatusNotFound:
+		if strings.Contains(desc, "bucket") {
+			return &BucketNotFoundError{
+				APIError: APIError{
+					StatusCode:  resp.StatusCode,
+					Title:       resp.Status,
+					Description: desc,
+				},
+				Bucket: bucket,
+			}
+		}
 	case http.StatusTooManyRequests:
 		retryAfter := resp.Header.Get("Retry-After")
 		retry, err := strconv.Atoi(retryAfter)
@@ -286,6 +353,140 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 	}
 }
 
+// recordBytes updates the write size statistics; the compression ratio is
+// the size of the line protocol relative to the size of the request body.
+func (c *httpClient) recordBytes(uncompressed, sent int64) {
+	c.BytesUncompressed.Incr(uncompressed)
+	c.BytesSent.Incr(sent)
+	if sent > 0 {
+		c.CompressionRatio.Set(uncompressed * 100 / sent)
+	}
+}
+
+type bucketRetentionRule struct {
+	Type         string `json:"type"`
+	EverySeconds int64  `json:"everySeconds"`
+}
+
+type createBucketRequest struct {
+	Name           string                `json:"name"`
+	OrgID          string                `json:"orgID"`
+	RetentionRules []bucketRetentionRule `json:"retentionRules"`
+}
+
+type organizationsResponse struct {
+	Orgs []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"orgs"`
+}
+
+// CreateBucket attempts to create the bucket in the configured organization,
+// it is not an error if the bucket already exists.
+func (c *httpClient) CreateBucket(ctx context.Context, bucket string) error {
+	orgID, err := c.organizationID(ctx)
+	if err != nil {
+		return err
+	}
+
+	body := createBucketRequest{
+		Name:           bucket,
+		OrgID:          orgID,
+		RetentionRules: []bucketRetentionRule{},
+	}
+	if c.BucketRetention > 0 {
+		body.RetentionRules = append(body.RetentionRules, bucketRetentionRule{
+			Type:         "expire",
+			EverySeconds: int64(c.BucketRetention / time.Second),
+		})
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	loc, err := makeAPIURL(*c.url, "/api/v2/buckets", nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", loc, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := c.addHeaders(req); err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+		return nil
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		// The bucket has been created since the write failed.
+		return nil
+	}
+	return c.apiError(resp)
+}
+
+func (c *httpClient) organizationID(ctx context.Context) (string, error) {
+	params := url.Values{}
+	params.Set("org", c.Organization)
+	loc, err := makeAPIURL(*c.url, "/api/v2/orgs", params)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("GET", loc, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := c.addHeaders(req); err != nil {
+		return "", err
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.apiError(resp)
+	}
+
+	orgs := &organizationsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(orgs); err != nil {
+		return "", err
+	}
+	for _, org := range orgs.Orgs {
+		if org.Name == c.Organization {
+			return org.ID, nil
+		}
+	}
+	return "", fmt.Errorf("organization %q not found", c.Organization)
+}
+
+func (c *httpClient) apiError(resp *http.Response) error {
+	errResp := &genericRespError{}
+	desc := resp.Status
+	if err := json.NewDecoder(resp.Body).Decode(errResp); err == nil {
+		desc = errResp.Error()
+	}
+	return &APIError{
+		StatusCode:  resp.StatusCode,
+		Title:       resp.Status,
+		Description: desc,
+	}
+}
+
 func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request, error) {
 	var err error
 
@@ -295,7 +496,9 @@ func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request
 	}
 
 	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
-	c.addHeaders(req)
+	if err := c.addHeaders(req); err != nil {
+		return nil, err
+	}
 
 	if c.ContentEncoding == "gzip" {
 		req.Header.Set("Content-Encoding", "gzip")
@@ -305,26 +508,53 @@ func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request
 }
 
 // requestBodyReader warp io.Reader from influx.NewReader to io.ReadCloser, which is usefully to fast close the write
-// side of the connection in case of error
-func (c *httpClient) requestBodyReader(metrics []telegraf.Metric) (io.ReadCloser, error) {
-	reader := influx.NewReader(metrics, c.serializer)
+// side of the connection in case of error.  The number of bytes of line
+// protocol and of the request body read are counted in uncompressed and sent.
+func (c *httpClient) requestBodyReader(metrics []telegraf.Metric, uncompressed, sent *int64) (io.ReadCloser, error) {
+	reader := &countingReader{r: influx.NewReader(metrics, c.serializer), n: uncompressed}
 
 	if c.ContentEncoding == "gzip" {
-		rc, err := internal.CompressWithGzip(reader)
+		rc, err := internal.CompressWithGzip(reader,
+			internal.WithCompressionLevel(c.CompressionLevel))
 		if err != nil {
 			return nil, err
 		}
 
-		return rc, nil
+		return &countingReadCloser{countingReader{r: rc, n: sent}, rc}, nil
 	}
 
-	return ioutil.NopCloser(reader), nil
+	return &countingReadCloser{countingReader{r: reader, n: sent}, ioutil.NopCloser(nil)}, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
 }
 
-func (c *httpClient) addHeaders(req *http.Request) {
+type countingReadCloser struct {
+	countingReader
+	io.Closer
+}
+
+func (c *httpClient) addHeaders(req *http.Request) error {
 	for header, value := range c.Headers {
 		req.Header.Set(header, value)
 	}
+
+	if c.tokenFile != nil {
+		token, err := c.tokenFile.Token()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	return nil
 }
 
 func makeWriteURL(loc url.URL, org, bucket string) (string, error) {
@@ -332,13 +562,17 @@ func makeWriteURL(loc url.URL, org, bucket string) (string, error) {
 	params.Set("bucket", bucket)
 	params.Set("org", org)
 
+	return makeAPIURL(loc, "/api/v2/write", params)
+}
+
+func makeAPIURL(loc url.URL, endpoint string, params url.Values) (string, error) {
 	switch loc.Scheme {
 	case "unix":
 		loc.Scheme = "http"
 		loc.Host = "127.0.0.1"
-		loc.Path = "/api/v2/write"
+		loc.Path = endpoint
 	case "http", "https":
-		loc.Path = path.Join(loc.Path, "/api/v2/write")
+		loc.Path = path.Join(loc.Path, endpoint)
 	default:
 		return "", fmt.Errorf("unsupported scheme: %q", loc.Scheme)
 	}
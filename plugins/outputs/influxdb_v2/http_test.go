@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -111,3 +113,172 @@ func TestWriteBucketTagWorksOnRetry(t *testing.T) {
 	err = client.Write(ctx, metrics)
 	require.NoError(t, err)
 }
+
+func TestCreateBucketOnBucketNotFound(t *testing.T) {
+	var created bool
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v2/write":
+				if !created {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"code":"not found","message":"bucket \"telegraf\" not found"}`))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			case "/api/v2/orgs":
+				require.Equal(t, "influx", r.URL.Query().Get("org"))
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"orgs":[{"id":"0123456789abcdef","name":"influx"}]}`))
+			case "/api/v2/buckets":
+				require.Equal(t, "POST", r.Method)
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.JSONEq(t,
+					`{"name":"telegraf","orgID":"0123456789abcdef","retentionRules":[{"type":"expire","everySeconds":3600}]}`,
+					string(body))
+				created = true
+				w.WriteHeader(http.StatusCreated)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer ts.Close()
+
+	config := &influxdb.HTTPConfig{
+		URL:             genURL(ts.URL),
+		Organization:    "influx",
+		Bucket:          "telegraf",
+		BucketRetention: time.Hour,
+	}
+
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": 42.0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+
+	ctx := context.Background()
+	err = client.Write(ctx, metrics)
+	require.IsType(t, &influxdb.BucketNotFoundError{}, err)
+	require.Equal(t, "telegraf", err.(*influxdb.BucketNotFoundError).Bucket)
+
+	err = client.CreateBucket(ctx, "telegraf")
+	require.NoError(t, err)
+	require.True(t, created)
+
+	err = client.Write(ctx, metrics)
+	require.NoError(t, err)
+}
+
+func TestTokenFileRotation(t *testing.T) {
+	var token string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "influxdb_v2")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("first\n"), 0600))
+
+	config := &influxdb.HTTPConfig{
+		URL:       genURL(ts.URL),
+		TokenFile: tokenFile,
+		Bucket:    "telegraf",
+	}
+
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": 42.0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+
+	ctx := context.Background()
+	require.NoError(t, client.Write(ctx, metrics))
+	require.Equal(t, "Token first", token)
+
+	// Rotate the token, setting the modification time explicitly as the
+	// resolution of the file system may be too low to notice the change.
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("second\n"), 0600))
+	mtime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(tokenFile, mtime, mtime))
+
+	require.NoError(t, client.Write(ctx, metrics))
+	require.Equal(t, "Token second", token)
+}
+
+func TestWriteCompressionStats(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer ts.Close()
+
+	config := &influxdb.HTTPConfig{
+		URL:             genURL(ts.URL),
+		Bucket:          "telegraf",
+		ContentEncoding: "gzip",
+	}
+
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 100; i++ {
+		metrics = append(metrics, testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"host": "localhost",
+			},
+			map[string]interface{}{
+				"value": 42.0,
+			},
+			time.Unix(0, 0),
+		))
+	}
+
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+
+	require.True(t, client.BytesSent.Get() > 0)
+	require.True(t, client.BytesUncompressed.Get() > client.BytesSent.Get())
+	require.True(t, client.CompressionRatio.Get() > 100)
+	require.Equal(t, int64(100), client.RetryQueueDepth.Get())
+}
+
+func TestInvalidCompressionLevel(t *testing.T) {
+	config := &influxdb.HTTPConfig{
+		URL:              genURL("http://localhost:8086"),
+		ContentEncoding:  "gzip",
+		CompressionLevel: 10,
+	}
+
+	_, err := influxdb.NewHTTPClient(config)
+	require.Error(t, err)
+}
@@ -33,6 +33,11 @@ var sampleConfig = `
   ## Token for authentication.
   token = ""
 
+  ## File containing the token for authentication, as alternative to 'token'.
+  ## The file is read again when it is modified, allowing a token rotated by a
+  ## secret store to be used without restarting Telegraf.
+  # token_file = "/run/secrets/influxdb-token"
+
   ## Organization is the name of the organization you wish to write to; must exist.
   organization = ""
 
@@ -46,6 +51,13 @@ var sampleConfig = `
   ## If true, the bucket tag will not be added to the metric.
   # exclude_bucket_tag = false
 
+  ## If true, buckets which do not exist are created when a write fails.  The
+  ## token must be allowed to read organizations and write buckets.
+  # bucket_creation = false
+
+  ## Retention period of created buckets, 0s retains data forever.
+  # bucket_retention = "0s"
+
   ## Timeout for HTTP messages.
   # timeout = "5s"
 
@@ -63,9 +75,18 @@ var sampleConfig = `
   ## compress body or "identity" to apply no encoding.
   # content_encoding = "gzip"
 
+  ## Compression level of the "gzip" encoding, from 1 (fastest) to 9 (best
+  ## compression), 0 uses the default level.
+  # compression_level = 0
+
   ## Enable or disable uint support for writing uints influxdb 2.0.
   # influx_uint_support = false
 
+  ## Line protocol dialect of the database, one of "influxdb", "greptimedb",
+  ## "opengemini" or "tdengine", for the databases compatible with the
+  ## InfluxDB 2.0 write API.
+  # influx_dialect = "influxdb"
+
   ## Optional TLS Config for use on HTTP connections.
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
@@ -76,6 +97,7 @@ var sampleConfig = `
 
 type Client interface {
 	Write(context.Context, []telegraf.Metric) error
+	CreateBucket(ctx context.Context, bucket string) error
 
 	URL() string // for logging
 	Close()
@@ -84,16 +106,21 @@ type Client interface {
 type InfluxDB struct {
 	URLs             []string          `toml:"urls"`
 	Token            string            `toml:"token"`
+	TokenFile        string            `toml:"token_file"`
 	Organization     string            `toml:"organization"`
 	Bucket           string            `toml:"bucket"`
 	BucketTag        string            `toml:"bucket_tag"`
 	ExcludeBucketTag bool              `toml:"exclude_bucket_tag"`
+	BucketCreation   bool              `toml:"bucket_creation"`
+	BucketRetention  internal.Duration `toml:"bucket_retention"`
 	Timeout          internal.Duration `toml:"timeout"`
 	HTTPHeaders      map[string]string `toml:"http_headers"`
 	HTTPProxy        string            `toml:"http_proxy"`
 	UserAgent        string            `toml:"user_agent"`
 	ContentEncoding  string            `toml:"content_encoding"`
+	CompressionLevel int               `toml:"compression_level"`
 	UintSupport      bool              `toml:"influx_uint_support"`
+	Dialect          string            `toml:"influx_dialect"`
 	tls.ClientConfig
 
 	clients []Client
@@ -106,6 +133,14 @@ func (i *InfluxDB) Connect() error {
 		i.URLs = append(i.URLs, defaultURL)
 	}
 
+	if _, err := influx.GetDialect(i.Dialect); err != nil {
+		return err
+	}
+
+	if i.Token != "" && i.TokenFile != "" {
+		return errors.New("only one of token and token_file can be set")
+	}
+
 	for _, u := range i.URLs {
 		parts, err := url.Parse(u)
 		if err != nil {
@@ -165,6 +200,17 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 			return nil
 		}
 
+		switch apiError := err.(type) {
+		case *BucketNotFoundError:
+			if i.BucketCreation {
+				err := client.CreateBucket(ctx, apiError.Bucket)
+				if err != nil {
+					log.Printf("E! [outputs.influxdb_v2] when writing to [%s]: bucket %q not found and failed to create: %v",
+						client.URL(), apiError.Bucket, err)
+				}
+			}
+		}
+
 		log.Printf("E! [outputs.influxdb_v2] when writing to [%s]: %v", client.URL(), err)
 	}
 
@@ -180,15 +226,18 @@ func (i *InfluxDB) getHTTPClient(ctx context.Context, url *url.URL, proxy *url.U
 	config := &HTTPConfig{
 		URL:              url,
 		Token:            i.Token,
+		TokenFile:        i.TokenFile,
 		Organization:     i.Organization,
 		Bucket:           i.Bucket,
 		BucketTag:        i.BucketTag,
 		ExcludeBucketTag: i.ExcludeBucketTag,
+		BucketRetention:  i.BucketRetention.Duration,
 		Timeout:          i.Timeout.Duration,
 		Headers:          i.HTTPHeaders,
 		Proxy:            proxy,
 		UserAgent:        i.UserAgent,
 		ContentEncoding:  i.ContentEncoding,
+		CompressionLevel: i.CompressionLevel,
 		TLSConfig:        tlsConfig,
 		Serializer:       i.newSerializer(),
 	}
@@ -206,6 +255,9 @@ func (i *InfluxDB) newSerializer() *influx.Serializer {
 	if i.UintSupport {
 		serializer.SetFieldTypeSupport(influx.UintSupport)
 	}
+	if dialect, err := influx.GetDialect(i.Dialect); err == nil {
+		serializer.SetDialect(dialect)
+	}
 
 	return serializer
 }
@@ -0,0 +1,65 @@
+package influxdb_v2
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenFile provides the authentication token stored in a file.  The file is
+// read again whenever its modification time changes, so that a token rotated
+// by a secret store, such as a mounted Kubernetes secret or a Vault agent
+// template, is used without restarting Telegraf.
+type tokenFile struct {
+	sync.Mutex
+
+	path    string
+	modTime time.Time
+	token   string
+}
+
+func newTokenFile(path string) *tokenFile {
+	return &tokenFile{path: path}
+}
+
+// Token returns the current token, the last successfully read token is
+// returned if the file cannot be read.
+func (t *tokenFile) Token() (string, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		if t.token != "" {
+			return t.token, nil
+		}
+		return "", fmt.Errorf("reading token file: %v", err)
+	}
+
+	if t.token != "" && info.ModTime().Equal(t.modTime) {
+		return t.token, nil
+	}
+
+	b, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		if t.token != "" {
+			return t.token, nil
+		}
+		return "", fmt.Errorf("reading token file: %v", err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		if t.token != "" {
+			return t.token, nil
+		}
+		return "", fmt.Errorf("token file %q is empty", t.path)
+	}
+
+	t.token = token
+	t.modTime = info.ModTime()
+	return t.token, nil
+}
@@ -0,0 +1,343 @@
+package iotdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// IoTDB data types
+const (
+	typeBoolean = "BOOLEAN"
+	typeInt64   = "INT64"
+	typeDouble  = "DOUBLE"
+	typeText    = "TEXT"
+)
+
+var sampleConfig = `
+  ## URL of the REST service of the IoTDB server.
+  url = "http://127.0.0.1:18080"
+
+  ## Credentials of the IoTDB user.
+  # username = "root"
+  # password = "root"
+
+  ## Timeout for HTTP requests.
+  # timeout = "5s"
+
+  ## Path prefix of the devices written, must start with "root".  The storage
+  ## group is created automatically by IoTDB unless disabled on the server.
+  # storage_group = "root.telegraf"
+
+  ## How tags are mapped to IoTDB, either:
+  ##   "device_id": tag values are appended as nodes to the device path,
+  ##                root.telegraf.<measurement>.<tag value>...
+  ##   "fields":    tags are written as TEXT measurements of the device
+  ##                root.telegraf.<measurement>
+  # convert_tags_to = "device_id"
+
+  ## Order of the tags in the device path when convert_tags_to is
+  ## "device_id".  Tags which are not listed are appended sorted by key.
+  # tag_order = []
+
+  ## How unsigned integers larger than the maximum INT64 are written, either
+  ## "int64_clip" to clip to the maximum INT64, "int64" to wrap around or
+  ## "text" to write all unsigned integers as TEXT.
+  # uint64_conversion = "int64_clip"
+
+  ## Precision of the timestamps written, IoTDB uses "millisecond" unless
+  ## the timestamp precision of the server has been changed.
+  ##   one of: "second", "millisecond", "microsecond", "nanosecond"
+  # timestamp_precision = "millisecond"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+var validNode = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// record holds the data of a single IoTDB row.
+type record struct {
+	deviceID     string
+	measurements []string
+	dataTypes    []string
+	values       []interface{}
+	timestamp    int64
+}
+
+// insertRecordsRequest is the body of the insertRecords REST request.
+type insertRecordsRequest struct {
+	Devices         []string        `json:"devices"`
+	MeasurementList [][]string      `json:"measurements_list"`
+	DataTypesList   [][]string      `json:"data_types_list"`
+	ValuesList      [][]interface{} `json:"values_list"`
+	Timestamps      []int64         `json:"timestamps"`
+	IsAligned       bool            `json:"is_aligned"`
+}
+
+type response struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type IoTDB struct {
+	URL                string            `toml:"url"`
+	Username           string            `toml:"username"`
+	Password           string            `toml:"password"`
+	Timeout            internal.Duration `toml:"timeout"`
+	StorageGroup       string            `toml:"storage_group"`
+	ConvertTagsTo      string            `toml:"convert_tags_to"`
+	TagOrder           []string          `toml:"tag_order"`
+	ConvertUint64To    string            `toml:"uint64_conversion"`
+	TimestampPrecision string            `toml:"timestamp_precision"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+	unit   time.Duration
+}
+
+func (s *IoTDB) Description() string {
+	return "Write metrics to Apache IoTDB"
+}
+
+func (s *IoTDB) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *IoTDB) Init() error {
+	if _, err := url.Parse(s.URL); err != nil {
+		return fmt.Errorf("error parsing url [%q]: %v", s.URL, err)
+	}
+
+	if s.StorageGroup != "root" && !strings.HasPrefix(s.StorageGroup, "root.") {
+		return fmt.Errorf("storage_group %q must start with \"root\"", s.StorageGroup)
+	}
+
+	switch s.ConvertTagsTo {
+	case "device_id", "fields":
+	default:
+		return fmt.Errorf("unknown convert_tags_to %q", s.ConvertTagsTo)
+	}
+
+	switch s.ConvertUint64To {
+	case "int64_clip", "int64", "text":
+	default:
+		return fmt.Errorf("unknown uint64_conversion %q", s.ConvertUint64To)
+	}
+
+	switch s.TimestampPrecision {
+	case "second":
+		s.unit = time.Second
+	case "millisecond":
+		s.unit = time.Millisecond
+	case "microsecond":
+		s.unit = time.Microsecond
+	case "nanosecond":
+		s.unit = time.Nanosecond
+	default:
+		return fmt.Errorf("unknown timestamp_precision %q", s.TimestampPrecision)
+	}
+	return nil
+}
+
+func (s *IoTDB) Connect() error {
+	tlsCfg, err := s.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	s.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: s.Timeout.Duration,
+	}
+	return nil
+}
+
+func (s *IoTDB) Close() error {
+	if s.client != nil {
+		internal.CloseIdleConnections(s.client)
+	}
+	return nil
+}
+
+func (s *IoTDB) Write(metrics []telegraf.Metric) error {
+	req := &insertRecordsRequest{}
+	for _, m := range metrics {
+		r := s.convert(m)
+		if len(r.measurements) == 0 {
+			continue
+		}
+
+		req.Devices = append(req.Devices, r.deviceID)
+		req.MeasurementList = append(req.MeasurementList, r.measurements)
+		req.DataTypesList = append(req.DataTypesList, r.dataTypes)
+		req.ValuesList = append(req.ValuesList, r.values)
+		req.Timestamps = append(req.Timestamps, r.timestamp)
+	}
+	if len(req.Devices) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	loc, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	loc.Path = path.Join(loc.Path, "/rest/v2/insertRecords")
+
+	httpReq, err := http.NewRequest("POST", loc.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", internal.ProductToken())
+	httpReq.SetBasicAuth(s.Username, s.Password)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	result := &response{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("when writing to [%s] received status code %d: %v", s.URL, resp.StatusCode, err)
+	}
+
+	// IoTDB reports success with the status code 200 of its own status
+	// codes.
+	if resp.StatusCode != http.StatusOK || result.Code != http.StatusOK {
+		return fmt.Errorf("when writing to [%s] received status code %d: %d: %s",
+			s.URL, resp.StatusCode, result.Code, result.Message)
+	}
+	return nil
+}
+
+// convert maps a metric to an IoTDB record; fields with an unsupported
+// type are skipped.
+func (s *IoTDB) convert(m telegraf.Metric) *record {
+	r := &record{
+		timestamp: m.Time().UnixNano() / int64(s.unit),
+	}
+
+	path := []string{s.StorageGroup, node(m.Name())}
+	switch s.ConvertTagsTo {
+	case "device_id":
+		for _, key := range s.orderTags(m) {
+			value, _ := m.GetTag(key)
+			path = append(path, node(value))
+		}
+	case "fields":
+		for _, tag := range m.TagList() {
+			r.add(node(tag.Key), typeText, tag.Value)
+		}
+	}
+	r.deviceID = strings.Join(path, ".")
+
+	for _, field := range m.FieldList() {
+		name := node(field.Key)
+		switch v := field.Value.(type) {
+		case bool:
+			r.add(name, typeBoolean, v)
+		case int64:
+			r.add(name, typeInt64, v)
+		case uint64:
+			switch s.ConvertUint64To {
+			case "int64_clip":
+				if v > math.MaxInt64 {
+					v = math.MaxInt64
+				}
+				r.add(name, typeInt64, int64(v))
+			case "int64":
+				r.add(name, typeInt64, int64(v))
+			case "text":
+				r.add(name, typeText, fmt.Sprintf("%d", v))
+			}
+		case float64:
+			r.add(name, typeDouble, v)
+		case string:
+			r.add(name, typeText, v)
+		default:
+			s.Log.Debugf("Skipping field %q of unsupported type %T", field.Key, field.Value)
+		}
+	}
+	return r
+}
+
+// orderTags returns the tag keys of the metric, ordered by tag_order first
+// and then sorted by key.
+func (s *IoTDB) orderTags(m telegraf.Metric) []string {
+	keys := make([]string, 0, len(m.TagList()))
+	used := make(map[string]bool, len(s.TagOrder))
+	for _, key := range s.TagOrder {
+		if m.HasTag(key) && !used[key] {
+			keys = append(keys, key)
+			used[key] = true
+		}
+	}
+
+	var rest []string
+	for _, tag := range m.TagList() {
+		if !used[tag.Key] {
+			rest = append(rest, tag.Key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+func (r *record) add(measurement string, dataType string, value interface{}) {
+	r.measurements = append(r.measurements, measurement)
+	r.dataTypes = append(r.dataTypes, dataType)
+	r.values = append(r.values, value)
+}
+
+// node quotes a path node which contains characters that are not allowed in
+// an unquoted IoTDB identifier.
+func node(s string) string {
+	if validNode.MatchString(s) {
+		return s
+	}
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
+
+func init() {
+	outputs.Add("iotdb", func() telegraf.Output {
+		return &IoTDB{
+			URL:                "http://127.0.0.1:18080",
+			Username:           "root",
+			Password:           "root",
+			Timeout:            internal.Duration{Duration: 5 * time.Second},
+			StorageGroup:       "root.telegraf",
+			ConvertTagsTo:      "device_id",
+			ConvertUint64To:    "int64_clip",
+			TimestampPrecision: "millisecond",
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package iotdb
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newIoTDB() *IoTDB {
+	return &IoTDB{
+		URL:                "http://127.0.0.1:18080",
+		Username:           "root",
+		Password:           "root",
+		Timeout:            internal.Duration{Duration: 5 * time.Second},
+		StorageGroup:       "root.telegraf",
+		ConvertTagsTo:      "device_id",
+		ConvertUint64To:    "int64_clip",
+		TimestampPrecision: "millisecond",
+		Log:                testutil.Logger{},
+	}
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*IoTDB)
+		err    bool
+	}{
+		{
+			name:   "defaults",
+			modify: func(*IoTDB) {},
+		},
+		{
+			name:   "storage group without root",
+			modify: func(s *IoTDB) { s.StorageGroup = "telegraf" },
+			err:    true,
+		},
+		{
+			name:   "unknown tag conversion",
+			modify: func(s *IoTDB) { s.ConvertTagsTo = "path" },
+			err:    true,
+		},
+		{
+			name:   "unknown uint64 conversion",
+			modify: func(s *IoTDB) { s.ConvertUint64To = "uint64" },
+			err:    true,
+		},
+		{
+			name:   "unknown precision",
+			modify: func(s *IoTDB) { s.TimestampPrecision = "minute" },
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newIoTDB()
+			tt.modify(s)
+			err := s.Init()
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestConvertTagsToDeviceID(t *testing.T) {
+	s := newIoTDB()
+	s.TagOrder = []string{"region"}
+	require.NoError(t, s.Init())
+
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"host":   "server-01",
+			"cpu":    "cpu0",
+			"region": "eu",
+		},
+		map[string]interface{}{
+			"usage_idle": 42.0,
+			"count":      int64(3),
+			"big":        uint64(math.MaxUint64),
+			"ok":         true,
+			"state":      "running",
+		},
+		time.Unix(1, 500000000),
+	)
+
+	r := s.convert(m)
+	require.Equal(t, "root.telegraf.cpu.eu.cpu0.`server-01`", r.deviceID)
+	require.Equal(t, int64(1500), r.timestamp)
+
+	type value struct {
+		dataType string
+		value    interface{}
+	}
+	expected := map[string]value{
+		"big":        {typeInt64, int64(math.MaxInt64)},
+		"count":      {typeInt64, int64(3)},
+		"ok":         {typeBoolean, true},
+		"state":      {typeText, "running"},
+		"usage_idle": {typeDouble, 42.0},
+	}
+	actual := make(map[string]value)
+	for i, measurement := range r.measurements {
+		actual[measurement] = value{r.dataTypes[i], r.values[i]}
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestConvertTagsToFields(t *testing.T) {
+	s := newIoTDB()
+	s.ConvertTagsTo = "fields"
+	s.ConvertUint64To = "text"
+	s.TimestampPrecision = "second"
+	require.NoError(t, s.Init())
+
+	m := testutil.MustMetric(
+		"disk",
+		map[string]string{
+			"path": "/var",
+		},
+		map[string]interface{}{
+			"free": uint64(10),
+		},
+		time.Unix(1, 500000000),
+	)
+
+	r := s.convert(m)
+	require.Equal(t, "root.telegraf.disk", r.deviceID)
+	require.Equal(t, int64(1), r.timestamp)
+	require.Equal(t, []string{"path", "free"}, r.measurements)
+	require.Equal(t, []string{typeText, typeText}, r.dataTypes)
+	require.Equal(t, []interface{}{"/var", "10"}, r.values)
+}
+
+func TestWrite(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/rest/v2/insertRecords", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "root", user)
+		require.Equal(t, "root", pass)
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"devices": ["root.telegraf.cpu.localhost"],
+			"measurements_list": [["usage_idle"]],
+			"data_types_list": [["DOUBLE"]],
+			"values_list": [[42.0]],
+			"timestamps": [1000],
+			"is_aligned": false
+		}`, string(body))
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"code":200,"message":"SUCCESS_STATUS"}`))
+	}))
+	defer ts.Close()
+
+	s := newIoTDB()
+	s.URL = ts.URL
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"host": "localhost",
+		},
+		map[string]interface{}{
+			"usage_idle": 42.0,
+		},
+		time.Unix(1, 0),
+	)
+	require.NoError(t, s.Write([]telegraf.Metric{m}))
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"code":507,"message":"storage group not set"}`))
+	}))
+	defer ts.Close()
+
+	s := newIoTDB()
+	s.URL = ts.URL
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	err := s.Write([]telegraf.Metric{testutil.TestMetric(1.0)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "storage group not set")
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 	"time"
 
@@ -24,6 +25,9 @@ var ValidTopicSuffixMethods = []string{
 
 var zeroTime = time.Unix(0, 0)
 
+// topicTagRe matches the {{tag_name}} placeholders of a topic template.
+var topicTagRe = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
 type (
 	Kafka struct {
 		Brokers          []string
@@ -32,10 +36,13 @@ type (
 		TopicSuffix      TopicSuffix `toml:"topic_suffix"`
 		RoutingTag       string      `toml:"routing_tag"`
 		RoutingKey       string      `toml:"routing_key"`
+		Partitioner      string      `toml:"partitioner"`
 		CompressionCodec int
 		RequiredAcks     int
 		MaxRetry         int
-		MaxMessageBytes  int `toml:"max_message_bytes"`
+		MaxMessageBytes  int      `toml:"max_message_bytes"`
+		IdempotentWrites bool     `toml:"idempotent_writes"`
+		HeaderTags       []string `toml:"header_tags"`
 
 		Version string `toml:"version"`
 
@@ -91,7 +98,10 @@ func (*DebugLogger) Println(v ...interface{}) {
 var sampleConfig = `
   ## URLs of kafka brokers
   brokers = ["localhost:9092"]
-  ## Kafka topic for producer messages
+  ## Kafka topic for producer messages.  The topic can contain tag names using
+  ## the notation {{tag_name}}, which are replaced by the value of the tag on
+  ## each metric.  Missing tags are replaced with an empty string.
+  ##   ex: topic = "telegraf-{{tenant}}"
   topic = "telegraf"
 
   ## Optional Client id
@@ -141,14 +151,30 @@ var sampleConfig = `
   ##       routing_key = "telegraf"
   # routing_key = ""
 
+  ## Partitioning strategy used to assign messages to partitions.
+  ##   hash        - FNV-1a hash of the routing key, random if there is no key
+  ##   murmur2     - murmur2 hash of the routing key, compatible with the
+  ##                 default partitioner of the Java client, random if there is
+  ##                 no key
+  ##   random      - random partition
+  ##   round_robin - partitions are chosen in turn
+  # partitioner = "hash"
+
   ## CompressionCodec represents the various compression codecs recognized by
   ## Kafka in messages.
   ##  0 : No compression
   ##  1 : Gzip compression
   ##  2 : Snappy compression
   ##  3 : LZ4 compression
+  ##  4 : ZSTD compression, requires at least version 2.1.0
   # compression_codec = 0
 
+  ## Enable the idempotent producer.  When enabled the broker deduplicates
+  ## messages that are resent on retry, so each message in a flush is written
+  ## exactly once to its partition.  Requires at least version 0.11.0.0,
+  ## required_acks = -1 and max_retry greater than 0.
+  # idempotent_writes = false
+
   ##  RequiredAcks is used in Produce Requests to tell the broker how many
   ##  replica acknowledgements it must see before responding
   ##   0 : the producer never waits for an acknowledgement from the broker.
@@ -173,6 +199,10 @@ var sampleConfig = `
   ## smaller than the broker's 'message.max.bytes'.
   # max_message_bytes = 1000000
 
+  ## Tags to copy into the Kafka record headers of each message, the tag key
+  ## is used as header key.  Requires at least version 0.11.0.0.
+  # header_tags = []
+
   ## Optional TLS Config
   # enable_tls = true
   # tls_ca = "/etc/telegraf/ca.pem"
@@ -204,14 +234,28 @@ func ValidateTopicSuffixMethod(method string) error {
 	return fmt.Errorf("Unknown topic suffix method provided: %s", method)
 }
 
+func (k *Kafka) topic(metric telegraf.Metric) string {
+	if !strings.Contains(k.Topic, "{{") {
+		return k.Topic
+	}
+
+	return topicTagRe.ReplaceAllStringFunc(k.Topic, func(match string) string {
+		key := topicTagRe.FindStringSubmatch(match)[1]
+		value, _ := metric.GetTag(key)
+		return value
+	})
+}
+
 func (k *Kafka) GetTopicName(metric telegraf.Metric) string {
+	topic := k.topic(metric)
+
 	var topicName string
 	switch k.TopicSuffix.Method {
 	case "measurement":
-		topicName = k.Topic + k.TopicSuffix.Separator + metric.Name()
+		topicName = topic + k.TopicSuffix.Separator + metric.Name()
 	case "tags":
 		var topicNameComponents []string
-		topicNameComponents = append(topicNameComponents, k.Topic)
+		topicNameComponents = append(topicNameComponents, topic)
 		for _, tag := range k.TopicSuffix.Keys {
 			tagValue := metric.Tags()[tag]
 			if tagValue != "" {
@@ -220,7 +264,7 @@ func (k *Kafka) GetTopicName(metric telegraf.Metric) string {
 		}
 		topicName = strings.Join(topicNameComponents, k.TopicSuffix.Separator)
 	default:
-		topicName = k.Topic
+		topicName = topic
 	}
 	return topicName
 }
@@ -234,6 +278,11 @@ func (k *Kafka) Connect() error {
 	if err != nil {
 		return err
 	}
+	partitioner, err := partitionerConstructor(k.Partitioner)
+	if err != nil {
+		return err
+	}
+
 	config := sarama.NewConfig()
 
 	if k.Version != "" {
@@ -254,11 +303,19 @@ func (k *Kafka) Connect() error {
 	config.Producer.Compression = sarama.CompressionCodec(k.CompressionCodec)
 	config.Producer.Retry.Max = k.MaxRetry
 	config.Producer.Return.Successes = true
+	config.Producer.Partitioner = partitioner
 
 	if k.MaxMessageBytes > 0 {
 		config.Producer.MaxMessageBytes = k.MaxMessageBytes
 	}
 
+	if k.IdempotentWrites {
+		// The idempotent producer can only guarantee ordering, and therefore
+		// deduplication, with a single in-flight request per broker.
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+	}
+
 	// Legacy support ssl config
 	if k.Certificate != "" {
 		k.TLSCert = k.Certificate
@@ -337,6 +394,25 @@ func (k *Kafka) routingKey(metric telegraf.Metric) (string, error) {
 	return k.RoutingKey, nil
 }
 
+func (k *Kafka) headers(metric telegraf.Metric) []sarama.RecordHeader {
+	if len(k.HeaderTags) == 0 {
+		return nil
+	}
+
+	headers := make([]sarama.RecordHeader, 0, len(k.HeaderTags))
+	for _, key := range k.HeaderTags {
+		value, ok := metric.GetTag(key)
+		if !ok {
+			continue
+		}
+		headers = append(headers, sarama.RecordHeader{
+			Key:   []byte(key),
+			Value: []byte(value),
+		})
+	}
+	return headers
+}
+
 func (k *Kafka) Write(metrics []telegraf.Metric) error {
 	msgs := make([]*sarama.ProducerMessage, 0, len(metrics))
 	for _, metric := range metrics {
@@ -364,6 +440,8 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 		if key != "" {
 			m.Key = sarama.StringEncoder(key)
 		}
+
+		m.Headers = k.headers(metric)
 		msgs = append(msgs, m)
 	}
 
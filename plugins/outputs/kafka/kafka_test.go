@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Shopify/sarama"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers"
@@ -86,6 +87,52 @@ func TestTopicSuffixes(t *testing.T) {
 	}
 }
 
+func TestTopicTemplate(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"tenant": "acme",
+		},
+		map[string]interface{}{
+			"value": 42.0,
+		},
+		time.Unix(0, 0),
+	)
+
+	tests := []struct {
+		name        string
+		topic       string
+		topicSuffix TopicSuffix
+		expected    string
+	}{
+		{
+			name:     "tag placeholder",
+			topic:    "telegraf-{{tenant}}",
+			expected: "telegraf-acme",
+		},
+		{
+			name:     "missing tag",
+			topic:    "telegraf-{{ region }}",
+			expected: "telegraf-",
+		},
+		{
+			name:        "with suffix",
+			topic:       "{{tenant}}",
+			topicSuffix: TopicSuffix{Method: "measurement", Separator: "."},
+			expected:    "acme.cpu",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &Kafka{
+				Topic:       tt.topic,
+				TopicSuffix: tt.topicSuffix,
+			}
+			require.Equal(t, tt.expected, k.GetTopicName(m))
+		})
+	}
+}
+
 func TestValidateTopicSuffixMethod(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -156,3 +203,27 @@ func TestRoutingKey(t *testing.T) {
 		})
 	}
 }
+
+func TestHeaders(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"host":   "example.org",
+			"tenant": "acme",
+		},
+		map[string]interface{}{
+			"value": 42.0,
+		},
+		time.Unix(0, 0),
+	)
+
+	k := &Kafka{
+		HeaderTags: []string{"tenant", "missing"},
+	}
+	require.Equal(t, []sarama.RecordHeader{
+		{Key: []byte("tenant"), Value: []byte("acme")},
+	}, k.headers(m))
+
+	k = &Kafka{}
+	require.Nil(t, k.headers(m))
+}
@@ -0,0 +1,105 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+var ValidPartitioners = []string{
+	"",
+	"hash",
+	"murmur2",
+	"random",
+	"round_robin",
+}
+
+// partitionerConstructor returns the sarama partitioner for the named
+// partitioning strategy.
+func partitionerConstructor(name string) (sarama.PartitionerConstructor, error) {
+	switch name {
+	case "", "hash":
+		return sarama.NewHashPartitioner, nil
+	case "murmur2":
+		return newMurmur2Partitioner, nil
+	case "random":
+		return sarama.NewRandomPartitioner, nil
+	case "round_robin":
+		return sarama.NewRoundRobinPartitioner, nil
+	default:
+		return nil, fmt.Errorf("Unknown partitioner provided: %s", name)
+	}
+}
+
+// murmur2Partitioner selects the partition using the same murmur2 hash of
+// the message key as the default partitioner of the Java Kafka client, so
+// that messages with a given key land in the same partition regardless of
+// which client produced them.  Messages without a key are assigned a random
+// partition.
+type murmur2Partitioner struct {
+	random sarama.Partitioner
+}
+
+func newMurmur2Partitioner(topic string) sarama.Partitioner {
+	return &murmur2Partitioner{
+		random: sarama.NewRandomPartitioner(topic),
+	}
+}
+
+func (p *murmur2Partitioner) Partition(message *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if message.Key == nil {
+		return p.random.Partition(message, numPartitions)
+	}
+
+	key, err := message.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+
+	// Matches org.apache.kafka.common.utils.Utils.toPositive
+	hash := int32(murmur2(key) & 0x7fffffff)
+	return hash % numPartitions, nil
+}
+
+func (p *murmur2Partitioner) RequiresConsistency() bool {
+	return true
+}
+
+// murmur2 is a port of the murmur2 hash as implemented in
+// org.apache.kafka.common.utils.Utils.
+func murmur2(data []byte) uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
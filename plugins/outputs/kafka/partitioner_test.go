@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMurmur2(t *testing.T) {
+	// Expected values from the Java client's UtilsTest.
+	tests := []struct {
+		key      string
+		expected int32
+	}{
+		{"21", -973932308},
+		{"foobar", -790332482},
+		{"a-little-bit-long-string", -985981536},
+		{"a-little-bit-longer-string", -1486304829},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+		{"abc", 479470107},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			require.Equal(t, tt.expected, int32(murmur2([]byte(tt.key))))
+		})
+	}
+}
+
+func TestMurmur2Partitioner(t *testing.T) {
+	p := newMurmur2Partitioner("telegraf")
+	require.True(t, p.RequiresConsistency())
+
+	msg := &sarama.ProducerMessage{Key: sarama.StringEncoder("foobar")}
+	first, err := p.Partition(msg, 12)
+	require.NoError(t, err)
+	require.True(t, first >= 0 && first < 12)
+
+	for i := 0; i < 10; i++ {
+		partition, err := p.Partition(msg, 12)
+		require.NoError(t, err)
+		require.Equal(t, first, partition)
+	}
+
+	partition, err := p.Partition(&sarama.ProducerMessage{}, 12)
+	require.NoError(t, err)
+	require.True(t, partition >= 0 && partition < 12)
+}
+
+func TestPartitionerConstructor(t *testing.T) {
+	for _, name := range ValidPartitioners {
+		_, err := partitionerConstructor(name)
+		require.NoError(t, err)
+	}
+
+	_, err := partitionerConstructor("invalid")
+	require.Error(t, err)
+}
@@ -0,0 +1,298 @@
+package snowflake
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	_ "github.com/snowflakedb/gosnowflake" // register the snowflake driver
+)
+
+const sampleConfig = `
+  ## Data source name of the Snowflake account, the options are described in
+  ## https://godoc.org/github.com/snowflakedb/gosnowflake.
+  dsn = "user:password@account/database/schema?warehouse=wh&role=role"
+
+  ## Unencrypted PKCS#8 private key in PEM format for key pair
+  ## authentication, the dsn does not need a password then.
+  # private_key = "/etc/telegraf/snowflake_key.p8"
+
+  ## Name of the table of each metric, {MEASUREMENT} is replaced by the
+  ## measurement name.  The tables must exist, their columns are matched
+  ## case-insensitively with the timestamp column, the tags and the fields.
+  # table_name = "{MEASUREMENT}"
+
+  ## Name of the column containing the time of the metrics.
+  # timestamp_column = "timestamp"
+
+  ## Stage the files are uploaded to, optionally with a path, for example
+  ## "my_stage/telegraf".  Defaults to the stage of the table.
+  # stage = ""
+
+  ## Maximum uncompressed size of the uploaded files, the metrics of a table
+  ## are split in multiple files loaded with a single COPY INTO statement.
+  # file_size_limit = "100MB"
+
+  ## Timeout for uploading and loading the metrics of a table.
+  # timeout = "5m"
+`
+
+// driverName is the name of the registered database driver.
+var driverName = "snowflake"
+
+// Snowflake is an output plugin bulk loading metrics into Snowflake.
+type Snowflake struct {
+	DSN             string            `toml:"dsn"`
+	PrivateKey      string            `toml:"private_key"`
+	TableName       string            `toml:"table_name"`
+	TimestampColumn string            `toml:"timestamp_column"`
+	Stage           string            `toml:"stage"`
+	FileSizeLimit   internal.Size     `toml:"file_size_limit"`
+	Timeout         internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	dsn string
+	db  *sql.DB
+}
+
+func (s *Snowflake) Description() string {
+	return "Bulk load metrics into Snowflake tables using stages"
+}
+
+func (s *Snowflake) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Snowflake) Init() error {
+	if s.DSN == "" {
+		return errors.New("dsn must be set")
+	}
+	s.dsn = s.DSN
+
+	if s.PrivateKey != "" {
+		key, err := readPrivateKey(s.PrivateKey)
+		if err != nil {
+			return err
+		}
+
+		separator := "?"
+		if strings.Contains(s.dsn, "?") {
+			separator = "&"
+		}
+		s.dsn += separator + "authenticator=SNOWFLAKE_JWT&privateKey=" + key
+	}
+	return nil
+}
+
+// readPrivateKey returns the private key encoded as expected by the driver.
+func readPrivateKey(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("no PEM data found in %q", path)
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("parsing private key %q failed: %v", path, err)
+	}
+	return base64.URLEncoding.EncodeToString(block.Bytes), nil
+}
+
+func (s *Snowflake) Connect() error {
+	db, err := sql.Open(driverName, s.dsn)
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+func (s *Snowflake) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *Snowflake) Write(metrics []telegraf.Metric) error {
+	var order []string
+	tables := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		table := strings.Replace(s.TableName, "{MEASUREMENT}", m.Name(), -1)
+		if _, ok := tables[table]; !ok {
+			order = append(order, table)
+		}
+		tables[table] = append(tables[table], m)
+	}
+
+	for _, table := range order {
+		if err := s.load(table, tables[table]); err != nil {
+			return fmt.Errorf("loading into table %q failed: %v", table, err)
+		}
+	}
+	return nil
+}
+
+// load uploads the metrics to the stage and copies them into the table.
+func (s *Snowflake) load(table string, metrics []telegraf.Metric) error {
+	dir, err := ioutil.TempDir("", "telegraf-snowflake")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := s.writeFiles(dir, table, metrics)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout.Duration)
+	defer cancel()
+
+	stage := "@%" + table
+	if s.Stage != "" {
+		stage = "@" + strings.TrimSuffix(s.Stage, "/")
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		query := fmt.Sprintf("PUT 'file://%s' %s AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP", file, stage)
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+		names = append(names, "'"+filepath.Base(file)+"'")
+	}
+
+	query := fmt.Sprintf("COPY INTO %s FROM %s FILES = (%s) FILE_FORMAT = (TYPE = JSON)"+
+		" MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE PURGE = TRUE",
+		table, stage, strings.Join(names, ", "))
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		// Remove the uploaded files, they are uploaded again with the retry
+		for _, name := range names {
+			query := fmt.Sprintf("REMOVE %s/%s", stage, strings.Trim(name, "'"))
+			if _, err := s.db.ExecContext(ctx, query); err != nil {
+				s.Log.Warnf("Removing staged file %s failed: %v", name, err)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// writeFiles writes the metrics as gzip compressed JSON lines to files not
+// exceeding the size limit.
+func (s *Snowflake) writeFiles(dir, table string, metrics []telegraf.Metric) ([]string, error) {
+	var files []string
+	var file *os.File
+	var writer *gzip.Writer
+	var size int64
+
+	closeFile := func() error {
+		if file == nil {
+			return nil
+		}
+		err := writer.Close()
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		file = nil
+		return err
+	}
+
+	prefix := fmt.Sprintf("telegraf_%s_%d", sanitize(table), time.Now().UnixNano())
+	for _, m := range metrics {
+		line, err := json.Marshal(s.record(m))
+		if err != nil {
+			s.Log.Errorf("Could not serialize metric: %v", err)
+			continue
+		}
+		line = append(line, '\n')
+
+		if file != nil && size+int64(len(line)) > s.FileSizeLimit.Size {
+			if err := closeFile(); err != nil {
+				return nil, err
+			}
+		}
+		if file == nil {
+			name := filepath.Join(dir, fmt.Sprintf("%s_%d.json.gz", prefix, len(files)))
+			file, err = os.Create(name)
+			if err != nil {
+				return nil, err
+			}
+			writer = gzip.NewWriter(file)
+			files = append(files, name)
+			size = 0
+		}
+
+		if _, err := writer.Write(line); err != nil {
+			closeFile()
+			return nil, err
+		}
+		size += int64(len(line))
+	}
+
+	if err := closeFile(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// record returns the row of the metric, floats not representable in JSON
+// are dropped.
+func (s *Snowflake) record(m telegraf.Metric) map[string]interface{} {
+	record := make(map[string]interface{}, len(m.TagList())+len(m.FieldList())+1)
+	for _, tag := range m.TagList() {
+		record[tag.Key] = tag.Value
+	}
+	for _, field := range m.FieldList() {
+		if v, ok := field.Value.(float64); ok && (math.IsNaN(v) || math.IsInf(v, 0)) {
+			continue
+		}
+		record[field.Key] = field.Value
+	}
+	record[s.TimestampColumn] = m.Time().UTC().Format(time.RFC3339Nano)
+	return record
+}
+
+// sanitize returns the name with all characters not allowed in file names
+// of stages replaced.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func init() {
+	outputs.Add("snowflake", func() telegraf.Output {
+		return &Snowflake{
+			TableName:       "{MEASUREMENT}",
+			TimestampColumn: "timestamp",
+			FileSizeLimit:   internal.Size{Size: 100 * 1024 * 1024},
+			Timeout:         internal.Duration{Duration: 5 * time.Minute},
+		}
+	})
+}
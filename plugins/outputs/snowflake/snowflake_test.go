@@ -0,0 +1,216 @@
+package snowflake
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver records the statements and the content of the uploaded files.
+type fakeDriver struct {
+	statements []string
+	files      map[string]string
+	copyErr    error
+}
+
+var fake = &fakeDriver{}
+
+var putFile = regexp.MustCompile(`^PUT 'file://([^']*)'`)
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return d, nil
+}
+
+func (d *fakeDriver) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{driver: d, query: query}, nil
+}
+
+func (d *fakeDriver) Close() error              { return nil }
+func (d *fakeDriver) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	driver *fakeDriver
+	query  string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return 0 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.driver
+	if match := putFile.FindStringSubmatch(s.query); match != nil {
+		f, err := os.Open(match[1])
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		d.files[filepath.Base(match[1])] = string(content)
+		s.query = strings.Replace(s.query, match[1], filepath.Base(match[1]), 1)
+	}
+	d.statements = append(d.statements, s.query)
+
+	if strings.HasPrefix(s.query, "COPY INTO") && d.copyErr != nil {
+		return nil, d.copyErr
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func init() {
+	sql.Register("fake_snowflake", fake)
+	driverName = "fake_snowflake"
+}
+
+func newTestSnowflake() *Snowflake {
+	return &Snowflake{
+		DSN:             "user:password@account/db/schema",
+		TableName:       "{MEASUREMENT}",
+		TimestampColumn: "timestamp",
+		FileSizeLimit:   internal.Size{Size: 100 * 1024 * 1024},
+		Timeout:         internal.Duration{Duration: 5 * time.Second},
+		Log:             testutil.Logger{},
+	}
+}
+
+var testMetrics = []telegraf.Metric{
+	testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.5},
+		time.Unix(0, 0),
+	),
+	testutil.MustMetric(
+		"mem",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"used": int64(42)},
+		time.Unix(1, 0),
+	),
+	testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "b"},
+		map[string]interface{}{"usage": 2.5},
+		time.Unix(2, 0),
+	),
+}
+
+// normalize replaces the generated file names.
+func normalize(statements []string) []string {
+	name := regexp.MustCompile(`telegraf_(\w+)_\d+_(\d+)\.json\.gz`)
+	var result []string
+	for _, s := range statements {
+		result = append(result, name.ReplaceAllString(s, "telegraf_${1}_${2}.json.gz"))
+	}
+	return result
+}
+
+func TestWrite(t *testing.T) {
+	*fake = fakeDriver{files: make(map[string]string)}
+
+	plugin := newTestSnowflake()
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.NoError(t, plugin.Write(testMetrics))
+
+	require.Equal(t, []string{
+		"PUT 'file://telegraf_cpu_0.json.gz' @%cpu AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP",
+		"COPY INTO cpu FROM @%cpu FILES = ('telegraf_cpu_0.json.gz') FILE_FORMAT = (TYPE = JSON) MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE PURGE = TRUE",
+		"PUT 'file://telegraf_mem_0.json.gz' @%mem AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP",
+		"COPY INTO mem FROM @%mem FILES = ('telegraf_mem_0.json.gz') FILE_FORMAT = (TYPE = JSON) MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE PURGE = TRUE",
+	}, normalize(fake.statements))
+
+	var content []string
+	for name, c := range fake.files {
+		if strings.HasPrefix(name, "telegraf_cpu_") {
+			content = append(content, c)
+		}
+	}
+	require.Equal(t, []string{
+		`{"host":"a","timestamp":"1970-01-01T00:00:00Z","usage":1.5}` + "\n" +
+			`{"host":"b","timestamp":"1970-01-01T00:00:02Z","usage":2.5}` + "\n",
+	}, content)
+}
+
+func TestWriteSplitFiles(t *testing.T) {
+	*fake = fakeDriver{files: make(map[string]string)}
+
+	plugin := newTestSnowflake()
+	plugin.Stage = "my_stage/telegraf/"
+	plugin.FileSizeLimit.Size = 10
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.NoError(t, plugin.Write([]telegraf.Metric{testMetrics[0], testMetrics[2]}))
+
+	require.Equal(t, []string{
+		"PUT 'file://telegraf_cpu_0.json.gz' @my_stage/telegraf AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP",
+		"PUT 'file://telegraf_cpu_1.json.gz' @my_stage/telegraf AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP",
+		"COPY INTO cpu FROM @my_stage/telegraf FILES = ('telegraf_cpu_0.json.gz', 'telegraf_cpu_1.json.gz') FILE_FORMAT = (TYPE = JSON) MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE PURGE = TRUE",
+	}, normalize(fake.statements))
+}
+
+func TestWriteCopyError(t *testing.T) {
+	*fake = fakeDriver{files: make(map[string]string), copyErr: errors.New("copy failed")}
+
+	plugin := newTestSnowflake()
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.Error(t, plugin.Write(testMetrics[:1]))
+
+	require.Equal(t, []string{
+		"PUT 'file://telegraf_cpu_0.json.gz' @%cpu AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP",
+		"COPY INTO cpu FROM @%cpu FILES = ('telegraf_cpu_0.json.gz') FILE_FORMAT = (TYPE = JSON) MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE PURGE = TRUE",
+		"REMOVE @%cpu/telegraf_cpu_0.json.gz",
+	}, normalize(fake.statements))
+}
+
+func TestInitPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "snowflake")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "key.p8")
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	plugin := newTestSnowflake()
+	plugin.DSN = "user@account/db/schema?warehouse=wh"
+	plugin.PrivateKey = path
+	require.NoError(t, plugin.Init())
+	require.True(t, strings.HasPrefix(plugin.dsn, "user@account/db/schema?warehouse=wh&authenticator=SNOWFLAKE_JWT&privateKey="))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("invalid"), 0600))
+	require.Error(t, plugin.Init())
+}
@@ -0,0 +1,154 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConvertTypes are the SQL types of the columns.
+type ConvertTypes struct {
+	Integer   string `toml:"integer"`
+	Unsigned  string `toml:"unsigned"`
+	Real      string `toml:"real"`
+	Text      string `toml:"text"`
+	Bool      string `toml:"bool"`
+	Timestamp string `toml:"timestamp"`
+	Tag       string `toml:"tag"`
+}
+
+// dialect contains the differences of the supported databases.
+type dialect struct {
+	driverName     string
+	quoteOpen      string
+	quoteClose     string
+	placeholder    func(n int) string
+	types          ConvertTypes
+	tableTemplate  string
+	columnTemplate string
+
+	// upsert returns the clause appended to inserts to update the existing
+	// rows, dialects without upsert support leave it nil.
+	upsert func(d *dialect, keys, columns []string) string
+}
+
+func (d *dialect) quote(name string) string {
+	return d.quoteOpen + strings.Replace(name, d.quoteClose, d.quoteClose+d.quoteClose, -1) + d.quoteClose
+}
+
+func questionMark(int) string {
+	return "?"
+}
+
+var dialects = map[string]dialect{
+	"mysql": {
+		driverName:  "mysql",
+		quoteOpen:   "`",
+		quoteClose:  "`",
+		placeholder: questionMark,
+		types: ConvertTypes{
+			Integer:   "BIGINT",
+			Unsigned:  "BIGINT UNSIGNED",
+			Real:      "DOUBLE",
+			Text:      "TEXT",
+			Bool:      "BOOLEAN",
+			Timestamp: "DATETIME(6)",
+			Tag:       "VARCHAR(255)",
+		},
+		tableTemplate:  "CREATE TABLE {TABLE}({COLUMNS})",
+		columnTemplate: "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+		upsert: func(d *dialect, keys, columns []string) string {
+			var updates []string
+			for _, c := range columns {
+				updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", d.quote(c), d.quote(c)))
+			}
+			return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+		},
+	},
+	"postgres": {
+		driverName: "pgx",
+		quoteOpen:  `"`,
+		quoteClose: `"`,
+		placeholder: func(n int) string {
+			return fmt.Sprintf("$%d", n)
+		},
+		types: ConvertTypes{
+			Integer:   "BIGINT",
+			Unsigned:  "NUMERIC(20)",
+			Real:      "DOUBLE PRECISION",
+			Text:      "TEXT",
+			Bool:      "BOOLEAN",
+			Timestamp: "TIMESTAMP",
+			Tag:       "TEXT",
+		},
+		tableTemplate:  "CREATE TABLE {TABLE}({COLUMNS})",
+		columnTemplate: "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+		upsert: func(d *dialect, keys, columns []string) string {
+			var quoted, updates []string
+			for _, k := range keys {
+				quoted = append(quoted, d.quote(k))
+			}
+			for _, c := range columns {
+				updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", d.quote(c), d.quote(c)))
+			}
+			return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(quoted, ", "), strings.Join(updates, ", "))
+		},
+	},
+	"sqlserver": {
+		driverName: "sqlserver",
+		quoteOpen:  "[",
+		quoteClose: "]",
+		placeholder: func(n int) string {
+			return fmt.Sprintf("@p%d", n)
+		},
+		types: ConvertTypes{
+			Integer:   "BIGINT",
+			Unsigned:  "DECIMAL(20,0)",
+			Real:      "FLOAT",
+			Text:      "NVARCHAR(MAX)",
+			Bool:      "BIT",
+			Timestamp: "DATETIME2",
+			Tag:       "NVARCHAR(255)",
+		},
+		tableTemplate:  "CREATE TABLE {TABLE}({COLUMNS})",
+		columnTemplate: "ALTER TABLE {TABLE} ADD {COLUMN}",
+	},
+	"clickhouse": {
+		driverName:  "clickhouse",
+		quoteOpen:   "`",
+		quoteClose:  "`",
+		placeholder: questionMark,
+		types: ConvertTypes{
+			Integer:   "Nullable(Int64)",
+			Unsigned:  "Nullable(UInt64)",
+			Real:      "Nullable(Float64)",
+			Text:      "Nullable(String)",
+			Bool:      "Nullable(UInt8)",
+			Timestamp: "DateTime64(9)",
+			Tag:       "String",
+		},
+		tableTemplate:  "CREATE TABLE {TABLE}({COLUMNS}) ENGINE = MergeTree() ORDER BY ({KEY_COLUMNS})",
+		columnTemplate: "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+	},
+	"snowflake": {
+		driverName:  "snowflake",
+		quoteOpen:   `"`,
+		quoteClose:  `"`,
+		placeholder: questionMark,
+		types: ConvertTypes{
+			Integer:   "BIGINT",
+			Unsigned:  "NUMBER(20,0)",
+			Real:      "DOUBLE",
+			Text:      "TEXT",
+			Bool:      "BOOLEAN",
+			Timestamp: "TIMESTAMP",
+			Tag:       "TEXT",
+		},
+		tableTemplate:  "CREATE TABLE {TABLE}({COLUMNS})",
+		columnTemplate: "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+	},
+}
+
+func init() {
+	dialects["pgx"] = dialects["postgres"]
+	dialects["mssql"] = dialects["sqlserver"]
+}
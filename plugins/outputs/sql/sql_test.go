@@ -0,0 +1,204 @@
+package sql
+
+import (
+	dbsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB records the executed statements, tables exist if they have
+// columns.
+type fakeDB struct {
+	sync.Mutex
+	tables     map[string][]string
+	statements []string
+	args       [][]driver.Value
+}
+
+var db = &fakeDB{}
+
+func (d *fakeDB) reset(tables map[string][]string) {
+	d.Lock()
+	defer d.Unlock()
+	d.tables = tables
+	d.statements = nil
+	d.args = nil
+}
+
+func (d *fakeDB) Open(name string) (driver.Conn, error) {
+	return &fakeConn{db: d}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeConn) Commit() error             { return nil }
+func (c *fakeConn) Rollback() error           { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.Lock()
+	defer s.db.Unlock()
+	s.db.statements = append(s.db.statements, s.query)
+	s.db.args = append(s.db.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.Lock()
+	defer s.db.Unlock()
+	for table, columns := range s.db.tables {
+		if strings.HasPrefix(s.query, "SELECT * FROM "+table+" ") {
+			return &fakeRows{columns: columns}, nil
+		}
+	}
+	return nil, errors.New("table does not exist")
+}
+
+type fakeRows struct {
+	columns []string
+}
+
+func (r *fakeRows) Columns() []string              { return r.columns }
+func (r *fakeRows) Close() error                   { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+func init() {
+	dbsql.Register("fake", db)
+	d := dialects["postgres"]
+	d.driverName = "fake"
+	dialects["fake"] = d
+}
+
+func newTestSQL() *SQL {
+	return &SQL{
+		Driver:          "fake",
+		DSN:             "fake",
+		TableName:       "{MEASUREMENT}",
+		TimestampColumn: "timestamp",
+		Log:             testutil.Logger{},
+	}
+}
+
+var testMetrics = []telegraf.Metric{
+	testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.5, "count": int64(3)},
+		time.Unix(0, 0),
+	),
+	testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "b", "region": "east"},
+		map[string]interface{}{"usage": 2.5, "state": "ok"},
+		time.Unix(1, 0),
+	),
+}
+
+func TestWriteCreateTable(t *testing.T) {
+	db.reset(map[string][]string{})
+
+	plugin := newTestSQL()
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.NoError(t, plugin.Write(testMetrics))
+
+	insert := `INSERT INTO "cpu" ("timestamp", "host", "region", "count", "state", "usage") VALUES ($1, $2, $3, $4, $5, $6)`
+	require.Equal(t, []string{
+		`CREATE TABLE "cpu"("timestamp" TIMESTAMP, "host" TEXT, "region" TEXT, "count" BIGINT, "state" TEXT, "usage" DOUBLE PRECISION)`,
+		insert,
+		insert,
+	}, db.statements)
+	require.Equal(t, []driver.Value{time.Unix(0, 0), "a", "", int64(3), nil, 1.5}, db.args[1])
+	require.Equal(t, []driver.Value{time.Unix(1, 0), "b", "east", nil, "ok", 2.5}, db.args[2])
+}
+
+func TestWriteMissingColumns(t *testing.T) {
+	db.reset(map[string][]string{`"cpu"`: {"timestamp", "host", "usage"}})
+
+	plugin := newTestSQL()
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.NoError(t, plugin.Write(testMetrics[:1]))
+
+	require.Equal(t, []string{
+		`INSERT INTO "cpu" ("timestamp", "host", "usage") VALUES ($1, $2, $3)`,
+	}, db.statements)
+}
+
+func TestWriteAddColumns(t *testing.T) {
+	db.reset(map[string][]string{`"cpu"`: {"timestamp", "host", "usage"}})
+
+	plugin := newTestSQL()
+	plugin.AddColumns = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.NoError(t, plugin.Write(testMetrics[:1]))
+	require.NoError(t, plugin.Write(testMetrics[:1]))
+
+	insert := `INSERT INTO "cpu" ("timestamp", "host", "count", "usage") VALUES ($1, $2, $3, $4)`
+	require.Equal(t, []string{
+		`ALTER TABLE "cpu" ADD COLUMN "count" BIGINT`,
+		insert,
+		insert,
+	}, db.statements)
+}
+
+func TestWriteUpsert(t *testing.T) {
+	db.reset(map[string][]string{})
+
+	plugin := newTestSQL()
+	plugin.Upsert = true
+	plugin.TableName = "{MEASUREMENT}_{TAG:host}"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.NoError(t, plugin.Write(testMetrics[:1]))
+
+	require.Equal(t, []string{
+		`CREATE TABLE "cpu_a"("timestamp" TIMESTAMP, "host" TEXT, "count" BIGINT, "usage" DOUBLE PRECISION, PRIMARY KEY ("timestamp", "host"))`,
+		`INSERT INTO "cpu_a" ("timestamp", "host", "count", "usage") VALUES ($1, $2, $3, $4)` +
+			` ON CONFLICT ("timestamp", "host") DO UPDATE SET "count" = EXCLUDED."count", "usage" = EXCLUDED."usage"`,
+	}, db.statements)
+}
+
+func TestInit(t *testing.T) {
+	plugin := newTestSQL()
+	plugin.Driver = "sqlserver"
+	plugin.Upsert = true
+	require.Error(t, plugin.Init())
+
+	plugin = newTestSQL()
+	plugin.Driver = "mysql"
+	plugin.Convert.Real = "FLOAT"
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "FLOAT", plugin.Convert.Real)
+	require.Equal(t, "BIGINT", plugin.Convert.Integer)
+	require.Equal(t, "`a``b`", plugin.dialect.quote("a`b"))
+}
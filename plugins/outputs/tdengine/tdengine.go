@@ -0,0 +1,261 @@
+package tdengine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+const (
+	defaultURL           = "http://127.0.0.1:6041"
+	defaultClientTimeout = 5 * time.Second
+)
+
+var sampleConfig = `
+  ## URL of the taosAdapter REST service of TDengine.
+  url = "http://127.0.0.1:6041"
+
+  ## Database to write to.
+  database = "telegraf"
+
+  ## If true, no CREATE DATABASE statement is issued when the database does
+  ## not exist.
+  # skip_database_creation = false
+
+  ## Credentials of the TDengine user.
+  # username = "root"
+  # password = "taosdata"
+
+  ## Timeout for HTTP requests.
+  # timeout = "5s"
+
+  ## Metrics are written using the schemaless InfluxDB line protocol; the
+  ## measurement is used as super table, and a child table is created for
+  ## each tag set.  Tags whose values should instead be stored as columns of
+  ## the super table can be listed here.
+  # tags_as_fields = []
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type TDengine struct {
+	URL                  string            `toml:"url"`
+	Database             string            `toml:"database"`
+	SkipDatabaseCreation bool              `toml:"skip_database_creation"`
+	Username             string            `toml:"username"`
+	Password             string            `toml:"password"`
+	Timeout              internal.Duration `toml:"timeout"`
+	TagsAsFields         []string          `toml:"tags_as_fields"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client          *http.Client
+	serializer      *influx.Serializer
+	databaseCreated bool
+}
+
+// sqlResponse is the response of the /rest/sql endpoint.  TDengine 2.x
+// reports the result in status, TDengine 3.x in code.
+type sqlResponse struct {
+	Status string `json:"status"`
+	Code   int    `json:"code"`
+	Desc   string `json:"desc"`
+}
+
+func (t *TDengine) Description() string {
+	return "Write metrics to TDengine using the schemaless line protocol"
+}
+
+func (t *TDengine) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *TDengine) Init() error {
+	if t.Database == "" {
+		return fmt.Errorf("database must be configured")
+	}
+
+	if _, err := url.Parse(t.URL); err != nil {
+		return fmt.Errorf("error parsing url [%q]: %v", t.URL, err)
+	}
+
+	t.serializer = influx.NewSerializer()
+	t.serializer.SetFieldTypeSupport(influx.UintSupport)
+	return nil
+}
+
+func (t *TDengine) Connect() error {
+	tlsCfg, err := t.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	t.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: t.Timeout.Duration,
+	}
+
+	if !t.SkipDatabaseCreation {
+		if err := t.createDatabase(context.Background()); err != nil {
+			t.Log.Warnf("Database %q creation failed: %v", t.Database, err)
+		}
+	}
+	return nil
+}
+
+func (t *TDengine) Close() error {
+	if t.client != nil {
+		internal.CloseIdleConnections(t.client)
+	}
+	return nil
+}
+
+func (t *TDengine) Write(metrics []telegraf.Metric) error {
+	ctx := context.Background()
+
+	if !t.SkipDatabaseCreation && !t.databaseCreated {
+		if err := t.createDatabase(ctx); err != nil {
+			return fmt.Errorf("database %q creation failed: %v", t.Database, err)
+		}
+	}
+
+	if len(t.TagsAsFields) > 0 {
+		metrics = t.convertTags(metrics)
+	}
+
+	reader := influx.NewReader(metrics, t.serializer)
+
+	params := url.Values{}
+	params.Set("db", t.Database)
+	params.Set("precision", "ns")
+	loc, err := t.makeURL("/influxdb/v1/write", params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.do(ctx, loc, "text/plain; charset=utf-8", reader)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("when writing to [%s] received status code: %d: %s",
+		t.URL, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// convertTags moves the tags listed in tags_as_fields to string fields, so
+// that they are stored as columns instead of defining the child table.
+func (t *TDengine) convertTags(metrics []telegraf.Metric) []telegraf.Metric {
+	converted := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		var copied bool
+		for _, key := range t.TagsAsFields {
+			value, ok := m.GetTag(key)
+			if !ok {
+				continue
+			}
+
+			if !copied {
+				// Avoid modifying the metric in case we need to retry the
+				// request.
+				m = m.Copy()
+				m.Accept()
+				copied = true
+			}
+			m.RemoveTag(key)
+			m.AddField(key, value)
+		}
+		converted = append(converted, m)
+	}
+	return converted
+}
+
+func (t *TDengine) createDatabase(ctx context.Context) error {
+	loc, err := t.makeURL("/rest/sql", nil)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", strings.Replace(t.Database, "`", "", -1))
+	resp, err := t.do(ctx, loc, "text/plain; charset=utf-8", bytes.NewBufferString(query))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	result := &sqlResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("received status code %d: %v", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK || result.Code != 0 || (result.Status != "" && result.Status != "succ") {
+		return fmt.Errorf("received status code %d: %s", resp.StatusCode, result.Desc)
+	}
+
+	t.databaseCreated = true
+	return nil
+}
+
+func (t *TDengine) do(ctx context.Context, loc string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest("POST", loc, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", internal.ProductToken())
+	if t.Username != "" || t.Password != "" {
+		req.SetBasicAuth(t.Username, t.Password)
+	}
+
+	return t.client.Do(req.WithContext(ctx))
+}
+
+func (t *TDengine) makeURL(endpoint string, params url.Values) (string, error) {
+	loc, err := url.Parse(t.URL)
+	if err != nil {
+		return "", err
+	}
+	loc.Path = path.Join(loc.Path, endpoint)
+	loc.RawQuery = params.Encode()
+	return loc.String(), nil
+}
+
+func init() {
+	outputs.Add("tdengine", func() telegraf.Output {
+		return &TDengine{
+			URL:      defaultURL,
+			Database: "telegraf",
+			Username: "root",
+			Password: "taosdata",
+			Timeout:  internal.Duration{Duration: defaultClientTimeout},
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package tdengine
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTDengine(u string) *TDengine {
+	return &TDengine{
+		URL:      u,
+		Database: "telegraf",
+		Username: "root",
+		Password: "taosdata",
+		Timeout:  internal.Duration{Duration: defaultClientTimeout},
+		Log:      testutil.Logger{},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var queries []string
+	var lines []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "root", user)
+		require.Equal(t, "taosdata", pass)
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		switch r.URL.Path {
+		case "/rest/sql":
+			queries = append(queries, string(body))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"code":0,"column_meta":[],"data":[],"rows":0}`))
+		case "/influxdb/v1/write":
+			require.Equal(t, "telegraf", r.URL.Query().Get("db"))
+			require.Equal(t, "ns", r.URL.Query().Get("precision"))
+			lines = append(lines, string(body))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := newTDengine(ts.URL)
+	plugin.TagsAsFields = []string{"path"}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.Equal(t, []string{"CREATE DATABASE IF NOT EXISTS `telegraf`"}, queries)
+
+	m := testutil.MustMetric(
+		"disk",
+		map[string]string{
+			"host": "localhost",
+			"path": "/var",
+		},
+		map[string]interface{}{
+			"free": uint64(42),
+		},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Equal(t, []string{"disk,host=localhost free=42u,path=\"/var\" 0\n"}, lines)
+
+	// The original metric is not modified.
+	require.True(t, m.HasTag("path"))
+
+	// The database is only created once.
+	require.Len(t, queries, 1)
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":1,"desc":"invalid line"}`))
+	}))
+	defer ts.Close()
+
+	plugin := newTDengine(ts.URL)
+	plugin.SkipDatabaseCreation = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	err := plugin.Write([]telegraf.Metric{testutil.TestMetric(1.0)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid line")
+}
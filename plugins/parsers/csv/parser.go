@@ -27,8 +27,10 @@ type Parser struct {
 	MeasurementColumn string
 	TimestampColumn   string
 	TimestampFormat   string
-	DefaultTags       map[string]string
-	TimeFunc          func() time.Time
+	// Timezone of the timestamps without offset, UTC when empty.
+	Timezone    string
+	DefaultTags map[string]string
+	TimeFunc    func() time.Time
 }
 
 func (p *Parser) SetTimeFunc(fn metric.TimeFunc) {
@@ -209,7 +211,7 @@ outer:
 		measurementName = fmt.Sprintf("%v", recordFields[p.MeasurementColumn])
 	}
 
-	metricTime, err := parseTimestamp(p.TimeFunc, recordFields, p.TimestampColumn, p.TimestampFormat)
+	metricTime, err := parseTimestamp(p.TimeFunc, recordFields, p.TimestampColumn, p.TimestampFormat, p.Timezone)
 	if err != nil {
 		return nil, err
 	}
@@ -225,7 +227,7 @@ outer:
 // will be the current timestamp, else it will try to parse the time according
 // to the format.
 func parseTimestamp(timeFunc func() time.Time, recordFields map[string]interface{},
-	timestampColumn, timestampFormat string,
+	timestampColumn, timestampFormat, timezone string,
 ) (time.Time, error) {
 	if timestampColumn != "" {
 		if recordFields[timestampColumn] == nil {
@@ -236,7 +238,10 @@ func parseTimestamp(timeFunc func() time.Time, recordFields map[string]interface
 		case "":
 			return time.Time{}, fmt.Errorf("timestamp format must be specified")
 		default:
-			metricTime, err := internal.ParseTimestamp(timestampFormat, recordFields[timestampColumn], "UTC")
+			if timezone == "" {
+				timezone = "UTC"
+			}
+			metricTime, err := internal.ParseTimestamp(timestampFormat, recordFields[timestampColumn], timezone)
 			if err != nil {
 				return time.Time{}, err
 			}
@@ -74,6 +74,24 @@ func TestTimestamp(t *testing.T) {
 	require.Equal(t, metrics[1].Time().UnixNano(), int64(1257609906000000000))
 }
 
+func TestTimestampTimezone(t *testing.T) {
+	p := Parser{
+		HeaderRowCount:    1,
+		ColumnNames:       []string{"first", "second", "third"},
+		MeasurementColumn: "third",
+		TimestampColumn:   "first",
+		TimestampFormat:   "02/01/06 03:04:05 PM",
+		Timezone:          "Asia/Jakarta",
+		TimeFunc:          DefaultTime,
+	}
+	testCSV := `line1,line2,line3
+23/05/09 11:05:06 PM,70,test_name`
+	metrics, err := p.Parse([]byte(testCSV))
+
+	require.NoError(t, err)
+	require.Equal(t, metrics[0].Time().UnixNano(), int64(1243094706000000000))
+}
+
 func TestTimestampError(t *testing.T) {
 	p := Parser{
 		HeaderRowCount:    1,
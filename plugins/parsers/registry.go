@@ -108,6 +108,10 @@ type Config struct {
 	// DefaultTags are the default tags that will be added to all parsed metrics.
 	DefaultTags map[string]string `toml:"default_tags"`
 
+	// Timezone of the timestamps without offset, for the parsers without
+	// their own timezone setting.
+	Timezone string `toml:"timezone"`
+
 	// an optional json path containing the metric registry object
 	// if left empty, the whole json object is parsed as a metric registry
 	DropwizardMetricRegistryPath string `toml:"dropwizard_metric_registry_path"`
@@ -165,7 +169,7 @@ func NewParser(config *Config) (Parser, error) {
 				Query:        config.JSONQuery,
 				TimeKey:      config.JSONTimeKey,
 				TimeFormat:   config.JSONTimeFormat,
-				Timezone:     config.JSONTimezone,
+				Timezone:     defaultString(config.JSONTimezone, config.Timezone),
 				DefaultTags:  config.DefaultTags,
 				Strict:       config.JSONStrict,
 			},
@@ -202,7 +206,7 @@ func NewParser(config *Config) (Parser, error) {
 			config.GrokNamedPatterns,
 			config.GrokCustomPatterns,
 			config.GrokCustomPatternFiles,
-			config.GrokTimezone,
+			defaultString(config.GrokTimezone, config.Timezone),
 			config.GrokUniqueTimestamp)
 	case "csv":
 		parser, err = newCSVParser(config.MetricName,
@@ -218,6 +222,7 @@ func NewParser(config *Config) (Parser, error) {
 			config.CSVMeasurementColumn,
 			config.CSVTimestampColumn,
 			config.CSVTimestampFormat,
+			config.Timezone,
 			config.DefaultTags)
 	case "logfmt":
 		parser, err = NewLogFmtParser(config.MetricName, config.DefaultTags)
@@ -233,6 +238,14 @@ func NewParser(config *Config) (Parser, error) {
 	return parser, err
 }
 
+// defaultString returns the value, or the default when empty.
+func defaultString(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
 func newCSVParser(metricName string,
 	headerRowCount int,
 	skipRows int,
@@ -246,6 +259,7 @@ func newCSVParser(metricName string,
 	nameColumn string,
 	timestampColumn string,
 	timestampFormat string,
+	timezone string,
 	defaultTags map[string]string) (Parser, error) {
 
 	if headerRowCount == 0 && len(columnNames) == 0 {
@@ -284,6 +298,7 @@ func newCSVParser(metricName string,
 		MeasurementColumn: nameColumn,
 		TimestampColumn:   timestampColumn,
 		TimestampFormat:   timestampFormat,
+		Timezone:          timezone,
 		DefaultTags:       defaultTags,
 		TimeFunc:          time.Now,
 	}
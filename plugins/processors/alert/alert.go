@@ -0,0 +1,344 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Name of the alert state metrics.
+  # measurement = "alert"
+
+  ## Emit a metric when the condition of a rule becomes true, before the
+  ## alert fires after the "for" duration.
+  # emit_pending = false
+
+  ## Forget alerts without metrics for this duration, firing alerts are
+  ## resolved.  If 0, alerts are kept until their condition is false.
+  # expire_after = "0s"
+
+  ## Rules evaluated against the metrics passing the processor.
+  [[processors.alert.rule]]
+    ## Name of the alert.
+    name = "high_cpu"
+
+    ## Measurements and field the rule is evaluated against, the
+    ## measurement supports globs.
+    measurement = "cpu"
+    field = "usage_user"
+
+    ## The alert is active while the value of the field compared with the
+    ## operator to the threshold is true.  Operators are ">", ">=", "<",
+    ## "<=", "==" and "!=".
+    operator = ">"
+    threshold = 90.0
+
+    ## Duration the condition must be true for the alert to fire, based on
+    ## the time of the metrics.
+    # for = "0s"
+
+    ## Severity of the alert.
+    # severity = "warning"
+
+    ## Tags identifying an alert, by default all tags of the metrics.
+    # group_by = ["host"]
+
+    ## Additional tags of the alert state metrics.
+    # [processors.alert.rule.tags]
+    #   team = "ops"
+`
+
+// Alert states
+const (
+	statePending  = "pending"
+	stateFiring   = "firing"
+	stateResolved = "resolved"
+)
+
+// Rule is a threshold rule.
+type Rule struct {
+	Name        string            `toml:"name"`
+	Measurement string            `toml:"measurement"`
+	Field       string            `toml:"field"`
+	Operator    string            `toml:"operator"`
+	Threshold   float64           `toml:"threshold"`
+	For         internal.Duration `toml:"for"`
+	Severity    string            `toml:"severity"`
+	GroupBy     []string          `toml:"group_by"`
+	Tags        map[string]string `toml:"tags"`
+
+	id                int
+	measurementFilter filter.Filter
+	compare           func(v, threshold float64) bool
+}
+
+// alert is the state of an alert of a rule.
+type alert struct {
+	rule     *Rule
+	tags     map[string]string
+	state    string
+	since    time.Time
+	lastSeen time.Time
+}
+
+// Alert is a processor evaluating threshold rules and emitting metrics of
+// the alert state transitions.
+type Alert struct {
+	Measurement string            `toml:"measurement"`
+	EmitPending bool              `toml:"emit_pending"`
+	ExpireAfter internal.Duration `toml:"expire_after"`
+	Rules       []*Rule           `toml:"rule"`
+
+	alerts map[string]*alert
+}
+
+var operators = map[string]func(v, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"==": func(v, t float64) bool { return v == t },
+	"!=": func(v, t float64) bool { return v != t },
+}
+
+func (a *Alert) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Alert) Description() string {
+	return "Evaluate threshold rules and emit metrics of alert state transitions"
+}
+
+func (a *Alert) Init() error {
+	for i, r := range a.Rules {
+		r.id = i
+		if r.Name == "" {
+			return errors.New("rule name must be set")
+		}
+		if r.Field == "" {
+			return fmt.Errorf("rule %q: field must be set", r.Name)
+		}
+
+		if r.Operator == "" {
+			r.Operator = ">"
+		}
+		compare, ok := operators[r.Operator]
+		if !ok {
+			return fmt.Errorf("rule %q: unknown operator %q", r.Name, r.Operator)
+		}
+		r.compare = compare
+
+		if r.Severity == "" {
+			r.Severity = "warning"
+		}
+
+		var err error
+		if r.Measurement != "" {
+			r.measurementFilter, err = filter.Compile([]string{r.Measurement})
+			if err != nil {
+				return fmt.Errorf("rule %q: %v", r.Name, err)
+			}
+		}
+	}
+
+	a.alerts = make(map[string]*alert)
+	return nil
+}
+
+func (a *Alert) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in
+	var latest time.Time
+	for _, m := range in {
+		if m.Time().After(latest) {
+			latest = m.Time()
+		}
+
+		for _, r := range a.Rules {
+			if r.measurementFilter != nil && !r.measurementFilter.Match(m.Name()) {
+				continue
+			}
+			field, ok := m.GetField(r.Field)
+			if !ok {
+				continue
+			}
+			value, ok := toFloat(field)
+			if !ok {
+				continue
+			}
+
+			if transition := a.evaluate(r, m, value); transition != nil {
+				out = append(out, transition)
+			}
+		}
+	}
+
+	if a.ExpireAfter.Duration > 0 && !latest.IsZero() {
+		out = append(out, a.expire(latest)...)
+	}
+	return out
+}
+
+// evaluate updates the state of the alert of the metric and returns the
+// state metric if the state changed.
+func (a *Alert) evaluate(r *Rule, m telegraf.Metric, value float64) telegraf.Metric {
+	tags := groupTags(r, m)
+	key := alertKey(r, tags)
+	t := m.Time()
+
+	current, exists := a.alerts[key]
+	if exists {
+		current.lastSeen = t
+	}
+
+	if !r.compare(value, r.Threshold) {
+		if !exists {
+			return nil
+		}
+		delete(a.alerts, key)
+		if current.state == stateFiring || a.EmitPending {
+			return a.stateMetric(current, stateResolved, value, t)
+		}
+		return nil
+	}
+
+	if !exists {
+		current = &alert{rule: r, tags: tags, state: statePending, since: t, lastSeen: t}
+		a.alerts[key] = current
+		if r.For.Duration > 0 {
+			if a.EmitPending {
+				return a.stateMetric(current, statePending, value, t)
+			}
+			return nil
+		}
+	}
+
+	if current.state == statePending && t.Sub(current.since) >= r.For.Duration {
+		current.state = stateFiring
+		return a.stateMetric(current, stateFiring, value, t)
+	}
+	return nil
+}
+
+// expire removes the alerts without metrics since the expiry duration,
+// relative to the latest metric.
+func (a *Alert) expire(now time.Time) []telegraf.Metric {
+	var keys []string
+	for key, current := range a.alerts {
+		if now.Sub(current.lastSeen) >= a.ExpireAfter.Duration {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var out []telegraf.Metric
+	for _, key := range keys {
+		current := a.alerts[key]
+		delete(a.alerts, key)
+		if current.state == stateFiring || a.EmitPending {
+			if m := a.stateMetric(current, stateResolved, nil, now); m != nil {
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+func (a *Alert) stateMetric(current *alert, state string, value interface{}, t time.Time) telegraf.Metric {
+	r := current.rule
+	tags := make(map[string]string, len(current.tags)+len(r.Tags)+2)
+	for k, v := range current.tags {
+		tags[k] = v
+	}
+	for k, v := range r.Tags {
+		tags[k] = v
+	}
+	tags["alertname"] = r.Name
+	tags["severity"] = r.Severity
+
+	fields := map[string]interface{}{
+		"state":     state,
+		"active":    state != stateResolved,
+		"threshold": r.Threshold,
+		"duration":  int64(t.Sub(current.since) / time.Second),
+	}
+	if value != nil {
+		fields["value"] = value
+	}
+
+	m, err := metric.New(a.Measurement, tags, fields, t)
+	if err != nil {
+		return nil
+	}
+	return m
+}
+
+// groupTags returns the tags identifying the alert of the metric.
+func groupTags(r *Rule, m telegraf.Metric) map[string]string {
+	tags := make(map[string]string)
+	if len(r.GroupBy) == 0 {
+		for _, tag := range m.TagList() {
+			tags[tag.Key] = tag.Value
+		}
+		return tags
+	}
+	for _, key := range r.GroupBy {
+		if value, ok := m.GetTag(key); ok {
+			tags[key] = value
+		}
+	}
+	return tags
+}
+
+func alertKey(r *Rule, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(r.id))
+	for _, k := range keys {
+		b.WriteString("\x00")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(tags[k])
+	}
+	return b.String()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.Add("alert", func() telegraf.Processor {
+		return &Alert{
+			Measurement: "alert",
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func cpu(host string, usage float64, seconds int64) telegraf.Metric {
+	return testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": host, "cpu": "cpu-total"},
+		map[string]interface{}{"usage_user": usage},
+		time.Unix(seconds, 0),
+	)
+}
+
+func alertMetric(host, state string, value interface{}, duration, seconds int64) telegraf.Metric {
+	fields := map[string]interface{}{
+		"state":     state,
+		"active":    state != stateResolved,
+		"threshold": 90.0,
+		"duration":  duration,
+	}
+	if value != nil {
+		fields["value"] = value
+	}
+	return testutil.MustMetric(
+		"alert",
+		map[string]string{"host": host, "alertname": "high_cpu", "severity": "critical", "team": "ops"},
+		fields,
+		time.Unix(seconds, 0),
+	)
+}
+
+func newAlert() *Alert {
+	return &Alert{
+		Measurement: "alert",
+		Rules: []*Rule{
+			{
+				Name:        "high_cpu",
+				Measurement: "cp*",
+				Field:       "usage_user",
+				Operator:    ">",
+				Threshold:   90,
+				For:         internal.Duration{Duration: 20 * time.Second},
+				Severity:    "critical",
+				GroupBy:     []string{"host"},
+				Tags:        map[string]string{"team": "ops"},
+			},
+		},
+	}
+}
+
+func TestApplyFor(t *testing.T) {
+	plugin := newAlert()
+	require.NoError(t, plugin.Init())
+
+	var out []telegraf.Metric
+	for _, batch := range [][]telegraf.Metric{
+		{cpu("a", 95, 0), cpu("b", 50, 0)},
+		{cpu("a", 96, 10), cpu("b", 95, 10)},
+		{cpu("a", 97, 20), cpu("b", 50, 20)},
+		{cpu("a", 98, 30)},
+		{cpu("a", 10, 40)},
+	} {
+		for _, m := range plugin.Apply(batch...) {
+			if m.Name() == "alert" {
+				out = append(out, m)
+			}
+		}
+	}
+
+	expected := []telegraf.Metric{
+		alertMetric("a", stateFiring, 97.0, 20, 20),
+		alertMetric("a", stateResolved, 10.0, 40, 40),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestApplyPending(t *testing.T) {
+	plugin := newAlert()
+	plugin.EmitPending = true
+	require.NoError(t, plugin.Init())
+
+	out := plugin.Apply(cpu("a", 95, 0))
+	require.Len(t, out, 2)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{cpu("a", 95, 0), alertMetric("a", statePending, 95.0, 0, 0)}, out)
+
+	out = plugin.Apply(cpu("a", 50, 10))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{cpu("a", 50, 10), alertMetric("a", stateResolved, 50.0, 10, 10)}, out)
+}
+
+func TestApplyImmediate(t *testing.T) {
+	plugin := newAlert()
+	plugin.Rules[0].For.Duration = 0
+	require.NoError(t, plugin.Init())
+
+	out := plugin.Apply(cpu("a", 95, 0))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{cpu("a", 95, 0), alertMetric("a", stateFiring, 95.0, 0, 0)}, out)
+
+	// Firing alerts are not emitted again
+	out = plugin.Apply(cpu("a", 95, 10))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{cpu("a", 95, 10)}, out)
+}
+
+func TestApplyExpire(t *testing.T) {
+	plugin := newAlert()
+	plugin.Rules[0].For.Duration = 0
+	plugin.ExpireAfter.Duration = 30 * time.Second
+	require.NoError(t, plugin.Init())
+
+	plugin.Apply(cpu("a", 95, 0), cpu("b", 95, 0))
+	out := plugin.Apply(cpu("b", 95, 20))
+	require.Len(t, out, 1)
+
+	out = plugin.Apply(cpu("b", 95, 30))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{cpu("b", 95, 30), alertMetric("a", stateResolved, nil, 30, 30)}, out)
+	require.Len(t, plugin.alerts, 1)
+}
+
+func TestApplyIgnored(t *testing.T) {
+	plugin := newAlert()
+	plugin.Rules[0].For.Duration = 0
+	require.NoError(t, plugin.Init())
+
+	in := []telegraf.Metric{
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"usage_user": 95.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"usage_user": "high"}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"usage_system": 95.0}, time.Unix(0, 0)),
+	}
+	require.Len(t, plugin.Apply(in...), 3)
+}
+
+func TestInit(t *testing.T) {
+	plugin := newAlert()
+	plugin.Rules[0].Operator = "=>"
+	require.Error(t, plugin.Init())
+
+	plugin = newAlert()
+	plugin.Rules[0].Field = ""
+	require.Error(t, plugin.Init())
+
+	plugin = &Alert{Rules: []*Rule{{Name: "test", Field: "value"}}}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, ">", plugin.Rules[0].Operator)
+	require.Equal(t, "warning", plugin.Rules[0].Severity)
+}
@@ -0,0 +1,309 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Fields set to the result of arithmetic expressions over the fields of
+  ## the metric, evaluated in order so an expression can use the fields of
+  ## the previous ones.  The expressions support the +, -, *, / and %
+  ## operators, parentheses, numbers and the functions abs, ceil, floor,
+  ## round, sqrt, pow, min and max.  Fields with names that are not
+  ## identifiers are referenced with field("name").  The field is not set if
+  ## a field of the expression is missing or the result is not a number.
+  [[processors.expression.field]]
+    name = "util"
+    expression = "busy / total * 100"
+
+  # [[processors.expression.field]]
+  #   name = "free_ratio"
+  #   expression = "round(1 - field(\"mem-used\") / max(field(\"mem-total\"), 1), 2)"
+`
+
+// Field is a field set to the result of an expression.
+type Field struct {
+	Name       string `toml:"name"`
+	Expression string `toml:"expression"`
+
+	eval evalFunc
+}
+
+// Expression is a processor setting fields to the result of expressions.
+type Expression struct {
+	Fields []*Field `toml:"field"`
+}
+
+// evalFunc returns the value of a compiled expression for a metric, or false
+// if a field of the expression is missing.
+type evalFunc func(m telegraf.Metric) (float64, bool)
+
+type function struct {
+	minArgs int
+	maxArgs int
+	call    func(args []float64) float64
+}
+
+var functions = map[string]function{
+	"abs":   {1, 1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"ceil":  {1, 1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"floor": {1, 1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"round": {1, 2, round},
+	"sqrt":  {1, 1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"pow":   {2, 2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"min":   {1, -1, minimum},
+	"max":   {1, -1, maximum},
+}
+
+func (e *Expression) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Expression) Description() string {
+	return "Set fields to the result of arithmetic expressions over the fields of the metric"
+}
+
+func (e *Expression) Init() error {
+	for _, f := range e.Fields {
+		if f.Name == "" {
+			return errors.New("field name must be set")
+		}
+		expr, err := parser.ParseExpr(f.Expression)
+		if err != nil {
+			return fmt.Errorf("field %q: invalid expression: %v", f.Name, err)
+		}
+		f.eval, err = compile(expr)
+		if err != nil {
+			return fmt.Errorf("field %q: %v", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func (e *Expression) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		for _, f := range e.Fields {
+			v, ok := f.eval(m)
+			if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			m.AddField(f.Name, v)
+		}
+	}
+	return in
+}
+
+// compile returns the function evaluating the parsed expression.
+func compile(expr ast.Expr) (evalFunc, error) {
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return compile(expr.X)
+	case *ast.BasicLit:
+		if expr.Kind != token.INT && expr.Kind != token.FLOAT {
+			return nil, fmt.Errorf("unexpected literal %s", expr.Value)
+		}
+		v, err := strconv.ParseFloat(expr.Value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return func(telegraf.Metric) (float64, bool) { return v, true }, nil
+	case *ast.Ident:
+		return fieldValue(expr.Name), nil
+	case *ast.SelectorExpr:
+		// Field names containing dots are parsed as selectors
+		name, ok := selectorName(expr)
+		if !ok {
+			return nil, errors.New("unexpected selector")
+		}
+		return fieldValue(name), nil
+	case *ast.UnaryExpr:
+		x, err := compile(expr.X)
+		if err != nil {
+			return nil, err
+		}
+		switch expr.Op {
+		case token.ADD:
+			return x, nil
+		case token.SUB:
+			return func(m telegraf.Metric) (float64, bool) {
+				v, ok := x(m)
+				return -v, ok
+			}, nil
+		}
+		return nil, fmt.Errorf("unsupported operator %s", expr.Op)
+	case *ast.BinaryExpr:
+		return compileBinary(expr)
+	case *ast.CallExpr:
+		return compileCall(expr)
+	}
+	return nil, fmt.Errorf("unsupported expression at position %d", expr.Pos())
+}
+
+func compileBinary(expr *ast.BinaryExpr) (evalFunc, error) {
+	var op func(x, y float64) float64
+	switch expr.Op {
+	case token.ADD:
+		op = func(x, y float64) float64 { return x + y }
+	case token.SUB:
+		op = func(x, y float64) float64 { return x - y }
+	case token.MUL:
+		op = func(x, y float64) float64 { return x * y }
+	case token.QUO:
+		op = func(x, y float64) float64 { return x / y }
+	case token.REM:
+		op = math.Mod
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", expr.Op)
+	}
+
+	x, err := compile(expr.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := compile(expr.Y)
+	if err != nil {
+		return nil, err
+	}
+	return func(m telegraf.Metric) (float64, bool) {
+		a, ok := x(m)
+		if !ok {
+			return 0, false
+		}
+		b, ok := y(m)
+		if !ok {
+			return 0, false
+		}
+		return op(a, b), true
+	}, nil
+}
+
+func compileCall(expr *ast.CallExpr) (evalFunc, error) {
+	ident, ok := expr.Fun.(*ast.Ident)
+	if !ok {
+		return nil, errors.New("unsupported function call")
+	}
+
+	if ident.Name == "field" {
+		if len(expr.Args) != 1 {
+			return nil, errors.New("field expects the name of a field")
+		}
+		lit, ok := expr.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return nil, errors.New("field expects the name of a field")
+		}
+		name, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return nil, err
+		}
+		return fieldValue(name), nil
+	}
+
+	fn, ok := functions[ident.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", ident.Name)
+	}
+	if len(expr.Args) < fn.minArgs || (fn.maxArgs >= 0 && len(expr.Args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for %q", ident.Name)
+	}
+
+	args := make([]evalFunc, 0, len(expr.Args))
+	for _, arg := range expr.Args {
+		a, err := compile(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	return func(m telegraf.Metric) (float64, bool) {
+		values := make([]float64, 0, len(args))
+		for _, a := range args {
+			v, ok := a(m)
+			if !ok {
+				return 0, false
+			}
+			values = append(values, v)
+		}
+		return fn.call(values), true
+	}, nil
+}
+
+func selectorName(expr ast.Expr) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name, true
+	case *ast.SelectorExpr:
+		x, ok := selectorName(expr.X)
+		if !ok {
+			return "", false
+		}
+		return x + "." + expr.Sel.Name, true
+	}
+	return "", false
+}
+
+func fieldValue(name string) evalFunc {
+	return func(m telegraf.Metric) (float64, bool) {
+		v, ok := m.GetField(name)
+		if !ok {
+			return 0, false
+		}
+		return toFloat(v)
+	}
+}
+
+func round(args []float64) float64 {
+	if len(args) == 1 {
+		return math.Round(args[0])
+	}
+	p := math.Pow(10, math.Trunc(args[1]))
+	return math.Round(args[0]*p) / p
+}
+
+func minimum(args []float64) float64 {
+	v := args[0]
+	for _, a := range args[1:] {
+		v = math.Min(v, a)
+	}
+	return v
+}
+
+func maximum(args []float64) float64 {
+	v := args[0]
+	for _, a := range args[1:] {
+		v = math.Max(v, a)
+	}
+	return v
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.Add("expression", func() telegraf.Processor {
+		return &Expression{}
+	})
+}
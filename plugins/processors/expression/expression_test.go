@@ -0,0 +1,127 @@
+package expression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		fields     map[string]interface{}
+		expected   interface{}
+	}{
+		{
+			name:       "arithmetic",
+			expression: "busy / total * 100",
+			fields:     map[string]interface{}{"busy": int64(25), "total": uint64(50)},
+			expected:   50.0,
+		},
+		{
+			name:       "precedence and unary",
+			expression: "-(a + b) * 2 + a % 2",
+			fields:     map[string]interface{}{"a": 3.0, "b": 1.0},
+			expected:   -7.0,
+		},
+		{
+			name:       "functions",
+			expression: "round(max(a, b, 1) / 3, 2) + abs(-1) + min(a, b)",
+			fields:     map[string]interface{}{"a": 2.0, "b": 4.0},
+			expected:   4.33,
+		},
+		{
+			name:       "field names",
+			expression: `field("mem-used") + mem.free`,
+			fields:     map[string]interface{}{"mem-used": 2.0, "mem.free": 3.0},
+			expected:   5.0,
+		},
+		{
+			name:       "missing field",
+			expression: "a + b",
+			fields:     map[string]interface{}{"a": 1.0},
+		},
+		{
+			name:       "not a number",
+			expression: "a / b",
+			fields:     map[string]interface{}{"a": 1.0, "b": 0.0},
+		},
+		{
+			name:       "string field",
+			expression: "a + 1",
+			fields:     map[string]interface{}{"a": "x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Expression{
+				Fields: []*Field{{Name: "result", Expression: tt.expression}},
+			}
+			require.NoError(t, e.Init())
+
+			now := time.Now()
+			m := testutil.MustMetric("test", map[string]string{}, tt.fields, now)
+
+			fields := map[string]interface{}{}
+			for k, v := range tt.fields {
+				fields[k] = v
+			}
+			if tt.expected != nil {
+				fields["result"] = tt.expected
+			}
+			expected := []telegraf.Metric{
+				testutil.MustMetric("test", map[string]string{}, fields, now),
+			}
+			testutil.RequireMetricsEqual(t, expected, e.Apply(m), testutil.FloatTolerance(1e-9))
+		})
+	}
+}
+
+func TestApplyInOrder(t *testing.T) {
+	e := &Expression{
+		Fields: []*Field{
+			{Name: "total", Expression: "used + free"},
+			{Name: "used_percent", Expression: "used / total * 100"},
+		},
+	}
+	require.NoError(t, e.Init())
+
+	now := time.Now()
+	m := testutil.MustMetric("mem", map[string]string{},
+		map[string]interface{}{"used": int64(1), "free": int64(3)}, now)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("mem", map[string]string{},
+			map[string]interface{}{
+				"used":         int64(1),
+				"free":         int64(3),
+				"total":        4.0,
+				"used_percent": 25.0,
+			}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, e.Apply(m))
+}
+
+func TestInitErrors(t *testing.T) {
+	for _, expression := range []string{
+		"a +",
+		"a == b",
+		"unknown(a)",
+		"abs(a, b)",
+		`"a" + 1`,
+		"field(a)",
+		"a[0]",
+	} {
+		e := &Expression{
+			Fields: []*Field{{Name: "result", Expression: expression}},
+		}
+		require.Error(t, e.Init(), expression)
+	}
+
+	e := &Expression{Fields: []*Field{{Expression: "a"}}}
+	require.Error(t, e.Init())
+}
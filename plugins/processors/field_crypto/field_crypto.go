@@ -0,0 +1,208 @@
+package field_crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const (
+	description  = "Encrypt or sign the values of fields with a shared key"
+	sampleConfig = `
+  ## Mode of the processor:
+  ##   encrypt - the values of the fields are replaced by their encryption with
+  ##             AES-GCM, encoded in base64.
+  ##   sign    - a field with the HMAC-SHA256 signature of the value, encoded
+  ##             in hex, is added for each field.
+  # mode = "encrypt"
+
+  ## Fields to process.  Globs accepted.
+  fields = []
+
+  ## Key encoded in hex, of 16, 24 or 32 bytes for the encrypt mode to use
+  ## AES-128, AES-192 or AES-256, and of at least 32 bytes for the sign mode.
+  ## The key can be generated with "openssl rand -hex 32".
+  # key = ""
+
+  ## File of the key encoded in hex, instead of the key option.
+  # key_file = "/etc/telegraf/field_crypto.key"
+
+  ## Suffix of the name of the fields of the signatures in sign mode.
+  # signature_suffix = "_signature"
+`
+)
+
+type FieldCrypto struct {
+	Mode            string   `toml:"mode"`
+	Fields          []string `toml:"fields"`
+	Key             string   `toml:"key"`
+	KeyFile         string   `toml:"key_file"`
+	SignatureSuffix string   `toml:"signature_suffix"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	fieldFilter filter.Filter
+	aead        cipher.AEAD
+	key         []byte
+}
+
+func (c *FieldCrypto) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *FieldCrypto) Description() string {
+	return description
+}
+
+func (c *FieldCrypto) Init() error {
+	if len(c.Fields) == 0 {
+		return errors.New("no field configured")
+	}
+	var err error
+	c.fieldFilter, err = filter.Compile(c.Fields)
+	if err != nil {
+		return err
+	}
+
+	key, err := c.readKey()
+	if err != nil {
+		return err
+	}
+
+	switch c.Mode {
+	case "encrypt":
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("invalid key: %v", err)
+		}
+		if c.aead, err = cipher.NewGCM(block); err != nil {
+			return err
+		}
+	case "sign":
+		if len(key) < sha256.Size {
+			return fmt.Errorf("key of %d bytes is too short, at least %d bytes are required", len(key), sha256.Size)
+		}
+		if c.SignatureSuffix == "" {
+			return errors.New("signature_suffix must be set")
+		}
+		c.key = key
+	default:
+		return fmt.Errorf("invalid mode %q", c.Mode)
+	}
+	return nil
+}
+
+// readKey decodes the key of the key or key_file option.
+func (c *FieldCrypto) readKey() ([]byte, error) {
+	encoded := c.Key
+	switch {
+	case c.Key != "" && c.KeyFile != "":
+		return nil, errors.New("key and key_file are exclusive")
+	case c.KeyFile != "":
+		b, err := ioutil.ReadFile(c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading key file: %v", err)
+		}
+		encoded = string(b)
+	case c.Key == "":
+		return nil, errors.New("key or key_file must be set")
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("key is not encoded in hex: %v", err)
+	}
+	return key, nil
+}
+
+func (c *FieldCrypto) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range metrics {
+		// The fields are copied as they are added or removed during the
+		// iteration.
+		fields := make([]telegraf.Field, 0, len(m.FieldList()))
+		for _, field := range m.FieldList() {
+			if c.fieldFilter.Match(field.Key) {
+				fields = append(fields, *field)
+			}
+		}
+
+		for _, field := range fields {
+			plaintext := []byte(formatValue(field.Value))
+			if c.Mode == "sign" {
+				m.AddField(field.Key+c.SignatureSuffix, c.sign(field.Key, plaintext))
+				continue
+			}
+
+			value, err := c.encrypt(field.Key, plaintext)
+			if err != nil {
+				// The plaintext must not be sent.
+				c.Log.Errorf("Error encrypting field %q, dropping it: %v", field.Key, err)
+				m.RemoveField(field.Key)
+				continue
+			}
+			m.AddField(field.Key, value)
+		}
+	}
+	return metrics
+}
+
+// encrypt returns the nonce followed by the ciphertext, encoded in base64.
+// The field name is authenticated with the value so that the values cannot
+// be swapped between fields.
+func (c *FieldCrypto) encrypt(name string, plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(name))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// sign returns the signature of the field name and value, joined with "=".
+func (c *FieldCrypto) sign(name string, plaintext []byte) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(name))
+	mac.Write([]byte("="))
+	mac.Write(plaintext)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// formatValue returns the text of the value that is encrypted or signed.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func init() {
+	processors.Add("field_crypto", func() telegraf.Processor {
+		return &FieldCrypto{
+			Mode:            "encrypt",
+			SignatureSuffix: "_signature",
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package field_crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func decrypt(t *testing.T, name string, value interface{}) string {
+	key, err := hex.DecodeString(testKey)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	sealed, err := base64.StdEncoding.DecodeString(value.(string))
+	require.NoError(t, err)
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	require.NoError(t, err)
+	return string(plaintext)
+}
+
+func TestEncrypt(t *testing.T) {
+	plugin := &FieldCrypto{
+		Mode:   "encrypt",
+		Fields: []string{"card_*", "pin"},
+		Key:    testKey,
+		Log:    testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	m := testutil.MustMetric(
+		"payment",
+		map[string]string{"shop": "berlin"},
+		map[string]interface{}{
+			"card_number": "4111111111111111",
+			"card_limit":  1500.5,
+			"pin":         int64(1234),
+			"amount":      int64(42),
+		},
+		time.Unix(0, 0),
+	)
+	out := plugin.Apply(m)
+	require.Len(t, out, 1)
+
+	fields := out[0].Fields()
+	require.Equal(t, int64(42), fields["amount"])
+	require.Equal(t, "4111111111111111", decrypt(t, "card_number", fields["card_number"]))
+	require.Equal(t, "1500.5", decrypt(t, "card_limit", fields["card_limit"]))
+	require.Equal(t, "1234", decrypt(t, "pin", fields["pin"]))
+
+	// The values are bound to the name of their field.
+	key, _ := hex.DecodeString(testKey)
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	sealed, _ := base64.StdEncoding.DecodeString(fields["pin"].(string))
+	_, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte("card_number"))
+	require.Error(t, err)
+
+	// The same value is encrypted with a different nonce each time.
+	again := plugin.Apply(testutil.MustMetric("payment", nil, map[string]interface{}{"pin": int64(1234)}, time.Unix(0, 0)))
+	require.NotEqual(t, fields["pin"], again[0].Fields()["pin"])
+}
+
+func TestSign(t *testing.T) {
+	plugin := &FieldCrypto{
+		Mode:            "sign",
+		Fields:          []string{"reading"},
+		Key:             testKey,
+		SignatureSuffix: "_signature",
+	}
+	require.NoError(t, plugin.Init())
+
+	key, _ := hex.DecodeString(testKey)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("reading=21.5"))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"sensor",
+			map[string]string{},
+			map[string]interface{}{
+				"reading":           21.5,
+				"reading_signature": hex.EncodeToString(mac.Sum(nil)),
+				"battery":           int64(90),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	out := plugin.Apply(testutil.MustMetric(
+		"sensor",
+		map[string]string{},
+		map[string]interface{}{"reading": 21.5, "battery": int64(90)},
+		time.Unix(0, 0),
+	))
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "field_crypto")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "field.key")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testKey+"\n"), 0600))
+
+	plugin := &FieldCrypto{Mode: "encrypt", Fields: []string{"pin"}, KeyFile: path}
+	require.NoError(t, plugin.Init())
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *FieldCrypto
+	}{
+		{
+			name:   "no fields",
+			plugin: &FieldCrypto{Mode: "encrypt", Key: testKey},
+		},
+		{
+			name:   "no key",
+			plugin: &FieldCrypto{Mode: "encrypt", Fields: []string{"pin"}},
+		},
+		{
+			name:   "key and key file",
+			plugin: &FieldCrypto{Mode: "encrypt", Fields: []string{"pin"}, Key: testKey, KeyFile: "field.key"},
+		},
+		{
+			name:   "key not in hex",
+			plugin: &FieldCrypto{Mode: "encrypt", Fields: []string{"pin"}, Key: "secret"},
+		},
+		{
+			name:   "invalid key size",
+			plugin: &FieldCrypto{Mode: "encrypt", Fields: []string{"pin"}, Key: "0001020304"},
+		},
+		{
+			name:   "short signing key",
+			plugin: &FieldCrypto{Mode: "sign", Fields: []string{"pin"}, Key: testKey[:32], SignatureSuffix: "_signature"},
+		},
+		{
+			name:   "invalid mode",
+			plugin: &FieldCrypto{Mode: "age", Fields: []string{"pin"}, Key: testKey},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package flatten
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const (
+	description  = "Flatten nested field names and JSON documents, or nest flat field names"
+	sampleConfig = `
+  ## Mode of the processor:
+  ##   flatten   - the string fields holding a JSON object or array are
+  ##               expanded into a field per value, and the nested field
+  ##               names are joined with the separator.
+  ##   unflatten - the flat field names are split on the separator and joined
+  ##               with the nested separator, for the outputs nesting fields
+  ##               on it such as Elasticsearch with ".".
+  # mode = "flatten"
+
+  ## Fields to process, all the fields by default.
+  # fields = ["*"]
+
+  ## Separator of the levels of the flat field names.
+  # separator = "_"
+
+  ## Separator of the levels of the nested field names.
+  # nested_separator = "."
+
+  ## Maximum number of levels of the field names, 0 for no limit.  The
+  ## deeper values of the JSON documents are kept as JSON strings.
+  # max_depth = 0
+`
+)
+
+type Flatten struct {
+	Mode            string   `toml:"mode"`
+	Fields          []string `toml:"fields"`
+	Separator       string   `toml:"separator"`
+	NestedSeparator string   `toml:"nested_separator"`
+	MaxDepth        int      `toml:"max_depth"`
+
+	fieldFilter filter.Filter
+}
+
+func (f *Flatten) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *Flatten) Description() string {
+	return description
+}
+
+func (f *Flatten) Init() error {
+	switch f.Mode {
+	case "flatten", "unflatten":
+	default:
+		return fmt.Errorf("invalid mode %q", f.Mode)
+	}
+
+	if f.Separator == "" || f.NestedSeparator == "" {
+		return errors.New("separator and nested_separator must be set")
+	}
+	if f.Separator == f.NestedSeparator {
+		return errors.New("separator and nested_separator must differ")
+	}
+	if f.MaxDepth < 0 {
+		return errors.New("max_depth must not be negative")
+	}
+
+	var err error
+	f.fieldFilter, err = filter.Compile(f.Fields)
+	return err
+}
+
+func (f *Flatten) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range metrics {
+		// The fields are copied as they are replaced during the iteration
+		fields := make([]*telegraf.Field, 0, len(m.FieldList()))
+		for _, field := range m.FieldList() {
+			fields = append(fields, &telegraf.Field{Key: field.Key, Value: field.Value})
+		}
+
+		for _, field := range fields {
+			if f.fieldFilter != nil && !f.fieldFilter.Match(field.Key) {
+				continue
+			}
+
+			if f.Mode == "unflatten" {
+				f.rename(m, field, f.join(field.Key, f.Separator, f.NestedSeparator))
+				continue
+			}
+
+			key := f.join(field.Key, f.NestedSeparator, f.Separator)
+			doc, ok := parseDocument(field.Value)
+			if !ok {
+				f.rename(m, field, key)
+				continue
+			}
+			m.RemoveField(field.Key)
+			f.addValue(m, key, strings.Count(key, f.Separator)+1, doc)
+		}
+	}
+	return metrics
+}
+
+// join splits the name on the separator and joins the levels with the new
+// separator, the levels deeper than the maximum depth are left as is.
+func (f *Flatten) join(name, sep, newSep string) string {
+	n := -1
+	if f.MaxDepth > 0 {
+		n = f.MaxDepth
+	}
+	return strings.Join(strings.SplitN(name, sep, n), newSep)
+}
+
+func (f *Flatten) rename(m telegraf.Metric, field *telegraf.Field, key string) {
+	if key == field.Key {
+		return
+	}
+	m.RemoveField(field.Key)
+	m.AddField(key, field.Value)
+}
+
+// addValue adds the fields of the JSON value at the depth, the values deeper
+// than the maximum depth are added as JSON strings.
+func (f *Flatten) addValue(m telegraf.Metric, key string, depth int, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if f.MaxDepth > 0 && depth >= f.MaxDepth {
+			f.addJSON(m, key, v)
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			f.addValue(m, key+f.Separator+k, depth+1, v[k])
+		}
+	case []interface{}:
+		if f.MaxDepth > 0 && depth >= f.MaxDepth {
+			f.addJSON(m, key, v)
+			return
+		}
+		for i, e := range v {
+			f.addValue(m, key+f.Separator+strconv.Itoa(i), depth+1, e)
+		}
+	case nil:
+		// null values have no field
+	default:
+		m.AddField(key, v)
+	}
+}
+
+func (f *Flatten) addJSON(m telegraf.Metric, key string, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	m.AddField(key, string(b))
+}
+
+// parseDocument returns the JSON object or array of the string value.
+func parseDocument(value interface{}) (interface{}, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, false
+	}
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") && !strings.HasPrefix(s, "[") {
+		return nil, false
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+func init() {
+	processors.Add("flatten", func() telegraf.Processor {
+		return &Flatten{
+			Mode:            "flatten",
+			Fields:          []string{"*"},
+			Separator:       "_",
+			NestedSeparator: ".",
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package flatten
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newFlatten() *Flatten {
+	return &Flatten{
+		Mode:            "flatten",
+		Fields:          []string{"*"},
+		Separator:       "_",
+		NestedSeparator: ".",
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name     string
+		flatten  func(f *Flatten)
+		fields   map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "json object",
+			fields: map[string]interface{}{
+				"doc": `{"a": 1, "b": {"c": "x", "d": true, "e": null}}`,
+			},
+			expected: map[string]interface{}{
+				"doc_a":   1.0,
+				"doc_b_c": "x",
+				"doc_b_d": true,
+			},
+		},
+		{
+			name: "json array",
+			fields: map[string]interface{}{
+				"doc": `[1, [2, 3]]`,
+			},
+			expected: map[string]interface{}{
+				"doc_0":   1.0,
+				"doc_1_0": 2.0,
+				"doc_1_1": 3.0,
+			},
+		},
+		{
+			name: "nested names",
+			fields: map[string]interface{}{
+				"a.b.c": 42,
+				"value": "plain",
+			},
+			expected: map[string]interface{}{
+				"a_b_c": 42,
+				"value": "plain",
+			},
+		},
+		{
+			name:    "max depth",
+			flatten: func(f *Flatten) { f.MaxDepth = 2 },
+			fields: map[string]interface{}{
+				"doc":   `{"a": {"b": [1, 2]}, "c": 3}`,
+				"x.y.z": 1,
+			},
+			expected: map[string]interface{}{
+				"doc_a": `{"b":[1,2]}`,
+				"doc_c": 3.0,
+				"x_y.z": 1,
+			},
+		},
+		{
+			name:    "field filter",
+			flatten: func(f *Flatten) { f.Fields = []string{"doc"} },
+			fields: map[string]interface{}{
+				"doc":   `{"a": 1}`,
+				"other": `{"a": 1}`,
+			},
+			expected: map[string]interface{}{
+				"doc_a": 1.0,
+				"other": `{"a": 1}`,
+			},
+		},
+		{
+			name:    "unflatten",
+			flatten: func(f *Flatten) { f.Mode = "unflatten"; f.MaxDepth = 2 },
+			fields: map[string]interface{}{
+				"a_b":   1,
+				"c_d_e": 2,
+				"f":     3,
+			},
+			expected: map[string]interface{}{
+				"a.b":   1,
+				"c.d_e": 2,
+				"f":     3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFlatten()
+			if tt.flatten != nil {
+				tt.flatten(f)
+			}
+			require.NoError(t, f.Init())
+
+			now := time.Now()
+			m := testutil.MustMetric("test", map[string]string{}, tt.fields, now)
+			expected := []telegraf.Metric{
+				testutil.MustMetric("test", map[string]string{}, tt.expected, now),
+			}
+			testutil.RequireMetricsEqual(t, expected, f.Apply(m))
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	f := newFlatten()
+	f.Mode = "nest"
+	require.Error(t, f.Init())
+
+	f = newFlatten()
+	f.NestedSeparator = "_"
+	require.Error(t, f.Init())
+
+	f = newFlatten()
+	f.MaxDepth = -1
+	require.Error(t, f.Init())
+}
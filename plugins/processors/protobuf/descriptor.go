@@ -0,0 +1,482 @@
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Wire types of the protocol buffers encoding.
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireGroupStart = 3
+	wireGroupEnd   = 4
+	wireFixed32    = 5
+)
+
+// Types of the fields of FieldDescriptorProto.
+const (
+	typeDouble   = 1
+	typeFloat    = 2
+	typeInt64    = 3
+	typeUint64   = 4
+	typeInt32    = 5
+	typeFixed64  = 6
+	typeFixed32  = 7
+	typeBool     = 8
+	typeString   = 9
+	typeGroup    = 10
+	typeMessage  = 11
+	typeBytes    = 12
+	typeUint32   = 13
+	typeEnum     = 14
+	typeSfixed32 = 15
+	typeSfixed64 = 16
+	typeSint32   = 17
+	typeSint64   = 18
+)
+
+const labelRepeated = 3
+
+var errTruncated = errors.New("truncated message")
+
+// reader reads the fields of a message in the protocol buffers encoding.
+type reader struct {
+	buf []byte
+}
+
+func (r *reader) done() bool {
+	return len(r.buf) == 0
+}
+
+func (r *reader) varint() (uint64, error) {
+	var v uint64
+	for i := 0; i < 10; i++ {
+		if i >= len(r.buf) {
+			return 0, errTruncated
+		}
+		b := r.buf[i]
+		v |= uint64(b&0x7f) << (7 * uint(i))
+		if b < 0x80 {
+			r.buf = r.buf[i+1:]
+			return v, nil
+		}
+	}
+	return 0, errors.New("varint overflow")
+}
+
+func (r *reader) fixed(n int) (uint64, error) {
+	if len(r.buf) < n {
+		return 0, errTruncated
+	}
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(r.buf[i])
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *reader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.buf)) < n {
+		return nil, errTruncated
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+// key reads the number and the wire type of the next field.
+func (r *reader) key() (uint64, int, error) {
+	k, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return k >> 3, int(k & 7), nil
+}
+
+// skip skips the value of a field of the wire type.
+func (r *reader) skip(wire int) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed(8)
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		_, err = r.fixed(4)
+	case wireGroupStart:
+		for {
+			var w int
+			_, w, err = r.key()
+			if err != nil || w == wireGroupEnd {
+				break
+			}
+			if err = r.skip(w); err != nil {
+				break
+			}
+		}
+	default:
+		err = fmt.Errorf("invalid wire type %d", wire)
+	}
+	return err
+}
+
+// fields calls fn for each field of the message, fn reads the value of the
+// field or returns false to skip it.
+func (r *reader) fields(fn func(number uint64, wire int) (bool, error)) error {
+	for !r.done() {
+		number, wire, err := r.key()
+		if err != nil {
+			return err
+		}
+		read, err := fn(number, wire)
+		if err != nil {
+			return err
+		}
+		if !read {
+			if err := r.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type fieldDesc struct {
+	name     string
+	number   uint64
+	repeated bool
+	typ      uint64
+	// typeName is the fully qualified name of the message or of the enum of
+	// the field, without the leading dot.
+	typeName string
+}
+
+type messageDesc struct {
+	name   string
+	fields map[uint64]*fieldDesc
+	byName map[string]*fieldDesc
+}
+
+// descriptors are the messages and enums of a descriptor set, by fully
+// qualified name.
+type descriptors struct {
+	messages map[string]*messageDesc
+	enums    map[string]map[int64]string
+}
+
+// parseDescriptorSet parses a FileDescriptorSet, as generated by protoc with
+// --descriptor_set_out.
+func parseDescriptorSet(buf []byte) (*descriptors, error) {
+	d := &descriptors{
+		messages: make(map[string]*messageDesc),
+		enums:    make(map[string]map[int64]string),
+	}
+	r := &reader{buf: buf}
+	err := r.fields(func(number uint64, wire int) (bool, error) {
+		if number != 1 || wire != wireBytes {
+			return false, nil
+		}
+		file, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		return true, d.parseFile(file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *descriptors) parseFile(buf []byte) error {
+	var pkg string
+	var messages, enums [][]byte
+	r := &reader{buf: buf}
+	err := r.fields(func(number uint64, wire int) (bool, error) {
+		if wire != wireBytes {
+			return false, nil
+		}
+		var b []byte
+		var err error
+		switch number {
+		case 2:
+			b, err = r.bytes()
+			pkg = string(b)
+		case 4:
+			b, err = r.bytes()
+			messages = append(messages, b)
+		case 5:
+			b, err = r.bytes()
+			enums = append(enums, b)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The package is not always the first field of the file.
+	for _, m := range messages {
+		if err := d.parseMessage(pkg, m); err != nil {
+			return err
+		}
+	}
+	for _, e := range enums {
+		if err := d.parseEnum(pkg, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *descriptors) parseMessage(scope string, buf []byte) error {
+	m := &messageDesc{
+		fields: make(map[uint64]*fieldDesc),
+		byName: make(map[string]*fieldDesc),
+	}
+	var nested, enums [][]byte
+	r := &reader{buf: buf}
+	err := r.fields(func(number uint64, wire int) (bool, error) {
+		if wire != wireBytes {
+			return false, nil
+		}
+		b, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		switch number {
+		case 1:
+			m.name = string(b)
+		case 2:
+			f, err := parseField(b)
+			if err != nil {
+				return true, err
+			}
+			m.fields[f.number] = f
+			m.byName[f.name] = f
+		case 3:
+			nested = append(nested, b)
+		case 4:
+			enums = append(enums, b)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	name := join(scope, m.name)
+	d.messages[name] = m
+	for _, n := range nested {
+		if err := d.parseMessage(name, n); err != nil {
+			return err
+		}
+	}
+	for _, e := range enums {
+		if err := d.parseEnum(name, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseField(buf []byte) (*fieldDesc, error) {
+	f := &fieldDesc{}
+	r := &reader{buf: buf}
+	err := r.fields(func(number uint64, wire int) (bool, error) {
+		switch {
+		case number == 1 && wire == wireBytes:
+			b, err := r.bytes()
+			f.name = string(b)
+			return true, err
+		case number == 6 && wire == wireBytes:
+			b, err := r.bytes()
+			f.typeName = strings.TrimPrefix(string(b), ".")
+			return true, err
+		case wire == wireVarint:
+			v, err := r.varint()
+			switch number {
+			case 3:
+				f.number = v
+			case 4:
+				f.repeated = v == labelRepeated
+			case 5:
+				f.typ = v
+			}
+			return true, err
+		}
+		return false, nil
+	})
+	return f, err
+}
+
+func (d *descriptors) parseEnum(scope string, buf []byte) error {
+	var name string
+	values := make(map[int64]string)
+	r := &reader{buf: buf}
+	err := r.fields(func(number uint64, wire int) (bool, error) {
+		if wire != wireBytes {
+			return false, nil
+		}
+		b, err := r.bytes()
+		if err != nil {
+			return true, err
+		}
+		switch number {
+		case 1:
+			name = string(b)
+		case 2:
+			var valueName string
+			var value int64
+			vr := &reader{buf: b}
+			err := vr.fields(func(number uint64, wire int) (bool, error) {
+				switch {
+				case number == 1 && wire == wireBytes:
+					b, err := vr.bytes()
+					valueName = string(b)
+					return true, err
+				case number == 2 && wire == wireVarint:
+					v, err := vr.varint()
+					value = int64(int32(v))
+					return true, err
+				}
+				return false, nil
+			})
+			if err != nil {
+				return true, err
+			}
+			values[value] = valueName
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	d.enums[join(scope, name)] = values
+	return nil
+}
+
+func join(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// scalar decodes a scalar value of the wire type into the value of a field
+// of a metric.
+func (d *descriptors) scalar(r *reader, f *fieldDesc, wire int) (interface{}, error) {
+	switch f.typ {
+	case typeDouble, typeFixed64, typeSfixed64:
+		if wire != wireFixed64 {
+			break
+		}
+		v, err := r.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		switch f.typ {
+		case typeDouble:
+			return math.Float64frombits(v), nil
+		case typeFixed64:
+			return v, nil
+		default:
+			return int64(v), nil
+		}
+	case typeFloat, typeFixed32, typeSfixed32:
+		if wire != wireFixed32 {
+			break
+		}
+		v, err := r.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		switch f.typ {
+		case typeFloat:
+			return float64(math.Float32frombits(uint32(v))), nil
+		case typeFixed32:
+			return v, nil
+		default:
+			return int64(int32(v)), nil
+		}
+	case typeInt64, typeInt32, typeUint64, typeUint32, typeBool, typeEnum, typeSint32, typeSint64:
+		if wire != wireVarint {
+			break
+		}
+		v, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		switch f.typ {
+		case typeInt64:
+			return int64(v), nil
+		case typeInt32:
+			return int64(int32(v)), nil
+		case typeUint64, typeUint32:
+			return v, nil
+		case typeBool:
+			return v != 0, nil
+		case typeEnum:
+			if name, ok := d.enums[f.typeName][int64(int32(v))]; ok {
+				return name, nil
+			}
+			return int64(int32(v)), nil
+		default:
+			return int64(v>>1) ^ -int64(v&1), nil
+		}
+	case typeString:
+		if wire != wireBytes {
+			break
+		}
+		b, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	return nil, fmt.Errorf("field %s: unexpected wire type %d", f.name, wire)
+}
+
+// packable tells if the repeated values of a field may be packed.
+func packable(f *fieldDesc) bool {
+	switch f.typ {
+	case typeString, typeBytes, typeMessage, typeGroup:
+		return false
+	}
+	return true
+}
+
+// zero is the default value of a scalar field absent from a message.
+func (d *descriptors) zero(f *fieldDesc) interface{} {
+	switch f.typ {
+	case typeDouble, typeFloat:
+		return 0.0
+	case typeUint64, typeUint32, typeFixed64, typeFixed32:
+		return uint64(0)
+	case typeBool:
+		return false
+	case typeString:
+		return ""
+	case typeEnum:
+		if name, ok := d.enums[f.typeName][0]; ok {
+			return name
+		}
+		return int64(0)
+	case typeMessage, typeGroup, typeBytes:
+		return nil
+	}
+	return int64(0)
+}
@@ -0,0 +1,296 @@
+package protobuf
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const (
+	description  = "Extract the fields of a protocol buffers message held in a field"
+	sampleConfig = `
+  ## Field holding the encoded message.
+  field = "payload"
+
+  ## Encoding of the message in the field, "binary" if the string field
+  ## holds the bytes of the message, "base64" or "hex".
+  # encoding = "binary"
+
+  ## Descriptor set of the message, generated from the .proto files with
+  ##   protoc --include_imports --descriptor_set_out=sensors.pb sensors.proto
+  descriptor_set = "/etc/telegraf/sensors.pb"
+
+  ## Fully qualified name of the message.
+  message_type = "sensors.Reading"
+
+  ## Fields of the message extracted into fields, by path of the names of
+  ## the fields separated by dots, such as "location.latitude".  A message
+  ## field extracts all its sub-fields.  All the fields of the message are
+  ## extracted if empty.
+  # fields = []
+
+  ## Fields of the message extracted into tags.
+  # tags = []
+
+  ## Separator of the names of the nested fields and of the indexes of the
+  ## repeated fields in the names of the extracted fields.
+  # separator = "_"
+
+  ## Keep the field holding the message once it is decoded.
+  # keep_original_field = false
+`
+)
+
+type Protobuf struct {
+	Field             string   `toml:"field"`
+	Encoding          string   `toml:"encoding"`
+	DescriptorSet     string   `toml:"descriptor_set"`
+	MessageType       string   `toml:"message_type"`
+	Fields            []string `toml:"fields"`
+	Tags              []string `toml:"tags"`
+	Separator         string   `toml:"separator"`
+	KeepOriginalField bool     `toml:"keep_original_field"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	descriptors *descriptors
+	message     *messageDesc
+	// selected are the paths of the fields, and their descriptor.
+	selected map[string]*fieldDesc
+}
+
+func (p *Protobuf) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Protobuf) Description() string {
+	return description
+}
+
+func (p *Protobuf) Init() error {
+	if p.Field == "" {
+		return errors.New("field must be set")
+	}
+	switch p.Encoding {
+	case "binary", "base64", "hex":
+	default:
+		return fmt.Errorf("invalid encoding %q", p.Encoding)
+	}
+
+	buf, err := ioutil.ReadFile(p.DescriptorSet)
+	if err != nil {
+		return err
+	}
+	p.descriptors, err = parseDescriptorSet(buf)
+	if err != nil {
+		return fmt.Errorf("invalid descriptor set %s: %v", p.DescriptorSet, err)
+	}
+	var ok bool
+	p.message, ok = p.descriptors.messages[p.MessageType]
+	if !ok {
+		return fmt.Errorf("message %s not found in %s", p.MessageType, p.DescriptorSet)
+	}
+
+	p.selected = make(map[string]*fieldDesc)
+	for _, path := range append(p.Fields, p.Tags...) {
+		f, err := p.resolve(path)
+		if err != nil {
+			return err
+		}
+		p.selected[path] = f
+	}
+	return nil
+}
+
+// resolve returns the descriptor of the field of a path.
+func (p *Protobuf) resolve(path string) (*fieldDesc, error) {
+	m := p.message
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		f, ok := m.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("field %s: no field %s in %s", path, name, m.name)
+		}
+		if i == len(names)-1 {
+			return f, nil
+		}
+		if f.typ != typeMessage || f.repeated {
+			return nil, fmt.Errorf("field %s: %s is not a message", path, name)
+		}
+		if m, ok = p.descriptors.messages[f.typeName]; !ok {
+			return nil, fmt.Errorf("field %s: message %s not found", path, f.typeName)
+		}
+	}
+	return nil, fmt.Errorf("invalid field %q", path)
+}
+
+func (p *Protobuf) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range metrics {
+		value, ok := m.GetField(p.Field)
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			p.Log.Errorf("Field %s is not a string", p.Field)
+			continue
+		}
+
+		buf, err := p.decodeField(s)
+		if err != nil {
+			p.Log.Errorf("Decoding field %s: %v", p.Field, err)
+			continue
+		}
+		values := make(map[string]interface{})
+		if err := p.decode(p.message, buf, "", values); err != nil {
+			p.Log.Errorf("Decoding %s: %v", p.MessageType, err)
+			continue
+		}
+
+		if !p.KeepOriginalField {
+			m.RemoveField(p.Field)
+		}
+		p.addValues(m, values)
+	}
+	return metrics
+}
+
+func (p *Protobuf) decodeField(s string) ([]byte, error) {
+	switch p.Encoding {
+	case "base64":
+		return base64.StdEncoding.DecodeString(s)
+	case "hex":
+		return hex.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// decode decodes the scalar fields of a message into values by path, the
+// indexes of the repeated fields being path elements.
+func (p *Protobuf) decode(m *messageDesc, buf []byte, prefix string, values map[string]interface{}) error {
+	counts := make(map[uint64]int)
+	r := &reader{buf: buf}
+	return r.fields(func(number uint64, wire int) (bool, error) {
+		f, ok := m.fields[number]
+		if !ok {
+			return false, nil
+		}
+
+		path := join(prefix, f.name)
+		// key returns the path of the next value of the field.
+		key := func() string {
+			if !f.repeated {
+				return path
+			}
+			key := path + "." + strconv.Itoa(counts[number])
+			counts[number]++
+			return key
+		}
+
+		switch {
+		case f.typ == typeMessage:
+			if wire != wireBytes {
+				return false, fmt.Errorf("field %s: unexpected wire type %d", f.name, wire)
+			}
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			nested, ok := p.descriptors.messages[f.typeName]
+			if !ok {
+				return true, nil
+			}
+			return true, p.decode(nested, b, key(), values)
+		case f.typ == typeBytes || f.typ == typeGroup:
+			// The bytes have no field type, the groups are deprecated.
+			return false, nil
+		case f.repeated && wire == wireBytes && packable(f):
+			b, err := r.bytes()
+			if err != nil {
+				return true, err
+			}
+			packed := &reader{buf: b}
+			wire := wireVarint
+			switch f.typ {
+			case typeDouble, typeFixed64, typeSfixed64:
+				wire = wireFixed64
+			case typeFloat, typeFixed32, typeSfixed32:
+				wire = wireFixed32
+			}
+			for !packed.done() {
+				v, err := p.descriptors.scalar(packed, f, wire)
+				if err != nil {
+					return true, err
+				}
+				values[key()] = v
+			}
+			return true, nil
+		}
+
+		v, err := p.descriptors.scalar(r, f, wire)
+		if err != nil {
+			return true, err
+		}
+		values[key()] = v
+		return true, nil
+	})
+}
+
+// addValues adds the selected values to the fields and the tags of the
+// metric.
+func (p *Protobuf) addValues(m telegraf.Metric, values map[string]interface{}) {
+	name := func(path string) string {
+		return strings.Replace(path, ".", p.Separator, -1)
+	}
+
+	if len(p.Fields) == 0 {
+		for path, v := range values {
+			m.AddField(name(path), v)
+		}
+	}
+	for _, path := range p.Fields {
+		p.addSelected(path, values, func(path string, v interface{}) {
+			m.AddField(name(path), v)
+		})
+	}
+	for _, path := range p.Tags {
+		p.addSelected(path, values, func(path string, v interface{}) {
+			m.AddTag(name(path), fmt.Sprint(v))
+		})
+	}
+}
+
+// addSelected calls add with the values of a selected path, the values of
+// its sub-fields and of its repeated values.  The proto3 messages omit the
+// fields of default value, so the default value of a scalar field is used if
+// absent.
+func (p *Protobuf) addSelected(path string, values map[string]interface{}, add func(string, interface{})) {
+	found := false
+	for key, v := range values {
+		if key == path || strings.HasPrefix(key, path+".") {
+			add(key, v)
+			found = true
+		}
+	}
+	if f := p.selected[path]; !found && !f.repeated {
+		if v := p.descriptors.zero(f); v != nil {
+			add(path, v)
+		}
+	}
+}
+
+func init() {
+	processors.Add("protobuf", func() telegraf.Processor {
+		return &Protobuf{
+			Encoding:  "binary",
+			Separator: "_",
+		}
+	})
+}
@@ -0,0 +1,298 @@
+package protobuf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// encoder encodes the fields of a message.
+type encoder []byte
+
+func (e encoder) varint(number int, v uint64) encoder {
+	e = appendVarint(e, uint64(number)<<3|wireVarint)
+	return appendVarint(e, v)
+}
+
+func (e encoder) double(number int, v float64) encoder {
+	e = appendVarint(e, uint64(number)<<3|wireFixed64)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	return append(e, b[:]...)
+}
+
+func (e encoder) float(number int, v float32) encoder {
+	e = appendVarint(e, uint64(number)<<3|wireFixed32)
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], math.Float32bits(v))
+	return append(e, b[:]...)
+}
+
+func (e encoder) bytes(number int, b []byte) encoder {
+	e = appendVarint(e, uint64(number)<<3|wireBytes)
+	e = appendVarint(e, uint64(len(b)))
+	return append(e, b...)
+}
+
+func (e encoder) string(number int, s string) encoder {
+	return e.bytes(number, []byte(s))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func field(name string, number, typ int, typeName string, repeated bool) []byte {
+	e := encoder{}.string(1, name).varint(3, uint64(number))
+	if repeated {
+		e = e.varint(4, labelRepeated)
+	} else {
+		e = e.varint(4, 1)
+	}
+	e = e.varint(5, uint64(typ))
+	if typeName != "" {
+		e = e.string(6, typeName)
+	}
+	return e
+}
+
+// descriptorSet is the descriptor set of:
+//
+//	package sensors;
+//
+//	enum Status { UNKNOWN = 0; OK = 1; FAILED = 2; }
+//
+//	message Location { double latitude = 1; double longitude = 2; }
+//
+//	message Reading {
+//	  message Alarm { string name = 1; int64 code = 2; }
+//	  string device_id = 1;
+//	  double temperature = 2;
+//	  Location location = 3;
+//	  repeated sint32 samples = 4;
+//	  Status status = 5;
+//	  repeated Alarm alarms = 6;
+//	  uint32 battery = 7;
+//	  bytes raw = 8;
+//	  bool ok = 9;
+//	  float humidity = 10;
+//	}
+func descriptorSet() []byte {
+	alarm := encoder{}.string(1, "Alarm").
+		bytes(2, field("name", 1, typeString, "", false)).
+		bytes(2, field("code", 2, typeInt64, "", false))
+	reading := encoder{}.string(1, "Reading").
+		bytes(2, field("device_id", 1, typeString, "", false)).
+		bytes(2, field("temperature", 2, typeDouble, "", false)).
+		bytes(2, field("location", 3, typeMessage, ".sensors.Location", false)).
+		bytes(2, field("samples", 4, typeSint32, "", true)).
+		bytes(2, field("status", 5, typeEnum, ".sensors.Status", false)).
+		bytes(2, field("alarms", 6, typeMessage, ".sensors.Reading.Alarm", true)).
+		bytes(2, field("battery", 7, typeUint32, "", false)).
+		bytes(2, field("raw", 8, typeBytes, "", false)).
+		bytes(2, field("ok", 9, typeBool, "", false)).
+		bytes(2, field("humidity", 10, typeFloat, "", false)).
+		bytes(3, alarm)
+	location := encoder{}.string(1, "Location").
+		bytes(2, field("latitude", 1, typeDouble, "", false)).
+		bytes(2, field("longitude", 2, typeDouble, "", false))
+	status := encoder{}.string(1, "Status").
+		bytes(2, encoder{}.string(1, "UNKNOWN").varint(2, 0)).
+		bytes(2, encoder{}.string(1, "OK").varint(2, 1)).
+		bytes(2, encoder{}.string(1, "FAILED").varint(2, 2))
+	file := encoder{}.string(1, "sensors.proto").
+		bytes(4, reading).
+		bytes(4, location).
+		bytes(5, status).
+		string(2, "sensors")
+	return encoder{}.bytes(1, file)
+}
+
+// reading is an encoded sensors.Reading.
+func reading() []byte {
+	var samples encoder
+	for _, v := range []int64{1, -2, 300} {
+		samples = appendVarint(samples, uint64(v<<1^v>>63))
+	}
+	return encoder{}.
+		string(1, "sensor-1").
+		double(2, 21.5).
+		bytes(3, encoder{}.double(1, 48.85).double(2, 2.35)).
+		bytes(4, samples).
+		varint(5, 2).
+		bytes(6, encoder{}.string(1, "overheat").varint(2, 7)).
+		bytes(6, encoder{}.string(1, "door")).
+		varint(7, 87).
+		bytes(8, []byte{0xde, 0xad}).
+		varint(9, 1).
+		float(10, 40.5).
+		// Unknown field of a newer version of the message.
+		string(42, "unknown")
+}
+
+func newProtobuf(t *testing.T) *Protobuf {
+	f, err := ioutil.TempFile("", "descriptor")
+	require.NoError(t, err)
+	_, err = f.Write(descriptorSet())
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	p := &Protobuf{
+		Field:         "payload",
+		Encoding:      "binary",
+		DescriptorSet: f.Name(),
+		MessageType:   "sensors.Reading",
+		Separator:     "_",
+		Log:           testutil.Logger{},
+	}
+	return p
+}
+
+func TestAllFields(t *testing.T) {
+	p := newProtobuf(t)
+	defer os.Remove(p.DescriptorSet)
+	require.NoError(t, p.Init())
+
+	m := testutil.MustMetric("kafka",
+		map[string]string{},
+		map[string]interface{}{"payload": string(reading())},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("kafka",
+			map[string]string{},
+			map[string]interface{}{
+				"device_id":          "sensor-1",
+				"temperature":        21.5,
+				"location_latitude":  48.85,
+				"location_longitude": 2.35,
+				"samples_0":          int64(1),
+				"samples_1":          int64(-2),
+				"samples_2":          int64(300),
+				"status":             "FAILED",
+				"alarms_0_name":      "overheat",
+				"alarms_0_code":      int64(7),
+				"alarms_1_name":      "door",
+				"battery":            uint64(87),
+				"ok":                 true,
+				"humidity":           40.5,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, p.Apply(m))
+}
+
+func TestSelectedFields(t *testing.T) {
+	p := newProtobuf(t)
+	defer os.Remove(p.DescriptorSet)
+	p.Encoding = "base64"
+	p.Fields = []string{"temperature", "location", "alarms", "status"}
+	p.Tags = []string{"device_id"}
+	p.KeepOriginalField = true
+	require.NoError(t, p.Init())
+
+	payload := base64.StdEncoding.EncodeToString(reading())
+	m := testutil.MustMetric("mqtt",
+		map[string]string{},
+		map[string]interface{}{"payload": payload},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("mqtt",
+			map[string]string{"device_id": "sensor-1"},
+			map[string]interface{}{
+				"payload":            payload,
+				"temperature":        21.5,
+				"location_latitude":  48.85,
+				"location_longitude": 2.35,
+				"alarms_0_name":      "overheat",
+				"alarms_0_code":      int64(7),
+				"alarms_1_name":      "door",
+				"status":             "FAILED",
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, p.Apply(m))
+}
+
+func TestDefaultValues(t *testing.T) {
+	p := newProtobuf(t)
+	defer os.Remove(p.DescriptorSet)
+	p.Encoding = "hex"
+	p.Fields = []string{"temperature", "location.latitude", "status", "ok", "samples"}
+	require.NoError(t, p.Init())
+
+	// An empty message, all its fields have the default value.
+	m := testutil.MustMetric("mqtt",
+		map[string]string{},
+		map[string]interface{}{"payload": ""},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("mqtt",
+			map[string]string{},
+			map[string]interface{}{
+				"temperature":       0.0,
+				"location_latitude": 0.0,
+				"status":            "UNKNOWN",
+				"ok":                false,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, p.Apply(m))
+}
+
+func TestInvalidMessage(t *testing.T) {
+	p := newProtobuf(t)
+	defer os.Remove(p.DescriptorSet)
+	require.NoError(t, p.Init())
+
+	// The message is truncated, the metric is left unchanged.
+	payload := string(reading()[:20])
+	m := testutil.MustMetric("kafka",
+		map[string]string{},
+		map[string]interface{}{"payload": payload},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{m.Copy()}
+	testutil.RequireMetricsEqual(t, expected, p.Apply(m))
+}
+
+func TestInit(t *testing.T) {
+	p := newProtobuf(t)
+	defer os.Remove(p.DescriptorSet)
+
+	p.MessageType = "sensors.Unknown"
+	require.Error(t, p.Init())
+
+	p.MessageType = "sensors.Reading"
+	p.Fields = []string{"location.altitude"}
+	require.Error(t, p.Init())
+
+	p.Fields = []string{"temperature.value"}
+	require.Error(t, p.Init())
+
+	p.Fields = []string{"location.latitude"}
+	p.Encoding = "base32"
+	require.Error(t, p.Init())
+
+	p.Encoding = "binary"
+	require.NoError(t, p.Init())
+	require.Contains(t, p.descriptors.messages, "sensors.Reading.Alarm")
+	require.Contains(t, p.descriptors.messages, "sensors.Location")
+}
@@ -2,6 +2,10 @@ package strings
 
 import (
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"regexp"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -21,6 +25,11 @@ type Strings struct {
 	Replace      []converter `toml:"replace"`
 	Left         []converter `toml:"left"`
 	Base64Decode []converter `toml:"base64decode"`
+	HexDecode    []converter `toml:"hex_decode"`
+	URLDecode    []converter `toml:"url_decode"`
+	JSONUnescape []converter `toml:"json_unescape"`
+	Substring    []converter `toml:"substring"`
+	Split        []converter `toml:"split"`
 
 	converters []converter
 	init       bool
@@ -42,7 +51,14 @@ type converter struct {
 	New         string
 	Width       int
 
+	CaseInsensitive bool
+	Delimiter       string
+	Index           int
+	Keys            []string
+
 	fn ConvertFunc
+	// split adds the values split from the value to the metric.
+	split func(metric telegraf.Metric, value string)
 }
 
 const sampleConfig = `
@@ -79,11 +95,13 @@ const sampleConfig = `
   #   field = "read_count"
   #   suffix = "_count"
 
-  ## Replace all non-overlapping instances of old with new
+  ## Replace all non-overlapping instances of old with new, optionally
+  ## ignoring the case of old
   # [[processors.strings.replace]]
   #   measurement = "*"
   #   old = ":"
   #   new = "_"
+  #   case_insensitive = false
 
   ## Trims strings based on width
   # [[processors.strings.left]]
@@ -93,6 +111,32 @@ const sampleConfig = `
   ## Decode a base64 encoded utf-8 string
   # [[processors.strings.base64decode]]
   #   field = "message"
+
+  ## Decode a hex encoded utf-8 string
+  # [[processors.strings.hex_decode]]
+  #   field = "serial"
+
+  ## Decode a URL query encoded string
+  # [[processors.strings.url_decode]]
+  #   tag = "path"
+
+  ## Unescape the JSON escape sequences of a string
+  # [[processors.strings.json_unescape]]
+  #   field = "message"
+
+  ## Keep the part at index of the string split on the delimiter, negative
+  ## indexes count from the end
+  # [[processors.strings.substring]]
+  #   tag = "device"
+  #   delimiter = "-"
+  #   index = -1
+
+  ## Split the string on the delimiter and add the parts as tags named
+  ## after keys, empty keys skip a part
+  # [[processors.strings.split]]
+  #   tag = "device"
+  #   delimiter = "-"
+  #   keys = ["site", "", "rack"]
 `
 
 func (s *Strings) SampleConfig() string {
@@ -117,6 +161,10 @@ func (c *converter) convertTag(metric telegraf.Metric) {
 	}
 
 	for key, value := range tags {
+		if c.split != nil {
+			c.split(metric, value)
+			continue
+		}
 		dest := key
 		if c.Tag != "*" && c.Dest != "" {
 			dest = c.Dest
@@ -160,13 +208,19 @@ func (c *converter) convertField(metric telegraf.Metric) {
 	}
 
 	for key, value := range fields {
+		fv, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if c.split != nil {
+			c.split(metric, fv)
+			continue
+		}
 		dest := key
 		if c.Field != "*" && c.Dest != "" {
 			dest = c.Dest
 		}
-		if fv, ok := value.(string); ok {
-			metric.AddField(dest, c.fn(fv))
-		}
+		metric.AddField(dest, c.fn(fv))
 	}
 }
 
@@ -204,6 +258,14 @@ func (c *converter) convert(metric telegraf.Metric) {
 		c.convertField(metric)
 	}
 
+	// Splitting only applies to the values of tags and fields
+	if c.split != nil {
+		if c.Tag != "" {
+			c.convertTag(metric)
+		}
+		return
+	}
+
 	if c.FieldKey != "" {
 		c.convertFieldKey(metric)
 	}
@@ -274,8 +336,17 @@ func (s *Strings) initOnce() {
 	}
 	for _, c := range s.Replace {
 		c := c
+		var re *regexp.Regexp
+		if c.CaseInsensitive {
+			re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(c.Old))
+		}
 		c.fn = func(s string) string {
-			newString := strings.Replace(s, c.Old, c.New, -1)
+			var newString string
+			if re != nil {
+				newString = re.ReplaceAllLiteralString(s, c.New)
+			} else {
+				newString = strings.Replace(s, c.Old, c.New, -1)
+			}
 			if newString == "" {
 				return s
 			} else {
@@ -309,6 +380,72 @@ func (s *Strings) initOnce() {
 		}
 		s.converters = append(s.converters, c)
 	}
+	for _, c := range s.HexDecode {
+		c := c
+		c.fn = func(s string) string {
+			data, err := hex.DecodeString(s)
+			if err != nil {
+				return s
+			}
+			if utf8.Valid(data) {
+				return string(data)
+			}
+			return s
+		}
+		s.converters = append(s.converters, c)
+	}
+	for _, c := range s.URLDecode {
+		c := c
+		c.fn = func(s string) string {
+			decoded, err := url.QueryUnescape(s)
+			if err != nil {
+				return s
+			}
+			return decoded
+		}
+		s.converters = append(s.converters, c)
+	}
+	for _, c := range s.JSONUnescape {
+		c := c
+		c.fn = func(s string) string {
+			var unescaped string
+			if err := json.Unmarshal([]byte(`"`+s+`"`), &unescaped); err != nil {
+				return s
+			}
+			return unescaped
+		}
+		s.converters = append(s.converters, c)
+	}
+	for _, c := range s.Substring {
+		c := c
+		c.fn = func(s string) string {
+			parts := strings.Split(s, c.Delimiter)
+			i := c.Index
+			if i < 0 {
+				i += len(parts)
+			}
+			if i < 0 || i >= len(parts) {
+				return s
+			}
+			return parts[i]
+		}
+		s.converters = append(s.converters, c)
+	}
+	for _, c := range s.Split {
+		c := c
+		c.split = func(metric telegraf.Metric, value string) {
+			parts := strings.Split(value, c.Delimiter)
+			for i, key := range c.Keys {
+				if i >= len(parts) {
+					break
+				}
+				if key != "" && parts[i] != "" {
+					metric.AddTag(key, parts[i])
+				}
+			}
+		}
+		s.converters = append(s.converters, c)
+	}
 
 	s.init = true
 }
@@ -1000,3 +1000,115 @@ func TestBase64Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeAndExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Strings
+		value    string
+		expected string
+	}{
+		{
+			name:     "hex_decode",
+			plugin:   &Strings{HexDecode: []converter{{Field: "message"}}},
+			value:    "686f776479",
+			expected: "howdy",
+		},
+		{
+			name:     "hex_decode not valid hex returns original string",
+			plugin:   &Strings{HexDecode: []converter{{Field: "message"}}},
+			value:    "howdy",
+			expected: "howdy",
+		},
+		{
+			name:     "url_decode",
+			plugin:   &Strings{URLDecode: []converter{{Field: "message"}}},
+			value:    "dev%2F01+a",
+			expected: "dev/01 a",
+		},
+		{
+			name:     "json_unescape",
+			plugin:   &Strings{JSONUnescape: []converter{{Field: "message"}}},
+			value:    `line\n\"quoted\"\u00e9`,
+			expected: "line\n\"quoted\"é",
+		},
+		{
+			name:     "json_unescape not valid returns original string",
+			plugin:   &Strings{JSONUnescape: []converter{{Field: "message"}}},
+			value:    `bad\x`,
+			expected: `bad\x`,
+		},
+		{
+			name:     "substring",
+			plugin:   &Strings{Substring: []converter{{Field: "message", Delimiter: "-", Index: 1}}},
+			value:    "par-rack07-u12",
+			expected: "rack07",
+		},
+		{
+			name:     "substring from the end",
+			plugin:   &Strings{Substring: []converter{{Field: "message", Delimiter: "-", Index: -1}}},
+			value:    "par-rack07-u12",
+			expected: "u12",
+		},
+		{
+			name:     "substring out of range returns original string",
+			plugin:   &Strings{Substring: []converter{{Field: "message", Delimiter: "-", Index: 3}}},
+			value:    "par-rack07-u12",
+			expected: "par-rack07-u12",
+		},
+		{
+			name:     "case insensitive replace",
+			plugin:   &Strings{Replace: []converter{{Field: "message", Old: "eth", New: "if", CaseInsensitive: true}}},
+			value:    "ETH0-Eth1-eth2",
+			expected: "if0-if1-if2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testutil.MustMetric("cpu", map[string]string{},
+				map[string]interface{}{"message": tt.value}, time.Unix(0, 0))
+			expected := []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{},
+					map[string]interface{}{"message": tt.expected}, time.Unix(0, 0)),
+			}
+			testutil.RequireMetricsEqual(t, expected, tt.plugin.Apply(m))
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	plugin := &Strings{
+		Split: []converter{
+			{
+				Tag:       "device",
+				Delimiter: "-",
+				Keys:      []string{"site", "", "unit", "slot"},
+			},
+			{
+				Field:     "path",
+				Delimiter: "/",
+				Keys:      []string{"", "volume"},
+			},
+		},
+	}
+
+	m := testutil.MustMetric("disk",
+		map[string]string{"device": "par-rack07-u12"},
+		map[string]interface{}{"path": "/data/x"},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{
+				"device": "par-rack07-u12",
+				"site":   "par",
+				"unit":   "u12",
+				"volume": "data",
+			},
+			map[string]interface{}{"path": "/data/x"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, plugin.Apply(m))
+}
@@ -27,6 +27,52 @@ const (
 	UintSupport FieldTypeSupport = 1 << iota
 )
 
+// Dialect is a variant of line protocol accepted by a database compatible
+// with InfluxDB, differing in the type suffixes of the field values.
+type Dialect struct {
+	// IntSuffix is the suffix of the integer field values.
+	IntSuffix string
+	// UintSuffix is the suffix of the unsigned integer field values, if
+	// empty the database has no unsigned integers and they are written as
+	// integers.
+	UintSuffix string
+	// FloatSuffix is the suffix of the float field values.
+	FloatSuffix string
+}
+
+var (
+	// InfluxDB is the line protocol of InfluxDB.
+	InfluxDB = Dialect{IntSuffix: "i", UintSuffix: "u"}
+	// GreptimeDB is the line protocol of the InfluxDB API of GreptimeDB.
+	GreptimeDB = Dialect{IntSuffix: "i", UintSuffix: "u"}
+	// OpenGemini is the line protocol of openGemini, without unsigned
+	// integers.
+	OpenGemini = Dialect{IntSuffix: "i"}
+	// TDengine is the schemaless line protocol of TDengine, where numbers
+	// without suffix are doubles and integers are sized.
+	TDengine = Dialect{IntSuffix: "i64", UintSuffix: "u64", FloatSuffix: "f64"}
+)
+
+// Dialects are the line protocol dialects by name.
+var Dialects = map[string]Dialect{
+	"influxdb":   InfluxDB,
+	"greptimedb": GreptimeDB,
+	"opengemini": OpenGemini,
+	"tdengine":   TDengine,
+}
+
+// GetDialect returns the dialect of the name, InfluxDB if empty.
+func GetDialect(name string) (Dialect, error) {
+	if name == "" {
+		return InfluxDB, nil
+	}
+	dialect, ok := Dialects[name]
+	if !ok {
+		return Dialect{}, fmt.Errorf("unknown line protocol dialect %q", name)
+	}
+	return dialect, nil
+}
+
 var (
 	NeedMoreSpace = "need more space"
 	InvalidName   = "invalid name"
@@ -61,6 +107,7 @@ type Serializer struct {
 	bytesWritten     int
 	fieldSortOrder   FieldSortOrder
 	fieldTypeSupport FieldTypeSupport
+	dialect          Dialect
 
 	buf    bytes.Buffer
 	header []byte
@@ -71,6 +118,7 @@ type Serializer struct {
 func NewSerializer() *Serializer {
 	serializer := &Serializer{
 		fieldSortOrder: NoSortFields,
+		dialect:        InfluxDB,
 
 		header: make([]byte, 0, 50),
 		footer: make([]byte, 0, 21),
@@ -91,6 +139,10 @@ func (s *Serializer) SetFieldTypeSupport(typeSupport FieldTypeSupport) {
 	s.fieldTypeSupport = typeSupport
 }
 
+func (s *Serializer) SetDialect(dialect Dialect) {
+	s.dialect = dialect
+}
+
 // Serialize writes the telegraf.Metric to a byte slice.  May produce multiple
 // lines of output if longer than maximum line length.  Lines are terminated
 // with a newline (LF) char.
@@ -291,17 +343,17 @@ func (s *Serializer) newMetricError(reason string) *MetricError {
 func (s *Serializer) appendFieldValue(buf []byte, value interface{}) ([]byte, error) {
 	switch v := value.(type) {
 	case uint64:
-		if s.fieldTypeSupport&UintSupport != 0 {
-			return appendUintField(buf, v), nil
+		if s.fieldTypeSupport&UintSupport != 0 && s.dialect.UintSuffix != "" {
+			return appendUintField(buf, v, s.dialect.UintSuffix), nil
 		} else {
 			if v <= uint64(MaxInt64) {
-				return appendIntField(buf, int64(v)), nil
+				return appendIntField(buf, int64(v), s.dialect.IntSuffix), nil
 			} else {
-				return appendIntField(buf, int64(MaxInt64)), nil
+				return appendIntField(buf, int64(MaxInt64), s.dialect.IntSuffix), nil
 			}
 		}
 	case int64:
-		return appendIntField(buf, v), nil
+		return appendIntField(buf, v, s.dialect.IntSuffix), nil
 	case float64:
 		if math.IsNaN(v) {
 			return nil, &FieldError{"is NaN"}
@@ -311,7 +363,7 @@ func (s *Serializer) appendFieldValue(buf []byte, value interface{}) ([]byte, er
 			return nil, &FieldError{"is Inf"}
 		}
 
-		return appendFloatField(buf, v), nil
+		return appendFloatField(buf, v, s.dialect.FloatSuffix), nil
 	case string:
 		return appendStringField(buf, v), nil
 	case bool:
@@ -321,16 +373,16 @@ func (s *Serializer) appendFieldValue(buf []byte, value interface{}) ([]byte, er
 	}
 }
 
-func appendUintField(buf []byte, value uint64) []byte {
-	return append(strconv.AppendUint(buf, value, 10), 'u')
+func appendUintField(buf []byte, value uint64, suffix string) []byte {
+	return append(strconv.AppendUint(buf, value, 10), suffix...)
 }
 
-func appendIntField(buf []byte, value int64) []byte {
-	return append(strconv.AppendInt(buf, value, 10), 'i')
+func appendIntField(buf []byte, value int64, suffix string) []byte {
+	return append(strconv.AppendInt(buf, value, 10), suffix...)
 }
 
-func appendFloatField(buf []byte, value float64) []byte {
-	return strconv.AppendFloat(buf, value, 'f', -1, 64)
+func appendFloatField(buf []byte, value float64, suffix string) []byte {
+	return append(strconv.AppendFloat(buf, value, 'f', -1, 64), suffix...)
 }
 
 func appendBoolField(buf []byte, value bool) []byte {
@@ -488,3 +488,47 @@ func TestSerialize_SerializeBatch(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte("cpu value=42 0\ncpu value=42 0\n"), output)
 }
+
+func TestSerializeDialect(t *testing.T) {
+	m := MustMetric(
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"float":  42.5,
+				"int":    int64(-1),
+				"uint":   uint64(2),
+				"string": "x",
+				"bool":   true,
+			},
+			time.Unix(0, 0),
+		),
+	)
+
+	tests := []struct {
+		dialect string
+		output  string
+	}{
+		{"", "cpu,host=a bool=true,float=42.5,int=-1i,string=\"x\",uint=2u 0\n"},
+		{"greptimedb", "cpu,host=a bool=true,float=42.5,int=-1i,string=\"x\",uint=2u 0\n"},
+		{"opengemini", "cpu,host=a bool=true,float=42.5,int=-1i,string=\"x\",uint=2i 0\n"},
+		{"tdengine", "cpu,host=a bool=true,float=42.5f64,int=-1i64,string=\"x\",uint=2u64 0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			dialect, err := GetDialect(tt.dialect)
+			require.NoError(t, err)
+
+			serializer := NewSerializer()
+			serializer.SetFieldSortOrder(SortFields)
+			serializer.SetFieldTypeSupport(UintSupport)
+			serializer.SetDialect(dialect)
+			output, err := serializer.Serialize(m)
+			require.NoError(t, err)
+			require.Equal(t, tt.output, string(output))
+		})
+	}
+
+	_, err := GetDialect("unknown")
+	require.Error(t, err)
+}
@@ -61,6 +61,9 @@ type Config struct {
 	// Support unsigned integer output; influx format only
 	InfluxUintSupport bool `toml:"influx_uint_support"`
 
+	// Line protocol dialect of the database; influx format only
+	InfluxDialect string `toml:"influx_dialect"`
+
 	// Prefix to add to all measurements, only supports Graphite
 	Prefix string `toml:"prefix"`
 
@@ -177,10 +180,16 @@ func NewInfluxSerializerConfig(config *Config) (Serializer, error) {
 		typeSupport = typeSupport + influx.UintSupport
 	}
 
+	dialect, err := influx.GetDialect(config.InfluxDialect)
+	if err != nil {
+		return nil, err
+	}
+
 	s := influx.NewSerializer()
 	s.SetMaxLineBytes(config.InfluxMaxLineBytes)
 	s.SetFieldSortOrder(sort)
 	s.SetFieldTypeSupport(typeSupport)
+	s.SetDialect(dialect)
 	return s, nil
 }
 
@@ -0,0 +1,152 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// UpdateGoldenEnv is the environment variable which, when set to "true",
+// makes RequireGolden write the actual metrics to the golden files instead
+// of comparing them:
+//
+//   TELEGRAF_UPDATE_GOLDEN=true go test ./plugins/inputs/example
+const UpdateGoldenEnv = "TELEGRAF_UPDATE_GOLDEN"
+
+// ParseMetricsFile returns the metrics of a file in the InfluxDB line
+// protocol.
+func ParseMetricsFile(path string) ([]telegraf.Metric, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parser := influx.NewParser(influx.NewMetricHandler())
+	return parser.Parse(content)
+}
+
+// RequireGolden halts the test with an error if the metrics are not equal to
+// those of the golden file in the InfluxDB line protocol.  The line protocol
+// doesn't have the value type of the metrics, so they are not compared.
+func RequireGolden(t *testing.T, path string, actual []telegraf.Metric, opts ...cmp.Option) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) == "true" {
+		s := serializer.NewSerializer()
+		s.SetFieldSortOrder(serializer.SortFields)
+		s.SetFieldTypeSupport(serializer.UintSupport)
+		var buf bytes.Buffer
+		for _, m := range actual {
+			if _, err := s.Write(&buf, m); err != nil {
+				t.Fatalf("serializing metric failed: %v", err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := ParseMetricsFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file %s failed: %v", path, err)
+	}
+	opts = append(opts, IgnoreType())
+	RequireMetricsEqual(t, expected, actual, opts...)
+}
+
+// RequireGatherGolden gathers the input once, initializing it first if it is
+// a telegraf.Initializer, and compares the metrics with the golden file.
+// Gather errors halt the test.
+func RequireGatherGolden(t *testing.T, input telegraf.Input, path string, opts ...cmp.Option) {
+	t.Helper()
+
+	if i, ok := input.(telegraf.Initializer); ok {
+		if err := i.Init(); err != nil {
+			t.Fatalf("initializing input failed: %v", err)
+		}
+	}
+
+	var acc Accumulator
+	if err := acc.GatherError(input.Gather); err != nil {
+		t.Fatalf("gathering input failed: %v", err)
+	}
+	RequireGolden(t, path, acc.GetTelegrafMetrics(), opts...)
+}
+
+// Interaction is a recorded HTTP request and its response.
+type Interaction struct {
+	Request struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+		// BodyFile is the file with the body, relative to the cassette.
+		BodyFile string `json:"body_file"`
+	} `json:"response"`
+}
+
+// NewCassetteServer returns a server replaying the interactions of the JSON
+// cassette file, an array of interactions.  A request is answered with the
+// first interaction with its method and path, including the query string;
+// requests without interaction fail the test.  The server must be closed.
+func NewCassetteServer(t *testing.T, path string) *httptest.Server {
+	t.Helper()
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading cassette failed: %v", err)
+	}
+	var interactions []*Interaction
+	if err := json.Unmarshal(content, &interactions); err != nil {
+		t.Fatalf("parsing cassette %s failed: %v", path, err)
+	}
+
+	bodies := make([][]byte, len(interactions))
+	for i, interaction := range interactions {
+		if interaction.Request.Method == "" {
+			interaction.Request.Method = http.MethodGet
+		}
+		if interaction.Response.Status == 0 {
+			interaction.Response.Status = http.StatusOK
+		}
+		bodies[i] = []byte(interaction.Response.Body)
+		if interaction.Response.BodyFile != "" {
+			bodies[i], err = ioutil.ReadFile(filepath.Join(filepath.Dir(path), interaction.Response.BodyFile))
+			if err != nil {
+				t.Fatalf("reading cassette body failed: %v", err)
+			}
+		}
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, interaction := range interactions {
+			if interaction.Request.Method != r.Method || interaction.Request.Path != r.URL.RequestURI() {
+				continue
+			}
+			for k, v := range interaction.Response.Headers {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(interaction.Response.Status)
+			w.Write(bodies[i])
+			return
+		}
+		t.Errorf("no interaction for request %s %s in cassette %s", r.Method, r.URL.RequestURI(), path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
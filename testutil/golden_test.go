@@ -0,0 +1,96 @@
+package testutil
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+// status is an input gathering the status of a server.
+type status struct {
+	URL string
+}
+
+func (s *status) SampleConfig() string { return "" }
+func (s *status) Description() string  { return "" }
+
+func (s *status) Gather(acc telegraf.Accumulator) error {
+	resp, err := http.Get(s.URL + "/status?format=json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Server      string  `json:"server"`
+		Connections int64   `json:"connections"`
+		Load        float64 `json:"load"`
+		Uptime      uint64  `json:"uptime"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	acc.AddGauge("status",
+		map[string]interface{}{
+			"connections": body.Connections,
+			"load":        body.Load,
+			"uptime":      body.Uptime,
+		},
+		map[string]string{"server": body.Server},
+		time.Unix(1600000000, 0),
+	)
+	return nil
+}
+
+func TestRequireGatherGolden(t *testing.T) {
+	ts := NewCassetteServer(t, "testdata/cassette.json")
+	defer ts.Close()
+
+	RequireGatherGolden(t, &status{URL: ts.URL}, "testdata/status.influx")
+}
+
+func TestCassetteServer(t *testing.T) {
+	ts := NewCassetteServer(t, "testdata/cassette.json")
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "ok", string(body))
+}
+
+func TestRequireGoldenUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golden")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.influx")
+
+	metrics := []telegraf.Metric{
+		MustMetric("cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage_user": 42.0, "usage_idle": 58.0},
+			time.Unix(1600000000, 0),
+			telegraf.Gauge,
+		),
+	}
+
+	os.Setenv(UpdateGoldenEnv, "true")
+	RequireGolden(t, path, metrics)
+	os.Unsetenv(UpdateGoldenEnv)
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "cpu,cpu=cpu0 usage_idle=58,usage_user=42 1600000000000000000\n", string(content))
+
+	RequireGolden(t, path, metrics)
+}
@@ -1,8 +1,10 @@
 package testutil
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -57,7 +59,7 @@ func lessFunc(lhs, rhs *metricDiff) bool {
 
 		if lhs.Fields[i].Value != rhs.Fields[i].Value {
 			ltype := reflect.TypeOf(lhs.Fields[i].Value)
-			rtype := reflect.TypeOf(lhs.Fields[i].Value)
+			rtype := reflect.TypeOf(rhs.Fields[i].Value)
 
 			if ltype.Kind() != rtype.Kind() {
 				return ltype.Kind() < rtype.Kind()
@@ -65,13 +67,13 @@ func lessFunc(lhs, rhs *metricDiff) bool {
 
 			switch v := lhs.Fields[i].Value.(type) {
 			case int64:
-				return v < lhs.Fields[i].Value.(int64)
+				return v < rhs.Fields[i].Value.(int64)
 			case uint64:
-				return v < lhs.Fields[i].Value.(uint64)
+				return v < rhs.Fields[i].Value.(uint64)
 			case float64:
-				return v < lhs.Fields[i].Value.(float64)
+				return v < rhs.Fields[i].Value.(float64)
 			case string:
-				return v < lhs.Fields[i].Value.(string)
+				return v < rhs.Fields[i].Value.(string)
 			case bool:
 				return !v
 			default:
@@ -128,6 +130,28 @@ func IgnoreTime() cmp.Option {
 	return cmpopts.IgnoreFields(metricDiff{}, "Time")
 }
 
+// IgnoreType disables comparison of the value type.
+func IgnoreType() cmp.Option {
+	return cmpopts.IgnoreFields(metricDiff{}, "Type")
+}
+
+// FloatTolerance compares float fields as equal when they differ by at most
+// epsilon.
+func FloatTolerance(epsilon float64) cmp.Option {
+	return cmpopts.EquateApprox(0, epsilon)
+}
+
+// TimeTolerance compares timestamps as equal when they differ by at most d.
+func TimeTolerance(d time.Duration) cmp.Option {
+	return cmp.Comparer(func(x, y time.Time) bool {
+		delta := x.Sub(y)
+		if delta < 0 {
+			delta = -delta
+		}
+		return delta <= d
+	})
+}
+
 // MetricEqual returns true if the metrics are equal.
 func MetricEqual(expected, actual telegraf.Metric, opts ...cmp.Option) bool {
 	var lhs, rhs *metricDiff
@@ -157,7 +181,8 @@ func RequireMetricEqual(t *testing.T, expected, actual telegraf.Metric, opts ...
 
 	opts = append(opts, cmpopts.EquateNaNs())
 	if diff := cmp.Diff(lhs, rhs, opts...); diff != "" {
-		t.Fatalf("telegraf.Metric\n--- expected\n+++ actual\n%s", diff)
+		t.Fatalf("telegraf.Metric\n--- expected\n+++ actual\n%s\n%s",
+			diff, formatDiff([]*metricDiff{lhs}, []*metricDiff{rhs}))
 	}
 }
 
@@ -177,7 +202,89 @@ func RequireMetricsEqual(t *testing.T, expected, actual []telegraf.Metric, opts
 
 	opts = append(opts, cmpopts.EquateNaNs())
 	if diff := cmp.Diff(lhs, rhs, opts...); diff != "" {
-		t.Fatalf("[]telegraf.Metric\n--- expected\n+++ actual\n%s", diff)
+		t.Fatalf("[]telegraf.Metric\n--- expected\n+++ actual\n%s\n%s", diff, formatDiff(lhs, rhs))
+	}
+}
+
+// formatDiff returns the metrics in a line protocol like format, the
+// expected metrics missing of the actual metrics are prefixed with "-" and
+// the unexpected actual metrics with "+".
+func formatDiff(expected, actual []*metricDiff) string {
+	lhs := make([]string, 0, len(expected))
+	for _, m := range expected {
+		lhs = append(lhs, formatMetric(m))
+	}
+	rhs := make([]string, 0, len(actual))
+	for _, m := range actual {
+		rhs = append(rhs, formatMetric(m))
+	}
+	sort.Strings(lhs)
+	sort.Strings(rhs)
+
+	count := make(map[string]int)
+	for _, line := range rhs {
+		count[line]++
+	}
+
+	var b strings.Builder
+	for _, line := range lhs {
+		if count[line] > 0 {
+			count[line]--
+			b.WriteString("  " + line + "\n")
+			continue
+		}
+		b.WriteString("- " + line + "\n")
+	}
+
+	count = make(map[string]int)
+	for _, line := range lhs {
+		count[line]++
+	}
+	for _, line := range rhs {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		b.WriteString("+ " + line + "\n")
+	}
+	return b.String()
+}
+
+// formatMetric returns the metric as a line protocol like string, with the
+// value types of the fields and the type of the metric.
+func formatMetric(m *metricDiff) string {
+	if m == nil {
+		return "<nil>"
+	}
+
+	var b strings.Builder
+	b.WriteString(m.Measurement)
+	for _, tag := range m.Tags {
+		fmt.Fprintf(&b, ",%s=%s", tag.Key, tag.Value)
+	}
+	for i, field := range m.Fields {
+		sep := ","
+		if i == 0 {
+			sep = " "
+		}
+		fmt.Fprintf(&b, "%s%s=%#v(%T)", sep, field.Key, field.Value, field.Value)
+	}
+	fmt.Fprintf(&b, " %s %d", typeName(m.Type), m.Time.UnixNano())
+	return b.String()
+}
+
+func typeName(t telegraf.ValueType) string {
+	switch t {
+	case telegraf.Counter:
+		return "counter"
+	case telegraf.Gauge:
+		return "gauge"
+	case telegraf.Summary:
+		return "summary"
+	case telegraf.Histogram:
+		return "histogram"
+	default:
+		return "untyped"
 	}
 }
 
@@ -7,6 +7,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRequireMetricEqual(t *testing.T) {
@@ -97,6 +98,78 @@ func TestRequireMetricsEqual(t *testing.T) {
 			},
 			opts: []cmp.Option{SortMetrics()},
 		},
+		{
+			name: "sort metrics option sorts by field value",
+			got: []telegraf.Metric{
+				MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 2.0},
+					time.Unix(0, 0),
+				),
+				MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 1.0},
+					time.Unix(0, 0),
+				),
+			},
+			want: []telegraf.Metric{
+				MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 1.0},
+					time.Unix(0, 0),
+				),
+				MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 2.0},
+					time.Unix(0, 0),
+				),
+			},
+			opts: []cmp.Option{SortMetrics()},
+		},
+		{
+			name: "float tolerance option",
+			got: []telegraf.Metric{
+				MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{"usage": 0.1 + 0.2},
+					time.Unix(0, 0),
+				),
+			},
+			want: []telegraf.Metric{
+				MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{"usage": 0.3},
+					time.Unix(0, 0),
+				),
+			},
+			opts: []cmp.Option{FloatTolerance(1e-9)},
+		},
+		{
+			name: "time tolerance option",
+			got: []telegraf.Metric{
+				MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{},
+					time.Unix(0, 0).Add(500*time.Millisecond),
+				),
+			},
+			want: []telegraf.Metric{
+				MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{},
+					time.Unix(0, 0),
+				),
+			},
+			opts: []cmp.Option{TimeTolerance(time.Second)},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -104,3 +177,42 @@ func TestRequireMetricsEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatDiff(t *testing.T) {
+	expected := []*metricDiff{
+		newMetricDiff(MustMetric(
+			"cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage": 42.0},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		)),
+		newMetricDiff(MustMetric(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"used": int64(1024)},
+			time.Unix(0, 0),
+		)),
+	}
+	actual := []*metricDiff{
+		newMetricDiff(MustMetric(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"used": int64(1024)},
+			time.Unix(0, 0),
+		)),
+		newMetricDiff(MustMetric(
+			"cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage": int64(42)},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		)),
+	}
+
+	require.Equal(t,
+		"- cpu,cpu=cpu0 usage=42(float64) gauge 0\n"+
+			"  mem used=1024(int64) untyped 0\n"+
+			"+ cpu,cpu=cpu0 usage=42(int64) gauge 0\n",
+		formatDiff(expected, actual))
+}